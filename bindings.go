@@ -0,0 +1,117 @@
+package graphql
+
+import (
+	"reflect"
+	"sort"
+)
+
+// FieldBinding describes how a field of the schema is bound to its resolver, see Schema.Bindings.
+type FieldBinding struct {
+	// Type and Field name the field of the schema.
+	Type  string
+	Field string
+
+	// ResolverType is the Go type of the values of Type. A type of the schema resolved by several
+	// Go types has a binding per Go type.
+	ResolverType reflect.Type
+
+	// Resolver is "method" if a method of ResolverType resolves the field, "field" if its value is
+	// read from a struct field, "func" if it is resolved by a FieldFunc, "delegate" if it is sent
+	// to an upstream service and "none" if it is unbound, see LenientBinding.
+	Resolver string
+
+	// Method is the name of the resolver method, empty if Resolver is not "method".
+	Method string
+
+	// StructField is the name of the struct field, empty if Resolver is not "field".
+	StructField string
+
+	// ArgsType is the struct type the arguments of the field are packed into, nil if the field has
+	// no arguments or they are not passed as a struct.
+	ArgsType reflect.Type
+
+	// ReturnType is the Go type of the values the resolver returns, nil if the field is delegated
+	// or unbound.
+	ReturnType reflect.Type
+
+	// HasContext, HasSelected and HasError report whether the resolver takes a context and the
+	// selected fields and whether it returns an error.
+	HasContext  bool
+	HasSelected bool
+	HasError    bool
+
+	// Async reports whether the resolver may block, e.g. because it takes a context. A selection
+	// set with an async field resolves all of its fields concurrently.
+	Async bool
+}
+
+// Bindings returns the bindings of the fields of the types reachable from the query and mutation
+// types, sorted by type, field and Go type. Introspection fields are left out. It panics if the
+// schema was created without a resolver.
+func (s *Schema) Bindings() []*FieldBinding {
+	if s.res == nil {
+		panic("schema created without resolver, can not list bindings")
+	}
+
+	var bindings []*FieldBinding
+	for _, o := range s.res.Objects() {
+		if o.ResolverType == placeholderType {
+			continue // the types below unbound fields, see LenientBinding
+		}
+		for _, f := range o.Fields {
+			b := &FieldBinding{
+				Type:         o.Name,
+				Field:        f.Name,
+				ResolverType: o.ResolverType,
+				HasContext:   f.HasContext,
+				HasSelected:  f.HasSelected,
+				HasError:     f.HasError,
+				Async:        f.HasContext || f.ArgsPacker != nil || f.HasError,
+			}
+			switch {
+			case f.Unbound:
+				b.Resolver = "none"
+			case f.Delegate != nil:
+				b.Resolver = "delegate"
+			case f.Func != nil:
+				b.Resolver = "func"
+				b.ReturnType = f.Func.ResultType
+			case f.FieldIndex != nil:
+				sf := structType(o.ResolverType).FieldByIndex(f.FieldIndex)
+				b.Resolver = "field"
+				b.StructField = sf.Name
+				b.ReturnType = sf.Type
+			default:
+				m := o.ResolverType.Method(f.MethodIndex)
+				b.Resolver = "method"
+				b.Method = m.Name
+				b.ReturnType = m.Type.Out(0)
+			}
+			if f.ArgsPacker != nil {
+				b.ArgsType = f.ArgsPacker.StructType()
+			}
+			bindings = append(bindings, b)
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		a, b := bindings[i], bindings[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.ResolverType.String() < b.ResolverType.String()
+	})
+	return bindings
+}
+
+var placeholderType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"time"
+
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// RequestDependent reports whether the responses of the schema may depend on the request beyond
+// its query and variables: with UseAuthorization, UseVisibility, HideInternal, variables hooks or a
+// constructor of root resolvers, whose results may differ by the user of the request. Shared caches
+// of responses have to tell these requests apart, see relay.ResponseCache.
+func (s *Schema) RequestDependent() bool {
+	return s.auth != nil || len(s.visibility) != 0 || len(s.variablesHooks) != 0 ||
+		s.res != nil && s.res.Constructor.IsValid()
+}
+
+// CacheControl returns how long the response of the operation may be cached, from the
+// @cacheControl(maxAge: Int!) schema directives, whose maxAge is given in seconds:
+//
+//	directive @cacheControl(maxAge: Int!) on FIELD_DEFINITION
+//
+// A field without the directive inherits the maximum age of its parent, a root field without it
+// makes the operation uncacheable. The maximum age of the operation is the least of all of its
+// fields. It is 0 for mutations, subscriptions and operations that can not be parsed.
+// relay.Handler caches responses for this long, see relay.ResponseCache.
+func (s *Schema) CacheControl(queryString string, operationName string) time.Duration {
+	doc, qErr := s.parseQuery(queryString)
+	if qErr != nil {
+		return 0
+	}
+	op, err := getOperation(doc, operationName)
+	if err != nil || op.Type != query.Query {
+		return 0
+	}
+	c := &cacheControl{doc: doc, schema: s.schema, spreading: make(map[string]bool)}
+	maxAge, ok := c.selections(op.Selections, s.schema.EntryPoints["query"], 0, false)
+	if !ok {
+		return 0
+	}
+	return time.Duration(maxAge) * time.Second
+}
+
+type cacheControl struct {
+	doc       *query.Document
+	schema    *schema.Schema
+	spreading map[string]bool // the fragments being spread
+}
+
+// selections returns the least maximum age of the selections of type t, whose parent has the
+// maximum age inherited, if known. It reports false if a selection has no maximum age.
+func (c *cacheControl) selections(sels []query.Selection, t schema.NamedType, inherited int32, known bool) (int32, bool) {
+	least, found := inherited, known
+	merge := func(maxAge int32, ok bool) bool {
+		if !ok {
+			return false
+		}
+		if !found || maxAge < least {
+			least, found = maxAge, true
+		}
+		return true
+	}
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			var fields schema.FieldList
+			switch t := t.(type) {
+			case *schema.Object:
+				fields = t.Fields
+			case *schema.Interface:
+				fields = t.Fields
+			}
+			f := fields.Get(sel.Name.Name)
+			if f == nil {
+				continue // __typename or an introspection field
+			}
+			maxAge, ok := directiveMaxAge(f.Directives)
+			fieldType, _ := namedType(f.Type).(schema.NamedType)
+			if !ok {
+				maxAge, ok = inherited, known
+			}
+			if !merge(maxAge, ok) {
+				return 0, false
+			}
+			if !merge(c.selections(sel.Selections, fieldType, maxAge, true)) {
+				return 0, false
+			}
+		case *query.InlineFragment:
+			fragType := t
+			if sel.On.Name != "" {
+				fragType = c.schema.Types[sel.On.Name]
+			}
+			if !merge(c.selections(sel.Selections, fragType, inherited, known)) {
+				return 0, false
+			}
+		case *query.FragmentSpread:
+			frag := c.doc.Fragments.Get(sel.Name.Name)
+			if frag == nil || c.spreading[sel.Name.Name] {
+				continue // the cycle is rejected by the validation
+			}
+			c.spreading[sel.Name.Name] = true
+			maxAge, ok := c.selections(frag.Selections, c.schema.Types[frag.On.Name], inherited, known)
+			c.spreading[sel.Name.Name] = false
+			if !merge(maxAge, ok) {
+				return 0, false
+			}
+		}
+	}
+	return least, found
+}
+
+// directiveMaxAge returns the maxAge of the @cacheControl directive, if any.
+func directiveMaxAge(directives common.DirectiveList) (int32, bool) {
+	d := directives.Get("cacheControl")
+	if d == nil {
+		return 0, false
+	}
+	lit, ok := d.Args.Get("maxAge")
+	if !ok {
+		return 0, false
+	}
+	maxAge, ok := lit.Value(nil).(int32)
+	return maxAge, ok
+}
@@ -0,0 +1,207 @@
+// Package client implements a GraphQL client speaking the protocol served by relay.Handler: queries
+// and mutations are posted as JSON, optionally as automatic persisted queries or as multipart
+// requests carrying file uploads, and subscriptions run over a websocket with the
+// graphql-transport-ws protocol. The client satisfies the Executor interface of the code generated
+// by codegen.GenerateClient.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/errors"
+)
+
+// Client executes GraphQL operations against the endpoint at a URL.
+type Client struct {
+	url              string
+	httpClient       *http.Client
+	header           http.Header
+	persistedQueries bool
+	hashes           sync.Map // query -> sha256 hash
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// HTTPClient sets the HTTP client used for requests. It defaults to http.DefaultClient.
+func HTTPClient(c *http.Client) Option {
+	return func(client *Client) {
+		client.httpClient = c
+	}
+}
+
+// Header adds a header that is sent with every request, including the websocket handshake of
+// subscriptions.
+func Header(key, value string) Option {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// PersistedQueries makes the client send automatic persisted queries: a request first carries the
+// SHA-256 hash of the query only and is repeated with the full query if the server does not know
+// the hash yet.
+func PersistedQueries() Option {
+	return func(c *Client) {
+		c.persistedQueries = true
+	}
+}
+
+// New returns a client for the GraphQL endpoint at url.
+func New(url string, opts ...Option) *Client {
+	c := &Client{
+		url:        url,
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Errors are the GraphQL errors of a response.
+type Errors []*errors.QueryError
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type request struct {
+	Query         string                 `json:"query,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     interface{}            `json:"variables,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors Errors          `json:"errors"`
+}
+
+// Execute executes the query with the given variables and decodes the data of the response into
+// out, which may be nil. Variables are encoded as JSON, values of type Upload are sent as files of
+// a multipart request. If the response has errors, the data is still decoded and the errors are
+// returned as Errors.
+func (c *Client) Execute(ctx context.Context, query string, variables interface{}, out interface{}) error {
+	return c.ExecuteOperation(ctx, query, "", variables, out)
+}
+
+// ExecuteOperation is like Execute, but selects the operation of the document by name.
+func (c *Client) ExecuteOperation(ctx context.Context, query string, operationName string, variables interface{}, out interface{}) error {
+	req := &request{Query: query, OperationName: operationName, Variables: variables}
+
+	var resp *response
+	var err error
+	if c.persistedQueries && len(findUploads(variables)) == 0 { // uploads can not be sent twice
+		req.Extensions = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{
+				"version":    1,
+				"sha256Hash": c.hash(query),
+			},
+		}
+		req.Query = ""
+		resp, err = c.do(ctx, req)
+		if err == nil && persistedQueryNotFound(resp.Errors) {
+			req.Query = query
+			resp, err = c.do(ctx, req)
+		}
+	} else {
+		resp, err = c.do(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+
+	if out != nil && len(resp.Data) != 0 && string(resp.Data) != "null" {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return perrors.Wrap(err, "client: decoding data")
+		}
+	}
+	if len(resp.Errors) != 0 {
+		return resp.Errors
+	}
+	return nil
+}
+
+func (c *Client) hash(query string) string {
+	if h, ok := c.hashes.Load(query); ok {
+		return h.(string)
+	}
+	sum := sha256.Sum256([]byte(query))
+	h := hex.EncodeToString(sum[:])
+	c.hashes.Store(query, h)
+	return h
+}
+
+func persistedQueryNotFound(errs Errors) bool {
+	for _, err := range errs {
+		if err.Message == "PersistedQueryNotFound" || err.Extensions["code"] == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) do(ctx context.Context, req *request) (*response, error) {
+	body, contentType, err := encodeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", c.url, body)
+	if err != nil {
+		return nil, perrors.Wrap(err, "client")
+	}
+	httpReq = httpReq.WithContext(ctx)
+	for key, values := range c.header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("Accept", "application/json")
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, perrors.Wrap(err, "client")
+	}
+	defer httpResp.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, perrors.Wrap(err, "client: reading response")
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		if httpResp.StatusCode != http.StatusOK {
+			return nil, perrors.Errorf("client: unexpected response status %s", httpResp.Status)
+		}
+		return nil, perrors.Wrap(err, "client: decoding response")
+	}
+	return &resp, nil
+}
+
+func encodeRequest(req *request) (io.Reader, string, error) {
+	uploads := findUploads(req.Variables)
+	if len(uploads) == 0 {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return nil, "", perrors.Wrap(err, "client: encoding request")
+		}
+		return bytes.NewReader(data), "application/json", nil
+	}
+	return encodeMultipart(req, uploads)
+}
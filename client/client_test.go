@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/example/starwars"
+	"github.com/qdentity/graphql-go/relay"
+)
+
+var starwarsSchema = graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{})
+
+func TestExecute(t *testing.T) {
+	srv := httptest.NewServer(&relay.Handler{Schema: starwarsSchema})
+	defer srv.Close()
+	c := New(srv.URL)
+
+	var out struct {
+		Hero struct {
+			Name string `json:"name"`
+		} `json:"hero"`
+	}
+	vars := struct {
+		Episode string `json:"episode"`
+	}{"EMPIRE"}
+	if err := c.Execute(context.Background(), `query($episode: Episode) { hero(episode: $episode) { name } }`, vars, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Hero.Name != "Luke Skywalker" {
+		t.Errorf("got hero %q, want %q", out.Hero.Name, "Luke Skywalker")
+	}
+
+	err := c.Execute(context.Background(), `{ hero { unknown } }`, nil, nil)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("got error %v, want one GraphQL error", err)
+	}
+	if len(errs[0].Locations) != 1 || errs[0].Locations[0].Line != 1 {
+		t.Errorf("got locations %v, want line 1", errs[0].Locations)
+	}
+}
+
+func TestPersistedQueries(t *testing.T) {
+	known := make(map[string]string)
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var req struct {
+			Query      string `json:"query"`
+			Extensions struct {
+				PersistedQuery struct {
+					Hash string `json:"sha256Hash"`
+				} `json:"persistedQuery"`
+			} `json:"extensions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		hash := req.Extensions.PersistedQuery.Hash
+		if req.Query == "" {
+			if _, ok := known[hash]; !ok {
+				io.WriteString(w, `{"errors":[{"message":"PersistedQueryNotFound"}]}`)
+				return
+			}
+		} else {
+			known[hash] = req.Query
+		}
+		io.WriteString(w, `{"data":{"hello":"world"}}`)
+	}))
+	defer srv.Close()
+	c := New(srv.URL, PersistedQueries())
+
+	for i, wantRequests := range []int{2, 3} {
+		var out struct{ Hello string }
+		if err := c.Execute(context.Background(), `{ hello }`, nil, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Hello != "world" {
+			t.Errorf("got %q, want %q", out.Hello, "world")
+		}
+		if requests != wantRequests {
+			t.Errorf("execution %d: got %d requests in total, want %d", i, requests, wantRequests)
+		}
+	}
+}
+
+func TestUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		if got, want := r.FormValue("operations"), `{"query":"mutation($files: [Upload!]!) { upload(files: $files) }","variables":{"files":[null,null]}}`; got != want {
+			t.Errorf("got operations %s, want %s", got, want)
+		}
+		if got, want := r.FormValue("map"), `{"0":["variables.files.0"],"1":["variables.files.1"]}`; got != want {
+			t.Errorf("got map %s, want %s", got, want)
+		}
+		f, h, err := r.FormFile("1")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		content, _ := ioutil.ReadAll(f)
+		if h.Filename != "b.txt" || string(content) != "bbb" {
+			t.Errorf("got file %q with content %q", h.Filename, content)
+		}
+		io.WriteString(w, `{"data":{"upload":true}}`)
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+
+	vars := map[string]interface{}{
+		"files": []*Upload{
+			{Filename: "a.txt", Content: strings.NewReader("aaa")},
+			{Filename: "b.txt", Content: strings.NewReader("bbb")},
+		},
+	}
+	var out struct{ Upload bool }
+	if err := c.Execute(context.Background(), `mutation($files: [Upload!]!) { upload(files: $files) }`, vars, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Upload {
+		t.Error("upload not acknowledged")
+	}
+}
+
+type uploadNode struct {
+	File *Upload     `json:"file"`
+	Next *uploadNode `json:"next"`
+}
+
+func TestFindUploadsCycles(t *testing.T) {
+	cyclic := &uploadNode{File: &Upload{Filename: "a.txt"}}
+	cyclic.Next = cyclic
+	shared := &uploadNode{File: &Upload{Filename: "b.txt"}}
+	vars := map[string]interface{}{"cyclic": cyclic, "x": shared, "y": shared}
+	vars["self"] = vars
+
+	var paths []string
+	for _, u := range findUploads(vars) {
+		paths = append(paths, u.path)
+	}
+	sort.Strings(paths)
+	if want := []string{"variables.cyclic.file", "variables.x.file", "variables.y.file"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got paths %v, want %v", paths, want)
+	}
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	perrors "github.com/pkg/errors"
+)
+
+// subscriptionProtocol is the websocket subprotocol of subscriptions, see
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+const subscriptionProtocol = "graphql-transport-ws"
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Subscription is a running subscription. Its events are read with Next.
+type Subscription struct {
+	conn      *wsConn
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Subscribe starts a subscription over a websocket connection to the client's URL, where the
+// scheme http is replaced by ws and https by wss. The subscription ends when the server completes
+// it, when ctx is done or when it is closed.
+func (c *Client) Subscribe(ctx context.Context, query string, variables interface{}) (*Subscription, error) {
+	conn, err := dialWebsocket(ctx, c.url, subscriptionProtocol, c.header)
+	if err != nil {
+		return nil, err
+	}
+	s := &Subscription{conn: conn, done: make(chan struct{})}
+
+	if err := s.send(&wsMessage{Type: "connection_init"}); err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+	msg, err := s.read()
+	if err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+	if msg.Type != "connection_ack" {
+		conn.conn.Close()
+		return nil, perrors.Errorf("client: expected connection_ack, got %q", msg.Type)
+	}
+
+	payload, err := json.Marshal(&request{Query: query, Variables: variables})
+	if err != nil {
+		conn.conn.Close()
+		return nil, perrors.Wrap(err, "client: encoding request")
+	}
+	if err := s.send(&wsMessage{ID: "1", Type: "subscribe", Payload: payload}); err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.done:
+		}
+	}()
+	return s, nil
+}
+
+func (s *Subscription) send(msg *wsMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return perrors.Wrap(err, "client")
+	}
+	return perrors.Wrap(s.conn.writeMessage(data), "client")
+}
+
+func (s *Subscription) read() (*wsMessage, error) {
+	data, err := s.conn.readMessage()
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, perrors.Wrap(err, "client")
+	}
+	var msg wsMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, perrors.Wrap(err, "client: decoding message")
+	}
+	return &msg, nil
+}
+
+// Next waits for the next event of the subscription and decodes its data into out, which may be
+// nil. Like Execute it returns Errors if the event has errors. It returns io.EOF once the
+// subscription has ended.
+func (s *Subscription) Next(out interface{}) error {
+	for {
+		select {
+		case <-s.done:
+			return io.EOF
+		default:
+		}
+
+		msg, err := s.read()
+		if err != nil {
+			select {
+			case <-s.done:
+				return io.EOF // closed concurrently
+			default:
+			}
+			s.Close()
+			return err
+		}
+
+		switch msg.Type {
+		case "next":
+			var resp response
+			if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+				return perrors.Wrap(err, "client: decoding response")
+			}
+			if out != nil && len(resp.Data) != 0 && string(resp.Data) != "null" {
+				if err := json.Unmarshal(resp.Data, out); err != nil {
+					return perrors.Wrap(err, "client: decoding data")
+				}
+			}
+			if len(resp.Errors) != 0 {
+				return resp.Errors
+			}
+			return nil
+
+		case "error":
+			var errs Errors
+			if err := json.Unmarshal(msg.Payload, &errs); err != nil {
+				return perrors.Wrap(err, "client: decoding errors")
+			}
+			s.close(false)
+			return errs
+
+		case "complete":
+			s.close(false)
+			return io.EOF
+
+		case "ping":
+			if err := s.send(&wsMessage{Type: "pong"}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Close stops the subscription and closes its connection.
+func (s *Subscription) Close() error {
+	return s.close(true)
+}
+
+// close closes the connection, telling the server to complete the subscription first unless it
+// has ended it already.
+func (s *Subscription) close(complete bool) error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		if complete {
+			s.send(&wsMessage{ID: "1", Type: "complete"})
+		}
+		err = s.conn.close()
+	})
+	return err
+}
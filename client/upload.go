@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+
+	perrors "github.com/pkg/errors"
+)
+
+// Upload is a file passed as a variable. Requests with uploads are sent as multipart requests
+// following the GraphQL multipart request specification, where the variable itself is null.
+type Upload struct {
+	Filename    string
+	ContentType string
+	Content     io.Reader
+}
+
+// MarshalJSON encodes the upload as null, its content is sent as a separate part.
+func (Upload) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+type upload struct {
+	path string
+	file *Upload
+}
+
+var uploadType = reflect.TypeOf(Upload{})
+
+// findUploads returns the uploads in the variables together with their object paths, e.g.
+// "variables.input.files.0".
+func findUploads(variables interface{}) []upload {
+	var uploads []upload
+	if variables != nil {
+		walkUploads(reflect.ValueOf(variables), "variables", &uploads, make(map[visit]bool))
+	}
+	return uploads
+}
+
+// visit is a pointer, map or slice on the path walked by walkUploads, which stops at cycles. The
+// type and length tell apart the values at the same address, e.g. a struct and its first field.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter marks the pointer, map or slice as on the path. It returns false if it is already, i.e.
+// the variables have a cycle, which their encoding fails for.
+func enter(v reflect.Value, onPath map[visit]bool) (leave func(), ok bool) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if onPath[key] {
+		return nil, false
+	}
+	onPath[key] = true
+	return func() { delete(onPath, key) }, true
+}
+
+func walkUploads(v reflect.Value, path string, uploads *[]upload, onPath map[visit]bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		if v.Type() == reflect.PtrTo(uploadType) {
+			*uploads = append(*uploads, upload{path: path, file: v.Interface().(*Upload)})
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			leave, ok := enter(v, onPath)
+			if !ok {
+				return
+			}
+			defer leave()
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && !v.IsNil() {
+		leave, ok := enter(v, onPath)
+		if !ok {
+			return
+		}
+		defer leave()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == uploadType {
+			u := v.Interface().(Upload)
+			*uploads = append(*uploads, upload{path: path, file: &u})
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous {
+				continue
+			}
+			name := sf.Name
+			if tag := sf.Tag.Get("json"); tag != "" {
+				if tag == "-" {
+					continue
+				}
+				if i := strings.IndexByte(tag, ','); i != -1 {
+					tag = tag[:i]
+				}
+				if tag != "" {
+					name = tag
+				}
+			} else if sf.Anonymous {
+				walkUploads(v.Field(i), path, uploads, onPath)
+				continue
+			}
+			walkUploads(v.Field(i), path+"."+name, uploads, onPath)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			walkUploads(v.MapIndex(key), path+"."+key.String(), uploads, onPath)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkUploads(v.Index(i), path+"."+strconv.Itoa(i), uploads, onPath)
+		}
+	}
+}
+
+func encodeMultipart(req *request, uploads []upload) (io.Reader, string, error) {
+	operations, err := json.Marshal(req)
+	if err != nil {
+		return nil, "", perrors.Wrap(err, "client: encoding request")
+	}
+	fileMap := make(map[string][]string, len(uploads))
+	for i, u := range uploads {
+		fileMap[strconv.Itoa(i)] = []string{u.path}
+	}
+	mapJSON, err := json.Marshal(fileMap)
+	if err != nil {
+		return nil, "", perrors.Wrap(err, "client: encoding request")
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("operations", string(operations)); err != nil {
+		return nil, "", perrors.Wrap(err, "client: encoding request")
+	}
+	if err := w.WriteField("map", string(mapJSON)); err != nil {
+		return nil, "", perrors.Wrap(err, "client: encoding request")
+	}
+	for i, u := range uploads {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+strconv.Itoa(i)+`"; filename="`+escapeQuotes(u.file.Filename)+`"`)
+		contentType := u.file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Type", contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", perrors.Wrap(err, "client: encoding request")
+		}
+		if u.file.Content != nil {
+			if _, err := io.Copy(part, u.file.Content); err != nil {
+				return nil, "", perrors.Wrapf(err, "client: reading upload %q", u.file.Filename)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", perrors.Wrap(err, "client: encoding request")
+	}
+	return &body, w.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
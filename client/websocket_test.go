@@ -0,0 +1,87 @@
+//go:build !js && !wasip1
+// +build !js,!wasip1
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	served := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(served)
+		if r.Header.Get("Sec-WebSocket-Protocol") != subscriptionProtocol {
+			http.Error(w, "unsupported protocol", http.StatusBadRequest)
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		brw.WriteString("Sec-WebSocket-Protocol: " + subscriptionProtocol + "\r\n")
+		brw.WriteString("Sec-WebSocket-Accept: " + websocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		brw.Flush()
+		ws := &wsConn{conn: conn, br: bufio.NewReader(brw)}
+
+		for _, want := range []string{"connection_init", "subscribe"} {
+			data, err := ws.readMessage()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var msg wsMessage
+			json.Unmarshal(data, &msg)
+			if msg.Type != want {
+				t.Errorf("got message %q, want %q", msg.Type, want)
+				return
+			}
+			if want == "connection_init" {
+				ws.writeMessage([]byte(`{"type":"connection_ack"}`))
+			}
+		}
+		ws.writeMessage([]byte(`{"id":"1","type":"next","payload":{"data":{"count":1}}}`))
+		ws.writeMessage([]byte(`{"type":"ping"}`))
+		ws.writeMessage([]byte(`{"id":"1","type":"next","payload":{"data":{"count":2}}}`))
+		ws.writeMessage([]byte(`{"id":"1","type":"next","payload":{"errors":[{"message":"boom","path":["count"]}]}}`))
+		ws.writeMessage([]byte(`{"id":"1","type":"complete"}`))
+		ws.readMessage() // pong
+		if data, err := ws.readMessage(); err != io.EOF {
+			t.Errorf("got message %s and error %v after completing the subscription, want the connection closed", data, err)
+		}
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+
+	sub, err := c.Subscribe(context.Background(), `subscription { count }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	for _, want := range []int{1, 2} {
+		var out struct{ Count int }
+		if err := sub.Next(&out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Count != want {
+			t.Errorf("got count %d, want %d", out.Count, want)
+		}
+	}
+	if errs, ok := sub.Next(nil).(Errors); !ok || errs[0].Message != "boom" {
+		t.Errorf("got %v, want error boom", errs)
+	}
+	if err := sub.Next(nil); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+	<-served
+}
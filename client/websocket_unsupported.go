@@ -0,0 +1,35 @@
+//go:build js || wasip1
+// +build js wasip1
+
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"runtime"
+
+	perrors "github.com/pkg/errors"
+)
+
+// WebAssembly has no sockets to run websockets over, so that subscriptions are not supported.
+
+type wsConn struct {
+	conn net.Conn
+}
+
+func dialWebsocket(ctx context.Context, u string, protocol string, header http.Header) (*wsConn, error) {
+	return nil, perrors.Errorf("client: subscriptions are not supported on %s", runtime.GOOS)
+}
+
+func (c *wsConn) writeMessage(data []byte) error {
+	return perrors.Errorf("client: subscriptions are not supported on %s", runtime.GOOS)
+}
+
+func (c *wsConn) readMessage() ([]byte, error) {
+	return nil, perrors.Errorf("client: subscriptions are not supported on %s", runtime.GOOS)
+}
+
+func (c *wsConn) close() error {
+	return nil
+}
@@ -0,0 +1,71 @@
+// Command graphql-codegen generates Go declarations for a GraphQL schema, or a typed client for
+// the operations of a document if -operations is given, see package codegen.
+//
+// Usage:
+//
+//	graphql-codegen -schema schema.graphql -package schema -out schema_gen.go
+//	graphql-codegen -schema schema.graphql -operations queries.graphql -package client -out client_gen.go
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/qdentity/graphql-go/codegen"
+)
+
+func main() {
+	schemaFile := flag.String("schema", "", "file containing the GraphQL schema (required)")
+	operationsFile := flag.String("operations", "", "file containing GraphQL operations to generate a client for")
+	pkg := flag.String("package", "", "name of the generated package (default \"schema\", or \"client\" with -operations)")
+	out := flag.String("out", "", "output file (default stdout)")
+	scalars := flag.String("scalars", "", "comma separated custom scalar mappings, e.g. Time=graphql.Time,JSON=JSON")
+	flag.Parse()
+
+	if *schemaFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	sdl, err := ioutil.ReadFile(*schemaFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := codegen.Options{Package: *pkg, Scalars: make(map[string]string)}
+	for _, m := range strings.Split(*scalars, ",") {
+		if m == "" {
+			continue
+		}
+		i := strings.IndexByte(m, '=')
+		if i == -1 {
+			log.Fatalf("invalid scalar mapping %q", m)
+		}
+		opts.Scalars[m[:i]] = m[i+1:]
+	}
+
+	var src []byte
+	if *operationsFile != "" {
+		ops, readErr := ioutil.ReadFile(*operationsFile)
+		if readErr != nil {
+			log.Fatal(readErr)
+		}
+		src, err = codegen.GenerateClient(string(sdl), string(ops), opts)
+	} else {
+		src, err = codegen.Generate(string(sdl), opts)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,73 @@
+// Command graphql-sdlfmt formats GraphQL schema files, see package sdlfmt. Without files it formats
+// the standard input.
+//
+// Usage:
+//
+//	graphql-sdlfmt [-l] [-w] [file ...]
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/qdentity/graphql-go/sdlfmt"
+)
+
+func main() {
+	list := flag.Bool("l", false, "list files whose formatting differs from sdlfmt's")
+	write := flag.Bool("w", false, "write the result to the file instead of stdout")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			log.Fatal("cannot use -w with standard input")
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := format("<standard input>", src, *list, false); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	failed := false
+	for _, file := range flag.Args() {
+		src, err := ioutil.ReadFile(file)
+		if err == nil {
+			err = format(file, src, *list, *write)
+		}
+		if err != nil {
+			log.Print(err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func format(file string, src []byte, list, write bool) error {
+	res, err := sdlfmt.Format(src)
+	if err != nil {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+	if list && !bytes.Equal(src, res) {
+		fmt.Println(file)
+	}
+	if write {
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		return ioutil.WriteFile(file, res, 0644)
+	}
+	if !list {
+		os.Stdout.Write(res)
+	}
+	return nil
+}
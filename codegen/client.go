@@ -0,0 +1,350 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+	"github.com/qdentity/graphql-go/internal/validation"
+)
+
+var clientScalars = map[string]string{
+	"Int":     "int32",
+	"Float":   "float64",
+	"String":  "string",
+	"Boolean": "bool",
+	"ID":      "string",
+}
+
+type clientGenerator struct {
+	opts     Options
+	schema   *schema.Schema
+	doc      *query.Document
+	buf      bytes.Buffer
+	enums    map[string]*schema.Enum
+	inputs   map[string]*schema.InputObject
+	usesJSON bool
+}
+
+// GenerateClient generates a typed client for the named operations of the document, which get
+// validated against the schema. For every operation it emits the operation's source, a struct for
+// its variables, a struct for the data of its response and a function executing it. Custom scalars
+// that are not mapped in opts.Scalars are decoded as json.RawMessage.
+func GenerateClient(schemaString string, operations string, opts Options) ([]byte, error) {
+	s := schema.New()
+	if err := s.Parse(schemaString); err != nil {
+		return nil, err
+	}
+	doc, qErr := query.Parse(operations)
+	if qErr != nil {
+		return nil, qErr
+	}
+	if errs := validation.Validate(s, doc, nil); len(errs) != 0 {
+		return nil, errs[0]
+	}
+	if opts.Package == "" {
+		opts.Package = "client"
+	}
+
+	g := &clientGenerator{
+		opts:   opts,
+		schema: s,
+		doc:    doc,
+		enums:  make(map[string]*schema.Enum),
+		inputs: make(map[string]*schema.InputObject),
+	}
+	for _, op := range doc.Operations {
+		if op.Name.Name == "" {
+			return nil, perrors.Errorf("codegen: operations need a name to generate a client")
+		}
+		if err := g.genOperation(op); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by graphql-go codegen. DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+	out.WriteString("import (\n\t\"context\"\n")
+	if g.usesJSON {
+		out.WriteString("\t\"encoding/json\"\n")
+	}
+	out.WriteString(")\n\n")
+	out.WriteString("// Executor executes a GraphQL query with the given variables and decodes the data of the\n")
+	out.WriteString("// response into out. It is implemented by *client.Client of github.com/qdentity/graphql-go/client.\n")
+	out.WriteString("type Executor interface {\n")
+	out.WriteString("Execute(ctx context.Context, query string, variables interface{}, out interface{}) error\n")
+	out.WriteString("}\n\n")
+	g.genTypes(&out)
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, perrors.Errorf("codegen: invalid source generated: %s", err)
+	}
+	return src, nil
+}
+
+func (g *clientGenerator) printf(format string, a ...interface{}) {
+	fmt.Fprintf(&g.buf, format, a...)
+}
+
+func (g *clientGenerator) genOperation(op *query.Operation) error {
+	name := exportName(op.Name.Name)
+
+	g.printf("// %sQuery is the source of the operation %q.\n", name, op.Name.Name)
+	g.printf("const %sQuery = %s\n\n", name, goRawString(query.PrintOperation(g.doc, op)))
+
+	if len(op.Vars) > 0 {
+		g.printf("// %sVariables are the variables of the operation %q.\n", name, op.Name.Name)
+		g.printf("type %sVariables struct {\n", name)
+		for _, v := range op.Vars {
+			t, err := common.ResolveType(v.Type, g.schema.Resolve)
+			if err != nil {
+				return err
+			}
+			g.printf("%s %s %s\n", exportName(v.Name.Name), g.inputType(t), jsonTag(v.Name.Name, t))
+		}
+		g.printf("}\n\n")
+	}
+
+	var root schema.NamedType
+	switch op.Type {
+	case query.Query:
+		root = g.schema.EntryPoints["query"]
+	case query.Mutation:
+		root = g.schema.EntryPoints["mutation"]
+	case query.Subscription:
+		root = g.schema.EntryPoints["subscription"]
+	}
+	fields := g.collect(op.Selections, root, false, nil)
+
+	g.printf("// %sResponse is the data of the response of the operation %q.\n", name, op.Name.Name)
+	g.printf("type %sResponse ", name)
+	g.printStruct(fields)
+	g.printf("\n\n")
+
+	g.printf("// %s executes the operation %q.\n", name, op.Name.Name)
+	if len(op.Vars) > 0 {
+		g.printf("func %s(ctx context.Context, e Executor, vars %sVariables) (*%sResponse, error) {\n", name, name, name)
+	} else {
+		g.printf("func %s(ctx context.Context, e Executor) (*%sResponse, error) {\n", name, name)
+	}
+	g.printf("var resp %sResponse\n", name)
+	if len(op.Vars) > 0 {
+		g.printf("if err := e.Execute(ctx, %sQuery, vars, &resp); err != nil {\n", name)
+	} else {
+		g.printf("if err := e.Execute(ctx, %sQuery, nil, &resp); err != nil {\n", name)
+	}
+	g.printf("return nil, err\n}\nreturn &resp, nil\n}\n\n")
+	return nil
+}
+
+// selectedField is a field of a response, with the fields selected on it merged from all
+// selections and fragments.
+type selectedField struct {
+	alias    string
+	typ      common.Type
+	fields   []*selectedField
+	optional bool // selected only in fragments that might not apply, so it might be missing
+}
+
+// collect merges the fields of the selections on type t into fields. The fields are optional if
+// the selections are in a fragment that might not apply to the object.
+func (g *clientGenerator) collect(sels []query.Selection, t schema.NamedType, optional bool, fields []*selectedField) []*selectedField {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			var typ common.Type
+			if sel.Name.Name == "__typename" {
+				typ = &common.NonNull{OfType: g.schema.Types["String"]}
+			} else {
+				typ = fieldsOf(t).Get(sel.Name.Name).Type
+			}
+
+			var f *selectedField
+			for _, existing := range fields {
+				if existing.alias == sel.Alias.Name {
+					f = existing
+				}
+			}
+			if f == nil {
+				f = &selectedField{alias: sel.Alias.Name, typ: typ, optional: optional}
+				fields = append(fields, f)
+			}
+			f.optional = f.optional && optional
+			if len(sel.Selections) > 0 {
+				f.fields = g.collect(sel.Selections, namedType(typ), false, f.fields)
+			}
+
+		case *query.InlineFragment:
+			fragType := t
+			if sel.On.Name != "" {
+				fragType = g.schema.Types[sel.On.Name]
+			}
+			fields = g.collect(sel.Selections, fragType, optional || !alwaysApplies(fragType, t), fields)
+
+		case *query.FragmentSpread:
+			frag := g.doc.Fragments.Get(sel.Name.Name)
+			fragType := g.schema.Types[frag.On.Name]
+			fields = g.collect(frag.Selections, fragType, optional || !alwaysApplies(fragType, t), fields)
+		}
+	}
+	return fields
+}
+
+// alwaysApplies reports whether a fragment on type cond applies to every object of type t. A
+// valid fragment in the selections of an object type always applies to it.
+func alwaysApplies(cond, t schema.NamedType) bool {
+	if _, ok := t.(*schema.Object); ok {
+		return true
+	}
+	return cond.TypeName() == t.TypeName()
+}
+
+func (g *clientGenerator) printStruct(fields []*selectedField) {
+	g.printf("struct {\n")
+	for _, f := range fields {
+		g.printf("%s ", exportName(f.alias))
+		typ := f.typ
+		if nn, ok := typ.(*common.NonNull); ok && f.optional {
+			typ = nn.OfType // a pointer, nil if the fragment did not apply
+		}
+		g.outputType(typ, f.fields)
+		g.printf(" `json:%q`\n", f.alias)
+	}
+	g.printf("}")
+}
+
+func (g *clientGenerator) outputType(t common.Type, fields []*selectedField) {
+	nn, ok := t.(*common.NonNull)
+	if ok {
+		t = nn.OfType
+	} else {
+		g.printf("*")
+	}
+	switch t := t.(type) {
+	case *common.List:
+		g.printf("[]")
+		g.outputType(t.OfType, fields)
+	case *schema.Object, *schema.Interface, *schema.Union:
+		g.printStruct(fields)
+	case *schema.Enum:
+		g.enums[t.Name] = t
+		g.printf("%s", t.Name)
+	case *schema.Scalar:
+		g.printf("%s", g.scalarType(t))
+	}
+}
+
+func (g *clientGenerator) inputType(t common.Type) string {
+	prefix := "*"
+	if nn, ok := t.(*common.NonNull); ok {
+		t = nn.OfType
+		prefix = ""
+	}
+	switch t := t.(type) {
+	case *common.List:
+		return prefix + "[]" + g.inputType(t.OfType)
+	case *schema.InputObject:
+		if _, ok := g.inputs[t.Name]; !ok {
+			g.inputs[t.Name] = t
+			for _, v := range t.Values {
+				g.inputType(v.Type) // collect nested types
+			}
+		}
+		return prefix + t.Name
+	case *schema.Enum:
+		g.enums[t.Name] = t
+		return prefix + t.Name
+	case *schema.Scalar:
+		return prefix + g.scalarType(t)
+	default:
+		panic("unreachable")
+	}
+}
+
+func (g *clientGenerator) scalarType(t *schema.Scalar) string {
+	if goType, ok := g.opts.Scalars[t.Name]; ok {
+		return goType
+	}
+	if goType, ok := clientScalars[t.Name]; ok {
+		return goType
+	}
+	g.usesJSON = true
+	return "json.RawMessage"
+}
+
+// genTypes emits the enums and input objects used by the operations.
+func (g *clientGenerator) genTypes(out *bytes.Buffer) {
+	var names []string
+	for name := range g.enums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "// %s is the GraphQL enum type %q.\n", name, name)
+		fmt.Fprintf(out, "type %s string\n\nconst (\n", name)
+		for _, v := range g.enums[name].Values {
+			fmt.Fprintf(out, "%s%s %s = %q\n", name, enumValueName(v.Name), name, v.Name)
+		}
+		out.WriteString(")\n\n")
+	}
+
+	names = names[:0]
+	for name := range g.inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "// %s is the GraphQL input object type %q.\n", name, name)
+		fmt.Fprintf(out, "type %s struct {\n", name)
+		for _, v := range g.inputs[name].Values {
+			fmt.Fprintf(out, "%s %s %s\n", exportName(v.Name.Name), g.inputType(v.Type), jsonTag(v.Name.Name, v.Type))
+		}
+		out.WriteString("}\n\n")
+	}
+}
+
+func jsonTag(name string, t common.Type) string {
+	if _, ok := t.(*common.NonNull); ok {
+		return fmt.Sprintf("`json:%q`", name)
+	}
+	return fmt.Sprintf("`json:%q`", name+",omitempty")
+}
+
+func fieldsOf(t schema.NamedType) schema.FieldList {
+	switch t := t.(type) {
+	case *schema.Object:
+		return t.Fields
+	case *schema.Interface:
+		return t.Fields
+	default:
+		return nil
+	}
+}
+
+func namedType(t common.Type) schema.NamedType {
+	for {
+		switch t2 := t.(type) {
+		case *common.List:
+			t = t2.OfType
+		case *common.NonNull:
+			t = t2.OfType
+		default:
+			return t.(schema.NamedType)
+		}
+	}
+}
+
+func goRawString(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return fmt.Sprintf("%q", s)
+}
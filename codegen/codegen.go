@@ -0,0 +1,248 @@
+// Package codegen generates Go declarations from a GraphQL schema: a struct for every input object
+// type and every set of field arguments, constants for every enum and an interface for the
+// resolver of every object, interface and union type. Asserting that the resolvers implement these
+// interfaces makes the compiler check that they are in sync with the schema.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// Options configures the generated code.
+type Options struct {
+	// Package is the name of the generated package. It defaults to "schema".
+	Package string
+
+	// Scalars maps the names of custom scalars to Go types, e.g. "Time" to "graphql.Time". Types
+	// qualified with "graphql." are imported from this library. Unmapped custom scalars are
+	// referred to by their name and have to be declared in the generated package.
+	Scalars map[string]string
+}
+
+var builtinScalars = map[string]string{
+	"Int":     "int32",
+	"Float":   "float64",
+	"String":  "string",
+	"Boolean": "bool",
+	"ID":      "graphql.ID",
+	"Time":    "graphql.Time",
+}
+
+type generator struct {
+	opts        Options
+	buf         bytes.Buffer
+	usesGraphQL bool
+	usesContext bool
+}
+
+// Generate parses the schema and returns the formatted Go source of its declarations.
+func Generate(schemaString string, opts Options) ([]byte, error) {
+	s := schema.New()
+	if err := s.Parse(schemaString); err != nil {
+		return nil, err
+	}
+	if opts.Package == "" {
+		opts.Package = "schema"
+	}
+
+	g := &generator{opts: opts}
+	var names []string
+	for name := range s.Types {
+		if _, ok := schema.Meta.Types[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		switch t := s.Types[name].(type) {
+		case *schema.Enum:
+			g.genEnum(t)
+		case *schema.InputObject:
+			g.genInputObject(t)
+		case *schema.Object:
+			g.genResolver(t.Name, t.Desc, t.Fields, nil)
+		case *schema.Interface:
+			g.genResolver(t.Name, t.Desc, t.Fields, t.PossibleTypes)
+		case *schema.Union:
+			g.genResolver(t.Name, t.Desc, nil, t.PossibleTypes)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by graphql-go codegen. DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+	if g.usesContext || g.usesGraphQL {
+		out.WriteString("import (\n")
+		if g.usesContext {
+			out.WriteString("\t\"context\"\n")
+		}
+		if g.usesGraphQL {
+			out.WriteString("\n\tgraphql \"github.com/qdentity/graphql-go\"\n")
+		}
+		out.WriteString(")\n\n")
+	}
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, perrors.Errorf("codegen: invalid source generated: %s", err)
+	}
+	return src, nil
+}
+
+func (g *generator) printf(format string, a ...interface{}) {
+	fmt.Fprintf(&g.buf, format, a...)
+}
+
+func (g *generator) doc(desc, fallback string) {
+	if desc == "" {
+		desc = fallback
+	}
+	for _, line := range strings.Split(desc, "\n") {
+		g.printf("// %s\n", line)
+	}
+}
+
+func (g *generator) genEnum(t *schema.Enum) {
+	g.doc(t.Desc, fmt.Sprintf("%s is the GraphQL enum type %q.", t.Name, t.Name))
+	g.printf("type %s string\n\n", t.Name)
+	g.printf("const (\n")
+	for _, v := range t.Values {
+		if v.Desc != "" {
+			g.doc(v.Desc, "")
+		}
+		g.printf("%s%s %s = %q\n", t.Name, enumValueName(v.Name), t.Name, v.Name)
+	}
+	g.printf(")\n\n")
+}
+
+func (g *generator) genInputObject(t *schema.InputObject) {
+	g.doc(t.Desc, fmt.Sprintf("%s is the GraphQL input object type %q.", t.Name, t.Name))
+	g.genStruct(t.Name, t.Values)
+}
+
+func (g *generator) genStruct(name string, values common.InputValueList) {
+	g.printf("type %s struct {\n", name)
+	for _, v := range values {
+		if v.Desc != "" {
+			g.doc(v.Desc, "")
+		}
+		g.printf("%s %s\n", exportName(v.Name.Name), g.inputType(v.Type))
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) genResolver(typeName, desc string, fields schema.FieldList, possibleTypes []*schema.Object) {
+	for _, f := range fields {
+		if len(f.Args) > 0 {
+			g.printf("// %s are the arguments of the field %q of %q.\n", argsName(typeName, f), f.Name, typeName)
+			g.genStruct(argsName(typeName, f), f.Args)
+		}
+	}
+
+	g.doc(desc, fmt.Sprintf("%sResolver resolves the GraphQL type %q.", typeName, typeName))
+	g.printf("type %sResolver interface {\n", typeName)
+	for _, f := range fields {
+		if f.Desc != "" {
+			g.doc(f.Desc, "")
+		}
+		g.usesContext = true
+		params := "ctx context.Context"
+		if len(f.Args) > 0 {
+			params += ", args " + argsName(typeName, f)
+		}
+		g.printf("%s(%s) (%s, error)\n", exportName(f.Name), params, g.outputType(f.Type))
+	}
+	for _, impl := range possibleTypes {
+		g.printf("To%s() (%sResolver, bool)\n", impl.Name, impl.Name)
+	}
+	g.printf("}\n\n")
+}
+
+// inputType returns the Go type used for packing a value of the GraphQL input type.
+func (g *generator) inputType(t common.Type) string {
+	if nn, ok := t.(*common.NonNull); ok {
+		return g.baseType(nn.OfType, g.inputType)
+	}
+	return "*" + g.baseType(t, g.inputType)
+}
+
+// outputType returns the Go type of a resolver result of the GraphQL output type.
+func (g *generator) outputType(t common.Type) string {
+	if nn, ok := t.(*common.NonNull); ok {
+		return g.baseType(nn.OfType, g.outputType)
+	}
+	switch t.(type) {
+	case *schema.Object, *schema.Interface, *schema.Union:
+		return g.baseType(t, g.outputType) // interfaces are nilable
+	}
+	return "*" + g.baseType(t, g.outputType)
+}
+
+func (g *generator) baseType(t common.Type, elemType func(common.Type) string) string {
+	switch t := t.(type) {
+	case *common.List:
+		return "[]" + elemType(t.OfType)
+	case *schema.Object, *schema.Interface, *schema.Union:
+		return t.String() + "Resolver"
+	case *schema.Scalar:
+		goType, ok := g.opts.Scalars[t.Name]
+		if !ok {
+			goType, ok = builtinScalars[t.Name]
+		}
+		if !ok {
+			goType = t.Name
+		}
+		if strings.HasPrefix(goType, "graphql.") {
+			g.usesGraphQL = true
+		}
+		return goType
+	default:
+		return t.String()
+	}
+}
+
+func argsName(typeName string, f *schema.Field) string {
+	return typeName + exportName(f.Name) + "Args"
+}
+
+// exportName turns a GraphQL name into an exported Go identifier, e.g. "say_hello" into "SayHello"
+// and "userId" into "UserID". Resolver methods and struct fields match names regardless of case and
+// underscores, so the result still binds to the schema.
+func exportName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "Id" || strings.HasSuffix(s, "Id") {
+		s = s[:len(s)-2] + "ID"
+	}
+	return s
+}
+
+// enumValueName turns an enum value like "NEW_HOPE" into "NewHope".
+func enumValueName(value string) string {
+	if strings.ToUpper(value) == value {
+		value = strings.ToLower(value)
+	}
+	return exportName(value)
+}
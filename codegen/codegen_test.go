@@ -0,0 +1,98 @@
+package codegen_test
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/qdentity/graphql-go/codegen"
+	"github.com/qdentity/graphql-go/example/starwars"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := codegen.Generate(starwars.Schema, codegen.Options{Package: "starwars"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "starwars_gen.go", src, 0); err != nil {
+		t.Fatalf("generated invalid Go source: %s\n%s", err, src)
+	}
+
+	for _, want := range []string{
+		`EpisodeNewhope Episode = "NEWHOPE"`,
+		"\tStars int32\n",
+		"\tCommentary *string\n",
+		"type QueryHeroArgs struct {\n\tEpisode *Episode\n}",
+		"Hero(ctx context.Context, args QueryHeroArgs) (CharacterResolver, error)",
+		"Reviews(ctx context.Context, args QueryReviewsArgs) ([]ReviewResolver, error)",
+		"AppearsIn(ctx context.Context) ([]Episode, error)",
+		"ToHuman() (HumanResolver, bool)",
+		"ID(ctx context.Context) (graphql.ID, error)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("generated source:\n%s", src)
+	}
+}
+
+func TestGenerateClient(t *testing.T) {
+	src, err := codegen.GenerateClient(starwars.Schema, `
+		query HeroFriends($episode: Episode, $first: Int) {
+			hero(episode: $episode) {
+				...CharacterName
+				... on Human {
+					id
+					height
+				}
+				id
+				friendsConnection(first: $first) {
+					totalCount
+				}
+			}
+		}
+
+		mutation CreateReview($episode: Episode!, $review: ReviewInput!) {
+			createReview(episode: $episode, review: $review) {
+				stars
+			}
+		}
+
+		fragment CharacterName on Character {
+			__typename
+			name
+		}
+	`, codegen.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "client_gen.go", src, 0); err != nil {
+		t.Fatalf("generated invalid Go source: %s\n%s", err, src)
+	}
+
+	// struct fields are aligned by gofmt, so compare with runs of whitespace collapsed
+	collapsed := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{
+		"type HeroFriendsVariables struct {",
+		"Episode *Episode `json:\"episode,omitempty\"`",
+		"Typename string `json:\"__typename\"`",
+		"TotalCount int32 `json:\"totalCount\"`",
+		"Height *float64 `json:\"height\"`",
+		"ID string `json:\"id\"`",
+		"Review ReviewInput `json:\"review\"`",
+		"func HeroFriends(ctx context.Context, e Executor, vars HeroFriendsVariables) (*HeroFriendsResponse, error) {",
+		"fragment CharacterName on Character {",
+	} {
+		if !strings.Contains(collapsed, want) {
+			t.Errorf("generated source does not contain %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("generated source:\n%s", src)
+	}
+}
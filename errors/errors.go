@@ -2,6 +2,7 @@ package errors
 
 import (
 	"fmt"
+	"strings"
 )
 
 type QueryError struct {
@@ -12,8 +13,27 @@ type QueryError struct {
 	Rule          string                 `json:"-"`
 	OriginalError error                  `json:"-"`
 	PanicValue    interface{}            `json:"-"`
+	PanicStack    []byte                 `json:"-"`
 }
 
+// The codes of the errors of the engine, set as their "code" extension. They follow the conventions
+// of Apollo Server, so that clients can tell the errors of the request from those of resolvers.
+const (
+	// CodeParseFailed is the code of syntax errors of the query.
+	CodeParseFailed = "GRAPHQL_PARSE_FAILED"
+
+	// CodeValidationFailed is the code of queries that are invalid for the schema or exceed its
+	// limits, e.g. its maximum depth.
+	CodeValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+
+	// CodeBadUserInput is the code of invalid values of variables and arguments and of unknown
+	// operations.
+	CodeBadUserInput = "BAD_USER_INPUT"
+
+	// CodeInternal is the code of the errors of panics recovered during execution.
+	CodeInternal = "INTERNAL_SERVER_ERROR"
+)
+
 type Location struct {
 	Line   int `json:"line"`
 	Column int `json:"column"`
@@ -29,6 +49,18 @@ func Errorf(format string, a ...interface{}) *QueryError {
 	}
 }
 
+// WithCode sets the "code" extension of the error, unless it has one, and returns the error.
+func (err *QueryError) WithCode(code string) *QueryError {
+	if _, ok := err.Extensions["code"]; ok {
+		return err
+	}
+	if err.Extensions == nil {
+		err.Extensions = make(map[string]interface{})
+	}
+	err.Extensions["code"] = code
+	return err
+}
+
 func (err *QueryError) Error() string {
 	if err == nil {
 		return "<nil>"
@@ -40,4 +72,41 @@ func (err *QueryError) Error() string {
 	return str
 }
 
+// Unwrap returns the original error, e.g. the error of a resolver or context.DeadlineExceeded, so
+// that errors.Is and errors.As see through query errors.
+func (err *QueryError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.OriginalError
+}
+
 var _ error = &QueryError{}
+
+// Join returns an error wrapping the errors, e.g. those of a response, so that errors.Is and
+// errors.As find the original errors of any of them. It returns nil if there are none.
+func Join(errs []*QueryError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return joinedErrors(errs)
+}
+
+type joinedErrors []*QueryError
+
+func (errs joinedErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the joined errors.
+func (errs joinedErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
@@ -0,0 +1,29 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUnwrap(t *testing.T) {
+	err := Errorf("field timed out")
+	err.OriginalError = context.DeadlineExceeded
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("%v does not wrap its original error", err)
+	}
+	if errors.Unwrap(Errorf("no original error")) != nil {
+		t.Error("got an original error for an error without one")
+	}
+
+	joined := Join([]*QueryError{Errorf("first"), err})
+	if !errors.Is(joined, context.DeadlineExceeded) {
+		t.Errorf("%v does not wrap the original error of its second error", joined)
+	}
+	if want := "graphql: first\ngraphql: field timed out"; joined.Error() != want {
+		t.Errorf("got message %q, want %q", joined.Error(), want)
+	}
+	if Join(nil) != nil {
+		t.Error("got an error for no errors")
+	}
+}
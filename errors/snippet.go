@@ -0,0 +1,73 @@
+package errors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Snippet renders the line of the source at the location together with the lines around it and a
+// caret pointing at the column, e.g.
+//
+//	1 | {
+//	2 |   hero { unknown }
+//	  |          ^
+//	3 | }
+//
+// It returns an empty string if the location is not within the source.
+func Snippet(source string, loc Location) string {
+	lines := strings.Split(source, "\n")
+	if loc.Line < 1 || loc.Line > len(lines) || loc.Column < 1 {
+		return ""
+	}
+
+	first, last := loc.Line-1, loc.Line+1
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		line := strings.TrimRight(lines[n-1], "\r")
+		fmt.Fprintf(&b, "%*d | %s\n", width, n, line)
+		if n != loc.Line {
+			continue
+		}
+		// keep tabs before the caret so that it lines up with the column
+		var indent strings.Builder
+		for i, r := range []rune(line) {
+			if i >= loc.Column-1 {
+				break
+			}
+			if r == '\t' {
+				indent.WriteRune('\t')
+			} else {
+				indent.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(&b, "%*s | %s^\n", width, "", indent.String())
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// AddSnippets sets the "snippet" extension of the errors with locations to the Snippet of the
+// source at their first location.
+func AddSnippets(source string, errs []*QueryError) {
+	for _, err := range errs {
+		if len(err.Locations) == 0 {
+			continue
+		}
+		snippet := Snippet(source, err.Locations[0])
+		if snippet == "" {
+			continue
+		}
+		if err.Extensions == nil {
+			err.Extensions = make(map[string]interface{})
+		}
+		err.Extensions["snippet"] = snippet
+	}
+}
@@ -0,0 +1,37 @@
+package errors
+
+import "testing"
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		source string
+		loc    Location
+		want   string
+	}{
+		{
+			source: "{ hero { unknown } }",
+			loc:    Location{Line: 1, Column: 10},
+			want:   "1 | { hero { unknown } }\n  |          ^",
+		},
+		{
+			source: "query {\n\thero {\n\t\tunknown\n\t}\n}",
+			loc:    Location{Line: 3, Column: 3},
+			want:   "2 | \thero {\n3 | \t\tunknown\n  | \t\t^\n4 | \t}",
+		},
+		{
+			source: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj",
+			loc:    Location{Line: 9, Column: 1},
+			want:   " 8 | h\n 9 | i\n   | ^\n10 | j",
+		},
+		{
+			source: "{ hero }",
+			loc:    Location{Line: 2, Column: 1},
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		if got := Snippet(tt.source, tt.loc); got != tt.want {
+			t.Errorf("got snippet\n%s\nwant\n%s", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/exec/selected"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/validation"
+)
+
+// Plan describes how an operation would be executed, see Schema.Explain.
+type Plan struct {
+	// Operation is the type of the operation, "query" or "mutation".
+	Operation string `json:"operation"`
+
+	// Name is the name of the operation.
+	Name string `json:"name,omitempty"`
+
+	// Parallel reports whether the root fields are resolved concurrently. The root fields of
+	// mutations are resolved one after another.
+	Parallel bool `json:"parallel"`
+
+	// Fields are the root fields in the order of the response.
+	Fields []*PlanField `json:"fields"`
+
+	// Cost is the estimated cost of the operation, the sum of the costs of the root fields.
+	Cost int `json:"cost"`
+}
+
+// PlanField is a field of a Plan.
+type PlanField struct {
+	Alias      string `json:"alias"`
+	Name       string `json:"name"`
+	ParentType string `json:"parentType"`
+	Type       string `json:"type"`
+
+	// TypeCondition is the type the parent value has to be of for the field to be resolved, if the
+	// field was selected by a fragment on a type of an abstract parent.
+	TypeCondition string `json:"typeCondition,omitempty"`
+
+	// Args are the coerced arguments of the field.
+	Args map[string]interface{} `json:"args,omitempty"`
+
+	// Resolver is "method" if a resolver method is called, "field" if the value is read from a
+	// struct field, "delegate" if the field is sent to an upstream service and "fixed" if the value
+	// is known without calling a resolver, e.g. for __typename and introspection.
+	Resolver string `json:"resolver"`
+
+	// Async reports whether the resolver may block, e.g. because it takes a context. A selection
+	// set with an async field resolves all of its fields concurrently.
+	Async bool `json:"async"`
+
+	// Parallel reports whether the fields of the field's value are resolved concurrently.
+	Parallel bool `json:"parallel"`
+
+	// Weight is the estimated cost of the resolver, set with the @cost(weight: Int!) directive in
+	// the schema. It defaults to 1, introspection fields are free.
+	Weight int `json:"weight"`
+
+	// Cost is the weight of the field plus the costs of its fields.
+	Cost int `json:"cost"`
+
+	// Directives are the directives applied to the field, both in the schema and in the query.
+	Directives []*PlanDirective `json:"directives,omitempty"`
+
+	Fields []*PlanField `json:"fields,omitempty"`
+}
+
+// PlanDirective is a directive applied to a PlanField.
+type PlanDirective struct {
+	Name string `json:"name"`
+
+	// Location is "FIELD_DEFINITION" for directives of the schema and "FIELD" for directives of
+	// the query.
+	Location string `json:"location"`
+
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// Explain returns the execution plan of the operation without calling any resolvers: the fields
+// that would be resolved, which of them are resolved concurrently, their estimated costs and the
+// directives applied to them. Fields skipped with @skip or @include are left out. It panics if the
+// schema was created without a resolver.
+func (s *Schema) Explain(ctx context.Context, queryString string, operationName string, variables map[string]interface{}) (*Plan, []*errors.QueryError) {
+	if s.res == nil {
+		panic("schema created without resolver, can not explain")
+	}
+
+	doc, qErr := s.parseQuery(queryString)
+	if qErr != nil {
+		return nil, s.queryErrors(queryString, []*errors.QueryError{qErr})
+	}
+
+	visible := s.visibleFunc(ctx)
+	if errs, _ := s.validate(doc, visible); len(errs) != 0 {
+		return nil, s.queryErrors(queryString, errs)
+	}
+
+	op, err := getOperation(doc, operationName)
+	if err != nil {
+		return nil, []*errors.QueryError{errors.Errorf("%s", err).WithCode(errors.CodeBadUserInput)}
+	}
+
+	if errs := validation.ValidateVariableDepth(op, variables, s.limits); len(errs) != 0 {
+		return nil, s.queryErrors(queryString, withCode(errs, errors.CodeBadUserInput))
+	}
+	variables = withVariableDefaults(op, variables)
+	if errs := validation.ValidateVariables(s.schema, op, variables); len(errs) != 0 {
+		return nil, s.queryErrors(queryString, withCode(errs, errors.CodeBadUserInput))
+	}
+	r := &selected.Request{
+		Doc:     doc,
+		Vars:    variables,
+		Schema:  s.schema,
+		Visible: visible,
+	}
+	sels := selected.ApplyOperation(r, s.res, op)
+	if len(r.Errs) != 0 {
+		return nil, r.Errs
+	}
+
+	plan := &Plan{
+		Operation: strings.ToLower(string(op.Type)),
+		Name:      op.Name.Name,
+		Parallel:  op.Type != query.Mutation && selected.HasAsyncSel(sels),
+		Fields:    planFields(sels, variables, ""),
+	}
+	for _, f := range plan.Fields {
+		plan.Cost += f.Cost
+	}
+	return plan, nil
+}
+
+func planFields(sels []selected.Selection, variables map[string]interface{}, typeCondition string) []*PlanField {
+	var fields []*PlanField
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *selected.SchemaField:
+			f := &PlanField{
+				Alias:         sel.Alias,
+				Name:          sel.Name,
+				ParentType:    sel.TypeName,
+				Type:          sel.Type.String(),
+				TypeCondition: typeCondition,
+				Args:          sel.Args,
+				Resolver:      "method",
+				Async:         sel.Async,
+				Parallel:      selected.HasAsyncSel(sel.Sels),
+				Weight:        sel.Cost,
+				Fields:        planFields(sel.Sels, variables, ""),
+			}
+			if sel.FixedResult.IsValid() {
+				f.Resolver = "fixed"
+			}
+			if sel.Delegate != nil {
+				f.Resolver = "delegate"
+			}
+			if sel.FieldIndex != nil {
+				f.Resolver = "field"
+			}
+			f.Directives = append(planDirectives(sel.Field.Directives, "FIELD_DEFINITION", nil),
+				planDirectives(sel.QueryDirectives, "FIELD", variables)...)
+			f.Cost = f.Weight
+			for _, child := range f.Fields {
+				f.Cost += child.Cost
+			}
+			fields = append(fields, f)
+
+		case *selected.TypenameField:
+			fields = append(fields, &PlanField{
+				Alias:         sel.Alias,
+				Name:          "__typename",
+				ParentType:    sel.Name,
+				Type:          resolvable.MetaFieldTypename.Type.String(),
+				TypeCondition: typeCondition,
+				Resolver:      "fixed",
+			})
+
+		case *selected.TypeAssertion:
+			fields = append(fields, planFields(sel.Sels, variables, sel.TypeExec.(*resolvable.Object).Name)...)
+		}
+	}
+	return fields
+}
+
+func planDirectives(directives common.DirectiveList, location string, variables map[string]interface{}) []*PlanDirective {
+	var result []*PlanDirective
+	for _, d := range directives {
+		pd := &PlanDirective{Name: d.Name.Name, Location: location}
+		if len(d.Args) != 0 {
+			pd.Args = make(map[string]interface{}, len(d.Args))
+			for _, arg := range d.Args {
+				pd.Args[arg.Name.Name] = arg.Value.Value(variables)
+			}
+		}
+		result = append(result, pd)
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/qdentity/graphql-go/internal/exec"
+)
+
+// FieldInfo describes the field being resolved, see FieldContext.
+type FieldInfo struct {
+	// Path is the path of the field in the response, e.g. ["hero", "friends", 0, "name"].
+	Path []interface{}
+
+	// Alias is the name of the field in the response, Name the one in the schema and ParentType
+	// the type declaring it.
+	Alias      string
+	Name       string
+	ParentType string
+
+	// Args are the arguments of the field, with the variables and default values applied.
+	Args map[string]interface{}
+
+	// Directives are the directives applied to the field, the ones of its definition in the schema
+	// followed by the ones of the field in the query, e.g. to implement @cacheControl in a tracer.
+	Directives []*FieldDirective
+
+	// Cancel cancels the context of the field's resolver and of the resolvers of its subtree, e.g.
+	// to abort their expensive work when a cheap check fails. The resolvers of the subtree that
+	// have not been called yet are not called anymore, and the field is null with an error with
+	// the "code" extension CANCELLED.
+	Cancel func()
+
+	// Parent is the closest enclosing field with a field context, nil if there is none.
+	Parent *FieldInfo
+}
+
+// FieldDirective is a directive applied to a field, see FieldInfo.
+type FieldDirective struct {
+	Name string
+
+	// Location is "FIELD_DEFINITION" for directives of the schema and "FIELD" for directives of
+	// the query.
+	Location string
+
+	// Args are the arguments of the directive, with the variables and default values applied.
+	Args map[string]interface{}
+}
+
+// FieldContext returns the field whose resolver got ctx, or a context derived from it, nil if there
+// is none. Only resolvers taking a context get a field context; the context of any other resolver
+// is the one of the enclosing field. Tracers implementing trace.FieldContextTracer get it as well,
+// in the contexts passed to them for fields. The deadline of the field, e.g. of its @timeout directive, is
+// the one of ctx.
+func FieldContext(ctx context.Context) *FieldInfo {
+	return fieldInfo(exec.FieldContextFrom(ctx))
+}
+
+func fieldInfo(fc *exec.FieldContext) *FieldInfo {
+	if fc == nil {
+		return nil
+	}
+	return &FieldInfo{
+		Path:       fc.Path(),
+		Alias:      fc.Field.Alias,
+		Name:       fc.Field.Name,
+		ParentType: fc.Field.TypeName,
+		Args:       fc.Field.Args,
+		Directives: fieldDirectives(fc),
+		Cancel:     fc.Cancel,
+		Parent:     fieldInfo(fc.Parent),
+	}
+}
+
+func fieldDirectives(fc *exec.FieldContext) []*FieldDirective {
+	var result []*FieldDirective
+	for _, d := range fc.Directives() {
+		location := "FIELD_DEFINITION"
+		if d.Query {
+			location = "FIELD"
+		}
+		result = append(result, &FieldDirective{Name: d.Name, Location: location, Args: d.Args})
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+// Package gateway delegates fields of a schema to upstream GraphQL services over HTTP, so that a
+// server can expose other services through its schema without Go resolvers for their fields.
+//
+// The routing table maps root fields to the URLs of the services:
+//
+//	routes := gateway.Routes{
+//		"Query.products":   "http://catalog/graphql",
+//		"Query.orders":     "http://orders/graphql",
+//		"Mutation.order":   "http://orders/graphql",
+//	}
+//	schema := graphql.MustParseSchema(schemaString, &resolver{}, routes.SchemaOpts()...)
+//
+// Each delegated field is sent as a separate request with its arguments, selections and the
+// variables and fragments they use. The schemas of the services have to declare the fields with
+// the same types.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/client"
+	"github.com/qdentity/graphql-go/errors"
+)
+
+// Routes maps root fields given as "Query.field" or "Mutation.field" to the URLs of the GraphQL
+// services resolving them.
+type Routes map[string]string
+
+// SchemaOpts returns the options delegating the fields of the routes to their services. The
+// services are called by clients created with the options, one per URL.
+func (r Routes) SchemaOpts(opts ...client.Option) []graphql.SchemaOpt {
+	clients := make(map[string]*client.Client)
+	var schemaOpts []graphql.SchemaOpt
+	for field, url := range r {
+		c, ok := clients[url]
+		if !ok {
+			c = client.New(url, opts...)
+			clients[url] = c
+		}
+		schemaOpts = append(schemaOpts, graphql.DelegateField(field, Upstream(c)))
+	}
+	return schemaOpts
+}
+
+// Upstream returns a delegate executing queries with the client. Errors of the request itself are
+// reported with the code UPSTREAM_ERROR.
+func Upstream(c *client.Client) graphql.Delegate {
+	return func(ctx context.Context, query string, variables map[string]interface{}) (json.RawMessage, []*errors.QueryError) {
+		var data json.RawMessage
+		err := c.Execute(ctx, query, variables, &data)
+		if errs, ok := err.(client.Errors); ok {
+			return data, errs
+		}
+		if err != nil {
+			qErr := errors.Errorf("upstream service: %s", err)
+			qErr.OriginalError = err
+			qErr.Extensions = map[string]interface{}{"code": "UPSTREAM_ERROR"}
+			return nil, []*errors.QueryError{qErr}
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,135 @@
+package gateway_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/gateway"
+	"github.com/qdentity/graphql-go/gqltesting"
+	"github.com/qdentity/graphql-go/relay"
+)
+
+const productTypes = `
+	type Product {
+		id: ID!
+		name: String!
+		price: Float!
+	}
+`
+
+type catalogResolver struct{}
+
+func (r *catalogResolver) Product(args struct{ ID graphql.ID }) (*productResolver, error) {
+	if args.ID != "1" {
+		return nil, fmt.Errorf("product %s not found", args.ID)
+	}
+	return &productResolver{"1", "Lamp", 12.5}, nil
+}
+
+func (r *catalogResolver) Products(args struct{ First int32 }) []*productResolver {
+	return []*productResolver{{"1", "Lamp", 12.5}, {"2", "Chair", 40}}[:args.First]
+}
+
+type productResolver struct {
+	id    graphql.ID
+	name  string
+	price float64
+}
+
+func (r *productResolver) ID() graphql.ID { return r.id }
+func (r *productResolver) Name() string   { return r.name }
+func (r *productResolver) Price() float64 { return r.price }
+
+type gatewayResolver struct{}
+
+func (r *gatewayResolver) Hello() string {
+	return "Hello world!"
+}
+
+func TestRoutes(t *testing.T) {
+	catalog := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			product(id: ID!): Product
+			products(first: Int = 2): [Product!]!
+		}
+	`+productTypes, &catalogResolver{})
+	srv := httptest.NewServer(&relay.Handler{Schema: catalog})
+	defer srv.Close()
+
+	gatewaySchema := `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello: String!
+			product(id: ID!): Product
+			products(first: Int = 2): [Product!]!
+			unavailable: String
+		}
+	` + productTypes
+	routes := gateway.Routes{
+		"Query.product":     srv.URL,
+		"Query.products":    srv.URL,
+		"Query.unavailable": "http://127.0.0.1:1/graphql",
+	}
+	schema := graphql.MustParseSchema(gatewaySchema, &gatewayResolver{}, routes.SchemaOpts()...)
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				query($id: ID!, $first: Int) {
+					hello
+					p: product(id: $id) {
+						...Name
+						price
+					}
+					products(first: $first) {
+						id
+					}
+				}
+
+				fragment Name on Product {
+					name
+				}
+			`,
+			Variables: map[string]interface{}{"id": "1", "first": 1},
+			ExpectedResult: `
+				{
+					"hello": "Hello world!",
+					"p": {"name": "Lamp", "price": 12.5},
+					"products": [{"id": "1"}]
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query:  `{ product(id: "2") { name } }`,
+			ExpectedResult: `
+				{
+					"product": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{Message: "product 2 not found", Path: []interface{}{"product"}},
+			},
+		},
+	})
+
+	resp := schema.Exec(context.Background(), `{ hello unavailable }`, "", nil)
+	if string(resp.Data) != `{"hello":"Hello world!","unavailable":null}` {
+		t.Errorf("got data %s", resp.Data)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "UPSTREAM_ERROR" || len(resp.Errors[0].Path) != 1 {
+		t.Errorf("got errors %v, want one upstream error", resp.Errors)
+	}
+}
@@ -0,0 +1,34 @@
+package gqltesting
+
+import (
+	"strings"
+	"testing"
+
+	graphql "github.com/qdentity/graphql-go"
+)
+
+// Fuzz fuzzes the parsing and validation of documents against the schema, starting from the seed
+// queries. It is meant to be called by a fuzz target:
+//
+//	func FuzzQueries(f *testing.F) {
+//		gqltesting.Fuzz(f, schema, `{ hero(episode: JEDI) { name } }`)
+//	}
+//
+// A document fails the target if validating it panics, or if it is rejected with a syntax error
+// without a location within the document.
+func Fuzz(f *testing.F, schema *graphql.Schema, seeds ...string) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, query string) {
+		lines := strings.Count(query, "\n") + 1
+		for _, err := range schema.Validate(query) {
+			if !strings.HasPrefix(err.Message, "syntax error") {
+				continue
+			}
+			if len(err.Locations) != 1 || err.Locations[0].Line < 1 || err.Locations[0].Line > lines || err.Locations[0].Column < 1 {
+				t.Fatalf("got syntax error %q at %v for %q", err.Message, err.Locations, query)
+			}
+		}
+	})
+}
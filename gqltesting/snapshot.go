@@ -0,0 +1,192 @@
+package gqltesting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	perrors "github.com/pkg/errors"
+	graphql "github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/sdlfmt"
+)
+
+// snapshotSeparator separates the SDL of a snapshot from its introspection result.
+const snapshotSeparator = "\n# ---- introspection ----\n"
+
+// UpdateEnv is the environment variable which, set to a non-empty value, makes CompareSnapshot
+// write the snapshots instead of comparing them, e.g. GQLTESTING_UPDATE=1 go test ./...
+const UpdateEnv = "GQLTESTING_UPDATE"
+
+// Snapshot returns a canonical snapshot of the schema for golden tests: its definition formatted
+// by sdlfmt, followed by the result of the introspection query with the keys of objects sorted.
+func Snapshot(schema *graphql.Schema) ([]byte, error) {
+	sdl, err := sdlfmt.Format([]byte(schema.SDL()))
+	if err != nil {
+		return nil, err
+	}
+	data, err := schema.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+	introspection, err := canonicalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(sdl)
+	buf.WriteString(snapshotSeparator)
+	buf.Write(introspection)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// CompareSnapshot compares the snapshot of the schema with the golden file at path, see Snapshot,
+// and fails the test if they differ semantically, listing the differences. Types, fields,
+// arguments, enum values and directives may be ordered differently, and descriptions may differ in
+// whitespace. The file is written if it does not exist or UpdateEnv is set.
+func CompareSnapshot(t testing.TB, schema *graphql.Schema, path string) {
+	t.Helper()
+	snapshot, err := Snapshot(schema)
+	if err != nil {
+		t.Fatalf("snapshot of the schema: %s", err)
+	}
+	golden, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) || os.Getenv(UpdateEnv) != "" {
+		if err := ioutil.WriteFile(path, snapshot, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := snapshotIntrospection(golden)
+	if err != nil {
+		t.Fatalf("invalid snapshot %s: %s", path, err)
+	}
+	got, err := snapshotIntrospection(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diffs []string
+	diff("", normalize(want), normalize(got), &diffs)
+	if len(diffs) != 0 {
+		t.Errorf("schema differs from the snapshot %s, set %s=1 to update it:\n\t%s", path, UpdateEnv, strings.Join(diffs, "\n\t"))
+	}
+}
+
+func canonicalJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(v, "", "  ") // the keys of maps are sorted
+}
+
+// snapshotIntrospection returns the decoded introspection result of the snapshot.
+func snapshotIntrospection(snapshot []byte) (interface{}, error) {
+	i := bytes.Index(snapshot, []byte(snapshotSeparator))
+	if i == -1 {
+		return nil, perrors.New("no introspection result")
+	}
+	var v interface{}
+	if err := json.Unmarshal(snapshot[i+len(snapshotSeparator):], &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// normalize replaces the lists of named elements, e.g. fields, by maps keyed by name, so that their
+// order does not matter, and collapses the whitespace of descriptions.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if s, ok := value.(string); ok && key == "description" {
+				value = strings.Join(strings.Fields(s), " ")
+			}
+			m[key] = normalize(value)
+		}
+		return m
+	case []interface{}:
+		named := make(map[string]interface{}, len(v))
+		for _, elem := range v {
+			obj, ok := elem.(map[string]interface{})
+			if !ok {
+				return normalizeList(v)
+			}
+			name, ok := obj["name"].(string)
+			if !ok {
+				return normalizeList(v)
+			}
+			named[name] = normalize(elem)
+		}
+		return named
+	default:
+		return v
+	}
+}
+
+func normalizeList(v []interface{}) []interface{} {
+	l := make([]interface{}, len(v))
+	for i, elem := range v {
+		l[i] = normalize(elem)
+	}
+	return l
+}
+
+// diff appends the paths at which the values differ, with the values.
+func diff(path string, want, got interface{}, diffs *[]string) {
+	wantMap, ok1 := want.(map[string]interface{})
+	gotMap, ok2 := got.(map[string]interface{})
+	if !ok1 || !ok2 {
+		if !jsonEqual(want, got) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: got %s, want %s", path, encode(got), encode(want)))
+		}
+		return
+	}
+
+	var keys []string
+	for key := range wantMap {
+		keys = append(keys, key)
+	}
+	for key := range gotMap {
+		if _, ok := wantMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		p := key
+		if path != "" {
+			p = path + "." + key
+		}
+		w, inWant := wantMap[key]
+		g, inGot := gotMap[key]
+		switch {
+		case !inGot:
+			*diffs = append(*diffs, fmt.Sprintf("%s: removed", p))
+		case !inWant:
+			*diffs = append(*diffs, fmt.Sprintf("%s: added", p))
+		default:
+			diff(p, w, g, diffs)
+		}
+	}
+}
+
+func jsonEqual(a, b interface{}) bool {
+	return bytes.Equal(encode(a), encode(b))
+}
+
+func encode(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
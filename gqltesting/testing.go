@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"reflect"
 	"strconv"
 	"testing"
 
 	graphql "github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/errors"
 )
 
 // Test is a GraphQL test case to be used with RunTest(s).
@@ -18,6 +20,7 @@ type Test struct {
 	OperationName  string
 	Variables      map[string]interface{}
 	ExpectedResult string
+	ExpectedErrors []*errors.QueryError
 }
 
 // RunTests runs the given GraphQL test cases as subtests.
@@ -40,8 +43,9 @@ func RunTest(t *testing.T, test *Test) {
 		test.Context = context.Background()
 	}
 	result := test.Schema.Exec(test.Context, test.Query, test.OperationName, test.Variables)
-	if len(result.Errors) != 0 {
-		t.Fatal(result.Errors[0])
+	checkErrors(t, test.ExpectedErrors, result.Errors)
+	if test.ExpectedResult == "" && result.Data == nil {
+		return
 	}
 	got := formatJSON(t, result.Data)
 
@@ -54,6 +58,31 @@ func RunTest(t *testing.T, test *Test) {
 	}
 }
 
+// checkErrors compares the client visible parts of the errors, ignoring transient fields like
+// the original error and the panic value.
+func checkErrors(t *testing.T, want, got []*errors.QueryError) {
+	if len(want) == 0 && len(got) != 0 {
+		t.Fatal(got[0])
+	}
+
+	visible := func(errs []*errors.QueryError) []errors.QueryError {
+		l := make([]errors.QueryError, len(errs))
+		for i, err := range errs {
+			l[i] = errors.QueryError{
+				Message:    err.Message,
+				Locations:  err.Locations,
+				Path:       err.Path,
+				Extensions: err.Extensions,
+			}
+		}
+		return l
+	}
+
+	if !reflect.DeepEqual(visible(want), visible(got)) {
+		t.Fatalf("unexpected errors\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
 func formatJSON(t *testing.T, data []byte) []byte {
 	var v interface{}
 	if err := json.Unmarshal(data, &v); err != nil {
@@ -4,7 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"net/http"
 	"os"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	perrors "github.com/pkg/errors"
 	"github.com/qdentity/graphql-go/errors"
@@ -12,37 +21,129 @@ import (
 	"github.com/qdentity/graphql-go/internal/exec"
 	"github.com/qdentity/graphql-go/internal/exec/resolvable"
 	"github.com/qdentity/graphql-go/internal/exec/selected"
+	"github.com/qdentity/graphql-go/internal/httpsource"
+	"github.com/qdentity/graphql-go/internal/mock"
 	"github.com/qdentity/graphql-go/internal/query"
 	"github.com/qdentity/graphql-go/internal/schema"
 	"github.com/qdentity/graphql-go/internal/validation"
 	"github.com/qdentity/graphql-go/introspection"
 	"github.com/qdentity/graphql-go/log"
+	"github.com/qdentity/graphql-go/recording"
+	"github.com/qdentity/graphql-go/rewrite"
 	"github.com/qdentity/graphql-go/trace"
 )
 
 // ParseSchema parses a GraphQL schema and attaches the given root resolver. It returns an error if
 // the Go type signature of the resolvers does not match the schema. If nil is passed as the
 // resolver, then the schema can not be executed, but it may be inspected (e.g. with ToJSON).
+//
+// The resolver may also be a constructor of type func(context.Context) (T, error), which is called
+// with the context of each request that resolves a root field with a method of T. This way the
+// root resolver can hold request-scoped dependencies like database transactions and loaders. An
+// error of the constructor is the only error of the response. See CacheResolvers to reuse the
+// constructed resolvers.
 func ParseSchema(schemaString string, resolver interface{}, opts ...SchemaOpt) (*Schema, error) {
 	s := &Schema{
 		schema:         schema.New(),
+		sdl:            schemaString,
 		maxParallelism: 10,
+		limits:         validation.Limits{MaxInputDepth: defaultMaxInputDepth},
+		synchronous:    defaultSynchronous,
 		tracer:         trace.OpenTracingTracer{},
 		logger:         &log.DefaultLogger{},
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
+	if s.responseCache != nil && s.responseCache.opts.Scope == nil && (s.auth != nil || len(s.visibility) != 0) {
+		return nil, perrors.Errorf("response cache: Scope is required with UseAuthorization, UseVisibility or HideInternal")
+	}
+	if s.live != nil && s.live.PubSub == nil {
+		return nil, perrors.Errorf("live queries: PubSub is required")
+	}
+	for rule := range s.warnRules {
+		if !demotableRules[rule] {
+			return nil, perrors.Errorf("validation rule %q can not be demoted to a warning", rule)
+		}
+	}
 
-	if err := s.schema.Parse(schemaString); err != nil {
+	if err := s.schema.ParseWithLimits(schemaString, s.schemaSize); err != nil {
 		return nil, err
 	}
+	if len(s.directiveHandlers) != 0 {
+		if err := s.transformSchema(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.applySchemaLimits(); err != nil {
+		return nil, err
+	}
+	if len(s.visibility) != 0 {
+		s.relatedFields = interfaceFields(s.schema)
+	}
+
+	if s.httpClient != nil {
+		delegates, err := httpsource.Delegates(s.schema, s.httpClient)
+		if err != nil {
+			return nil, err
+		}
+		for field, d := range delegates {
+			if _, ok := s.delegates[field]; !ok {
+				DelegateField(field, Delegate(d))(s)
+			}
+		}
+	}
+
+	if s.mock != nil {
+		for _, operation := range []string{"query", "mutation"} {
+			t, ok := s.schema.EntryPoints[operation].(*schema.Object)
+			if !ok {
+				continue
+			}
+			for _, f := range t.Fields {
+				field := t.Name + "." + f.Name
+				_, delegated := s.delegates[field]
+				_, hasFunc := s.fieldFuncs[field]
+				if delegated || hasFunc || resolver != nil && resolvable.HasMethod(resolvable.RootType(resolver), f.Name) {
+					continue
+				}
+				DelegateField(field, Delegate(mock.Delegate(f, *s.mock)))(s)
+			}
+		}
+		if resolver == nil {
+			resolver = &struct{}{}
+		}
+	}
 
 	if resolver != nil {
-		r, err := resolvable.ApplyResolver(s.schema, resolver)
+		r, err := resolvable.ApplyResolver(s.schema, resolver, resolvable.Options{
+			RetryPolicies:     s.retryPolicies,
+			HedgePolicies:     s.hedgePolicies,
+			AuthPolicies:      s.authPolicies,
+			Delegates:         s.delegates,
+			FieldFuncs:        s.fieldFuncs,
+			EventFilters:      s.eventFilters,
+			ParentProjections: s.parentProjections,
+			ScalarTypes:       s.scalarTypes,
+			RawJSON:           reflect.TypeOf(RawJSON(nil)),
+			Lenient:           s.lenient,
+			OptionalFields:    s.optionalFields,
+			TypeResolvers:     s.typeResolvers,
+			Workers:           s.bindWorkers,
+		})
 		if err != nil {
 			return nil, err
 		}
+		var unbound []*errors.QueryError
+		for _, msg := range r.Unbound {
+			err := errors.Errorf("field bound leniently: %s", msg)
+			err.Extensions = map[string]interface{}{"code": "UNBOUND_FIELD"}
+			unbound = append(unbound, err)
+		}
+		s.logWarnings(context.Background(), unbound)
+		if s.responseCache != nil && s.responseCache.opts.Scope == nil && r.Constructor.IsValid() {
+			return nil, perrors.Errorf("response cache: Scope is required with a constructor of root resolvers")
+		}
 		s.res = r
 	}
 
@@ -63,10 +164,65 @@ func MustParseSchema(schemaString string, resolver interface{}, opts ...SchemaOp
 type Schema struct {
 	schema *schema.Schema
 	res    *resolvable.Schema
+	sdl    string
 
-	maxParallelism int
-	tracer         trace.Tracer
-	logger         log.Logger
+	maxParallelism    int
+	synchronous       bool
+	tracer            trace.Tracer
+	logger            log.Logger
+	retryPolicies     map[string]*resolvable.RetryPolicy
+	hedgePolicies     map[string]*resolvable.HedgePolicy
+	breaker           CircuitBreaker
+	auth              *exec.Auth
+	authPolicies      map[string][]string
+	visibility        []Visibility
+	relatedFields     map[string][]string // see visibleFunc
+	variablesHooks    []VariablesHook
+	panicHandler      PanicHandler
+	operationCache    *selected.OperationCache
+	listWorkers       int
+	sourceSnippets    bool
+	delegates         map[string]resolvable.Delegate
+	fieldFuncs        map[string]*resolvable.FieldFunc
+	typeResolvers     map[string]*resolvable.TypeResolver
+	eventFilters      map[string]interface{}
+	parentProjections map[[2]reflect.Type]reflect.Value
+	warnRules         map[string]bool
+	rejectUnknownVars bool
+	lenient           bool
+	bindWorkers       int
+	optionalFields    map[string]bool
+	compiledMu        sync.RWMutex
+	compiled          map[string]*query.Document // see PrecompileDocuments
+	injectIdentities  bool
+	injectTypenames   bool
+	idAlias           string
+	typenameAlias     string
+	responseHooks     []ResponseHook
+	directiveHandlers map[string]DirectiveHandler
+	warnDeprecated    bool
+	slowFields        time.Duration
+	noHTMLEscaping    bool
+	strictScalars     bool
+	validateRawJSON   bool
+	scalarTypes       map[reflect.Type]string
+	httpClient        *http.Client
+	mock              *mock.Options
+	record            *recording.Fixture
+	replay            *recording.Fixture
+	limits            validation.Limits
+	resolverCache     *exec.ResolverCache
+	live              *LiveQueryOptions
+	queryStats        bool
+	responseCache     *responseCache
+
+	maxIntrospectionSize int
+	maxComplexity        int
+	schemaSize           common.ParserLimits
+	querySize            common.ParserLimits
+
+	operationLogger log.OperationLogger
+	sensitive       map[string]bool
 }
 
 // SchemaOpt is an option to pass to ParseSchema or MustParseSchema.
@@ -79,36 +235,876 @@ func MaxParallelism(n int) SchemaOpt {
 	}
 }
 
-// Tracer is used to trace queries and fields. It defaults to trace.OpenTracingTracer.
+// Synchronous resolves all fields of a request on the goroutine executing it, one after the other,
+// instead of resolving the fields of resolvers taking a context or returning an error, and the
+// entries of lists with such fields, concurrently. MaxParallelism, MaxListWorkers and hedge policies
+// have no effect then. It is the default on GOOS=js and GOOS=wasip1, e.g. to execute queries in the
+// browser, where there is a single thread anyway. Subscriptions still receive their events on a
+// goroutine of their own.
+func Synchronous() SchemaOpt {
+	return func(s *Schema) {
+		s.synchronous = true
+	}
+}
+
+// MaxListWorkers specifies the maximum number of goroutines resolving the entries of a list with
+// fields that may block, e.g. because their resolvers take a context. The entries are distributed
+// among the goroutines as they become idle. By default each entry is resolved by its own goroutine,
+// which is wasteful for large lists. MaxParallelism still limits the resolvers running at once.
+func MaxListWorkers(n int) SchemaOpt {
+	return func(s *Schema) {
+		s.listWorkers = n
+	}
+}
+
+// ParentProjection projects parent resolvers of type P to T for the resolver methods taking a
+// query.Parent[T], e.g. to pass them only the id of the parent entity, or a type they share with
+// other parents. Without a projection, the parent resolver has to be assignable to T, otherwise
+// the field fails with an error.
+func ParentProjection[P, T any](project func(parent P) T) SchemaOpt {
+	return func(s *Schema) {
+		if s.parentProjections == nil {
+			s.parentProjections = make(map[[2]reflect.Type]reflect.Value)
+		}
+		types := [2]reflect.Type{reflect.TypeOf((*P)(nil)).Elem(), reflect.TypeOf((*T)(nil)).Elem()}
+		s.parentProjections[types] = reflect.ValueOf(project)
+	}
+}
+
+// Tracer is used to trace queries and fields. It defaults to trace.OpenTracingTracer. Panics of the
+// tracer are recovered and logged as a log.CallbackPanic, the query or field is not traced then.
 func Tracer(tracer trace.Tracer) SchemaOpt {
 	return func(s *Schema) {
 		s.tracer = tracer
 	}
 }
 
-// Logger is used to log panics durring query execution. It defaults to exec.DefaultLogger.
+// Logger is used to log panics durring query execution. It defaults to exec.DefaultLogger. Panics
+// of the tracer, the operation logger and the panic handler are logged as a log.CallbackPanic. A
+// log.WarningLogger also gets the warnings of the responses.
 func Logger(logger log.Logger) SchemaOpt {
 	return func(s *Schema) {
 		s.logger = logger
 	}
 }
 
+// PanicHandler is called with every panic recovered during query execution, in addition to the
+// logger. The path is the one of the field whose resolution panicked, nil outside of a field, and
+// the stack is the one of the panicking goroutine. It can be used e.g. to report panics to an error
+// tracking service.
+type PanicHandler func(ctx context.Context, value interface{}, path []interface{}, stack []byte)
+
+// UsePanicHandler sets a handler that is called with every recovered panic.
+func UsePanicHandler(h PanicHandler) SchemaOpt {
+	return func(s *Schema) {
+		s.panicHandler = h
+	}
+}
+
+// RetryPolicy describes how the resolver of a field is called again after it returned an error.
+type RetryPolicy struct {
+	// Attempts is the maximum number of calls of the resolver, including the first one. It has to
+	// be at least 1.
+	Attempts int
+
+	// Backoff returns the delay before the given retry, starting at 1. There is no delay if nil.
+	Backoff func(retry int) time.Duration
+
+	// Retryable reports whether the error returned by the resolver is transient. If nil, all
+	// errors except context.Canceled and context.DeadlineExceeded are retried.
+	Retryable func(err error) bool
+}
+
+// FieldRetryPolicy sets the retry policy of the field given as "Type.field", e.g. "Query.user", of an
+// object type. It takes precedence over a @retry(attempts: Int!, backoffMs: Int) directive on the
+// field.
+func FieldRetryPolicy(field string, policy RetryPolicy) SchemaOpt {
+	return func(s *Schema) {
+		if s.retryPolicies == nil {
+			s.retryPolicies = make(map[string]*resolvable.RetryPolicy)
+		}
+		s.retryPolicies[field] = &resolvable.RetryPolicy{
+			Attempts:  policy.Attempts,
+			Backoff:   policy.Backoff,
+			Retryable: policy.Retryable,
+		}
+	}
+}
+
+// HedgePolicy describes when the resolver of a field is called again while the earlier calls are
+// still running, to cut the latency of upstreams that are occasionally slow, e.g. ones backed by
+// redundant replicas.
+type HedgePolicy struct {
+	// Attempts is the maximum number of calls of the resolver, including the first one. It has to
+	// be at least 2.
+	Attempts int
+
+	// Delay is how long the calls so far get to succeed before the next one is made.
+	Delay time.Duration
+}
+
+// FieldHedgePolicy hedges the resolver method of the field given as "Type.field", e.g.
+// "Query.product", of an object type: if it has not returned after the delay, it is called again
+// concurrently, and the first successful result is used. The contexts of the other calls are
+// cancelled then. A call failing makes the next one right away. The resolver has to be idempotent
+// and safe to call concurrently, and if it has a retry policy, each call retries on its own. The
+// policy takes precedence over a @hedge(delayMs: Int!, attempts: Int) directive on the field.
+func FieldHedgePolicy(field string, policy HedgePolicy) SchemaOpt {
+	return func(s *Schema) {
+		if s.hedgePolicies == nil {
+			s.hedgePolicies = make(map[string]*resolvable.HedgePolicy)
+		}
+		s.hedgePolicies[field] = &resolvable.HedgePolicy{
+			Attempts: policy.Attempts,
+			Delay:    policy.Delay,
+		}
+	}
+}
+
+// OptionalFields makes the fields given as "Type.field", e.g. "Query.recommendations", optional, like
+// an @optional directive on them in the schema. The errors of an optional field and of the fields
+// below it are not returned as errors of the response but as warnings, in its "warnings" extension,
+// so that clients can degrade gracefully when a non-critical part of the response fails. Errors
+// still make the closest nullable field null, the optional field at the latest, which therefore
+// has to be of a nullable type.
+func OptionalFields(fields ...string) SchemaOpt {
+	return func(s *Schema) {
+		if s.optionalFields == nil {
+			s.optionalFields = make(map[string]bool)
+		}
+		for _, f := range fields {
+			s.optionalFields[f] = true
+		}
+	}
+}
+
+// CircuitBreaker can veto the execution of fields whose upstream is unhealthy.
+type CircuitBreaker interface {
+	// Allow is called before the resolver of the field given as "Type.field" gets called. If ok is
+	// false, the resolver is skipped and the field resolves to null with an error that has the
+	// extension code "CIRCUIT_OPEN". Otherwise done gets called with the field's error, or nil.
+	Allow(ctx context.Context, field string) (done func(err error), ok bool)
+}
+
+// Delegate executes a query on an upstream GraphQL service and returns the data and the errors of
+// its response, see DelegateField. The package gateway provides delegates for services reachable
+// over HTTP.
+type Delegate func(ctx context.Context, query string, variables map[string]interface{}) (json.RawMessage, []*errors.QueryError)
+
+// DelegateField resolves the root field given as "Query.field" or "Mutation.field" by sending it,
+// with its arguments, selections and the variables and fragments they use, to an upstream service
+// instead of calling a resolver method. The resolver does not need a method for the field and the
+// type of the field is not bound to Go types, the value returned by the service is passed through.
+func DelegateField(field string, d Delegate) SchemaOpt {
+	return func(s *Schema) {
+		if s.delegates == nil {
+			s.delegates = make(map[string]resolvable.Delegate)
+		}
+		s.delegates[field] = resolvable.Delegate(d)
+	}
+}
+
+// UseHTTPDirective resolves the root fields with an @http(url: String!, method: String, body: String)
+// schema directive by calling the REST endpoint with the client, or http.DefaultClient if it is nil.
+// Placeholders like {id} in the URL and the body are replaced with the values of the arguments and
+// the JSON response is mapped onto the type of the field, so that the resolver does not need methods
+// for these fields. The directive has to be declared in the schema, e.g.
+// "directive @http(url: String!, method: String = "GET", body: String) on FIELD_DEFINITION". It is
+// meant for prototyping APIs wrapping REST services.
+func UseHTTPDirective(client *http.Client) SchemaOpt {
+	return func(s *Schema) {
+		if client == nil {
+			client = http.DefaultClient
+		}
+		s.httpClient = client
+	}
+}
+
+// MockOptions configures the fake data served by Mock.
+type MockOptions struct {
+	// Seed determines the data. The same query gets the same data for the same seed.
+	Seed int64
+
+	// Types maps names of types to functions returning their values, e.g. "DateTime" to a
+	// function returning timestamps. Values of scalars and enums are encoded as JSON, values of
+	// object types are maps from field names to values replacing the generated ones.
+	Types map[string]func(r *rand.Rand) interface{}
+
+	// MinListLength and MaxListLength bound the number of entries of lists. Lists have 1 to 3
+	// entries if both are 0.
+	MinListLength int
+	MaxListLength int
+}
+
+// Mock resolves the root fields that have no resolver method, delegate or field func with fake
+// data, generated from the types of the schema for the selections of the query. The resolver
+// passed to ParseSchema may be nil then. It lets clients be developed against a schema before its
+// resolvers exist.
+func Mock(opts MockOptions) SchemaOpt {
+	return func(s *Schema) {
+		if opts.MinListLength == 0 && opts.MaxListLength == 0 {
+			opts.MinListLength, opts.MaxListLength = 1, 3
+		}
+		s.mock = &mock.Options{
+			Seed:          opts.Seed,
+			Types:         opts.Types,
+			MinListLength: opts.MinListLength,
+			MaxListLength: opts.MaxListLength,
+		}
+	}
+}
+
+// MaxAliases rejects operations with more than n aliased fields, which can make a single request
+// resolve the same expensive field many times. The aliases of fragments count every time they are
+// spread. There is no limit by default.
+func MaxAliases(n int) SchemaOpt {
+	return func(s *Schema) {
+		s.limits.MaxAliases = n
+	}
+}
+
+// MaxDirectives rejects operations with more than n directives, counting the ones of fragments
+// every time they are spread. There is no limit by default.
+func MaxDirectives(n int) SchemaOpt {
+	return func(s *Schema) {
+		s.limits.MaxDirectives = n
+	}
+}
+
+// MaxDepth rejects operations with fields nested more than n levels deep, root fields have a depth
+// of 1. Introspection fields are not counted, see LimitIntrospection. It takes precedence over a
+// @depthLimit(max: Int!) directive on the schema definition. There is no limit by default.
+func MaxDepth(n int) SchemaOpt {
+	return func(s *Schema) {
+		s.limits.MaxDepth = n
+	}
+}
+
+// defaultMaxInputDepth is the limit of MaxInputDepth unless the option is given.
+const defaultMaxInputDepth = 100
+
+// MaxInputDepth rejects requests with values of arguments, directives or variables in which lists
+// and input objects are nested more than n levels deep, with errors of the rule "MaxInputDepth",
+// since walking such values can exhaust the stack. A scalar has a depth of 0 and a list of scalars
+// a depth of 1. The limit is 100 by default, 0 disables it. Literals nested more than 1000 levels
+// deep are rejected when the query is parsed regardless.
+func MaxInputDepth(n int) SchemaOpt {
+	return func(s *Schema) {
+		s.limits.MaxInputDepth = n
+	}
+}
+
+// ParserLimits bound the size of a document, which is rejected while it is parsed as soon as it
+// exceeds one of them, before it is processed further. A limit of 0 disables it.
+type ParserLimits struct {
+	// MaxBytes limits the length of the source in bytes. It is checked before parsing.
+	MaxBytes int
+
+	// MaxTokens limits the tokens of the source, e.g. names, punctuators and values.
+	MaxTokens int
+
+	// MaxDefinitions limits the definitions of the document: the operations and fragments of a
+	// query, the types, directives and extensions of a schema.
+	MaxDefinitions int
+}
+
+// MaxSchemaSize rejects schemas exceeding the limits with an error with the code
+// "DOCUMENT_TOO_LARGE", e.g. to parse schemas uploaded by untrusted users. There are no limits by
+// default.
+func MaxSchemaSize(limits ParserLimits) SchemaOpt {
+	return func(s *Schema) {
+		s.schemaSize = common.ParserLimits(limits)
+	}
+}
+
+// MaxQuerySize rejects queries exceeding the limits with an error with the code
+// "DOCUMENT_TOO_LARGE", so that giant queries fail before they are validated. Precompiled
+// documents, see PrecompileDocuments, are subject to the limits too. There are no limits by
+// default.
+func MaxQuerySize(limits ParserLimits) SchemaOpt {
+	return func(s *Schema) {
+		s.querySize = common.ParserLimits(limits)
+	}
+}
+
+// parseQuery parses the query within the limits of MaxQuerySize.
+func (s *Schema) parseQuery(queryString string) (*query.Document, *errors.QueryError) {
+	doc, err := query.ParseWithLimits(queryString, s.querySize)
+	if err != nil {
+		return nil, err.WithCode(errors.CodeParseFailed)
+	}
+	return doc, nil
+}
+
+// withCode sets the code of the errors unless they have one, see errors.QueryError.WithCode.
+func withCode(errs []*errors.QueryError, code string) []*errors.QueryError {
+	for _, err := range errs {
+		err.WithCode(code)
+	}
+	return errs
+}
+
+// MaxComplexity rejects operations whose complexity exceeds n. The complexity of a field is set
+// with a @complexity(value: Int!, multipliers: [String!]) directive in the schema: its value, 1 by
+// default, plus the complexity of its selections, multiplied by the arguments named by
+// multipliers, e.g. "first". The limit takes precedence over a @complexity directive on the schema
+// definition. There is no limit by default.
+func MaxComplexity(n int) SchemaOpt {
+	return func(s *Schema) {
+		s.maxComplexity = n
+	}
+}
+
+// RejectUnknownVariables rejects requests with variables that are not declared by their operation,
+// with errors of the rule "NoUnknownVariables". By default such variables are ignored.
+func RejectUnknownVariables() SchemaOpt {
+	return func(s *Schema) {
+		s.rejectUnknownVars = true
+	}
+}
+
+// WarnOnly demotes the validation rules with the names from errors to warnings: a query breaking
+// them is executed, and their errors are added to the extensions of the response as "warnings".
+// It lets clients learn about documents to clean up before they are rejected. The rules that can
+// be demoted are NoUnusedVariables, NoUnusedFragments and NoUnknownVariables, which reports the
+// variables of the request not declared by its operation even without RejectUnknownVariables.
+func WarnOnly(rules ...string) SchemaOpt {
+	return func(s *Schema) {
+		if s.warnRules == nil {
+			s.warnRules = make(map[string]bool)
+		}
+		for _, rule := range rules {
+			s.warnRules[rule] = true
+		}
+	}
+}
+
+// WarnDeprecated adds a warning with the code "DEPRECATED" to the extensions of responses for every
+// deprecated field and enum value the operation uses, so that clients learn about the selections
+// to migrate before they are removed.
+func WarnDeprecated() SchemaOpt {
+	return func(s *Schema) {
+		s.warnDeprecated = true
+	}
+}
+
+// WarnSlowFields adds a warning with the code "SLOW_FIELD" and the duration in milliseconds as
+// "durationMs" to the extensions of responses for every field whose resolver takes longer than the
+// threshold.
+func WarnSlowFields(threshold time.Duration) SchemaOpt {
+	return func(s *Schema) {
+		s.slowFields = threshold
+	}
+}
+
+// StrictScalars checks the values of the builtin scalar types before they are written: Int has to
+// be a 32-bit integer, Float a number, String a string, Boolean true or false, and ID a string or
+// an integer. The resolvers of mapped Go types, see MapScalar, and of types implementing
+// ImplementsGraphQLType may return values encoded as something else, e.g. an int64 out of the
+// range of Int. A field with such a value is null with an error with the code
+// "INVALID_SCALAR_OUTPUT" instead.
+func StrictScalars() SchemaOpt {
+	return func(s *Schema) {
+		s.strictScalars = true
+	}
+}
+
+// ValidateRawJSON checks the values of the fields whose resolvers return RawJSON before they are
+// written, e.g. while debugging a gateway: the JSON has to be a value of the field's type, with
+// exactly the fields selected by the query, by their aliases, and no null for non-null types. A
+// field with a mismatching value is null with an error with the code "INVALID_RAW_JSON" and the
+// path of the mismatch instead. The checks decode the values, which RawJSON is meant to avoid.
+func ValidateRawJSON() SchemaOpt {
+	return func(s *Schema) {
+		s.validateRawJSON = true
+	}
+}
+
+// DisableHTMLEscaping writes the characters <, > and & of strings in the data of responses as they
+// are, instead of escaping them like encoding/json, e.g. for responses that are not embedded in
+// HTML. The response has to be encoded without escaping too, since json.Marshal escapes Data
+// again, see relay.Handler.DisableHTMLEscaping.
+func DisableHTMLEscaping() SchemaOpt {
+	return func(s *Schema) {
+		s.noHTMLEscaping = true
+	}
+}
+
+var demotableRules = map[string]bool{
+	"NoUnusedVariables":  true,
+	"NoUnusedFragments":  true,
+	"NoUnknownVariables": true,
+}
+
+// ReportQueryStats computes the depth, complexity and number of fields of each query, and how many
+// distinct fragments it spreads how many times. The stats are added to the extensions of the
+// response as "queryStats" and passed to the tracer if it implements trace.StatsTracer. They help
+// to monitor the queries of clients and to choose limits before enforcing them, see MaxDepth and
+// MaxComplexity.
+func ReportQueryStats() SchemaOpt {
+	return func(s *Schema) {
+		s.queryStats = true
+	}
+}
+
+// operationStats returns the stats of the operation and passes them to the tracer.
+func (s *Schema) operationStats(ctx context.Context, doc *query.Document, op *query.Operation, variables map[string]interface{}) *trace.QueryStats {
+	stats := validation.OperationStats(s.schema, doc, op, variables)
+	qs := &trace.QueryStats{
+		Depth:           stats.Depth,
+		Complexity:      stats.Complexity,
+		Fields:          stats.Fields,
+		Fragments:       stats.Fragments,
+		FragmentSpreads: stats.FragmentSpreads,
+	}
+	if t, ok := s.tracer.(trace.StatsTracer); ok {
+		defer s.recoverCallback(ctx, "tracer")
+		t.TraceQueryStats(ctx, *qs)
+	}
+	return qs
+}
+
+// applySchemaLimits applies the @depthLimit(max: Int!) and @complexity(value: Int!) directives of
+// the schema definition for the limits not set with MaxDepth and MaxComplexity.
+func (s *Schema) applySchemaLimits() error {
+	limit := func(directive, arg string, n *int) error {
+		d := s.schema.SchemaDirectives.Get(directive)
+		if d == nil || *n != 0 {
+			return nil
+		}
+		lit, ok := d.Args.Get(arg)
+		if !ok || lit == nil {
+			return perrors.Errorf("directive @%s requires argument %q on the schema", directive, arg)
+		}
+		v, ok := lit.Value(nil).(int32)
+		if !ok || v <= 0 {
+			return perrors.Errorf("directive @%s requires a positive value for %q on the schema, got %s", directive, arg, lit)
+		}
+		*n = int(v)
+		return nil
+	}
+	if err := limit("depthLimit", "max", &s.limits.MaxDepth); err != nil {
+		return err
+	}
+	return limit("complexity", "value", &s.maxComplexity)
+}
+
+// IntrospectionLimits protects against introspection queries built to be expensive, e.g. by nesting
+// "ofType" fields deeply. A limit of 0 disables it.
+type IntrospectionLimits struct {
+	// MaxOfTypeDepth limits the nesting of the "ofType" field. The introspection query of
+	// graphql-js nests it 7 times.
+	MaxOfTypeDepth int
+
+	// MaxDepth limits the nesting of the "fields", "inputFields", "interfaces" and
+	// "possibleTypes" fields, through which introspection queries recurse. The introspection
+	// query of graphql-js nests them once.
+	MaxDepth int
+
+	// MaxResponseSize limits the size in bytes of the data of operations selecting __schema or
+	// __type. Larger responses are replaced by an error with the code INTROSPECTION_TOO_LARGE.
+	MaxResponseSize int
+}
+
+// LimitIntrospection rejects introspection queries exceeding the limits. The depth limits are
+// checked when the query is validated.
+func LimitIntrospection(limits IntrospectionLimits) SchemaOpt {
+	return func(s *Schema) {
+		s.limits.MaxIntrospectionOfTypeDepth = limits.MaxOfTypeDepth
+		s.limits.MaxIntrospectionDepth = limits.MaxDepth
+		s.maxIntrospectionSize = limits.MaxResponseSize
+	}
+}
+
+// RecordFields records the value of each resolved field in the fixture, keyed by its path and the
+// arguments of the fields along it. Save the fixture after running the queries to replay them with
+// ReplayFields.
+func RecordFields(f *recording.Fixture) SchemaOpt {
+	return func(s *Schema) {
+		s.record = f
+	}
+}
+
+// ReplayFields serves the values recorded in the fixture instead of calling resolvers, so that
+// queries can be tested deterministically without the backends of the resolvers. Fields without a
+// recorded value resolve to an error with the code NOT_RECORDED.
+func ReplayFields(f *recording.Fixture) SchemaOpt {
+	return func(s *Schema) {
+		s.replay = f
+	}
+}
+
+// CacheResolvers reuses the root resolvers returned by a constructor passed to ParseSchema for
+// requests with the same key, e.g. the tenant of the request. Up to size resolvers are cached,
+// constructors returning an error are called again. Requests for which key returns "" always get
+// a new resolver.
+func CacheResolvers(size int, key func(ctx context.Context) string) SchemaOpt {
+	return func(s *Schema) {
+		s.resolverCache = exec.NewResolverCache(size, key)
+	}
+}
+
+// FieldFunc resolves a field with the arguments of the query, see ResolveFieldFunc.
+type FieldFunc func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+// ResolveFieldFunc resolves the root field given as "Query.field" or "Mutation.field" with fn
+// instead of a resolver method. fn gets the arguments as they are given in the query, with the
+// default values of the schema applied. The values it returns have to be assignable to resultType,
+// which is bound to the type of the field like the result type of a resolver method. Adapters use
+// it to expose other APIs without writing a resolver method per field, see package grpcbind.
+func ResolveFieldFunc(field string, resultType reflect.Type, fn FieldFunc) SchemaOpt {
+	return func(s *Schema) {
+		if s.fieldFuncs == nil {
+			s.fieldFuncs = make(map[string]*resolvable.FieldFunc)
+		}
+		s.fieldFuncs[field] = &resolvable.FieldFunc{Resolve: fn, ResultType: resultType}
+	}
+}
+
+// TypeOf returns the name of the object type of a value of an interface or union type and the
+// resolver of the object, see ResolveTypes.
+type TypeOf func(value interface{}) (typeName string, resolver interface{})
+
+// ResolveTypes resolves the object types of the values of the interface or union type with typeOf
+// instead of the methods converting them, e.g. ToHuman, so that resolvers can return any Go value
+// for the type, e.g. of an interface type implemented by the resolvers of its objects. typeOf is
+// passed the value returned by the resolver of the parent. resolverTypes maps each object type of
+// the type to the Go type of the resolvers typeOf returns for it, which is bound to the object type.
+// The fields of an interface are still resolved by the methods of the value itself.
+func ResolveTypes(typeName string, typeOf TypeOf, resolverTypes map[string]reflect.Type) SchemaOpt {
+	return func(s *Schema) {
+		if s.typeResolvers == nil {
+			s.typeResolvers = make(map[string]*resolvable.TypeResolver)
+		}
+		s.typeResolvers[typeName] = &resolvable.TypeResolver{TypeOf: typeOf, ResolverTypes: resolverTypes}
+	}
+}
+
+// MapScalar maps the Go type to the scalar type of the schema with the name, e.g.
+// MapScalar(reflect.TypeOf(time.Time{}), "DateTime"), so that resolvers can return and take values
+// of types like time.Time, uuid.UUID or decimal.Decimal without wrapping them in a type
+// implementing UnmarshalGraphQL. The values are written as encoded by json.Marshal. Pointers to
+// them have to implement json.Unmarshaler, which is passed the input encoded as JSON, or
+// encoding.TextUnmarshaler, which is passed input strings.
+//
+// The values of any scalar type, mapped or not, which implement
+// MarshalGraphQLContext(ctx context.Context) ([]byte, error) are written as encoded by that method
+// instead, which is passed the context of the request, so that e.g. a DateTime can be written in the
+// time zone of the user. An error of the method makes the field null with an error.
+func MapScalar(goType reflect.Type, scalar string) SchemaOpt {
+	return func(s *Schema) {
+		if s.scalarTypes == nil {
+			s.scalarTypes = make(map[reflect.Type]string)
+		}
+		s.scalarTypes[goType] = scalar
+	}
+}
+
+// LenientBinding makes ParseSchema accept a resolver without methods or struct fields for some
+// fields of the schema, e.g. while a large schema is being implemented. Each of them is logged as a
+// warning when the schema is parsed, see log.WarningLogger, and it is null with an error with the
+// "code" extension UNBOUND_FIELD when it is resolved. Without the option, which is the default,
+// ParseSchema fails for them.
+func LenientBinding() SchemaOpt {
+	return func(s *Schema) {
+		s.lenient = true
+	}
+}
+
+// BindConcurrency binds the resolver to the object, interface and union types of the schema on up
+// to n goroutines, which shortens ParseSchema for schemas with thousands of types, e.g. for the cold
+// starts of serverless functions. If binding fails, ParseSchema binds again on a single goroutine to
+// return the same error as without the option. It is 1, a single goroutine, by default.
+func BindConcurrency(n int) SchemaOpt {
+	return func(s *Schema) {
+		s.bindWorkers = n
+	}
+}
+
+// InjectIdentities makes every selection set of an object or interface with an id field select it,
+// and the one of an interface or union select __typename, without returning them if the query does
+// not select them itself. Entity caches and live queries then learn the objects of every response,
+// see CacheResponses and LiveQueries, and resolvers can rely on their ids having been resolved. The
+// id field is not injected if it takes arguments, is restricted by authorization, resolved by a
+// delegate or hidden from the request, or if the query uses its response name for another field.
+func InjectIdentities() SchemaOpt {
+	return func(s *Schema) {
+		s.injectIdentities = true
+	}
+}
+
+// InjectTypenames makes every selection set of an interface or union select __typename, which is
+// returned even if the query does not select it, as many client caches require to normalize
+// responses. It is not injected if the query uses its response name for another field.
+func InjectTypenames() SchemaOpt {
+	return func(s *Schema) {
+		s.injectTypenames = true
+	}
+}
+
+// InjectedAliases sets the response names of the id and __typename fields added by
+// InjectIdentities, e.g. "__id" and "__type", which then are added to every selection set unless
+// the query uses these names itself, even if it selects id or __typename. The added fields are
+// returned to the hooks of UseResponseHook, e.g. to normalize responses into a cache, and removed
+// from the responses of Exec and ExecAST afterwards, so clients never see them. They are not
+// returned for a query using one of these names anywhere.
+func InjectedAliases(id, typename string) SchemaOpt {
+	return func(s *Schema) {
+		s.idAlias = id
+		s.typenameAlias = typename
+	}
+}
+
+// UseCircuitBreaker sets the circuit breaker consulted before each resolver call.
+func UseCircuitBreaker(breaker CircuitBreaker) SchemaOpt {
+	return func(s *Schema) {
+		s.breaker = breaker
+	}
+}
+
+// Authorization configures the access control of fields restricted with the @auth or
+// @hasRole(role: [String!]!) schema directives or with Policies. The restriction of a field of an
+// interface applies to the same field of the object types implementing it, and the other way
+// around, so that selecting the field on the interface or in a fragment of an object type is
+// restricted alike. ParseSchema fails if they are restricted to different roles.
+type Authorization struct {
+	// Principal extracts the principal of the request from the context. A nil principal is not
+	// authenticated.
+	Principal func(ctx context.Context) interface{}
+
+	// HasRole reports whether the principal has the given role.
+	HasRole func(principal interface{}, role string) bool
+
+	// Policies maps fields given as "Type.field" to the roles of which the principal needs to have
+	// at least one. An empty list only requires an authenticated principal. They take precedence
+	// over the directives in the schema.
+	Policies map[string][]string
+
+	// SilentNull resolves denied fields to null without adding an error to the response. Denied
+	// non-null fields still add the error, since their null makes the parent null.
+	SilentNull bool
+}
+
+// UseAuthorization enables the access control of fields. Restricted fields are denied for all
+// requests if it is not used.
+func UseAuthorization(a Authorization) SchemaOpt {
+	return func(s *Schema) {
+		s.auth = &exec.Auth{
+			Principal:  a.Principal,
+			HasRole:    a.HasRole,
+			SilentNull: a.SilentNull,
+		}
+		s.authPolicies = a.Policies
+	}
+}
+
+// Visibility reports whether a type, or the field of a type if fieldName is not empty, is visible
+// to the request. Hidden types and fields are omitted from introspection and queries selecting them
+// fail validation as if they did not exist. A field of an interface and the same field of the object
+// types implementing it are hidden together if any of them is hidden.
+type Visibility func(ctx context.Context, typeName, fieldName string) bool
+
+// UseVisibility adds a filter for serving different views of the schema to different clients,
+// e.g. based on the client's identity stored in the context. A type or field is only visible if
+// all filters report it as visible.
+func UseVisibility(v Visibility) SchemaOpt {
+	return func(s *Schema) {
+		s.visibility = append(s.visibility, v)
+	}
+}
+
+// HideInternal hides all fields marked with the @internal schema directive from requests for which
+// isInternal returns false. The directive has to be declared in the schema, e.g.
+// "directive @internal on FIELD_DEFINITION".
+func HideInternal(isInternal func(ctx context.Context) bool) SchemaOpt {
+	return func(s *Schema) {
+		s.visibility = append(s.visibility, func(ctx context.Context, typeName, fieldName string) bool {
+			if fieldName == "" {
+				return true
+			}
+			var fields schema.FieldList
+			switch t := s.schema.Types[typeName].(type) {
+			case *schema.Object:
+				fields = t.Fields
+			case *schema.Interface:
+				fields = t.Fields
+			}
+			f := fields.Get(fieldName)
+			return f == nil || f.Directives.Get("internal") == nil || isInternal(ctx)
+		})
+	}
+}
+
+// visibleFunc returns whether types and fields are visible to the request. A field of an interface
+// is only visible if the same field of the object types implementing it is, and the other way
+// around, since selecting the field on the interface resolves the one of the object type.
+func (s *Schema) visibleFunc(ctx context.Context) func(typeName, fieldName string) bool {
+	if len(s.visibility) == 0 {
+		return nil
+	}
+	visible := func(typeName, fieldName string) bool {
+		for _, v := range s.visibility {
+			if !v(ctx, typeName, fieldName) {
+				return false
+			}
+		}
+		return true
+	}
+	return func(typeName, fieldName string) bool {
+		refs, ok := s.relatedFields[typeName+"."+fieldName]
+		if fieldName == "" || !ok {
+			return visible(typeName, fieldName)
+		}
+		for _, ref := range refs {
+			i := strings.IndexByte(ref, '.')
+			if !visible(ref[:i], ref[i+1:]) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// interfaceFields maps the fields of interfaces and of the object types implementing them, given as
+// "Type.field", to the same fields of the related types, see resolvable.RelatedFields.
+func interfaceFields(s *schema.Schema) map[string][]string {
+	result := make(map[string][]string)
+	for _, t := range s.Types {
+		intf, ok := t.(*schema.Interface)
+		if !ok {
+			continue
+		}
+		for _, f := range intf.Fields {
+			refs := resolvable.RelatedFields(s, intf.Name, f.Name)
+			for _, ref := range refs {
+				result[ref] = refs
+			}
+		}
+	}
+	return result
+}
+
+// VariablesHook is called before execution with the variables of the operation, in which the
+// default values of the operation's variable definitions have already been set. The returned
+// variables are used for packing the arguments of the resolvers, so the hook may rewrite them, e.g.
+// to clamp pagination limits or to inject a tenant ID. An error aborts the request.
+type VariablesHook func(ctx context.Context, operationName string, variables map[string]interface{}) (map[string]interface{}, error)
+
+// UseVariablesHook adds a hook that may rewrite the variables of each request. Hooks are called in
+// the order they were added.
+func UseVariablesHook(hook VariablesHook) SchemaOpt {
+	return func(s *Schema) {
+		s.variablesHooks = append(s.variablesHooks, hook)
+	}
+}
+
+// ResponseHook is called with the data of each response of Exec and ExecAST, including the fields
+// added under the names of InjectedAliases, and returns the data to respond with. It is not called
+// for responses served by the response cache, see CacheResponses.
+type ResponseHook func(ctx context.Context, data json.RawMessage) json.RawMessage
+
+// UseResponseHook adds a hook that may inspect or rewrite the data of each response. Hooks are
+// called in the order they were added. If a hook panics, the data is left as it was.
+func UseResponseHook(hook ResponseHook) SchemaOpt {
+	return func(s *Schema) {
+		s.responseHooks = append(s.responseHooks, hook)
+	}
+}
+
+// OperationCacheSize enables caching the selections of up to n operations, so that repeated
+// executions of an operation skip flattening its fragments and evaluating @skip and @include. The
+// selections are cached per value of the variables used by @skip and @include. Operations with
+// introspection fields are not cached.
+func OperationCacheSize(n int) SchemaOpt {
+	return func(s *Schema) {
+		s.operationCache = selected.NewOperationCache(n)
+	}
+}
+
+// SourceSnippets adds the source around the location of parse and validation errors, with a caret
+// pointing at the column, as "snippet" extension of the errors. It helps developers spot the
+// problem while iterating on a query.
+func SourceSnippets() SchemaOpt {
+	return func(s *Schema) {
+		s.sourceSnippets = true
+	}
+}
+
+// queryErrors adds source snippets to the errors of the query if enabled.
+func (s *Schema) queryErrors(queryString string, errs []*errors.QueryError) []*errors.QueryError {
+	if s.sourceSnippets {
+		errors.AddSnippets(queryString, errs)
+	}
+	return errs
+}
+
 // Response represents a typical response of a GraphQL server. It may be encoded to JSON directly or
 // it may be further processed to a custom response type, for example to include custom error data.
+// The fields of the objects in Data are in the order of the selections of the operation, so they
+// are the same for every execution of it, and json.Marshal sorts the keys of the Extensions and of
+// the extensions of Errors.
 type Response struct {
 	Data       json.RawMessage        `json:"data,omitempty"`
 	Errors     []*errors.QueryError   `json:"errors,omitempty"`
 	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
+// Err returns the errors of the response as a single error, nil if there are none. errors.Is and
+// errors.As find the original errors of resolvers in it, e.g. sql.ErrNoRows, and the reason of a
+// request whose context is done, e.g. context.DeadlineExceeded.
+func (r *Response) Err() error {
+	return errors.Join(r.Errors)
+}
+
+// validate validates the document with the schema and checks its operations against the limits.
+// The errors of the rules demoted to warnings are returned as warnings, see WarnOnly.
+func (s *Schema) validate(doc *query.Document, visible func(typeName, fieldName string) bool) (errs []*errors.QueryError, warnings []*errors.QueryError) {
+	for _, err := range validation.Validate(s.schema, doc, visible) {
+		if s.warnRules[err.Rule] {
+			warnings = append(warnings, err)
+			continue
+		}
+		errs = append(errs, err.WithCode(errors.CodeValidationFailed))
+	}
+	if len(errs) != 0 {
+		return errs, warnings
+	}
+	return withCode(validation.ValidateLimits(doc, s.limits), errors.CodeValidationFailed), warnings
+}
+
+// unknownVariables returns an error for each variable of the request that is not declared by the
+// operation, sorted by name.
+func unknownVariables(op *query.Operation, variables map[string]interface{}) []*errors.QueryError {
+	var names []string
+	for name := range variables {
+		if op.Vars.Get(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	errs := make([]*errors.QueryError, len(names))
+	for i, name := range names {
+		byOp := ""
+		if op.Name.Name != "" {
+			byOp = fmt.Sprintf(" by operation %q", op.Name.Name)
+		}
+		errs[i] = &errors.QueryError{
+			Message: fmt.Sprintf("Variable %q is not declared%s.", "$"+name, byOp),
+			Rule:    "NoUnknownVariables",
+		}
+	}
+	return errs
+}
+
 // Validate validates the given query with the schema.
 func (s *Schema) Validate(queryString string) []*errors.QueryError {
-	doc, qErr := query.Parse(queryString)
+	doc, qErr := s.parseQuery(queryString)
 	if qErr != nil {
-		return []*errors.QueryError{qErr}
+		return s.queryErrors(queryString, []*errors.QueryError{qErr})
 	}
 
-	return validation.Validate(s.schema, doc)
+	errs, _ := s.validate(doc, nil)
+	return s.queryErrors(queryString, errs)
 }
 
 // Exec executes the given query with the schema's resolver. It panics if the schema was created
@@ -118,34 +1114,115 @@ func (s *Schema) Exec(ctx context.Context, queryString string, operationName str
 	if s.res == nil {
 		panic("schema created without resolver, can not exec")
 	}
-	return s.exec(ctx, queryString, operationName, variables, s.res)
+	ctx = WithRequestStore(ctx)
+	return s.exec(ctx, queryString, nil, nil, operationName, variables, s.res)
 }
 
-func (s *Schema) exec(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, res *resolvable.Schema) *Response {
-	doc, qErr := query.Parse(queryString)
-	if qErr != nil {
-		return &Response{Errors: []*errors.QueryError{qErr}}
+// ExecAST executes an operation of a document that was parsed and possibly transformed with the
+// rewrite package, e.g. by a gateway, without printing and parsing it again. The document is
+// validated like the ones passed to Exec. Tracers, the operation cache and the response cache get
+// the document printed as its source, see rewrite.Document.String.
+func (s *Schema) ExecAST(ctx context.Context, doc *rewrite.Document, operationName string, variables map[string]interface{}) *Response {
+	if s.res == nil {
+		panic("schema created without resolver, can not exec")
 	}
+	ctx = WithRequestStore(ctx)
+	parsed := query.DocumentOf(doc)
+	return s.exec(ctx, query.Print(parsed), parsed, nil, operationName, variables, s.res)
+}
 
-	errs := validation.Validate(s.schema, doc)
+// preparedQuery is the result of prepare for a query without errors.
+type preparedQuery struct {
+	doc       *query.Document
+	op        *query.Operation
+	variables map[string]interface{}
+	warnings  []*errors.QueryError
+}
+
+// exec executes an operation of the query. The query is parsed unless the parsed document is
+// given, and prepared unless the prepared query is given, e.g. by Subscribe, which has prepared it
+// to tell its type. The variables are those of the request either way.
+func (s *Schema) exec(ctx context.Context, queryString string, parsed *query.Document, prepared *preparedQuery, operationName string, variables map[string]interface{}, res *resolvable.Schema) (resp *Response) {
+	var doc *query.Document
+	var op *query.Operation
+	if s.operationLogger != nil && res == s.res {
+		start := time.Now()
+		requestVariables := variables
+		defer func() {
+			s.logOperation(ctx, doc, op, operationName, requestVariables, time.Since(start), resp)
+		}()
+	}
+
+	entities, _ := ctx.Value(entitySetKey{}).(*entitySet)
+	var cacheKey string
+	cached := func(variables map[string]interface{}) *Response {
+		key, ok := s.responseCache.key(ctx, queryString, operationName, variables)
+		if !ok {
+			return nil
+		}
+		if cached, ok := s.responseCache.get(key, entities); ok {
+			return cached
+		}
+		cacheKey = key
+		return nil
+	}
+	cacheable := s.responseCache != nil && res == s.res
+	if cacheable && len(s.variablesHooks) == 0 && prepared == nil {
+		if resp := cached(variables); resp != nil {
+			return resp
+		}
+	}
+
+	visible := s.visibleFunc(ctx)
+	var warnings, errs []*errors.QueryError
+	if prepared != nil {
+		doc, op, variables = prepared.doc, prepared.op, prepared.variables
+		warnings = append([]*errors.QueryError(nil), prepared.warnings...) // appended to below
+	} else {
+		doc, op, variables, warnings, errs = s.prepare(ctx, queryString, parsed, operationName, variables, visible)
+	}
+	defer func() {
+		if len(warnings) == 0 {
+			return
+		}
+		if resp.Extensions == nil {
+			resp.Extensions = make(map[string]interface{})
+		}
+		resp.Extensions["warnings"] = warnings
+		s.logWarnings(ctx, warnings)
+	}()
 	if len(errs) != 0 {
 		return &Response{Errors: errs}
 	}
-
-	op, err := getOperation(doc, operationName)
-	if err != nil {
-		return &Response{Errors: []*errors.QueryError{errors.Errorf("%s", err)}}
+	if cacheable && (len(s.variablesHooks) != 0 || prepared != nil) {
+		// the hooks may set variables from the context, e.g. the tenant, which the key has to include
+		if resp := cached(variables); resp != nil {
+			return resp
+		}
+	}
+	if s.warnDeprecated {
+		warnings = append(warnings, validation.Deprecations(s.schema, doc, op)...)
+	}
+	if op.Type == query.Subscription {
+		return &Response{Errors: []*errors.QueryError{errors.Errorf("subscriptions are executed with Subscribe")}}
 	}
 
-	r := &exec.Request{
-		Request: selected.Request{
-			Doc:    doc,
-			Vars:   variables,
-			Schema: s.schema,
-		},
-		Limiter: make(chan struct{}, s.maxParallelism),
-		Tracer:  s.tracer,
-		Logger:  s.logger,
+	r := s.newRequest(doc, variables, visible)
+	r.SlowFields = s.slowFields
+	r.WriteHidden = s.injectIdentities && (s.idAlias != "" || s.typenameAlias != "") && !usesResponseName(doc, s.idAlias, s.typenameAlias)
+	if cacheKey != "" && op.Type == query.Query {
+		if entities == nil {
+			entities = newEntitySet()
+		}
+	} else {
+		cacheKey = ""
+	}
+	if entities != nil {
+		r.TouchEntity = entities.touch
+	}
+	if s.operationCache != nil && res == s.res {
+		r.Cache = s.operationCache
+		r.CacheKey = op.Name.Name + "\x00" + queryString
 	}
 	varTypes := make(map[string]*introspection.Type)
 	for _, v := range op.Vars {
@@ -155,14 +1232,190 @@ func (s *Schema) exec(ctx context.Context, queryString string, operationName str
 		}
 		varTypes[v.Name.Name] = introspection.WrapType(t)
 	}
-	traceCtx, finish := s.tracer.TraceQuery(ctx, queryString, operationName, variables, varTypes)
+	traceCtx, finish := s.traceQuery(ctx, queryString, operationName, variables, varTypes)
+	var stats *trace.QueryStats
+	if s.queryStats {
+		stats = s.operationStats(traceCtx, doc, op, variables)
+	}
 	data, errs := r.Execute(traceCtx, res, op)
-	finish(errs)
+	warnings = append(warnings, r.Warnings...)
+	s.finishQuery(traceCtx, finish, errs, len(data))
+	if len(s.responseHooks) != 0 && data != nil {
+		data = s.callResponseHooks(traceCtx, data)
+	}
+	if r.WriteHidden && data != nil {
+		data = stripFields(data, s.idAlias, s.typenameAlias)
+	}
 
-	return &Response{
+	if s.maxIntrospectionSize > 0 && len(data) > s.maxIntrospectionSize && validation.SelectsIntrospection(doc, op) {
+		err := errors.Errorf("introspection response exceeds the limit of %d bytes", s.maxIntrospectionSize)
+		err.Extensions = map[string]interface{}{"code": "INTROSPECTION_TOO_LARGE"}
+		return &Response{Errors: []*errors.QueryError{err}}
+	}
+
+	if cacheKey != "" && len(errs) == 0 && len(r.Warnings) == 0 {
+		s.responseCache.add(cacheKey, data, entities)
+	}
+	resp = &Response{
 		Data:   data,
 		Errors: errs,
 	}
+	if stats != nil {
+		resp.Extensions = map[string]interface{}{"queryStats": stats}
+	}
+	return resp
+}
+
+// traceQuery starts tracing the query. If the tracer panics, the query is not traced.
+func (s *Schema) traceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (traceCtx context.Context, finish trace.TraceQueryFinishFunc) {
+	traceCtx, finish = ctx, func([]*errors.QueryError) {}
+	defer s.recoverCallback(ctx, "tracer")
+	return s.tracer.TraceQuery(ctx, queryString, operationName, variables, varTypes)
+}
+
+// finishQuery finishes tracing the query, see trace.RequestTracer.
+func (s *Schema) finishQuery(ctx context.Context, finish trace.TraceQueryFinishFunc, errs []*errors.QueryError, size int) {
+	defer s.recoverCallback(ctx, "tracer")
+	if t, ok := s.tracer.(trace.RequestTracer); ok {
+		t.TraceRequestDone(ctx, errs, size)
+	}
+	finish(errs)
+}
+
+// logWarnings passes the warnings of a response to the logger, if it is a log.WarningLogger.
+func (s *Schema) logWarnings(ctx context.Context, warnings []*errors.QueryError) {
+	l, ok := s.logger.(log.WarningLogger)
+	if !ok {
+		return
+	}
+	defer s.recoverCallback(ctx, "logger")
+	for _, w := range warnings {
+		l.LogWarning(ctx, w)
+	}
+}
+
+// callResponseHooks returns the data rewritten by the response hooks.
+func (s *Schema) callResponseHooks(ctx context.Context, data []byte) []byte {
+	for _, hook := range s.responseHooks {
+		func() {
+			defer s.recoverCallback(ctx, "response hook")
+			data = hook(ctx, data)
+		}()
+	}
+	return data
+}
+
+// recoverCallback recovers a panic of the component, e.g. the tracer, and logs it, see
+// log.CallbackPanic. It has to be deferred around the call of the component.
+func (s *Schema) recoverCallback(ctx context.Context, component string) {
+	if value := recover(); value != nil {
+		exec.LogCallbackPanic(ctx, s.logger, component, value, debug.Stack())
+	}
+}
+
+// prepare parses and validates the query, unless the parsed document is given, which is validated
+// then, and returns the operation to execute with its variables. The document is nil if the query
+// could not be parsed, the operation if it is not known. The warnings are the errors of the rules
+// demoted to warnings, see WarnOnly.
+func (s *Schema) prepare(ctx context.Context, queryString string, parsed *query.Document, operationName string, variables map[string]interface{}, visible func(typeName, fieldName string) bool) (doc *query.Document, op *query.Operation, vars map[string]interface{}, warnings []*errors.QueryError, errs []*errors.QueryError) {
+	if parsed != nil {
+		doc = parsed
+		errs, warnings = s.validate(doc, visible)
+	} else {
+		doc, warnings, errs = s.parseAndValidate(queryString, visible)
+	}
+	if doc == nil {
+		return nil, nil, nil, nil, s.queryErrors(queryString, errs)
+	}
+	warnings = s.queryErrors(queryString, warnings)
+	if len(errs) != 0 {
+		return doc, nil, nil, warnings, s.queryErrors(queryString, errs)
+	}
+
+	op, err := getOperation(doc, operationName)
+	if err != nil {
+		return doc, nil, nil, warnings, []*errors.QueryError{errors.Errorf("%s", err).WithCode(errors.CodeBadUserInput)}
+	}
+
+	if s.rejectUnknownVars || s.warnRules["NoUnknownVariables"] {
+		if errs := unknownVariables(op, variables); len(errs) != 0 {
+			if !s.warnRules["NoUnknownVariables"] {
+				return doc, op, nil, warnings, withCode(errs, errors.CodeBadUserInput)
+			}
+			warnings = append(warnings, errs...)
+		}
+	}
+
+	if errs := validation.ValidateVariableDepth(op, variables, s.limits); len(errs) != 0 {
+		return doc, op, nil, warnings, s.queryErrors(queryString, withCode(errs, errors.CodeBadUserInput))
+	}
+
+	variables = withVariableDefaults(op, variables)
+	for _, hook := range s.variablesHooks {
+		variables, err = hook(ctx, op.Name.Name, variables)
+		if err != nil {
+			qErr := errors.Errorf("%s", err)
+			qErr.OriginalError = err
+			return doc, op, variables, warnings, []*errors.QueryError{qErr}
+		}
+	}
+	if errs := validation.ValidateVariables(s.schema, op, variables); len(errs) != 0 {
+		return doc, op, variables, warnings, s.queryErrors(queryString, withCode(errs, errors.CodeBadUserInput))
+	}
+	if s.maxComplexity > 0 {
+		if errs := validation.ValidateComplexity(s.schema, doc, op, variables, s.maxComplexity); len(errs) != 0 {
+			return doc, op, variables, warnings, s.queryErrors(queryString, withCode(errs, errors.CodeValidationFailed))
+		}
+	}
+	return doc, op, variables, warnings, nil
+}
+
+// newRequest returns the request executing an operation of the document.
+func (s *Schema) newRequest(doc *query.Document, variables map[string]interface{}, visible func(typeName, fieldName string) bool) *exec.Request {
+	return &exec.Request{
+		Request: selected.Request{
+			Doc:     doc,
+			Vars:    variables,
+			Schema:  s.schema,
+			Visible: visible,
+
+			InjectIdentities: s.injectIdentities,
+			InjectTypenames:  s.injectTypenames,
+			IDAlias:          s.idAlias,
+			TypenameAlias:    s.typenameAlias,
+		},
+		Limiter:      make(chan struct{}, s.maxParallelism),
+		Tracer:       s.tracer,
+		Logger:       s.logger,
+		Breaker:      s.breaker,
+		Auth:         s.auth,
+		PanicHandler: s.panicHandler,
+		ListWorkers:  s.listWorkers,
+		Synchronous:  s.synchronous,
+		NoHTMLEscape: s.noHTMLEscaping,
+
+		StrictScalars:   s.strictScalars,
+		ValidateRawJSON: s.validateRawJSON,
+		Record:          s.record,
+		Replay:          s.replay,
+
+		ResolverCache: s.resolverCache,
+	}
+}
+
+// withVariableDefaults returns a copy of the variables in which missing variables are set to the
+// default values of the operation's variable definitions.
+func withVariableDefaults(op *query.Operation, variables map[string]interface{}) map[string]interface{} {
+	vars := make(map[string]interface{}, len(variables))
+	for name, value := range variables {
+		vars[name] = value
+	}
+	for _, v := range op.Vars {
+		if _, ok := vars[v.Name.Name]; !ok && v.Default != nil {
+			vars[v.Name.Name] = v.Default.Value(nil)
+		}
+	}
+	return vars
 }
 
 func getOperation(document *query.Document, operationName string) (*query.Operation, error) {
@@ -172,16 +1425,29 @@ func getOperation(document *query.Document, operationName string) (*query.Operat
 
 	if operationName == "" {
 		if len(document.Operations) > 1 {
-			return nil, perrors.Errorf("more than one operation in query document and no operation name given")
-		}
-		for _, op := range document.Operations {
-			return op, nil // return the one and only operation
+			return nil, perrors.Errorf("more than one operation in query document and no operation name given, available operations: %s", operationNames(document))
 		}
+		return document.Operations[0], nil
 	}
 
 	op := document.Operations.Get(operationName)
 	if op == nil {
-		return nil, perrors.Errorf("no operation with name %q", operationName)
+		if len(document.Operations) == 1 && document.Operations[0].Name.Name == "" {
+			return nil, perrors.Errorf("no operation with name %q, the query document only contains an anonymous operation", operationName)
+		}
+		return nil, perrors.Errorf("no operation with name %q, available operations: %s", operationName, operationNames(document))
 	}
 	return op, nil
 }
+
+// operationNames returns the quoted names of the named operations of the document for use in error
+// messages.
+func operationNames(document *query.Document) string {
+	var names []string
+	for _, op := range document.Operations {
+		if op.Name.Name != "" {
+			names = append(names, strconv.Quote(op.Name.Name))
+		}
+	}
+	return strings.Join(names, ", ")
+}
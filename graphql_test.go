@@ -1,15 +1,39 @@
 package graphql_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io/ioutil"
+	"iter"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/errors"
 	"github.com/qdentity/graphql-go/example/starwars"
 	"github.com/qdentity/graphql-go/gqltesting"
+	"github.com/qdentity/graphql-go/introspection"
+	"github.com/qdentity/graphql-go/log"
+	"github.com/qdentity/graphql-go/pubsub"
 	"github.com/qdentity/graphql-go/query"
+	"github.com/qdentity/graphql-go/recording"
+	"github.com/qdentity/graphql-go/rewrite"
+	"github.com/qdentity/graphql-go/sdlfmt"
+	"github.com/qdentity/graphql-go/trace"
 )
 
 type helloWorldResolver1 struct{}
@@ -156,7 +180,7 @@ func TestSelectedFields(t *testing.T) {
 			`, &selectedFieldsResolver{
 				assert: func(got []query.SelectedField) {
 					want := []query.SelectedField{
-						{Name: "hello"},
+						{Name: "hello", Alias: "hello"},
 					}
 					if !reflect.DeepEqual(want, got) {
 						t.Errorf("want %#v, got %#v", want, got)
@@ -188,7 +212,7 @@ func TestSelectedFields(t *testing.T) {
 			`, &selectedFieldsResolver{
 				assert: func(got []query.SelectedField) {
 					want := []query.SelectedField{
-						{Name: "hello"},
+						{Name: "hello", Alias: "hello"},
 					}
 					if !reflect.DeepEqual(want, got) {
 						t.Errorf("want %#v, got %#v", want, got)
@@ -220,7 +244,7 @@ func TestSelectedFields(t *testing.T) {
 			`, &selectedFieldsResolver{
 				assert: func(got []query.SelectedField) {
 					want := []query.SelectedField{
-						{Name: "hello"},
+						{Name: "hello", Alias: "hello"},
 					}
 					if !reflect.DeepEqual(want, got) {
 						t.Errorf("want %#v, got %#v", want, got)
@@ -252,7 +276,7 @@ func TestSelectedFields(t *testing.T) {
 			`, &selectedFieldsResolver{
 				assert: func(got []query.SelectedField) {
 					want := []query.SelectedField{
-						{Name: "hello"},
+						{Name: "hello", Alias: "hello"},
 					}
 					if !reflect.DeepEqual(want, got) {
 						t.Errorf("want %#v, got %#v", want, got)
@@ -273,6 +297,90 @@ func TestSelectedFields(t *testing.T) {
 	})
 }
 
+type projectionResolver struct {
+	assert func(p *query.Projection)
+}
+
+func (r *projectionResolver) Author(fields []query.SelectedField) *authorResolver {
+	r.assert(query.Project(fields, nil))
+	return &authorResolver{}
+}
+
+type authorResolver struct{}
+
+func (r *authorResolver) FullName() string { return "Ursula K. Le Guin" }
+
+func (r *authorResolver) Books(args struct {
+	First *int32
+	After *string
+}) []*bookResolver {
+	return []*bookResolver{{}}
+}
+
+type bookResolver struct{}
+
+func (r *bookResolver) Title() string { return "The Dispossessed" }
+
+func TestSelectedFieldsProjection(t *testing.T) {
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: graphql.MustParseSchema(`
+				schema {
+					query: Query
+				}
+				type Query {
+					author: Author!
+				}
+				type Author {
+					fullName: String!
+					books(first: Int, after: String): [Book!]!
+				}
+				type Book {
+					title: String!
+				}
+			`, &projectionResolver{
+				assert: func(got *query.Projection) {
+					first := 2
+					want := &query.Projection{
+						Columns: []string{"full_name"},
+						Relations: []*query.Relation{{
+							Field:      "books",
+							Args:       map[string]interface{}{"first": int32(2)},
+							Projection: &query.Projection{Columns: []string{"title"}},
+						}},
+					}
+					if !reflect.DeepEqual(want, got) {
+						t.Errorf("want %#v, got %#v", want, got)
+					}
+					page, err := query.ParsePage(got.Relation("books").Args)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !reflect.DeepEqual(page, &query.Page{First: &first}) {
+						t.Errorf("unexpected page %#v", page)
+					}
+				},
+			}),
+			Query: `
+				{
+					author {
+						fullName
+						books(first: 2) { title }
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"author": {
+						"fullName": "Ursula K. Le Guin",
+						"books": [{ "title": "The Dispossessed" }]
+					}
+				}
+			`,
+		},
+	})
+}
+
 func TestHelloSnake(t *testing.T) {
 	gqltesting.RunTests(t, []*gqltesting.Test{
 		{
@@ -1915,3 +2023,6161 @@ func TestComposedFragments(t *testing.T) {
 		},
 	})
 }
+
+type timeoutResolver struct{}
+
+func (r *timeoutResolver) Slow(ctx context.Context) (*string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (r *timeoutResolver) Fast(ctx context.Context) (string, error) {
+	return "fast", nil
+}
+
+func TestFieldTimeout(t *testing.T) {
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: graphql.MustParseSchema(`
+				directive @timeout(ms: Int!) on FIELD_DEFINITION
+
+				schema {
+					query: Query
+				}
+
+				type Query {
+					slow: String @timeout(ms: 10)
+					fast: String! @timeout(ms: 1000)
+				}
+			`, &timeoutResolver{}),
+			Query: `
+				{
+					slow
+					fast
+				}
+			`,
+			ExpectedResult: `
+				{
+					"slow": null,
+					"fast": "fast"
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{
+					Message:    "field timed out after 10ms",
+					Path:       []interface{}{"slow"},
+					Extensions: map[string]interface{}{"code": "FIELD_TIMEOUT"},
+				},
+			},
+		},
+	})
+}
+
+type flakyResolver struct {
+	calls int32
+}
+
+func (r *flakyResolver) Flaky() (*string, error) {
+	if atomic.AddInt32(&r.calls, 1) < 3 {
+		return nil, fmt.Errorf("upstream unavailable")
+	}
+	ok := "ok"
+	return &ok, nil
+}
+
+func TestRetryPolicy(t *testing.T) {
+	const schema = `
+		directive @retry(attempts: Int!, backoffMs: Int = 0) on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			flaky: String @retry(attempts: 3, backoffMs: 1)
+		}
+	`
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: graphql.MustParseSchema(schema, &flakyResolver{}),
+			Query: `
+				{
+					flaky
+				}
+			`,
+			ExpectedResult: `
+				{
+					"flaky": "ok"
+				}
+			`,
+		},
+		{
+			Schema: graphql.MustParseSchema(schema, &flakyResolver{},
+				graphql.FieldRetryPolicy("Query.flaky", graphql.RetryPolicy{Attempts: 2})),
+			Query: `
+				{
+					flaky
+				}
+			`,
+			ExpectedResult: `
+				{
+					"flaky": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{
+					Message: "upstream unavailable",
+					Path:    []interface{}{"flaky"},
+				},
+			},
+		},
+	})
+
+	if _, err := graphql.ParseSchema(strings.Replace(schema, "backoffMs: 1", "backoffMs: -1", 1), &flakyResolver{}); err == nil {
+		t.Error("got no error for a negative backoff")
+	}
+	if _, err := graphql.ParseSchema(schema, &flakyResolver{}, graphql.FieldRetryPolicy("Query.flaky", graphql.RetryPolicy{})); err == nil {
+		t.Error("got no error for no attempts")
+	}
+	const interfaceSchema = `
+		schema {
+			query: Query
+		}
+
+		interface Unreliable {
+			flaky: String
+		}
+
+		type Query implements Unreliable {
+			flaky: String
+		}
+	`
+	if _, err := graphql.ParseSchema(interfaceSchema, &flakyResolver{}, graphql.FieldRetryPolicy("Unreliable.flaky", graphql.RetryPolicy{Attempts: 3})); err == nil {
+		t.Error("got no error for the field of an interface")
+	}
+	if _, err := graphql.ParseSchema(interfaceSchema, &flakyResolver{}, graphql.FieldHedgePolicy("Unreliable.flaky", graphql.HedgePolicy{Attempts: 2})); err == nil {
+		t.Error("got no error for the hedge policy of the field of an interface")
+	}
+}
+
+type openBreaker struct {
+	open map[string]bool
+}
+
+func (b *openBreaker) Allow(ctx context.Context, field string) (func(error), bool) {
+	return func(error) {}, !b.open[field]
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: graphql.MustParseSchema(`
+				schema {
+					query: Query
+				}
+
+				type Query {
+					slow: String
+					fast: String!
+				}
+			`, &timeoutResolver{}, graphql.UseCircuitBreaker(&openBreaker{open: map[string]bool{"Query.slow": true}})),
+			Query: `
+				{
+					slow
+					fast
+				}
+			`,
+			ExpectedResult: `
+				{
+					"slow": null,
+					"fast": "fast"
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{
+					Message:    "circuit open for field Query.slow",
+					Path:       []interface{}{"slow"},
+					Extensions: map[string]interface{}{"code": "CIRCUIT_OPEN"},
+				},
+			},
+		},
+	})
+}
+
+type principalKey struct{}
+
+type authResolver struct{}
+
+func (r *authResolver) Public() string {
+	return "public"
+}
+
+func (r *authResolver) Private() *string {
+	s := "private"
+	return &s
+}
+
+func (r *authResolver) Admin() *string {
+	s := "admin"
+	return &s
+}
+
+func TestAuthorization(t *testing.T) {
+	const schema = `
+		directive @auth on FIELD_DEFINITION
+		directive @hasRole(role: [String!]!) on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			public: String!
+			private: String @auth
+			admin: String @hasRole(role: "admin")
+		}
+	`
+	auth := graphql.Authorization{
+		Principal: func(ctx context.Context) interface{} {
+			return ctx.Value(principalKey{})
+		},
+		HasRole: func(principal interface{}, role string) bool {
+			return principal.(string) == role
+		},
+	}
+	silentAuth := auth
+	silentAuth.SilentNull = true
+	silentPublic := silentAuth
+	silentPublic.Policies = map[string][]string{"Query.public": {"admin"}}
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: graphql.MustParseSchema(schema, &authResolver{}, graphql.UseAuthorization(auth)),
+			Query: `
+				{
+					public
+					private
+				}
+			`,
+			ExpectedResult: `
+				{
+					"public": "public",
+					"private": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{
+					Message:    "not authenticated to access field Query.private",
+					Path:       []interface{}{"private"},
+					Extensions: map[string]interface{}{"code": "UNAUTHENTICATED"},
+				},
+			},
+		},
+		{
+			Context: context.WithValue(context.Background(), principalKey{}, "user"),
+			Schema:  graphql.MustParseSchema(schema, &authResolver{}, graphql.UseAuthorization(auth)),
+			Query: `
+				{
+					private
+					admin
+				}
+			`,
+			ExpectedResult: `
+				{
+					"private": "private",
+					"admin": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{
+					Message:    "not authorized to access field Query.admin",
+					Path:       []interface{}{"admin"},
+					Extensions: map[string]interface{}{"code": "FORBIDDEN"},
+				},
+			},
+		},
+		{
+			Context: context.WithValue(context.Background(), principalKey{}, "admin"),
+			Schema:  graphql.MustParseSchema(schema, &authResolver{}, graphql.UseAuthorization(auth)),
+			Query: `
+				{
+					admin
+				}
+			`,
+			ExpectedResult: `
+				{
+					"admin": "admin"
+				}
+			`,
+		},
+		{
+			Context: context.WithValue(context.Background(), principalKey{}, "user"),
+			Schema:  graphql.MustParseSchema(schema, &authResolver{}, graphql.UseAuthorization(silentAuth)),
+			Query: `
+				{
+					admin
+				}
+			`,
+			ExpectedResult: `
+				{
+					"admin": null
+				}
+			`,
+		},
+		{
+			Context: context.WithValue(context.Background(), principalKey{}, "user"),
+			Schema:  graphql.MustParseSchema(schema, &authResolver{}, graphql.UseAuthorization(silentPublic)),
+			Query: `
+				{
+					public
+				}
+			`,
+			ExpectedResult: `null`,
+			ExpectedErrors: []*errors.QueryError{
+				{
+					Message:    "not authorized to access field Query.public",
+					Path:       []interface{}{"public"},
+					Extensions: map[string]interface{}{"code": "FORBIDDEN"},
+				},
+			},
+		},
+	})
+
+	// @hasRole without roles does not fall back to requiring only an authenticated principal
+	for _, roles := range []string{`[]`, `""`, `["admin", ""]`} {
+		invalid := strings.Replace(schema, `@hasRole(role: "admin")`, `@hasRole(role: `+roles+`)`, 1)
+		if _, err := graphql.ParseSchema(invalid, &authResolver{}, graphql.UseAuthorization(auth)); err == nil {
+			t.Errorf("got no error for roles %s", roles)
+		}
+	}
+}
+
+type authNodeResolver struct{}
+
+func (r *authNodeResolver) Node() *authNode {
+	return &authNode{}
+}
+
+type authNode struct{}
+
+func (n *authNode) Secret() *string {
+	s := "secret"
+	return &s
+}
+
+func (n *authNode) ToUser() (*authNode, bool) {
+	return n, true
+}
+
+func TestAuthorizationInterfaces(t *testing.T) {
+	const schema = `
+		directive @hasRole(role: [String!]!) on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			node: Node
+		}
+
+		interface Node {
+			secret: String
+		}
+
+		type User implements Node {
+			secret: String %s
+		}
+	`
+	auth := graphql.Authorization{
+		Principal: func(ctx context.Context) interface{} {
+			return ctx.Value(principalKey{})
+		},
+		HasRole: func(principal interface{}, role string) bool {
+			return principal.(string) == role
+		},
+	}
+	policyAuth := auth
+	policyAuth.Policies = map[string][]string{"Node.secret": {"admin"}}
+
+	for _, tt := range []struct {
+		name  string
+		auth  graphql.Authorization
+		rule  string
+		query string
+	}{
+		{"directive of the object selected on the interface", auth, `@hasRole(role: "admin")`, `{ node { secret } }`},
+		{"directive of the object selected on the object", auth, `@hasRole(role: "admin")`, `{ node { ... on User { secret } } }`},
+		{"policy of the interface selected on the interface", policyAuth, "", `{ node { secret } }`},
+		{"policy of the interface selected on the object", policyAuth, "", `{ node { ... on User { secret } } }`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := graphql.MustParseSchema(fmt.Sprintf(schema, tt.rule), &authNodeResolver{}, graphql.UseAuthorization(tt.auth))
+			for principal, want := range map[string]string{"user": `{"node":{"secret":null}}`, "admin": `{"node":{"secret":"secret"}}`} {
+				ctx := context.WithValue(context.Background(), principalKey{}, principal)
+				res := schema.Exec(ctx, tt.query, "", nil)
+				if string(res.Data) != want {
+					t.Errorf("principal %s: got data %s, want %s", principal, res.Data, want)
+				}
+				if principal == "user" && (len(res.Errors) != 1 || res.Errors[0].Extensions["code"] != "FORBIDDEN") {
+					t.Errorf("principal %s: got errors %v, want one FORBIDDEN error", principal, res.Errors)
+				}
+			}
+		})
+	}
+
+	_, err := graphql.ParseSchema(fmt.Sprintf(schema, `@hasRole(role: "admin")`), &authNodeResolver{}, graphql.UseAuthorization(graphql.Authorization{
+		Policies: map[string][]string{"Node.secret": {"editor"}},
+	}))
+	if err == nil || !strings.Contains(err.Error(), "the auth rules of Node.secret and User.secret differ") {
+		t.Errorf("got error %v for different roles, want one about different auth rules", err)
+	}
+}
+
+type clientKey struct{}
+
+type visibilityResolver struct{}
+
+func (r *visibilityResolver) Hello() string {
+	return "Hello world!"
+}
+
+func (r *visibilityResolver) Secret() string {
+	return "secret"
+}
+
+func (r *visibilityResolver) Beta() *visibilityResolver {
+	return r
+}
+
+func TestVisibilityInterfaces(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		directive @internal on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			node: Node
+		}
+
+		interface Node {
+			secret: String
+		}
+
+		type User implements Node {
+			secret: String @internal
+		}
+	`, &authNodeResolver{}, graphql.HideInternal(func(ctx context.Context) bool {
+		return ctx.Value(clientKey{}) == "internal"
+	}))
+
+	for _, query := range []string{`{ node { secret } }`, `{ node { ... on User { secret } } }`} {
+		res := schema.Exec(context.Background(), query, "", nil)
+		if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, `Cannot query field "secret"`) {
+			t.Errorf("query %s: got errors %v, want the field to be hidden", query, res.Errors)
+		}
+		ctx := context.WithValue(context.Background(), clientKey{}, "internal")
+		res = schema.Exec(ctx, query, "", nil)
+		if want := `{"node":{"secret":"secret"}}`; string(res.Data) != want {
+			t.Errorf("query %s: got data %s, want %s for internal clients", query, res.Data, want)
+		}
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		directive @internal on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello: String!
+			secret: String! @internal
+			beta: Beta!
+		}
+
+		type Beta {
+			hello: String!
+		}
+	`, &visibilityResolver{},
+		graphql.HideInternal(func(ctx context.Context) bool {
+			return ctx.Value(clientKey{}) == "internal"
+		}),
+		graphql.UseVisibility(func(ctx context.Context, typeName, fieldName string) bool {
+			return typeName != "Beta" || ctx.Value(clientKey{}) == "beta"
+		}),
+	)
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					__type(name: "Query") {
+						fields {
+							name
+						}
+					}
+					beta: __type(name: "Beta") {
+						name
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"__type": {
+						"fields": [
+							{"name": "hello"}
+						]
+					},
+					"beta": null
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					secret
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{
+					Message:    `Cannot query field "secret" on type "Query".`,
+					Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+					Locations:  []errors.Location{{Line: 3, Column: 6}},
+				},
+			},
+		},
+		{
+			Context: context.WithValue(context.Background(), clientKey{}, "internal"),
+			Schema:  schema,
+			Query: `
+				{
+					secret
+				}
+			`,
+			ExpectedResult: `
+				{
+					"secret": "secret"
+				}
+			`,
+		},
+		{
+			Context: context.WithValue(context.Background(), clientKey{}, "beta"),
+			Schema:  schema,
+			Query: `
+				{
+					beta {
+						hello
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"beta": {
+						"hello": "Hello world!"
+					}
+				}
+			`,
+		},
+	})
+}
+
+type langResolver struct{}
+
+func (r *langResolver) Hello(args struct {
+	Filter *struct {
+		Text *string
+		Lang *string
+	}
+	Lang *string
+}) string {
+	return "hello"
+}
+
+func TestVisibilityInputValues(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		directive @translate(to: Lang, fallback: String) on FIELD
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello(filter: Filter, lang: Lang): String!
+		}
+
+		input Filter {
+			text: String
+			lang: Lang
+		}
+
+		enum Lang {
+			EN
+			DE
+		}
+	`, &langResolver{}, graphql.UseVisibility(func(ctx context.Context, typeName, fieldName string) bool {
+		return typeName != "Lang"
+	}))
+
+	res := schema.Exec(context.Background(), `
+		{
+			query: __type(name: "Query") { fields { args { name } } }
+			filter: __type(name: "Filter") { inputFields { name } }
+			__schema { directives { name args { name } } }
+		}
+	`, "", nil)
+	if len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	var data struct {
+		Query struct {
+			Fields []struct {
+				Args []struct{ Name string }
+			}
+		}
+		Filter struct {
+			InputFields []struct{ Name string }
+		}
+		Schema struct {
+			Directives []struct {
+				Name string
+				Args []struct{ Name string }
+			}
+		} `json:"__schema"`
+	}
+	if err := json.Unmarshal(res.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if args := data.Query.Fields[0].Args; len(args) != 1 || args[0].Name != "filter" {
+		t.Errorf("got arguments %v of Query.hello, want only filter", args)
+	}
+	if fields := data.Filter.InputFields; len(fields) != 1 || fields[0].Name != "text" {
+		t.Errorf("got input fields %v of Filter, want only text", fields)
+	}
+	for _, d := range data.Schema.Directives {
+		if d.Name == "translate" && (len(d.Args) != 1 || d.Args[0].Name != "fallback") {
+			t.Errorf("got arguments %v of @translate, want only fallback", d.Args)
+		}
+	}
+}
+
+func TestVariablesHook(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			say_hello(full_name: String!): String!
+		}
+	`, &helloSnakeResolver1{},
+		graphql.UseVariablesHook(func(ctx context.Context, operationName string, variables map[string]interface{}) (map[string]interface{}, error) {
+			if operationName == "Forbidden" {
+				return nil, fmt.Errorf("operation %q is not allowed", operationName)
+			}
+			if name, ok := variables["name"].(string); ok && len(name) > 5 {
+				variables["name"] = name[:5]
+			}
+			return variables, nil
+		}),
+	)
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				query Hello($name: String = "Rudolph") {
+					say_hello(full_name: $name)
+				}
+			`,
+			ExpectedResult: `
+				{
+					"say_hello": "Hello Rudol!"
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				query Hello($name: String!) {
+					say_hello(full_name: $name)
+				}
+			`,
+			Variables: map[string]interface{}{"name": "Bob"},
+			ExpectedResult: `
+				{
+					"say_hello": "Hello Bob!"
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				query Forbidden {
+					say_hello(full_name: "Bob")
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{
+					Message: `operation "Forbidden" is not allowed`,
+				},
+			},
+		},
+	})
+}
+
+type defaultsResolver struct{}
+
+type defaultsOptions struct {
+	Upper  bool
+	Suffix *string
+}
+
+func (r *defaultsResolver) Greet(args struct {
+	Name    string
+	Times   int32
+	Options defaultsOptions
+	Tags    []string
+	Nick    *string
+	Count   *int32
+}) string {
+	name := args.Name
+	if args.Options.Upper {
+		name = strings.ToUpper(name)
+	}
+	if args.Options.Suffix != nil {
+		name += *args.Options.Suffix
+	}
+	s := strings.Repeat(name, int(args.Times)) + " " + strings.Join(args.Tags, ",")
+	if args.Nick != nil {
+		s += " nick:" + *args.Nick
+	}
+	if args.Count != nil {
+		s += fmt.Sprintf(" count:%d", *args.Count)
+	}
+	return s
+}
+
+func TestArgumentDefaults(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		input Options {
+			upper: Boolean = false
+			suffix: String = "!"
+		}
+
+		type Query {
+			greet(
+				name: String = "bob",
+				times: Int = 2,
+				options: Options = {upper: true},
+				tags: [String!] = ["a", "b"],
+				nick: String = null,
+				count: Int = 3,
+			): String!
+		}
+	`, &defaultsResolver{})
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					greet
+				}
+			`,
+			ExpectedResult: `
+				{
+					"greet": "BOB!BOB! a,b count:3"
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				query($name: String, $suffix: String, $count: Int) {
+					greet(name: $name, options: {suffix: $suffix}, nick: "bobby", count: $count)
+				}
+			`,
+			Variables: map[string]interface{}{"count": nil},
+			ExpectedResult: `
+				{
+					"greet": "bob!bob! a,b nick:bobby"
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					__type(name: "Query") {
+						fields {
+							args {
+								name
+								defaultValue
+							}
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"__type": {
+						"fields": [
+							{
+								"args": [
+									{"name": "name", "defaultValue": "\"bob\""},
+									{"name": "times", "defaultValue": "2"},
+									{"name": "options", "defaultValue": "{upper: true}"},
+									{"name": "tags", "defaultValue": "[\"a\", \"b\"]"},
+									{"name": "nick", "defaultValue": "null"},
+									{"name": "count", "defaultValue": "3"}
+								]
+							}
+						]
+					}
+				}
+			`,
+		},
+	})
+}
+
+type deadlineResolver struct {
+	cancel context.CancelFunc
+}
+
+func (r *deadlineResolver) Wait(ctx context.Context) (*string, error) {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (r *deadlineResolver) Done() string {
+	return "done"
+}
+
+func TestContextDeadline(t *testing.T) {
+	const schemaString = `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			wait: String
+			done: String!
+		}
+	`
+
+	for _, tt := range []struct {
+		name string
+		ctx  func(r *deadlineResolver) (context.Context, context.CancelFunc)
+		code string
+	}{
+		{
+			name: "deadline",
+			ctx: func(r *deadlineResolver) (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			code: "DEADLINE_EXCEEDED",
+		},
+		{
+			name: "cancel",
+			ctx: func(r *deadlineResolver) (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				r.cancel = cancel
+				return ctx, cancel
+			},
+			code: "CANCELLED",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &deadlineResolver{}
+			ctx, cancel := tt.ctx(r)
+			defer cancel()
+			result := graphql.MustParseSchema(schemaString, r).Exec(ctx, `{ done wait }`, "", nil)
+			if result.Data != nil {
+				t.Errorf("got data %s, want none", result.Data)
+			}
+			if len(result.Errors) != 1 {
+				t.Fatalf("got errors %v, want one", result.Errors)
+			}
+			ext := result.Errors[0].Extensions
+			if ext["code"] != tt.code {
+				t.Errorf("got code %v, want %s", ext["code"], tt.code)
+			}
+			if _, ok := ext["elapsedMs"].(int64); !ok {
+				t.Errorf("got elapsedMs %v, want a number of milliseconds", ext["elapsedMs"])
+			}
+			if !reflect.DeepEqual(ext["inFlight"], []string{"wait"}) {
+				t.Errorf("got fields in flight %v, want [wait]", ext["inFlight"])
+			}
+		})
+	}
+}
+
+type panicResolver struct{}
+
+func (r *panicResolver) Hero() *panicHeroResolver {
+	return &panicHeroResolver{}
+}
+
+type panicHeroResolver struct{}
+
+func (r *panicHeroResolver) Name() string {
+	panic("no name")
+}
+
+type stackLogger struct {
+	path  []interface{}
+	stack []byte
+}
+
+func (l *stackLogger) LogPanic(ctx context.Context, value interface{}) {
+	panic("LogPanicStack should be called instead")
+}
+
+func (l *stackLogger) LogPanicStack(ctx context.Context, value interface{}, path []interface{}, stack []byte) {
+	l.path = path
+	l.stack = stack
+}
+
+func TestPanicStack(t *testing.T) {
+	logger := &stackLogger{}
+	var handled []interface{}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hero: Hero
+		}
+
+		type Hero {
+			name: String!
+		}
+	`, &panicResolver{}, graphql.Logger(logger), graphql.UsePanicHandler(func(ctx context.Context, value interface{}, path []interface{}, stack []byte) {
+		handled = append(handled, value)
+	}))
+
+	result := schema.Exec(context.Background(), `{ hero { name } }`, "", nil)
+	if len(result.Errors) != 1 {
+		t.Fatalf("got errors %v, want one", result.Errors)
+	}
+	if err := result.Errors[0]; err.Message != "internal server error" || err.PanicValue != "no name" {
+		t.Errorf("got error %q with panic value %v", err.Message, err.PanicValue)
+	}
+	if !strings.Contains(string(result.Errors[0].PanicStack), "panicHeroResolver).Name") {
+		t.Errorf("stack of error does not contain the panicking resolver:\n%s", result.Errors[0].PanicStack)
+	}
+
+	if !reflect.DeepEqual(logger.path, []interface{}{"hero", "name"}) {
+		t.Errorf("got path %v, want [hero name]", logger.path)
+	}
+	if !strings.Contains(string(logger.stack), "panicHeroResolver).Name") {
+		t.Errorf("logged stack does not contain the panicking resolver:\n%s", logger.stack)
+	}
+	if !reflect.DeepEqual(handled, []interface{}{"no name"}) {
+		t.Errorf("got handled panics %v, want [no name]", handled)
+	}
+}
+
+type brokenScalar struct{}
+
+func (brokenScalar) ImplementsGraphQLType(name string) bool   { return name == "Broken" }
+func (brokenScalar) UnmarshalGraphQL(input interface{}) error { return nil }
+func (brokenScalar) MarshalJSON() ([]byte, error)             { panic("broken scalar") }
+
+type brokenScalarResolver struct{}
+
+func (r *brokenScalarResolver) Broken(ctx context.Context) *brokenScalar {
+	return &brokenScalar{}
+}
+
+func TestPanicPath(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		scalar Broken
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			broken: Broken
+		}
+	`, &brokenScalarResolver{}, graphql.Logger(&stackLogger{}))
+
+	// the panic of the field's value, after its resolver returned, has the field's path too
+	result := schema.Exec(context.Background(), `{ broken }`, "", nil)
+	if len(result.Errors) != 1 || result.Errors[0].PanicValue != "broken scalar" {
+		t.Fatalf("got errors %v, want the panic of the scalar", result.Errors)
+	}
+	if want := []interface{}{"broken"}; !reflect.DeepEqual(result.Errors[0].Path, want) {
+		t.Errorf("got path %v, want %v", result.Errors[0].Path, want)
+	}
+}
+
+type callbackPanicTracer struct{}
+
+func (callbackPanicTracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, trace.TraceQueryFinishFunc) {
+	panic("query")
+}
+
+func (callbackPanicTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	return ctx, func(*errors.QueryError) {
+		panic("field")
+	}
+}
+
+func (callbackPanicTracer) TraceRequestDone(ctx context.Context, errs []*errors.QueryError, responseSize int) {
+	panic("done")
+}
+
+type callbackLogger struct {
+	mu     sync.Mutex
+	logged []string
+}
+
+func (l *callbackLogger) LogPanic(ctx context.Context, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if p, ok := value.(*log.CallbackPanic); ok {
+		l.logged = append(l.logged, p.String())
+	} else {
+		l.logged = append(l.logged, fmt.Sprint(value))
+	}
+}
+
+func TestCallbackPanics(t *testing.T) {
+	logger := &callbackLogger{}
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.Tracer(callbackPanicTracer{}), graphql.Logger(logger))
+
+	result := schema.Exec(context.Background(), `{ hero { name } }`, "", nil)
+	if len(result.Errors) != 0 || string(result.Data) != `{"hero":{"name":"R2-D2"}}` {
+		t.Fatalf("got data %s and errors %v", result.Data, result.Errors)
+	}
+	sort.Strings(logger.logged)
+	want := []string{"panic in tracer: done", "panic in tracer: field", "panic in tracer: field", "panic in tracer: query"}
+	if !reflect.DeepEqual(logger.logged, want) {
+		t.Errorf("got logged panics %q, want %q", logger.logged, want)
+	}
+
+	logger = &callbackLogger{}
+	schema = graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hero: Hero
+		}
+
+		type Hero {
+			name: String!
+		}
+	`, &panicResolver{}, graphql.Logger(logger), graphql.UsePanicHandler(func(ctx context.Context, value interface{}, path []interface{}, stack []byte) {
+		panic("handler")
+	}))
+	result = schema.Exec(context.Background(), `{ hero { name } }`, "", nil)
+	if len(result.Errors) != 1 || result.Errors[0].PanicValue != "no name" {
+		t.Fatalf("got errors %v, want the panic of the resolver", result.Errors)
+	}
+	want = []string{"no name", "panic in panic handler: handler"}
+	if !reflect.DeepEqual(logger.logged, want) {
+		t.Errorf("got logged panics %q, want %q", logger.logged, want)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hero: Hero
+		}
+
+		type Hero {
+			name: String!
+		}
+	`, &panicResolver{}, graphql.Logger(&callbackLogger{}))
+
+	for query, code := range map[string]string{
+		`{ hero { name }`:      errors.CodeParseFailed,
+		`{ villain { name } }`: errors.CodeValidationFailed,
+		`query A { hero { name } } query B { hero { name } }`: errors.CodeBadUserInput,
+		`{ hero { name } }`: errors.CodeInternal,
+	} {
+		result := schema.Exec(context.Background(), query, "", nil)
+		if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != code {
+			t.Errorf("got errors %v for %q, want one with the code %s", result.Errors, query, code)
+		}
+	}
+}
+
+type nullPropagationResolver struct{}
+
+func (r *nullPropagationResolver) Items() *[]*itemResolver {
+	items := []*itemResolver{{"good"}, {"bad"}}
+	return &items
+}
+
+func (r *nullPropagationResolver) NullableItems() *[]*itemResolver {
+	return r.Items()
+}
+
+func (r *nullPropagationResolver) NilItems() *[]*itemResolver {
+	items := []*itemResolver{{"good"}, nil}
+	return &items
+}
+
+func (r *nullPropagationResolver) Wrapper() *nullPropagationResolver {
+	return r
+}
+
+func (r *nullPropagationResolver) RequiredItems() []*itemResolver {
+	return *r.Items()
+}
+
+type itemResolver struct {
+	name string
+}
+
+func (r *itemResolver) Name() (string, error) {
+	if r.name == "bad" {
+		return "", fmt.Errorf("bad item")
+	}
+	return r.name, nil
+}
+
+func TestNullPropagation(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			items: [Item!]
+			nullableItems: [Item]
+			nilItems: [Item!]
+			wrapper: Wrapper
+		}
+
+		type Wrapper {
+			requiredItems: [Item!]!
+		}
+
+		type Item {
+			name: String!
+		}
+	`, &nullPropagationResolver{})
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					items {
+						name
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"items": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{Message: "bad item", Path: []interface{}{"items", 1, "name"}},
+			},
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					nullableItems {
+						name
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"nullableItems": [{"name": "good"}, null]
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{Message: "bad item", Path: []interface{}{"nullableItems", 1, "name"}},
+			},
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					nilItems {
+						name
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"nilItems": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{Message: `got nil for non-null "Item"`, Path: []interface{}{"nilItems", 1}},
+			},
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					wrapper {
+						requiredItems {
+							name
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"wrapper": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{Message: "bad item", Path: []interface{}{"wrapper", "requiredItems", 1, "name"}},
+			},
+		},
+	})
+}
+
+func TestOperationName(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello: String!
+		}
+	`, &helloWorldResolver1{})
+
+	const multiple = `
+		query A {
+			a: hello
+		}
+
+		query B {
+			b: hello
+		}
+	`
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:        schema,
+			Query:         multiple,
+			OperationName: "B",
+			ExpectedResult: `
+				{
+					"b": "Hello world!"
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query:  multiple,
+			ExpectedErrors: []*errors.QueryError{
+				{Message: `more than one operation in query document and no operation name given, available operations: "A", "B"`, Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"}},
+			},
+		},
+		{
+			Schema:        schema,
+			Query:         multiple,
+			OperationName: "C",
+			ExpectedErrors: []*errors.QueryError{
+				{Message: `no operation with name "C", available operations: "A", "B"`, Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"}},
+			},
+		},
+		{
+			Schema:        schema,
+			Query:         `{ hello }`,
+			OperationName: "C",
+			ExpectedErrors: []*errors.QueryError{
+				{Message: `no operation with name "C", the query document only contains an anonymous operation`, Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"}},
+			},
+		},
+	})
+}
+
+func TestOperationCache(t *testing.T) {
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.OperationCacheSize(2))
+
+	const heroQuery = `
+		query Hero($episode: Episode, $withFriends: Boolean!) {
+			hero(episode: $episode) {
+				name
+				...Friends @include(if: $withFriends)
+			}
+		}
+
+		fragment Friends on Character {
+			friends {
+				name
+			}
+		}
+	`
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:    schema,
+			Query:     heroQuery,
+			Variables: map[string]interface{}{"episode": "EMPIRE", "withFriends": false},
+			ExpectedResult: `
+				{
+					"hero": {
+						"name": "Luke Skywalker"
+					}
+				}
+			`,
+		},
+		{
+			Schema:    schema,
+			Query:     heroQuery,
+			Variables: map[string]interface{}{"episode": "JEDI", "withFriends": false},
+			ExpectedResult: `
+				{
+					"hero": {
+						"name": "R2-D2"
+					}
+				}
+			`,
+		},
+		{
+			Schema:    schema,
+			Query:     heroQuery,
+			Variables: map[string]interface{}{"episode": "EMPIRE", "withFriends": true},
+			ExpectedResult: `
+				{
+					"hero": {
+						"name": "Luke Skywalker",
+						"friends": [
+							{"name": "Han Solo"},
+							{"name": "Leia Organa"},
+							{"name": "C-3PO"},
+							{"name": "R2-D2"}
+						]
+					}
+				}
+			`,
+		},
+		{
+			Schema:    schema,
+			Query:     heroQuery,
+			Variables: map[string]interface{}{"episode": "JEDI", "withFriends": false},
+			ExpectedResult: `
+				{
+					"hero": {
+						"name": "R2-D2"
+					}
+				}
+			`,
+		},
+		{
+			Schema:    schema,
+			Query:     heroQuery,
+			Variables: map[string]interface{}{"episode": "NEWHOPE", "withFriends": true},
+			ExpectedResult: `
+				{
+					"hero": {
+						"name": "R2-D2",
+						"friends": [
+							{"name": "Luke Skywalker"},
+							{"name": "Han Solo"},
+							{"name": "Leia Organa"}
+						]
+					}
+				}
+			`,
+		},
+	})
+}
+
+type explainResolver struct{}
+
+func (r *explainResolver) User(ctx context.Context, args struct{ ID graphql.ID }) *explainUserResolver {
+	panic("Explain must not call resolvers")
+}
+
+type explainUserResolver struct{}
+
+func (r *explainUserResolver) Name() string {
+	panic("Explain must not call resolvers")
+}
+
+func (r *explainUserResolver) Friends(ctx context.Context) []*explainUserResolver {
+	panic("Explain must not call resolvers")
+}
+
+func TestExplain(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		directive @cost(weight: Int!) on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			user(id: ID!): User @cost(weight: 5)
+		}
+
+		type User {
+			name: String!
+			friends: [User!]! @cost(weight: 10)
+		}
+	`, &explainResolver{})
+
+	plan, errs := schema.Explain(context.Background(), `
+		query Friends($withFriends: Boolean!) {
+			user(id: "1") {
+				__typename
+				name
+				friends @include(if: $withFriends) {
+					name
+				}
+			}
+		}
+	`, "", map[string]interface{}{"withFriends": true})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	got, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+		"operation": "query",
+		"name": "Friends",
+		"parallel": true,
+		"fields": [{
+			"alias": "user", "name": "user", "parentType": "Query", "type": "User",
+			"args": {"id": "1"},
+			"resolver": "method", "async": true, "parallel": true, "weight": 5, "cost": 17,
+			"directives": [{"name": "cost", "location": "FIELD_DEFINITION", "args": {"weight": 5}}],
+			"fields": [
+				{
+					"alias": "__typename", "name": "__typename", "parentType": "User", "type": "String!",
+					"resolver": "fixed", "async": false, "parallel": false, "weight": 0, "cost": 0
+				},
+				{
+					"alias": "name", "name": "name", "parentType": "User", "type": "String!",
+					"resolver": "method", "async": false, "parallel": false, "weight": 1, "cost": 1
+				},
+				{
+					"alias": "friends", "name": "friends", "parentType": "User", "type": "[User!]!",
+					"resolver": "method", "async": true, "parallel": false, "weight": 10, "cost": 11,
+					"directives": [
+						{"name": "cost", "location": "FIELD_DEFINITION", "args": {"weight": 10}},
+						{"name": "include", "location": "FIELD", "args": {"if": true}}
+					],
+					"fields": [{
+						"alias": "name", "name": "name", "parentType": "User", "type": "String!",
+						"resolver": "method", "async": false, "parallel": false, "weight": 1, "cost": 1
+					}]
+				}
+			]
+		}],
+		"cost": 17
+	}`
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got plan %s", got)
+	}
+}
+
+type fieldIDTracer struct {
+	trace.NoopTracer
+	mu     sync.Mutex
+	fields []*trace.FieldIdentifier
+}
+
+func (t *fieldIDTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	panic("TraceField called instead of TraceFieldID")
+}
+
+func (t *fieldIDTracer) TraceFieldID(ctx context.Context, field *trace.FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	t.mu.Lock()
+	t.fields = append(t.fields, field)
+	t.mu.Unlock()
+	return ctx, func(*errors.QueryError) {}
+}
+
+func TestTraceFieldID(t *testing.T) {
+	tracer := &fieldIDTracer{}
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.Tracer(tracer))
+
+	for i := 0; i < 2; i++ {
+		if resp := schema.Exec(context.Background(), `{ hero { __typename name } }`, "", nil); len(resp.Errors) != 0 {
+			t.Fatal(resp.Errors)
+		}
+	}
+
+	hero := trace.NewFieldIdentifier("Query", "hero")
+	if hero.Label != "GraphQL field: Query.hero" {
+		t.Errorf("got label %q", hero.Label)
+	}
+	var heroes, typenames int
+	for _, f := range tracer.fields {
+		switch {
+		case f == hero:
+			heroes++
+		case f == trace.NewFieldIdentifier("", "__typename"):
+			typenames++
+		}
+	}
+	if heroes != 2 || typenames != 2 {
+		t.Errorf("got %d traces of the interned hero identifier and %d of __typename, want 2 each", heroes, typenames)
+	}
+}
+
+type listWorkersResolver struct {
+	running, maxRunning int32
+}
+
+func (r *listWorkersResolver) Items() []*listWorkersItem {
+	items := make([]*listWorkersItem, 100)
+	for i := range items {
+		items[i] = &listWorkersItem{r, int32(i)}
+	}
+	return items
+}
+
+type listWorkersItem struct {
+	root *listWorkersResolver
+	id   int32
+}
+
+func (i *listWorkersItem) ID(ctx context.Context) int32 {
+	running := atomic.AddInt32(&i.root.running, 1)
+	defer atomic.AddInt32(&i.root.running, -1)
+	for {
+		max := atomic.LoadInt32(&i.root.maxRunning)
+		if running <= max || atomic.CompareAndSwapInt32(&i.root.maxRunning, max, running) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return i.id
+}
+
+func TestMaxListWorkers(t *testing.T) {
+	resolver := &listWorkersResolver{}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			items: [Item!]!
+		}
+
+		type Item {
+			id: Int!
+		}
+	`, resolver, graphql.MaxParallelism(100), graphql.MaxListWorkers(3))
+
+	resp := schema.Exec(context.Background(), `{ items { id } }`, "", nil)
+	if len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+	var data struct {
+		Items []struct{ ID int32 }
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 100 {
+		t.Fatalf("got %d items, want 100", len(data.Items))
+	}
+	for i, item := range data.Items {
+		if item.ID != int32(i) {
+			t.Fatalf("got item %d at index %d", item.ID, i)
+		}
+	}
+	if resolver.maxRunning > 3 {
+		t.Errorf("got %d resolvers running at once, want at most 3", resolver.maxRunning)
+	}
+}
+
+type names []string
+
+type listBindingResolver struct{}
+
+func (r *listBindingResolver) Named() names {
+	return names{"a", "b"}
+}
+
+func (r *listBindingResolver) Array() [3]int32 {
+	return [3]int32{1, 2, 3}
+}
+
+func (r *listBindingResolver) Nullable() []string {
+	return nil
+}
+
+func (r *listBindingResolver) Mixed() []interface{} {
+	b := "b"
+	return []interface{}{"a", nil, &b}
+}
+
+func (r *listBindingResolver) Invalid() []interface{} {
+	return []interface{}{int32(1), "two"}
+}
+
+func (r *listBindingResolver) Items() [2]*helloWorldResolver1 {
+	return [2]*helloWorldResolver1{{}, {}}
+}
+
+func TestListBinding(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			named: [String!]!
+			array: [Int!]!
+			nullable: [String!]
+			mixed: [String]!
+			invalid: [Int!]
+			items: [Item!]!
+		}
+
+		type Item {
+			hello: String!
+		}
+	`, &listBindingResolver{})
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query:  `{ named array nullable mixed items { hello } }`,
+			ExpectedResult: `
+				{
+					"named": ["a", "b"],
+					"array": [1, 2, 3],
+					"nullable": null,
+					"mixed": ["a", null, "b"],
+					"items": [{"hello": "Hello world!"}, {"hello": "Hello world!"}]
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query:  `{ invalid }`,
+			ExpectedResult: `
+				{
+					"invalid": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{Message: "can not use string as Int", Path: []interface{}{"invalid", 1}},
+			},
+		},
+	})
+}
+
+type mapListResolver struct{}
+
+func (r *mapListResolver) Names() map[string]string {
+	return nil
+}
+
+func TestListBindingError(t *testing.T) {
+	_, err := graphql.ParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			names: [String!]!
+		}
+	`, &mapListResolver{})
+	if err == nil || !strings.Contains(err.Error(), "map[string]string is not a slice, array or iterator") {
+		t.Errorf("got error %v, want binding error", err)
+	}
+}
+
+type positive int32
+
+func (positive) ImplementsGraphQLType(name string) bool {
+	return name == "Positive"
+}
+
+func (p *positive) UnmarshalGraphQL(input interface{}) error {
+	n, ok := input.(int32)
+	if !ok {
+		if f, isFloat := input.(float64); isFloat {
+			n, ok = int32(f), true
+		}
+	}
+	if !ok || n <= 0 {
+		return fmt.Errorf("%v is not positive", input)
+	}
+	*p = positive(n)
+	return nil
+}
+
+type SearchPaging struct {
+	Limit  int32
+	Offset int32
+}
+
+type searchRange struct {
+	Min positive
+	Max *positive
+}
+
+type searchFilter struct {
+	Field string
+	Range *searchRange
+}
+
+type searchInput struct {
+	*SearchPaging
+	Filters *[]*searchFilter
+	Labels  map[string]string
+	Meta    map[string]interface{}
+}
+
+type searchResolver struct{}
+
+func (r *searchResolver) Search(args struct{ Input searchInput }) string {
+	in := args.Input
+	var filters []string
+	if in.Filters != nil {
+		for _, f := range *in.Filters {
+			s := f.Field
+			if f.Range != nil {
+				s += fmt.Sprintf(">=%d", f.Range.Min)
+				if f.Range.Max != nil {
+					s += fmt.Sprintf("<=%d", *f.Range.Max)
+				}
+			}
+			filters = append(filters, s)
+		}
+	}
+	return fmt.Sprintf("limit=%d offset=%d filters=%s labels=%v meta=%v", in.Limit, in.Offset, strings.Join(filters, ","), in.Labels, in.Meta)
+}
+
+func TestInputCoercion(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			search(input: SearchInput!): String!
+		}
+
+		scalar Positive
+
+		input SearchInput {
+			limit: Int = 10
+			offset: Int = 0
+			filters: [Filter]
+			labels: Labels
+			meta: Meta
+		}
+
+		input Filter {
+			field: String!
+			range: Range
+		}
+
+		input Range {
+			min: Positive!
+			max: Positive
+		}
+
+		input Labels {
+			team: String
+			env: String = "prod"
+		}
+
+		input Meta {
+			source: String
+			version: Int
+		}
+	`, &searchResolver{})
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					search(input: {
+						offset: 5
+						filters: [{field: "a"}, {field: "b", range: {min: 1, max: 3}}]
+						labels: {team: "core"}
+						meta: {source: "web"}
+					})
+				}
+			`,
+			ExpectedResult: `
+				{
+					"search": "limit=10 offset=5 filters=a,b>=1<=3 labels=map[env:prod team:core] meta=map[source:web]"
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query:  `{ search(input: {}) }`,
+			ExpectedResult: `
+				{
+					"search": "limit=10 offset=0 filters= labels=map[] meta=map[]"
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query:  `{ search(input: {filters: [{field: "a"}, {field: "b"}, {field: "c", range: {min: -1}}]}) }`,
+			ExpectedResult: `
+				{}
+			`,
+			ExpectedErrors: []*errors.QueryError{
+				{Message: "input.filters[2].range.min: -1 is not positive", Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"}},
+			},
+		},
+	})
+}
+
+func TestVariableValidation(t *testing.T) {
+	const reviewMutation = `mutation($review: ReviewInput!) { createReview(episode: JEDI, review: $review) { stars } }`
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: starwarsSchema,
+			Query:  `query($episode: Episode!) { hero(episode: $episode) { name } }`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Variable "$episode" of required type "Episode!" was not provided.`,
+				Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+				Locations:  []errors.Location{{Line: 1, Column: 7}},
+			}},
+		},
+		{
+			Schema:    starwarsSchema,
+			Query:     `query($episode: Episode) { hero(episode: $episode) { name } }`,
+			Variables: map[string]interface{}{"episode": "MOON"},
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Variable "$episode" got invalid value "MOON"; Expected type "Episode", found "MOON".`,
+				Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+				Locations:  []errors.Location{{Line: 1, Column: 7}},
+			}},
+		},
+		{
+			Schema:    starwarsSchema,
+			Query:     reviewMutation,
+			Variables: map[string]interface{}{"review": map[string]interface{}{"stars": 1.5}},
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Variable "$review" got invalid value {"stars":1.5}; In field "stars": Expected type "Int", found 1.5.`,
+				Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+				Locations:  []errors.Location{{Line: 1, Column: 10}},
+			}},
+		},
+		{
+			Schema:    starwarsSchema,
+			Query:     reviewMutation,
+			Variables: map[string]interface{}{"review": map[string]interface{}{"commentary": "great"}},
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Variable "$review" got invalid value {"commentary":"great"}; In field "stars": Expected "Int!", found null.`,
+				Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+				Locations:  []errors.Location{{Line: 1, Column: 10}},
+			}},
+		},
+		{
+			Schema:    starwarsSchema,
+			Query:     reviewMutation,
+			Variables: map[string]interface{}{"review": map[string]interface{}{"stars": 5.0, "rating": 1.0}},
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Variable "$review" got invalid value {"rating":1,"stars":5}; In field "rating": Unknown field.`,
+				Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+				Locations:  []errors.Location{{Line: 1, Column: 10}},
+			}},
+		},
+		{
+			Schema:    starwarsSchema,
+			Query:     reviewMutation,
+			Variables: map[string]interface{}{"review": map[string]interface{}{"stars": 5.0}},
+			ExpectedResult: `
+				{
+					"createReview": {
+						"stars": 5
+					}
+				}
+			`,
+		},
+	})
+}
+
+func TestSourceSnippets(t *testing.T) {
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.SourceSnippets())
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `{
+	hero {
+		unknown
+	}
+}`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Cannot query field "unknown" on type "Character".`,
+				Locations:  []errors.Location{{Line: 3, Column: 3}},
+				Rule:       "FieldsOnCorrectType",
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED", "snippet": "2 | \thero {\n3 | \t\tunknown\n  | \t\t^\n4 | \t}"},
+			}},
+		},
+		{
+			Schema: schema,
+			Query:  `{ hero { name }`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `syntax error: unexpected "", expecting Ident`,
+				Locations:  []errors.Location{{Line: 1, Column: 16}},
+				Extensions: map[string]interface{}{"code": "GRAPHQL_PARSE_FAILED", "snippet": "1 | { hero { name }\n  |                ^"},
+			}},
+		},
+	})
+}
+
+const httpSchema = `
+	directive @http(url: String!, method: String = "GET", body: String) on FIELD_DEFINITION
+
+	schema {
+		query: Query
+		mutation: Mutation
+	}
+
+	type Query {
+		hello: String!
+		user(id: ID!): User @http(url: "%[1]s/users/{id}")
+		users: [User!]! @http(url: "%[1]s/users")
+		search(name: String!): [User!]! @http(url: "%[1]s/users?name={name}")
+		broken: [User!]! @http(url: "%[1]s/broken")
+	}
+
+	type Mutation {
+		rename(id: ID!, name: String!): User @http(url: "%[1]s/users/{id}", method: "PATCH", body: "{\"name\": {name}}")
+	}
+
+	type User {
+		id: ID!
+		fullName: String!
+		role: Role!
+		age: Int
+	}
+
+	enum Role {
+		ADMIN
+		MEMBER
+	}
+`
+
+type httpResolver struct{}
+
+func (r *httpResolver) Hello() string {
+	return "Hello world!"
+}
+
+func TestHTTPDirective(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/users" && r.URL.Query().Get("name") == "Ada & Grace":
+			fmt.Fprint(w, `[{"id": 1, "full_name": "Ada", "role": "ADMIN"}]`)
+		case r.Method == "GET" && r.URL.Path == "/users":
+			fmt.Fprint(w, `[{"id": 1, "full_name": "Ada", "role": "ADMIN", "age": 36}, {"id": 2, "full_name": "Grace", "role": "MEMBER"}]`)
+		case r.Method == "GET" && r.URL.Path == "/users/1":
+			fmt.Fprint(w, `{"id": "1", "fullName": "Ada", "role": "ADMIN", "age": 36, "email": "ada@example.com"}`)
+		case r.Method == "PATCH" && r.URL.Path == "/users/2":
+			var body struct{ Name string }
+			json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprintf(w, `{"id": "2", "fullName": %q, "role": "MEMBER"}`, body.Name)
+		case r.URL.Path == "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	schema := graphql.MustParseSchema(fmt.Sprintf(httpSchema, srv.URL), &httpResolver{}, graphql.UseHTTPDirective(srv.Client()))
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				query($id: ID!) {
+					hello
+					user(id: $id) {
+						__typename
+						fullName
+						...UserRole
+					}
+					missing: user(id: "3") {
+						id
+					}
+					users {
+						id
+						name: fullName
+						age
+					}
+				}
+
+				fragment UserRole on User {
+					role
+				}
+			`,
+			Variables: map[string]interface{}{"id": "1"},
+			ExpectedResult: `
+				{
+					"hello": "Hello world!",
+					"user": {
+						"__typename": "User",
+						"fullName": "Ada",
+						"role": "ADMIN"
+					},
+					"missing": null,
+					"users": [
+						{
+							"id": "1",
+							"name": "Ada",
+							"age": 36
+						},
+						{
+							"id": "2",
+							"name": "Grace",
+							"age": null
+						}
+					]
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				mutation {
+					rename(id: "2", name: "Grace Hopper") {
+						fullName
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"rename": {
+						"fullName": "Grace Hopper"
+					}
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					broken {
+						id
+					}
+				}
+			`,
+			ExpectedResult: `null`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "endpoint of Query.broken: 502 Bad Gateway",
+				Path:       []interface{}{"broken"},
+				Extensions: map[string]interface{}{"code": "HTTP_ERROR", "status": 502},
+			}},
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					search(name: "Ada & Grace") {
+						fullName
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"search": [
+						{
+							"fullName": "Ada"
+						}
+					]
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					user(id: "..") {
+						id
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"user": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message: `endpoint of Query.user: invalid value ".." for argument "id"`,
+				Path:    []interface{}{"user"},
+			}},
+		},
+	})
+}
+
+func TestHTTPDirectiveErrors(t *testing.T) {
+	for _, schemaString := range []string{
+		`
+			directive @http(url: String!, method: String = "GET", body: String) on FIELD_DEFINITION
+			schema { query: Query }
+			type Query { user(id: ID!): User }
+			type User { name: String! @http(url: "http://localhost/name") }
+		`,
+		`
+			directive @http(url: String!, method: String = "GET", body: String) on FIELD_DEFINITION
+			schema { query: Query }
+			type Query { user(id: ID!): String @http(url: "http://localhost/users/{userId}") }
+		`,
+		`
+			directive @http(url: String!, method: String = "GET", body: String) on FIELD_DEFINITION
+			schema { query: Query }
+			type Query { user(id: ID!): String @http(url: "http://localhost/users/{id}", method: "TRACE") }
+		`,
+	} {
+		if _, err := graphql.ParseSchema(schemaString, &httpResolver{}, graphql.UseHTTPDirective(nil)); err == nil {
+			t.Errorf("expected error for schema %s", schemaString)
+		}
+	}
+}
+
+const mockSchema = `
+	schema {
+		query: Query
+		mutation: Mutation
+	}
+
+	scalar Time
+
+	type Query {
+		hello: String!
+		me: User!
+		search(text: String!): [SearchResult!]!
+	}
+
+	type Mutation {
+		rename(name: String!): User
+	}
+
+	union SearchResult = User | Post
+
+	type User {
+		id: ID!
+		name: String!
+		role: Role!
+		createdAt: Time!
+		posts: [Post!]!
+	}
+
+	type Post {
+		title: String!
+		likes: Int!
+	}
+
+	enum Role {
+		ADMIN
+		MEMBER
+	}
+`
+
+type mockResolver struct{}
+
+func (r *mockResolver) Hello() string {
+	return "Hello world!"
+}
+
+func TestMock(t *testing.T) {
+	schema := graphql.MustParseSchema(mockSchema, &mockResolver{}, graphql.Mock(graphql.MockOptions{
+		Seed: 42,
+		Types: map[string]func(r *rand.Rand) interface{}{
+			"Time": func(r *rand.Rand) interface{} {
+				return time.Date(2020, 1, 1+r.Intn(28), 0, 0, 0, 0, time.UTC)
+			},
+			"Post": func(r *rand.Rand) interface{} {
+				return map[string]interface{}{"title": "Hello"}
+			},
+		},
+		MinListLength: 2,
+		MaxListLength: 4,
+	}))
+
+	queryString := `
+		{
+			hello
+			me {
+				__typename
+				id
+				name
+				alias: name
+				role
+				createdAt
+				posts {
+					title
+					likes
+				}
+			}
+			search(text: "x") {
+				... on User {
+					name
+				}
+				... on Post {
+					title
+				}
+			}
+		}
+	`
+	result := schema.Exec(context.Background(), queryString, "", nil)
+	if len(result.Errors) != 0 {
+		t.Fatal(result.Errors)
+	}
+	var data struct {
+		Hello string
+		Me    struct {
+			Typename  string `json:"__typename"`
+			ID        string
+			Name      string
+			Alias     string
+			Role      string
+			CreatedAt time.Time
+			Posts     []struct {
+				Title string
+				Likes *int
+			}
+		}
+		Search []map[string]string
+	}
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	if data.Hello != "Hello world!" {
+		t.Errorf("the resolver method was not called, got %q", data.Hello)
+	}
+	if data.Me.Typename != "User" || data.Me.ID == "" || data.Me.Name == "" {
+		t.Errorf("unexpected user %+v", data.Me)
+	}
+	if data.Me.Alias != data.Me.Name {
+		t.Errorf("aliases of a field got different values %q and %q", data.Me.Name, data.Me.Alias)
+	}
+	if data.Me.Role != "ADMIN" && data.Me.Role != "MEMBER" {
+		t.Errorf("unexpected role %q", data.Me.Role)
+	}
+	if data.Me.CreatedAt.Year() != 2020 {
+		t.Errorf("unexpected time %s", data.Me.CreatedAt)
+	}
+	if n := len(data.Me.Posts); n < 2 || n > 4 {
+		t.Errorf("unexpected number of posts %d", n)
+	}
+	for _, post := range data.Me.Posts {
+		if post.Title != "Hello" || post.Likes == nil {
+			t.Errorf("unexpected post %+v", post)
+		}
+	}
+	for _, result := range data.Search {
+		if result["name"] == "" && result["title"] == "" {
+			t.Errorf("unexpected search result %v", result)
+		}
+	}
+
+	again := schema.Exec(context.Background(), queryString, "", nil)
+	if string(again.Data) != string(result.Data) {
+		t.Errorf("the data is not deterministic:\n%s\n%s", result.Data, again.Data)
+	}
+
+	mutation := graphql.MustParseSchema(mockSchema, nil, graphql.Mock(graphql.MockOptions{}))
+	renamed := mutation.Exec(context.Background(), `mutation { rename(name: "x") { name } }`, "", nil)
+	if len(renamed.Errors) != 0 || !strings.HasPrefix(string(renamed.Data), `{"rename":{"name":"name `) {
+		t.Errorf("unexpected result %s %v", renamed.Data, renamed.Errors)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	queryString := `
+		query($episode: Episode) {
+			hero(episode: $episode) {
+				__typename
+				name
+				... on Droid {
+					primaryFunction
+				}
+				friends {
+					name
+					... on Human {
+						height(unit: FOOT)
+					}
+				}
+			}
+			empire: hero(episode: EMPIRE) {
+				name
+			}
+		}
+	`
+	variables := map[string]interface{}{"episode": "JEDI"}
+
+	fixture := recording.New()
+	recorder := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.RecordFields(fixture))
+	recorded := recorder.Exec(context.Background(), queryString, "", variables)
+	if len(recorded.Errors) != 0 {
+		t.Fatal(recorded.Errors)
+	}
+
+	var buf bytes.Buffer
+	if err := fixture.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := fixture.Get(`hero(episode:"JEDI").friends.0.height(unit:"FOOT")`); !ok || string(e.Value) != "5.6430448" {
+		t.Errorf("unexpected entry %+v in fixture:\n%s", e, buf.String())
+	}
+	loaded, err := recording.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayer := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.ReplayFields(loaded))
+	replayed := replayer.Exec(context.Background(), queryString, "", variables)
+	if len(replayed.Errors) != 0 {
+		t.Fatal(replayed.Errors)
+	}
+	if string(replayed.Data) != string(recorded.Data) {
+		t.Errorf("replayed data differs:\n%s\n%s", recorded.Data, replayed.Data)
+	}
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: replayer,
+			Query: `
+				{
+					hero(episode: NEWHOPE) {
+						name
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"hero": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `no recorded value for hero(episode:"NEWHOPE")`,
+				Path:       []interface{}{"hero"},
+				Extensions: map[string]interface{}{"code": "NOT_RECORDED"},
+			}},
+		},
+		{
+			// the recorded values are only replayed for the requests allowed to access the fields
+			Schema: graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.ReplayFields(loaded),
+				graphql.UseAuthorization(graphql.Authorization{
+					Principal: func(ctx context.Context) interface{} { return nil },
+					Policies:  map[string][]string{"Query.hero": {"admin"}},
+				})),
+			Query: `
+				{
+					empire: hero(episode: EMPIRE) {
+						name
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"empire": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "not authenticated to access field Query.hero",
+				Path:       []interface{}{"empire"},
+				Extensions: map[string]interface{}{"code": "UNAUTHENTICATED"},
+			}},
+		},
+	})
+}
+
+func TestLimitIntrospection(t *testing.T) {
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.LimitIntrospection(graphql.IntrospectionLimits{
+		MaxOfTypeDepth: 2,
+		MaxDepth:       1,
+	}))
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					__type(name: "Droid") {
+						fields {
+							type {
+								ofType {
+									ofType {
+										name
+									}
+								}
+							}
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"__type": {
+						"fields": [
+							{"type": {"ofType": {"ofType": null}}},
+							{"type": {"ofType": {"ofType": null}}},
+							{"type": {"ofType": {"ofType": null}}},
+							{"type": {"ofType": {"ofType": null}}},
+							{"type": {"ofType": {"ofType": {"name": null}}}},
+							{"type": {"ofType": null}}
+						]
+					}
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					__schema {
+						types {
+							...TypeRef
+						}
+					}
+				}
+
+				fragment TypeRef on __Type {
+					ofType {
+						ofType {
+							ofType {
+								name
+							}
+						}
+					}
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Field "ofType" is nested more than 2 times.`,
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+				Locations:  []errors.Location{{Line: 13, Column: 8}},
+				Rule:       "MaxIntrospectionDepth",
+			}},
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					__type(name: "Droid") {
+						fields {
+							type {
+								fields {
+									name
+								}
+							}
+						}
+					}
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Field "fields" exceeds the maximum introspection depth of 1.`,
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+				Locations:  []errors.Location{{Line: 6, Column: 9}},
+				Rule:       "MaxIntrospectionDepth",
+			}},
+		},
+		{
+			Schema: graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.LimitIntrospection(graphql.IntrospectionLimits{
+				MaxResponseSize: 100,
+			})),
+			Query: `
+				{
+					__schema {
+						types {
+							name
+						}
+					}
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "introspection response exceeds the limit of 100 bytes",
+				Extensions: map[string]interface{}{"code": "INTROSPECTION_TOO_LARGE"},
+			}},
+		},
+	})
+
+	// the limits are for the queries of clients, not for the introspection query of ToJSON
+	limited := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{},
+		graphql.LimitIntrospection(graphql.IntrospectionLimits{MaxOfTypeDepth: 2, MaxDepth: 1, MaxResponseSize: 100}),
+		graphql.MaxDepth(2),
+		graphql.MaxQuerySize(graphql.ParserLimits{MaxBytes: 100}),
+	)
+	got, err := limited.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}).ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("got a different JSON with limits")
+	}
+}
+
+type filter struct {
+	And  *[]*filter
+	Name *string
+}
+
+type filterResolver struct{}
+
+func (r *filterResolver) Count(args struct{ Filter *filter }) int32 {
+	return countFilters(args.Filter)
+}
+
+func countFilters(f *filter) int32 {
+	if f == nil {
+		return 0
+	}
+	n := int32(1)
+	if f.And != nil {
+		for _, g := range *f.And {
+			n += countFilters(g)
+		}
+	}
+	return n
+}
+
+func TestMaxInputDepth(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		input Filter {
+			and: [Filter!]
+			name: String
+		}
+
+		type Query {
+			count(filter: Filter): Int!
+		}
+	`, &filterResolver{}, graphql.MaxInputDepth(5))
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					count(filter: {and: [{and: [{name: "a"}]}]})
+				}
+			`,
+			ExpectedResult: `
+				{
+					"count": 3
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					count(filter: {and: [{and: [{name: "a"}]}]})
+					deeper: count(filter: {and: [{and: [{and: [{name: "a"}]}]}]})
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "Value is nested more than 5 levels deep.",
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+				Locations:  []errors.Location{{Line: 4, Column: 28}},
+				Rule:       "MaxInputDepth",
+			}},
+		},
+		{
+			Schema: schema,
+			Query: `
+				query($filter: Filter) {
+					count(filter: $filter)
+				}
+			`,
+			Variables: map[string]interface{}{
+				"filter": map[string]interface{}{"and": []interface{}{map[string]interface{}{"and": []interface{}{map[string]interface{}{"and": []interface{}{}}}}}},
+			},
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `Variable "$filter" is nested more than 5 levels deep.`,
+				Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+				Locations:  []errors.Location{{Line: 2, Column: 11}},
+				Rule:       "MaxInputDepth",
+			}},
+		},
+	})
+
+	query := "{ count(filter: " + strings.Repeat("{and: [", 600) + strings.Repeat("]}", 600) + ") }"
+	resp := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		input Filter {
+			and: [Filter!]
+		}
+
+		type Query {
+			count(filter: Filter): Int!
+		}
+	`, &filterResolver{}, graphql.MaxInputDepth(0)).Exec(context.Background(), query, "", nil)
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "value nested more than 1000 levels deep") {
+		t.Errorf("got errors %v for a hostile literal, want a syntax error", resp.Errors)
+	}
+}
+
+func TestMaxQuerySize(t *testing.T) {
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.MaxQuerySize(graphql.ParserLimits{
+		MaxBytes:       200,
+		MaxTokens:      20,
+		MaxDefinitions: 2,
+	}))
+
+	tooLarge := map[string]interface{}{"code": "DOCUMENT_TOO_LARGE"}
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					hero { ...Names }
+				}
+
+				fragment Names on Character { name }
+			`,
+			ExpectedResult: `
+				{
+					"hero": {"name": "R2-D2"}
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query:  "{ hero { name } }" + strings.Repeat(" ", 200),
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "document exceeds the limit of 200 bytes",
+				Extensions: tooLarge,
+			}},
+		},
+		{
+			Schema: schema,
+			Query:  `{ hero { name id appearsIn friends { name id appearsIn friends { name id appearsIn friends { name } } } } } }`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "document exceeds the limit of 20 tokens",
+				Locations:  []errors.Location{{Line: 1, Column: 101}},
+				Extensions: tooLarge,
+			}},
+		},
+		{
+			Schema: schema,
+			Query:  `{ a } { b } { c }`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "document exceeds the limit of 2 definitions",
+				Locations:  []errors.Location{{Line: 1, Column: 13}},
+				Extensions: tooLarge,
+			}},
+		},
+	})
+}
+
+func TestMaxSchemaSize(t *testing.T) {
+	limits := graphql.ParserLimits{MaxDefinitions: 3}
+	if _, err := graphql.ParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello: String!
+		}
+	`, nil, graphql.MaxSchemaSize(limits)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := graphql.ParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello: String!
+		}
+
+		type A {
+			a: String!
+		}
+
+		type B {
+			b: String!
+		}
+	`, nil, graphql.MaxSchemaSize(limits))
+	qErr, ok := err.(*errors.QueryError)
+	if !ok || qErr.Extensions["code"] != "DOCUMENT_TOO_LARGE" || qErr.Message != "document exceeds the limit of 3 definitions" {
+		t.Fatalf("got error %v, want the limit of definitions to be exceeded", err)
+	}
+
+	_, err = graphql.ParseSchema(strings.Repeat("type A { a: String! } ", 1000), nil, graphql.MaxSchemaSize(graphql.ParserLimits{MaxBytes: 1024}))
+	if err == nil || err.Error() != "graphql: document exceeds the limit of 1024 bytes" {
+		t.Fatalf("got error %v, want the limit of bytes to be exceeded", err)
+	}
+}
+
+func TestMaxAliasesAndDirectives(t *testing.T) {
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.MaxAliases(3), graphql.MaxDirectives(2))
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					a: hero { name }
+					b: hero { name @include(if: true) }
+					hero { ...Names }
+				}
+
+				fragment Names on Character {
+					n: name
+				}
+			`,
+			ExpectedResult: `
+				{
+					"a": {"name": "R2-D2"},
+					"b": {"name": "R2-D2"},
+					"hero": {"n": "R2-D2"}
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				query Amplified {
+					a: hero { ...Names }
+					b: hero { ...Names }
+				}
+
+				fragment Names on Character {
+					n: name
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "Operation has more than 3 aliases.",
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+				Locations:  []errors.Location{{Line: 2, Column: 5}},
+				Rule:       "MaxAliases",
+			}},
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					hero {
+						name @skip(if: false) @include(if: true)
+						...Names @skip(if: false)
+					}
+				}
+
+				fragment Names on Character {
+					id
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "Operation has more than 2 directives.",
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+				Locations:  []errors.Location{{Line: 2, Column: 5}},
+				Rule:       "MaxDirectives",
+			}},
+		},
+	})
+}
+
+func TestBindings(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			user(id: ID!): User
+			count: Int!
+		}
+
+		type User {
+			name: String!
+			friends: [User!]!
+		}
+	`, &explainResolver{}, graphql.ResolveFieldFunc("Query.count", reflect.TypeOf(int32(0)), func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return int32(0), nil
+	}))
+
+	var got []string
+	for _, b := range schema.Bindings() {
+		got = append(got, fmt.Sprintf("%s.%s %s %s %s(%v) %v ctx=%t err=%t async=%t",
+			b.Type, b.Field, b.ResolverType, b.Resolver, b.Method, b.ArgsType, b.ReturnType, b.HasContext, b.HasError, b.Async))
+	}
+	want := []string{
+		"Query.count *graphql_test.explainResolver func (<nil>) int32 ctx=true err=true async=true",
+		"Query.user *graphql_test.explainResolver method User(struct { ID graphql.ID }) *graphql_test.explainUserResolver ctx=true err=false async=true",
+		"User.friends *graphql_test.explainUserResolver method Friends(<nil>) []*graphql_test.explainUserResolver ctx=true err=false async=true",
+		"User.name *graphql_test.explainUserResolver method Name(<nil>) string ctx=false err=false async=false",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong bindings\nwant: %q\ngot:  %q", want, got)
+	}
+}
+
+type tenantKey struct{}
+
+type tenantResolver struct {
+	tenant string
+}
+
+func (r *tenantResolver) Tenant() string {
+	return r.tenant
+}
+
+func TestResolverConstructor(t *testing.T) {
+	var calls int32
+	constructor := func(ctx context.Context) (*tenantResolver, error) {
+		atomic.AddInt32(&calls, 1)
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		if tenant == "" {
+			return nil, fmt.Errorf("no tenant")
+		}
+		return &tenantResolver{tenant: tenant}, nil
+	}
+	schemaString := `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			tenant: String!
+		}
+	`
+	exec := func(schema *graphql.Schema, tenant string, query string) *graphql.Response {
+		return schema.Exec(context.WithValue(context.Background(), tenantKey{}, tenant), query, "", nil)
+	}
+
+	t.Run("per request", func(t *testing.T) {
+		calls = 0
+		schema := graphql.MustParseSchema(schemaString, constructor)
+		for _, tenant := range []string{"a", "b", "a"} {
+			res := exec(schema, tenant, `{ tenant t2: tenant }`)
+			if want := `{"tenant":"` + tenant + `","t2":"` + tenant + `"}`; len(res.Errors) != 0 || string(res.Data) != want {
+				t.Errorf("got %s %v, want %s", res.Data, res.Errors, want)
+			}
+		}
+		if res := exec(schema, "a", `{ __typename }`); len(res.Errors) != 0 {
+			t.Error(res.Errors)
+		}
+		if calls != 3 {
+			t.Errorf("constructor called %d times, want 3", calls)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		schema := graphql.MustParseSchema(schemaString, constructor)
+		res := exec(schema, "", `{ tenant }`)
+		if string(res.Data) != "null" || len(res.Errors) != 1 || res.Errors[0].Message != "no tenant" {
+			t.Errorf("got %s %v", res.Data, res.Errors)
+		}
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		calls = 0
+		schema := graphql.MustParseSchema(schemaString, constructor, graphql.CacheResolvers(1, func(ctx context.Context) string {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return tenant
+		}))
+		for _, tenant := range []string{"a", "a", "b", "b", "a", ""} {
+			exec(schema, tenant, `{ tenant }`)
+		}
+		if calls != 4 {
+			t.Errorf("constructor called %d times, want 4", calls)
+		}
+	})
+
+	t.Run("invalid constructor", func(t *testing.T) {
+		_, err := graphql.ParseSchema(schemaString, func() *tenantResolver { return nil })
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+type plainResolver struct{}
+
+type plainBook struct {
+	ID      graphql.ID
+	Title   string
+	Tags    []string
+	Author  *plainAuthor
+	Ratings []int32
+	*plainDetails
+}
+
+type plainDetails struct {
+	Pages *int32
+}
+
+type plainAuthor struct {
+	Name string
+}
+
+// Books mixes a type resolved by methods with plain struct fields.
+func (r *plainResolver) Books() []*plainBook {
+	pages := int32(412)
+	return []*plainBook{
+		{ID: "1", Title: "Dune", Tags: []string{"sf"}, Author: &plainAuthor{Name: "Herbert"}, Ratings: []int32{5, 4}, plainDetails: &plainDetails{Pages: &pages}},
+		{ID: "2", Title: "Untitled"},
+	}
+}
+
+func (a *plainAuthor) Initial() string {
+	return a.Name[:1]
+}
+
+func TestStructFields(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			books: [Book!]!
+		}
+
+		type Book {
+			id: ID!
+			title: String!
+			tags: [String!]
+			author: Author
+			ratings: [Int!]
+			pages: Int
+		}
+
+		type Author {
+			name: String!
+			initial: String!
+		}
+	`, &plainResolver{})
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					books {
+						id
+						heading: title
+						tags
+						author {
+							name
+							initial
+						}
+						... on Book {
+							author {
+								name
+							}
+						}
+						ratings
+						pages
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"books": [
+						{
+							"id": "1",
+							"heading": "Dune",
+							"tags": ["sf"],
+							"author": {"name": "Herbert", "initial": "H"},
+							"ratings": [5, 4],
+							"pages": 412
+						},
+						{
+							"id": "2",
+							"heading": "Untitled",
+							"tags": null,
+							"author": null,
+							"ratings": null,
+							"pages": null
+						}
+					]
+				}
+			`,
+		},
+	})
+
+	var got []string
+	for _, b := range schema.Bindings() {
+		if b.Type == "Book" && b.Resolver == "field" {
+			got = append(got, b.Field+":"+b.StructField)
+		}
+	}
+	want := []string{"author:Author", "id:ID", "pages:Pages", "ratings:Ratings", "tags:Tags", "title:Title"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong bindings %q, want %q", got, want)
+	}
+}
+
+const limitsSchema = `
+	directive @depthLimit(max: Int!) on SCHEMA
+	directive @complexity(value: Int!, multipliers: [String!]) on SCHEMA | FIELD_DEFINITION
+
+	schema @depthLimit(max: 3) @complexity(value: 50) {
+		query: Query
+	}
+
+	type Query {
+		users(first: Int = 10, ids: [ID!]): [User!]! @complexity(value: 2, multipliers: ["first", "ids"])
+		me: User
+	}
+
+	type User {
+		name: String!
+		friends(first: Int): [User!]! @complexity(value: 1, multipliers: ["first"])
+	}
+`
+
+type limitsResolver struct{}
+
+func (r *limitsResolver) Users(args struct {
+	First *int32
+	Ids   *[]graphql.ID
+}) []*limitsUser {
+	return []*limitsUser{{}}
+}
+
+func (r *limitsResolver) Me() *limitsUser {
+	return &limitsUser{}
+}
+
+type limitsUser struct{}
+
+func (u *limitsUser) Name() string {
+	return "Alice"
+}
+
+func (u *limitsUser) Friends(args struct{ First *int32 }) []*limitsUser {
+	return nil
+}
+
+func TestDepthAndComplexityLimits(t *testing.T) {
+	schema := graphql.MustParseSchema(limitsSchema, &limitsResolver{})
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					users(first: 2, ids: ["1", "2"]) { name }
+					me { friends { name } }
+				}
+			`,
+			ExpectedResult: `
+				{
+					"users": [{"name": "Alice"}],
+					"me": {"friends": []}
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					users { friends(first: 5) { name } }
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "Operation has a complexity of 120, which exceeds the maximum of 50.",
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+				Locations:  []errors.Location{{Line: 2, Column: 5}},
+				Rule:       "MaxComplexity",
+			}},
+		},
+		{
+			Schema: schema,
+			Query: `
+				query Users($n: Int) {
+					users(first: $n) { name }
+				}
+			`,
+			Variables: map[string]interface{}{"n": 20},
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "Operation has a complexity of 60, which exceeds the maximum of 50.",
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+				Locations:  []errors.Location{{Line: 2, Column: 5}},
+				Rule:       "MaxComplexity",
+			}},
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					me { ...Friends }
+				}
+
+				fragment Friends on User {
+					friends { friends { name } }
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "Operation has fields nested more than 3 levels deep.",
+				Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+				Locations:  []errors.Location{{Line: 2, Column: 5}},
+				Rule:       "MaxDepth",
+			}},
+		},
+		{
+			Schema: graphql.MustParseSchema(limitsSchema, &limitsResolver{}, graphql.MaxDepth(5), graphql.MaxComplexity(200)),
+			Query: `
+				{
+					users { friends(first: 5) { name } }
+					me { friends { friends { name } } }
+				}
+			`,
+			ExpectedResult: `
+				{
+					"users": [{"friends": []}],
+					"me": {"friends": []}
+				}
+			`,
+		},
+	})
+}
+
+type operationRecorder struct {
+	ops []*log.Operation
+}
+
+func (r *operationRecorder) LogOperation(ctx context.Context, op *log.Operation) {
+	r.ops = append(r.ops, op)
+}
+
+type loginResolver struct{}
+
+func (r *loginResolver) Login(args struct {
+	Input struct {
+		User     string
+		Password string
+		Devices  *[]struct {
+			Name  string
+			Token string
+		}
+	}
+	Otp *string
+}) bool {
+	return true
+}
+
+func (r *loginResolver) Hello() string {
+	return "Hello"
+}
+
+func TestLogOperations(t *testing.T) {
+	recorder := &operationRecorder{}
+	schema := graphql.MustParseSchema(`
+		directive @sensitive on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION
+
+		schema {
+			query: Query
+			mutation: Mutation
+		}
+
+		type Query {
+			hello: String!
+		}
+
+		type Mutation {
+			login(input: LoginInput!, otp: String): Boolean!
+		}
+
+		input LoginInput {
+			user: String!
+			password: String! @sensitive
+			devices: [Device!]
+		}
+
+		input Device {
+			name: String!
+			token: String!
+		}
+	`, &loginResolver{}, graphql.LogOperations(recorder, "Mutation.login.otp", "Device.token"))
+
+	vars := map[string]interface{}{
+		"input": map[string]interface{}{
+			"user":     "alice",
+			"password": "secret",
+			"devices":  []interface{}{map[string]interface{}{"name": "phone", "token": "t0k3n"}},
+		},
+		"otp": "123456",
+	}
+	query := `mutation Login($input: LoginInput!, $otp: String) { login(input: $input, otp: $otp) }`
+	if res := schema.Exec(context.Background(), query, "", vars); len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	// the same operation formatted differently
+	schema.Exec(context.Background(), "mutation Login($input: LoginInput!, $otp: String) {\n  login(input: $input, otp: $otp)\n}", "", vars)
+	schema.Exec(context.Background(), `{ hello(`, "", map[string]interface{}{"password": "secret"})
+
+	if len(recorder.ops) != 3 {
+		t.Fatalf("got %d logged operations, want 3", len(recorder.ops))
+	}
+	op := recorder.ops[0]
+	if op.Name != "Login" || op.Type != "mutation" || op.Errors != 0 || op.Fingerprint == "" {
+		t.Errorf("got operation %+v", op)
+	}
+	wantVars := map[string]interface{}{
+		"input": map[string]interface{}{
+			"user":     "alice",
+			"password": log.Redacted,
+			"devices":  []interface{}{map[string]interface{}{"name": "phone", "token": log.Redacted}},
+		},
+		"otp": log.Redacted,
+	}
+	if !reflect.DeepEqual(op.Variables, wantVars) {
+		t.Errorf("got variables %v, want %v", op.Variables, wantVars)
+	}
+	if vars["otp"] != "123456" || vars["input"].(map[string]interface{})["password"] != "secret" {
+		t.Error("the variables of the request were modified")
+	}
+	if recorder.ops[1].Fingerprint != op.Fingerprint {
+		t.Error("fingerprints of the same operation differ")
+	}
+
+	failed := recorder.ops[2]
+	if failed.Errors != 1 || failed.Fingerprint != "" || failed.Variables["password"] != log.Redacted {
+		t.Errorf("got failed operation %+v", failed)
+	}
+
+	// a single object given for a list is coerced to a list of it
+	query = `mutation($token: String!) { login(input: {user: "alice", password: "secret", devices: {name: "phone", token: $token}}) }`
+	if res := schema.Exec(context.Background(), query, "", map[string]interface{}{"token": "t0k3n"}); len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	if got := recorder.ops[3].Variables["token"]; got != log.Redacted {
+		t.Errorf("got token %v in a single object for a list, want it redacted", got)
+	}
+
+	// variables not passed to arguments are redacted
+	query = `query($skip: Boolean!) { hello @skip(if: $skip) }`
+	if res := schema.Exec(context.Background(), query, "", map[string]interface{}{"skip": false, "password": "secret"}); len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	if want := map[string]interface{}{"skip": log.Redacted, "password": log.Redacted}; !reflect.DeepEqual(recorder.ops[4].Variables, want) {
+		t.Errorf("got variables %v, want %v", recorder.ops[4].Variables, want)
+	}
+}
+
+type chatMessage struct {
+	Room string
+	Text string
+}
+
+type chatResolver struct {
+	broadcaster *graphql.Broadcaster
+}
+
+func (r *chatResolver) Hello() string { return "hello" }
+
+func (r *chatResolver) MessageAdded(ctx context.Context) <-chan *chatMessage {
+	return r.broadcaster.Subscribe(ctx, 10).(<-chan *chatMessage)
+}
+
+type chatUserKey struct{}
+
+func TestSubscribe(t *testing.T) {
+	upstream := make(chan *chatMessage)
+	resolver := &chatResolver{broadcaster: graphql.NewBroadcaster(upstream)}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+			subscription: Subscription
+		}
+
+		type Query {
+			hello: String!
+		}
+
+		type Subscription {
+			messageAdded: Message!
+		}
+
+		type Message {
+			room: String!
+			text: String!
+		}
+	`, resolver, graphql.FilterEvents("Subscription.messageAdded", func(ctx context.Context, m *chatMessage) (*chatMessage, bool, error) {
+		if m.Text == "boom" {
+			return nil, false, fmt.Errorf("invalid message")
+		}
+		if m.Room != ctx.Value(chatUserKey{}) && m.Room != "lobby" {
+			return nil, false, nil
+		}
+		return &chatMessage{Room: m.Room, Text: strings.ToUpper(m.Text)}, true, nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	alice := schema.Subscribe(context.WithValue(ctx, chatUserKey{}, "alice"), `subscription { messageAdded { text } }`, "", nil)
+	bob := schema.Subscribe(context.WithValue(ctx, chatUserKey{}, "bob"), `subscription { msg: messageAdded { room text } }`, "", nil)
+
+	go func() {
+		for _, m := range []*chatMessage{{"lobby", "hi all"}, {"alice", "hi alice"}, {"bob", "boom"}, {"bob", "hi bob"}} {
+			upstream <- m
+		}
+		close(upstream)
+	}()
+
+	read := func(responses <-chan *graphql.Response) []string {
+		var got []string
+		for resp := range responses {
+			s := string(resp.Data)
+			for _, err := range resp.Errors {
+				s += " " + err.Message
+			}
+			got = append(got, s)
+		}
+		return got
+	}
+	wantAlice := []string{`{"messageAdded":{"text":"HI ALL"}}`, `{"messageAdded":{"text":"HI ALICE"}}`, `null invalid message`}
+	if got := read(alice); !reflect.DeepEqual(got, wantAlice) {
+		t.Errorf("alice got %q, want %q", got, wantAlice)
+	}
+	wantBob := []string{`{"msg":{"room":"lobby","text":"HI ALL"}}`, `null invalid message`, `{"msg":{"room":"bob","text":"HI BOB"}}`}
+	if got := read(bob); !reflect.DeepEqual(got, wantBob) {
+		t.Errorf("bob got %q, want %q", got, wantBob)
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{`{ hello }`, `{"hello":"hello"}`},
+		{`subscription { messageAdded { text } hello: messageAdded { room } }`, `Anonymous Subscription must select only one top level field.`},
+		{`query { hello } mutation Add { hello }`, `Schema is not configured for mutations.`},
+	} {
+		got := read(schema.Subscribe(context.Background(), tt.query, "", nil))
+		if len(got) != 1 || !strings.Contains(got[0], tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	if res := schema.Exec(context.Background(), `subscription { messageAdded { text } }`, "", nil); len(res.Errors) != 1 {
+		t.Errorf("got errors %v, want an error for executing a subscription", res.Errors)
+	}
+}
+
+func TestSubscribePrepareOnce(t *testing.T) {
+	upstream := make(chan *chatMessage)
+	resolver := &chatResolver{broadcaster: graphql.NewBroadcaster(upstream)}
+	var hookCalls int32
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+			subscription: Subscription
+		}
+
+		type Query {
+			hello: String!
+		}
+
+		type Subscription {
+			messageAdded: Message!
+		}
+
+		type Message {
+			room: String!
+			text: String!
+		}
+	`, resolver,
+		graphql.WarnOnly("NoUnusedVariables"),
+		graphql.UseVariablesHook(func(ctx context.Context, operationName string, variables map[string]interface{}) (map[string]interface{}, error) {
+			atomic.AddInt32(&hookCalls, 1)
+			return variables, nil
+		}),
+	)
+
+	check := func(resp *graphql.Response, want string) {
+		t.Helper()
+		if string(resp.Data) != want || len(resp.Errors) != 0 {
+			t.Errorf("got %s %v, want %s", resp.Data, resp.Errors, want)
+		}
+		if warnings, _ := resp.Extensions["warnings"].([]*errors.QueryError); len(warnings) != 1 {
+			t.Errorf("got warnings %v, want the unused variable", resp.Extensions["warnings"])
+		}
+		if calls := atomic.SwapInt32(&hookCalls, 0); calls != 1 {
+			t.Errorf("got %d calls of the variables hook, want 1", calls)
+		}
+	}
+
+	check(<-schema.Subscribe(context.Background(), `query Hello($unused: String) { hello }`, "", nil), `{"hello":"hello"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	responses := schema.Subscribe(ctx, `subscription Messages($unused: String) { messageAdded { text } }`, "", nil)
+	go func() {
+		upstream <- &chatMessage{Room: "lobby", Text: "hi"}
+		close(upstream)
+	}()
+	check(<-responses, `{"messageAdded":{"text":"hi"}}`)
+}
+
+type liveUser struct {
+	ID   graphql.ID
+	Name string
+}
+
+type liveResolver struct {
+	mu    sync.Mutex
+	users map[graphql.ID]*liveUser
+}
+
+func (r *liveResolver) User(args struct{ ID graphql.ID }) *liveUser {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[args.ID]; ok {
+		return &liveUser{ID: u.ID, Name: u.Name}
+	}
+	return nil
+}
+
+func (r *liveResolver) rename(id graphql.ID, name string) {
+	r.mu.Lock()
+	r.users[id].Name = name
+	r.mu.Unlock()
+}
+
+func TestLiveQueries(t *testing.T) {
+	resolver := &liveResolver{users: map[graphql.ID]*liveUser{"1": {"1", "Alice"}, "2": {"2", "Bob"}}}
+	schema := graphql.MustParseSchema(`
+		directive @live on QUERY
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			user(id: ID!): User
+		}
+
+		type User {
+			id: ID!
+			name: String!
+		}
+	`, resolver, graphql.LiveQueries(graphql.LiveQueryOptions{PubSub: pubsub.New(pubsub.NewMemory())}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	responses := schema.Subscribe(ctx, `query @live { user(id: "1") { id name } }`, "", nil)
+	next := func(want string) {
+		t.Helper()
+		select {
+		case resp := <-responses:
+			if got := string(resp.Data); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("got no response, want %s", want)
+		}
+	}
+	next(`{"user":{"id":"1","name":"Alice"}}`)
+
+	resolver.rename("1", "Alicia")
+	if err := schema.Invalidate(context.Background(), "User:1"); err != nil {
+		t.Fatal(err)
+	}
+	next(`{"user":{"id":"1","name":"Alicia"}}`)
+
+	// neither a user the query did not resolve nor an unchanged result cause a response
+	resolver.rename("2", "Robert")
+	schema.Invalidate(context.Background(), "User:2", "User:1")
+	resolver.rename("1", "Ali")
+	schema.Invalidate(context.Background(), "User")
+	next(`{"user":{"id":"1","name":"Ali"}}`)
+
+	got := <-schema.Subscribe(ctx, `query @live { user(id: "1") { name } }`, "", nil)
+	if string(got.Data) != `{"user":{"name":"Ali"}}` {
+		t.Errorf("got %s for a live query without ids", got.Data)
+	}
+
+	plain := graphql.MustParseSchema(`directive @live on QUERY schema { query: Query } type Query { user(id: ID!): User } type User { id: ID! name: String! }`, resolver)
+	got = <-plain.Subscribe(ctx, `query @live { user(id: "1") { name } }`, "", nil)
+	if len(got.Errors) != 1 || got.Errors[0].Message != "live queries are not enabled" {
+		t.Errorf("got errors %v, want live queries to be disabled", got.Errors)
+	}
+
+	if _, err := graphql.ParseSchema(`directive @live on QUERY schema { query: Query } type Query { user(id: ID!): User } type User { id: ID! name: String! }`, resolver, graphql.LiveQueries(graphql.LiveQueryOptions{})); err == nil || !strings.Contains(err.Error(), "PubSub is required") {
+		t.Errorf("got error %v, want one requiring a PubSub", err)
+	}
+}
+
+type countingLiveResolver struct {
+	liveResolver
+	calls int32
+}
+
+func (r *countingLiveResolver) User(args struct{ ID graphql.ID }) *liveUser {
+	atomic.AddInt32(&r.calls, 1)
+	return r.liveResolver.User(args)
+}
+
+func TestResponseCache(t *testing.T) {
+	resolver := &countingLiveResolver{liveResolver: liveResolver{users: map[graphql.ID]*liveUser{"1": {"1", "Alice"}, "2": {"2", "Bob"}}}}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			user(id: ID!): User
+		}
+
+		type User {
+			id: ID!
+			name: String!
+		}
+	`, resolver, graphql.CacheResponses(graphql.ResponseCacheOptions{Size: 10}))
+
+	query := `query User($id: ID!) { user(id: $id) { id name } }`
+	exec := func(id string, want string, wantCalls int32) {
+		t.Helper()
+		res := schema.Exec(context.Background(), query, "", map[string]interface{}{"id": id})
+		if got := string(res.Data); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+		if calls := atomic.LoadInt32(&resolver.calls); calls != wantCalls {
+			t.Errorf("got %d resolver calls, want %d", calls, wantCalls)
+		}
+	}
+	exec("1", `{"user":{"id":"1","name":"Alice"}}`, 1)
+	exec("1", `{"user":{"id":"1","name":"Alice"}}`, 1)
+	exec("2", `{"user":{"id":"2","name":"Bob"}}`, 2)
+
+	resolver.rename("1", "Alicia")
+	if err := schema.InvalidateEntity(context.Background(), "User", "1"); err != nil {
+		t.Fatal(err)
+	}
+	exec("1", `{"user":{"id":"1","name":"Alicia"}}`, 3)
+	exec("2", `{"user":{"id":"2","name":"Bob"}}`, 3)
+
+	schema.Invalidate(context.Background(), "User")
+	exec("2", `{"user":{"id":"2","name":"Bob"}}`, 4)
+}
+
+type statsTracer struct {
+	trace.NoopTracer
+	stats []trace.QueryStats
+}
+
+func (t *statsTracer) TraceQueryStats(ctx context.Context, stats trace.QueryStats) {
+	t.stats = append(t.stats, stats)
+}
+
+func TestResponseCacheScopeRequired(t *testing.T) {
+	const schemaString = `
+		directive @internal on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello: String!
+			secret: String! @internal
+		}
+	`
+	cache := graphql.CacheResponses(graphql.ResponseCacheOptions{Size: 10})
+	for name, opt := range map[string]graphql.SchemaOpt{
+		"authorization": graphql.UseAuthorization(graphql.Authorization{}),
+		"visibility": graphql.UseVisibility(func(ctx context.Context, typeName, fieldName string) bool {
+			return true
+		}),
+		"internal": graphql.HideInternal(func(ctx context.Context) bool { return false }),
+	} {
+		_, err := graphql.ParseSchema(schemaString, &visibilityResolver{}, cache, opt)
+		if err == nil || !strings.Contains(err.Error(), "Scope is required") {
+			t.Errorf("%s: got error %v, want one requiring a scope", name, err)
+		}
+	}
+
+	scoped := graphql.CacheResponses(graphql.ResponseCacheOptions{Size: 10, Scope: func(ctx context.Context) (string, bool) {
+		client, ok := ctx.Value(clientKey{}).(string)
+		return client, ok
+	}})
+	if _, err := graphql.ParseSchema(schemaString, &visibilityResolver{}, scoped, graphql.HideInternal(func(ctx context.Context) bool { return false })); err != nil {
+		t.Errorf("got error %v with a scope", err)
+	}
+}
+
+type tenantDataResolver struct{}
+
+func (r *tenantDataResolver) Me(args struct{ Tenant string }) string {
+	return "data of " + args.Tenant
+}
+
+func TestResponseCacheTenants(t *testing.T) {
+	const schemaString = `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			me(tenant: String!): String!
+		}
+	`
+	schema := graphql.MustParseSchema(schemaString, &tenantDataResolver{},
+		graphql.CacheResponses(graphql.ResponseCacheOptions{Size: 10}),
+		graphql.UseVariablesHook(func(ctx context.Context, operationName string, variables map[string]interface{}) (map[string]interface{}, error) {
+			variables["tenant"], _ = ctx.Value(tenantKey{}).(string)
+			return variables, nil
+		}),
+	)
+	for _, tenant := range []string{"A", "B", "A"} {
+		ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+		res := schema.Exec(ctx, `query Me($tenant: String = "") { me(tenant: $tenant) }`, "", nil)
+		if want := `{"me":"data of ` + tenant + `"}`; len(res.Errors) != 0 || string(res.Data) != want {
+			t.Errorf("tenant %s: got %s %v, want %s", tenant, res.Data, res.Errors, want)
+		}
+	}
+
+	constructor := func(ctx context.Context) (*tenantResolver, error) {
+		return &tenantResolver{}, nil
+	}
+	_, err := graphql.ParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			tenant: String!
+		}
+	`, constructor, graphql.CacheResponses(graphql.ResponseCacheOptions{Size: 10}))
+	if err == nil || !strings.Contains(err.Error(), "Scope is required") {
+		t.Errorf("got error %v with a constructor, want one requiring a scope", err)
+	}
+}
+
+func TestReportQueryStats(t *testing.T) {
+	tracer := &statsTracer{}
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.ReportQueryStats(), graphql.Tracer(tracer))
+
+	res := schema.Exec(context.Background(), `
+		{
+			hero {
+				...character
+				friends {
+					...character
+				}
+			}
+		}
+
+		fragment character on Character {
+			name
+			... on Droid {
+				primaryFunction
+			}
+			...id
+		}
+
+		fragment id on Character {
+			id
+		}
+	`, "", nil)
+	if len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	got, err := json.Marshal(res.Extensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"queryStats":{"depth":3,"complexity":8,"fields":8,"fragments":2,"fragmentSpreads":4}}`
+	if string(got) != want {
+		t.Errorf("got extensions %s, want %s", got, want)
+	}
+	if len(tracer.stats) != 1 || tracer.stats[0].Fields != 8 {
+		t.Errorf("got traced stats %+v", tracer.stats)
+	}
+}
+
+type concurrencyTracer struct {
+	trace.NoopTracer
+	mu     sync.Mutex
+	fields map[string]trace.FieldConcurrency
+}
+
+func (t *concurrencyTracer) TraceFieldConcurrency(ctx context.Context, field *trace.FieldIdentifier, c trace.FieldConcurrency) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fields[field.FieldName] = c
+}
+
+type slowFieldsResolver struct{}
+
+func (r *slowFieldsResolver) A(ctx context.Context) int32 {
+	time.Sleep(20 * time.Millisecond)
+	return 1
+}
+
+func (r *slowFieldsResolver) B(ctx context.Context) int32 {
+	time.Sleep(20 * time.Millisecond)
+	return 2
+}
+
+func (r *slowFieldsResolver) C() int32 { return 3 }
+
+func TestConcurrencyTracer(t *testing.T) {
+	tracer := &concurrencyTracer{fields: make(map[string]trace.FieldConcurrency)}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+			mutation: Query
+		}
+
+		type Query {
+			a: Int!
+			b: Int!
+			c: Int!
+		}
+	`, &slowFieldsResolver{}, graphql.MaxParallelism(1), graphql.Tracer(tracer))
+
+	res := schema.Exec(context.Background(), `{ a b c }`, "", nil)
+	if len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	if len(tracer.fields) != 2 {
+		t.Fatalf("got traced fields %v, want a and b", tracer.fields)
+	}
+	var waited int
+	for name, c := range tracer.fields {
+		if !c.Parallel || c.Group != 3 || c.Run < 20*time.Millisecond {
+			t.Errorf("got %+v for field %s", c, name)
+		}
+		if c.QueueWait >= 15*time.Millisecond {
+			waited++
+		}
+	}
+	if waited != 1 {
+		t.Errorf("got %d fields waiting for the other, want 1: %v", waited, tracer.fields)
+	}
+
+	tracer.fields = make(map[string]trace.FieldConcurrency)
+	res = schema.Exec(context.Background(), `mutation { a b }`, "", nil)
+	if len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	for _, name := range []string{"a", "b"} {
+		if c := tracer.fields[name]; c.Parallel || c.Group != 1 || c.QueueWait != 0 || c.Run < 20*time.Millisecond {
+			t.Errorf("got %+v for serial field %s", c, name)
+		}
+	}
+}
+
+type mappedScalarResolver struct{}
+
+func (r *mappedScalarResolver) Later(args struct {
+	At       time.Time
+	Duration int32
+}) time.Time {
+	return args.At.Add(time.Duration(args.Duration) * time.Hour)
+}
+
+func (r *mappedScalarResolver) Loopback(args struct{ Addr *net.IP }) *net.IP {
+	if args.Addr == nil || !args.Addr.IsLoopback() {
+		return nil
+	}
+	return args.Addr
+}
+
+func (r *mappedScalarResolver) Times() []interface{} {
+	return []interface{}{time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func TestMapScalar(t *testing.T) {
+	schemaString := `
+		schema {
+			query: Query
+		}
+
+		scalar DateTime
+		scalar IP
+
+		type Query {
+			later(at: DateTime!, duration: Int!): DateTime!
+			loopback(addr: IP): IP
+			times: [DateTime!]!
+		}
+	`
+	schema := graphql.MustParseSchema(schemaString, &mappedScalarResolver{},
+		graphql.MapScalar(reflect.TypeOf(time.Time{}), "DateTime"),
+		graphql.MapScalar(reflect.TypeOf(net.IP{}), "IP"))
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				query($at: DateTime!) {
+					later(at: $at, duration: 1)
+					loopback(addr: "127.0.0.1")
+					other: loopback(addr: "10.0.0.1")
+					times
+				}
+			`,
+			Variables: map[string]interface{}{"at": "2020-01-02T03:04:05Z"},
+			ExpectedResult: `
+				{
+					"later": "2020-01-02T04:04:05Z",
+					"loopback": "127.0.0.1",
+					"other": null,
+					"times": ["2020-01-02T03:04:05Z"]
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					later(at: "yesterday", duration: 1)
+				}
+			`,
+			ExpectedResult: `{}`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    `at: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`,
+				Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+			}},
+		},
+	})
+
+	if _, err := graphql.ParseSchema(schemaString, &mappedScalarResolver{},
+		graphql.MapScalar(reflect.TypeOf(time.Time{}), "DateTime")); err == nil {
+		t.Error("got no error for net.IP without mapping")
+	}
+	if _, err := graphql.ParseSchema(schemaString, &mappedScalarResolver{},
+		graphql.MapScalar(reflect.TypeOf(time.Time{}), "DateTime"),
+		graphql.MapScalar(reflect.TypeOf(net.IP{}), "Query")); err == nil {
+		t.Error("got no error for mapping to an object type")
+	}
+}
+
+type timeZoneKey struct{}
+
+// localTime is written in the time zone of the request.
+type localTime time.Time
+
+func (localTime) ImplementsGraphQLType(name string) bool { return name == "DateTime" }
+
+func (t *localTime) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("wrong type for DateTime: %T", input)
+	}
+	parsed, err := time.Parse(time.RFC3339, s)
+	*t = localTime(parsed)
+	return err
+}
+
+func (t localTime) MarshalGraphQLContext(ctx context.Context) ([]byte, error) {
+	loc, _ := ctx.Value(timeZoneKey{}).(*time.Location)
+	if loc == nil {
+		return nil, fmt.Errorf("no time zone")
+	}
+	return json.Marshal(time.Time(t).In(loc).Format(time.RFC3339))
+}
+
+type localTimeResolver struct{}
+
+func (r *localTimeResolver) Now() *localTime {
+	t := localTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	return &t
+}
+
+func (r *localTimeResolver) Times() []*localTime {
+	return []*localTime{r.Now(), nil}
+}
+
+func TestContextMarshaler(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		scalar DateTime
+
+		type Query {
+			now: DateTime
+			times: [DateTime]!
+		}
+	`, &localTimeResolver{})
+
+	berlin := time.FixedZone("CET", 3600)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  schema,
+			Context: context.WithValue(context.Background(), timeZoneKey{}, berlin),
+			Query: `
+				{
+					now
+					times
+				}
+			`,
+			ExpectedResult: `
+				{
+					"now": "2020-01-02T04:04:05+01:00",
+					"times": ["2020-01-02T04:04:05+01:00", null]
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					now
+				}
+			`,
+			ExpectedResult: `
+				{
+					"now": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message: "could not marshal DateTime: no time zone",
+				Path:    []interface{}{"now"},
+			}},
+		},
+	})
+}
+
+type valueRoot struct {
+	greeting string
+}
+
+func (r *valueRoot) Hello() string { return r.greeting }
+
+func (r valueRoot) Pet() pet { return pet{name: "Rex"} }
+
+func (r valueRoot) MaybePet() pet { return pet{name: "Tom"} }
+
+func (r valueRoot) Pets() []pet { return []pet{{name: "Rex"}, {name: "Tom"}} }
+
+type pet struct {
+	name string
+	Age  int32
+}
+
+func (p *pet) Name() string { return p.name }
+
+func TestReceiverKinds(t *testing.T) {
+	schemaString := `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello: String!
+			pet: Pet!
+			maybePet: Pet
+			pets: [Pet!]!
+		}
+
+		type Pet {
+			name: String!
+			age: Int!
+		}
+	`
+	gqltesting.RunTest(t, &gqltesting.Test{
+		Schema: graphql.MustParseSchema(schemaString, valueRoot{greeting: "hello"}),
+		Query: `
+			{
+				hello
+				pet { name age }
+				maybePet { name }
+				pets { name }
+			}
+		`,
+		ExpectedResult: `
+			{
+				"hello": "hello",
+				"pet": {"name": "Rex", "age": 0},
+				"maybePet": {"name": "Tom"},
+				"pets": [{"name": "Rex"}, {"name": "Tom"}]
+			}
+		`,
+	})
+
+	_, err := graphql.ParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			pet: Pet!
+		}
+
+		type Pet {
+			name: String!
+			owner_name: String!
+		}
+	`, valueRoot{})
+	want := `*graphql_test.pet does not resolve "Pet": missing method for field "owner_name" (expected method OwnerName with receiver graphql_test.pet or *graphql_test.pet, or an exported struct field)`
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+type fieldContextResolver struct{}
+
+func (r *fieldContextResolver) Describe(ctx context.Context, args struct{ Prefix string }) string {
+	info := graphql.FieldContext(ctx)
+	return fmt.Sprintf("%s%v %s %s.%s %v", args.Prefix, info.Path, info.Alias, info.ParentType, info.Name, info.Args)
+}
+
+func (r *fieldContextResolver) Report(ctx context.Context) *reportResolver {
+	return &reportResolver{}
+}
+
+type reportResolver struct{}
+
+func (r *reportResolver) Cheap(ctx context.Context) bool {
+	graphql.FieldContext(ctx).Parent.Cancel()
+	return false
+}
+
+func (r *reportResolver) Expensive(ctx context.Context) string {
+	<-ctx.Done()
+	return "late"
+}
+
+func TestFieldContext(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			describe(prefix: String!): String!
+			report: Report
+		}
+
+		type Report {
+			cheap: Boolean!
+			expensive: String!
+		}
+	`, &fieldContextResolver{})
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					d: describe(prefix: "at ")
+				}
+			`,
+			ExpectedResult: `
+				{
+					"d": "at [d] d Query.describe map[prefix:at ]"
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					report {
+						cheap
+						expensive
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"report": null
+				}
+			`,
+			ExpectedErrors: []*errors.QueryError{{
+				Message:    "field cancelled",
+				Path:       []interface{}{"report"},
+				Extensions: map[string]interface{}{"code": "CANCELLED"},
+			}},
+		},
+	})
+}
+
+type directiveTracer struct {
+	trace.NoopTracer
+	mu         sync.Mutex
+	directives map[string]string
+}
+
+func (t *directiveTracer) TracesFieldContext() bool { return true }
+
+func (t *directiveTracer) TraceFieldID(ctx context.Context, field *trace.FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	var directives []string
+	for _, d := range graphql.FieldContext(ctx).Directives {
+		directives = append(directives, fmt.Sprintf("%s@%s(%v)", d.Location, d.Name, d.Args))
+	}
+	t.mu.Lock()
+	t.directives[field.FieldName] = strings.Join(directives, " ")
+	t.mu.Unlock()
+	return ctx, func(*errors.QueryError) {}
+}
+
+type cachedResolver struct{}
+
+func (r *cachedResolver) Profile() string { return "private" }
+
+func (r *cachedResolver) News() string { return "public" }
+
+func TestFieldDirectives(t *testing.T) {
+	tracer := &directiveTracer{directives: make(map[string]string)}
+	schema := graphql.MustParseSchema(`
+		directive @cacheControl(maxAge: Int = 60, scope: String) on FIELD_DEFINITION | FIELD
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			profile: String! @cacheControl(scope: "PRIVATE")
+			news: String!
+		}
+	`, &cachedResolver{}, graphql.Tracer(tracer))
+
+	gqltesting.RunTest(t, &gqltesting.Test{
+		Schema: schema,
+		Query: `
+			query($maxAge: Int) {
+				profile @cacheControl(maxAge: $maxAge)
+				news @cacheControl(maxAge: 300)
+			}
+		`,
+		Variables: map[string]interface{}{"maxAge": 10},
+		ExpectedResult: `
+			{
+				"profile": "private",
+				"news": "public"
+			}
+		`,
+	})
+
+	want := map[string]string{
+		"profile": "FIELD_DEFINITION@cacheControl(map[maxAge:60 scope:PRIVATE]) FIELD@cacheControl(map[maxAge:10])",
+		"news":    "FIELD@cacheControl(map[maxAge:300])",
+	}
+	if !reflect.DeepEqual(tracer.directives, want) {
+		t.Errorf("got directives %q, want %q", tracer.directives, want)
+	}
+}
+
+type rewritingTracer struct {
+	trace.NoopTracer
+	mu     sync.Mutex
+	fields []string
+	errs   int
+	size   int
+}
+
+func (t *rewritingTracer) TraceFieldRewrite(ctx context.Context, field *trace.FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldRewriteFunc) {
+	return ctx, func(err *errors.QueryError) *errors.QueryError {
+		t.mu.Lock()
+		t.fields = append(t.fields, field.TypeName+"."+field.FieldName)
+		t.mu.Unlock()
+		switch {
+		case err == nil:
+			return nil
+		case strings.Contains(err.Message, "timeout"):
+			err.Extensions = map[string]interface{}{"code": "UPSTREAM_TIMEOUT"}
+			return err
+		case strings.Contains(err.Message, "ignore"):
+			return nil
+		}
+		return err
+	}
+}
+
+func (t *rewritingTracer) TraceRequestDone(ctx context.Context, errs []*errors.QueryError, responseSize int) {
+	t.errs = len(errs)
+	t.size = responseSize
+}
+
+type failingResolver struct{}
+
+func (r *failingResolver) Slow() (*string, error) {
+	return nil, fmt.Errorf("upstream timeout")
+}
+
+func (r *failingResolver) Flaky() (*string, error) {
+	return nil, fmt.Errorf("ignore me")
+}
+
+func (r *failingResolver) Fine() string {
+	return "fine"
+}
+
+func (r *failingResolver) Required() (string, error) {
+	return "", fmt.Errorf("ignore me")
+}
+
+func TestRewritingTracer(t *testing.T) {
+	tracer := &rewritingTracer{}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			slow: String
+			flaky: String
+			fine: String!
+		}
+	`, &failingResolver{}, graphql.Tracer(tracer))
+
+	gqltesting.RunTest(t, &gqltesting.Test{
+		Schema: schema,
+		Query: `
+			{
+				slow
+				flaky
+				fine
+			}
+		`,
+		ExpectedResult: `
+			{
+				"slow": null,
+				"flaky": null,
+				"fine": "fine"
+			}
+		`,
+		ExpectedErrors: []*errors.QueryError{{
+			Message:    "upstream timeout",
+			Path:       []interface{}{"slow"},
+			Extensions: map[string]interface{}{"code": "UPSTREAM_TIMEOUT"},
+		}},
+	})
+	if len(tracer.fields) != 3 {
+		t.Errorf("got traced fields %v, want 3", tracer.fields)
+	}
+	if want := len(`{"slow":null,"flaky":null,"fine":"fine"}`); tracer.errs != 1 || tracer.size != want {
+		t.Errorf("got %d errors and size %d, want 1 and %d", tracer.errs, tracer.size, want)
+	}
+
+	// the error of a non-null field is kept, since its null makes the parent null
+	gqltesting.RunTest(t, &gqltesting.Test{
+		Schema: graphql.MustParseSchema(`
+			schema {
+				query: Query
+			}
+
+			type Query {
+				required: String!
+			}
+		`, &failingResolver{}, graphql.Tracer(&rewritingTracer{})),
+		Query: `
+			{
+				required
+			}
+		`,
+		ExpectedResult: `null`,
+		ExpectedErrors: []*errors.QueryError{{
+			Message: "ignore me",
+			Path:    []interface{}{"required"},
+		}},
+	})
+}
+
+type panickingTracer struct{}
+
+func (panickingTracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, trace.TraceQueryFinishFunc) {
+	panic("broken tracer")
+}
+
+func (panickingTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	return ctx, func(*errors.QueryError) { panic("broken tracer") }
+}
+
+func TestMultiTracer(t *testing.T) {
+	rewriting := &rewritingTracer{}
+	stats := &statsTracer{}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			slow: String
+			flaky: String
+			fine: String!
+		}
+	`, &failingResolver{}, graphql.ReportQueryStats(), graphql.Tracer(trace.Multi(panickingTracer{}, rewriting, stats)))
+
+	res := schema.Exec(context.Background(), `{ slow flaky fine }`, "", nil)
+	if len(res.Errors) != 1 || res.Errors[0].Extensions["code"] != "UPSTREAM_TIMEOUT" {
+		t.Errorf("got errors %v, want the rewritten timeout", res.Errors)
+	}
+	if len(rewriting.fields) != 3 || rewriting.errs != 1 {
+		t.Errorf("got traced fields %v and %d errors", rewriting.fields, rewriting.errs)
+	}
+	if len(stats.stats) != 1 || stats.stats[0].Fields != 3 {
+		t.Errorf("got traced stats %+v", stats.stats)
+	}
+}
+
+func TestWarnOnly(t *testing.T) {
+	query := `
+		query Hero($unused: Int) {
+			hero {
+				name
+			}
+		}
+
+		fragment unused on Character {
+			id
+		}
+	`
+	strict := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.RejectUnknownVariables())
+	res := strict.Exec(context.Background(), query, "", map[string]interface{}{"extra": 1})
+	if len(res.Errors) != 2 || res.Errors[0].Rule != "NoUnusedFragments" || res.Errors[1].Rule != "NoUnusedVariables" {
+		t.Errorf("got errors %v", res.Errors)
+	}
+	res = strict.Exec(context.Background(), `query Hero { hero { name } }`, "", map[string]interface{}{"extra": 1})
+	if len(res.Errors) != 1 || res.Errors[0].Message != `Variable "$extra" is not declared by operation "Hero".` {
+		t.Errorf("got errors %v", res.Errors)
+	}
+
+	lenient := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.WarnOnly("NoUnusedVariables", "NoUnusedFragments", "NoUnknownVariables"))
+	res = lenient.Exec(context.Background(), query, "", map[string]interface{}{"extra": 1})
+	if len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	got, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"data":{"hero":{"name":"R2-D2"}},"extensions":{"warnings":[` +
+		`{"message":"Fragment \"unused\" is never used.","locations":[{"line":8,"column":3}]},` +
+		`{"message":"Variable \"$unused\" is never used in operation \"Hero\".","locations":[{"line":2,"column":14}]},` +
+		`{"message":"Variable \"$extra\" is not declared by operation \"Hero\"."}]}}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if _, err := graphql.ParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.WarnOnly("ScalarLeafs")); err == nil {
+		t.Error("got no error for demoting ScalarLeafs")
+	}
+}
+
+type partialResolver struct{}
+
+func (r *partialResolver) Hello() string { return "hello" }
+
+func (r *partialResolver) Pet() *partialPet { return &partialPet{} }
+
+type partialPet struct{}
+
+func (p *partialPet) Name() string { return "Rex" }
+
+func TestLenientBinding(t *testing.T) {
+	schemaString := `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hello: String!
+			missing: Thing
+			things: [Thing!]!
+			pet: Pet
+		}
+
+		type Thing {
+			name: String!
+		}
+
+		interface Pet {
+			name: String!
+		}
+
+		type Dog implements Pet {
+			name: String!
+		}
+	`
+	if _, err := graphql.ParseSchema(schemaString, &partialResolver{}); err == nil {
+		t.Fatal("got no error for missing resolvers without LenientBinding")
+	}
+
+	logger := &warningLogger{}
+	schema := graphql.MustParseSchema(schemaString, &partialResolver{}, graphql.LenientBinding(), graphql.Logger(logger))
+	if want := []string{"UNBOUND_FIELD", "UNBOUND_FIELD", "UNBOUND_FIELD"}; !reflect.DeepEqual(logger.warnings, want) {
+		t.Errorf("got logged warnings %v, want %v", logger.warnings, want)
+	}
+	gqltesting.RunTest(t, &gqltesting.Test{
+		Schema: schema,
+		Query: `
+			{
+				hello
+				missing { name }
+				pet { name ... on Dog { name } }
+			}
+		`,
+		ExpectedResult: `
+			{
+				"hello": "hello",
+				"missing": null,
+				"pet": {"name": "Rex"}
+			}
+		`,
+		ExpectedErrors: []*errors.QueryError{{
+			Message:    "field Query.missing has no resolver",
+			Path:       []interface{}{"missing"},
+			Extensions: map[string]interface{}{"code": "UNBOUND_FIELD"},
+		}},
+	})
+
+	var unbound []string
+	for _, b := range schema.Bindings() {
+		if b.Resolver == "none" {
+			unbound = append(unbound, b.Type+"."+b.Field)
+		}
+	}
+	if want := []string{"Query.missing", "Query.things"}; !reflect.DeepEqual(unbound, want) {
+		t.Errorf("got unbound fields %v, want %v", unbound, want)
+	}
+}
+
+type queryCountingTracer struct {
+	trace.NoopTracer
+	queries int32
+}
+
+func (t *queryCountingTracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, trace.TraceQueryFinishFunc) {
+	atomic.AddInt32(&t.queries, 1)
+	return t.NoopTracer.TraceQuery(ctx, queryString, operationName, variables, varTypes)
+}
+
+func TestInjectIdentities(t *testing.T) {
+	tracer := &queryCountingTracer{}
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{},
+		graphql.InjectIdentities(),
+		graphql.CacheResponses(graphql.ResponseCacheOptions{Size: 10}),
+		graphql.Tracer(tracer),
+	)
+
+	exec := func(query string, want string, wantQueries int32) {
+		t.Helper()
+		res := schema.Exec(context.Background(), query, "", nil)
+		if len(res.Errors) != 0 {
+			t.Fatal(res.Errors)
+		}
+		if got := string(res.Data); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+		if queries := atomic.LoadInt32(&tracer.queries); queries != wantQueries {
+			t.Errorf("got %d executed queries, want %d", queries, wantQueries)
+		}
+	}
+
+	// the injected fields are not returned, but tag the cached response with the objects
+	query := `{ hero { name friends { name } } }`
+	want := `{"hero":{"name":"R2-D2","friends":[{"name":"Luke Skywalker"},{"name":"Han Solo"},{"name":"Leia Organa"}]}}`
+	exec(query, want, 1)
+	exec(query, want, 1)
+	schema.InvalidateEntity(context.Background(), "Starship", "3000")
+	exec(query, want, 1)
+	schema.InvalidateEntity(context.Background(), "Human", "1002")
+	exec(query, want, 2)
+	schema.InvalidateEntity(context.Background(), "Droid", "2001")
+	exec(query, want, 3)
+
+	// ids and types selected by the query are returned once, also from fragments
+	exec(`{ hero { ... on Droid { id } __typename name } }`, `{"hero":{"id":"2001","__typename":"Droid","name":"R2-D2"}}`, 4)
+	exec(`{ hero { id: name } }`, `{"hero":{"id":"R2-D2"}}`, 5)
+}
+
+func TestInjectTypenames(t *testing.T) {
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.InjectTypenames(), graphql.InjectIdentities())
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					hero {
+						name
+						friends { name }
+					}
+					search(text: "an") {
+						... on Starship { name }
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"hero": {
+						"name": "R2-D2",
+						"friends": [
+							{"name": "Luke Skywalker", "__typename": "Human"},
+							{"name": "Han Solo", "__typename": "Human"},
+							{"name": "Leia Organa", "__typename": "Human"}
+						],
+						"__typename": "Droid"
+					},
+					"search": [
+						{"__typename": "Human"},
+						{"__typename": "Human"},
+						{"name": "TIE Advanced x1", "__typename": "Starship"}
+					]
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					hero {
+						... on Droid { __typename primaryFunction }
+						name
+					}
+					human(id: "1000") { name }
+				}
+			`,
+			ExpectedResult: `
+				{
+					"hero": {"__typename": "Droid", "primaryFunction": "Astromech", "name": "R2-D2"},
+					"human": {"name": "Luke Skywalker"}
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					hero { __typename: name }
+				}
+			`,
+			ExpectedResult: `
+				{
+					"hero": {"__typename": "R2-D2"}
+				}
+			`,
+		},
+	})
+}
+
+type panelResolver struct{}
+
+func (r *panelResolver) Title() string { return "Home" }
+
+func (r *panelResolver) Recommendations() *panel { return &panel{} }
+
+func (r *panelResolver) Ads() ([]string, error) { return nil, fmt.Errorf("ad server down") }
+
+func (r *panelResolver) Critical() (*string, error) { return nil, fmt.Errorf("database down") }
+
+type panel struct{}
+
+func (p *panel) Items() ([]string, error) { return nil, fmt.Errorf("recommender down") }
+
+func (p *panel) Count() int32 { return 3 }
+
+func TestOptionalFields(t *testing.T) {
+	sdl := `
+		directive @optional on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			title: String!
+			recommendations: Panel @optional
+			ads: [String!]
+			critical: String
+		}
+
+		type Panel {
+			items: [String!]!
+			count: Int!
+		}
+	`
+	schema := graphql.MustParseSchema(sdl, &panelResolver{}, graphql.OptionalFields("Query.ads"))
+	res := schema.Exec(context.Background(), `{ title recommendations { count items } ads critical }`, "", nil)
+	if got, want := string(res.Data), `{"title":"Home","recommendations":null,"ads":null,"critical":null}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Message != "database down" {
+		t.Errorf("got errors %v, want only the one of the critical field", res.Errors)
+	}
+	warnings, _ := res.Extensions["warnings"].([]*errors.QueryError)
+	var got []string
+	for _, w := range warnings {
+		got = append(got, fmt.Sprintf("%s at %v", w.Message, w.Path))
+	}
+	sort.Strings(got)
+	if want := []string{"ad server down at [ads]", "recommender down at [recommendations items]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got warnings %q, want %q", got, want)
+	}
+
+	if _, err := graphql.ParseSchema(sdl, &panelResolver{}, graphql.OptionalFields("Query.title")); err == nil {
+		t.Error("got no error for an optional non-null field")
+	}
+	if _, err := graphql.ParseSchema(sdl, &panelResolver{}, graphql.OptionalFields("Query.unknown")); err == nil {
+		t.Error("got no error for an unknown optional field")
+	}
+}
+
+type hedgedResolver struct {
+	calls     int32
+	cancelled chan struct{}
+}
+
+// Price hangs on its first call until its context is cancelled and fails on its second one.
+func (r *hedgedResolver) Price(ctx context.Context) (*int32, error) {
+	price := int32(42)
+	switch atomic.AddInt32(&r.calls, 1) {
+	case 1:
+		<-ctx.Done()
+		close(r.cancelled)
+		return nil, ctx.Err()
+	case 2:
+		return nil, fmt.Errorf("replica down")
+	default:
+		return &price, nil
+	}
+}
+
+func TestHedging(t *testing.T) {
+	sdl := `
+		directive @hedge(delayMs: Int!, attempts: Int) on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			price: Int @hedge(delayMs: 10, attempts: 3)
+		}
+	`
+	resolver := &hedgedResolver{cancelled: make(chan struct{})}
+	schema := graphql.MustParseSchema(sdl, resolver)
+	res := schema.Exec(context.Background(), `{ price }`, "", nil)
+	if len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	if got, want := string(res.Data), `{"price":42}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	select {
+	case <-resolver.cancelled:
+	case <-time.After(time.Second):
+		t.Error("the context of the first call was not cancelled")
+	}
+
+	// a failing call makes the next one without waiting for the delay
+	resolver = &hedgedResolver{calls: 1}
+	schema = graphql.MustParseSchema(sdl, resolver, graphql.FieldHedgePolicy("Query.price", graphql.HedgePolicy{Attempts: 2, Delay: time.Hour}))
+	res = schema.Exec(context.Background(), `{ price }`, "", nil)
+	if got, want := string(res.Data), `{"price":42}`; got != want || len(res.Errors) != 0 {
+		t.Errorf("got %s with errors %v, want %s", got, res.Errors, want)
+	}
+
+	if _, err := graphql.ParseSchema(sdl, resolver, graphql.FieldHedgePolicy("Query.price", graphql.HedgePolicy{Attempts: 1})); err == nil {
+		t.Error("got no error for a single attempt")
+	}
+}
+
+type lateHedgedResolver struct {
+	calls int32
+	done  chan string
+}
+
+// Price hangs on its first call until its context is cancelled, and reads its field context after
+// the request is done.
+func (r *lateHedgedResolver) Price(ctx context.Context) *int32 {
+	price := int32(42)
+	if atomic.AddInt32(&r.calls, 1) == 1 {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		r.done <- graphql.FieldContext(ctx).Alias
+	}
+	return &price
+}
+
+func TestHedgingLoserAfterRequest(t *testing.T) {
+	resolver := &lateHedgedResolver{done: make(chan string, 1)}
+	schema := graphql.MustParseSchema(`
+		directive @hedge(delayMs: Int!, attempts: Int) on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			price: Int @hedge(delayMs: 1)
+		}
+	`, resolver)
+
+	// the selections of the request are not reused by the following ones while the call that lost
+	// still uses them
+	for i := 0; i < 20; i++ {
+		query := fmt.Sprintf(`{ p%d: price }`, i)
+		if res := schema.Exec(context.Background(), query, "", nil); len(res.Errors) != 0 {
+			t.Fatal(res.Errors)
+		}
+	}
+	if alias := <-resolver.done; alias != "p0" {
+		t.Errorf("got alias %q in the call that lost, want %q", alias, "p0")
+	}
+}
+
+func TestPrecompileDocuments(t *testing.T) {
+	docs := []string{
+		`query Hero { hero { name friends { name } } }`,
+		`query Droid($id: ID!) { droid(id: $id) { name } }`,
+	}
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.OperationCacheSize(10))
+	plan, err := schema.PrecompileDocuments(docs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := schema.Exec(context.Background(), docs[1], "", map[string]interface{}{"id": "2001"})
+	if got, want := string(res.Data), `{"droid":{"name":"R2-D2"}}`; got != want || len(res.Errors) != 0 {
+		t.Errorf("got %s with errors %v, want %s", got, res.Errors, want)
+	}
+	res = schema.Exec(context.Background(), docs[1], "", nil)
+	if len(res.Errors) != 1 {
+		t.Errorf("got errors %v, want the variables to be validated", res.Errors)
+	}
+
+	// a plan is reused by schemas validating documents the same way
+	again, err := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}).PrecompileDocuments(docs, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(plan) {
+		t.Errorf("got plan %s, want %s", again, plan)
+	}
+
+	limited := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.MaxDepth(2))
+	limitedPlan, err := limited.PrecompileDocuments(docs, plan)
+	if err == nil || !strings.Contains(err.Error(), "query Hero") {
+		t.Errorf("got error %v, want the deep document to be validated again", err)
+	}
+	if string(limitedPlan) == string(plan) {
+		t.Error("got the plan of a schema with other limits")
+	}
+	res = limited.Exec(context.Background(), docs[1], "", map[string]interface{}{"id": "2001"})
+	if got, want := string(res.Data), `{"droid":{"name":"R2-D2"}}`; got != want || len(res.Errors) != 0 {
+		t.Errorf("got %s with errors %v, want %s", got, res.Errors, want)
+	}
+
+	if _, err := schema.PrecompileDocuments(docs, []byte("{")); err == nil {
+		t.Error("got no error for an invalid plan")
+	}
+}
+
+func TestBindConcurrency(t *testing.T) {
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{}, graphql.BindConcurrency(8))
+	query := `{ hero { name friends { name ... on Human { starships { name } } } } search(text: "an") { __typename } }`
+	got := schema.Exec(context.Background(), query, "", nil)
+	want := starwarsSchema.Exec(context.Background(), query, "", nil)
+	if string(got.Data) != string(want.Data) || len(got.Errors) != 0 {
+		t.Errorf("got %s with errors %v, want %s", got.Data, got.Errors, want.Data)
+	}
+
+	_, wantErr := graphql.ParseSchema(starwars.Schema, &partialResolver{})
+	_, err := graphql.ParseSchema(starwars.Schema, &partialResolver{}, graphql.BindConcurrency(8))
+	if err == nil || wantErr == nil || err.Error() != wantErr.Error() {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+}
+
+func FuzzLiterals(f *testing.F) {
+	gqltesting.Fuzz(f, starwarsSchema,
+		`{ search(text: "R2 \u{1F916} 😀 \"quoted\" \/ \n") { __typename } }`,
+		"{ search(text: \"\"\"\n    block\n      \\\"\"\" string\n  \"\"\") { __typename } }",
+		`{ human(id: "1000") { height(unit: METER) } }`,
+		`mutation { createReview(episode: JEDI, review: {stars: 5, commentary: "nested [{}]"}) { stars } }`,
+		`query($first: Int = -1) { hero { friendsConnection(first: $first) { totalCount } } }`,
+		`{ human(id: 1.5e-3) { name } }`,
+	)
+}
+
+func TestExecAST(t *testing.T) {
+	doc, err := rewrite.Parse(`query Hero { hero { nickname: name } } query Other { hero { id } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Walk(func(f *rewrite.Field) error {
+		switch f.Name() {
+		case "name":
+			f.SetAlias("name")
+		case "hero":
+			if !f.Selects("id") {
+				return f.AddSelections("id")
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	res := starwarsSchema.ExecAST(context.Background(), doc, "Hero", nil)
+	if got, want := string(res.Data), `{"hero":{"name":"R2-D2","id":"2001"}}`; got != want || len(res.Errors) != 0 {
+		t.Errorf("got %s with errors %v, want %s", got, res.Errors, want)
+	}
+	res = starwarsSchema.ExecAST(context.Background(), doc, "", nil)
+	if len(res.Errors) != 1 {
+		t.Errorf("got errors %v, want an error for the ambiguous operation", res.Errors)
+	}
+
+	// the transformed document is validated
+	if err := doc.Walk(func(f *rewrite.Field) error {
+		if f.Name() == "hero" {
+			return f.AddSelections("bogus")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	res = starwarsSchema.ExecAST(context.Background(), doc, "Other", nil)
+	if len(res.Errors) == 0 || res.Errors[0].Rule != "FieldsOnCorrectType" {
+		t.Errorf("got errors %v, want an error for the unknown field", res.Errors)
+	}
+}
+
+var (
+	storeTenantKey = graphql.NewKey[string]("tenant")
+	hooksKey       = graphql.NewKey[int]("hooks")
+)
+
+type storeResolver struct{}
+
+func (r *storeResolver) Greeting(ctx context.Context) string {
+	tenant, _ := graphql.Get(ctx, storeTenantKey)
+	hooks, _ := graphql.Get(ctx, hooksKey)
+	return fmt.Sprintf("hello %s after %d hooks", tenant, hooks)
+}
+
+func TestRequestStore(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			greeting: String!
+		}
+	`, &storeResolver{}, graphql.UseVariablesHook(func(ctx context.Context, operationName string, variables map[string]interface{}) (map[string]interface{}, error) {
+		hooks, _ := graphql.Get(ctx, hooksKey)
+		graphql.Set(ctx, hooksKey, hooks+1)
+		return variables, nil
+	}))
+
+	result := schema.Exec(context.Background(), `{ greeting }`, "", nil)
+	if string(result.Data) != `{"greeting":"hello  after 1 hooks"}` {
+		t.Errorf("got %s, want the value of the hook", result.Data)
+	}
+
+	ctx := graphql.WithRequestStore(context.Background())
+	graphql.Set(ctx, storeTenantKey, "acme")
+	result = schema.Exec(ctx, `{ greeting }`, "", nil)
+	if string(result.Data) != `{"greeting":"hello acme after 1 hooks"}` {
+		t.Errorf("got %s, want the values set before the request", result.Data)
+	}
+
+	if _, ok := graphql.Get(context.Background(), storeTenantKey); ok {
+		t.Error("got a value without a request store")
+	}
+}
+
+type book struct{ title string }
+
+func (b *book) ID() graphql.ID { return graphql.ID("book:" + b.title) }
+func (b *book) Title() string  { return b.title }
+func (b *book) Pages() int32   { return 320 }
+
+type movie struct{ title string }
+
+func (m *movie) ID() graphql.ID { return graphql.ID("movie:" + m.title) }
+func (m *movie) Title() string  { return m.title }
+
+type node interface {
+	ID() graphql.ID
+}
+
+type mediaResolver struct{}
+
+func (r *mediaResolver) Search() []interface{} {
+	return []interface{}{&book{"Dune"}, &movie{"Alien"}}
+}
+
+func (r *mediaResolver) Node() node {
+	return &movie{"Heat"}
+}
+
+func mediaTypeOf(value interface{}) (string, interface{}) {
+	switch value := value.(type) {
+	case *book:
+		return "Book", value
+	case *movie:
+		return "Movie", value
+	}
+	return "", nil
+}
+
+func TestResolveTypes(t *testing.T) {
+	sdl := `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			search: [SearchResult!]!
+			node: Node
+		}
+
+		interface Node {
+			id: ID!
+		}
+
+		union SearchResult = Book | Movie
+
+		type Book implements Node {
+			id: ID!
+			title: String!
+			pages: Int!
+		}
+
+		type Movie implements Node {
+			id: ID!
+			title: String!
+		}
+	`
+	resolverTypes := map[string]reflect.Type{
+		"Book":  reflect.TypeOf(&book{}),
+		"Movie": reflect.TypeOf(&movie{}),
+	}
+	schema := graphql.MustParseSchema(sdl, &mediaResolver{},
+		graphql.ResolveTypes("SearchResult", mediaTypeOf, resolverTypes),
+		graphql.ResolveTypes("Node", mediaTypeOf, resolverTypes),
+	)
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					search {
+						__typename
+						... on Book { title pages }
+						... on Movie { title }
+					}
+					node {
+						id
+						... on Movie { title }
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"search": [
+						{"__typename": "Book", "title": "Dune", "pages": 320},
+						{"__typename": "Movie", "title": "Alien"}
+					],
+					"node": {"id": "movie:Heat", "title": "Heat"}
+				}
+			`,
+		},
+	})
+
+	_, err := graphql.ParseSchema(sdl, &mediaResolver{}, graphql.ResolveTypes("SearchResult", mediaTypeOf, map[string]reflect.Type{
+		"Book": reflect.TypeOf(&book{}),
+	}))
+	if err == nil || err.Error() != `type resolver: "SearchResult" has no resolver type for "Movie"` {
+		t.Errorf("got error %v, want the missing resolver type", err)
+	}
+
+	schema = graphql.MustParseSchema(sdl, &mediaResolver{},
+		graphql.ResolveTypes("SearchResult", func(value interface{}) (string, interface{}) {
+			return "Book", value
+		}, resolverTypes),
+		graphql.ResolveTypes("Node", mediaTypeOf, resolverTypes),
+		graphql.Logger(&callbackLogger{}),
+	)
+	result := schema.Exec(context.Background(), `{ search { ... on Book { title } } }`, "", nil)
+	if len(result.Errors) != 1 || !strings.Contains(fmt.Sprint(result.Errors[0].PanicValue), `type resolver returned *graphql_test.movie for "Book", want *graphql_test.book`) {
+		t.Errorf("got errors %v, want the mismatched resolver type", result.Errors)
+	}
+}
+
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCompareSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.golden")
+	gqltesting.CompareSnapshot(t, graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		# The root type.
+		type Query {
+			hero: Hero
+			version: String!
+		}
+
+		type Hero {
+			name: String!
+		}
+	`, nil), path)
+	golden, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(golden), "schema {\n  query: Query\n}\n\ntype Hero {") {
+		t.Errorf("snapshot does not start with the formatted schema:\n%s", golden)
+	}
+
+	// the order of the types and fields and the whitespace of descriptions do not matter
+	tb := &recordingTB{TB: t}
+	gqltesting.CompareSnapshot(tb, graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Hero {
+			name: String!
+		}
+
+		#   The root   type.
+		type Query {
+			version: String!
+			hero: Hero
+		}
+	`, nil), path)
+	if len(tb.errors) != 0 {
+		t.Errorf("got differences %q for an equivalent schema", tb.errors)
+	}
+
+	tb = &recordingTB{TB: t}
+	gqltesting.CompareSnapshot(tb, graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		# The root type.
+		type Query {
+			hero: Hero
+		}
+
+		type Hero {
+			name: String
+		}
+	`, nil), path)
+	if len(tb.errors) != 1 ||
+		!strings.Contains(tb.errors[0], "__schema.types.Hero.fields.name.type.kind: got \"SCALAR\", want \"NON_NULL\"") ||
+		!strings.Contains(tb.errors[0], "__schema.types.Query.fields.version: removed") {
+		t.Errorf("got differences %q, want the changed and removed fields", tb.errors)
+	}
+}
+
+type loaderAuthor struct {
+	id     int32
+	loader *graphql.Loader[int32, *loaderAuthor]
+}
+
+func (a *loaderAuthor) Name() string {
+	return fmt.Sprintf("author %d", a.id)
+}
+
+func (a *loaderAuthor) Friend(ctx context.Context) (*loaderAuthor, error) {
+	return a.loader.Load(ctx, a.id%3+1)
+}
+
+type loaderPost struct {
+	author int32
+	loader *graphql.Loader[int32, *loaderAuthor]
+}
+
+func (p *loaderPost) Author(ctx context.Context) (*loaderAuthor, error) {
+	return p.loader.Load(ctx, p.author)
+}
+
+type loaderResolver struct {
+	loader *graphql.Loader[int32, *loaderAuthor]
+}
+
+func (r *loaderResolver) Posts() []*loaderPost {
+	return []*loaderPost{{1, r.loader}, {2, r.loader}, {1, r.loader}, {4, r.loader}}
+}
+
+func TestLoader(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]int32
+	var loader *graphql.Loader[int32, *loaderAuthor]
+	loader = graphql.NewLoader(func(ctx context.Context, ids []int32) ([]*loaderAuthor, []error) {
+		mu.Lock()
+		batch := append([]int32(nil), ids...)
+		sort.Slice(batch, func(i, j int) bool { return batch[i] < batch[j] })
+		batches = append(batches, batch)
+		mu.Unlock()
+		authors := make([]*loaderAuthor, len(ids))
+		errs := make([]error, len(ids))
+		for i, id := range ids {
+			if id > 3 {
+				errs[i] = fmt.Errorf("author %d not found", id)
+				continue
+			}
+			authors[i] = &loaderAuthor{id, loader}
+		}
+		return authors, errs
+	})
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			posts: [Post!]!
+		}
+
+		type Post {
+			author: Author
+		}
+
+		type Author {
+			name: String!
+			friend: Author
+		}
+	`, &loaderResolver{loader})
+
+	query := `{ posts { author { name friend { name } } } }`
+	result := schema.Exec(context.Background(), query, "", nil)
+	want := `{"posts":[` +
+		`{"author":{"name":"author 1","friend":{"name":"author 2"}}},` +
+		`{"author":{"name":"author 2","friend":{"name":"author 3"}}},` +
+		`{"author":{"name":"author 1","friend":{"name":"author 2"}}},` +
+		`{"author":null}]}`
+	if string(result.Data) != want {
+		t.Errorf("got %s, want %s", result.Data, want)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Message != "author 4 not found" {
+		t.Errorf("got errors %v, want the error of the missing author", result.Errors)
+	}
+	if fmt.Sprint(batches) != "[[1 2 4] [3]]" {
+		t.Errorf("got batches %v, want the authors and then the uncached friends", batches)
+	}
+
+	batches = nil
+	schema.Exec(context.Background(), query, "", nil)
+	if fmt.Sprint(batches) != "[[1 2 4] [3]]" {
+		t.Errorf("got batches %v, want the values of the previous request not to be cached", batches)
+	}
+
+	batches = nil
+	loader.MaxBatch = 2
+	schema.Exec(context.Background(), query, "", nil)
+	for _, batch := range batches {
+		if len(batch) > 2 {
+			t.Errorf("got batch %v, want at most 2 keys", batch)
+		}
+	}
+
+	batches = nil
+	loader.MaxBatch = 0
+	if author, err := loader.Load(context.Background(), 2); err != nil || author.id != 2 || fmt.Sprint(batches) != "[[2]]" {
+		t.Errorf("got %v, %v and batches %v, want a batch of the key outside of a request", author, err, batches)
+	}
+}
+
+type rawArgsResolver struct{}
+
+func (r *rawArgsResolver) Proxy(ctx context.Context, args struct {
+	ID    graphql.ID
+	Limit *int32
+}, raw query.RawArgs, vars query.Variables) string {
+	return fmt.Sprintf("%s %s %v", args.ID, raw, map[string]interface{}(vars))
+}
+
+func (r *rawArgsResolver) Vars(vars query.Variables) string {
+	return fmt.Sprint(len(vars))
+}
+
+type rawArgsWrongOrderResolver struct{}
+
+func (r *rawArgsWrongOrderResolver) Proxy(vars query.Variables, raw query.RawArgs) string {
+	return ""
+}
+
+func (r *rawArgsWrongOrderResolver) Vars() string {
+	return ""
+}
+
+func TestRawArgsAndVariables(t *testing.T) {
+	sdl := `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			proxy(id: ID!, limit: Int): String!
+			vars: String!
+		}
+	`
+	schema := graphql.MustParseSchema(sdl, &rawArgsResolver{})
+	result := schema.Exec(context.Background(), `query($id: ID!) { proxy(id: $id, limit: 10) vars }`, "", map[string]interface{}{"id": "42"})
+	if len(result.Errors) != 0 {
+		t.Fatal(result.Errors)
+	}
+	want := `{"proxy":"42 {\"id\":\"42\",\"limit\":10} map[id:42]","vars":"1"}`
+	if string(result.Data) != want {
+		t.Errorf("got %s, want %s", result.Data, want)
+	}
+
+	sdl = strings.Replace(sdl, "proxy(id: ID!, limit: Int)", "proxy", 1)
+	if _, err := graphql.ParseSchema(sdl, &rawArgsWrongOrderResolver{}); err == nil || !strings.Contains(err.Error(), "too many parameters") {
+		t.Errorf("got error %v, want the raw arguments to precede the variables", err)
+	}
+}
+
+func TestInjectedAliases(t *testing.T) {
+	var hooked []string
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{},
+		graphql.InjectIdentities(),
+		graphql.InjectedAliases("_id", "_type"),
+		graphql.UseResponseHook(func(ctx context.Context, data json.RawMessage) json.RawMessage {
+			hooked = append(hooked, string(data))
+			return data
+		}),
+		graphql.UseResponseHook(func(ctx context.Context, data json.RawMessage) json.RawMessage {
+			panic("response hook")
+		}),
+		graphql.Logger(&callbackLogger{}),
+	)
+
+	result := schema.Exec(context.Background(), `{ hero { id name friends { name } } }`, "", nil)
+	if len(result.Errors) != 0 {
+		t.Fatal(result.Errors)
+	}
+	want := `{"hero":{"id":"2001","name":"R2-D2","friends":[{"name":"Luke Skywalker"},{"name":"Han Solo"},{"name":"Leia Organa"}]}}`
+	if string(result.Data) != want {
+		t.Errorf("got %s, want %s", result.Data, want)
+	}
+	wantHooked := `{"hero":{"id":"2001","name":"R2-D2","friends":[` +
+		`{"name":"Luke Skywalker","_id":"1000","_type":"Human"},` +
+		`{"name":"Han Solo","_id":"1002","_type":"Human"},` +
+		`{"name":"Leia Organa","_id":"1003","_type":"Human"}],"_id":"2001","_type":"Droid"}}`
+	if len(hooked) != 1 || hooked[0] != wantHooked {
+		t.Errorf("got hooked data %q, want %s", hooked, wantHooked)
+	}
+
+	// the aliases of the query are returned
+	result = schema.Exec(context.Background(), `{ hero { _id: name } }`, "", nil)
+	if want := `{"hero":{"_id":"R2-D2"}}`; string(result.Data) != want {
+		t.Errorf("got %s, want %s", result.Data, want)
+	}
+}
+
+func TestInjectedIdentitiesVisibility(t *testing.T) {
+	var hooked []string
+	schema := graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{},
+		graphql.InjectIdentities(),
+		graphql.InjectedAliases("_id", "_type"),
+		graphql.OperationCacheSize(10),
+		graphql.UseVisibility(func(ctx context.Context, typeName, fieldName string) bool {
+			return fieldName != "id" || ctx.Value(clientKey{}) == "internal"
+		}),
+		graphql.UseResponseHook(func(ctx context.Context, data json.RawMessage) json.RawMessage {
+			hooked = append(hooked, string(data))
+			return data
+		}),
+	)
+
+	// the cached selections of the operation inject the id only for the requests seeing it
+	query := `{ hero { name } }`
+	for _, client := range []string{"internal", "external", "internal"} {
+		ctx := context.WithValue(context.Background(), clientKey{}, client)
+		if res := schema.Exec(ctx, query, "", nil); len(res.Errors) != 0 {
+			t.Fatal(res.Errors)
+		}
+	}
+	want := []string{
+		`{"hero":{"name":"R2-D2","_id":"2001","_type":"Droid"}}`,
+		`{"hero":{"name":"R2-D2","_type":"Droid"}}`,
+		`{"hero":{"name":"R2-D2","_id":"2001","_type":"Droid"}}`,
+	}
+	if !reflect.DeepEqual(hooked, want) {
+		t.Errorf("got hooked data %q, want %q", hooked, want)
+	}
+}
+
+func TestDescriptions(t *testing.T) {
+	sdl := `
+		"The schema of the library."
+		schema {
+			query: Query
+		}
+
+		"""
+		Limits the rate of a field.
+		"""
+		directive @rateLimit(
+			"Calls per minute."
+			limit: Int!
+		) on FIELD_DEFINITION
+
+		type Query {
+			"""
+			The books of a genre.
+			"""
+			books(
+				"The genre of the books."
+				genre: Genre!
+			): [String!]! @rateLimit(limit: 10)
+		}
+
+		enum Genre {
+			"Novels and short stories."
+			FICTION
+			NONFICTION
+		}
+	`
+	query := `
+		{
+			__schema {
+				description
+				directives { name description args { name description } }
+			}
+			__type(name: "Query") {
+				fields { description args { description type { ofType { enumValues { name description } } } } }
+			}
+		}
+	`
+	want := `{"__schema":{"description":"The schema of the library.","directives":[` +
+		`{"name":"deprecated","description":"Marks an element of a GraphQL schema as no longer supported.","args":[{"name":"reason","description":"Explains why this element was deprecated, usually also including a suggestion\nfor how to access supported similar data. Formatted in\n[Markdown](https://daringfireball.net/projects/markdown/)."}]},` +
+		`{"name":"include","description":"Directs the executor to include this field or fragment only when the ` + "`if`" + ` argument is true.","args":[{"name":"if","description":"Included when true."}]},` +
+		`{"name":"rateLimit","description":"Limits the rate of a field.","args":[{"name":"limit","description":"Calls per minute."}]},` +
+		`{"name":"skip","description":"Directs the executor to skip this field or fragment when the ` + "`if`" + ` argument is true.","args":[{"name":"if","description":"Skipped when true."}]}]},` +
+		`"__type":{"fields":[{"description":"The books of a genre.","args":[{"description":"The genre of the books.","type":{"ofType":{"enumValues":[` +
+		`{"name":"FICTION","description":"Novels and short stories."},{"name":"NONFICTION","description":null}]}}}]}]}}`
+
+	// the descriptions survive printing the schema and parsing it again
+	schema := graphql.MustParseSchema(sdl, &struct{}{}, graphql.LenientBinding())
+	printed, err := sdlfmt.Format([]byte(sdl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed := graphql.MustParseSchema(string(printed), &struct{}{}, graphql.LenientBinding())
+	for _, s := range []*graphql.Schema{schema, reparsed} {
+		result := s.Exec(context.Background(), query, "", nil)
+		if len(result.Errors) != 0 {
+			t.Fatal(result.Errors)
+		}
+		if string(result.Data) != want {
+			t.Errorf("got %s, want %s", result.Data, want)
+		}
+	}
+}
+
+type transformConnection struct {
+	items []string
+}
+
+func (c *transformConnection) Items() []string { return c.items }
+func (c *transformConnection) Total() int32    { return int32(len(c.items)) }
+
+type transformResolver struct{}
+
+func (r *transformResolver) Books(args struct{ First *int32 }) *transformConnection {
+	books := []string{"Dune", "Emma", "Ulysses"}
+	if args.First != nil && int(*args.First) < len(books) {
+		books = books[:*args.First]
+	}
+	return &transformConnection{books}
+}
+
+func (r *transformResolver) Authors(args struct{ First *int32 }) *transformConnection {
+	return &transformConnection{[]string{"Austen"}}
+}
+
+func TestTransformDirective(t *testing.T) {
+	sdl := `
+		schema {
+			query: Query
+		}
+
+		directive @paginate(max: Int = 100) on FIELD_DEFINITION
+		directive @constant(value: Int!) on FIELD_DEFINITION
+
+		type Query {
+			books: [String!]! @paginate(max: 10)
+			authors: [String!]! @paginate
+			answer: Int! @constant(value: 42)
+		}
+	`
+	var maxes []interface{}
+	paginate := graphql.TransformDirective("paginate", func(f *graphql.DirectiveField) error {
+		if f.Type() != "[String!]!" {
+			return fmt.Errorf("can not paginate %s", f.Type())
+		}
+		maxes = append(maxes, f.Args["max"])
+		f.SetType("StringConnection!")
+		f.AddArgument("first: Int")
+		f.DefineType("StringConnection", `type StringConnection { items: [String!]! total: Int! }`)
+		return nil
+	})
+	constant := graphql.TransformDirective("constant", func(f *graphql.DirectiveField) error {
+		value := f.Args["value"]
+		f.Apply(graphql.ResolveFieldFunc(f.TypeName+"."+f.Name, reflect.TypeOf(int32(0)), func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return value, nil
+		}))
+		return nil
+	})
+	schema := graphql.MustParseSchema(sdl, &transformResolver{}, paginate, constant)
+	if fmt.Sprint(maxes) != "[10 100]" {
+		t.Errorf("got the maxima %v, want the arguments in the order of the fields", maxes)
+	}
+
+	result := schema.Exec(context.Background(), `{ books(first: 2) { items total } authors { total } answer }`, "", nil)
+	if len(result.Errors) != 0 {
+		t.Fatal(result.Errors)
+	}
+	if want := `{"books":{"items":["Dune","Emma"],"total":2},"authors":{"total":1},"answer":42}`; string(result.Data) != want {
+		t.Errorf("got %s, want %s", result.Data, want)
+	}
+	if !strings.Contains(schema.SDL(), "books(first: Int): StringConnection! @paginate(max: 10)") {
+		t.Errorf("got SDL %s, want the changed field", schema.SDL())
+	}
+
+	_, err := graphql.ParseSchema(strings.Replace(sdl, "answer: Int!", "answer: [Int!]!", 1), &transformResolver{}, paginate,
+		graphql.TransformDirective("constant", func(f *graphql.DirectiveField) error {
+			f.SetType("Missing")
+			return nil
+		}))
+	if err == nil || !strings.Contains(err.Error(), "schema changed by directives") {
+		t.Errorf("got error %v, want the changed schema to be invalid", err)
+	}
+}
+
+type warningLogger struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (l *warningLogger) LogPanic(ctx context.Context, value interface{}) {}
+
+func (l *warningLogger) LogWarning(ctx context.Context, warning *errors.QueryError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, warning.Extensions["code"].(string))
+}
+
+type warningsResolver struct{}
+
+func (r *warningsResolver) Name() string { return "Alice" }
+
+func (r *warningsResolver) Nickname() string { return "Al" }
+
+func (r *warningsResolver) Greeting(args struct{ Style string }) string { return args.Style }
+
+func (r *warningsResolver) Slow(ctx context.Context) (string, error) {
+	time.Sleep(20 * time.Millisecond)
+	return "done", nil
+}
+
+func TestWarnings(t *testing.T) {
+	logger := &warningLogger{}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			name: String!
+			nickname: String! @deprecated(reason: "Use name.")
+			greeting(style: Style!): String!
+			slow: String!
+		}
+
+		enum Style {
+			FORMAL
+			CASUAL @deprecated
+		}
+	`, &warningsResolver{}, graphql.WarnDeprecated(), graphql.WarnSlowFields(5*time.Millisecond), graphql.Logger(logger))
+
+	result := schema.Exec(context.Background(), `{ name nickname greeting(style: CASUAL) ...F } fragment F on Query { nickname slow }`, "", nil)
+	if len(result.Errors) != 0 {
+		t.Fatal(result.Errors)
+	}
+	warnings, _ := result.Extensions["warnings"].([]*errors.QueryError)
+	var messages []string
+	for _, w := range warnings {
+		messages = append(messages, w.Message)
+	}
+	want := []string{
+		"The field Query.nickname is deprecated: Use name.",
+		"The enum value Style.CASUAL is deprecated: No longer supported",
+	}
+	if len(messages) != 3 || !reflect.DeepEqual(messages[:2], want) || !strings.HasPrefix(messages[2], "field Query.slow took ") {
+		t.Errorf("got warnings %q, want the deprecations and the slow field", messages)
+	}
+	if warnings[2].Extensions["code"] != "SLOW_FIELD" || !reflect.DeepEqual(warnings[2].Path, []interface{}{"slow"}) {
+		t.Errorf("got %v at %v, want a slow field warning at its path", warnings[2].Extensions, warnings[2].Path)
+	}
+	if want := []string{"DEPRECATED", "DEPRECATED", "SLOW_FIELD"}; !reflect.DeepEqual(logger.warnings, want) {
+		t.Errorf("got logged warnings %q, want %q", logger.warnings, want)
+	}
+
+	result = schema.Exec(context.Background(), `{ name greeting(style: FORMAL) }`, "", nil)
+	if result.Extensions != nil {
+		t.Errorf("got extensions %v, want no warnings", result.Extensions)
+	}
+}
+
+type iterBook struct {
+	title string
+}
+
+func (b *iterBook) Title() string { return b.title }
+
+func (b *iterBook) Upper(ctx context.Context) (string, error) {
+	return strings.ToUpper(b.title), nil
+}
+
+type iterResolver struct {
+	yielded int
+}
+
+func (r *iterResolver) Books() iter.Seq[*iterBook] {
+	return func(yield func(*iterBook) bool) {
+		for _, title := range []string{"a", "b", "c"} {
+			r.yielded++
+			if !yield(&iterBook{title}) {
+				return
+			}
+		}
+	}
+}
+
+func (r *iterResolver) Numbers() iter.Seq2[int32, error] {
+	return func(yield func(int32, error) bool) {
+		for i := int32(1); i <= 3; i++ {
+			if !yield(i, nil) {
+				return
+			}
+		}
+		yield(0, fmt.Errorf("cursor closed"))
+	}
+}
+
+func (r *iterResolver) Missing() iter.Seq[int32] { return nil }
+
+func TestIteratorLists(t *testing.T) {
+	resolver := &iterResolver{}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			books: [Book!]!
+			numbers: [Int!]
+			missing: [Int!]
+		}
+
+		type Book {
+			title: String!
+			upper: String!
+		}
+	`, resolver, graphql.MaxListWorkers(2))
+
+	for _, tt := range []struct {
+		query      string
+		want       string
+		wantErrors []string
+	}{
+		{`{ books { title } }`, `{"books":[{"title":"a"},{"title":"b"},{"title":"c"}]}`, nil},
+		{`{ books { title upper } }`, `{"books":[{"title":"a","upper":"A"},{"title":"b","upper":"B"},{"title":"c","upper":"C"}]}`, nil},
+		{`{ numbers missing }`, `{"numbers":null,"missing":null}`, []string{"cursor closed"}},
+	} {
+		res := schema.Exec(context.Background(), tt.query, "", nil)
+		if string(res.Data) != tt.want {
+			t.Errorf("query %s: got data %s, want %s", tt.query, res.Data, tt.want)
+		}
+		var errs []string
+		for _, err := range res.Errors {
+			errs = append(errs, err.Message)
+		}
+		if !reflect.DeepEqual(errs, tt.wantErrors) {
+			t.Errorf("query %s: got errors %q, want %q", tt.query, errs, tt.wantErrors)
+		}
+	}
+	if resolver.yielded != 6 {
+		t.Errorf("got %d yielded books, want 6", resolver.yielded)
+	}
+}
+
+type parentQueryResolver struct{}
+
+func (r *parentQueryResolver) Author() *parentAuthor {
+	return &parentAuthor{id: "a1", name: "Ann"}
+}
+
+func (r *parentQueryResolver) Books() []*parentBook {
+	return []*parentBook{{title: "Orphan"}}
+}
+
+type parentAuthor struct {
+	id, name string
+}
+
+func (a *parentAuthor) Name() string { return a.name }
+
+func (a *parentAuthor) Books() []*parentBook {
+	return []*parentBook{{title: "First"}, {title: "Second"}}
+}
+
+type parentBook struct {
+	title string
+}
+
+func (b *parentBook) Title() string { return b.title }
+
+func (b *parentBook) AuthorName(parent query.Parent[*parentAuthor]) *string {
+	return &parent.Value.name
+}
+
+func (b *parentBook) AuthorID(ctx context.Context, parent query.Parent[string]) (string, error) {
+	return parent.Value, nil
+}
+
+func TestParentParameter(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			author: Author
+			books: [Book!]!
+		}
+
+		type Author {
+			name: String!
+			books: [Book!]!
+		}
+
+		type Book {
+			title: String!
+			authorName: String
+			authorID: String!
+		}
+	`, &parentQueryResolver{}, graphql.ParentProjection(func(a *parentAuthor) string { return a.id }))
+
+	for _, tt := range []struct {
+		query      string
+		want       string
+		wantErrors []string
+	}{
+		{
+			`{ author { name books { title authorName authorID } } }`,
+			`{"author":{"name":"Ann","books":[{"title":"First","authorName":"Ann","authorID":"a1"},{"title":"Second","authorName":"Ann","authorID":"a1"}]}}`,
+			nil,
+		},
+		{
+			`{ books { title authorName } }`,
+			`{"books":[{"title":"Orphan","authorName":null}]}`,
+			[]string{"can not use parent *graphql_test.parentQueryResolver as *graphql_test.parentAuthor"},
+		},
+	} {
+		res := schema.Exec(context.Background(), tt.query, "", nil)
+		if string(res.Data) != tt.want {
+			t.Errorf("query %s: got data %s, want %s", tt.query, res.Data, tt.want)
+		}
+		var errs []string
+		for _, err := range res.Errors {
+			errs = append(errs, err.Message)
+		}
+		if !reflect.DeepEqual(errs, tt.wantErrors) {
+			t.Errorf("query %s: got errors %q, want %q", tt.query, errs, tt.wantErrors)
+		}
+	}
+}
+
+var errNoRows = stderrors.New("no rows in result set")
+
+type wrappedErrorResolver struct{}
+
+func (r *wrappedErrorResolver) Missing() (*string, error) {
+	return nil, fmt.Errorf("loading user: %w", errNoRows)
+}
+
+func (r *wrappedErrorResolver) Panicking() *string {
+	panic(&os.PathError{Op: "open", Path: "users.db", Err: os.ErrNotExist})
+}
+
+func (r *wrappedErrorResolver) Slow(ctx context.Context) (*string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestErrorsIsAs(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			missing: String
+			panicking: String
+			slow: String
+		}
+	`, &wrappedErrorResolver{}, graphql.Logger(&stackLogger{}))
+
+	res := schema.Exec(context.Background(), `{ missing panicking }`, "", nil)
+	if len(res.Errors) != 2 {
+		t.Fatalf("got errors %v, want 2", res.Errors)
+	}
+	for _, err := range res.Errors {
+		if err.Path[0] == "missing" && !stderrors.Is(err, errNoRows) {
+			t.Errorf("error %v of the resolver does not wrap errNoRows", err)
+		}
+	}
+	if !stderrors.Is(res.Err(), errNoRows) || !stderrors.Is(res.Err(), os.ErrNotExist) {
+		t.Errorf("errors %v of the response do not wrap errNoRows and os.ErrNotExist", res.Err())
+	}
+	var pathErr *os.PathError
+	if !stderrors.As(res.Err(), &pathErr) || pathErr.Path != "users.db" {
+		t.Errorf("errors %v of the response do not wrap the panic's *os.PathError", res.Err())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res = schema.Exec(ctx, `{ slow }`, "", nil)
+	if !stderrors.Is(res.Err(), context.DeadlineExceeded) {
+		t.Errorf("errors %v of the response do not wrap context.DeadlineExceeded", res.Err())
+	}
+
+	if err := (&graphql.Response{}).Err(); err != nil {
+		t.Errorf("got error %v for a response without errors", err)
+	}
+}
+
+type yesNo string
+
+func (b *yesNo) UnmarshalText(text []byte) error {
+	*b = yesNo(text)
+	return nil
+}
+
+type bigInt int64
+
+func (i *bigInt) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*int64)(i))
+}
+
+type strictScalarResolver struct{}
+
+func (strictScalarResolver) Small() bigInt { return 42 }
+
+func (strictScalarResolver) Big() *bigInt {
+	big := bigInt(3000000000)
+	return &big
+}
+
+func (strictScalarResolver) Flag() *yesNo {
+	flag := yesNo("yes")
+	return &flag
+}
+
+func TestStrictScalars(t *testing.T) {
+	sdl := `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			small: Int!
+			big: Int
+			flag: Boolean
+		}
+	`
+	opts := []graphql.SchemaOpt{
+		graphql.MapScalar(reflect.TypeOf(bigInt(0)), "Int"),
+		graphql.MapScalar(reflect.TypeOf(yesNo("")), "Boolean"),
+	}
+	loose := graphql.MustParseSchema(sdl, strictScalarResolver{}, opts...)
+	strict := graphql.MustParseSchema(sdl, strictScalarResolver{}, append(opts, graphql.StrictScalars())...)
+	query := `{ small big flag }`
+
+	res := loose.Exec(context.Background(), query, "", nil)
+	if want := `{"small":42,"big":3000000000,"flag":"yes"}`; string(res.Data) != want || len(res.Errors) != 0 {
+		t.Errorf("got data %s and errors %v without strict scalars, want %s", res.Data, res.Errors, want)
+	}
+
+	res = strict.Exec(context.Background(), query, "", nil)
+	if want := `{"small":42,"big":null,"flag":null}`; string(res.Data) != want {
+		t.Errorf("got data %s, want %s", res.Data, want)
+	}
+	var errs []string
+	for _, err := range res.Errors {
+		errs = append(errs, fmt.Sprintf("%v %s %s", err.Path, err.Message, err.Extensions["code"]))
+	}
+	wantErrs := []string{
+		"[big] Int cannot represent value: 3000000000 INVALID_SCALAR_OUTPUT",
+		`[flag] Boolean cannot represent value: "yes" INVALID_SCALAR_OUTPUT`,
+	}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("got errors %q, want %q", errs, wantErrs)
+	}
+}
+
+var pooledResets int32
+
+type pooledRoot struct {
+	ids []string
+}
+
+func (r *pooledRoot) Reset() {
+	atomic.AddInt32(&pooledResets, 1)
+	*r = pooledRoot{}
+}
+
+var pooledRoots = graphql.NewResolverPool[pooledRoot]()
+
+var pooledUsers = graphql.NewResolverPool[pooledUser]()
+
+func (r *pooledRoot) Users(ctx context.Context) []*pooledUser {
+	var users []*pooledUser
+	for _, id := range r.ids {
+		u := pooledUsers.Get(ctx)
+		u.id = id
+		users = append(users, u)
+	}
+	return users
+}
+
+type pooledUser struct {
+	id string
+}
+
+func (u *pooledUser) Reset() {
+	atomic.AddInt32(&pooledResets, 1)
+	*u = pooledUser{}
+}
+
+func (u *pooledUser) Name(ctx context.Context) (string, error) {
+	time.Sleep(time.Millisecond)
+	return "user " + u.id, nil
+}
+
+func TestResolverPool(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			users: [User!]!
+		}
+
+		type User {
+			name: String!
+		}
+	`, func(ctx context.Context) (*pooledRoot, error) {
+		root := pooledRoots.Get(ctx)
+		root.ids = []string{"1", "2", "3"}
+		return root, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		atomic.StoreInt32(&pooledResets, 0)
+		res := schema.Exec(context.Background(), `{ users { name } }`, "", nil)
+		if want := `{"users":[{"name":"user 1"},{"name":"user 2"},{"name":"user 3"}]}`; string(res.Data) != want {
+			t.Errorf("got %s, want %s", res.Data, want)
+		}
+		if resets := atomic.LoadInt32(&pooledResets); resets != 4 {
+			t.Errorf("got %d resolvers reset after the request, want 4", resets)
+		}
+	}
+
+	atomic.StoreInt32(&pooledResets, 0)
+	if u := pooledUsers.Get(context.Background()); u.id != "" {
+		t.Errorf("got resolver %+v outside of a request, want a new one", u)
+	}
+	if resets := atomic.LoadInt32(&pooledResets); resets != 0 {
+		t.Errorf("got %d resolvers reset outside of a request", resets)
+	}
+}
+
+func TestExecIntrospection(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			me: User
+		}
+
+		"A user."
+		type User {
+			name: String!
+		}
+	`, nil)
+
+	res := schema.ExecIntrospection(context.Background(), `{ __typename __type(name: "User") { description fields { name } } }`)
+	if len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	if want := `{"__typename":"Query","__type":{"description":"A user.","fields":[{"name":"name"}]}}`; string(res.Data) != want {
+		t.Errorf("got %s, want %s", res.Data, want)
+	}
+
+	for _, tt := range []struct {
+		query   string
+		wantErr string
+	}{
+		{`{ __typename ...me } fragment me on Query { me { name } }`, `graphql: Field "me" is not an introspection field. (line 1, column 45)`},
+		{`mutation { __typename }`, `graphql: introspection requires a query operation`},
+		{`{ __schema { unknown } }`, `graphql: Cannot query field "unknown" on type "__Schema". (line 1, column 14)`},
+	} {
+		res := schema.ExecIntrospection(context.Background(), tt.query)
+		if len(res.Errors) != 1 || res.Errors[0].Error() != tt.wantErr || res.Data != nil {
+			t.Errorf("query %s: got data %s and errors %v, want error %s", tt.query, res.Data, res.Errors, tt.wantErr)
+		}
+	}
+
+	// hooks get a request store like for Exec
+	hooked := graphql.MustParseSchema(`schema { query: Query } type Query { me: String }`, nil,
+		graphql.UseVariablesHook(func(ctx context.Context, operationName string, variables map[string]interface{}) (map[string]interface{}, error) {
+			graphql.Set(ctx, hooksKey, 1)
+			return variables, nil
+		}),
+	)
+	if res := hooked.ExecIntrospection(context.Background(), `{ __typename }`); len(res.Errors) != 0 || string(res.Data) != `{"__typename":"Query"}` {
+		t.Errorf("got data %s and errors %v", res.Data, res.Errors)
+	}
+}
+
+type pathQueryResolver struct {
+	paths []string
+}
+
+func (r *pathQueryResolver) Heroes() []*pathHeroResolver {
+	return []*pathHeroResolver{{r}, {r}}
+}
+
+type pathHeroResolver struct {
+	q *pathQueryResolver
+}
+
+func (r *pathHeroResolver) Friends(path query.Path, fields []query.SelectedField) []*pathFriendResolver {
+	for _, f := range fields {
+		r.q.paths = append(r.q.paths, path.Append(0, f.Alias).String())
+	}
+	return []*pathFriendResolver{{}}
+}
+
+type pathFriendResolver struct{}
+
+func (r *pathFriendResolver) Name() string {
+	return "Luke"
+}
+
+func TestPathParameter(t *testing.T) {
+	resolver := &pathQueryResolver{}
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			heroes: [Hero!]!
+		}
+
+		type Hero {
+			friends: [Friend!]!
+		}
+
+		type Friend {
+			name: String!
+		}
+	`, resolver, graphql.Synchronous())
+
+	res := schema.Exec(context.Background(), `{ h: heroes { friends { name first: name } } }`, "", nil)
+	if len(res.Errors) != 0 {
+		t.Fatal(res.Errors)
+	}
+	if want := `{"h":[{"friends":[{"name":"Luke","first":"Luke"}]},{"friends":[{"name":"Luke","first":"Luke"}]}]}`; string(res.Data) != want {
+		t.Errorf("got data %s, want %s", res.Data, want)
+	}
+	want := []string{"h[0].friends[0].name", "h[0].friends[0].first", "h[1].friends[0].name", "h[1].friends[0].first"}
+	if !reflect.DeepEqual(resolver.paths, want) {
+		t.Errorf("got paths %q, want %q", resolver.paths, want)
+	}
+}
+
+type rawJSONResolver struct {
+	selected []query.SelectedField
+}
+
+func (r *rawJSONResolver) User(args struct{ ID graphql.ID }, fields []query.SelectedField) graphql.RawJSON {
+	r.selected = fields
+	return graphql.RawJSON(`{"id":"` + string(args.ID) + `","name":"Ann"}`)
+}
+
+func (r *rawJSONResolver) Users() graphql.RawJSON {
+	return graphql.RawJSON(`[{"id":"1","name":"Ann"},{"id":2,"name":null}]`)
+}
+
+func (r *rawJSONResolver) Missing() graphql.RawJSON {
+	return nil
+}
+
+func TestRawJSON(t *testing.T) {
+	const schemaString = `
+		schema {
+			query: Query
+		}
+
+		type Query {
+			user(id: ID!): User
+			users: [User!]
+			missing: User
+		}
+
+		type User {
+			id: ID!
+			name: String!
+		}
+	`
+
+	for _, tt := range []struct {
+		name         string
+		validate     bool
+		query        string
+		want         string
+		wantErrors   []string
+		wantPaths    [][]interface{}
+		wantSelected []query.SelectedField
+	}{
+		{
+			name:         "written as it is",
+			query:        `{ user(id: "1") { id name } missing { id } }`,
+			want:         `{"user":{"id":"1","name":"Ann"},"missing":null}`,
+			wantSelected: []query.SelectedField{{Name: "id", Alias: "id"}, {Name: "name", Alias: "name"}},
+		},
+		{
+			name:         "not validated",
+			query:        `{ user(id: "1") { id } }`,
+			want:         `{"user":{"id":"1","name":"Ann"}}`,
+			wantSelected: []query.SelectedField{{Name: "id", Alias: "id"}},
+		},
+		{
+			name:         "validated",
+			validate:     true,
+			query:        `{ user(id: "1") { ... on User { name } id } }`,
+			want:         `{"user":{"id":"1","name":"Ann"}}`,
+			wantSelected: []query.SelectedField{{Name: "name", Alias: "name"}, {Name: "id", Alias: "id"}},
+		},
+		{
+			name:         "unselected field",
+			validate:     true,
+			query:        `{ user(id: "1") { id name @skip(if: true) } }`,
+			want:         `{"user":null}`,
+			wantErrors:   []string{`invalid raw JSON: field "name" is not selected`},
+			wantPaths:    [][]interface{}{{"user"}},
+			wantSelected: []query.SelectedField{{Name: "id", Alias: "id"}},
+		},
+		{
+			name:         "missing field",
+			validate:     true,
+			query:        `{ user(id: "1") { id n: name } }`,
+			want:         `{"user":null}`,
+			wantErrors:   []string{`invalid raw JSON: missing field "n"`},
+			wantPaths:    [][]interface{}{{"user"}},
+			wantSelected: []query.SelectedField{{Name: "id", Alias: "id"}, {Name: "name", Alias: "n"}},
+		},
+		{
+			name:       "null for non-null",
+			validate:   true,
+			query:      `{ users { id name } }`,
+			want:       `{"users":null}`,
+			wantErrors: []string{`invalid raw JSON: null for non-null "String!"`},
+			wantPaths:  [][]interface{}{{"users", 1, "name"}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &rawJSONResolver{}
+			var opts []graphql.SchemaOpt
+			if tt.validate {
+				opts = append(opts, graphql.ValidateRawJSON())
+			}
+			schema := graphql.MustParseSchema(schemaString, resolver, opts...)
+			res := schema.Exec(context.Background(), tt.query, "", nil)
+			if string(res.Data) != tt.want {
+				t.Errorf("got data %s, want %s", res.Data, tt.want)
+			}
+			var errs []string
+			var paths [][]interface{}
+			for _, err := range res.Errors {
+				errs = append(errs, err.Message)
+				paths = append(paths, err.Path)
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrors) {
+				t.Errorf("got errors %q, want %q", errs, tt.wantErrors)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("got paths %v, want %v", paths, tt.wantPaths)
+			}
+			if !reflect.DeepEqual(resolver.selected, tt.wantSelected) {
+				t.Errorf("got selected fields %#v, want %#v", resolver.selected, tt.wantSelected)
+			}
+		})
+	}
+}
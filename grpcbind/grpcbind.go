@@ -0,0 +1,193 @@
+// Package grpcbind exposes methods of gRPC services as root fields of a GraphQL schema. A
+// declarative Binding maps a field to a unary method, the arguments of the field to the fields of
+// the request message and a field of the response message to the value of the field. The bindings
+// are checked and turned into resolvers when the schema is created:
+//
+//	opts, err := grpcbind.SchemaOpts(conn, grpcbind.Binding{
+//		Field:    "Query.user",
+//		Method:   "/users.v1.Users/GetUser",
+//		Request:  (*userspb.GetUserRequest)(nil),
+//		Response: (*userspb.GetUserResponse)(nil),
+//		Result:   "User",
+//	})
+//	schema := graphql.MustParseSchema(schemaString, &resolver{}, opts...)
+//
+// Messages generated by protoc-gen-go are bound to GraphQL object types through their getters, e.g.
+// GetName for the field "name", and their enums to GraphQL enums through their String methods.
+package grpcbind
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go"
+)
+
+// Conn invokes unary gRPC methods. A *grpc.ClientConn is adapted with
+//
+//	grpcbind.ConnFunc(func(ctx context.Context, method string, req, resp interface{}) error {
+//		return conn.Invoke(ctx, method, req, resp)
+//	})
+type Conn interface {
+	Invoke(ctx context.Context, method string, req, resp interface{}) error
+}
+
+// ConnFunc is a function implementing Conn.
+type ConnFunc func(ctx context.Context, method string, req, resp interface{}) error
+
+// Invoke calls f.
+func (f ConnFunc) Invoke(ctx context.Context, method string, req, resp interface{}) error {
+	return f(ctx, method, req, resp)
+}
+
+// Binding maps a root field to a gRPC method.
+type Binding struct {
+	// Field is the field given as "Query.field" or "Mutation.field".
+	Field string
+
+	// Method is the full name of the method, e.g. "/users.v1.Users/GetUser".
+	Method string
+
+	// Request and Response are values of the pointer types of the request and response
+	// messages, e.g. (*userspb.GetUserRequest)(nil).
+	Request  interface{}
+	Response interface{}
+
+	// Args maps arguments of the field to the names of the Go fields of the request they are
+	// set to. Other arguments are set to the fields with the same name, ignoring case and
+	// underscores.
+	Args map[string]string
+
+	// Result is the name of the Go field of the response holding the value of the field. The
+	// response itself is the value if it is empty.
+	Result string
+}
+
+// SchemaOpts checks the bindings and returns the options resolving their fields by invoking the
+// methods with conn.
+func SchemaOpts(conn Conn, bindings ...Binding) ([]graphql.SchemaOpt, error) {
+	opts := make([]graphql.SchemaOpt, 0, len(bindings))
+	for _, b := range bindings {
+		m, err := newMethod(conn, b)
+		if err != nil {
+			return nil, perrors.Wrapf(err, "grpcbind: %s", b.Field)
+		}
+		opts = append(opts, graphql.ResolveFieldFunc(b.Field, m.resultType, m.resolve))
+	}
+	return opts, nil
+}
+
+// method is the glue between a field and a gRPC method.
+type method struct {
+	conn         Conn
+	name         string
+	requestType  reflect.Type      // struct type of the request
+	responseType reflect.Type      // struct type of the response
+	args         map[string]string // normalized argument names to JSON names of request fields
+	explicit     map[string]string // arguments of Binding.Args to JSON names of request fields
+	result       []int             // index of the result field, nil for the response itself
+	resultType   reflect.Type
+}
+
+func newMethod(conn Conn, b Binding) (*method, error) {
+	if b.Method == "" {
+		return nil, perrors.New("no method")
+	}
+	requestType, err := messageType(b.Request)
+	if err != nil {
+		return nil, perrors.Wrap(err, "request")
+	}
+	responseType, err := messageType(b.Response)
+	if err != nil {
+		return nil, perrors.Wrap(err, "response")
+	}
+
+	m := &method{
+		conn:         conn,
+		name:         b.Method,
+		requestType:  requestType,
+		responseType: responseType,
+		args:         make(map[string]string),
+		explicit:     make(map[string]string),
+		resultType:   reflect.PtrTo(responseType),
+	}
+	for i := 0; i < requestType.NumField(); i++ {
+		f := requestType.Field(i)
+		if f.PkgPath == "" {
+			m.args[normalize(f.Name)] = jsonName(f)
+		}
+	}
+	for arg, fieldName := range b.Args {
+		f, ok := requestType.FieldByName(fieldName)
+		if !ok || f.PkgPath != "" {
+			return nil, perrors.Errorf("argument %q: %s has no exported field %q", arg, requestType, fieldName)
+		}
+		m.explicit[arg] = jsonName(f)
+	}
+	if b.Result != "" {
+		f, ok := responseType.FieldByName(b.Result)
+		if !ok || f.PkgPath != "" {
+			return nil, perrors.Errorf("result: %s has no exported field %q", responseType, b.Result)
+		}
+		m.result = f.Index
+		m.resultType = f.Type
+	}
+	return m, nil
+}
+
+// resolve invokes the method with the arguments of the field set to the request.
+func (m *method) resolve(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	fields := make(map[string]interface{}, len(args))
+	for arg, value := range args {
+		name, ok := m.explicit[arg]
+		if !ok {
+			name, ok = m.args[normalize(arg)]
+		}
+		if !ok {
+			return nil, perrors.Errorf("grpcbind: %s has no field for argument %q", m.requestType, arg)
+		}
+		fields[name] = value
+	}
+
+	// the arguments are set through encoding/json, which converts them to the types of the fields
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, perrors.Wrap(err, "grpcbind: encoding arguments")
+	}
+	req := reflect.New(m.requestType)
+	if err := json.Unmarshal(data, req.Interface()); err != nil {
+		return nil, perrors.Wrapf(err, "grpcbind: setting arguments of %s", m.requestType)
+	}
+
+	resp := reflect.New(m.responseType)
+	if err := m.conn.Invoke(ctx, m.name, req.Interface(), resp.Interface()); err != nil {
+		return nil, err
+	}
+	if m.result == nil {
+		return resp.Interface(), nil
+	}
+	return resp.Elem().FieldByIndex(m.result).Interface(), nil
+}
+
+func messageType(v interface{}) (reflect.Type, error) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, perrors.Errorf("expected pointer to message struct, got %v", t)
+	}
+	return t.Elem(), nil
+}
+
+// jsonName returns the name of the field in the JSON encoding of the struct.
+func jsonName(f reflect.StructField) string {
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
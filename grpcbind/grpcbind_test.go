@@ -0,0 +1,212 @@
+package grpcbind_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/gqltesting"
+	"github.com/qdentity/graphql-go/grpcbind"
+)
+
+// The messages mimic those generated by protoc-gen-go.
+
+type GetUserRequest struct {
+	UserId  string `json:"user_id,omitempty"`
+	Verbose bool   `json:"verbose,omitempty"`
+}
+
+type GetUserResponse struct {
+	User *User `json:"user,omitempty"`
+}
+
+type ListUsersRequest struct {
+	PageSize int32 `json:"page_size,omitempty"`
+}
+
+type ListUsersResponse struct {
+	Users []*User `json:"users,omitempty"`
+}
+
+type User struct {
+	Id     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Status Status `json:"status,omitempty"`
+}
+
+func (x *User) GetId() string     { return x.Id }
+func (x *User) GetName() string   { return x.Name }
+func (x *User) GetStatus() Status { return x.Status }
+
+type Status int32
+
+const (
+	Status_ACTIVE   Status = 0
+	Status_DISABLED Status = 1
+)
+
+func (x Status) String() string {
+	return map[Status]string{Status_ACTIVE: "ACTIVE", Status_DISABLED: "DISABLED"}[x]
+}
+
+var users = []*User{
+	{Id: "1", Name: "Ada", Status: Status_ACTIVE},
+	{Id: "2", Name: "Grace", Status: Status_DISABLED},
+}
+
+var conn = grpcbind.ConnFunc(func(ctx context.Context, method string, req, resp interface{}) error {
+	switch method {
+	case "/users.v1.Users/GetUser":
+		for _, u := range users {
+			if u.Id == req.(*GetUserRequest).UserId {
+				resp.(*GetUserResponse).User = u
+				return nil
+			}
+		}
+		return fmt.Errorf("rpc error: code = NotFound desc = user %s", req.(*GetUserRequest).UserId)
+	case "/users.v1.Users/ListUsers":
+		n := int(req.(*ListUsersRequest).PageSize)
+		if n > len(users) {
+			n = len(users)
+		}
+		resp.(*ListUsersResponse).Users = users[:n]
+		return nil
+	}
+	return fmt.Errorf("unknown method %s", method)
+})
+
+const schemaString = `
+	schema {
+		query: Query
+	}
+
+	type Query {
+		user(userId: String!): User
+		users(pageSize: Int = 10): [User!]!
+		hello: String!
+	}
+
+	type User {
+		id: String!
+		name: String!
+		status: Status!
+	}
+
+	enum Status {
+		ACTIVE
+		DISABLED
+	}
+`
+
+type resolver struct{}
+
+func (r *resolver) Hello() string { return "Hello world!" }
+
+func mustSchemaOpts(t *testing.T) []graphql.SchemaOpt {
+	opts, err := grpcbind.SchemaOpts(conn,
+		grpcbind.Binding{
+			Field:    "Query.user",
+			Method:   "/users.v1.Users/GetUser",
+			Request:  (*GetUserRequest)(nil),
+			Response: (*GetUserResponse)(nil),
+			Result:   "User",
+		},
+		grpcbind.Binding{
+			Field:    "Query.users",
+			Method:   "/users.v1.Users/ListUsers",
+			Request:  (*ListUsersRequest)(nil),
+			Response: (*ListUsersResponse)(nil),
+			Args:     map[string]string{"pageSize": "PageSize"},
+			Result:   "Users",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
+
+func TestSchemaOpts(t *testing.T) {
+	schema := graphql.MustParseSchema(schemaString, &resolver{}, mustSchemaOpts(t)...)
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: schema,
+			Query: `
+				{
+					hello
+					user(userId: "2") {
+						id
+						name
+						status
+					}
+					users(pageSize: 1) {
+						name
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"hello": "Hello world!",
+					"user": {
+						"id": "2",
+						"name": "Grace",
+						"status": "DISABLED"
+					},
+					"users": [
+						{
+							"name": "Ada"
+						}
+					]
+				}
+			`,
+		},
+		{
+			Schema: schema,
+			Query: `
+				{
+					users {
+						id
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"users": [
+						{
+							"id": "1"
+						},
+						{
+							"id": "2"
+						}
+					]
+				}
+			`,
+		},
+	})
+}
+
+func TestSchemaOptsError(t *testing.T) {
+	schema := graphql.MustParseSchema(schemaString, &resolver{}, mustSchemaOpts(t)...)
+
+	result := schema.Exec(context.Background(), `{ user(userId: "3") { name } }`, "", nil)
+	if string(result.Data) != `{"user":null}` {
+		t.Errorf("unexpected data %s", result.Data)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Message != "rpc error: code = NotFound desc = user 3" {
+		t.Errorf("unexpected errors %v", result.Errors)
+	}
+}
+
+func TestBindingErrors(t *testing.T) {
+	for _, b := range []grpcbind.Binding{
+		{Field: "Query.user", Method: "/users.v1.Users/GetUser", Request: GetUserRequest{}, Response: (*GetUserResponse)(nil)},
+		{Field: "Query.user", Method: "/users.v1.Users/GetUser", Request: (*GetUserRequest)(nil), Response: (*GetUserResponse)(nil), Result: "Account"},
+		{Field: "Query.user", Method: "/users.v1.Users/GetUser", Request: (*GetUserRequest)(nil), Response: (*GetUserResponse)(nil), Args: map[string]string{"userId": "Id"}},
+	} {
+		if _, err := grpcbind.SchemaOpts(conn, b); err == nil {
+			t.Errorf("expected error for binding %+v", b)
+		}
+	}
+}
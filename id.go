@@ -1,6 +1,7 @@
 package graphql
 
 import (
+	"math"
 	"strconv"
 
 	perrors "github.com/pkg/errors"
@@ -20,6 +21,13 @@ func (id *ID) UnmarshalGraphQL(input interface{}) error {
 		*id = ID(input)
 	case int32:
 		*id = ID(strconv.Itoa(int(input)))
+	case int:
+		*id = ID(strconv.Itoa(input))
+	case float64:
+		if input != math.Trunc(input) {
+			return perrors.New("wrong type")
+		}
+		*id = ID(strconv.FormatFloat(input, 'f', -1, 64))
 	default:
 		err = perrors.New("wrong type")
 	}
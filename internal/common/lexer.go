@@ -2,18 +2,60 @@ package common
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"text/scanner"
+	"unicode"
+	"unicode/utf16"
 
 	"github.com/qdentity/graphql-go/errors"
 )
 
 type syntaxError string
 
+// limitError is raised when a document exceeds a parser limit, see ParserLimits.
+type limitError string
+
+// ParserLimits bound the size of documents, so that large documents are rejected while they are
+// parsed, before they are processed further. A limit of 0 disables it.
+type ParserLimits struct {
+	MaxBytes       int
+	MaxTokens      int
+	MaxDefinitions int
+}
+
+// LimitError returns the error of a document exceeding a parser limit.
+func LimitError(message string) *errors.QueryError {
+	err := errors.Errorf("%s", message)
+	err.Extensions = map[string]interface{}{"code": "DOCUMENT_TOO_LARGE"}
+	return err
+}
+
+// CheckSize returns an error if the document exceeds MaxBytes.
+func (limits ParserLimits) CheckSize(document string) *errors.QueryError {
+	if limits.MaxBytes > 0 && len(document) > limits.MaxBytes {
+		return LimitError(fmt.Sprintf("document exceeds the limit of %d bytes", limits.MaxBytes))
+	}
+	return nil
+}
+
+// locatedSyntaxError is a syntax error within a token, e.g. an invalid escape sequence of a string,
+// which is reported at its own location instead of the one of the token.
+type locatedSyntaxError struct {
+	message string
+	loc     errors.Location
+}
+
 type Lexer struct {
 	sc          *scanner.Scanner
 	next        rune
 	descComment string
+	valueDepth  int    // the nesting of the list and object literal being parsed
+	text        string // the text of a String token, see scanString
+	pos         scanner.Position
+	limits      ParserLimits
+	tokens      int
+	definitions int
 }
 
 type Ident struct {
@@ -22,21 +64,37 @@ type Ident struct {
 }
 
 func NewLexer(s string) *Lexer {
-	sc := &scanner.Scanner{
-		Mode: scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings,
-	}
+	sc := &scanner.Scanner{}
 	sc.Init(strings.NewReader(s))
+	sc.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats // strings are scanned by scanString
 
 	return &Lexer{sc: sc}
 }
 
+// NewLimitedLexer returns a lexer of a document that has to stay within the limits. MaxBytes is
+// checked by the caller, see ParserLimits.CheckSize.
+func NewLimitedLexer(s string, limits ParserLimits) *Lexer {
+	l := NewLexer(s)
+	l.limits = limits
+	return l
+}
+
 func (l *Lexer) CatchSyntaxError(f func()) (errRes *errors.QueryError) {
 	defer func() {
 		if err := recover(); err != nil {
-			if err, ok := err.(syntaxError); ok {
+			switch err := err.(type) {
+			case syntaxError:
 				errRes = errors.Errorf("syntax error: %s", err)
 				errRes.Locations = []errors.Location{l.Location()}
 				return
+			case locatedSyntaxError:
+				errRes = errors.Errorf("syntax error: %s", err.message)
+				errRes.Locations = []errors.Location{err.loc}
+				return
+			case limitError:
+				errRes = LimitError(string(err))
+				errRes.Locations = []errors.Location{l.Location()}
+				return
 			}
 			panic(err)
 		}
@@ -82,6 +140,26 @@ func (l *Lexer) Consume() {
 
 		break
 	}
+
+	l.pos = l.sc.Position // reading strings with Next invalidates the position of the scanner
+	l.tokens++
+	if l.limits.MaxTokens > 0 && l.tokens > l.limits.MaxTokens {
+		panic(limitError(fmt.Sprintf("document exceeds the limit of %d tokens", l.limits.MaxTokens)))
+	}
+	switch l.next {
+	case '"':
+		l.scanString()
+	case scanner.Int, scanner.Float:
+		l.checkNumber()
+	}
+}
+
+// tokenText returns the text of the current token.
+func (l *Lexer) tokenText() string {
+	if l.next == scanner.String {
+		return l.text
+	}
+	return l.sc.TokenText()
 }
 
 func (l *Lexer) ConsumeIdent() string {
@@ -99,36 +177,63 @@ func (l *Lexer) ConsumeIdentWithLoc() Ident {
 
 func (l *Lexer) ConsumeKeyword(keyword string) {
 	if l.next != scanner.Ident || l.sc.TokenText() != keyword {
-		l.SyntaxError(fmt.Sprintf("unexpected %q, expecting %q", l.sc.TokenText(), keyword))
+		l.SyntaxError(fmt.Sprintf("unexpected %q, expecting %q", l.tokenText(), keyword))
 	}
 	l.Consume()
 }
 
 func (l *Lexer) ConsumeLiteral() *BasicLit {
-	lit := &BasicLit{Type: l.next, Text: l.sc.TokenText()}
+	lit := &BasicLit{Type: l.next, Text: l.tokenText()}
 	l.Consume()
 	return lit
 }
 
 func (l *Lexer) ConsumeToken(expected rune) {
 	if l.next != expected {
-		l.SyntaxError(fmt.Sprintf("unexpected %q, expecting %s", l.sc.TokenText(), scanner.TokenString(expected)))
+		l.SyntaxError(fmt.Sprintf("unexpected %q, expecting %s", l.tokenText(), scanner.TokenString(expected)))
 	}
 	l.Consume()
 }
 
+// Definition counts a definition of the document against MaxDefinitions.
+func (l *Lexer) Definition() {
+	l.definitions++
+	if l.limits.MaxDefinitions > 0 && l.definitions > l.limits.MaxDefinitions {
+		panic(limitError(fmt.Sprintf("document exceeds the limit of %d definitions", l.limits.MaxDefinitions)))
+	}
+}
+
 func (l *Lexer) DescComment() string {
 	return l.descComment
 }
 
+// Description consumes the description string of the following definition, if it has one, and
+// returns its value. Otherwise it returns the comments preceding the definition, see DescComment.
+func (l *Lexer) Description() string {
+	if l.next != scanner.String {
+		return l.descComment
+	}
+	desc, err := strconv.Unquote(l.text)
+	if err != nil {
+		panic(err)
+	}
+	l.ConsumeToken(scanner.String)
+	return desc
+}
+
 func (l *Lexer) SyntaxError(message string) {
 	panic(syntaxError(message))
 }
 
+// syntaxErrorAt reports a syntax error at the position within the current token.
+func (l *Lexer) syntaxErrorAt(pos scanner.Position, message string) {
+	panic(locatedSyntaxError{message, errors.Location{Line: pos.Line, Column: pos.Column}})
+}
+
 func (l *Lexer) Location() errors.Location {
 	return errors.Location{
-		Line:   l.sc.Line,
-		Column: l.sc.Column,
+		Line:   l.pos.Line,
+		Column: l.pos.Column,
 	}
 }
 
@@ -159,3 +264,273 @@ func (l *Lexer) consumeComment() {
 		l.descComment += string(next)
 	}
 }
+
+// scanString reads a string or a block string, whose opening quote was scanned, and makes it the
+// current String token. Its text is the value quoted like a Go string literal, so that
+// strconv.Unquote returns the value, and it is valid GraphQL as well, so that it can be printed as
+// is.
+func (l *Lexer) scanString() {
+	l.next = scanner.String
+	if l.sc.Peek() == '"' {
+		l.sc.Next()
+		if l.sc.Peek() != '"' {
+			l.text = `""`
+			return
+		}
+		l.sc.Next()
+		l.text = quote(blockStringValue(l.scanBlockString()))
+		return
+	}
+
+	var b strings.Builder
+	for {
+		pos := l.sc.Pos()
+		c := l.sc.Next()
+		switch {
+		case c == '"':
+			l.text = quote(b.String())
+			return
+		case c == scanner.EOF || c == '\n' || c == '\r':
+			l.syntaxErrorAt(pos, "unterminated string")
+		case c < 0x20 && c != '\t':
+			l.syntaxErrorAt(pos, fmt.Sprintf("invalid character %U in string", c))
+		case c == '\\':
+			b.WriteRune(l.scanEscape(pos))
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+// scanEscape reads the escape sequence following the backslash at pos.
+func (l *Lexer) scanEscape(pos scanner.Position) rune {
+	c := l.sc.Next()
+	switch c {
+	case '"', '\\', '/':
+		return c
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'u':
+		r, braced := l.scanUnicode(pos)
+		if braced && (r > unicode.MaxRune || utf16.IsSurrogate(r)) {
+			l.syntaxErrorAt(pos, fmt.Sprintf("invalid unicode escape sequence: %U is not a scalar value", r))
+		}
+		if braced || !utf16.IsSurrogate(r) {
+			return r
+		}
+		// a leading surrogate has to be followed by the escape sequence of a trailing one
+		if r < 0xdc00 && l.sc.Peek() == '\\' {
+			trailingPos := l.sc.Pos()
+			l.sc.Next()
+			if l.sc.Next() == 'u' {
+				trailing, braced := l.scanUnicode(trailingPos)
+				if combined := utf16.DecodeRune(r, trailing); !braced && combined != unicode.ReplacementChar {
+					return combined
+				}
+			}
+		}
+		l.syntaxErrorAt(pos, fmt.Sprintf("invalid unicode escape sequence: unpaired surrogate %U", r))
+	case scanner.EOF:
+		l.syntaxErrorAt(pos, "unterminated string")
+	}
+	l.syntaxErrorAt(pos, fmt.Sprintf("invalid escape sequence %q", "\\"+string(c)))
+	panic("unreachable")
+}
+
+// scanUnicode reads the code point of a \\u escape sequence at pos, either four hexadecimal digits
+// or, if braced is true, any number of them in braces.
+func (l *Lexer) scanUnicode(pos scanner.Position) (r rune, braced bool) {
+	braced = l.sc.Peek() == '{'
+	if braced {
+		l.sc.Next()
+	}
+	digits := 0
+	for {
+		if braced && l.sc.Peek() == '}' && digits > 0 {
+			l.sc.Next()
+			return r, true
+		}
+		d, ok := hexDigit(l.sc.Peek())
+		if !ok {
+			l.syntaxErrorAt(pos, "invalid unicode escape sequence")
+		}
+		l.sc.Next()
+		if r <= unicode.MaxRune {
+			r = r<<4 | d // larger values are rejected by the caller
+		}
+		digits++
+		if !braced && digits == 4 {
+			return r, false
+		}
+	}
+}
+
+func hexDigit(c rune) (rune, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// scanBlockString reads the raw value of a block string, whose opening quotes were scanned.
+func (l *Lexer) scanBlockString() string {
+	var b strings.Builder
+	quotes := 0 // the number of quotes just read
+	for {
+		pos := l.sc.Pos()
+		c := l.sc.Next()
+		switch c {
+		case '"':
+			quotes++
+			if quotes == 3 {
+				return b.String()
+			}
+			continue
+		case '\\':
+			if quotes == 0 && l.sc.Peek() == '"' {
+				// \""" is an escaped triple quote, anything else is taken as is
+				l.sc.Next()
+				if l.sc.Peek() == '"' {
+					l.sc.Next()
+					if l.sc.Peek() == '"' {
+						l.sc.Next()
+						b.WriteString(`"""`)
+						continue
+					}
+					b.WriteString(`\""`)
+					continue
+				}
+				b.WriteString(`\"`)
+				continue
+			}
+		case scanner.EOF:
+			l.syntaxErrorAt(pos, "unterminated block string")
+		}
+		b.WriteString(strings.Repeat(`"`, quotes))
+		quotes = 0
+		b.WriteRune(c)
+	}
+}
+
+// blockStringValue removes the common indentation of the lines of a block string but the first,
+// and the leading and trailing blank lines.
+func blockStringValue(raw string) string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	lines := strings.Split(strings.ReplaceAll(raw, "\r", "\n"), "\n")
+	commonIndent := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (commonIndent < 0 || indent < commonIndent) {
+			commonIndent = indent
+		}
+	}
+	if commonIndent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) < commonIndent {
+				lines[i] = ""
+			} else {
+				lines[i] = lines[i][commonIndent:]
+			}
+		}
+	}
+	for len(lines) > 0 && strings.Trim(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.Trim(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quote returns the string literal of the value, which is valid in both Go and GraphQL.
+func quote(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// checkNumber rejects the forms of numbers that text/scanner accepts but GraphQL does not, e.g.
+// hexadecimal integers, underscores, leading zeros and fractions without digits, and numbers that
+// are directly followed by a name or a dot.
+func (l *Lexer) checkNumber() {
+	text := l.sc.TokenText()
+	if !isNumber(text) {
+		l.SyntaxError(fmt.Sprintf("invalid number %q", text))
+	}
+	if c := l.sc.Peek(); c == '.' || c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c) {
+		l.SyntaxError(fmt.Sprintf("invalid number %q", text+string(c)))
+	}
+}
+
+// isNumber reports whether the text is an IntValue or a FloatValue without the sign.
+func isNumber(text string) bool {
+	digits := func(s string) int {
+		n := 0
+		for n < len(s) && '0' <= s[n] && s[n] <= '9' {
+			n++
+		}
+		return n
+	}
+	n := digits(text)
+	if n == 0 || n > 1 && text[0] == '0' {
+		return false
+	}
+	text = text[n:]
+	if strings.HasPrefix(text, ".") {
+		n := digits(text[1:])
+		if n == 0 {
+			return false
+		}
+		text = text[1+n:]
+	}
+	if strings.HasPrefix(text, "e") || strings.HasPrefix(text, "E") {
+		text = text[1:]
+		if strings.HasPrefix(text, "+") || strings.HasPrefix(text, "-") {
+			text = text[1:]
+		}
+		n := digits(text)
+		if n == 0 {
+			return false
+		}
+		text = text[n:]
+	}
+	return text == ""
+}
@@ -1,6 +1,7 @@
 package common_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/qdentity/graphql-go/internal/common"
@@ -41,3 +42,65 @@ func TestConsume(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLiteral(t *testing.T) {
+	for _, test := range []struct {
+		literal string
+		value   interface{}
+		err     string
+	}{
+		{literal: `"plain"`, value: "plain"},
+		{literal: `""`, value: ""},
+		{literal: `"quote \" slash \/ backslash \\ \b\f\n\r\t"`, value: "quote \" slash / backslash \\ \b\f\n\r\t"},
+		{literal: `"é \u{1F600} 😀"`, value: "é 😀 😀"},
+		{literal: `"ü # not a comment"`, value: "ü # not a comment"},
+		{literal: "\"\"\"\n    Hello,\n      World!\n\n    Yours,\n      \\\"\"\" GraphQL\n  \"\"\"", value: "Hello,\n  World!\n\nYours,\n  \"\"\" GraphQL"},
+		{literal: `"""  raw \n "quotes" """`, value: `  raw \n "quotes" `},
+		{literal: `-1.5e-3`, value: -1.5e-3},
+		{literal: `2E+2`, value: 200.0},
+		{literal: `0`, value: int32(0)},
+		{literal: `[[1], {a: [{b: "c"}]}, []]`, value: []interface{}{[]interface{}{int32(1)}, map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "c"}}}, []interface{}{}}},
+		{literal: "\"line\nbreak\"", err: `graphql: syntax error: unterminated string (line 1, column 6)`},
+		{literal: `"bad \q escape"`, err: `graphql: syntax error: invalid escape sequence "\\q" (line 1, column 6)`},
+		{literal: `"lone \uD83D surrogate"`, err: `graphql: syntax error: invalid unicode escape sequence: unpaired surrogate U+D83D (line 1, column 7)`},
+		{literal: `"\uDE00"`, err: `graphql: syntax error: invalid unicode escape sequence: unpaired surrogate U+DE00 (line 1, column 2)`},
+		{literal: `"\u{D83D}"`, err: `graphql: syntax error: invalid unicode escape sequence: U+D83D is not a scalar value (line 1, column 2)`},
+		{literal: `"\u{110000}"`, err: `graphql: syntax error: invalid unicode escape sequence: U+110000 is not a scalar value (line 1, column 2)`},
+		{literal: `"\u12"`, err: `graphql: syntax error: invalid unicode escape sequence (line 1, column 2)`},
+		{literal: `"""never closed`, err: `graphql: syntax error: unterminated block string (line 1, column 16)`},
+		{literal: `007`, err: `graphql: syntax error: invalid number "007" (line 1, column 1)`},
+		{literal: `0x1F`, err: `graphql: syntax error: invalid number "0x1F" (line 1, column 1)`},
+		{literal: `1.`, err: `graphql: syntax error: invalid number "1." (line 1, column 1)`},
+		{literal: `12abc`, err: `graphql: syntax error: invalid number "12a" (line 1, column 1)`},
+		{literal: `-"minus"`, err: `graphql: syntax error: invalid value (line 1, column 2)`},
+	} {
+		l := common.NewLexer(test.literal)
+		var lit common.Literal
+		err := l.CatchSyntaxError(func() {
+			l.Consume()
+			lit = common.ParseLiteral(l, true)
+		})
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("got error %v for %s, want %s", err, test.literal, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("got error %v for %s", err, test.literal)
+			continue
+		}
+		if got := lit.Value(nil); !reflect.DeepEqual(got, test.value) {
+			t.Errorf("got %#v for %s, want %#v", got, test.literal, test.value)
+		}
+		// the printed literal is parsed to the same value
+		l = common.NewLexer(lit.String())
+		err = l.CatchSyntaxError(func() {
+			l.Consume()
+			lit = common.ParseLiteral(l, true)
+		})
+		if err != nil || !reflect.DeepEqual(lit.Value(nil), test.value) {
+			t.Errorf("could not parse %s printed as %s: %v", test.literal, lit, err)
+		}
+	}
+}
@@ -104,6 +104,9 @@ type ObjectLitField struct {
 func (lit *ObjectLit) Value(vars map[string]interface{}) interface{} {
 	fields := make(map[string]interface{}, len(lit.Fields))
 	for _, f := range lit.Fields {
+		if IsMissingVariable(f.Value, vars) {
+			continue // an omitted variable leaves the field unset, so its default value applies
+		}
 		fields[f.Name.Name] = f.Value.Value(vars)
 	}
 	return fields
@@ -154,6 +157,49 @@ func (v *Variable) Location() errors.Location {
 	return v.Loc
 }
 
+// IsMissingVariable reports whether the literal is a variable that is not set in vars.
+func IsMissingVariable(lit Literal, vars map[string]interface{}) bool {
+	v, ok := lit.(*Variable)
+	if !ok {
+		return false
+	}
+	_, set := vars[v.Name]
+	return !set
+}
+
+// HasVariables reports whether the literal is or contains a variable.
+func HasVariables(lit Literal) bool {
+	switch lit := lit.(type) {
+	case *Variable:
+		return true
+	case *ListLit:
+		for _, entry := range lit.Entries {
+			if HasVariables(entry) {
+				return true
+			}
+		}
+	case *ObjectLit:
+		for _, f := range lit.Fields {
+			if HasVariables(f.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxLiteralDepth bounds the nesting of lists and objects in literals, so that hostile documents
+// can not exhaust the stack of the parser and of the functions walking the literals. Stricter limits
+// are applied by validation, see validation.Limits.MaxInputDepth.
+const maxLiteralDepth = 1000
+
+func (l *Lexer) nestValue() {
+	l.valueDepth++
+	if l.valueDepth > maxLiteralDepth {
+		l.SyntaxError("value nested more than " + strconv.Itoa(maxLiteralDepth) + " levels deep")
+	}
+}
+
 func ParseLiteral(l *Lexer, constOnly bool) Literal {
 	loc := l.Location()
 	switch l.Peek() {
@@ -174,21 +220,27 @@ func ParseLiteral(l *Lexer, constOnly bool) Literal {
 		return lit
 	case '-':
 		l.ConsumeToken('-')
+		if l.Peek() != scanner.Int && l.Peek() != scanner.Float {
+			l.SyntaxError("invalid value")
+		}
 		lit := l.ConsumeLiteral()
 		lit.Text = "-" + lit.Text
 		lit.Loc = loc
 		return lit
 	case '[':
 		l.ConsumeToken('[')
+		l.nestValue()
 		var list []Literal
 		for l.Peek() != ']' {
 			list = append(list, ParseLiteral(l, constOnly))
 		}
+		l.valueDepth--
 		l.ConsumeToken(']')
 		return &ListLit{list, loc}
 
 	case '{':
 		l.ConsumeToken('{')
+		l.nestValue()
 		var fields []*ObjectLitField
 		for l.Peek() != '}' {
 			name := l.ConsumeIdentWithLoc()
@@ -196,6 +248,7 @@ func ParseLiteral(l *Lexer, constOnly bool) Literal {
 			value := ParseLiteral(l, constOnly)
 			fields = append(fields, &ObjectLitField{name, value})
 		}
+		l.valueDepth--
 		l.ConsumeToken('}')
 		return &ObjectLit{fields, loc}
 
@@ -6,12 +6,13 @@ import (
 
 // http://facebook.github.io/graphql/draft/#InputValueDefinition
 type InputValue struct {
-	Name    Ident
-	Type    Type
-	Default Literal
-	Desc    string
-	Loc     errors.Location
-	TypeLoc errors.Location
+	Name       Ident
+	Type       Type
+	Default    Literal
+	Directives DirectiveList
+	Desc       string
+	Loc        errors.Location
+	TypeLoc    errors.Location
 }
 
 type InputValueList []*InputValue
@@ -27,8 +28,8 @@ func (l InputValueList) Get(name string) *InputValue {
 
 func ParseInputValue(l *Lexer) *InputValue {
 	p := &InputValue{}
+	p.Desc = l.Description()
 	p.Loc = l.Location()
-	p.Desc = l.DescComment()
 	p.Name = l.ConsumeIdentWithLoc()
 	l.ConsumeToken(':')
 	p.TypeLoc = l.Location()
@@ -37,6 +38,7 @@ func ParseInputValue(l *Lexer) *InputValue {
 		l.ConsumeToken('=')
 		p.Default = ParseLiteral(l, true)
 	}
+	p.Directives = ParseDirectives(l)
 	return p
 }
 
@@ -0,0 +1,107 @@
+package exec
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/exec/selected"
+)
+
+// ResolverCache caches the root resolvers returned by the constructor of a schema under a key
+// derived from the context of the request, see graphql.CacheResolvers. It is safe for concurrent
+// use.
+type ResolverCache struct {
+	key     func(ctx context.Context) string
+	mu      sync.Mutex
+	size    int
+	lru     *list.List // of *resolverEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type resolverEntry struct {
+	key      string
+	resolver reflect.Value
+}
+
+// NewResolverCache returns a cache of up to size root resolvers. Resolvers are not cached for
+// requests for which key returns "".
+func NewResolverCache(size int, key func(ctx context.Context) string) *ResolverCache {
+	return &ResolverCache{
+		key:     key,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *ResolverCache) get(key string) (reflect.Value, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*resolverEntry).resolver, true
+}
+
+func (c *ResolverCache) add(key string, resolver reflect.Value) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*resolverEntry).resolver = resolver
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&resolverEntry{key: key, resolver: resolver})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resolverEntry).key)
+	}
+}
+
+// rootResolver returns the root resolver of the request. If the schema has a constructor, it is
+// called only if a root field is resolved by a method, and at most once per request.
+func (r *Request) rootResolver(ctx context.Context, s *resolvable.Schema, sels []selected.Selection) (reflect.Value, *errors.QueryError) {
+	if !s.Constructor.IsValid() || !needsResolver(sels) {
+		return s.Resolver, nil
+	}
+
+	var key string
+	if r.ResolverCache != nil {
+		key = r.ResolverCache.key(ctx)
+		if key != "" {
+			if resolver, ok := r.ResolverCache.get(key); ok {
+				return resolver, nil
+			}
+		}
+	}
+
+	out := s.Constructor.Call([]reflect.Value{reflect.ValueOf(ctx)})
+	if !out[1].IsNil() {
+		constructorErr := out[1].Interface().(error)
+		err := errors.Errorf("%s", constructorErr)
+		err.OriginalError = constructorErr
+		return reflect.Value{}, err
+	}
+	if key != "" {
+		r.ResolverCache.add(key, out[0])
+	}
+	return out[0], nil
+}
+
+// needsResolver reports whether any of the root selections is resolved by a method of the root
+// resolver, unlike introspection fields, delegated fields and fields resolved by field funcs.
+func needsResolver(sels []selected.Selection) bool {
+	for _, sel := range sels {
+		if f, ok := sel.(*selected.SchemaField); ok && !f.FixedResult.IsValid() && f.Delegate == nil && f.Func == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/exec/selected"
+	"github.com/qdentity/graphql-go/internal/query"
+)
+
+// delegate resolves the field with its delegate. It returns the value of the field as written by
+// the upstream service and the errors of its response. Their paths start at the root like the ones
+// of this request, since only root fields are delegated.
+func (r *Request) delegate(ctx context.Context, f *selected.SchemaField, path *pathSegment) (json.RawMessage, []*errors.QueryError) {
+	queryString, names := printDelegated(r.Doc, r.op, f.Delegated)
+	variables := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		if value, ok := r.Vars[name]; ok {
+			variables[name] = value
+		}
+	}
+
+	data, errs := f.Delegate(ctx, queryString, variables)
+	for _, err := range errs {
+		if err.Path == nil {
+			err.Path = path.toSlice()
+		}
+		err.Locations = nil // of the upstream query
+	}
+	if len(data) == 0 {
+		return nil, errs
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		qErr := errors.Errorf("invalid response of upstream service: %s", err)
+		qErr.Path = path.toSlice()
+		qErr.OriginalError = err
+		return nil, append(errs, qErr)
+	}
+	return fields[f.Alias], errs
+}
+
+// printDelegated prints an operation selecting only the field, together with the fragments and
+// variables it uses. It returns the names of the variables.
+func printDelegated(doc *query.Document, op *query.Operation, field *query.Field) (string, []string) {
+	p := &printer{doc: doc, vars: make(map[string]struct{}), fragments: make(map[string]struct{})}
+	var body strings.Builder
+	body.WriteString("{ ")
+	p.printField(&body, field)
+	body.WriteString(" }")
+
+	// fragments are printed after collecting the variables and fragments they use in turn
+	var fragments strings.Builder
+	for i := 0; i < len(p.fragmentOrder); i++ {
+		frag := doc.Fragments.Get(p.fragmentOrder[i])
+		fragments.WriteString(" fragment " + frag.Name.Name + " on " + frag.On.Name)
+		p.printDirectives(&fragments, frag.Directives)
+		fragments.WriteByte(' ')
+		p.printSelections(&fragments, frag.Selections)
+	}
+
+	names := make([]string, 0, len(p.vars))
+	for name := range p.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	if op.Type == query.Mutation {
+		out.WriteString("mutation")
+	} else {
+		out.WriteString("query")
+	}
+	if len(names) != 0 {
+		out.WriteByte('(')
+		for i, name := range names {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString("$" + name + ": ")
+			if v := op.Vars.Get(name); v != nil {
+				out.WriteString(printType(v.Type))
+			}
+		}
+		out.WriteByte(')')
+	}
+	out.WriteByte(' ')
+	out.WriteString(body.String())
+	out.WriteString(fragments.String())
+	return out.String(), names
+}
+
+type printer struct {
+	doc           *query.Document
+	vars          map[string]struct{}
+	fragments     map[string]struct{}
+	fragmentOrder []string
+}
+
+func (p *printer) printSelections(b *strings.Builder, sels []query.Selection) {
+	b.WriteString("{")
+	for _, sel := range sels {
+		b.WriteByte(' ')
+		switch sel := sel.(type) {
+		case *query.Field:
+			p.printField(b, sel)
+		case *query.InlineFragment:
+			b.WriteString("...")
+			if sel.On.Name != "" {
+				b.WriteString(" on " + sel.On.Name)
+			}
+			p.printDirectives(b, sel.Directives)
+			b.WriteByte(' ')
+			p.printSelections(b, sel.Selections)
+		case *query.FragmentSpread:
+			b.WriteString("..." + sel.Name.Name)
+			p.printDirectives(b, sel.Directives)
+			if _, ok := p.fragments[sel.Name.Name]; !ok {
+				p.fragments[sel.Name.Name] = struct{}{}
+				p.fragmentOrder = append(p.fragmentOrder, sel.Name.Name)
+			}
+		}
+	}
+	b.WriteString(" }")
+}
+
+func (p *printer) printField(b *strings.Builder, f *query.Field) {
+	if f.Alias.Name != f.Name.Name {
+		b.WriteString(f.Alias.Name + ": ")
+	}
+	b.WriteString(f.Name.Name)
+	p.printArguments(b, f.Arguments)
+	p.printDirectives(b, f.Directives)
+	if len(f.Selections) != 0 {
+		b.WriteByte(' ')
+		p.printSelections(b, f.Selections)
+	}
+}
+
+func (p *printer) printArguments(b *strings.Builder, args common.ArgumentList) {
+	if len(args) == 0 {
+		return
+	}
+	b.WriteByte('(')
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(arg.Name.Name + ": " + arg.Value.String())
+		p.collectVars(arg.Value)
+	}
+	b.WriteByte(')')
+}
+
+func (p *printer) printDirectives(b *strings.Builder, directives common.DirectiveList) {
+	for _, d := range directives {
+		b.WriteString(" @" + d.Name.Name)
+		p.printArguments(b, d.Args)
+	}
+}
+
+func (p *printer) collectVars(lit common.Literal) {
+	switch lit := lit.(type) {
+	case *common.Variable:
+		p.vars[lit.Name] = struct{}{}
+	case *common.ListLit:
+		for _, entry := range lit.Entries {
+			p.collectVars(entry)
+		}
+	case *common.ObjectLit:
+		for _, f := range lit.Fields {
+			p.collectVars(f.Value)
+		}
+	}
+}
+
+// printType prints the type of a variable as written in the query.
+func printType(t common.Type) string {
+	switch t := t.(type) {
+	case *common.TypeName:
+		return t.Name
+	case *common.List:
+		return "[" + printType(t.OfType) + "]"
+	case *common.NonNull:
+		return printType(t.OfType) + "!"
+	default:
+		return t.String()
+	}
+}
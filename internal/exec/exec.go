@@ -4,8 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	stdlog "log"
 	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/qdentity/graphql-go/errors"
 	"github.com/qdentity/graphql-go/internal/common"
@@ -15,6 +23,7 @@ import (
 	"github.com/qdentity/graphql-go/internal/schema"
 	"github.com/qdentity/graphql-go/log"
 	pubquery "github.com/qdentity/graphql-go/query"
+	"github.com/qdentity/graphql-go/recording"
 	"github.com/qdentity/graphql-go/trace"
 )
 
@@ -23,47 +32,255 @@ type Request struct {
 	Limiter chan struct{}
 	Tracer  trace.Tracer
 	Logger  log.Logger
+	Breaker CircuitBreaker
+	Auth    *Auth
+
+	// PanicHandler is called with every recovered panic, see graphql.PanicHandler.
+	PanicHandler func(ctx context.Context, value interface{}, path []interface{}, stack []byte)
+
+	// Cache, if set, caches the selections of the operation under CacheKey.
+	Cache    *selected.OperationCache
+	CacheKey string
+
+	// Synchronous resolves all fields on the goroutine calling Execute, one after the other.
+	Synchronous bool
+
+	// ListWorkers, if positive, is the maximum number of goroutines resolving the entries of a list
+	// with async fields. Otherwise each entry is resolved by its own goroutine.
+	ListWorkers int
+
+	// Record, if set, records the values of the fields. Replay, if set, serves the recorded values
+	// instead of calling resolvers.
+	Record *recording.Fixture
+	Replay *recording.Fixture
+
+	// ResolverCache, if set, caches the root resolvers returned by the constructor of the schema.
+	ResolverCache *ResolverCache
+
+	// TouchEntity, if set, is called with the key "Type:id" of every object whose id field is
+	// selected, see graphql.LiveQueries. It may be called concurrently.
+	TouchEntity func(key string)
+
+	// WriteHidden writes the hidden fields too, which still never make their objects null, see
+	// graphql.InjectedAliases.
+	WriteHidden bool
+
+	// StrictScalars checks that the values of the builtin scalar types are written as such, see
+	// graphql.StrictScalars.
+	StrictScalars bool
+
+	// ValidateRawJSON checks the values of fields whose resolvers return JSON against their types
+	// and selections, see graphql.ValidateRawJSON.
+	ValidateRawJSON bool
+
+	// NoHTMLEscape writes <, > and & of strings as they are, see graphql.DisableHTMLEscaping.
+	NoHTMLEscape bool
+
+	// SlowFields, if positive, adds a warning for every field whose resolver takes longer, see
+	// graphql.WarnSlowFields.
+	SlowFields time.Duration
+
+	// Warnings are the errors of optional fields, see graphql.OptionalFields, and the slow fields.
+	Warnings []*errors.QueryError
+
+	op          *query.Operation
+	scalarTypes map[reflect.Type]string
+	mu          sync.Mutex
+	interrupted []string // paths of fields whose resolvers were running when the context was done
+	loaders     *Loaders
+}
+
+// Auth evaluates the authorization rules of fields, see graphql.Authorization.
+type Auth struct {
+	Principal  func(ctx context.Context) interface{}
+	HasRole    func(principal interface{}, role string) bool
+	SilentNull bool
 }
 
-func (r *Request) handlePanic(ctx context.Context) {
+// authorize returns an error if the principal of the request is not allowed to access the field.
+// Without an Auth configuration every restricted field is denied.
+func (r *Request) authorize(ctx context.Context, f *selected.SchemaField) *errors.QueryError {
+	var principal interface{}
+	if r.Auth != nil && r.Auth.Principal != nil {
+		principal = r.Auth.Principal(ctx)
+	}
+	if principal == nil {
+		err := errors.Errorf("not authenticated to access field %s.%s", f.TypeName, f.Name)
+		err.Extensions = map[string]interface{}{"code": "UNAUTHENTICATED"}
+		return err
+	}
+	if len(f.Auth.Roles) == 0 {
+		return nil
+	}
+	if r.Auth.HasRole != nil {
+		for _, role := range f.Auth.Roles {
+			if r.Auth.HasRole(principal, role) {
+				return nil
+			}
+		}
+	}
+	err := errors.Errorf("not authorized to access field %s.%s", f.TypeName, f.Name)
+	err.Extensions = map[string]interface{}{"code": "FORBIDDEN"}
+	return err
+}
+
+// CircuitBreaker mirrors graphql.CircuitBreaker.
+type CircuitBreaker interface {
+	Allow(ctx context.Context, field string) (done func(err error), ok bool)
+}
+
+func (r *Request) handlePanic(ctx context.Context, path *pathSegment) {
+	if value := recover(); value != nil {
+		stack := debug.Stack()
+		r.logPanic(ctx, value, path, stack)
+		err := panicError(value, stack)
+		err.Path = path.toSlice()
+		r.AddError(err)
+	}
+}
+
+// logPanic passes a recovered panic to the logger and the panic handler, together with the path of
+// the field and the stack of the panicking goroutine.
+func (r *Request) logPanic(ctx context.Context, value interface{}, path *pathSegment, stack []byte) {
+	p := path.toSlice()
+	func() {
+		defer r.recoverCallback(ctx, "logger")
+		if l, ok := r.Logger.(log.StackLogger); ok {
+			l.LogPanicStack(ctx, value, p, stack)
+		} else {
+			r.Logger.LogPanic(ctx, value)
+		}
+	}()
+	if r.PanicHandler != nil {
+		defer r.recoverCallback(ctx, "panic handler")
+		r.PanicHandler(ctx, value, p, stack)
+	}
+}
+
+// recoverCallback recovers a panic of the component, e.g. the tracer, and logs it. It has to be
+// deferred around the call of the component.
+func (r *Request) recoverCallback(ctx context.Context, component string) {
 	if value := recover(); value != nil {
-		r.Logger.LogPanic(ctx, value)
-		r.AddError(panicError(value))
+		LogCallbackPanic(ctx, r.Logger, component, value, debug.Stack())
+	}
+}
+
+// LogCallbackPanic logs a recovered panic of a callback of the schema as a log.CallbackPanic. If
+// the logger panics too, or the logger is the component, the panic is written to the standard
+// logger instead.
+func LogCallbackPanic(ctx context.Context, logger log.Logger, component string, value interface{}, stack []byte) {
+	p := &log.CallbackPanic{Component: component, Value: value}
+	if component != "logger" {
+		defer func() {
+			if value := recover(); value != nil {
+				LogCallbackPanic(ctx, logger, "logger", value, debug.Stack())
+			}
+		}()
+		if l, ok := logger.(log.StackLogger); ok {
+			l.LogPanicStack(ctx, p, nil, stack)
+		} else {
+			logger.LogPanic(ctx, p)
+		}
+		return
 	}
+	stdlog.Printf("graphql: %v\n%s", p, stack)
 }
 
-func panicError(value interface{}) *errors.QueryError {
-	err := errors.Errorf("internal server error")
+func panicError(value interface{}, stack []byte) *errors.QueryError {
+	err := errors.Errorf("internal server error").WithCode(errors.CodeInternal)
 	err.PanicValue = value
+	err.PanicStack = stack
+	if valueErr, ok := value.(error); ok {
+		err.OriginalError = valueErr // e.g. of panic(err) in a resolver
+	}
 	return err
 }
 
+// contextError returns the error of a request whose context is done. Its "code" extension is
+// DEADLINE_EXCEEDED or CANCELLED depending on the reason.
+func contextError(err error) *errors.QueryError {
+	code := "CANCELLED"
+	if err == context.DeadlineExceeded {
+		code = "DEADLINE_EXCEEDED"
+	}
+	qErr := errors.Errorf("%s", err)
+	qErr.OriginalError = err
+	qErr.Extensions = map[string]interface{}{"code": code}
+	return qErr
+}
+
+func (r *Request) addInterrupted(path *pathSegment) {
+	segments := path.toSlice()
+	parts := make([]string, len(segments))
+	for i, s := range segments {
+		parts[i] = fmt.Sprint(s)
+	}
+	r.mu.Lock()
+	r.interrupted = append(r.interrupted, strings.Join(parts, "."))
+	r.mu.Unlock()
+}
+
 func (r *Request) Execute(ctx context.Context, s *resolvable.Schema, op *query.Operation) ([]byte, []*errors.QueryError) {
+	start := time.Now()
+	r.op = op
+	r.scalarTypes = s.ScalarTypes
+	r.UseArena()
+	r.loaders, ctx = newLoaders(ctx)
+	r.loaders.OnRelease(r.Release) // hedged calls that lost may still use the selections
+	r.loaders.enter(1)
+	defer r.loaders.finish()
 	var out bytes.Buffer
+	var ok bool
 	func() {
-		defer r.handlePanic(ctx)
-		sels := selected.ApplyOperation(&r.Request, s, op)
-		r.execSelections(ctx, sels, nil, s.Resolver, &out, op.Type == query.Mutation)
+		defer r.handlePanic(ctx, nil)
+		var sels []selected.Selection
+		if r.Cache != nil {
+			sels = r.Cache.Apply(&r.Request, s, op, r.CacheKey)
+		} else {
+			sels = selected.ApplyOperation(&r.Request, s, op)
+		}
+		resolver, err := r.rootResolver(ctx, s, sels)
+		if err != nil {
+			r.AddError(err)
+			return
+		}
+		ok = r.execSelections(ctx, sels, nil, resolver, &out, op.Type == query.Mutation)
 	}()
 
 	if err := ctx.Err(); err != nil {
-		qErr := errors.Errorf("%s", err)
-		qErr.OriginalError = err
+		qErr := contextError(err)
+		qErr.Extensions["elapsedMs"] = time.Since(start).Nanoseconds() / int64(time.Millisecond)
+		if len(r.interrupted) != 0 {
+			sort.Strings(r.interrupted)
+			qErr.Extensions["inFlight"] = r.interrupted
+		}
 		return nil, []*errors.QueryError{qErr}
 	}
 
+	if !ok {
+		return []byte("null"), r.Errs // a non-null root field is null
+	}
 	return out.Bytes(), r.Errs
 }
 
 type fieldToExec struct {
 	field    *selected.SchemaField
 	sels     []selected.Selection
+	rawSels  []query.Selection // of a field whose resolver returns JSON, see selected.SchemaField.RawSels
 	resolver reflect.Value
 	out      *bytes.Buffer
+	ok       bool
+	group    int // the number of fields resolved in parallel with it
 }
 
-func (r *Request) execSelections(ctx context.Context, sels []selected.Selection, path *pathSegment, resolver reflect.Value, out *bytes.Buffer, serially bool) {
-	async := !serially && selected.HasAsyncSel(sels)
+// execSelections writes the object with the selected fields to out. It returns false if a field of
+// non-null type is null, in which case the object has to be replaced by null.
+func (r *Request) execSelections(ctx context.Context, sels []selected.Selection, path *pathSegment, resolver reflect.Value, out *bytes.Buffer, serially bool) bool {
+	resolver = addressable(resolver)
+	async := !serially && !r.Synchronous && selected.HasAsyncSel(sels)
+	if r.Record != nil {
+		r.recordType(path, sels, resolver)
+	}
 
 	var fields []*fieldToExec
 	collectFieldsToResolve(sels, resolver, &fields, make(map[string]*fieldToExec))
@@ -71,34 +288,139 @@ func (r *Request) execSelections(ctx context.Context, sels []selected.Selection,
 	if async {
 		var wg sync.WaitGroup
 		wg.Add(len(fields))
+		r.loaders.enter(len(fields))
 		for _, f := range fields {
+			f.group = len(fields)
 			go func(f *fieldToExec) {
 				defer wg.Done()
-				defer r.handlePanic(ctx)
+				defer r.loaders.leave()
+				fieldPath := r.fieldPath(path, f.field)
+				fieldPath.owner = f.resolver
+				defer r.handlePanic(ctx, fieldPath)
 				f.out = new(bytes.Buffer)
-				execFieldSelection(ctx, r, f, &pathSegment{path, f.field.Alias}, true)
+				f.ok = execFieldSelection(ctx, r, f, fieldPath, true)
 			}(f)
 		}
+		r.loaders.leave() // waiting for the fields may dispatch the batches of loaders
 		wg.Wait()
+		r.loaders.enter(1)
 	}
 
+	ok := true
+	entityType := entityType(fields)
 	out.WriteByte('{')
-	for i, f := range fields {
-		if i > 0 {
-			out.WriteByte(',')
+	written := 0
+	for _, f := range fields {
+		fieldOut := out
+		if f.field.Hidden && !r.WriteHidden {
+			fieldOut = new(bytes.Buffer) // resolved for its side effects, but not returned
+		} else {
+			if written > 0 {
+				out.WriteByte(',')
+			}
+			written++
+			out.WriteByte('"')
+			out.WriteString(f.field.Alias)
+			out.WriteByte('"')
+			out.WriteByte(':')
 		}
-		out.WriteByte('"')
-		out.WriteString(f.field.Alias)
-		out.WriteByte('"')
-		out.WriteByte(':')
 		if async {
-			out.Write(f.out.Bytes())
+			switch {
+			case !f.field.Hidden:
+				out.Write(f.out.Bytes())
+				ok = ok && f.ok
+			case r.WriteHidden && !f.ok:
+				out.WriteString("null")
+			case r.WriteHidden:
+				out.Write(f.out.Bytes())
+			}
+			r.touchEntity(entityType, f.field, f.out.Bytes())
 			continue
 		}
-		f.out = out
-		execFieldSelection(ctx, r, f, &pathSegment{path, f.field.Alias}, false)
+		f.out = fieldOut
+		start := fieldOut.Len()
+		fieldPath := r.fieldPath(path, f.field)
+		fieldPath.owner = f.resolver
+		var fieldOK bool
+		if r.isPlain(f.field) {
+			fieldOK = r.execSelectionSet(ctx, f.sels, f.field.Type, fieldPath, structField(resolver, f.field.FieldIndex), fieldOut)
+		} else {
+			fieldOK = execFieldSelection(ctx, r, f, fieldPath, false)
+		}
+		if !fieldOK && !f.field.Hidden {
+			ok = false
+		} else if !fieldOK && r.WriteHidden {
+			fieldOut.Truncate(start)
+			fieldOut.WriteString("null")
+		}
+		r.touchEntity(entityType, f.field, fieldOut.Bytes()[start:])
 	}
 	out.WriteByte('}')
+	return ok
+}
+
+// entityType returns the type of the object if __typename is selected, which is more specific than
+// the type declaring its id field if that is an interface.
+func entityType(fields []*fieldToExec) string {
+	for _, f := range fields {
+		if f.field.Name == "__typename" && f.field.FixedResult.IsValid() {
+			return f.field.FixedResult.String()
+		}
+	}
+	return ""
+}
+
+// touchEntity passes the key of the object to TouchEntity if the field is its id, given as the
+// written value of the field. The key has the type of the object if known, otherwise the one
+// declaring the field.
+func (r *Request) touchEntity(typeName string, f *selected.SchemaField, value []byte) {
+	if r.TouchEntity == nil || f.Name != "id" || len(value) == 0 || string(value) == "null" {
+		return
+	}
+	if typeName == "" {
+		typeName = f.TypeName
+	}
+	id := string(value)
+	if unquoted, err := strconv.Unquote(id); err == nil {
+		id = unquoted
+	}
+	r.TouchEntity(typeName + ":" + id)
+}
+
+// isPlain reports whether the field is read from a struct field without any checks, so that it is
+// written directly instead of being traced and resolved like the fields of resolver methods.
+func (r *Request) isPlain(f *selected.SchemaField) bool {
+	return f.FieldIndex != nil && f.Auth == nil && !f.Optional && !f.RawJSON && r.Record == nil && r.Replay == nil
+}
+
+// addressable returns a pointer to a copy of the value if it is neither a pointer nor an interface,
+// since the objects of such types are resolved with the method set of the pointer type.
+func addressable(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Invalid, reflect.Ptr, reflect.Interface:
+		return v // invalid when no resolver is needed, see rootResolver
+	}
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
+
+// structField returns the struct field with the index of the struct or pointer to struct. The
+// value is the zero value of the field if an embedded pointer along the index is nil.
+func structField(v reflect.Value, index []int) reflect.Value {
+	for n, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Zero(v.Type().Elem().FieldByIndex(index[n:]).Type)
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
 }
 
 func collectFieldsToResolve(sels []selected.Selection, resolver reflect.Value, fields *[]*fieldToExec, fieldByAlias map[string]*fieldToExec) {
@@ -106,27 +428,44 @@ func collectFieldsToResolve(sels []selected.Selection, resolver reflect.Value, f
 		switch sel := sel.(type) {
 		case *selected.SchemaField:
 			field, ok := fieldByAlias[sel.Alias]
-			if !ok { // validation already checked for conflict (TODO)
+			if !ok { // validation checked that fields with the same alias can be merged
 				field = &fieldToExec{field: sel, resolver: resolver}
 				fieldByAlias[sel.Alias] = field
 				*fields = append(*fields, field)
 			}
 			field.sels = append(field.sels, sel.Sels...)
+			field.rawSels = append(field.rawSels, sel.RawSels...)
 
 		case *selected.TypenameField:
+			if field, ok := fieldByAlias[sel.Alias]; ok {
+				// selected by a fragment too, or injected, see graphql.InjectTypenames
+				if !sel.Hidden && field.field.Name == "__typename" {
+					field.field.Hidden = false
+				}
+				continue
+			}
 			sf := &selected.SchemaField{
 				Field:       resolvable.MetaFieldTypename,
 				Alias:       sel.Alias,
 				FixedResult: reflect.ValueOf(typeOf(sel, resolver)),
+				Hidden:      sel.Hidden,
 			}
-			*fields = append(*fields, &fieldToExec{field: sf, resolver: resolver})
+			field := &fieldToExec{field: sf, resolver: resolver}
+			fieldByAlias[sel.Alias] = field
+			*fields = append(*fields, field)
 
 		case *selected.TypeAssertion:
-			out := resolver.Method(sel.MethodIndex).Call(nil)
-			if !out[1].Bool() {
+			if obj, ok := replayed(resolver); ok {
+				if obj.typeName == sel.TypeExec.(*resolvable.Object).Name {
+					collectFieldsToResolve(sel.Sels, resolver, fields, fieldByAlias)
+				}
+				continue
+			}
+			converted, ok := assertType(&sel.TypeAssertion, resolver)
+			if !ok {
 				continue
 			}
-			collectFieldsToResolve(sel.Sels, out[0], fields, fieldByAlias)
+			collectFieldsToResolve(sel.Sels, converted, fields, fieldByAlias)
 
 		default:
 			panic("unreachable")
@@ -138,16 +477,112 @@ func typeOf(tf *selected.TypenameField, resolver reflect.Value) string {
 	if len(tf.TypeAssertions) == 0 {
 		return tf.Name
 	}
+	if obj, ok := replayed(resolver); ok {
+		return obj.typeName
+	}
 	for name, a := range tf.TypeAssertions {
-		out := resolver.Method(a.MethodIndex).Call(nil)
-		if out[1].Bool() {
+		if _, ok := assertType(a, resolver); ok {
 			return name
 		}
 	}
 	return ""
 }
 
-func selectionToSelectedFields(sels []selected.Selection) []pubquery.SelectedField {
+// assertType converts the resolver of an interface or union value to the object type of the
+// assertion. It reports false if the value is of another type, or if the resolver can not be
+// converted to the type, see resolvable.Options.Lenient.
+func assertType(a *resolvable.TypeAssertion, resolver reflect.Value) (reflect.Value, bool) {
+	if a.TypeOf != nil {
+		obj := a.TypeExec.(*resolvable.Object)
+		typeName, converted := a.TypeOf(resolver.Interface())
+		if typeName != obj.Name {
+			return reflect.Value{}, false
+		}
+		if t := reflect.TypeOf(converted); t != obj.ResolverType {
+			panic(fmt.Sprintf("type resolver returned %v for %q, want %s", t, typeName, obj.ResolverType))
+		}
+		return reflect.ValueOf(converted), true
+	}
+	if a.MethodIndex == -1 {
+		return reflect.Value{}, false
+	}
+	out := resolver.Method(a.MethodIndex).Call(nil)
+	return out[0], out[1].Bool()
+}
+
+// resolverIn returns the arguments of the call of the resolver method of the field.
+func (r *Request) resolverIn(ctx context.Context, f *fieldToExec, path *pathSegment) ([]reflect.Value, *errors.QueryError) {
+	var in []reflect.Value
+	if f.field.HasContext {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	if f.field.ArgsPacker != nil {
+		in = append(in, f.field.PackedArgs)
+	}
+	if f.field.HasRawArgs {
+		args := f.field.Args
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		raw, marshalErr := json.Marshal(args)
+		if marshalErr != nil {
+			err := errors.Errorf("could not encode the arguments: %s", marshalErr)
+			err.Path = path.toSlice()
+			err.OriginalError = marshalErr
+			return nil, err
+		}
+		in = append(in, reflect.ValueOf(pubquery.RawArgs(raw)))
+	}
+	if f.field.HasVars {
+		in = append(in, reflect.ValueOf(pubquery.Variables(r.Vars)))
+	}
+	if f.field.ParentType != nil {
+		parent, err := r.parent(f.field, path)
+		if err != nil {
+			return nil, err
+		}
+		in = append(in, parent)
+	}
+	if f.field.HasPath {
+		in = append(in, reflect.ValueOf(pubquery.Path(path.toSlice())))
+	}
+	if f.field.HasSelected && f.field.RawJSON {
+		in = append(in, reflect.ValueOf(r.rawSelectedFields(f.rawSels)))
+	} else if f.field.HasSelected {
+		in = append(in, reflect.ValueOf(r.selectionToSelectedFields(f.sels)))
+	}
+	return in, nil
+}
+
+// parent returns the query.Parent of the field at the path, with the resolver of the object whose
+// field returned the field's object, or its projection.
+func (r *Request) parent(f *selected.SchemaField, path *pathSegment) (reflect.Value, *errors.QueryError) {
+	parent := reflect.New(f.ParentType).Elem()
+	var owner reflect.Value
+	for p := path.parent; p != nil; p = p.parent {
+		if p.owner.IsValid() {
+			owner = p.owner
+			break
+		}
+	}
+	if !owner.IsValid() {
+		return parent, nil // a field of a root type
+	}
+	value := parent.Field(0)
+	if project, ok := f.ParentProjections[owner.Type()]; ok {
+		value.Set(project.Call([]reflect.Value{owner})[0])
+		return parent, nil
+	}
+	if !owner.Type().AssignableTo(value.Type()) {
+		err := errors.Errorf("can not use parent %s as %s", owner.Type(), value.Type())
+		err.Path = path.toSlice()
+		return reflect.Value{}, err
+	}
+	value.Set(owner)
+	return parent, nil
+}
+
+func (r *Request) selectionToSelectedFields(sels []selected.Selection) []pubquery.SelectedField {
 	n := len(sels)
 	if n == 0 {
 		return nil
@@ -155,34 +590,139 @@ func selectionToSelectedFields(sels []selected.Selection) []pubquery.SelectedFie
 	selectedFields := make([]pubquery.SelectedField, 0, n)
 	for _, sel := range sels {
 		selField, ok := sel.(*selected.SchemaField)
-		if ok {
+		if ok && !selField.Hidden {
+			var args map[string]interface{}
+			if len(selField.Args) != 0 {
+				args = selField.Args
+			}
+			subFields := r.selectionToSelectedFields(selField.Sels)
+			if selField.RawJSON {
+				subFields = r.rawSelectedFields(selField.RawSels)
+			}
 			selectedFields = append(selectedFields, pubquery.SelectedField{
 				Name:     selField.Field.Name,
-				Selected: selectionToSelectedFields(selField.Sels),
+				Alias:    selField.Alias,
+				Args:     args,
+				Selected: subFields,
 			})
 		}
 	}
 	return selectedFields
 }
 
-func execFieldSelection(ctx context.Context, r *Request, f *fieldToExec, path *pathSegment, applyLimiter bool) {
+// tracesFieldContext reports whether the tracer gets the field contexts, see
+// trace.FieldContextTracer.
+func (r *Request) tracesFieldContext() bool {
+	ft, ok := r.Tracer.(trace.FieldContextTracer)
+	if !ok {
+		return false
+	}
+	defer r.recoverCallback(context.Background(), "tracer")
+	return ft.TracesFieldContext()
+}
+
+// traceField starts tracing the field, by its identifier if the tracer supports it. If the tracer
+// rewrites the errors of fields, the returned finish function is nil and the rewrite function is
+// set instead. If the tracer panics, the field is not traced.
+func (r *Request) traceField(ctx context.Context, f *selected.SchemaField) (traceCtx context.Context, finish trace.TraceFieldFinishFunc, rewrite trace.TraceFieldRewriteFunc) {
+	traceCtx = ctx
+	defer r.recoverCallback(ctx, "tracer")
+	if rt, ok := r.Tracer.(trace.RewritingFieldTracer); ok {
+		id := f.TraceID
+		if id == nil {
+			id = trace.NewFieldIdentifier(f.TypeName, f.Name)
+		}
+		traceCtx, rewrite = rt.TraceFieldRewrite(ctx, id, !f.Async, f.Args)
+		return traceCtx, nil, rewrite
+	}
+	if ft, ok := r.Tracer.(trace.FieldTracer); ok && f.TraceID != nil {
+		traceCtx, finish = ft.TraceFieldID(ctx, f.TraceID, !f.Async, f.Args)
+		return traceCtx, finish, nil
+	}
+	traceCtx, finish = r.Tracer.TraceField(ctx, f.TraceLabel, f.TypeName, f.Name, !f.Async, f.Args)
+	return traceCtx, finish, nil
+}
+
+// traceConcurrency tells the tracer how the field was scheduled, see trace.ConcurrencyTracer.
+func (r *Request) traceConcurrency(ctx context.Context, f *fieldToExec, parallel bool, queueWait, run time.Duration) {
+	ct, ok := r.Tracer.(trace.ConcurrencyTracer)
+	if !ok {
+		return
+	}
+	defer r.recoverCallback(ctx, "tracer")
+	id := f.field.TraceID
+	if id == nil {
+		id = trace.NewFieldIdentifier(f.field.TypeName, f.field.Name)
+	}
+	c := trace.FieldConcurrency{Parallel: parallel, Group: 1, QueueWait: queueWait, Run: run}
+	if parallel {
+		c.Group = f.group
+	}
+	ct.TraceFieldConcurrency(ctx, id, c)
+}
+
+// rewriteError passes the error of a field to the rewrite function of the tracer. If the tracer
+// panics, the error is kept.
+func (r *Request) rewriteError(ctx context.Context, rewrite trace.TraceFieldRewriteFunc, err *errors.QueryError) (rewritten *errors.QueryError) {
+	rewritten = err
+	defer r.recoverCallback(ctx, "tracer")
+	return rewrite(err)
+}
+
+// execFieldSelection writes the value of the field to f.out. It returns false if the value is null
+// but the type of the field is non-null.
+func execFieldSelection(ctx context.Context, r *Request, f *fieldToExec, path *pathSegment, applyLimiter bool) bool {
+	if f.field.Optional {
+		defer r.demoteErrors(path)
+	}
+	var queueWait time.Duration
 	if applyLimiter {
-		r.Limiter <- struct{}{}
+		select {
+		case r.Limiter <- struct{}{}:
+		default:
+			queued := time.Now()
+			r.loaders.leave() // the fields holding the limiter may wait for loaders
+			r.Limiter <- struct{}{}
+			r.loaders.enter(1)
+			queueWait = time.Since(queued)
+		}
 	}
 
 	var result reflect.Value
 	var err *errors.QueryError
+	var breakerDone func(error)
+	var denied, cancelled bool
+	var delegated json.RawMessage
+	var replayedEntry *recording.Entry
 
-	traceCtx, finish := r.Tracer.TraceField(ctx, f.field.TraceLabel, f.field.TypeName, f.field.Name, !f.field.Async, f.field.Args)
-	defer func() {
-		finish(err)
-	}()
+	// the resolvers taking a context get a field context, which can cancel the field's subtree, and
+	// so do the tracers asking for it
+	var fc *FieldContext
+	tracedCtx := ctx
+	if f.field.HasContext || f.field.Func != nil || r.tracesFieldContext() {
+		tracedCtx, fc = withFieldContext(ctx, r, f.field, path)
+	}
+	traceCtx, finish, rewrite := r.traceField(tracedCtx, f.field)
+	if finish != nil {
+		defer func() {
+			defer r.recoverCallback(ctx, "tracer")
+			finish(err)
+		}()
+	}
+
+	fieldCtx := traceCtx
+	if fc != nil {
+		fieldCtx = fc.cancellable(traceCtx)
+		defer fc.cancel()
+	}
 
+	resolveStart := time.Now()
 	err = func() (err *errors.QueryError) {
 		defer func() {
 			if panicValue := recover(); panicValue != nil {
-				r.Logger.LogPanic(ctx, panicValue)
-				err = panicError(panicValue)
+				stack := debug.Stack()
+				r.logPanic(ctx, panicValue, path, stack)
+				err = panicError(panicValue, stack)
 				err.Path = path.toSlice()
 			}
 		}()
@@ -193,21 +733,138 @@ func execFieldSelection(ctx context.Context, r *Request, f *fieldToExec, path *p
 		}
 
 		if err := traceCtx.Err(); err != nil {
-			return errors.Errorf("%s", err) // don't execute any more resolvers if context got cancelled
+			if inCancelledField(traceCtx) {
+				cancelled = true
+				return nil
+			}
+			return contextError(err) // don't execute any more resolvers if context got cancelled
 		}
 
-		var in []reflect.Value
-		if f.field.HasContext {
-			in = append(in, reflect.ValueOf(traceCtx))
+		if f.field.Unbound {
+			err := errors.Errorf("field %s.%s has no resolver", f.field.TypeName, f.field.Name)
+			err.Path = path.toSlice()
+			err.Extensions = map[string]interface{}{"code": "UNBOUND_FIELD"}
+			return err
 		}
-		if f.field.ArgsPacker != nil {
-			in = append(in, f.field.PackedArgs)
+
+		if f.field.Auth != nil {
+			if err := r.authorize(traceCtx, f.field); err != nil {
+				// a null without an error is not allowed for a non-null field, it nulls the parent
+				if _, nonNull := f.field.Type.(*common.NonNull); !nonNull && r.Auth != nil && r.Auth.SilentNull {
+					denied = true
+					return nil
+				}
+				err.Path = path.toSlice()
+				return err
+			}
 		}
-		if f.field.HasSelected {
-			in = append(in, reflect.ValueOf(selectionToSelectedFields(f.sels)))
+
+		if r.Replay != nil {
+			e, err := r.replayEntry(path) // recorded for a request that may have been allowed more
+			replayedEntry = e
+			return err
 		}
-		callOut := f.resolver.Method(f.field.MethodIndex).Call(in)
+
+		if r.Breaker != nil {
+			done, ok := r.Breaker.Allow(traceCtx, f.field.TypeName+"."+f.field.Name)
+			if !ok {
+				err := errors.Errorf("circuit open for field %s.%s", f.field.TypeName, f.field.Name)
+				err.Path = path.toSlice()
+				err.Extensions = map[string]interface{}{"code": "CIRCUIT_OPEN"}
+				return err
+			}
+			breakerDone = done
+		}
+
+		resolverCtx := fieldCtx
+		if f.field.Timeout > 0 {
+			var cancel context.CancelFunc
+			resolverCtx, cancel = context.WithTimeout(fieldCtx, f.field.Timeout)
+			defer cancel()
+		}
+
+		if f.field.Delegate != nil {
+			var errs []*errors.QueryError
+			delegated, errs = r.delegate(resolverCtx, f.field, path)
+			for _, err := range errs {
+				r.AddError(err)
+			}
+			if ctxErr := traceCtx.Err(); ctxErr != nil {
+				if inCancelledField(traceCtx) {
+					cancelled = true
+					return nil
+				}
+				r.addInterrupted(path)
+				err := contextError(ctxErr)
+				err.Path = path.toSlice()
+				return err
+			}
+			return nil
+		}
+
+		if f.field.Func != nil {
+			value, resolverErr := f.field.Func.Resolve(resolverCtx, f.field.Args)
+			if ctxErr := traceCtx.Err(); ctxErr != nil {
+				if inCancelledField(traceCtx) {
+					cancelled = true
+					return nil
+				}
+				r.addInterrupted(path)
+				err := contextError(ctxErr)
+				err.Path = path.toSlice()
+				return err
+			}
+			if resolverErr != nil {
+				err := errors.Errorf("%s", resolverErr)
+				err.Path = path.toSlice()
+				err.OriginalError = resolverErr
+				return err
+			}
+			result = reflect.New(f.field.Func.ResultType).Elem()
+			if value != nil {
+				v := reflect.ValueOf(value)
+				if !v.Type().AssignableTo(f.field.Func.ResultType) {
+					err := errors.Errorf("field func returned %s instead of %s", v.Type(), f.field.Func.ResultType)
+					err.Path = path.toSlice()
+					return err
+				}
+				result.Set(v)
+			}
+			return nil
+		}
+
+		if f.field.FieldIndex != nil {
+			result = structField(f.resolver, f.field.FieldIndex)
+			return nil
+		}
+
+		in, err := r.resolverIn(resolverCtx, f, path)
+		if err != nil {
+			return err
+		}
+		callOut := r.callResolver(resolverCtx, f, in)
 		result = callOut[0]
+		if fc != nil && fc.isCancelled() {
+			return nil // the field is null, see below
+		}
+		if f.field.Timeout > 0 && resolverCtx.Err() == context.DeadlineExceeded && traceCtx.Err() == nil {
+			err := errors.Errorf("field timed out after %s", f.field.Timeout)
+			err.Path = path.toSlice()
+			err.Extensions = map[string]interface{}{"code": "FIELD_TIMEOUT"}
+			err.OriginalError = context.DeadlineExceeded
+			return err
+		}
+		if ctxErr := traceCtx.Err(); ctxErr != nil {
+			if inCancelledField(traceCtx) {
+				cancelled = true
+				return nil
+			}
+			// the request ended while the resolver was running, finish its span with the reason
+			r.addInterrupted(path)
+			err := contextError(ctxErr)
+			err.Path = path.toSlice()
+			return err
+		}
 		if f.field.HasError && !callOut[1].IsNil() {
 			resolverErr := callOut[1].Interface().(error)
 			err := errors.Errorf("%s", resolverErr)
@@ -217,61 +874,409 @@ func execFieldSelection(ctx context.Context, r *Request, f *fieldToExec, path *p
 		}
 		return nil
 	}()
+	if r.SlowFields > 0 && !f.field.FixedResult.IsValid() {
+		if d := time.Since(resolveStart); d > r.SlowFields {
+			r.warnSlowField(f.field, path, d)
+		}
+	}
+	if f.field.Async {
+		r.traceConcurrency(traceCtx, f, applyLimiter, queueWait, time.Since(resolveStart))
+	}
 
 	if applyLimiter {
 		<-r.Limiter
 	}
 
+	if breakerDone != nil {
+		if err != nil {
+			breakerDone(err)
+		} else {
+			breakerDone(nil)
+		}
+	}
+
+	if rewrite != nil {
+		rewritten := r.rewriteError(ctx, rewrite, err)
+		if err != nil && rewritten == nil {
+			if _, nonNull := f.field.Type.(*common.NonNull); nonNull {
+				rewritten = err // the error explains why the parent is null
+			} else {
+				denied = true // the field is null without an error
+			}
+		}
+		if rewritten != nil && rewritten.Path == nil {
+			rewritten.Path = path.toSlice()
+		}
+		err = rewritten
+	}
+
 	if err != nil {
 		r.AddError(err)
-		f.out.WriteString("null") // TODO handle non-nil
-		return
 	}
+	record := r.Record != nil && !f.field.FixedResult.IsValid()
+	if record && (err != nil || denied || cancelled) {
+		r.recordField(path, f.field.Type, []byte("null"), err)
+	}
+	if err != nil || denied || cancelled {
+		if _, nonNull := f.field.Type.(*common.NonNull); nonNull {
+			return false
+		}
+		f.out.WriteString("null")
+		return true
+	}
+
+	if replayedEntry != nil {
+		return r.replayField(traceCtx, f, path, replayedEntry.Value)
+	}
+
+	if f.field.Delegate != nil {
+		if record {
+			// the value of the service is recorded as a whole, its objects have no resolvers
+			value := json.RawMessage("null")
+			if len(delegated) != 0 {
+				value = delegated
+			}
+			r.Record.Set(fixtureKey(path), &recording.Entry{Value: value})
+		}
+		if len(delegated) == 0 || string(delegated) == "null" {
+			_, nonNull := f.field.Type.(*common.NonNull)
+			if !nonNull {
+				f.out.WriteString("null")
+			}
+			return !nonNull // the upstream service reported why
+		}
+		f.out.Write(delegated)
+		return true
+	}
+
+	start := f.out.Len()
+	if fc != nil && fc.isCancelled() {
+		return r.cancelField(f, path, start)
+	}
+	var ok bool
+	if f.field.RawJSON {
+		ok = r.execRawJSON(f, path, result)
+	} else {
+		ok = r.execSelectionSet(fieldCtx, f.sels, f.field.Type, path, result, f.out)
+	}
+	if fc != nil && fc.isCancelled() {
+		return r.cancelField(f, path, start)
+	}
+	if record && ok && f.field.RawJSON {
+		// like the value of a delegate, the JSON is recorded as a whole
+		r.Record.Set(fixtureKey(path), &recording.Entry{Value: append(json.RawMessage(nil), f.out.Bytes()[start:]...)})
+	} else if record && ok {
+		r.recordField(path, f.field.Type, f.out.Bytes()[start:], nil)
+	}
+	return ok
+}
+
+// warnSlowField adds a warning for the field with the path, whose resolver took d.
+func (r *Request) warnSlowField(field *selected.SchemaField, path *pathSegment, d time.Duration) {
+	err := errors.Errorf("field %s.%s took %s, more than %s", field.TypeName, field.Name, d.Round(time.Millisecond), r.SlowFields)
+	err.Path = path.toSlice()
+	err.Extensions = map[string]interface{}{
+		"code":       "SLOW_FIELD",
+		"durationMs": d.Nanoseconds() / int64(time.Millisecond),
+	}
+	r.Mu.Lock()
+	r.Warnings = append(r.Warnings, err)
+	r.Mu.Unlock()
+}
+
+// demoteErrors moves the errors of the field with the path and of its subtree to the warnings.
+func (r *Request) demoteErrors(path *pathSegment) {
+	prefix := path.toSlice()
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	errs := r.Errs[:0]
+	for _, err := range r.Errs {
+		if hasPathPrefix(err.Path, prefix) {
+			r.Warnings = append(r.Warnings, err)
+			continue
+		}
+		errs = append(errs, err)
+	}
+	r.Errs = errs
+}
+
+func hasPathPrefix(path, prefix []interface{}) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if path[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+// cancelField replaces the value of the field written from start with null, after its field context
+// was cancelled. It returns false if the field is non-null.
+func (r *Request) cancelField(f *fieldToExec, path *pathSegment, start int) bool {
+	f.out.Truncate(start)
+	err := errors.Errorf("field cancelled")
+	err.Path = path.toSlice()
+	err.Extensions = map[string]interface{}{"code": "CANCELLED"}
+	r.AddError(err)
+	if r.Record != nil {
+		r.recordField(path, f.field.Type, []byte("null"), err)
+	}
+	if _, nonNull := f.field.Type.(*common.NonNull); nonNull {
+		return false
+	}
+	f.out.WriteString("null")
+	return true
+}
 
-	r.execSelectionSet(traceCtx, f.sels, f.field.Type, path, result, f.out)
+// callResolver calls the resolver method of the field. It calls it again according to the field's
+// retry policy as long as the resolver returns a retryable error, and hedges the calls according to
+// its hedge policy unless the request is synchronous.
+func (r *Request) callResolver(ctx context.Context, f *fieldToExec, in []reflect.Value) []reflect.Value {
+	if f.field.Hedge != nil && !r.Synchronous {
+		return callHedged(ctx, f, in)
+	}
+	return callWithRetry(ctx, f, in)
 }
 
-func (r *Request) execSelectionSet(ctx context.Context, sels []selected.Selection, typ common.Type, path *pathSegment, resolver reflect.Value, out *bytes.Buffer) {
+// callWithRetry calls the resolver method, and again after errors according to the field's retry
+// policy.
+func callWithRetry(ctx context.Context, f *fieldToExec, in []reflect.Value) []reflect.Value {
+	m := f.resolver.Method(f.field.MethodIndex)
+	policy := f.field.Retry
+	for retry := 1; ; retry++ {
+		callOut := m.Call(in)
+		if policy == nil || !f.field.HasError || callOut[1].IsNil() || retry >= policy.Attempts {
+			return callOut
+		}
+
+		err := callOut[1].Interface().(error)
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			return callOut
+		}
+		if policy.Retryable == nil && (err == context.Canceled || err == context.DeadlineExceeded) {
+			return callOut
+		}
+
+		if policy.Backoff != nil {
+			t := time.NewTimer(policy.Backoff(retry))
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+			}
+		}
+		if ctx.Err() != nil {
+			return callOut
+		}
+	}
+}
+
+type hedgedCall struct {
+	out        []reflect.Value
+	panicValue interface{}
+}
+
+// callHedged calls the resolver method again, with a context of its own, whenever the calls so
+// far have neither succeeded within the delay of the field's hedge policy nor all failed, up to
+// its number of attempts. The first successful result is returned and the contexts of the other
+// calls are cancelled. If all calls fail, the result of the first one is returned. A panic of any
+// call is propagated.
+func callHedged(ctx context.Context, f *fieldToExec, in []reflect.Value) []reflect.Value {
+	policy := f.field.Hedge
+	calls := make(chan hedgedCall, policy.Attempts) // the calls that lost never block
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	loaders := LoadersOf(ctx)
+	launch := func() {
+		callCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		callIn := append([]reflect.Value(nil), in...)
+		if f.field.HasContext {
+			callIn[0] = reflect.ValueOf(callCtx)
+		}
+		loaders.enter(1)
+		go func() {
+			var call hedgedCall
+			defer loaders.leave()
+			defer func() {
+				if panicValue := recover(); panicValue != nil {
+					call.panicValue = panicValue
+				}
+				calls <- call
+			}()
+			call.out = callWithRetry(callCtx, f, callIn)
+		}()
+	}
+
+	launch()
+	loaders.leave() // the calls count as executing the request instead
+	defer loaders.enter(1)
+	timer := time.NewTimer(policy.Delay)
+	defer timer.Stop()
+	var failed []reflect.Value
+	for done := 0; ; {
+		select {
+		case call := <-calls:
+			done++
+			if call.panicValue != nil {
+				panic(call.panicValue)
+			}
+			if !f.field.HasError || call.out[1].IsNil() {
+				return call.out
+			}
+			if failed == nil {
+				failed = call.out
+			}
+			if done == len(cancels) {
+				if len(cancels) == policy.Attempts {
+					return failed
+				}
+				launch() // all calls so far failed, there is no point in waiting for the delay
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(policy.Delay)
+			}
+		case <-timer.C:
+			if len(cancels) < policy.Attempts {
+				launch()
+				timer.Reset(policy.Delay)
+			}
+		case <-ctx.Done():
+			if failed != nil {
+				return failed
+			}
+			return zeroResults(f.resolver.Method(f.field.MethodIndex).Type()) // the caller reports ctx.Err()
+		}
+	}
+}
+
+// zeroResults returns the zero values of the results of the method type.
+func zeroResults(t reflect.Type) []reflect.Value {
+	out := make([]reflect.Value, t.NumOut())
+	for i := range out {
+		out[i] = reflect.Zero(t.Out(i))
+	}
+	return out
+}
+
+// execSelectionSet writes the value of type typ to out. A null value, e.g. because of an error of a
+// non-null field within the value, is written as null if typ is nullable. Otherwise it returns false
+// and the caller has to discard what was written to out and propagate the null further up.
+func (r *Request) execSelectionSet(ctx context.Context, sels []selected.Selection, typ common.Type, path *pathSegment, resolver reflect.Value, out *bytes.Buffer) bool {
 	t, nonNull := unwrapNonNull(typ)
+	start := out.Len()
+	// null replaces the value written so far with null, or propagates it if typ is non-null
+	null := func() bool {
+		out.Truncate(start)
+		if nonNull {
+			return false
+		}
+		out.WriteString("null")
+		return true
+	}
+
 	switch t := t.(type) {
 	case *schema.Object, *schema.Interface, *schema.Union:
-		if resolver.Kind() == reflect.Ptr && resolver.IsNil() {
+		if (resolver.Kind() == reflect.Ptr || resolver.Kind() == reflect.Interface) && resolver.IsNil() {
 			if nonNull {
-				panic(errors.Errorf("got nil for non-null %q", t))
+				err := errors.Errorf("got nil for non-null %q", t)
+				err.Path = path.toSlice()
+				r.AddError(err)
 			}
-			out.WriteString("null")
-			return
+			return null()
 		}
 
-		r.execSelections(ctx, sels, path, resolver, out, false)
-		return
+		if !r.execSelections(ctx, sels, path, resolver, out, false) {
+			return null()
+		}
+		return true
 	}
 
 	if !nonNull {
 		if resolver.IsNil() {
 			out.WriteString("null")
-			return
+			return true
+		}
+		if resolver.Kind() == reflect.Ptr {
+			resolver = resolver.Elem()
+		}
+	}
+
+	// the resolver returned an interface, e.g. as element of a []interface{}, whose dynamic
+	// value has to be checked
+	dynamic := resolver.Kind() == reflect.Interface
+	if dynamic {
+		resolver = resolver.Elem() // invalid if the interface is nil
+		if !resolver.IsValid() || resolver.Kind() == reflect.Ptr && resolver.IsNil() {
+			if nonNull {
+				err := errors.Errorf("got nil for non-null %q", t)
+				err.Path = path.toSlice()
+				r.AddError(err)
+			}
+			return null()
+		}
+		if resolver.Kind() == reflect.Ptr {
+			resolver = resolver.Elem()
 		}
-		resolver = resolver.Elem()
 	}
 
 	switch t := t.(type) {
 	case *common.List:
+		if resolver.Kind() == reflect.Func {
+			return r.execIterator(ctx, sels, t, path, resolver, out, null)
+		}
 		l := resolver.Len()
 
-		if selected.HasAsyncSel(sels) {
-			var wg sync.WaitGroup
-			wg.Add(l)
+		if !r.Synchronous && selected.HasAsyncSel(sels) {
 			entryouts := make([]bytes.Buffer, l)
-			for i := 0; i < l; i++ {
-				go func(i int) {
+			entryoks := make([]bool, l)
+			execEntry := func(i int) {
+				defer r.handlePanic(ctx, &pathSegment{parent: path, value: i})
+				entryoks[i] = r.execSelectionSet(ctx, sels, t.OfType, &pathSegment{parent: path, value: i}, resolver.Index(i), &entryouts[i])
+			}
+
+			// Each worker takes the next entry until none are left, so that slow entries don't hold
+			// up the ones assigned to the same worker.
+			workers := l
+			if r.ListWorkers > 0 && r.ListWorkers < l {
+				workers = r.ListWorkers
+			}
+			var wg sync.WaitGroup
+			wg.Add(workers)
+			r.loaders.enter(workers)
+			next := int64(-1)
+			for w := 0; w < workers; w++ {
+				go func() {
 					defer wg.Done()
-					defer r.handlePanic(ctx)
-					r.execSelectionSet(ctx, sels, t.OfType, &pathSegment{path, i}, resolver.Index(i), &entryouts[i])
-				}(i)
+					defer r.loaders.leave()
+					for {
+						i := int(atomic.AddInt64(&next, 1))
+						if i >= l {
+							return
+						}
+						execEntry(i)
+					}
+				}()
 			}
+			r.loaders.leave()
 			wg.Wait()
+			r.loaders.enter(1)
 
+			for _, ok := range entryoks {
+				if !ok {
+					return null() // a null entry of non-null type nullifies the list
+				}
+			}
 			out.WriteByte('[')
 			for i, entryout := range entryouts {
 				if i > 0 {
@@ -280,34 +1285,72 @@ func (r *Request) execSelectionSet(ctx context.Context, sels []selected.Selectio
 				out.Write(entryout.Bytes())
 			}
 			out.WriteByte(']')
-			return
+			return true
 		}
 
+		ok := true
 		out.WriteByte('[')
 		for i := 0; i < l; i++ {
 			if i > 0 {
 				out.WriteByte(',')
 			}
-			r.execSelectionSet(ctx, sels, t.OfType, &pathSegment{path, i}, resolver.Index(i), out)
+			if !r.execSelectionSet(ctx, sels, t.OfType, &pathSegment{parent: path, value: i}, resolver.Index(i), out) {
+				ok = false
+			}
+		}
+		if !ok {
+			return null() // a null entry of non-null type nullifies the list
 		}
 		out.WriteByte(']')
 
 	case *schema.Scalar:
-		v := resolver.Interface()
-		data, err := json.Marshal(v)
-		if err != nil {
-			panic(errors.Errorf("could not marshal %v", v))
+		if dynamic && !resolvable.ImplementsScalar(t, resolver.Type(), r.scalarTypes) {
+			err := errors.Errorf("can not use %s as %s", resolver.Type(), t.Name)
+			err.Path = path.toSlice()
+			r.AddError(err)
+			return null()
+		}
+		if m, ok := contextMarshaler(resolver); ok {
+			if err := writeContextScalar(ctx, out, m); err != nil {
+				err := errors.Errorf("could not marshal %s: %s", t.Name, err)
+				err.Path = path.toSlice()
+				r.AddError(err)
+				return null()
+			}
+		} else {
+			writeScalar(out, resolver.Interface(), !r.NoHTMLEscape)
+		}
+		if r.StrictScalars {
+			if data := out.Bytes()[start:]; !validScalarOutput(t.Name, data) {
+				err := errors.Errorf("%s cannot represent value: %s", t.Name, data)
+				err.Path = path.toSlice()
+				err.Extensions = map[string]interface{}{"code": "INVALID_SCALAR_OUTPUT"}
+				r.AddError(err)
+				return null()
+			}
 		}
-		out.Write(data)
 
 	case *schema.Enum:
+		name := resolver.String()
+		if resolver.Kind() != reflect.String {
+			// e.g. the enums of protocol buffers, which are integers with a String method
+			stringer, ok := resolver.Interface().(fmt.Stringer)
+			if !ok {
+				err := errors.Errorf("can not use %s as %s", resolver.Type(), t.Name)
+				err.Path = path.toSlice()
+				r.AddError(err)
+				return null()
+			}
+			name = stringer.String()
+		}
 		out.WriteByte('"')
-		out.WriteString(resolver.String())
+		out.WriteString(name)
 		out.WriteByte('"')
 
 	default:
 		panic("unreachable")
 	}
+	return true
 }
 
 func unwrapNonNull(t common.Type) (common.Type, bool) {
@@ -320,6 +1363,8 @@ func unwrapNonNull(t common.Type) (common.Type, bool) {
 type pathSegment struct {
 	parent *pathSegment
 	value  interface{}
+	field  string        // the name and arguments of the field, when recording or replaying
+	owner  reflect.Value // the resolver of the object with the field, see query.Parent
 }
 
 func (p *pathSegment) toSlice() []interface{} {
@@ -0,0 +1,118 @@
+package exec
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/exec/selected"
+)
+
+// FieldContext is the field whose resolver got the context, see graphql.FieldContext.
+type FieldContext struct {
+	Field *selected.SchemaField
+
+	// Parent is the field context of the closest enclosing field with one, nil if there is none.
+	Parent *FieldContext
+
+	path      *pathSegment
+	req       *Request
+	cancel    context.CancelFunc
+	cancelled int32
+}
+
+// Directive is a directive applied to the field.
+type Directive struct {
+	Name string
+
+	// Query is set for a directive of the field in the query, as opposed to one of its definition
+	// in the schema.
+	Query bool
+
+	// Args are the arguments of the directive, with the variables and default values applied.
+	Args map[string]interface{}
+}
+
+type fieldContextKey struct{}
+
+// withFieldContext returns the context of the field's tracer, which has the field context but is
+// not cancelled by it, see cancellable.
+func withFieldContext(ctx context.Context, r *Request, f *selected.SchemaField, path *pathSegment) (context.Context, *FieldContext) {
+	fc := &FieldContext{Field: f, path: path, req: r}
+	fc.Parent, _ = ctx.Value(fieldContextKey{}).(*FieldContext)
+	return context.WithValue(ctx, fieldContextKey{}, fc), fc
+}
+
+// cancellable returns the context of the field's resolver and its subtree, derived from the one
+// returned by withFieldContext.
+func (fc *FieldContext) cancellable(ctx context.Context) context.Context {
+	ctx, fc.cancel = context.WithCancel(ctx)
+	return ctx
+}
+
+// FieldContextFrom returns the field context of the resolver that got ctx, nil if there is none.
+func FieldContextFrom(ctx context.Context) *FieldContext {
+	fc, _ := ctx.Value(fieldContextKey{}).(*FieldContext)
+	return fc
+}
+
+// Path returns the path of the field in the response.
+func (fc *FieldContext) Path() []interface{} {
+	return fc.path.toSlice()
+}
+
+// Directives returns the directives of the field's definition in the schema, followed by the ones
+// of the field in the query.
+func (fc *FieldContext) Directives() []Directive {
+	var result []Directive
+	for _, d := range fc.Field.Directives {
+		result = append(result, fc.directive(d, false))
+	}
+	for _, d := range fc.Field.QueryDirectives {
+		result = append(result, fc.directive(d, true))
+	}
+	return result
+}
+
+func (fc *FieldContext) directive(d *common.Directive, query bool) Directive {
+	result := Directive{Name: d.Name.Name, Query: query, Args: make(map[string]interface{})}
+	var vars map[string]interface{}
+	if query {
+		vars = fc.req.Vars
+	}
+	for _, arg := range d.Args {
+		if common.IsMissingVariable(arg.Value, vars) {
+			continue // an omitted variable leaves the argument unset, so its default value applies
+		}
+		result.Args[arg.Name.Name] = arg.Value.Value(vars)
+	}
+	if decl := fc.req.Schema.Directives[d.Name.Name]; decl != nil {
+		for _, arg := range decl.Args {
+			if _, ok := result.Args[arg.Name.Name]; !ok && arg.Default != nil {
+				result.Args[arg.Name.Name] = arg.Default.Value(nil)
+			}
+		}
+	}
+	return result
+}
+
+// Cancel cancels the context of the field and of its subtree. The field is null then.
+func (fc *FieldContext) Cancel() {
+	atomic.StoreInt32(&fc.cancelled, 1)
+	fc.cancel()
+}
+
+func (fc *FieldContext) isCancelled() bool {
+	return atomic.LoadInt32(&fc.cancelled) == 1
+}
+
+// inCancelledField reports whether ctx is done because an enclosing field was cancelled, in which
+// case the field is null without an error of its own.
+func inCancelledField(ctx context.Context) bool {
+	for fc := FieldContextFrom(ctx); fc != nil; fc = fc.Parent {
+		if fc.isCancelled() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"runtime/debug"
+	"sync"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/exec/selected"
+)
+
+// iterEntry is an entry of a list resolved by an iterator.
+type iterEntry struct {
+	out bytes.Buffer
+	ok  bool
+}
+
+// execIterator writes the list resolved by the iterator, see resolvable.IteratorElem. The entries
+// are resolved while they are iterated, concurrently if they have async fields, so that the
+// iterator never has to hold all of them. An error of the iterator makes the list null.
+func (r *Request) execIterator(ctx context.Context, sels []selected.Selection, t *common.List, path *pathSegment, resolver reflect.Value, out *bytes.Buffer, null func() bool) bool {
+	if resolver.IsNil() {
+		err := errors.Errorf("got nil for non-null %q", t)
+		err.Path = path.toSlice()
+		r.AddError(err)
+		return null()
+	}
+
+	if r.Synchronous || !selected.HasAsyncSel(sels) {
+		ok := true
+		i := 0
+		out.WriteByte('[')
+		err := r.iterate(ctx, path, resolver, func(value reflect.Value) bool {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if !r.execSelectionSet(ctx, sels, t.OfType, &pathSegment{parent: path, value: i}, value, out) {
+				ok = false // a null entry of non-null type nullifies the list
+			}
+			i++
+			return ok
+		})
+		if err != nil {
+			r.AddError(err)
+			return null()
+		}
+		if !ok {
+			return null()
+		}
+		out.WriteByte(']')
+		return true
+	}
+
+	// Each entry is resolved by its own goroutine, up to ListWorkers at a time.
+	var workers chan struct{}
+	if r.ListWorkers > 0 {
+		workers = make(chan struct{}, r.ListWorkers)
+	}
+	var entries []*iterEntry
+	var wg sync.WaitGroup
+	err := r.iterate(ctx, path, resolver, func(value reflect.Value) bool {
+		if workers != nil {
+			select {
+			case workers <- struct{}{}:
+			default:
+				r.loaders.leave() // the entries being resolved may wait for loaders
+				workers <- struct{}{}
+				r.loaders.enter(1)
+			}
+		}
+		e := &iterEntry{}
+		entryPath := &pathSegment{parent: path, value: len(entries)}
+		entries = append(entries, e)
+		wg.Add(1)
+		r.loaders.enter(1)
+		go func() {
+			defer wg.Done()
+			defer r.loaders.leave()
+			if workers != nil {
+				defer func() { <-workers }()
+			}
+			defer r.handlePanic(ctx, entryPath)
+			e.ok = r.execSelectionSet(ctx, sels, t.OfType, entryPath, value, &e.out)
+		}()
+		return true
+	})
+	r.loaders.leave()
+	wg.Wait()
+	r.loaders.enter(1)
+
+	if err != nil {
+		r.AddError(err)
+		return null()
+	}
+	for _, e := range entries {
+		if !e.ok {
+			return null() // a null entry of non-null type nullifies the list
+		}
+	}
+	out.WriteByte('[')
+	for i, e := range entries {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.Write(e.out.Bytes())
+	}
+	out.WriteByte(']')
+	return true
+}
+
+// iterate calls f with each value of the iterator until f returns false or the context is done.
+// It returns the error of the iterator, or of its panic.
+func (r *Request) iterate(ctx context.Context, path *pathSegment, iterator reflect.Value, f func(value reflect.Value) bool) (qErr *errors.QueryError) {
+	defer func() {
+		if value := recover(); value != nil {
+			stack := debug.Stack()
+			r.logPanic(ctx, value, path, stack)
+			qErr = panicError(value, stack)
+			qErr.Path = path.toSlice()
+		}
+	}()
+
+	_, withErr, _ := resolvable.IteratorElem(iterator.Type())
+	var iterErr error
+	yield := reflect.MakeFunc(iterator.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		more := false
+		switch {
+		case withErr && !args[1].IsNil():
+			iterErr = args[1].Interface().(error)
+		case ctx.Err() == nil:
+			more = f(args[0])
+		}
+		return []reflect.Value{reflect.ValueOf(more)}
+	})
+	iterator.Call([]reflect.Value{yield})
+	if iterErr != nil {
+		err := errors.Errorf("%s", iterErr)
+		err.Path = path.toSlice()
+		err.OriginalError = iterErr
+		return err
+	}
+	return nil
+}
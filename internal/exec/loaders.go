@@ -0,0 +1,129 @@
+package exec
+
+import (
+	"context"
+	"sync"
+)
+
+// Loaders holds the state of the loaders of a request, see graphql.Loader, and dispatches their
+// batches when execution quiesces: when every goroutine executing the request waits, either for a
+// loader or for other goroutines.
+type Loaders struct {
+	ctx     context.Context // of the request, passed to the batch functions
+	mu      sync.Mutex
+	active  int      // goroutines executing the request that do not wait
+	pending []func() // dispatches the batches when the execution quiesces
+	states  map[interface{}]interface{}
+
+	finished bool     // the execution returned, goroutines of hedged calls may still be active
+	release  []func() // called when the execution finished and no goroutine is active
+}
+
+type loadersKey struct{}
+
+// LoadersOf returns the loaders of the request executed with ctx, nil outside of a request.
+func LoadersOf(ctx context.Context) *Loaders {
+	l, _ := ctx.Value(loadersKey{}).(*Loaders)
+	return l
+}
+
+// newLoaders returns the loaders of a request and the context of the request carrying them.
+func newLoaders(ctx context.Context) (*Loaders, context.Context) {
+	l := &Loaders{ctx: ctx, states: make(map[interface{}]interface{})}
+	return l, context.WithValue(ctx, loadersKey{}, l)
+}
+
+// Context returns the context of the request.
+func (l *Loaders) Context() context.Context {
+	return l.ctx
+}
+
+// State returns the state of the loader with the key during the request, created by create on the
+// first call.
+func (l *Loaders) State(key interface{}, create func() interface{}) interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.states[key]
+	if !ok {
+		s = create()
+		l.states[key] = s
+	}
+	return s
+}
+
+// Schedule calls dispatch when the execution quiesces, or right away if it already has. dispatch
+// is called on a goroutine of its own.
+func (l *Loaders) Schedule(dispatch func()) {
+	l.mu.Lock()
+	if l.active > 0 {
+		l.pending = append(l.pending, dispatch)
+		l.mu.Unlock()
+		return
+	}
+	l.mu.Unlock()
+	go dispatch()
+}
+
+// Wait waits until done is closed. The calling goroutine does not count as executing the request
+// meanwhile, so that its waiting may dispatch the batches.
+func (l *Loaders) Wait(done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	default:
+	}
+	l.leave()
+	<-done
+	l.enter(1)
+}
+
+// OnRelease calls release when the request is done: its execution returned and every resolver
+// called for it, including the ones of hedged calls that lost, returned too.
+func (l *Loaders) OnRelease(release func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.release = append(l.release, release)
+}
+
+// finish leaves like the goroutine executing the request when the execution returns.
+func (l *Loaders) finish() {
+	l.mu.Lock()
+	l.finished = true
+	l.mu.Unlock()
+	l.leave()
+}
+
+// enter counts n goroutines as executing the request.
+func (l *Loaders) enter(n int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.active += n
+	l.mu.Unlock()
+}
+
+// leave stops counting a goroutine as executing the request, because it returned or waits. The
+// pending batches are dispatched if it was the last one, and the request is released if it was the
+// last one after the execution finished.
+func (l *Loaders) leave() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.active--
+	var dispatch, release []func()
+	if l.active <= 0 {
+		dispatch, l.pending = l.pending, nil
+		if l.finished {
+			release, l.release = l.release, nil
+		}
+	}
+	l.mu.Unlock()
+	for _, d := range dispatch {
+		go d()
+	}
+	for _, r := range release {
+		r()
+	}
+}
@@ -1,6 +1,9 @@
 package packer
 
 import (
+	"encoding"
+	"encoding/json"
+	"fmt"
 	"math"
 	"reflect"
 	"strings"
@@ -16,6 +19,10 @@ type packer interface {
 }
 
 type Builder struct {
+	// ScalarTypes maps Go types to the names of the scalar types they are unmarshaled from, see
+	// CanUnmarshalMapped.
+	ScalarTypes map[reflect.Type]string
+
 	packerMap     map[typePair]*packerMapEntry
 	structPackers []*StructPacker
 }
@@ -49,7 +56,11 @@ func (b *Builder) Finish() error {
 			if defaultVal := f.field.Default; defaultVal != nil {
 				v, err := f.fieldPacker.Pack(defaultVal.Value(nil))
 				if err != nil {
-					return err
+					return perrors.Errorf("default value %s of %q: %s", defaultVal, f.field.Name.Name, err)
+				}
+				if f.viaPtr {
+					f.defaultValue = v // the embedded structs are allocated per value
+					continue
 				}
 				p.defaultStruct.FieldByIndex(f.fieldIndex).Set(v)
 			}
@@ -78,6 +89,13 @@ func (b *Builder) assignPacker(target *packer, schemaType common.Type, reflectTy
 func (b *Builder) makePacker(schemaType common.Type, reflectType reflect.Type) (packer, error) {
 	t, nonNull := unwrapNonNull(schemaType)
 	if !nonNull {
+		if _, ok := t.(*schema.InputObject); ok && reflectType.Kind() == reflect.Map {
+			elem, err := b.makeNonNullPacker(t, reflectType)
+			if err != nil {
+				return nil, err
+			}
+			return &nullPacker{elemPacker: elem, valueType: reflectType}, nil // null is a nil map
+		}
 		if reflectType.Kind() != reflect.Ptr {
 			return nil, perrors.Errorf("%s is not a pointer", reflectType)
 		}
@@ -110,6 +128,14 @@ func (b *Builder) makeNonNullPacker(schemaType common.Type, reflectType reflect.
 			ValueType: reflectType,
 		}, nil
 	}
+	if name, ok := b.ScalarTypes[reflectType]; ok {
+		if name != schemaType.String() {
+			return nil, perrors.Errorf("can not unmarshal %s into %s", schemaType, reflectType)
+		}
+		return &mappedPacker{
+			ValueType: reflectType,
+		}, nil
+	}
 
 	switch t := schemaType.(type) {
 	case *schema.Scalar:
@@ -118,15 +144,17 @@ func (b *Builder) makeNonNullPacker(schemaType common.Type, reflectType reflect.
 		}, nil
 
 	case *schema.Enum:
-		want := reflect.TypeOf("")
-		if reflectType != want {
-			return nil, perrors.Errorf("wrong type, expected %s", want)
+		if reflectType.Kind() != reflect.String {
+			return nil, perrors.Errorf("wrong type, expected %s", reflect.TypeOf(""))
 		}
 		return &ValuePacker{
 			ValueType: reflectType,
 		}, nil
 
 	case *schema.InputObject:
+		if reflectType.Kind() == reflect.Map {
+			return b.makeMapPacker(t.Values, reflectType)
+		}
 		e, err := b.MakeStructPacker(t.Values, reflectType)
 		if err != nil {
 			return nil, err
@@ -180,8 +208,24 @@ func (b *Builder) MakeStructPacker(values common.InputValueList, typ reflect.Typ
 		}
 		fe.fieldIndex = sf.Index
 
+		// fields of embedded struct pointers are set after allocating the structs
+		t := structType
+		for _, i := range sf.Index[:len(sf.Index)-1] {
+			embedded := t.Field(i)
+			t = embedded.Type
+			if t.Kind() == reflect.Ptr {
+				if embedded.PkgPath != "" {
+					return nil, perrors.Errorf("embedded field %q must be exported", embedded.Name)
+				}
+				fe.viaPtr = true
+				t = t.Elem()
+			}
+		}
+
+		// A non-null default value guarantees a value, so a non-pointer Go type can be used even for
+		// a nullable argument. With a pointer type an explicit null can still be told apart.
 		ft := v.Type
-		if v.Default != nil {
+		if _, isNull := v.Default.(*common.NullLit); v.Default != nil && !isNull && sf.Type.Kind() != reflect.Ptr {
 			ft, _ = unwrapNonNull(ft)
 			ft = &common.NonNull{OfType: ft}
 		}
@@ -209,10 +253,17 @@ type StructPacker struct {
 	fields        []*structPackerField
 }
 
+// StructType returns the type of the structs the packer packs into, without a pointer.
+func (p *StructPacker) StructType() reflect.Type {
+	return p.structType
+}
+
 type structPackerField struct {
-	field       *common.InputValue
-	fieldIndex  []int
-	fieldPacker packer
+	field        *common.InputValue
+	fieldIndex   []int
+	fieldPacker  packer
+	viaPtr       bool          // the field belongs to an embedded struct pointer
+	defaultValue reflect.Value // the default value if viaPtr is set
 }
 
 func (p *StructPacker) Pack(value interface{}) (reflect.Value, error) {
@@ -227,9 +278,11 @@ func (p *StructPacker) Pack(value interface{}) (reflect.Value, error) {
 		if value, ok := values[f.field.Name.Name]; ok {
 			packed, err := f.fieldPacker.Pack(value)
 			if err != nil {
-				return reflect.Value{}, err
+				return reflect.Value{}, withPath(err, f.field.Name.Name)
 			}
-			v.Elem().FieldByIndex(f.fieldIndex).Set(packed)
+			fieldByIndex(v.Elem(), f.fieldIndex).Set(packed)
+		} else if f.defaultValue.IsValid() {
+			fieldByIndex(v.Elem(), f.fieldIndex).Set(f.defaultValue)
 		}
 	}
 	if !p.usePtr {
@@ -238,6 +291,131 @@ func (p *StructPacker) Pack(value interface{}) (reflect.Value, error) {
 	return v, nil
 }
 
+// fieldByIndex is like reflect.Value.FieldByIndex, but allocates nil embedded struct pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// makeMapPacker packs input objects into maps keyed by the names of the fields. The fields are
+// packed into the element type of the map, which has to be a pointer for null values to be kept.
+// With an element type of interface{} the values are kept as they are.
+func (b *Builder) makeMapPacker(values common.InputValueList, typ reflect.Type) (*mapPacker, error) {
+	if typ.Key().Kind() != reflect.String {
+		return nil, perrors.Errorf("expected map keyed by string, got %s", typ)
+	}
+
+	p := &mapPacker{mapType: typ}
+	elemType := typ.Elem()
+	for _, v := range values {
+		f := &mapPackerField{field: v}
+		switch {
+		case elemType.Kind() == reflect.Interface && elemType.NumMethod() == 0:
+			f.fieldPacker = &rawPacker{valueType: elemType}
+		case elemType.Kind() == reflect.Ptr:
+			if err := b.assignPacker(&f.fieldPacker, v.Type, elemType); err != nil {
+				return nil, perrors.Errorf("field %q: %s", v.Name.Name, err)
+			}
+		default:
+			t, _ := unwrapNonNull(v.Type)
+			if err := b.assignPacker(&f.fieldPacker, &common.NonNull{OfType: t}, elemType); err != nil {
+				return nil, perrors.Errorf("field %q: %s", v.Name.Name, err)
+			}
+			f.omitNull = true
+		}
+		p.fields = append(p.fields, f)
+	}
+	return p, nil
+}
+
+type mapPacker struct {
+	mapType reflect.Type
+	fields  []*mapPackerField
+}
+
+type mapPackerField struct {
+	field       *common.InputValue
+	fieldPacker packer
+	omitNull    bool // the element type can not hold null
+}
+
+func (p *mapPacker) Pack(value interface{}) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Value{}, errors.Errorf("got null for non-null")
+	}
+
+	values := value.(map[string]interface{})
+	m := reflect.MakeMapWithSize(p.mapType, len(p.fields))
+	for _, f := range p.fields {
+		value, ok := values[f.field.Name.Name]
+		if !ok {
+			if f.field.Default == nil {
+				continue
+			}
+			value = f.field.Default.Value(nil)
+		}
+		if value == nil && f.omitNull {
+			continue
+		}
+		packed, err := f.fieldPacker.Pack(value)
+		if err != nil {
+			return reflect.Value{}, withPath(err, f.field.Name.Name)
+		}
+		m.SetMapIndex(reflect.ValueOf(f.field.Name.Name).Convert(p.mapType.Key()), packed)
+	}
+	return m, nil
+}
+
+// rawPacker keeps values as they are.
+type rawPacker struct {
+	valueType reflect.Type
+}
+
+func (p *rawPacker) Pack(value interface{}) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(p.valueType), nil
+	}
+	return reflect.ValueOf(value), nil
+}
+
+// PathError is an error of packing the value at a path within an argument, e.g.
+// "input.filters[2].range.min".
+type PathError struct {
+	Path []interface{} // names of fields and indices of lists, outermost first
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	var path strings.Builder
+	for i, segment := range e.Path {
+		if index, ok := segment.(int); ok {
+			fmt.Fprintf(&path, "[%d]", index)
+			continue
+		}
+		if i > 0 {
+			path.WriteByte('.')
+		}
+		fmt.Fprint(&path, segment)
+	}
+	return fmt.Sprintf("%s: %s", path.String(), e.Err)
+}
+
+// withPath prefixes the path of the error with the segment.
+func withPath(err error, segment interface{}) error {
+	if pathErr, ok := err.(*PathError); ok {
+		return &PathError{Path: append([]interface{}{segment}, pathErr.Path...), Err: pathErr.Err}
+	}
+	return &PathError{Path: []interface{}{segment}, Err: err}
+}
+
 type listPacker struct {
 	sliceType reflect.Type
 	elem      packer
@@ -253,7 +431,7 @@ func (e *listPacker) Pack(value interface{}) (reflect.Value, error) {
 	for i := range list {
 		packed, err := e.elem.Pack(list[i])
 		if err != nil {
-			return reflect.Value{}, err
+			return reflect.Value{}, withPath(err, i)
 		}
 		v.Index(i).Set(packed)
 	}
@@ -317,6 +495,52 @@ func (p *unmarshalerPacker) Pack(value interface{}) (reflect.Value, error) {
 	return v.Elem(), nil
 }
 
+// mappedPacker unmarshals the values of a scalar type into a Go type mapped to it, which does not
+// implement Unmarshaler.
+type mappedPacker struct {
+	ValueType reflect.Type
+}
+
+func (p *mappedPacker) Pack(value interface{}) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Value{}, errors.Errorf("got null for non-null")
+	}
+	if reflect.TypeOf(value) == p.ValueType {
+		return reflect.ValueOf(value), nil
+	}
+
+	v := reflect.New(p.ValueType)
+	switch u := v.Interface().(type) {
+	case json.Unmarshaler:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if err := u.UnmarshalJSON(data); err != nil {
+			return reflect.Value{}, err
+		}
+	case encoding.TextUnmarshaler:
+		s, ok := value.(string)
+		if !ok {
+			return reflect.Value{}, perrors.Errorf("could not unmarshal %#v (%T) into %s: not a string", value, value, p.ValueType)
+		}
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return v.Elem(), nil
+}
+
+// CanUnmarshalMapped reports whether the Go type can be mapped to a scalar type, i.e. whether
+// pointers to its values implement json.Unmarshaler or encoding.TextUnmarshaler.
+func CanUnmarshalMapped(t reflect.Type) bool {
+	switch reflect.New(t).Interface().(type) {
+	case json.Unmarshaler, encoding.TextUnmarshaler:
+		return true
+	}
+	return false
+}
+
 type Unmarshaler interface {
 	ImplementsGraphQLType(name string) bool
 	UnmarshalGraphQL(input interface{}) error
@@ -328,6 +552,11 @@ func unmarshalInput(typ reflect.Type, input interface{}) (interface{}, error) {
 	}
 
 	switch typ.Kind() {
+	case reflect.String:
+		if input, ok := input.(string); ok {
+			return reflect.ValueOf(input).Convert(typ).Interface(), nil // named string type, e.g. for enums
+		}
+
 	case reflect.Int32:
 		switch input := input.(type) {
 		case int:
@@ -0,0 +1,331 @@
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+	pubquery "github.com/qdentity/graphql-go/query"
+)
+
+// execRawJSON writes the JSON returned by the resolver of the field as it is, see
+// resolvable.Field.RawJSON. With ValidateRawJSON it is checked against the type and the
+// selections of the field first. It returns false if the value is null but the type is non-null.
+func (r *Request) execRawJSON(f *fieldToExec, path *pathSegment, result reflect.Value) bool {
+	if result.Kind() == reflect.Ptr {
+		result = result.Elem()
+	}
+	data := bytes.TrimSpace(result.Bytes())
+	_, nonNull := f.field.Type.(*common.NonNull)
+	if len(data) == 0 || string(data) == "null" {
+		if nonNull {
+			err := errors.Errorf("got nil for non-null %q", f.field.Type)
+			err.Path = path.toSlice()
+			r.AddError(err)
+			return false
+		}
+		f.out.WriteString("null")
+		return true
+	}
+	if r.ValidateRawJSON {
+		if err := r.checkRawJSON(data, f.field.Type, f.rawSels, path); err != nil {
+			r.AddError(err)
+			if nonNull {
+				return false
+			}
+			f.out.WriteString("null")
+			return true
+		}
+	}
+	f.out.Write(data)
+	return true
+}
+
+// checkRawJSON checks that the JSON is a value of the type with the selections, i.e. the one that
+// would have been written for the selections if the value had been resolved.
+func (r *Request) checkRawJSON(data []byte, typ common.Type, sels []query.Selection, path *pathSegment) *errors.QueryError {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		qErr := rawJSONError(path, "%s", err)
+		qErr.OriginalError = err
+		return qErr
+	}
+	if dec.More() {
+		return rawJSONError(path, "more than one value")
+	}
+	return r.checkRawValue(value, typ, sels, path)
+}
+
+func (r *Request) checkRawValue(value interface{}, typ common.Type, sels []query.Selection, path *pathSegment) *errors.QueryError {
+	t, nonNull := unwrapNonNull(typ)
+	if value == nil {
+		if nonNull {
+			return rawJSONError(path, "null for non-null %q", typ)
+		}
+		return nil
+	}
+
+	switch t := t.(type) {
+	case *common.List:
+		entries, ok := value.([]interface{})
+		if !ok {
+			return rawJSONError(path, "expected a list of %q", t.OfType)
+		}
+		for i, entry := range entries {
+			if err := r.checkRawValue(entry, t.OfType, sels, &pathSegment{parent: path, value: i}); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *schema.Object, *schema.Interface, *schema.Union:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return rawJSONError(path, "expected an object of type %q", t)
+		}
+		named := t.(schema.NamedType)
+		typeName := ""
+		if o, ok := t.(*schema.Object); ok {
+			typeName = o.Name
+		} else if name, ok := obj[typenameAlias(sels)].(string); ok {
+			typeName = name
+		}
+		fields := &rawFields{byAlias: make(map[string]*rawField)}
+		r.collectRawFields(sels, named, typeName, true, fields)
+		for _, alias := range fields.aliases {
+			f := fields.byAlias[alias]
+			fieldValue, ok := obj[alias]
+			if !ok {
+				if f.required {
+					return rawJSONError(path, "missing field %q", alias)
+				}
+				continue
+			}
+			if err := r.checkRawValue(fieldValue, f.typ, f.sels, &pathSegment{parent: path, value: alias}); err != nil {
+				return err
+			}
+		}
+		var unselected []string
+		for key := range obj {
+			if fields.byAlias[key] == nil {
+				unselected = append(unselected, key)
+			}
+		}
+		if len(unselected) != 0 {
+			sort.Strings(unselected)
+			return rawJSONError(path, "field %q is not selected", unselected[0])
+		}
+		return nil
+
+	case *schema.Enum:
+		if name, ok := value.(string); ok {
+			for _, v := range t.Values {
+				if v.Name == name {
+					return nil
+				}
+			}
+		}
+		return rawJSONError(path, "expected a value of enum %q", t.Name)
+
+	case *schema.Scalar:
+		data, err := json.Marshal(value)
+		if err != nil || !validScalarOutput(t.Name, data) {
+			return rawJSONError(path, "%s cannot represent value: %s", t.Name, data)
+		}
+		return nil
+
+	default:
+		panic("invalid type")
+	}
+}
+
+// rawFields are the fields selected in an object, by their aliases in the order of the selections.
+type rawFields struct {
+	aliases []string
+	byAlias map[string]*rawField
+}
+
+type rawField struct {
+	typ      common.Type
+	sels     []query.Selection
+	required bool // the field is selected whatever the type of the object
+}
+
+var typenameType = &common.NonNull{OfType: &schema.Scalar{Name: "String"}}
+
+// collectRawFields adds the fields selected in an object of type t to fields. typeName is the
+// concrete type of the object, empty if unknown. The fields of fragments not applying to the type
+// are left out, and those of fragments that might not apply are not required.
+func (r *Request) collectRawFields(sels []query.Selection, t schema.NamedType, typeName string, required bool, fields *rawFields) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			if r.skipped(sel.Directives) {
+				continue
+			}
+			var typ common.Type
+			if sel.Name.Name == "__typename" {
+				typ = typenameType
+			} else if def := fieldsOf(t).Get(sel.Name.Name); def != nil {
+				typ = def.Type
+			} else {
+				continue // an introspection field, which the validation only allows on the root type
+			}
+			f, ok := fields.byAlias[sel.Alias.Name]
+			if !ok {
+				f = &rawField{typ: typ}
+				fields.aliases = append(fields.aliases, sel.Alias.Name)
+				fields.byAlias[sel.Alias.Name] = f
+			}
+			f.sels = append(f.sels, sel.Selections...)
+			f.required = f.required || required
+
+		case *query.InlineFragment:
+			if r.skipped(sel.Directives) {
+				continue
+			}
+			r.collectRawFragment(&sel.Fragment, t, typeName, required, fields)
+
+		case *query.FragmentSpread:
+			if r.skipped(sel.Directives) {
+				continue
+			}
+			if frag := r.Doc.Fragments.Get(sel.Name.Name); frag != nil {
+				r.collectRawFragment(&frag.Fragment, t, typeName, required, fields)
+			}
+
+		default:
+			panic("invalid type")
+		}
+	}
+}
+
+func (r *Request) collectRawFragment(frag *query.Fragment, t schema.NamedType, typeName string, required bool, fields *rawFields) {
+	cond := frag.On.Name
+	if cond == "" || cond == t.TypeName() {
+		r.collectRawFields(frag.Selections, t, typeName, required, fields)
+		return
+	}
+	condType := r.Schema.Types[cond]
+	if typeName == "" {
+		r.collectRawFields(frag.Selections, condType, typeName, false, fields)
+		return
+	}
+	if cond == typeName || isPossibleType(condType, typeName) {
+		r.collectRawFields(frag.Selections, condType, typeName, required, fields)
+	}
+}
+
+// fieldsOf returns the fields of an object or interface type, none for other types.
+func fieldsOf(t schema.NamedType) schema.FieldList {
+	switch t := t.(type) {
+	case *schema.Object:
+		return t.Fields
+	case *schema.Interface:
+		return t.Fields
+	}
+	return nil
+}
+
+// isPossibleType reports whether the object type is one of the possible types of the interface or
+// union.
+func isPossibleType(t schema.NamedType, typeName string) bool {
+	var possible []*schema.Object
+	switch t := t.(type) {
+	case *schema.Interface:
+		possible = t.PossibleTypes
+	case *schema.Union:
+		possible = t.PossibleTypes
+	}
+	for _, o := range possible {
+		if o.Name == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+// typenameAlias returns the alias of __typename among the fields of the selections.
+func typenameAlias(sels []query.Selection) string {
+	for _, sel := range sels {
+		if f, ok := sel.(*query.Field); ok && f.Name.Name == "__typename" {
+			return f.Alias.Name
+		}
+	}
+	return "__typename"
+}
+
+// skipped reports whether the directives @skip or @include leave out the selection.
+func (r *Request) skipped(directives common.DirectiveList) bool {
+	if d := directives.Get("skip"); d != nil {
+		if skip, _ := d.Args.MustGet("if").Value(r.Vars).(bool); skip {
+			return true
+		}
+	}
+	if d := directives.Get("include"); d != nil {
+		if include, ok := d.Args.MustGet("if").Value(r.Vars).(bool); ok && !include {
+			return true
+		}
+	}
+	return false
+}
+
+// rawSelectedFields returns the tree of the fields selected by the selections of a field whose
+// resolver returns JSON, see resolvable.Field.RawJSON. The fields of all fragments are included,
+// whatever their type conditions, with the arguments given in the query.
+func (r *Request) rawSelectedFields(sels []query.Selection) []pubquery.SelectedField {
+	var result []pubquery.SelectedField
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			if r.skipped(sel.Directives) || sel.Name.Name == "__typename" {
+				continue
+			}
+			var args map[string]interface{}
+			for _, arg := range sel.Arguments {
+				if common.IsMissingVariable(arg.Value, r.Vars) {
+					continue
+				}
+				if args == nil {
+					args = make(map[string]interface{})
+				}
+				args[arg.Name.Name] = arg.Value.Value(r.Vars)
+			}
+			result = append(result, pubquery.SelectedField{
+				Name:     sel.Name.Name,
+				Alias:    sel.Alias.Name,
+				Args:     args,
+				Selected: r.rawSelectedFields(sel.Selections),
+			})
+
+		case *query.InlineFragment:
+			if !r.skipped(sel.Directives) {
+				result = append(result, r.rawSelectedFields(sel.Selections)...)
+			}
+
+		case *query.FragmentSpread:
+			if r.skipped(sel.Directives) {
+				continue
+			}
+			if frag := r.Doc.Fragments.Get(sel.Name.Name); frag != nil {
+				result = append(result, r.rawSelectedFields(frag.Selections)...)
+			}
+		}
+	}
+	return result
+}
+
+func rawJSONError(path *pathSegment, format string, a ...interface{}) *errors.QueryError {
+	err := errors.Errorf("invalid raw JSON: %s", fmt.Sprintf(format, a...))
+	err.Path = path.toSlice()
+	err.Extensions = map[string]interface{}{"code": "INVALID_RAW_JSON"}
+	return err
+}
@@ -0,0 +1,245 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/exec/selected"
+	"github.com/qdentity/graphql-go/internal/schema"
+	"github.com/qdentity/graphql-go/recording"
+)
+
+// replayedObject is the resolver of objects when replaying. The fields of objects are replayed by
+// their paths, only the concrete type of the object is needed to apply type assertions.
+type replayedObject struct {
+	typeName string
+}
+
+var replayedObjectType = reflect.TypeOf(&replayedObject{})
+
+func replayed(resolver reflect.Value) (*replayedObject, bool) {
+	if !resolver.IsValid() || resolver.Type() != replayedObjectType {
+		return nil, false
+	}
+	return resolver.Interface().(*replayedObject), true
+}
+
+// fieldPath returns the path of the field, with its name and arguments for the keys of recorded
+// values when recording or replaying.
+func (r *Request) fieldPath(parent *pathSegment, f *selected.SchemaField) *pathSegment {
+	p := &pathSegment{parent: parent, value: f.Alias}
+	if r.Record != nil || r.Replay != nil {
+		p.field = fieldKey(f)
+	}
+	return p
+}
+
+// fieldKey returns the name of the field with its arguments sorted by name, e.g.
+// `hero(episode:"JEDI")`.
+func fieldKey(f *selected.SchemaField) string {
+	if len(f.Args) == 0 {
+		return f.Name
+	}
+	names := make([]string, 0, len(f.Args))
+	for name := range f.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(f.Name)
+	b.WriteByte('(')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value, _ := json.Marshal(f.Args[name])
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// fixtureKey returns the key of the value at the path in recordings.
+func fixtureKey(path *pathSegment) string {
+	var parts []string
+	for p := path; p != nil; p = p.parent {
+		if p.field != "" {
+			parts = append(parts, p.field)
+		} else {
+			parts = append(parts, strconv.Itoa(p.value.(int)))
+		}
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, ".")
+}
+
+// recordField records the value written for the field, or its error. Objects within the value are
+// recorded as {}, their fields record their own values.
+func (r *Request) recordField(path *pathSegment, typ common.Type, value []byte, err *errors.QueryError) {
+	key := fixtureKey(path)
+	if err != nil {
+		r.Record.Set(key, &recording.Entry{Error: err.Message})
+		return
+	}
+	if isLeaf(typ) {
+		r.Record.Set(key, &recording.Entry{Value: append(json.RawMessage(nil), value...)})
+		return
+	}
+	var v interface{}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return
+	}
+	data, _ := json.Marshal(skeleton(v))
+	r.Record.Set(key, &recording.Entry{Value: data})
+}
+
+// recordType records the concrete type of the object at the path if the selections depend on it.
+func (r *Request) recordType(path *pathSegment, sels []selected.Selection, resolver reflect.Value) {
+	typeName, found := "", false
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *selected.TypenameField:
+			if len(sel.TypeAssertions) != 0 {
+				typeName, found = typeOf(sel, resolver), true
+			}
+		case *selected.TypeAssertion:
+			found = true
+			if typeName != "" {
+				continue
+			}
+			if _, ok := assertType(&sel.TypeAssertion, resolver); ok {
+				typeName = sel.TypeExec.(*resolvable.Object).Name
+			}
+		}
+	}
+	if found {
+		data, _ := json.Marshal(typeName)
+		r.Record.Set(fixtureKey(path)+".__typename", &recording.Entry{Value: data})
+	}
+}
+
+// replayEntry returns the recorded value of the field at the path, or its recorded error.
+func (r *Request) replayEntry(path *pathSegment) (*recording.Entry, *errors.QueryError) {
+	key := fixtureKey(path)
+	e, ok := r.Replay.Get(key)
+	if !ok {
+		err := errors.Errorf("no recorded value for %s", key)
+		err.Path = path.toSlice()
+		err.Extensions = map[string]interface{}{"code": "NOT_RECORDED"}
+		return nil, err
+	}
+	if e.Error != "" {
+		err := errors.Errorf("%s", e.Error)
+		err.Path = path.toSlice()
+		return nil, err
+	}
+	return e, nil
+}
+
+// replayField writes the recorded value of the field. Like execSelectionSet, it returns false if
+// the value is null but the type of the field is non-null.
+func (r *Request) replayField(ctx context.Context, f *fieldToExec, path *pathSegment, value json.RawMessage) bool {
+	if isLeaf(f.field.Type) || f.field.Delegate != nil || f.field.RawJSON {
+		if len(value) == 0 || string(value) == "null" {
+			_, nonNull := f.field.Type.(*common.NonNull)
+			if !nonNull {
+				f.out.WriteString("null")
+			}
+			return !nonNull
+		}
+		f.out.Write(value)
+		return true
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(value, &v); err != nil {
+		qErr := errors.Errorf("invalid recorded value: %s", err)
+		qErr.Path = path.toSlice()
+		r.AddError(qErr)
+		v = nil
+	}
+	return r.replaySelectionSet(ctx, f.sels, f.field.Type, path, v, f.out)
+}
+
+func (r *Request) replaySelectionSet(ctx context.Context, sels []selected.Selection, typ common.Type, path *pathSegment, value interface{}, out *bytes.Buffer) bool {
+	t, nonNull := unwrapNonNull(typ)
+	start := out.Len()
+	null := func() bool {
+		out.Truncate(start)
+		if nonNull {
+			return false
+		}
+		out.WriteString("null")
+		return true
+	}
+	if value == nil {
+		return null()
+	}
+
+	if t, ok := t.(*common.List); ok {
+		entries, _ := value.([]interface{})
+		out.WriteByte('[')
+		for i, entry := range entries {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if !r.replaySelectionSet(ctx, sels, t.OfType, &pathSegment{parent: path, value: i}, entry, out) {
+				return null()
+			}
+		}
+		out.WriteByte(']')
+		return true
+	}
+
+	obj := &replayedObject{}
+	if e, ok := r.Replay.Get(fixtureKey(path) + ".__typename"); ok {
+		json.Unmarshal(e.Value, &obj.typeName)
+	}
+	if !r.execSelections(ctx, sels, path, reflect.ValueOf(obj), out, false) {
+		return null()
+	}
+	return true
+}
+
+// isLeaf reports whether values of the type are scalars, enums or lists of them.
+func isLeaf(t common.Type) bool {
+	for {
+		switch tt := t.(type) {
+		case *common.NonNull:
+			t = tt.OfType
+		case *common.List:
+			t = tt.OfType
+		case *schema.Scalar, *schema.Enum:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// skeleton replaces the objects within the value with empty objects.
+func skeleton(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return struct{}{}
+	case []interface{}:
+		for i, entry := range v {
+			v[i] = skeleton(entry)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -1,12 +1,12 @@
 package resolvable
 
 import (
-	"fmt"
 	"reflect"
 
 	"github.com/qdentity/graphql-go/internal/common"
 	"github.com/qdentity/graphql-go/internal/schema"
 	"github.com/qdentity/graphql-go/introspection"
+	"github.com/qdentity/graphql-go/trace"
 )
 
 var MetaSchema *Object
@@ -38,7 +38,8 @@ var MetaFieldTypename = Field{
 		Name: "__typename",
 		Type: &common.NonNull{OfType: schema.Meta.Types["String"]},
 	},
-	TraceLabel: fmt.Sprintf("GraphQL field: __typename"),
+	TraceLabel: "GraphQL field: __typename",
+	TraceID:    trace.NewFieldIdentifier("", "__typename"),
 }
 
 var MetaFieldSchema = Field{
@@ -46,7 +47,8 @@ var MetaFieldSchema = Field{
 		Name: "__schema",
 		Type: schema.Meta.Types["__Schema"],
 	},
-	TraceLabel: fmt.Sprintf("GraphQL field: __schema"),
+	TraceLabel: "GraphQL field: __schema",
+	TraceID:    trace.NewFieldIdentifier("", "__schema"),
 }
 
 var MetaFieldType = Field{
@@ -54,5 +56,6 @@ var MetaFieldType = Field{
 		Name: "__type",
 		Type: schema.Meta.Types["__Type"],
 	},
-	TraceLabel: fmt.Sprintf("GraphQL field: __type"),
+	TraceLabel: "GraphQL field: __type",
+	TraceID:    trace.NewFieldIdentifier("", "__type"),
 }
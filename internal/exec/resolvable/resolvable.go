@@ -2,22 +2,40 @@ package resolvable
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/errors"
 	"github.com/qdentity/graphql-go/internal/common"
 	"github.com/qdentity/graphql-go/internal/exec/packer"
 	"github.com/qdentity/graphql-go/internal/schema"
 	pubquery "github.com/qdentity/graphql-go/query"
+	"github.com/qdentity/graphql-go/trace"
 )
 
 type Schema struct {
 	schema.Schema
-	Query    Resolvable
-	Mutation Resolvable
-	Resolver reflect.Value
+	Query        Resolvable
+	Mutation     Resolvable
+	Subscription Resolvable
+	Resolver     reflect.Value
+
+	// Constructor, if valid, returns the root resolver of a request, see RootType. Resolver is not
+	// valid then.
+	Constructor reflect.Value
+
+	// ScalarTypes are the Go types mapped to scalar types, see Options.
+	ScalarTypes map[reflect.Type]string
+
+	// Unbound are the errors of the missing resolvers bound leniently, see Options.
+	Unbound []string
 }
 
 type Resolvable interface {
@@ -26,6 +44,7 @@ type Resolvable interface {
 
 type Object struct {
 	Name           string
+	ResolverType   reflect.Type
 	Fields         map[string]*Field
 	TypeAssertions map[string]*TypeAssertion
 }
@@ -37,13 +56,139 @@ type Field struct {
 	HasContext  bool
 	HasError    bool
 	HasSelected bool
+	HasRawArgs  bool         // the method takes the arguments of the field as query.RawArgs
+	HasVars     bool         // the method takes the variables of the operation as query.Variables
+	ParentType  reflect.Type // if not nil, the query.Parent the method takes
+	HasPath     bool         // the method takes the response path of the field as query.Path
 	ArgsPacker  *packer.StructPacker
 	ValueExec   Resolvable
 	TraceLabel  string
+	TraceID     *trace.FieldIdentifier
+	Timeout     time.Duration
+	Retry       *RetryPolicy
+	Hedge       *HedgePolicy
+	Auth        *AuthRule
+	Cost        int
+	Delegate    Delegate // resolves the field instead of a method, ValueExec is nil then
+	Func        *FieldFunc
+	FieldIndex  []int // of the struct field holding the value if the type has no method for it
+	Unbound     bool  // the field has no resolver, see Options.Lenient
+	RawJSON     bool  // the resolver returns the value as JSON, see Options.RawJSON; ValueExec is nil then
+	Optional    bool  // the errors of the field and its subtree are warnings, see Options.OptionalFields
+
+	// EventFilter, if valid, is the func(context.Context, T) (T, bool, error) applied to the events
+	// of a subscription field, whose method returns a channel of T.
+	EventFilter reflect.Value
+
+	// ParentProjections map the types of parent resolvers to the functions projecting them to the
+	// Value of ParentType, see Options.ParentProjections.
+	ParentProjections map[reflect.Type]reflect.Value
+}
+
+// AuthRule restricts a field to authenticated principals, optionally having one of the roles.
+type AuthRule struct {
+	Roles []string
+}
+
+// RetryPolicy describes how the resolver of a field is called again after it returned an error.
+type RetryPolicy struct {
+	Attempts  int
+	Backoff   func(retry int) time.Duration
+	Retryable func(err error) bool
+}
+
+// HedgePolicy describes when the resolver of a field is called again while the earlier calls have
+// not returned yet.
+type HedgePolicy struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// Options configures how a resolver gets bound to a schema.
+type Options struct {
+	// RetryPolicies maps fields given as "Type.field" to their retry policy. They take
+	// precedence over @retry directives in the schema.
+	RetryPolicies map[string]*RetryPolicy
+
+	// AuthPolicies maps fields given as "Type.field" to the roles of which the principal needs to
+	// have at least one. An empty list only requires an authenticated principal. They take
+	// precedence over @auth and @hasRole directives in the schema.
+	AuthPolicies map[string][]string
+
+	// HedgePolicies maps fields given as "Type.field" to their hedge policy. They take precedence
+	// over @hedge directives in the schema.
+	HedgePolicies map[string]*HedgePolicy
+
+	// Delegates maps root fields given as "Query.field" or "Mutation.field" to the delegates
+	// resolving them instead of resolver methods.
+	Delegates map[string]Delegate
+
+	// FieldFuncs maps root fields given as "Query.field" or "Mutation.field" to the functions
+	// resolving them instead of resolver methods.
+	FieldFuncs map[string]*FieldFunc
+
+	// EventFilters maps subscription fields given as "Subscription.field" to functions of type
+	// func(context.Context, T) (T, bool, error), where T is the type of the events of the channel
+	// returned by the field's resolver method.
+	EventFilters map[string]interface{}
+
+	// ScalarTypes maps Go types that do not implement packer.Unmarshaler to the names of the scalar
+	// types they are used as. Their values are written with json.Marshal and read from the input
+	// with json.Unmarshaler or encoding.TextUnmarshaler.
+	ScalarTypes map[reflect.Type]string
+
+	// OptionalFields are the fields given as "Type.field" whose errors, and those of their
+	// subtrees, are reported as warnings. They are in addition to the fields with an @optional
+	// directive in the schema.
+	OptionalFields map[string]bool
+
+	// ParentProjections map pairs of the type of a parent resolver and the type T of a query.Parent
+	// to functions projecting the resolvers to T.
+	ParentProjections map[[2]reflect.Type]reflect.Value
+
+	// RawJSON is the type of the resolver results holding the JSON of the values of their fields,
+	// which is written as it is, see graphql.RawJSON.
+	RawJSON reflect.Type
+
+	// Lenient binds the fields without a resolver method or struct field instead of failing. They
+	// are null with an error when they are resolved. A missing method converting an interface to
+	// one of its types makes the values never be of that type.
+	Lenient bool
+
+	// TypeResolvers maps interface and union types to the functions resolving the object types of
+	// their values instead of the methods converting them, e.g. ToHuman.
+	TypeResolvers map[string]*TypeResolver
+
+	// Workers, if greater than 1, is the number of goroutines binding the object, interface and
+	// union types of the schema concurrently. If binding fails, it is repeated on a single
+	// goroutine, so that the error is the same as without workers.
+	Workers int
+}
+
+// FieldFunc resolves a field with the arguments of the query. The values it returns are of type
+// ResultType, which is bound to the type of the field.
+type FieldFunc struct {
+	Resolve    func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+	ResultType reflect.Type
 }
 
+// TypeResolver resolves the object types of the values of an interface or union type. TypeOf
+// returns the name of the object type of a value and the resolver of the object, whose Go type has
+// to be the one of the object type in ResolverTypes.
+type TypeResolver struct {
+	TypeOf        func(value interface{}) (typeName string, resolver interface{})
+	ResolverTypes map[string]reflect.Type
+}
+
+// Delegate executes a query on an upstream service and returns the data and the errors of its
+// response.
+type Delegate func(ctx context.Context, query string, variables map[string]interface{}) (json.RawMessage, []*errors.QueryError)
+
+// TypeAssertion converts the values of an interface or union type to one of its object types,
+// either with the method at MethodIndex, -1 if there is none, or with TypeOf.
 type TypeAssertion struct {
 	MethodIndex int
+	TypeOf      func(value interface{}) (typeName string, resolver interface{})
 	TypeExec    Resolvable
 }
 
@@ -57,39 +202,177 @@ func (*Object) isResolvable() {}
 func (*List) isResolvable()   {}
 func (*Scalar) isResolvable() {}
 
-func ApplyResolver(s *schema.Schema, resolver interface{}) (*Schema, error) {
+func ApplyResolver(s *schema.Schema, resolver interface{}, opts Options) (*Schema, error) {
+	for name, policy := range opts.RetryPolicies {
+		if err := checkObjectFieldRef(s, name); err != nil {
+			return nil, perrors.Errorf("retry policy: %s", err)
+		}
+		if policy.Attempts < 1 {
+			return nil, perrors.Errorf("retry policy: %q needs at least 1 attempt", name)
+		}
+	}
+	for name := range opts.AuthPolicies {
+		if err := checkFieldRef(s, name); err != nil {
+			return nil, perrors.Errorf("auth policy: %s", err)
+		}
+	}
+
+	for name, policy := range opts.HedgePolicies {
+		if err := checkObjectFieldRef(s, name); err != nil {
+			return nil, perrors.Errorf("hedge policy: %s", err)
+		}
+		if policy.Attempts < 2 || policy.Delay < 0 {
+			return nil, perrors.Errorf("hedge policy: %q needs at least 2 attempts and a non-negative delay", name)
+		}
+	}
+	for name := range opts.OptionalFields {
+		if err := checkFieldRef(s, name); err != nil {
+			return nil, perrors.Errorf("optional field: %s", err)
+		}
+	}
+
+	for name := range opts.Delegates {
+		if err := checkRootFieldRef(s, name); err != nil {
+			return nil, perrors.Errorf("delegate: %s", err)
+		}
+	}
+	for name, fn := range opts.FieldFuncs {
+		if err := checkRootFieldRef(s, name); err != nil {
+			return nil, perrors.Errorf("field func: %s", err)
+		}
+		if fn.Resolve == nil || fn.ResultType == nil {
+			return nil, perrors.Errorf("field func: %q has no function or result type", name)
+		}
+	}
+
+	for name := range opts.EventFilters {
+		if err := checkFieldRef(s, name); err != nil {
+			return nil, perrors.Errorf("event filter: %s", err)
+		}
+		if !isEntryPoint(s, name[:strings.IndexByte(name, '.')], "subscription") {
+			return nil, perrors.Errorf("event filter: %q is not a field of the subscription type", name)
+		}
+	}
+
+	for name, tr := range opts.TypeResolvers {
+		var possibleTypes []*schema.Object
+		switch t := s.Types[name].(type) {
+		case *schema.Interface:
+			possibleTypes = t.PossibleTypes
+		case *schema.Union:
+			possibleTypes = t.PossibleTypes
+		default:
+			return nil, perrors.Errorf("type resolver: %q is not an interface or union type of the schema", name)
+		}
+		if tr.TypeOf == nil {
+			return nil, perrors.Errorf("type resolver: %q has no function", name)
+		}
+		for _, impl := range possibleTypes {
+			if tr.ResolverTypes[impl.Name] == nil {
+				return nil, perrors.Errorf("type resolver: %q has no resolver type for %q", name, impl.Name)
+			}
+		}
+		for impl := range tr.ResolverTypes {
+			if !isPossibleType(possibleTypes, impl) {
+				return nil, perrors.Errorf("type resolver: %q is not a possible type of %q", impl, name)
+			}
+		}
+	}
+
+	for t, name := range opts.ScalarTypes {
+		if _, ok := s.Types[name].(*schema.Scalar); !ok {
+			return nil, perrors.Errorf("scalar type: %q is not a scalar type of the schema", name)
+		}
+		if !packer.CanUnmarshalMapped(t) {
+			return nil, perrors.Errorf("scalar type: %s implements neither json.Unmarshaler nor encoding.TextUnmarshaler", t)
+		}
+	}
+
+	resolverType := RootType(resolver)
+	if t := reflect.TypeOf(resolver); t.Kind() == reflect.Func && t == resolverType {
+		return nil, perrors.Errorf("root resolver %s is not a constructor of type func(context.Context) (T, error)", t)
+	}
+
 	b := newBuilder(s)
+	b.opts = opts
+	b.packerBuilder.ScalarTypes = opts.ScalarTypes
+	if opts.Workers > 1 {
+		b.workers = make(chan struct{}, opts.Workers-1) // the calling goroutine is a worker as well
+	}
 
-	var query, mutation Resolvable
+	var query, mutation, subscription Resolvable
 
 	if t, ok := s.EntryPoints["query"]; ok {
-		if err := b.assignExec(&query, t, reflect.TypeOf(resolver)); err != nil {
+		if err := b.assignExec(&query, t, resolverType); err != nil {
 			return nil, err
 		}
 	}
 
 	if t, ok := s.EntryPoints["mutation"]; ok {
-		if err := b.assignExec(&mutation, t, reflect.TypeOf(resolver)); err != nil {
+		if err := b.assignExec(&mutation, t, resolverType); err != nil {
 			return nil, err
 		}
 	}
 
+	if t, ok := s.EntryPoints["subscription"]; ok {
+		if err := b.assignExec(&subscription, t, resolverType); err != nil {
+			return nil, err
+		}
+	}
+
+	b.wg.Wait()
+	if b.failed {
+		opts.Workers = 0
+		return ApplyResolver(s, resolver, opts)
+	}
+
 	if err := b.finish(); err != nil {
 		return nil, err
 	}
+	sort.Strings(b.unbound)
 
-	return &Schema{
-		Schema:   *s,
-		Resolver: reflect.ValueOf(resolver),
-		Query:    query,
-		Mutation: mutation,
-	}, nil
+	res := &Schema{
+		Schema:       *s,
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: subscription,
+		ScalarTypes:  opts.ScalarTypes,
+		Unbound:      b.unbound,
+	}
+	if resolverType != reflect.TypeOf(resolver) {
+		res.Constructor = reflect.ValueOf(resolver)
+	} else if v := reflect.ValueOf(resolver); v.Kind() != reflect.Ptr {
+		res.Resolver = reflect.New(resolverType)
+		res.Resolver.Elem().Set(v)
+	} else {
+		res.Resolver = v
+	}
+	return res, nil
+}
+
+// RootType returns the type of the root resolver. A root resolver of type
+// func(context.Context) (T, error) is a constructor of root resolvers of type T.
+func RootType(resolver interface{}) reflect.Type {
+	t := reflect.TypeOf(resolver)
+	if t.Kind() == reflect.Func && t.NumIn() == 1 && t.In(0) == contextType && t.NumOut() == 2 && t.Out(1) == errorType {
+		return t.Out(0)
+	}
+	return t
 }
 
 type execBuilder struct {
 	schema        *schema.Schema
+	opts          Options
 	resMap        map[typePair]*resMapEntry
 	packerBuilder *packer.Builder
+	unbound       []string
+
+	// workers, if set, limits the goroutines binding types concurrently, see Options.Workers. mu
+	// guards resMap, packerBuilder, unbound and failed then.
+	workers chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	failed  bool
 }
 
 type typePair struct {
@@ -122,18 +405,50 @@ func (b *execBuilder) finish() error {
 
 func (b *execBuilder) assignExec(target *Resolvable, t common.Type, resolverType reflect.Type) error {
 	k := typePair{t, resolverType}
+	b.mu.Lock()
 	ref, ok := b.resMap[k]
-	if !ok {
-		ref = &resMapEntry{}
-		b.resMap[k] = ref
-		var err error
-		ref.exec, err = b.makeExec(t, resolverType)
-		if err != nil {
-			return err
+	if ok {
+		ref.targets = append(ref.targets, target)
+		b.mu.Unlock()
+		return nil
+	}
+	ref = &resMapEntry{targets: []*Resolvable{target}}
+	b.resMap[k] = ref
+	b.mu.Unlock()
+
+	if b.workers != nil && isObjectType(t) {
+		select {
+		case b.workers <- struct{}{}:
+			b.wg.Add(1)
+			go func() {
+				defer b.wg.Done()
+				defer func() { <-b.workers }()
+				exec, err := b.makeExec(t, resolverType)
+				if err != nil {
+					b.mu.Lock()
+					b.failed = true
+					b.mu.Unlock()
+				}
+				ref.exec = exec // read by finish after all workers are done
+			}()
+			return nil
+		default: // all workers are busy
 		}
 	}
-	ref.targets = append(ref.targets, target)
-	return nil
+
+	var err error
+	ref.exec, err = b.makeExec(t, resolverType)
+	return err
+}
+
+// isObjectType reports whether the type is an object, interface or union type, possibly non-null.
+func isObjectType(t common.Type) bool {
+	t, _ = unwrapNonNull(t)
+	switch t.(type) {
+	case *schema.Object, *schema.Interface, *schema.Union:
+		return true
+	}
+	return false
 }
 
 func (b *execBuilder) makeExec(t common.Type, resolverType reflect.Type) (Resolvable, error) {
@@ -152,25 +467,37 @@ func (b *execBuilder) makeExec(t common.Type, resolverType reflect.Type) (Resolv
 	}
 
 	if !nonNull {
-		if resolverType.Kind() != reflect.Ptr {
-			return nil, perrors.Errorf("%s is not a pointer", resolverType)
+		// a nil interface or slice is null as well, the elements of interface values are checked
+		// when the values are resolved
+		_, isList := t.(*common.List)
+		switch {
+		case resolverType.Kind() == reflect.Interface:
+		case isList && (resolverType.Kind() == reflect.Slice || IsIterator(resolverType)):
+		case resolverType.Kind() == reflect.Ptr:
+			resolverType = resolverType.Elem()
+		case isList:
+			return nil, perrors.Errorf("%s is not a pointer, slice, iterator or interface", resolverType)
+		default:
+			return nil, perrors.Errorf("%s is not a pointer or interface", resolverType)
 		}
-		resolverType = resolverType.Elem()
 	}
 
 	switch t := t.(type) {
 	case *schema.Scalar:
-		return makeScalarExec(t, resolverType)
+		return makeScalarExec(t, resolverType, b.opts.ScalarTypes)
 
 	case *schema.Enum:
 		return &Scalar{}, nil
 
 	case *common.List:
-		if resolverType.Kind() != reflect.Slice {
-			return nil, perrors.Errorf("%s is not a slice", resolverType)
+		elemType := resolverType
+		if elem, _, ok := IteratorElem(resolverType); ok {
+			elemType = reflect.SliceOf(elem)
+		} else if resolverType.Kind() != reflect.Slice && resolverType.Kind() != reflect.Array {
+			return nil, perrors.Errorf("%s is not a slice, array or iterator", resolverType)
 		}
 		e := &List{}
-		if err := b.assignExec(&e.Elem, t.OfType, resolverType.Elem()); err != nil {
+		if err := b.assignExec(&e.Elem, t.OfType, elemType.Elem()); err != nil {
 			return nil, err
 		}
 		return e, nil
@@ -180,7 +507,23 @@ func (b *execBuilder) makeExec(t common.Type, resolverType reflect.Type) (Resolv
 	}
 }
 
-func makeScalarExec(t *schema.Scalar, resolverType reflect.Type) (Resolvable, error) {
+func makeScalarExec(t *schema.Scalar, resolverType reflect.Type, scalarTypes map[reflect.Type]string) (Resolvable, error) {
+	if resolverType.Kind() == reflect.Interface {
+		return &Scalar{}, nil // the dynamic type of each value is checked when it is resolved
+	}
+	if !ImplementsScalar(t, resolverType, scalarTypes) {
+		return nil, perrors.Errorf("can not use %s as %s", resolverType, t.Name)
+	}
+	return &Scalar{}, nil
+}
+
+// ImplementsScalar reports whether values of the Go type can be used as the scalar type, either
+// because it is a builtin one or implements packer.Unmarshaler, or because it is mapped to the
+// scalar type by scalarTypes.
+func ImplementsScalar(t *schema.Scalar, resolverType reflect.Type, scalarTypes map[reflect.Type]string) bool {
+	if name, ok := scalarTypes[resolverType]; ok {
+		return name == t.Name
+	}
 	implementsType := false
 	switch r := reflect.New(resolverType).Interface().(type) {
 	case *int32:
@@ -194,30 +537,60 @@ func makeScalarExec(t *schema.Scalar, resolverType reflect.Type) (Resolvable, er
 	case packer.Unmarshaler:
 		implementsType = r.ImplementsGraphQLType(t.Name)
 	}
-	if !implementsType {
-		return nil, perrors.Errorf("can not use %s as %s", resolverType, t.Name)
-	}
-	return &Scalar{}, nil
+	return implementsType
 }
 
 func (b *execBuilder) makeObjectExec(typeName string, fields schema.FieldList, possibleTypes []*schema.Object, nonNull bool, resolverType reflect.Type) (*Object, error) {
-	if !nonNull {
-		if resolverType.Kind() != reflect.Ptr && resolverType.Kind() != reflect.Interface {
-			return nil, perrors.Errorf("%s is not a pointer or interface", resolverType)
-		}
+	if resolverType.Kind() != reflect.Ptr && resolverType.Kind() != reflect.Interface {
+		// values are resolved through pointers to copies of them, see exec.addressable, so that
+		// methods with value and pointer receivers can be used alike; a value is never null
+		resolverType = reflect.PtrTo(resolverType)
 	}
 
 	methodHasReceiver := resolverType.Kind() != reflect.Interface
 
 	Fields := make(map[string]*Field)
 	for _, f := range fields {
+		if fn, ok := b.opts.FieldFuncs[typeName+"."+f.Name]; ok {
+			fe, err := b.makeFuncField(typeName, f, fn)
+			if err != nil {
+				return nil, perrors.Errorf("%s\n\treturned by field func of %s.%s", err, typeName, f.Name)
+			}
+			Fields[f.Name] = fe
+			continue
+		}
+		if d, ok := b.opts.Delegates[typeName+"."+f.Name]; ok {
+			fe, err := b.makeDelegatedField(typeName, f, d)
+			if err != nil {
+				return nil, err
+			}
+			Fields[f.Name] = fe
+			continue
+		}
+
 		methodIndex := findMethod(resolverType, f.Name)
 		if methodIndex == -1 {
-			hint := ""
-			if findMethod(reflect.PtrTo(resolverType), f.Name) != -1 {
-				hint = " (hint: the method exists on the pointer type)"
+			methodIndex = findMethod(resolverType, "Get"+f.Name) // getters of protocol buffer messages
+		}
+		if methodIndex == -1 {
+			if sf, ok := findStructField(resolverType, f.Name); ok {
+				fe, err := b.makeStructField(typeName, f, sf)
+				if err != nil {
+					return nil, perrors.Errorf("%s\n\treturned by struct field %s.%s", err, resolverType, sf.Name)
+				}
+				Fields[f.Name] = fe
+				continue
 			}
-			return nil, perrors.Errorf("%s does not resolve %q: missing method for field %q%s", resolverType, typeName, f.Name, hint)
+			err := perrors.Errorf("%s does not resolve %q: missing method for field %q%s", resolverType, typeName, f.Name, expectedMethod(resolverType, f.Name))
+			if !b.opts.Lenient {
+				return nil, err
+			}
+			fe, err := b.makeUnboundField(typeName, f, resolverType, err)
+			if err != nil {
+				return nil, err
+			}
+			Fields[f.Name] = fe
+			continue
 		}
 
 		m := resolverType.Method(methodIndex)
@@ -228,11 +601,44 @@ func (b *execBuilder) makeObjectExec(typeName string, fields schema.FieldList, p
 		Fields[f.Name] = fe
 	}
 
+	for _, f := range fields {
+		if Fields[f.Name].MethodIndex == -1 && (b.opts.HedgePolicies[typeName+"."+f.Name] != nil || f.Directives.Get("hedge") != nil) {
+			return nil, perrors.Errorf("hedge policy: field %s.%s is not resolved by a resolver method", typeName, f.Name)
+		}
+		if b.opts.OptionalFields[typeName+"."+f.Name] || f.Directives.Get("optional") != nil {
+			if _, nonNull := f.Type.(*common.NonNull); nonNull {
+				return nil, perrors.Errorf("optional field %s.%s must be nullable", typeName, f.Name)
+			}
+			Fields[f.Name].Optional = true
+		}
+	}
+
 	typeAssertions := make(map[string]*TypeAssertion)
+	if tr, ok := b.opts.TypeResolvers[typeName]; ok {
+		for _, impl := range possibleTypes {
+			a := &TypeAssertion{MethodIndex: -1, TypeOf: tr.TypeOf}
+			if err := b.assignExec(&a.TypeExec, impl, tr.ResolverTypes[impl.Name]); err != nil {
+				return nil, err
+			}
+			typeAssertions[impl.Name] = a
+		}
+		possibleTypes = nil
+	}
 	for _, impl := range possibleTypes {
 		methodIndex := findMethod(resolverType, "To"+impl.Name)
 		if methodIndex == -1 {
-			return nil, perrors.Errorf("%s does not resolve %q: missing method %q to convert to %q", resolverType, typeName, "To"+impl.Name, impl.Name)
+			err := perrors.Errorf("%s does not resolve %q: missing method %q to convert to %q%s", resolverType, typeName, "To"+impl.Name, impl.Name, expectedMethod(resolverType, "To"+impl.Name))
+			if !b.opts.Lenient {
+				return nil, err
+			}
+			// the values are never of the type then
+			b.addUnbound(resolverType, err)
+			a := &TypeAssertion{MethodIndex: -1}
+			if err := b.assignExec(&a.TypeExec, impl, placeholderType); err != nil {
+				return nil, err
+			}
+			typeAssertions[impl.Name] = a
+			continue
 		}
 		if resolverType.Method(methodIndex).Type.NumOut() != 2 {
 			return nil, perrors.Errorf("%s does not resolve %q: method %q should return a value and a bool indicating success", resolverType, typeName, "To"+impl.Name)
@@ -248,6 +654,7 @@ func (b *execBuilder) makeObjectExec(typeName string, fields schema.FieldList, p
 
 	return &Object{
 		Name:           typeName,
+		ResolverType:   resolverType,
 		Fields:         Fields,
 		TypeAssertions: typeAssertions,
 	}, nil
@@ -255,7 +662,12 @@ func (b *execBuilder) makeObjectExec(typeName string, fields schema.FieldList, p
 
 var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
 var selectedType = reflect.TypeOf(pubquery.SelectedField{})
+var rawArgsType = reflect.TypeOf(pubquery.RawArgs(nil))
+var variablesType = reflect.TypeOf(pubquery.Variables(nil))
+var pathType = reflect.TypeOf(pubquery.Path(nil))
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var boolType = reflect.TypeOf(false)
+var parentPkgPath = reflect.TypeOf(pubquery.Parent[struct{}]{}).PkgPath()
 
 func (b *execBuilder) makeFieldExec(typeName string, f *schema.Field, m reflect.Method, methodIndex int, methodHasReceiver bool) (*Field, error) {
 	in := make([]reflect.Type, m.Type.NumIn())
@@ -277,13 +689,36 @@ func (b *execBuilder) makeFieldExec(typeName string, f *schema.Field, m reflect.
 			return nil, perrors.Errorf("must have parameter for field arguments")
 		}
 		var err error
+		b.mu.Lock()
 		argsPacker, err = b.packerBuilder.MakeStructPacker(f.Args, in[0])
+		b.mu.Unlock()
 		if err != nil {
 			return nil, err
 		}
 		in = in[1:]
 	}
 
+	hasRawArgs := len(in) > 0 && in[0] == rawArgsType
+	if hasRawArgs {
+		in = in[1:]
+	}
+
+	hasVars := len(in) > 0 && in[0] == variablesType
+	if hasVars {
+		in = in[1:]
+	}
+
+	var parentType reflect.Type
+	if len(in) > 0 && isParentType(in[0]) {
+		parentType = in[0]
+		in = in[1:]
+	}
+
+	hasPath := len(in) > 0 && in[0] == pathType
+	if hasPath {
+		in = in[1:]
+	}
+
 	hasSelected := len(in) > 0 && isSelectedFieldType(in[0])
 	if hasSelected {
 		in = in[1:]
@@ -304,26 +739,597 @@ func (b *execBuilder) makeFieldExec(typeName string, f *schema.Field, m reflect.
 		}
 	}
 
+	fe, err := b.newField(typeName, f)
+	if err != nil {
+		return nil, err
+	}
+
+	retry, ok := b.opts.RetryPolicies[typeName+"."+f.Name]
+	if !ok {
+		retry, err = fieldRetryPolicy(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hedge, ok := b.opts.HedgePolicies[typeName+"."+f.Name]
+	if !ok {
+		hedge, err = fieldHedgePolicy(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fe.MethodIndex = methodIndex
+	fe.HasContext = hasContext
+	fe.HasSelected = hasSelected
+	fe.HasRawArgs = hasRawArgs
+	fe.HasVars = hasVars
+	fe.ParentType = parentType
+	fe.HasPath = hasPath
+	fe.ArgsPacker = argsPacker
+	fe.HasError = hasError
+	fe.Retry = retry
+	fe.Hedge = hedge
+	if parentType != nil {
+		valueType := parentType.Field(0).Type
+		for types, project := range b.opts.ParentProjections {
+			if types[1] == valueType {
+				if fe.ParentProjections == nil {
+					fe.ParentProjections = make(map[reflect.Type]reflect.Value)
+				}
+				fe.ParentProjections[types[0]] = project
+			}
+		}
+	}
+	out := m.Type.Out(0)
+	if b.isRawJSON(out) && !isEntryPoint(b.schema, typeName, "subscription") {
+		fe.RawJSON = true
+		return fe, nil
+	}
+	if isEntryPoint(b.schema, typeName, "subscription") {
+		if out.Kind() != reflect.Chan || out.ChanDir()&reflect.RecvDir == 0 {
+			return nil, perrors.Errorf("must return a channel of the events of the subscription")
+		}
+		out = out.Elem()
+		if filter, ok := b.opts.EventFilters[typeName+"."+f.Name]; ok {
+			fv := reflect.ValueOf(filter)
+			t := fv.Type()
+			if t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != contextType || t.In(1) != out ||
+				t.NumOut() != 3 || t.Out(0) != out || t.Out(1).Kind() != reflect.Bool || t.Out(2) != errorType {
+				return nil, perrors.Errorf("event filter %s is not of type func(context.Context, %s) (%s, bool, error)", t, out, out)
+			}
+			fe.EventFilter = fv
+		}
+	}
+	if err := b.assignExec(&fe.ValueExec, f.Type, out); err != nil {
+		return nil, err
+	}
+	return fe, nil
+}
+
+// fieldTimeout reads the optional @timeout(ms: Int) schema directive of a field. The directive
+// has to be declared in the schema, e.g. "directive @timeout(ms: Int!) on FIELD_DEFINITION".
+func fieldTimeout(f *schema.Field) (time.Duration, error) {
+	d := f.Directives.Get("timeout")
+	if d == nil {
+		return 0, nil
+	}
+	lit, ok := d.Args.Get("ms")
+	if !ok {
+		return 0, perrors.Errorf(`directive @timeout requires argument "ms"`)
+	}
+	ms, ok := lit.Value(nil).(int32)
+	if !ok || ms <= 0 {
+		return 0, perrors.Errorf(`directive @timeout requires a positive value for "ms", got %s`, lit)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// fieldCost reads the optional @cost(weight: Int!) schema directive of a field, an estimate of how
+// expensive its resolver is. The weight defaults to 1.
+func fieldCost(f *schema.Field) (int, error) {
+	d := f.Directives.Get("cost")
+	if d == nil {
+		return 1, nil
+	}
+	lit, ok := d.Args.Get("weight")
+	if !ok {
+		return 0, perrors.Errorf(`directive @cost requires argument "weight"`)
+	}
+	weight, ok := lit.Value(nil).(int32)
+	if !ok || weight < 0 {
+		return 0, perrors.Errorf(`directive @cost requires a non-negative value for "weight", got %s`, lit)
+	}
+	return int(weight), nil
+}
+
+// fieldRetryPolicy reads the optional @retry(attempts: Int!, backoffMs: Int) schema directive of
+// a field. The delay between attempts starts at backoffMs and doubles with every retry.
+func fieldRetryPolicy(f *schema.Field) (*RetryPolicy, error) {
+	d := f.Directives.Get("retry")
+	if d == nil {
+		return nil, nil
+	}
+	lit, ok := d.Args.Get("attempts")
+	if !ok {
+		return nil, perrors.Errorf(`directive @retry requires argument "attempts"`)
+	}
+	attempts, ok := lit.Value(nil).(int32)
+	if !ok || attempts < 1 {
+		return nil, perrors.Errorf(`directive @retry requires a positive value for "attempts", got %s`, lit)
+	}
+	var backoff time.Duration
+	if lit, ok := d.Args.Get("backoffMs"); ok {
+		ms, ok := lit.Value(nil).(int32)
+		if !ok || ms < 0 {
+			return nil, perrors.Errorf(`directive @retry requires a non-negative value for "backoffMs", got %s`, lit)
+		}
+		backoff = time.Duration(ms) * time.Millisecond
+	}
+	return &RetryPolicy{
+		Attempts: int(attempts),
+		Backoff: func(retry int) time.Duration {
+			shift := uint(retry - 1)
+			if backoff != 0 && (shift > 62 || backoff > math.MaxInt64>>shift) {
+				return math.MaxInt64 // the doubling overflows, the context ends the wait
+			}
+			return backoff << shift
+		},
+	}, nil
+}
+
+// fieldHedgePolicy reads the optional @hedge(delayMs: Int!, attempts: Int) schema directive of a
+// field. Attempts defaults to 2.
+func fieldHedgePolicy(f *schema.Field) (*HedgePolicy, error) {
+	d := f.Directives.Get("hedge")
+	if d == nil {
+		return nil, nil
+	}
+	lit, ok := d.Args.Get("delayMs")
+	if !ok {
+		return nil, perrors.Errorf(`directive @hedge requires argument "delayMs"`)
+	}
+	ms, ok := lit.Value(nil).(int32)
+	if !ok || ms < 0 {
+		return nil, perrors.Errorf(`directive @hedge requires a non-negative value for "delayMs", got %s`, lit)
+	}
+	policy := &HedgePolicy{Attempts: 2, Delay: time.Duration(ms) * time.Millisecond}
+	if lit, ok := d.Args.Get("attempts"); ok {
+		attempts, ok := lit.Value(nil).(int32)
+		if !ok || attempts < 2 {
+			return nil, perrors.Errorf(`directive @hedge requires a value of at least 2 for "attempts", got %s`, lit)
+		}
+		policy.Attempts = int(attempts)
+	}
+	return policy, nil
+}
+
+// authRule returns the rule restricting the field of the type, from its auth policy or directives.
+// A field of an interface is resolved by the field of the interface or by the one of the object
+// type, depending on whether it is selected on the interface or in a fragment of the object type.
+// Either has the rules of both then, so that the way of selecting it does not matter. Rules with
+// different roles can not be combined.
+func (b *execBuilder) authRule(typeName, fieldName string) (*AuthRule, error) {
+	var rule *AuthRule
+	var ruleRef string
+	for _, ref := range RelatedFields(b.schema, typeName, fieldName) {
+		var r *AuthRule
+		if roles, ok := b.opts.AuthPolicies[ref]; ok {
+			r = &AuthRule{Roles: roles}
+		} else {
+			var err error
+			if r, err = fieldAuthRule(fieldByRef(b.schema, ref)); err != nil {
+				return nil, perrors.Wrapf(err, "field %s", ref)
+			}
+		}
+		switch {
+		case r == nil:
+		case rule == nil || len(rule.Roles) == 0:
+			rule, ruleRef = r, ref
+		case len(r.Roles) != 0 && !sameRoles(rule.Roles, r.Roles):
+			return nil, perrors.Errorf("the auth rules of %s and %s differ", ruleRef, ref)
+		}
+	}
+	return rule, nil
+}
+
+// RelatedFields returns the field of the type, given as "Type.field", followed by the same field of
+// the interfaces the type implements and of the object types implementing them, and so on.
+func RelatedFields(s *schema.Schema, typeName, fieldName string) []string {
+	refs := []string{typeName + "." + fieldName}
+	seen := map[string]bool{typeName: true}
+	for i := 0; i < len(refs); i++ {
+		var related []string
+		switch t := s.Types[refs[i][:strings.IndexByte(refs[i], '.')]].(type) {
+		case *schema.Object:
+			for _, intf := range t.Interfaces {
+				related = append(related, intf.Name)
+			}
+		case *schema.Interface:
+			for _, obj := range t.PossibleTypes {
+				related = append(related, obj.Name)
+			}
+		}
+		for _, name := range related {
+			if !seen[name] && fieldByRef(s, name+"."+fieldName) != nil {
+				seen[name] = true
+				refs = append(refs, name+"."+fieldName)
+			}
+		}
+	}
+	return refs
+}
+
+// fieldByRef returns the field given as "Type.field" of an object or interface type, nil if there
+// is none.
+func fieldByRef(s *schema.Schema, ref string) *schema.Field {
+	i := strings.IndexByte(ref, '.')
+	switch t := s.Types[ref[:i]].(type) {
+	case *schema.Object:
+		return t.Fields.Get(ref[i+1:])
+	case *schema.Interface:
+		return t.Fields.Get(ref[i+1:])
+	}
+	return nil
+}
+
+func sameRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, role := range a {
+		found := false
+		for _, other := range b {
+			found = found || role == other
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldAuthRule reads the optional @auth and @hasRole(role: [String!]!) schema directives of a
+// field. A single role may also be given as a string. @hasRole without valid roles is an error,
+// rather than a rule requiring only an authenticated principal.
+func fieldAuthRule(f *schema.Field) (*AuthRule, error) {
+	if d := f.Directives.Get("hasRole"); d != nil {
+		lit, ok := d.Args.Get("role")
+		if !ok {
+			return nil, perrors.Errorf(`directive @hasRole requires argument "role"`)
+		}
+		rule := &AuthRule{}
+		switch v := lit.Value(nil).(type) {
+		case string:
+			rule.Roles = []string{v}
+		case []interface{}:
+			for _, role := range v {
+				role, _ := role.(string) // empty if invalid
+				rule.Roles = append(rule.Roles, role)
+			}
+		}
+		for _, role := range rule.Roles {
+			if role == "" {
+				rule.Roles = nil
+				break
+			}
+		}
+		if len(rule.Roles) == 0 {
+			return nil, perrors.Errorf(`directive @hasRole requires non-empty roles for "role", got %s`, lit)
+		}
+		return rule, nil
+	}
+	if f.Directives.Get("auth") != nil {
+		return &AuthRule{}, nil
+	}
+	return nil, nil
+}
+
+// newField returns the exec of the field with the settings of its schema directives and options,
+// which all kinds of resolvers share. It is not resolved by a method yet.
+func (b *execBuilder) newField(typeName string, f *schema.Field) (*Field, error) {
+	timeout, err := fieldTimeout(f)
+	if err != nil {
+		return nil, err
+	}
+	cost, err := fieldCost(f)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := b.authRule(typeName, f.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	traceID := trace.NewFieldIdentifier(typeName, f.Name)
+	return &Field{
+		Field:       *f,
+		TypeName:    typeName,
+		MethodIndex: -1,
+		TraceLabel:  traceID.Label,
+		TraceID:     traceID,
+		Timeout:     timeout,
+		Auth:        auth,
+		Cost:        cost,
+	}, nil
+}
+
+// makeDelegatedField returns the exec of a field resolved by a delegate. The type of the field is
+// not bound to Go types, its value is written as returned by the delegate.
+func (b *execBuilder) makeDelegatedField(typeName string, f *schema.Field, d Delegate) (*Field, error) {
+	fe, err := b.newField(typeName, f)
+	if err != nil {
+		return nil, err
+	}
+	fe.HasContext = true
+	fe.Delegate = d
+	return fe, nil
+}
+
+// isRawJSON reports whether the resolver results of the type hold the JSON of their values, see
+// Options.RawJSON.
+func (b *execBuilder) isRawJSON(t reflect.Type) bool {
+	return b.opts.RawJSON != nil && t == b.opts.RawJSON
+}
+
+// placeholderType is the resolver type of the values of unbound fields, which are always null.
+var placeholderType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// makeUnboundField returns the exec of a field without a resolver, which is null with an error, see
+// Options.Lenient.
+func (b *execBuilder) makeUnboundField(typeName string, f *schema.Field, resolverType reflect.Type, bindErr error) (*Field, error) {
+	b.addUnbound(resolverType, bindErr)
+	traceID := trace.NewFieldIdentifier(typeName, f.Name)
 	fe := &Field{
 		Field:       *f,
 		TypeName:    typeName,
-		MethodIndex: methodIndex,
-		HasContext:  hasContext,
-		HasSelected: hasSelected,
-		ArgsPacker:  argsPacker,
-		HasError:    hasError,
-		TraceLabel:  fmt.Sprintf("GraphQL field: %s.%s", typeName, f.Name),
-	}
-	if err := b.assignExec(&fe.ValueExec, f.Type, m.Type.Out(0)); err != nil {
+		MethodIndex: -1,
+		Unbound:     true,
+		TraceLabel:  traceID.Label,
+		TraceID:     traceID,
+	}
+	if err := b.assignExec(&fe.ValueExec, f.Type, placeholderResolverType(f.Type)); err != nil {
 		return nil, err
 	}
 	return fe, nil
 }
 
+// placeholderResolverType returns the resolver type of the values of an unbound field of the type:
+// lists are slices, everything else an interface.
+func placeholderResolverType(t common.Type) reflect.Type {
+	switch t := t.(type) {
+	case *common.NonNull:
+		return placeholderResolverType(t.OfType)
+	case *common.List:
+		return reflect.SliceOf(placeholderResolverType(t.OfType))
+	default:
+		return placeholderType
+	}
+}
+
+// addUnbound records the binding error of a missing resolver, unless it is one of the fields below
+// an unbound field.
+func (b *execBuilder) addUnbound(resolverType reflect.Type, err error) {
+	if resolverType != placeholderType {
+		b.mu.Lock()
+		b.unbound = append(b.unbound, err.Error())
+		b.mu.Unlock()
+	}
+}
+
+// makeFuncField returns the exec of a field resolved by a FieldFunc.
+func (b *execBuilder) makeFuncField(typeName string, f *schema.Field, fn *FieldFunc) (*Field, error) {
+	fe, err := b.newField(typeName, f)
+	if err != nil {
+		return nil, err
+	}
+	fe.HasContext = true
+	fe.HasError = true
+	fe.Func = fn
+	if err := b.assignExec(&fe.ValueExec, f.Type, fn.ResultType); err != nil {
+		return nil, err
+	}
+	return fe, nil
+}
+
+// makeStructField returns the exec of a field whose value is read from a struct field, for types
+// of plain data without resolver methods. The value is written without calling a resolver.
+func (b *execBuilder) makeStructField(typeName string, f *schema.Field, sf reflect.StructField) (*Field, error) {
+	if len(f.Args) > 0 {
+		return nil, perrors.Errorf("a struct field can not take the arguments of field %q", f.Name)
+	}
+	fe, err := b.newField(typeName, f)
+	if err != nil {
+		return nil, err
+	}
+	fe.FieldIndex = sf.Index
+	if b.isRawJSON(sf.Type) {
+		fe.RawJSON = true
+		return fe, nil
+	}
+	if err := b.assignExec(&fe.ValueExec, f.Type, sf.Type); err != nil {
+		return nil, err
+	}
+	return fe, nil
+}
+
+// checkRootFieldRef checks that the field given as "Type.field" is a field of the query or
+// mutation type.
+func checkRootFieldRef(s *schema.Schema, ref string) error {
+	if err := checkFieldRef(s, ref); err != nil {
+		return err
+	}
+	typeName := ref[:strings.IndexByte(ref, '.')]
+	if !isEntryPoint(s, typeName, "query") && !isEntryPoint(s, typeName, "mutation") {
+		return perrors.Errorf("%q is not a field of the query or mutation type", ref)
+	}
+	return nil
+}
+
+func isEntryPoint(s *schema.Schema, typeName, operation string) bool {
+	t, ok := s.EntryPoints[operation]
+	return ok && t.TypeName() == typeName
+}
+
+func isPossibleType(possibleTypes []*schema.Object, name string) bool {
+	for _, t := range possibleTypes {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkObjectFieldRef checks that a field given as "Type.field" exists in the schema and is not a
+// field of an interface, whose resolvers are the ones of the object types implementing it.
+func checkObjectFieldRef(s *schema.Schema, ref string) error {
+	if err := checkFieldRef(s, ref); err != nil {
+		return err
+	}
+	if _, ok := s.Types[ref[:strings.IndexByte(ref, '.')]].(*schema.Interface); ok {
+		return perrors.Errorf("%q is a field of an interface, set it for the object types implementing it", ref)
+	}
+	return nil
+}
+
+// checkFieldRef checks that a field given as "Type.field" exists in the schema.
+func checkFieldRef(s *schema.Schema, ref string) error {
+	i := strings.IndexByte(ref, '.')
+	if i == -1 {
+		return perrors.Errorf(`invalid field %q, expected "Type.field"`, ref)
+	}
+	var fields schema.FieldList
+	switch t := s.Types[ref[:i]].(type) {
+	case *schema.Object:
+		fields = t.Fields
+	case *schema.Interface:
+		fields = t.Fields
+	default:
+		return perrors.Errorf("type %q not found", ref[:i])
+	}
+	if fields.Get(ref[i+1:]) == nil {
+		return perrors.Errorf("field %q not found", ref)
+	}
+	return nil
+}
+
+// Objects returns the objects reachable from the query and mutation types, one for each pair of a
+// type of the schema and the Go type resolving it.
+func (s *Schema) Objects() []*Object {
+	type objectKey struct {
+		name         string
+		resolverType reflect.Type
+	}
+	var objects []*Object
+	seen := make(map[Resolvable]bool)
+	seenObjects := make(map[objectKey]bool)
+	var walk func(r Resolvable)
+	walk = func(r Resolvable) {
+		if r == nil || seen[r] {
+			return
+		}
+		seen[r] = true
+		switch r := r.(type) {
+		case *Object:
+			// a nullable and a non-null type resolved by the same Go type are separate objects
+			if k := (objectKey{r.Name, r.ResolverType}); !seenObjects[k] {
+				seenObjects[k] = true
+				objects = append(objects, r)
+			}
+			for _, f := range r.Fields {
+				walk(f.ValueExec)
+			}
+			for _, a := range r.TypeAssertions {
+				walk(a.TypeExec)
+			}
+		case *List:
+			walk(r.Elem)
+		}
+	}
+	walk(s.Query)
+	walk(s.Mutation)
+	walk(s.Subscription)
+	return objects
+}
+
+// IteratorElem returns the type of the elements of an iterator type, a function taking a yield
+// function like iter.Seq[T] or iter.Seq2[T, error], whose error, if withErr, ends the iteration.
+// Lists may be resolved by iterators, so that the entries are resolved while they are iterated.
+func IteratorElem(t reflect.Type) (elem reflect.Type, withErr bool, ok bool) {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 || t.IsVariadic() {
+		return nil, false, false
+	}
+	yield := t.In(0)
+	if yield.Kind() != reflect.Func || yield.NumOut() != 1 || yield.Out(0) != boolType || yield.IsVariadic() {
+		return nil, false, false
+	}
+	switch {
+	case yield.NumIn() == 1:
+		return yield.In(0), false, true
+	case yield.NumIn() == 2 && yield.In(1) == errorType:
+		return yield.In(0), true, true
+	}
+	return nil, false, false
+}
+
+// IsIterator reports whether the type is an iterator type, see IteratorElem.
+func IsIterator(t reflect.Type) bool {
+	_, _, ok := IteratorElem(t)
+	return ok
+}
+
+// isParentType reports whether the type is an instance of query.Parent.
+func isParentType(in reflect.Type) bool {
+	return in.Kind() == reflect.Struct && in.PkgPath() == parentPkgPath && strings.HasPrefix(in.Name(), "Parent[")
+}
+
 func isSelectedFieldType(in reflect.Type) bool {
 	return in.Kind() == reflect.Slice && in.Elem() == selectedType
 }
 
+// HasMethod reports whether the resolver type has a method or struct field resolving the field.
+func HasMethod(t reflect.Type, fieldName string) bool {
+	if findMethod(t, fieldName) != -1 || findMethod(t, "Get"+fieldName) != -1 {
+		return true
+	}
+	_, ok := findStructField(t, fieldName)
+	return ok
+}
+
+// findStructField returns the exported field of the struct or pointer to struct type matching the
+// name like the names of methods, including fields of embedded structs.
+func findStructField(t reflect.Type, name string) (reflect.StructField, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	return t.FieldByNameFunc(func(fieldName string) bool {
+		return isExported(fieldName) && strings.EqualFold(stripUnderscore(name), stripUnderscore(fieldName))
+	})
+}
+
+func isExported(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+// expectedMethod describes where the method resolving the field with the name is looked for, for
+// the errors of missing methods.
+func expectedMethod(t reflect.Type, name string) string {
+	var method string
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			method += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	if t.Kind() == reflect.Interface {
+		return fmt.Sprintf(" (expected method %s in the interface)", method)
+	}
+	return fmt.Sprintf(" (expected method %s with receiver %s or %s, or an exported struct field)", method, t.Elem(), t)
+}
+
 func findMethod(t reflect.Type, name string) int {
 	for i := 0; i < t.NumMethod(); i++ {
 		if strings.EqualFold(stripUnderscore(name), stripUnderscore(t.Method(i).Name)) {
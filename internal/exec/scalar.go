@@ -0,0 +1,192 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/qdentity/graphql-go/errors"
+)
+
+// writeScalar writes the JSON encoding of a scalar value. The common Go types are written directly,
+// producing the same output as encoding/json. Other types, including the ones implementing
+// json.Marshaler, are encoded with json.Marshal. Unless escapeHTML is set, <, > and & are written as
+// they are, like by a json.Encoder with SetEscapeHTML(false).
+func writeScalar(out *bytes.Buffer, v interface{}, escapeHTML bool) {
+	var buf [64]byte
+	switch v := v.(type) {
+	case string:
+		if isPlainString(v, escapeHTML) {
+			out.WriteByte('"')
+			out.WriteString(v)
+			out.WriteByte('"')
+			return
+		}
+	case bool:
+		out.Write(strconv.AppendBool(buf[:0], v))
+		return
+	case int:
+		out.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+		return
+	case int8:
+		out.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+		return
+	case int16:
+		out.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+		return
+	case int32:
+		out.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+		return
+	case int64:
+		out.Write(strconv.AppendInt(buf[:0], v, 10))
+		return
+	case uint:
+		out.Write(strconv.AppendUint(buf[:0], uint64(v), 10))
+		return
+	case uint8:
+		out.Write(strconv.AppendUint(buf[:0], uint64(v), 10))
+		return
+	case uint16:
+		out.Write(strconv.AppendUint(buf[:0], uint64(v), 10))
+		return
+	case uint32:
+		out.Write(strconv.AppendUint(buf[:0], uint64(v), 10))
+		return
+	case uint64:
+		out.Write(strconv.AppendUint(buf[:0], v, 10))
+		return
+	case float64:
+		if b, ok := appendFloat(buf[:0], v, 64); ok {
+			out.Write(b)
+			return
+		}
+	case float32:
+		if b, ok := appendFloat(buf[:0], float64(v), 32); ok {
+			out.Write(b)
+			return
+		}
+	case time.Time:
+		if y := v.Year(); y >= 0 && y <= 9999 {
+			b := append(buf[:0], '"')
+			b = v.AppendFormat(b, time.RFC3339Nano)
+			out.Write(append(b, '"'))
+			return
+		}
+	}
+
+	if !escapeHTML {
+		enc := json.NewEncoder(out) // writes nothing if it fails
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			panic(errors.Errorf("could not marshal %v", v))
+		}
+		out.Truncate(out.Len() - 1) // the newline written by Encode
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(errors.Errorf("could not marshal %v", v))
+	}
+	out.Write(data)
+}
+
+// ContextMarshaler is implemented by scalar values whose encoding depends on the request, e.g. a
+// DateTime written in the time zone or a Money written in the currency of the user. It is passed the
+// context of the request and returns the JSON encoding of the value.
+type ContextMarshaler interface {
+	MarshalGraphQLContext(ctx context.Context) ([]byte, error)
+}
+
+// contextMarshaler returns the ContextMarshaler of the scalar value, which may be implemented by the
+// pointer type if the value is addressable. A nil pointer is written as null by writeScalar.
+func contextMarshaler(v reflect.Value) (ContextMarshaler, bool) {
+	if !v.IsValid() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil, false
+	}
+	if m, ok := v.Interface().(ContextMarshaler); ok {
+		return m, true
+	}
+	if v.CanAddr() {
+		m, ok := v.Addr().Interface().(ContextMarshaler)
+		return m, ok
+	}
+	return nil, false
+}
+
+// writeContextScalar writes the encoding returned by the ContextMarshaler, which has to be valid
+// JSON.
+func writeContextScalar(ctx context.Context, out *bytes.Buffer, m ContextMarshaler) error {
+	data, err := m.MarshalGraphQLContext(ctx)
+	if err != nil {
+		return err
+	}
+	return json.Compact(out, data)
+}
+
+// validScalarOutput reports whether the JSON encoding of a value is valid for the scalar type: a
+// 32-bit integer for Int, a number for Float, a string for String, true or false for Boolean, and a
+// string or an integer for ID. Other scalar types can have any value.
+func validScalarOutput(name string, data []byte) bool {
+	switch name {
+	case "Int":
+		_, err := strconv.ParseInt(string(data), 10, 32)
+		return err == nil
+	case "Float":
+		_, err := strconv.ParseFloat(string(data), 64)
+		return err == nil
+	case "String":
+		return len(data) != 0 && data[0] == '"'
+	case "Boolean":
+		return string(data) == "true" || string(data) == "false"
+	case "ID":
+		if len(data) != 0 && data[0] == '"' {
+			return true
+		}
+		_, err := strconv.ParseInt(string(data), 10, 64)
+		return err == nil
+	}
+	return true
+}
+
+// isPlainString reports whether the string is encoded by encoding/json without escapes, with or
+// without escaping HTML.
+func isPlainString(s string, escapeHTML bool) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= utf8.RuneSelf || c == '"' || c == '\\' {
+			return false
+		}
+		if escapeHTML && (c == '<' || c == '>' || c == '&') {
+			return false
+		}
+	}
+	return true
+}
+
+// appendFloat formats the float like encoding/json. It returns false for NaN and infinities, which
+// are not valid JSON.
+func appendFloat(b []byte, f float64, bits int) ([]byte, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return b, false
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, true
+}
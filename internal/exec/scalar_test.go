@@ -0,0 +1,87 @@
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteScalar(t *testing.T) {
+	values := []interface{}{
+		"", "hello", "quote \" and backslash \\", "<b>&</b>", "tab\t", "ünïcödé", " ", string([]byte{0xff}),
+		true, false,
+		0, -42, int8(-8), int16(16), int32(-32), int64(math.MaxInt64),
+		uint(7), uint8(8), uint16(16), uint32(32), uint64(math.MaxUint64),
+		0.0, 1.5, -2.25, 1e-7, 1e21, 123456789.125, math.SmallestNonzeroFloat64, math.MaxFloat64,
+		float32(0.1), float32(1e-7), float32(3e22),
+		time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC),
+		time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600)),
+		[]string{"a"}, map[string]int{"a": 1}, nil,
+	}
+	for _, v := range values {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		writeScalar(&out, v, true)
+		if got := out.String(); got != string(want) {
+			t.Errorf("got %s for %#v, want %s", got, v, want)
+		}
+
+		var unescaped bytes.Buffer
+		enc := json.NewEncoder(&unescaped)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		writeScalar(&out, v, false)
+		if got, want := out.String(), strings.TrimSuffix(unescaped.String(), "\n"); got != want {
+			t.Errorf("got %s for %#v without escaping HTML, want %s", got, v, want)
+		}
+	}
+}
+
+func TestValidScalarOutput(t *testing.T) {
+	for _, tt := range []struct {
+		scalar string
+		data   string
+		want   bool
+	}{
+		{"Int", `42`, true},
+		{"Int", `-2147483648`, true},
+		{"Int", `2147483648`, false},
+		{"Int", `1.5`, false},
+		{"Int", `"42"`, false},
+		{"Float", `1.5e-7`, true},
+		{"Float", `"1.5"`, false},
+		{"String", `"a"`, true},
+		{"String", `1`, false},
+		{"Boolean", `true`, true},
+		{"Boolean", `"true"`, false},
+		{"ID", `"a1"`, true},
+		{"ID", `9007199254740993`, true},
+		{"ID", `1.5`, false},
+		{"DateTime", `{"any":"value"}`, true},
+	} {
+		if got := validScalarOutput(tt.scalar, []byte(tt.data)); got != tt.want {
+			t.Errorf("got %t for %s as %s, want %t", got, tt.data, tt.scalar, tt.want)
+		}
+	}
+}
+
+func BenchmarkWriteScalar(b *testing.B) {
+	values := []interface{}{"hello world", 42, 3.14, true, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	var out bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		out.Reset()
+		for _, v := range values {
+			writeScalar(&out, v, true)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package selected
+
+import "sync"
+
+// blockSize is the number of selections of a type allocated at once by an arena.
+const blockSize = 64
+
+type (
+	fieldBlock     [blockSize]SchemaField
+	assertionBlock [blockSize]TypeAssertion
+	typenameBlock  [blockSize]TypenameField
+)
+
+var (
+	fieldBlocks     = sync.Pool{New: func() interface{} { return new(fieldBlock) }}
+	assertionBlocks = sync.Pool{New: func() interface{} { return new(assertionBlock) }}
+	typenameBlocks  = sync.Pool{New: func() interface{} { return new(typenameBlock) }}
+)
+
+// arena allocates the selections of a request in blocks taken from pools, so that large queries
+// need few allocations. The blocks are returned to the pools when the request is released.
+type arena struct {
+	fields     []*fieldBlock
+	assertions []*assertionBlock
+	typenames  []*typenameBlock
+	nFields    int
+	nAssert    int
+	nTypenames int
+}
+
+// UseArena makes the request allocate its selections from an arena. The selections must not be
+// used after Release is called. Selections cached by an OperationCache are never allocated from
+// the arena.
+func (r *Request) UseArena() {
+	r.arena = &arena{}
+}
+
+// Release returns the selections allocated by the request's arena to their pools.
+func (r *Request) Release() {
+	a := r.arena
+	if a == nil {
+		return
+	}
+	r.arena = nil
+	for _, b := range a.fields {
+		*b = fieldBlock{}
+		fieldBlocks.Put(b)
+	}
+	for _, b := range a.assertions {
+		*b = assertionBlock{}
+		assertionBlocks.Put(b)
+	}
+	for _, b := range a.typenames {
+		*b = typenameBlock{}
+		typenameBlocks.Put(b)
+	}
+}
+
+// pooled reports whether selections are allocated from the arena, which is not the case while
+// they are applied for an OperationCache.
+func (r *Request) pooled() bool {
+	return r.arena != nil && r.deps == nil
+}
+
+func (r *Request) newField() *SchemaField {
+	if !r.pooled() {
+		return new(SchemaField)
+	}
+	a := r.arena
+	i := a.nFields % blockSize
+	if i == 0 {
+		a.fields = append(a.fields, fieldBlocks.Get().(*fieldBlock))
+	}
+	a.nFields++
+	return &a.fields[len(a.fields)-1][i]
+}
+
+func (r *Request) newAssertion() *TypeAssertion {
+	if !r.pooled() {
+		return new(TypeAssertion)
+	}
+	a := r.arena
+	i := a.nAssert % blockSize
+	if i == 0 {
+		a.assertions = append(a.assertions, assertionBlocks.Get().(*assertionBlock))
+	}
+	a.nAssert++
+	return &a.assertions[len(a.assertions)-1][i]
+}
+
+func (r *Request) newTypename() *TypenameField {
+	if !r.pooled() {
+		return new(TypenameField)
+	}
+	a := r.arena
+	i := a.nTypenames % blockSize
+	if i == 0 {
+		a.typenames = append(a.typenames, typenameBlocks.Get().(*typenameBlock))
+	}
+	a.nTypenames++
+	return &a.typenames[len(a.typenames)-1][i]
+}
@@ -0,0 +1,187 @@
+package selected
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/query"
+)
+
+// maxVariants is the number of selections cached per operation, for different values of the
+// variables of @skip and @include.
+const maxVariants = 8
+
+// OperationCache caches the selections of operations, so that repeated executions of an operation
+// skip flattening its fragments and evaluating @skip and @include. The cached selections are never
+// modified, so they are shared by concurrent requests. It is safe for concurrent use.
+type OperationCache struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key      string
+	variants []*variant
+}
+
+// variant are the selections of an operation for the values of the variables of @skip and @include
+// and the visibility of the fields injected into them.
+type variant struct {
+	conditions map[string]bool
+	visible    map[fieldRef]bool
+	sels       []Selection
+}
+
+// dependencies record how the selections of an operation depend on the variables and the
+// visibility function of the request.
+type dependencies struct {
+	conditions  map[string]bool   // values of the variables of @skip and @include
+	visible     map[fieldRef]bool // visibility of the fields injected if visible, see injectable
+	uncacheable bool
+}
+
+type fieldRef struct {
+	typeName, fieldName string
+}
+
+// NewOperationCache returns a cache of the selections of up to size operations.
+func NewOperationCache(size int) *OperationCache {
+	return &OperationCache{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Apply is like ApplyOperation, but reuses the selections of an earlier call with the same key if
+// the variables of @skip and @include have the same values and the fields injected into the
+// selections are visible alike. The key identifies the operation, e.g.
+// by its source and name. Arguments referencing variables are packed again for each request.
+// Operations with introspection fields or errors are not cached.
+func (c *OperationCache) Apply(r *Request, s *resolvable.Schema, op *query.Operation, key string) []Selection {
+	if sels, ok := c.get(r, key); ok {
+		return rebind(r, sels)
+	}
+
+	r.deps = &dependencies{conditions: make(map[string]bool), visible: make(map[fieldRef]bool)}
+	sels := ApplyOperation(r, s, op)
+	deps := r.deps
+	r.deps = nil
+	if !deps.uncacheable && len(r.Errs) == 0 {
+		c.add(key, &variant{conditions: deps.conditions, visible: deps.visible, sels: sels})
+	}
+	return sels
+}
+
+func (c *OperationCache) get(r *Request, key string) ([]Selection, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	for _, v := range e.Value.(*cacheEntry).variants {
+		if v.matches(r) {
+			return v.sels, true
+		}
+	}
+	return nil, false
+}
+
+func (c *OperationCache) add(key string, v *variant) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*cacheEntry)
+		if len(entry.variants) == maxVariants {
+			entry.variants = entry.variants[1:]
+		}
+		entry.variants = append(entry.variants, v)
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, variants: []*variant{v}})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (v *variant) matches(r *Request) bool {
+	for name, want := range v.conditions {
+		value, ok := r.Vars[name].(bool)
+		if !ok || value != want {
+			return false
+		}
+	}
+	for f, want := range v.visible {
+		if visible := r.Visible == nil || r.Visible(f.typeName, f.fieldName); visible != want {
+			return false
+		}
+	}
+	return true
+}
+
+// rebind returns the cached selections with the arguments referencing variables packed with the
+// variables of the request. Selections without such arguments are shared.
+func rebind(r *Request, sels []Selection) []Selection {
+	if !hasDynamic(sels) {
+		return sels
+	}
+	out := make([]Selection, len(sels))
+	for i, sel := range sels {
+		switch sel := sel.(type) {
+		case *SchemaField:
+			if !sel.dynamic {
+				out[i] = sel
+				continue
+			}
+			f := r.newField()
+			*f = *sel
+			if f.varArgs != nil {
+				args, packedArgs, ok := packArgs(r, &f.Field, f.varArgs)
+				if !ok {
+					return out[:i]
+				}
+				f.Args, f.PackedArgs = args, packedArgs
+			}
+			f.Sels = rebind(r, sel.Sels)
+			out[i] = f
+
+		case *TypeAssertion:
+			if !sel.dynamic {
+				out[i] = sel
+				continue
+			}
+			a := r.newAssertion()
+			*a = *sel
+			a.Sels = rebind(r, sel.Sels)
+			out[i] = a
+
+		default:
+			out[i] = sel
+		}
+	}
+	return out
+}
+
+func hasDynamic(sels []Selection) bool {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *SchemaField:
+			if sel.dynamic {
+				return true
+			}
+		case *TypeAssertion:
+			if sel.dynamic {
+				return true
+			}
+		}
+	}
+	return false
+}
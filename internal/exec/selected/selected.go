@@ -1,6 +1,7 @@
 package selected
 
 import (
+	"fmt"
 	"reflect"
 	"sync"
 
@@ -15,11 +16,35 @@ import (
 )
 
 type Request struct {
-	Schema *schema.Schema
-	Doc    *query.Document
-	Vars   map[string]interface{}
-	Mu     sync.Mutex
-	Errs   []*errors.QueryError
+	Schema  *schema.Schema
+	Doc     *query.Document
+	Vars    map[string]interface{}
+	Visible func(typeName, fieldName string) bool
+	Mu      sync.Mutex
+	Errs    []*errors.QueryError
+
+	// InjectIdentities adds hidden selections of the id field and of __typename to the selection
+	// sets of objects, see graphql.InjectIdentities.
+	InjectIdentities bool
+
+	// IDAlias and TypenameAlias, if set, are the response names of the fields added by
+	// InjectIdentities, which are added then unless the selections use these names, see
+	// graphql.InjectedAliases.
+	IDAlias       string
+	TypenameAlias string
+
+	// InjectTypenames adds selections of __typename to the selection sets of interfaces and
+	// unions, see graphql.InjectTypenames.
+	InjectTypenames bool
+
+	// spreads are the names of the fragments currently being applied. ApplyOperation runs on a
+	// single goroutine, so they need no locking.
+	spreads []string
+
+	// deps, if set, records how the selections depend on the variables, see OperationCache.
+	deps *dependencies
+
+	arena *arena // see UseArena
 }
 
 func (r *Request) AddError(err *errors.QueryError) {
@@ -35,6 +60,8 @@ func ApplyOperation(r *Request, s *resolvable.Schema, op *query.Operation) []Sel
 		obj = s.Query.(*resolvable.Object)
 	case query.Mutation:
 		obj = s.Mutation.(*resolvable.Object)
+	case query.Subscription:
+		obj = s.Subscription.(*resolvable.Object)
 	}
 	return applySelectionSet(r, obj, op.Selections)
 }
@@ -51,16 +78,37 @@ type SchemaField struct {
 	Sels        []Selection
 	Async       bool
 	FixedResult reflect.Value
+
+	// QueryDirectives are the directives of the field in the query, as opposed to the ones of its
+	// definition in the schema.
+	QueryDirectives common.DirectiveList
+
+	// Delegated is the field in the query if it is resolved by a delegate, which gets sent the
+	// field with its arguments and selections.
+	Delegated *query.Field
+
+	// Hidden is set for a field selected by InjectIdentities, which is resolved but not returned.
+	Hidden bool
+
+	// RawSels are the selections of the field in the query if its resolver returns the value as
+	// JSON, see resolvable.Field.RawJSON. Sels is empty then.
+	RawSels []query.Selection
+
+	varArgs common.ArgumentList // the arguments if they depend on variables
+	dynamic bool                // varArgs is set for the field or one of its descendants
 }
 
 type TypeAssertion struct {
 	resolvable.TypeAssertion
 	Sels []Selection
+
+	dynamic bool // a descendant depends on variables
 }
 
 type TypenameField struct {
 	resolvable.Object
-	Alias string
+	Alias  string
+	Hidden bool // see SchemaField.Hidden
 }
 
 func (*SchemaField) isSelection()   {}
@@ -78,21 +126,31 @@ func applySelectionSet(r *Request, e *resolvable.Object, sels []query.Selection)
 
 			switch field.Name.Name {
 			case "__typename":
-				flattenedSels = append(flattenedSels, &TypenameField{
+				tf := r.newTypename()
+				*tf = TypenameField{
 					Object: *e,
 					Alias:  field.Alias.Name,
-				})
+				}
+				flattenedSels = append(flattenedSels, tf)
 
 			case "__schema":
-				flattenedSels = append(flattenedSels, &SchemaField{
+				if r.deps != nil {
+					r.deps.uncacheable = true // the introspection result is filtered per request
+				}
+				sf := r.newField()
+				*sf = SchemaField{
 					Field:       resolvable.MetaFieldSchema,
 					Alias:       field.Alias.Name,
 					Sels:        applySelectionSet(r, resolvable.MetaSchema, field.Selections),
 					Async:       true,
-					FixedResult: reflect.ValueOf(introspection.WrapSchema(r.Schema)),
-				})
+					FixedResult: reflect.ValueOf(introspection.WrapSchemaFiltered(r.Schema, r.Visible)),
+				}
+				flattenedSels = append(flattenedSels, sf)
 
 			case "__type":
+				if r.deps != nil {
+					r.deps.uncacheable = true
+				}
 				p := packer.ValuePacker{ValueType: reflect.TypeOf("")}
 				v, err := p.Pack(field.Arguments.MustGet("name").Value(r.Vars))
 				if err != nil {
@@ -102,48 +160,70 @@ func applySelectionSet(r *Request, e *resolvable.Object, sels []query.Selection)
 					return nil
 				}
 
+				var typ *introspection.Type
 				t, ok := r.Schema.Types[v.String()]
-				if !ok {
-					return nil
+				if ok && (r.Visible == nil || r.Visible(t.TypeName(), "")) {
+					typ = introspection.WrapTypeFiltered(t, r.Visible)
 				}
 
-				flattenedSels = append(flattenedSels, &SchemaField{
+				sf := r.newField()
+				*sf = SchemaField{
 					Field:       resolvable.MetaFieldType,
 					Alias:       field.Alias.Name,
 					Sels:        applySelectionSet(r, resolvable.MetaType, field.Selections),
 					Async:       true,
-					FixedResult: reflect.ValueOf(introspection.WrapType(t)),
-				})
+					FixedResult: reflect.ValueOf(typ),
+				}
+				flattenedSels = append(flattenedSels, sf)
 
 			default:
 				fe := e.Fields[field.Name.Name]
+				if fe.Delegate != nil {
+					sf := r.newField()
+					*sf = SchemaField{
+						Field:           *fe,
+						Alias:           field.Alias.Name,
+						Async:           true,
+						QueryDirectives: field.Directives,
+						Delegated:       field,
+					}
+					flattenedSels = append(flattenedSels, sf)
+					continue
+				}
 
-				var args map[string]interface{}
-				var packedArgs reflect.Value
-				if fe.ArgsPacker != nil {
-					args = make(map[string]interface{})
+				args, packedArgs, ok := packArgs(r, fe, field.Arguments)
+				if !ok {
+					return
+				}
+
+				var fieldSels []Selection
+				var rawSels []query.Selection
+				if fe.RawJSON {
+					rawSels = field.Selections
+				} else {
+					fieldSels = applyField(r, fe.ValueExec, field.Selections)
+				}
+				sf := r.newField()
+				*sf = SchemaField{
+					Field:           *fe,
+					Alias:           field.Alias.Name,
+					Args:            args,
+					PackedArgs:      packedArgs,
+					Sels:            fieldSels,
+					Async:           fe.HasContext || fe.ArgsPacker != nil || fe.HasError || HasAsyncSel(fieldSels),
+					QueryDirectives: field.Directives,
+					RawSels:         rawSels,
+				}
+				if r.deps != nil {
 					for _, arg := range field.Arguments {
-						args[arg.Name.Name] = arg.Value.Value(r.Vars)
-					}
-					var err error
-					packedArgs, err = fe.ArgsPacker.Pack(args)
-					if err != nil {
-						qErr := errors.Errorf("%s", err)
-						qErr.OriginalError = err
-						r.AddError(qErr)
-						return
+						if common.HasVariables(arg.Value) {
+							sf.varArgs = field.Arguments
+							break
+						}
 					}
+					sf.dynamic = sf.varArgs != nil || hasDynamic(fieldSels)
 				}
-
-				fieldSels := applyField(r, fe.ValueExec, field.Selections)
-				flattenedSels = append(flattenedSels, &SchemaField{
-					Field:      *fe,
-					Alias:      field.Alias.Name,
-					Args:       args,
-					PackedArgs: packedArgs,
-					Sels:       fieldSels,
-					Async:      fe.HasContext || fe.ArgsPacker != nil || fe.HasError || HasAsyncSel(fieldSels),
-				})
+				flattenedSels = append(flattenedSels, sf)
 			}
 
 		case *query.InlineFragment:
@@ -158,7 +238,26 @@ func applySelectionSet(r *Request, e *resolvable.Object, sels []query.Selection)
 			if skipByDirective(r, spread.Directives) {
 				continue
 			}
-			flattenedSels = append(flattenedSels, applyFragment(r, e, &r.Doc.Fragments.Get(spread.Name.Name).Fragment)...)
+			frag := r.Doc.Fragments.Get(spread.Name.Name)
+			if frag == nil {
+				r.AddError(&errors.QueryError{
+					Message:   fmt.Sprintf("Unknown fragment %q.", spread.Name.Name),
+					Locations: []errors.Location{spread.Name.Loc},
+					Rule:      "KnownFragmentNames",
+				})
+				continue
+			}
+			if r.spreading(frag.Name.Name) {
+				r.AddError(&errors.QueryError{
+					Message:   fmt.Sprintf("Cannot spread fragment %q within itself.", frag.Name.Name),
+					Locations: []errors.Location{spread.Loc},
+					Rule:      "NoFragmentCycles",
+				})
+				continue
+			}
+			r.spreads = append(r.spreads, frag.Name.Name)
+			flattenedSels = append(flattenedSels, applyFragment(r, e, &frag.Fragment)...)
+			r.spreads = r.spreads[:len(r.spreads)-1]
 
 		default:
 			panic("invalid type")
@@ -167,6 +266,17 @@ func applySelectionSet(r *Request, e *resolvable.Object, sels []query.Selection)
 	return
 }
 
+// spreading reports whether the fragment is already being applied, i.e. spreading it again would
+// never terminate. Validation rejects such documents, this guards documents that skipped it.
+func (r *Request) spreading(name string) bool {
+	for _, n := range r.spreads {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
 func applyFragment(r *Request, e *resolvable.Object, frag *query.Fragment) []Selection {
 	if frag.On.Name != "" && frag.On.Name != e.Name {
 		a, ok := e.TypeAssertions[frag.On.Name]
@@ -174,18 +284,61 @@ func applyFragment(r *Request, e *resolvable.Object, frag *query.Fragment) []Sel
 			panic(perrors.Errorf("%q does not implement %q", frag.On, e.Name)) // TODO proper error handling
 		}
 
-		return []Selection{&TypeAssertion{
+		sels := applySelectionSet(r, a.TypeExec.(*resolvable.Object), frag.Selections)
+		ta := r.newAssertion()
+		*ta = TypeAssertion{
 			TypeAssertion: *a,
-			Sels:          applySelectionSet(r, a.TypeExec.(*resolvable.Object), frag.Selections),
-		}}
+			Sels:          sels,
+			dynamic:       r.deps != nil && hasDynamic(sels),
+		}
+		return []Selection{ta}
 	}
 	return applySelectionSet(r, e, frag.Selections)
 }
 
+// packArgs packs the arguments of the field. It returns false after adding an error to the request
+// if they are invalid.
+func packArgs(r *Request, fe *resolvable.Field, arguments common.ArgumentList) (map[string]interface{}, reflect.Value, bool) {
+	if fe.ArgsPacker == nil && fe.Func == nil {
+		return nil, reflect.Value{}, true
+	}
+	args := make(map[string]interface{})
+	for _, arg := range arguments {
+		if common.IsMissingVariable(arg.Value, r.Vars) {
+			continue // an omitted variable leaves the argument unset, so its default value applies
+		}
+		args[arg.Name.Name] = arg.Value.Value(r.Vars)
+	}
+	if fe.Func != nil {
+		// field funcs get the arguments as they are, with the default values of the schema
+		for _, decl := range fe.Args {
+			if _, ok := args[decl.Name.Name]; !ok && decl.Default != nil {
+				args[decl.Name.Name] = decl.Default.Value(nil)
+			}
+		}
+		return args, reflect.Value{}, true
+	}
+	packedArgs, err := fe.ArgsPacker.Pack(args)
+	if err != nil {
+		qErr := errors.Errorf("%s", err).WithCode(errors.CodeBadUserInput)
+		qErr.OriginalError = err
+		r.AddError(qErr)
+		return nil, reflect.Value{}, false
+	}
+	return args, packedArgs, true
+}
+
 func applyField(r *Request, e resolvable.Resolvable, sels []query.Selection) []Selection {
 	switch e := e.(type) {
 	case *resolvable.Object:
-		return applySelectionSet(r, e, sels)
+		flattened := applySelectionSet(r, e, sels)
+		if r.InjectTypenames && len(e.TypeAssertions) != 0 {
+			flattened = injectTypename(r, e, flattened)
+		}
+		if r.InjectIdentities {
+			flattened = injectIdentities(r, e, flattened)
+		}
+		return flattened
 	case *resolvable.List:
 		return applyField(r, e.Elem, sels)
 	case *resolvable.Scalar:
@@ -195,25 +348,111 @@ func applyField(r *Request, e resolvable.Resolvable, sels []query.Selection) []S
 	}
 }
 
+// injectIdentities adds hidden selections of the object's id field and, if it is abstract, of
+// __typename, unless the selections already have a field with their response names. A field of a
+// fragment with the same response name is merged with the injected one when the object is resolved.
+func injectIdentities(r *Request, e *resolvable.Object, sels []Selection) []Selection {
+	idAlias, typenameAlias := "id", "__typename"
+	if r.IDAlias != "" {
+		idAlias = r.IDAlias
+	}
+	if r.TypenameAlias != "" {
+		typenameAlias = r.TypenameAlias
+	}
+	aliases := make(map[string]bool)
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *SchemaField:
+			aliases[sel.Alias] = true
+		case *TypenameField:
+			aliases[sel.Alias] = true
+		}
+	}
+	if fe, ok := e.Fields["id"]; ok && !aliases[idAlias] && injectable(r, fe) {
+		sf := r.newField()
+		*sf = SchemaField{
+			Field:  *fe,
+			Alias:  idAlias,
+			Async:  fe.HasContext || fe.ArgsPacker != nil || fe.HasError,
+			Hidden: true,
+		}
+		if fe.Func != nil {
+			sf.Args = make(map[string]interface{})
+		}
+		sels = append(sels, sf)
+	}
+	if len(e.TypeAssertions) != 0 && !aliases[typenameAlias] {
+		tf := r.newTypename()
+		*tf = TypenameField{
+			Object: *e,
+			Alias:  typenameAlias,
+			Hidden: true,
+		}
+		sels = append(sels, tf)
+	}
+	return sels
+}
+
+// injectTypename adds a selection of __typename to the selections of an abstract type, unless they
+// select it already. A selection of __typename in a fragment is merged with the injected one when
+// the object is resolved.
+func injectTypename(r *Request, e *resolvable.Object, sels []Selection) []Selection {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *SchemaField:
+			if sel.Alias == "__typename" {
+				return sels
+			}
+		case *TypenameField:
+			if sel.Alias == "__typename" {
+				return sels
+			}
+		}
+	}
+	tf := r.newTypename()
+	*tf = TypenameField{
+		Object: *e,
+		Alias:  "__typename",
+	}
+	return append(sels, tf)
+}
+
+// injectable reports whether the id field can be selected without the query asking for it.
+func injectable(r *Request, fe *resolvable.Field) bool {
+	if len(fe.Args) != 0 || fe.Auth != nil || fe.Delegate != nil || fe.Unbound {
+		return false
+	}
+	if _, ok := fe.ValueExec.(*resolvable.Scalar); !ok {
+		return false
+	}
+	visible := r.Visible == nil || r.Visible(fe.TypeName, fe.Name)
+	if r.deps != nil && r.Visible != nil {
+		r.deps.visible[fieldRef{fe.TypeName, fe.Name}] = visible
+	}
+	return visible
+}
+
 func skipByDirective(r *Request, directives common.DirectiveList) bool {
 	if d := directives.Get("skip"); d != nil {
-		p := packer.ValuePacker{ValueType: reflect.TypeOf(false)}
-		v, err := p.Pack(d.Args.MustGet("if").Value(r.Vars))
+		v, err := r.condition(d.Args.MustGet("if"))
 		if err != nil {
-			r.AddError(errors.Errorf("%s", err))
+			qErr := errors.Errorf("%s", err)
+			qErr.OriginalError = err
+			r.AddError(qErr)
 		}
-		if err == nil && v.Bool() {
+		if err == nil && v {
 			return true
 		}
 	}
 
 	if d := directives.Get("include"); d != nil {
-		p := packer.ValuePacker{ValueType: reflect.TypeOf(false)}
-		v, err := p.Pack(d.Args.MustGet("if").Value(r.Vars))
+		v, err := r.condition(d.Args.MustGet("if"))
 		if err != nil {
-			r.AddError(errors.Errorf("%s", err))
+			qErr := errors.Errorf("%s", err)
+			qErr.OriginalError = err
+			r.AddError(qErr)
 		}
-		if err == nil && !v.Bool() {
+		if err == nil && !v {
 			return true
 		}
 	}
@@ -221,6 +460,23 @@ func skipByDirective(r *Request, directives common.DirectiveList) bool {
 	return false
 }
 
+// condition evaluates the "if" argument of @skip or @include.
+func (r *Request) condition(lit common.Literal) (bool, error) {
+	p := packer.ValuePacker{ValueType: reflect.TypeOf(false)}
+	v, err := p.Pack(lit.Value(r.Vars))
+	if r.deps != nil {
+		if variable, ok := lit.(*common.Variable); ok && err == nil {
+			r.deps.conditions[variable.Name] = v.Bool()
+		} else if err != nil {
+			r.deps.uncacheable = true
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return v.Bool(), nil
+}
+
 func HasAsyncSel(sels []Selection) bool {
 	for _, sel := range sels {
 		switch sel := sel.(type) {
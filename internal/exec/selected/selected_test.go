@@ -0,0 +1,201 @@
+package selected_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/exec/selected"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+type rootResolver struct{}
+
+func (r *rootResolver) Hero() *heroResolver {
+	return &heroResolver{}
+}
+
+type heroResolver struct{}
+
+func (r *heroResolver) Name() string {
+	return "R2-D2"
+}
+
+func (r *heroResolver) Friends() []*heroResolver {
+	return nil
+}
+
+// TestApplyOperationFragments checks that documents which did not pass validation produce errors
+// instead of panicking or recursing forever.
+func TestApplyOperationFragments(t *testing.T) {
+	s := schema.New()
+	if err := s.Parse(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hero: Hero!
+		}
+
+		type Hero {
+			name: String!
+			friends: [Hero!]!
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+	res, err := resolvable.ApplyResolver(s, &rootResolver{}, resolvable.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []*errors.QueryError
+	}{
+		{
+			name: "self-referential fragment",
+			query: `
+				{ hero { ...Friends } }
+				fragment Friends on Hero { name friends { ...Friends } }
+			`,
+			want: []*errors.QueryError{{
+				Message:   `Cannot spread fragment "Friends" within itself.`,
+				Locations: []errors.Location{{Line: 3, Column: 47}},
+				Rule:      "NoFragmentCycles",
+			}},
+		},
+		{
+			name: "fragment cycle",
+			query: `
+				{ hero { ...A } }
+				fragment A on Hero { name ...B }
+				fragment B on Hero { friends { ...A } }
+			`,
+			want: []*errors.QueryError{{
+				Message:   `Cannot spread fragment "A" within itself.`,
+				Locations: []errors.Location{{Line: 4, Column: 36}},
+				Rule:      "NoFragmentCycles",
+			}},
+		},
+		{
+			name:  "unknown fragment",
+			query: `{ hero { ...Unknown } }`,
+			want: []*errors.QueryError{{
+				Message:   `Unknown fragment "Unknown".`,
+				Locations: []errors.Location{{Line: 1, Column: 13}},
+				Rule:      "KnownFragmentNames",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, qErr := query.Parse(tt.query)
+			if qErr != nil {
+				t.Fatal(qErr)
+			}
+			r := &selected.Request{Schema: s, Doc: doc}
+			selected.ApplyOperation(r, res, doc.Operations[0])
+			if !reflect.DeepEqual(r.Errs, tt.want) {
+				for _, err := range r.Errs {
+					t.Logf("got error %+v", err)
+				}
+				t.Errorf("want errors %+v", tt.want)
+			}
+		})
+	}
+}
+
+func TestOperationCache(t *testing.T) {
+	s := schema.New()
+	if err := s.Parse(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hero: Hero!
+		}
+
+		type Hero {
+			name: String!
+			friends: [Hero!]!
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+	res, err := resolvable.ApplyResolver(s, &rootResolver{}, resolvable.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, qErr := query.Parse(`query($withFriends: Boolean!) { hero { name friends @include(if: $withFriends) { name } } }`)
+	if qErr != nil {
+		t.Fatal(qErr)
+	}
+
+	c := selected.NewOperationCache(1)
+	apply := func(withFriends bool) []selected.Selection {
+		r := &selected.Request{Schema: s, Doc: doc, Vars: map[string]interface{}{"withFriends": withFriends}}
+		sels := c.Apply(r, res, doc.Operations[0], "key")
+		if len(r.Errs) != 0 {
+			t.Fatal(r.Errs)
+		}
+		return sels
+	}
+
+	withFriends := apply(true)
+	withoutFriends := apply(false)
+	if got := len(withoutFriends[0].(*selected.SchemaField).Sels); got != 1 {
+		t.Errorf("got %d fields of hero without friends, want 1", got)
+	}
+	if got := apply(true); got[0] != withFriends[0] {
+		t.Error("selections with friends were not reused")
+	}
+	if got := apply(false); got[0] != withoutFriends[0] {
+		t.Error("selections without friends were not reused")
+	}
+}
+
+func TestArena(t *testing.T) {
+	s := schema.New()
+	if err := s.Parse(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			hero: Hero!
+		}
+
+		type Hero {
+			name: String!
+			friends: [Hero!]!
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+	res, err := resolvable.ApplyResolver(s, &rootResolver{}, resolvable.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, qErr := query.Parse(`{ hero { __typename name friends { name ... on Hero { friends { name } } } } }`)
+	if qErr != nil {
+		t.Fatal(qErr)
+	}
+
+	want := selected.ApplyOperation(&selected.Request{Schema: s, Doc: doc}, res, doc.Operations[0])
+	for i := 0; i < 2; i++ {
+		r := &selected.Request{Schema: s, Doc: doc}
+		r.UseArena()
+		if got := selected.ApplyOperation(r, res, doc.Operations[0]); !reflect.DeepEqual(got, want) {
+			t.Errorf("got selections %+v from the arena, want %+v", got, want)
+		}
+		r.Release()
+		r.Release()
+	}
+}
@@ -0,0 +1,173 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/exec/selected"
+	"github.com/qdentity/graphql-go/internal/query"
+)
+
+// Response is the result of executing the selections of a subscription for one of its events.
+type Response struct {
+	Data     json.RawMessage
+	Errors   []*errors.QueryError
+	Warnings []*errors.QueryError // see Request.Warnings
+}
+
+// Subscribe calls the resolver method of the subscription's root field and returns the responses
+// to the events of the channel it returns. The responses are closed when that channel is closed or
+// ctx is done. If the subscription can not be started, the only response has its errors.
+func (r *Request) Subscribe(ctx context.Context, s *resolvable.Schema, op *query.Operation) <-chan *Response {
+	r.op = op
+	r.scalarTypes = s.ScalarTypes
+	var f *fieldToExec
+	var events reflect.Value
+	func() {
+		defer r.handlePanic(ctx, nil)
+		sels := selected.ApplyOperation(&r.Request, s, op)
+		if len(r.Errs) != 0 {
+			return
+		}
+		resolver, err := r.rootResolver(ctx, s, sels)
+		if err != nil {
+			r.AddError(err)
+			return
+		}
+		var fields []*fieldToExec
+		collectFieldsToResolve(sels, addressable(resolver), &fields, make(map[string]*fieldToExec))
+		if len(fields) != 1 || fields[0].field.FixedResult.IsValid() {
+			r.AddError(errors.Errorf("a subscription must select a single field of the subscription type"))
+			return
+		}
+		f = fields[0]
+		events, err = r.subscribeField(ctx, f)
+		if err != nil {
+			r.AddError(err)
+		}
+	}()
+
+	responses := make(chan *Response, 1)
+	if len(r.Errs) != 0 {
+		responses <- &Response{Errors: r.Errs}
+		close(responses)
+		return responses
+	}
+
+	go func() {
+		defer close(responses)
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: events},
+		}
+		for {
+			chosen, event, ok := reflect.Select(cases)
+			if chosen == 0 || !ok {
+				return
+			}
+			resp := r.execEvent(ctx, f, event)
+			if resp == nil {
+				continue // filtered out
+			}
+			select {
+			case responses <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return responses
+}
+
+// subscribeField calls the resolver method of the subscription field, which returns the channel of
+// its events.
+func (r *Request) subscribeField(ctx context.Context, f *fieldToExec) (reflect.Value, *errors.QueryError) {
+	path := r.fieldPath(nil, f.field)
+	if f.field.Auth != nil {
+		if err := r.authorize(ctx, f.field); err != nil {
+			err.Path = path.toSlice()
+			return reflect.Value{}, err
+		}
+	}
+
+	if f.field.Unbound {
+		err := errors.Errorf("field %s.%s has no resolver", f.field.TypeName, f.field.Name)
+		err.Path = path.toSlice()
+		err.Extensions = map[string]interface{}{"code": "UNBOUND_FIELD"}
+		return reflect.Value{}, err
+	}
+
+	in, err := r.resolverIn(ctx, f, path)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	callOut := f.resolver.Method(f.field.MethodIndex).Call(in)
+	if f.field.HasError && !callOut[1].IsNil() {
+		resolverErr := callOut[1].Interface().(error)
+		err := errors.Errorf("%s", resolverErr)
+		err.Path = path.toSlice()
+		err.OriginalError = resolverErr
+		return reflect.Value{}, err
+	}
+	if callOut[0].IsNil() {
+		err := errors.Errorf("got nil channel for subscription field %q", f.field.Name)
+		err.Path = path.toSlice()
+		return reflect.Value{}, err
+	}
+	return callOut[0], nil
+}
+
+// execEvent executes the selections of the subscription field for the event. It returns nil if the
+// event filter of the field dropped the event.
+func (r *Request) execEvent(ctx context.Context, f *fieldToExec, event reflect.Value) *Response {
+	r.loaders, ctx = newLoaders(ctx) // the loaders of an event do not serve the values of the previous ones
+	r.loaders.enter(1)
+	defer r.loaders.finish()
+
+	r.Mu.Lock()
+	r.Errs = nil // the events are executed one after the other
+	r.Warnings = nil
+	r.Mu.Unlock()
+
+	path := r.fieldPath(nil, f.field)
+	var out bytes.Buffer
+	out.WriteString(`{"` + f.field.Alias + `":`)
+	dropped := false
+	ok := func() bool {
+		defer r.handlePanic(ctx, path)
+		if f.field.EventFilter.IsValid() {
+			filterOut := f.field.EventFilter.Call([]reflect.Value{reflect.ValueOf(ctx), event})
+			if !filterOut[2].IsNil() {
+				filterErr := filterOut[2].Interface().(error)
+				err := errors.Errorf("%s", filterErr)
+				err.Path = path.toSlice()
+				err.OriginalError = filterErr
+				r.AddError(err)
+				if _, nonNull := f.field.Type.(*common.NonNull); nonNull {
+					return false
+				}
+				out.WriteString("null")
+				return true
+			}
+			if !filterOut[1].Bool() {
+				dropped = true
+				return true
+			}
+			event = filterOut[0]
+		}
+		return r.execSelectionSet(ctx, f.sels, f.field.Type, path, event, &out)
+	}()
+	if dropped {
+		return nil
+	}
+	if !ok {
+		return &Response{Data: []byte("null"), Errors: r.Errs, Warnings: r.Warnings} // a non-null field is null, or it panicked
+	}
+	out.WriteByte('}')
+	return &Response{Data: out.Bytes(), Errors: r.Errs, Warnings: r.Warnings}
+}
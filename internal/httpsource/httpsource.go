@@ -0,0 +1,462 @@
+// Package httpsource resolves root fields with REST endpoints given by the @http schema directive:
+//
+//	directive @http(url: String!, method: String = "GET", body: String) on FIELD_DEFINITION
+//
+// Placeholders like {id} in the URL are replaced with the escaped values of the arguments, in the
+// body with their JSON encoding. The values "." and ".." are not allowed in the path. Without a body, POST, PUT and PATCH requests send the arguments as
+// a JSON object. The JSON response is mapped onto the type of the field, looking up the fields of
+// objects by their name or its snake case form.
+package httpsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+	pubquery "github.com/qdentity/graphql-go/query"
+)
+
+var placeholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// endpoint is the REST endpoint resolving a field.
+type endpoint struct {
+	client *http.Client
+	ref    string // the field, e.g. "Query.user", named in errors instead of the URL
+	field  *schema.Field
+	url    string
+	method string
+	body   *string
+}
+
+// Delegates returns the delegates resolving the root fields with an @http directive, keyed by
+// "Query.field" or "Mutation.field". The directive is an error on other fields.
+func Delegates(s *schema.Schema, client *http.Client) (map[string]resolvable.Delegate, error) {
+	delegates := make(map[string]resolvable.Delegate)
+	for _, t := range s.Types {
+		obj, ok := t.(*schema.Object)
+		if !ok {
+			continue
+		}
+		root := obj == s.EntryPoints["query"] || obj == s.EntryPoints["mutation"]
+		for _, f := range obj.Fields {
+			d := f.Directives.Get("http")
+			if d == nil {
+				continue
+			}
+			ref := obj.Name + "." + f.Name
+			if !root {
+				return nil, perrors.Errorf("directive @http of %q: only fields of the query or mutation type can be resolved by endpoints", ref)
+			}
+			e, err := newEndpoint(client, ref, f, d)
+			if err != nil {
+				return nil, perrors.Wrapf(err, "directive @http of %q", ref)
+			}
+			delegates[ref] = e.resolve
+		}
+	}
+	return delegates, nil
+}
+
+func newEndpoint(client *http.Client, ref string, f *schema.Field, d *common.Directive) (*endpoint, error) {
+	e := &endpoint{client: client, ref: ref, field: f, method: http.MethodGet}
+	lit, ok := d.Args.Get("url")
+	if !ok {
+		return nil, perrors.New(`requires argument "url"`)
+	}
+	if e.url, ok = lit.Value(nil).(string); !ok || e.url == "" {
+		return nil, perrors.Errorf(`requires a URL for "url", got %s`, lit)
+	}
+	// the schema adds the arguments omitted in the directive with their default values
+	if lit, ok := d.Args.Get("method"); ok && lit != nil {
+		method, _ := lit.Value(nil).(string)
+		switch method = strings.ToUpper(method); method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			e.method = method
+		default:
+			return nil, perrors.Errorf(`unsupported "method" %s`, lit)
+		}
+	}
+	if lit, ok := d.Args.Get("body"); ok && lit != nil {
+		body, ok := lit.Value(nil).(string)
+		if !ok {
+			return nil, perrors.Errorf(`requires a string for "body", got %s`, lit)
+		}
+		e.body = &body
+	}
+
+	templates := []string{e.url}
+	if e.body != nil {
+		templates = append(templates, *e.body)
+	}
+	for _, template := range templates {
+		for _, m := range placeholder.FindAllStringSubmatch(template, -1) {
+			if f.Args.Get(m[1]) == nil {
+				return nil, perrors.Errorf("placeholder %s is not an argument of the field", m[0])
+			}
+		}
+	}
+	return e, nil
+}
+
+// resolve is the delegate of the field. The query it gets selects only the field.
+func (e *endpoint) resolve(ctx context.Context, queryString string, variables map[string]interface{}) (json.RawMessage, []*errors.QueryError) {
+	doc, qErr := query.Parse(queryString)
+	if qErr != nil {
+		return nil, []*errors.QueryError{qErr}
+	}
+	field := doc.Operations[0].Selections[0].(*query.Field)
+
+	args := make(map[string]interface{}, len(e.field.Args))
+	for _, decl := range e.field.Args {
+		if lit, ok := field.Arguments.Get(decl.Name.Name); ok && !common.IsMissingVariable(lit, variables) {
+			args[decl.Name.Name] = lit.Value(variables)
+		} else if decl.Default != nil {
+			args[decl.Name.Name] = decl.Default.Value(nil)
+		}
+	}
+
+	value, err := e.fetch(ctx, args)
+	if err != nil {
+		return nil, []*errors.QueryError{err}
+	}
+
+	p := &projector{doc: doc, vars: variables}
+	var out bytes.Buffer
+	out.WriteString("{")
+	out.WriteString(quote(field.Alias.Name))
+	out.WriteString(":")
+	if err := p.write(&out, e.field.Type, value, field.Selections); err != nil {
+		return nil, []*errors.QueryError{errors.Errorf("invalid response of the endpoint of %s: %s", e.ref, err)}
+	}
+	out.WriteString("}")
+	return out.Bytes(), nil
+}
+
+// fetch calls the endpoint with the arguments and decodes the JSON of the response.
+func (e *endpoint) fetch(ctx context.Context, args map[string]interface{}) (interface{}, *errors.QueryError) {
+	u, qErr := e.expandURL(args)
+	if qErr != nil {
+		return nil, qErr
+	}
+
+	var body io.Reader
+	switch {
+	case e.body != nil:
+		b := placeholder.ReplaceAllStringFunc(*e.body, func(m string) string {
+			data, _ := json.Marshal(args[m[1:len(m)-1]])
+			return string(data)
+		})
+		body = strings.NewReader(b)
+	case e.method == http.MethodPost || e.method == http.MethodPut || e.method == http.MethodPatch:
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, errors.Errorf("encoding arguments: %s", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(e.method, u, body)
+	if err != nil {
+		return nil, errors.Errorf("%s", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		qErr := errors.Errorf("endpoint of %s: request failed", e.ref)
+		qErr.Extensions = map[string]interface{}{"code": "HTTP_ERROR"}
+		qErr.OriginalError = err
+		return nil, qErr
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && e.method == http.MethodGet {
+		return nil, nil // a missing resource is null
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		qErr := errors.Errorf("endpoint of %s: %s", e.ref, resp.Status)
+		qErr.Extensions = map[string]interface{}{"code": "HTTP_ERROR", "status": resp.StatusCode}
+		return nil, qErr
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	var value interface{}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, errors.Errorf("endpoint of %s: invalid JSON response: %s", e.ref, err)
+	}
+	return value, nil
+}
+
+// expandURL replaces the placeholders in the URL with the values of the arguments, escaped for the
+// path or, after the "?", for the query string. Path segments can not be "." or "..", which would
+// address another resource.
+func (e *endpoint) expandURL(args map[string]interface{}) (string, *errors.QueryError) {
+	queryStart := strings.IndexByte(e.url, '?')
+	var b strings.Builder
+	last := 0
+	for _, m := range placeholder.FindAllStringSubmatchIndex(e.url, -1) {
+		b.WriteString(e.url[last:m[0]])
+		last = m[1]
+		name := e.url[m[2]:m[3]]
+		var s string
+		if v := args[name]; v != nil {
+			s = fmt.Sprint(v)
+		}
+		if queryStart >= 0 && m[0] > queryStart {
+			b.WriteString(url.QueryEscape(s))
+			continue
+		}
+		if s == "." || s == ".." {
+			return "", errors.Errorf("endpoint of %s: invalid value %q for argument %q", e.ref, s, name)
+		}
+		b.WriteString(url.PathEscape(s))
+	}
+	b.WriteString(e.url[last:])
+	return b.String(), nil
+}
+
+// projector writes the parts of JSON values selected by a query.
+type projector struct {
+	doc  *query.Document
+	vars map[string]interface{}
+}
+
+func (p *projector) write(out *bytes.Buffer, t common.Type, value interface{}, sels []query.Selection) error {
+	if nn, ok := t.(*common.NonNull); ok {
+		if value == nil {
+			return perrors.Errorf("got null for non-null %s", nn.OfType)
+		}
+		t = nn.OfType
+	}
+	if value == nil {
+		out.WriteString("null")
+		return nil
+	}
+
+	switch t := t.(type) {
+	case *common.List:
+		entries, ok := value.([]interface{})
+		if !ok {
+			return perrors.Errorf("expected a list for %s, got %s", t, describe(value))
+		}
+		out.WriteByte('[')
+		for i, entry := range entries {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := p.write(out, t.OfType, entry, sels); err != nil {
+				return perrors.Wrapf(err, "[%d]", i)
+			}
+		}
+		out.WriteByte(']')
+		return nil
+
+	case *schema.Object, *schema.Interface, *schema.Union:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return perrors.Errorf("expected an object for %s, got %s", t, describe(value))
+		}
+		obj, err := concreteType(t.(schema.NamedType), fields)
+		if err != nil {
+			return err
+		}
+		out.WriteByte('{')
+		written := make(map[string]bool)
+		if err := p.writeFields(out, obj, fields, sels, written); err != nil {
+			return err
+		}
+		out.WriteByte('}')
+		return nil
+
+	case *schema.Enum:
+		name, ok := value.(string)
+		if ok {
+			for _, v := range t.Values {
+				if v.Name == name {
+					out.WriteString(quote(name))
+					return nil
+				}
+			}
+		}
+		return perrors.Errorf("can not use %s as %s", describe(value), t.Name)
+
+	case *schema.Scalar:
+		return writeScalar(out, t, value)
+	}
+	return perrors.Errorf("unsupported type %s", t)
+}
+
+func (p *projector) writeFields(out *bytes.Buffer, obj *schema.Object, fields map[string]interface{}, sels []query.Selection, written map[string]bool) error {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			if p.skip(sel.Directives) || written[sel.Alias.Name] {
+				continue
+			}
+			if len(written) > 0 {
+				out.WriteByte(',')
+			}
+			written[sel.Alias.Name] = true
+			out.WriteString(quote(sel.Alias.Name))
+			out.WriteByte(':')
+			if sel.Name.Name == "__typename" {
+				out.WriteString(quote(obj.Name))
+				continue
+			}
+			f := obj.Fields.Get(sel.Name.Name)
+			if f == nil {
+				return perrors.Errorf("%s has no field %q", obj.Name, sel.Name.Name)
+			}
+			value, ok := fields[sel.Name.Name]
+			if !ok {
+				value = fields[pubquery.SnakeCase(sel.Name.Name)]
+			}
+			if err := p.write(out, f.Type, value, sel.Selections); err != nil {
+				return perrors.Wrapf(err, "%s", sel.Alias.Name)
+			}
+
+		case *query.InlineFragment:
+			if p.skip(sel.Directives) || !applies(obj, sel.On.Name) {
+				continue
+			}
+			if err := p.writeFields(out, obj, fields, sel.Selections, written); err != nil {
+				return err
+			}
+
+		case *query.FragmentSpread:
+			frag := p.doc.Fragments.Get(sel.Name.Name)
+			if p.skip(sel.Directives) || frag == nil || !applies(obj, frag.On.Name) {
+				continue
+			}
+			if err := p.writeFields(out, obj, fields, frag.Selections, written); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *projector) skip(directives common.DirectiveList) bool {
+	if d := directives.Get("skip"); d != nil {
+		if lit, ok := d.Args.Get("if"); ok && lit.Value(p.vars) == true {
+			return true
+		}
+	}
+	if d := directives.Get("include"); d != nil {
+		if lit, ok := d.Args.Get("if"); ok && lit.Value(p.vars) == false {
+			return true
+		}
+	}
+	return false
+}
+
+// concreteType returns the object type of a value of type t. Values of interfaces and unions with
+// more than one possible type have to name theirs in the "__typename" field.
+func concreteType(t schema.NamedType, fields map[string]interface{}) (*schema.Object, error) {
+	var possible []*schema.Object
+	switch t := t.(type) {
+	case *schema.Object:
+		return t, nil
+	case *schema.Interface:
+		possible = t.PossibleTypes
+	case *schema.Union:
+		possible = t.PossibleTypes
+	}
+	name, _ := fields["__typename"].(string)
+	for _, obj := range possible {
+		if obj.Name == name || name == "" && len(possible) == 1 {
+			return obj, nil
+		}
+	}
+	return nil, perrors.Errorf("can not determine the type of %s, expected a %q field naming one of its possible types", t.TypeName(), "__typename")
+}
+
+// applies reports whether a fragment on the type applies to values of the object type.
+func applies(obj *schema.Object, on string) bool {
+	if on == "" || on == obj.Name {
+		return true
+	}
+	for _, intf := range obj.Interfaces {
+		if intf.Name == on {
+			return true
+		}
+	}
+	return false
+}
+
+func writeScalar(out *bytes.Buffer, t *schema.Scalar, value interface{}) error {
+	switch t.Name {
+	case "Int":
+		n, ok := value.(json.Number)
+		if !ok {
+			break
+		}
+		if i, err := n.Int64(); err == nil && i >= math.MinInt32 && i <= math.MaxInt32 {
+			out.WriteString(n.String())
+			return nil
+		}
+	case "Float":
+		if n, ok := value.(json.Number); ok {
+			out.WriteString(n.String())
+			return nil
+		}
+	case "String":
+		if s, ok := value.(string); ok {
+			out.WriteString(quote(s))
+			return nil
+		}
+	case "Boolean":
+		if b, ok := value.(bool); ok {
+			fmt.Fprint(out, b)
+			return nil
+		}
+	case "ID":
+		switch v := value.(type) {
+		case string:
+			out.WriteString(quote(v))
+			return nil
+		case json.Number:
+			out.WriteString(quote(v.String()))
+			return nil
+		}
+	default:
+		// custom scalars are passed through
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		out.Write(data)
+		return nil
+	}
+	return perrors.Errorf("can not use %s as %s", describe(value), t.Name)
+}
+
+func describe(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -0,0 +1,261 @@
+// Package mock resolves the root fields of a schema with fake data, so that clients can be
+// developed against a schema before its resolvers exist. The data is deterministic: it only depends
+// on the seed and the path of each value, so repeating a query returns the same data.
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// Options configures the fake data.
+type Options struct {
+	Seed int64
+
+	// Types maps names of types to functions returning their values. Values of scalars and enums
+	// are encoded as JSON, values of object types are maps from field names to values replacing
+	// the generated ones.
+	Types map[string]func(r *rand.Rand) interface{}
+
+	// MinListLength and MaxListLength bound the number of entries of lists.
+	MinListLength int
+	MaxListLength int
+}
+
+// Delegate returns the delegate resolving the root field with fake data.
+func Delegate(f *schema.Field, opts Options) resolvable.Delegate {
+	if opts.MaxListLength < opts.MinListLength {
+		opts.MaxListLength = opts.MinListLength
+	}
+	g := &generator{opts: &opts, field: f}
+	return g.resolve
+}
+
+type generator struct {
+	opts  *Options
+	field *schema.Field
+	doc   *query.Document
+	vars  map[string]interface{}
+}
+
+// resolve is the delegate of the field. The query it gets selects only the field.
+func (g *generator) resolve(ctx context.Context, queryString string, variables map[string]interface{}) (json.RawMessage, []*errors.QueryError) {
+	doc, qErr := query.Parse(queryString)
+	if qErr != nil {
+		return nil, []*errors.QueryError{qErr}
+	}
+	field := doc.Operations[0].Selections[0].(*query.Field)
+
+	// the generator of each request has its own document and variables
+	r := &generator{opts: g.opts, field: g.field, doc: doc, vars: variables}
+	var out bytes.Buffer
+	out.WriteString("{")
+	out.WriteString(quote(field.Alias.Name))
+	out.WriteString(":")
+	if err := r.write(&out, g.field.Type, field.Alias.Name, g.field.Name, nil, field.Selections); err != nil {
+		return nil, []*errors.QueryError{errors.Errorf("mock: %s", err)}
+	}
+	out.WriteString("}")
+	return out.Bytes(), nil
+}
+
+// write writes a fake value of type t at the path. The value replaces it unless it is nil.
+func (g *generator) write(out *bytes.Buffer, t common.Type, path, fieldName string, value interface{}, sels []query.Selection) error {
+	if nn, ok := t.(*common.NonNull); ok {
+		t = nn.OfType
+	}
+	rnd := g.rand(path)
+	if value == nil {
+		if fn, ok := g.opts.Types[typeName(t)]; ok {
+			value = fn(rnd)
+		}
+	}
+
+	switch t := t.(type) {
+	case *common.List:
+		entries, ok := value.([]interface{})
+		if !ok {
+			n := g.opts.MinListLength + rnd.Intn(g.opts.MaxListLength-g.opts.MinListLength+1)
+			entries = make([]interface{}, n)
+		}
+		out.WriteByte('[')
+		for i, entry := range entries {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := g.write(out, t.OfType, fmt.Sprintf("%s.%d", path, i), fieldName, entry, sels); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+		return nil
+
+	case *schema.Object, *schema.Interface, *schema.Union:
+		obj := concreteType(t.(schema.NamedType), rnd)
+		if obj == nil {
+			out.WriteString("null") // an interface without implementations
+			return nil
+		}
+		fields, _ := value.(map[string]interface{})
+		out.WriteByte('{')
+		if err := g.writeFields(out, obj, path, fields, sels, make(map[string]bool)); err != nil {
+			return err
+		}
+		out.WriteByte('}')
+		return nil
+	}
+
+	if value == nil {
+		value = fakeValue(t, fieldName, rnd)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return perrors.Errorf("%s: %s", path, err)
+	}
+	out.Write(data)
+	return nil
+}
+
+func (g *generator) writeFields(out *bytes.Buffer, obj *schema.Object, path string, values map[string]interface{}, sels []query.Selection, written map[string]bool) error {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			if g.skip(sel.Directives) || written[sel.Alias.Name] {
+				continue
+			}
+			if len(written) > 0 {
+				out.WriteByte(',')
+			}
+			written[sel.Alias.Name] = true
+			out.WriteString(quote(sel.Alias.Name))
+			out.WriteByte(':')
+			if sel.Name.Name == "__typename" {
+				out.WriteString(quote(obj.Name))
+				continue
+			}
+			f := obj.Fields.Get(sel.Name.Name)
+			if f == nil {
+				return perrors.Errorf("%s has no field %q", obj.Name, sel.Name.Name)
+			}
+			// the path uses the field name, so that aliases of a field get the same value
+			if err := g.write(out, f.Type, path+"."+f.Name, f.Name, values[f.Name], sel.Selections); err != nil {
+				return err
+			}
+
+		case *query.InlineFragment:
+			if g.skip(sel.Directives) || !applies(obj, sel.On.Name) {
+				continue
+			}
+			if err := g.writeFields(out, obj, path, values, sel.Selections, written); err != nil {
+				return err
+			}
+
+		case *query.FragmentSpread:
+			frag := g.doc.Fragments.Get(sel.Name.Name)
+			if g.skip(sel.Directives) || frag == nil || !applies(obj, frag.On.Name) {
+				continue
+			}
+			if err := g.writeFields(out, obj, path, values, frag.Selections, written); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rand returns the source of the value at the path.
+func (g *generator) rand(path string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	return rand.New(rand.NewSource(g.opts.Seed ^ int64(h.Sum64())))
+}
+
+func (g *generator) skip(directives common.DirectiveList) bool {
+	if d := directives.Get("skip"); d != nil {
+		if lit, ok := d.Args.Get("if"); ok && lit.Value(g.vars) == true {
+			return true
+		}
+	}
+	if d := directives.Get("include"); d != nil {
+		if lit, ok := d.Args.Get("if"); ok && lit.Value(g.vars) == false {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeValue returns a value of the scalar or enum type.
+func fakeValue(t common.Type, fieldName string, rnd *rand.Rand) interface{} {
+	switch t := t.(type) {
+	case *schema.Enum:
+		if len(t.Values) == 0 {
+			return nil
+		}
+		return t.Values[rnd.Intn(len(t.Values))].Name
+	case *schema.Scalar:
+		switch t.Name {
+		case "Int":
+			return rnd.Intn(100)
+		case "Float":
+			return float64(rnd.Intn(10000)) / 100
+		case "Boolean":
+			return rnd.Intn(2) == 1
+		case "ID":
+			return fmt.Sprint(rnd.Intn(1000000))
+		}
+	}
+	return fmt.Sprintf("%s %d", fieldName, rnd.Intn(1000))
+}
+
+// concreteType picks one of the object types of values of type t.
+func concreteType(t schema.NamedType, rnd *rand.Rand) *schema.Object {
+	var possible []*schema.Object
+	switch t := t.(type) {
+	case *schema.Object:
+		return t
+	case *schema.Interface:
+		possible = t.PossibleTypes
+	case *schema.Union:
+		possible = t.PossibleTypes
+	}
+	if len(possible) == 0 {
+		return nil
+	}
+	return possible[rnd.Intn(len(possible))]
+}
+
+// applies reports whether a fragment on the type applies to values of the object type.
+func applies(obj *schema.Object, on string) bool {
+	if on == "" || on == obj.Name {
+		return true
+	}
+	for _, intf := range obj.Interfaces {
+		if intf.Name == on {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(t common.Type) string {
+	if named, ok := t.(schema.NamedType); ok {
+		return named.TypeName()
+	}
+	return ""
+}
+
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -0,0 +1,193 @@
+package query
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/qdentity/graphql-go/internal/common"
+)
+
+// Print returns the GraphQL source of the document.
+func Print(doc *Document) string {
+	p := &printer{}
+	for i, op := range doc.Operations {
+		if i > 0 {
+			p.buf.WriteString("\n")
+		}
+		p.operation(op)
+	}
+	for i, frag := range doc.Fragments {
+		if i > 0 || len(doc.Operations) > 0 {
+			p.buf.WriteString("\n")
+		}
+		p.fragment(frag)
+	}
+	return p.buf.String()
+}
+
+// PrintOperation returns the GraphQL source of the operation followed by the fragments it uses,
+// which is a valid document on its own.
+func PrintOperation(doc *Document, op *Operation) string {
+	p := &printer{}
+	p.operation(op)
+
+	used := make(map[string]struct{})
+	collectFragments(doc, op.Selections, used)
+	for _, frag := range doc.Fragments {
+		if _, ok := used[frag.Name.Name]; ok {
+			p.buf.WriteString("\n")
+			p.fragment(frag)
+		}
+	}
+	return p.buf.String()
+}
+
+func collectFragments(doc *Document, sels []Selection, used map[string]struct{}) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *Field:
+			collectFragments(doc, sel.Selections, used)
+		case *InlineFragment:
+			collectFragments(doc, sel.Selections, used)
+		case *FragmentSpread:
+			if _, ok := used[sel.Name.Name]; ok {
+				continue
+			}
+			used[sel.Name.Name] = struct{}{}
+			if frag := doc.Fragments.Get(sel.Name.Name); frag != nil {
+				collectFragments(doc, frag.Selections, used)
+			}
+		}
+	}
+}
+
+type printer struct {
+	buf    bytes.Buffer
+	indent int
+}
+
+func (p *printer) operation(op *Operation) {
+	p.buf.WriteString(strings.ToLower(string(op.Type)))
+	if op.Name.Name != "" {
+		p.buf.WriteString(" ")
+		p.buf.WriteString(op.Name.Name)
+	}
+	if len(op.Vars) > 0 {
+		p.buf.WriteString("(")
+		for i, v := range op.Vars {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.buf.WriteString("$")
+			p.buf.WriteString(v.Name.Name)
+			p.buf.WriteString(": ")
+			p.buf.WriteString(PrintType(v.Type))
+			if v.Default != nil {
+				p.buf.WriteString(" = ")
+				p.buf.WriteString(v.Default.String())
+			}
+		}
+		p.buf.WriteString(")")
+	}
+	p.directives(op.Directives)
+	p.buf.WriteString(" ")
+	p.selectionSet(op.Selections)
+	p.buf.WriteString("\n")
+}
+
+func (p *printer) fragment(frag *FragmentDecl) {
+	p.buf.WriteString("fragment ")
+	p.buf.WriteString(frag.Name.Name)
+	p.buf.WriteString(" on ")
+	p.buf.WriteString(frag.On.Name)
+	p.directives(frag.Directives)
+	p.buf.WriteString(" ")
+	p.selectionSet(frag.Selections)
+	p.buf.WriteString("\n")
+}
+
+func (p *printer) selectionSet(sels []Selection) {
+	p.buf.WriteString("{\n")
+	p.indent++
+	for _, sel := range sels {
+		p.buf.WriteString(strings.Repeat("  ", p.indent))
+		p.selection(sel)
+		p.buf.WriteString("\n")
+	}
+	p.indent--
+	p.buf.WriteString(strings.Repeat("  ", p.indent))
+	p.buf.WriteString("}")
+}
+
+func (p *printer) selection(sel Selection) {
+	switch sel := sel.(type) {
+	case *Field:
+		if sel.Alias.Name != sel.Name.Name {
+			p.buf.WriteString(sel.Alias.Name)
+			p.buf.WriteString(": ")
+		}
+		p.buf.WriteString(sel.Name.Name)
+		p.arguments(sel.Arguments)
+		p.directives(sel.Directives)
+		if len(sel.Selections) > 0 {
+			p.buf.WriteString(" ")
+			p.selectionSet(sel.Selections)
+		}
+
+	case *InlineFragment:
+		p.buf.WriteString("...")
+		if sel.On.Name != "" {
+			p.buf.WriteString(" on ")
+			p.buf.WriteString(sel.On.Name)
+		}
+		p.directives(sel.Directives)
+		p.buf.WriteString(" ")
+		p.selectionSet(sel.Selections)
+
+	case *FragmentSpread:
+		p.buf.WriteString("...")
+		p.buf.WriteString(sel.Name.Name)
+		p.directives(sel.Directives)
+
+	default:
+		panic("unreachable")
+	}
+}
+
+func (p *printer) arguments(args common.ArgumentList) {
+	if len(args) == 0 {
+		return
+	}
+	p.buf.WriteString("(")
+	for i, arg := range args {
+		if i > 0 {
+			p.buf.WriteString(", ")
+		}
+		p.buf.WriteString(arg.Name.Name)
+		p.buf.WriteString(": ")
+		p.buf.WriteString(arg.Value.String())
+	}
+	p.buf.WriteString(")")
+}
+
+func (p *printer) directives(directives common.DirectiveList) {
+	for _, d := range directives {
+		p.buf.WriteString(" @")
+		p.buf.WriteString(d.Name.Name)
+		p.arguments(d.Args)
+	}
+}
+
+// PrintType returns the GraphQL notation of a type, which may still be unresolved.
+func PrintType(t common.Type) string {
+	switch t := t.(type) {
+	case *common.TypeName:
+		return t.Name
+	case *common.List:
+		return "[" + PrintType(t.OfType) + "]"
+	case *common.NonNull:
+		return PrintType(t.OfType) + "!"
+	default:
+		return t.String()
+	}
+}
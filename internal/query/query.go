@@ -93,8 +93,20 @@ func (Field) isSelection()          {}
 func (InlineFragment) isSelection() {}
 func (FragmentSpread) isSelection() {}
 
+// DocumentOf returns the parsed document of a document of a public package, which keeps it
+// unexported, e.g. of a rewrite.Document. It is set by that package.
+var DocumentOf func(doc interface{}) *Document
+
 func Parse(queryString string) (*Document, *errors.QueryError) {
-	l := common.NewLexer(queryString)
+	return ParseWithLimits(queryString, common.ParserLimits{})
+}
+
+// ParseWithLimits parses the document, which has to stay within the limits.
+func ParseWithLimits(queryString string, limits common.ParserLimits) (*Document, *errors.QueryError) {
+	if err := limits.CheckSize(queryString); err != nil {
+		return nil, err
+	}
+	l := common.NewLimitedLexer(queryString, limits)
 
 	var doc *Document
 	err := l.CatchSyntaxError(func() { doc = parseDocument(l) })
@@ -109,6 +121,7 @@ func parseDocument(l *common.Lexer) *Document {
 	d := &Document{}
 	l.Consume()
 	for l.Peek() != scanner.EOF {
+		l.Definition()
 		if l.Peek() == '{' {
 			op := &Operation{Type: Query, Loc: l.Location()}
 			op.Selections = parseSelectionSet(l)
@@ -232,3 +245,38 @@ func parseSpread(l *common.Lexer) Selection {
 	f.Selections = parseSelectionSet(l)
 	return f
 }
+
+// ParseSelections parses the selections of a selection set without its braces, e.g.
+// "id friends { name }".
+func ParseSelections(s string) ([]Selection, *errors.QueryError) {
+	l := common.NewLexer("{" + s + "}")
+	var sels []Selection
+	err := l.CatchSyntaxError(func() {
+		l.Consume()
+		sels = parseSelectionSet(l)
+		if l.Peek() != scanner.EOF {
+			l.SyntaxError("unexpected input after the selections")
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sels, nil
+}
+
+// ParseValue parses a value literal, e.g. `"id"`, `[1, 2]` or `$first`.
+func ParseValue(s string) (common.Literal, *errors.QueryError) {
+	l := common.NewLexer(s)
+	var lit common.Literal
+	err := l.CatchSyntaxError(func() {
+		l.Consume()
+		lit = common.ParseLiteral(l, false)
+		if l.Peek() != scanner.EOF {
+			l.SyntaxError("unexpected input after the value")
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lit, nil
+}
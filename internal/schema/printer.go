@@ -0,0 +1,250 @@
+package schema
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/qdentity/graphql-go/internal/common"
+)
+
+// Print returns the canonical GraphQL source of the schema: the schema definition, the directive
+// declarations and the types, each sorted by name, with the descriptions as comments. The built-in
+// types and directives are omitted.
+func Print(s *Schema) string {
+	return PrintEdited(s, nil)
+}
+
+// FieldEdit changes the definition of a field when the schema is printed, see PrintEdited.
+type FieldEdit struct {
+	Type string   // replaces the type of the field if not empty, e.g. "BookConnection!"
+	Args []string // definitions of arguments added to the field, e.g. "first: Int = 10"
+}
+
+// PrintEdited is Print with the edits of the fields, keyed by "Type.field", applied. The edits are
+// printed as they are, so the source is only valid if they are.
+func PrintEdited(s *Schema, edits map[string]*FieldEdit) string {
+	p := &printer{edits: edits}
+	if len(s.entryPointNames) > 0 || len(s.SchemaDirectives) > 0 || s.Desc != "" {
+		p.schema(s)
+	}
+
+	var directives []string
+	for name, d := range s.Directives {
+		if Meta.Directives[name] != d {
+			directives = append(directives, name)
+		}
+	}
+	sort.Strings(directives)
+	for _, name := range directives {
+		p.definition()
+		p.directiveDecl(s.Directives[name])
+	}
+
+	var types []string
+	for name, t := range s.Types {
+		if Meta.Types[name] != t {
+			types = append(types, name)
+		}
+	}
+	sort.Strings(types)
+	for _, name := range types {
+		p.definition()
+		p.namedType(s.Types[name])
+	}
+	return p.buf.String()
+}
+
+type printer struct {
+	buf    bytes.Buffer
+	indent int
+	edits  map[string]*FieldEdit
+}
+
+// definition separates the definitions by empty lines.
+func (p *printer) definition() {
+	if p.buf.Len() > 0 {
+		p.buf.WriteString("\n")
+	}
+}
+
+func (p *printer) schema(s *Schema) {
+	p.desc(s.Desc)
+	p.buf.WriteString("schema")
+	p.directives(s.SchemaDirectives)
+	p.buf.WriteString(" {\n")
+	for _, op := range []string{"query", "mutation", "subscription"} {
+		if name, ok := s.entryPointNames[op]; ok {
+			p.buf.WriteString("  " + op + ": " + name + "\n")
+		}
+	}
+	p.buf.WriteString("}\n")
+}
+
+func (p *printer) directiveDecl(d *DirectiveDecl) {
+	p.desc(d.Desc)
+	p.buf.WriteString("directive @")
+	p.buf.WriteString(d.Name)
+	p.inputValues(d.Args)
+	p.buf.WriteString(" on ")
+	p.buf.WriteString(strings.Join(d.Locs, " | "))
+	p.buf.WriteString("\n")
+}
+
+func (p *printer) namedType(t NamedType) {
+	p.desc(t.Description())
+	switch t := t.(type) {
+	case *Scalar:
+		p.buf.WriteString("scalar " + t.Name + "\n")
+
+	case *Object:
+		p.buf.WriteString("type " + t.Name)
+		if len(t.interfaceNames) > 0 {
+			p.buf.WriteString(" implements " + strings.Join(t.interfaceNames, " & "))
+		}
+		p.fields(t.Name, t.Fields)
+
+	case *Interface:
+		p.buf.WriteString("interface " + t.Name)
+		p.fields(t.Name, t.Fields)
+
+	case *Union:
+		p.buf.WriteString("union " + t.Name + " = " + strings.Join(t.typeNames, " | ") + "\n")
+
+	case *Enum:
+		p.buf.WriteString("enum " + t.Name + " {\n")
+		p.indent++
+		for _, v := range t.Values {
+			p.desc(v.Desc)
+			p.buf.WriteString(p.prefix() + v.Name)
+			p.directives(v.Directives)
+			p.buf.WriteString("\n")
+		}
+		p.indent--
+		p.buf.WriteString("}\n")
+
+	case *InputObject:
+		p.buf.WriteString("input " + t.Name + " {\n")
+		p.indent++
+		for _, v := range t.Values {
+			p.desc(v.Desc)
+			p.buf.WriteString(p.prefix())
+			p.inputValue(v)
+			p.buf.WriteString("\n")
+		}
+		p.indent--
+		p.buf.WriteString("}\n")
+	}
+}
+
+func (p *printer) fields(typeName string, fields FieldList) {
+	p.buf.WriteString(" {\n")
+	p.indent++
+	for _, f := range fields {
+		edit := p.edits[typeName+"."+f.Name]
+		if edit == nil {
+			edit = &FieldEdit{}
+		}
+		p.desc(f.Desc)
+		p.buf.WriteString(p.prefix() + f.Name)
+		p.inputValues(f.Args, edit.Args...)
+		typ := f.Type.String()
+		if edit.Type != "" {
+			typ = edit.Type
+		}
+		p.buf.WriteString(": " + typ)
+		p.directives(f.Directives)
+		p.buf.WriteString("\n")
+	}
+	p.indent--
+	p.buf.WriteString("}\n")
+}
+
+// inputValues writes the arguments, followed by the added ones given as source, in parentheses,
+// one per line if any of them has a description.
+func (p *printer) inputValues(values common.InputValueList, added ...string) {
+	if len(values) == 0 && len(added) == 0 {
+		return
+	}
+	multiline := false
+	for _, v := range values {
+		if v.Desc != "" {
+			multiline = true
+		}
+	}
+	p.buf.WriteString("(")
+	if multiline {
+		p.buf.WriteString("\n")
+		p.indent++
+	}
+	for i, v := range values {
+		if multiline {
+			p.desc(v.Desc)
+			p.buf.WriteString(p.prefix())
+		} else if i > 0 {
+			p.buf.WriteString(", ")
+		}
+		p.inputValue(v)
+		if multiline {
+			p.buf.WriteString("\n")
+		}
+	}
+	for i, v := range added {
+		if multiline {
+			p.buf.WriteString(p.prefix() + v + "\n")
+			continue
+		}
+		if i > 0 || len(values) > 0 {
+			p.buf.WriteString(", ")
+		}
+		p.buf.WriteString(v)
+	}
+	if multiline {
+		p.indent--
+		p.buf.WriteString(p.prefix())
+	}
+	p.buf.WriteString(")")
+}
+
+func (p *printer) inputValue(v *common.InputValue) {
+	p.buf.WriteString(v.Name.Name + ": " + v.Type.String())
+	if v.Default != nil {
+		p.buf.WriteString(" = " + v.Default.String())
+	}
+	p.directives(v.Directives)
+}
+
+func (p *printer) directives(directives common.DirectiveList) {
+	for _, d := range directives {
+		p.buf.WriteString(" @" + d.Name.Name)
+		if len(d.Args) == 0 {
+			continue
+		}
+		p.buf.WriteString("(")
+		for i, arg := range d.Args {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.buf.WriteString(arg.Name.Name + ": " + arg.Value.String())
+		}
+		p.buf.WriteString(")")
+	}
+}
+
+// desc writes the description as comments, which the parser reads back as the description.
+func (p *printer) desc(desc string) {
+	if desc == "" {
+		return
+	}
+	for _, line := range strings.Split(desc, "\n") {
+		p.buf.WriteString(p.prefix() + "#")
+		if line != "" {
+			p.buf.WriteString(" " + line)
+		}
+		p.buf.WriteString("\n")
+	}
+}
+
+func (p *printer) prefix() string {
+	return strings.Repeat("  ", p.indent)
+}
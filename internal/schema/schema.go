@@ -2,6 +2,8 @@ package schema
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"text/scanner"
 
 	"github.com/qdentity/graphql-go/errors"
@@ -41,6 +43,12 @@ type Schema struct {
 	// http://facebook.github.io/graphql/draft/#sec-Type-System.Directives
 	Directives map[string]*DirectiveDecl
 
+	// SchemaDirectives are the directives applied to the schema definition.
+	SchemaDirectives common.DirectiveList
+
+	// Desc is the description of the schema definition.
+	Desc string
+
 	entryPointNames map[string]string
 	objects         []*Object
 	unions          []*Union
@@ -247,7 +255,15 @@ func New() *Schema {
 
 // Parse the schema string.
 func (s *Schema) Parse(schemaString string) error {
-	l := common.NewLexer(schemaString)
+	return s.ParseWithLimits(schemaString, common.ParserLimits{})
+}
+
+// ParseWithLimits parses the schema, whose source has to stay within the limits.
+func (s *Schema) ParseWithLimits(schemaString string, limits common.ParserLimits) error {
+	if err := limits.CheckSize(schemaString); err != nil {
+		return err
+	}
+	l := common.NewLimitedLexer(schemaString, limits)
 
 	err := l.CatchSyntaxError(func() { parseSchema(s, l) })
 	if err != nil {
@@ -319,6 +335,63 @@ func (s *Schema) Parse(schemaString string) error {
 		}
 	}
 
+	if err := resolveDirectives(s, s.SchemaDirectives); err != nil {
+		return err
+	}
+
+	return checkInputCycles(s)
+}
+
+// checkInputCycles returns an error for an input object that references itself through a series
+// of non-null fields, since no finite value of it can be written.
+func checkInputCycles(s *Schema) error {
+	var names []string
+	for name, t := range s.Types {
+		if _, ok := t.(*InputObject); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	c := &inputCycles{visited: make(map[*InputObject]bool), onPath: make(map[*InputObject]int)}
+	for _, name := range names {
+		if err := c.check(s.Types[name].(*InputObject)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type inputCycles struct {
+	visited map[*InputObject]bool
+	onPath  map[*InputObject]int // the index in path of the field leading to the type
+	path    []string
+}
+
+func (c *inputCycles) check(t *InputObject) error {
+	if c.visited[t] {
+		return nil
+	}
+	c.visited[t] = true
+	c.onPath[t] = len(c.path)
+	for _, v := range t.Values {
+		nonNull, ok := v.Type.(*common.NonNull)
+		if !ok {
+			continue
+		}
+		in, ok := nonNull.OfType.(*InputObject)
+		if !ok {
+			continue
+		}
+		c.path = append(c.path, v.Name.Name)
+		if i, ok := c.onPath[in]; ok {
+			return errors.Errorf("input object %q references itself through the non-null fields %q", in.Name, strings.Join(c.path[i:], "."))
+		}
+		if err := c.check(in); err != nil {
+			return err
+		}
+		c.path = c.path[:len(c.path)-1]
+	}
+	delete(c.onPath, t)
 	return nil
 }
 
@@ -369,7 +442,7 @@ func resolveDirectives(s *Schema, directives common.DirectiveList) error {
 			}
 		}
 		for _, arg := range dd.Args {
-			if _, ok := d.Args.Get(arg.Name.Name); !ok {
+			if _, ok := d.Args.Get(arg.Name.Name); !ok && arg.Default != nil {
 				d.Args = append(d.Args, common.Argument{Name: arg.Name, Value: arg.Default})
 			}
 		}
@@ -384,6 +457,9 @@ func resolveInputObject(s *Schema, values common.InputValueList) error {
 			return err
 		}
 		v.Type = t
+		if err := resolveDirectives(s, v.Directives); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -392,10 +468,13 @@ func parseSchema(s *Schema, l *common.Lexer) {
 	l.Consume()
 
 	for l.Peek() != scanner.EOF {
-		desc := l.DescComment()
+		l.Definition()
+		desc := l.Description()
 		switch x := l.ConsumeIdent(); x {
 
 		case "schema":
+			s.Desc = desc
+			s.SchemaDirectives = common.ParseDirectives(l)
 			l.ConsumeToken('{')
 			for l.Peek() != '}' {
 				name := l.ConsumeIdent()
@@ -511,7 +590,7 @@ func parseEnumDef(l *common.Lexer) *Enum {
 	l.ConsumeToken('{')
 	for l.Peek() != '}' {
 		v := &EnumValue{
-			Desc:       l.DescComment(),
+			Desc:       l.Description(),
 			Name:       l.ConsumeIdent(),
 			Directives: common.ParseDirectives(l),
 		}
@@ -552,7 +631,7 @@ func parseFieldsDef(l *common.Lexer) FieldList {
 	var fields FieldList
 	for l.Peek() != '}' {
 		f := &Field{}
-		f.Desc = l.DescComment()
+		f.Desc = l.Description()
 		f.Name = l.ConsumeIdent()
 		if l.Peek() == '(' {
 			l.ConsumeToken('(')
@@ -77,7 +77,6 @@ func TestParse(t *testing.T) {
 
 	for _, test := range parseTests {
 		t.Run(test.description, func(t *testing.T) {
-			t.Skip("TODO: add support for descriptions")
 			schema := setup(t)
 
 			err := schema.Parse(test.sdl)
@@ -85,7 +84,51 @@ func TestParse(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			// TODO: verify schema is the same as expected.
+			for name, want := range test.expected.Types {
+				got, ok := schema.Types[name]
+				if !ok {
+					t.Fatalf("missing type %q", name)
+				}
+				if got.Description() != want.Description() {
+					t.Errorf("got description %q for %q, want %q", got.Description(), name, want.Description())
+				}
+			}
 		})
 	}
 }
+
+func TestInputCycles(t *testing.T) {
+	for _, test := range []struct {
+		sdl string
+		err string
+	}{
+		{
+			sdl: `
+				input Node { next: Node }
+				input List { items: [List!]! }
+			`,
+		},
+		{
+			sdl: `input Node { next: Node! }`,
+			err: `graphql: input object "Node" references itself through the non-null fields "next"`,
+		},
+		{
+			sdl: `
+				input A { b: B!, name: String }
+				input B { c: C }
+				input C { a: A! }
+				input D { e: E! }
+				input E { d: D!, f: B! }
+			`,
+			err: `graphql: input object "D" references itself through the non-null fields "e.d"`,
+		},
+	} {
+		err := schema.New().Parse(test.sdl)
+		if test.err == "" && err != nil {
+			t.Errorf("got error %q for %s", err, test.sdl)
+		}
+		if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("got error %v, want %q", err, test.err)
+		}
+	}
+}
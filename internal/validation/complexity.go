@@ -0,0 +1,140 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// ValidateComplexity checks that the complexity of the operation does not exceed max. The
+// complexity of a field is set with the @complexity(value: Int!, multipliers: [String!])
+// directive in the schema: its value plus the complexity of its selections, multiplied by the
+// values of the arguments named by multipliers, e.g. "first". Lists multiply by their length.
+// Fields without the directive have a value of 1, introspection fields are free. The operation has
+// to be valid and the variables have to include the default values of the operation.
+func ValidateComplexity(s *schema.Schema, doc *query.Document, op *query.Operation, variables map[string]interface{}, max int) []*errors.QueryError {
+	c := &complexityContext{schema: s, doc: doc, vars: variables, fragments: make(map[string]int)}
+	complexity := c.selections(op.Selections, s.EntryPoints[strings.ToLower(string(op.Type))])
+	if complexity <= max {
+		return nil
+	}
+	return []*errors.QueryError{{
+		Message:   fmt.Sprintf("Operation has a complexity of %d, which exceeds the maximum of %d.", complexity, max),
+		Locations: []errors.Location{op.Loc},
+		Rule:      "MaxComplexity",
+	}}
+}
+
+type complexityContext struct {
+	schema    *schema.Schema
+	doc       *query.Document
+	vars      map[string]interface{}
+	fragments map[string]int // complexities of fragments, which don't depend on where they are spread
+}
+
+func (c *complexityContext) selections(sels []query.Selection, t common.Type) int {
+	sum := 0
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			if f := fields(t).Get(sel.Name.Name); f != nil {
+				sum = saturatedAdd(sum, c.field(sel, f))
+			}
+		case *query.InlineFragment:
+			on := t
+			if sel.On.Name != "" {
+				on = c.resolve(sel.On.Name)
+			}
+			sum = saturatedAdd(sum, c.selections(sel.Selections, on))
+		case *query.FragmentSpread:
+			sum = saturatedAdd(sum, c.fragment(sel.Name.Name))
+		}
+	}
+	return sum
+}
+
+func (c *complexityContext) fragment(name string) int {
+	if n, ok := c.fragments[name]; ok {
+		return n
+	}
+	frag := c.doc.Fragments.Get(name)
+	if frag == nil {
+		return 0
+	}
+	n := c.selections(frag.Selections, c.resolve(frag.On.Name))
+	c.fragments[name] = n
+	return n
+}
+
+func (c *complexityContext) resolve(name string) common.Type {
+	return c.schema.Types[name]
+}
+
+// field returns the complexity of the field, see ValidateComplexity.
+func (c *complexityContext) field(sel *query.Field, f *schema.Field) int {
+	value := 1
+	var multipliers []interface{}
+	if d := f.Directives.Get("complexity"); d != nil {
+		if lit, ok := d.Args.Get("value"); ok && lit != nil {
+			if v, ok := lit.Value(nil).(int32); ok && v >= 0 {
+				value = int(v)
+			}
+		}
+		if lit, ok := d.Args.Get("multipliers"); ok && lit != nil {
+			multipliers, _ = lit.Value(nil).([]interface{})
+		}
+	}
+
+	n := saturatedAdd(value, c.selections(sel.Selections, unwrapType(f.Type)))
+	for _, name := range multipliers {
+		name, _ := name.(string)
+		var arg interface{}
+		if lit, ok := sel.Arguments.Get(name); ok {
+			arg = lit.Value(c.vars)
+		} else if decl := f.Args.Get(name); decl != nil && decl.Default != nil {
+			arg = decl.Default.Value(nil)
+		}
+		n = saturatedMul(n, multiplier(arg))
+	}
+	return n
+}
+
+// multiplier returns the number the complexity of a field is multiplied with for an argument. Null
+// and values other than numbers and lists don't change the complexity.
+func multiplier(arg interface{}) int {
+	var f float64
+	switch arg := arg.(type) {
+	case int32:
+		f = float64(arg)
+	case int:
+		f = float64(arg)
+	case int64:
+		f = float64(arg)
+	case float64:
+		f = arg
+	case []interface{}:
+		f = float64(len(arg))
+	default:
+		return 1
+	}
+	if f < 0 {
+		return 0
+	}
+	if f > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(f)
+}
+
+// saturatedMul multiplies non-negative numbers without overflowing.
+func saturatedMul(a, b int) int {
+	if b != 0 && a > math.MaxInt32/b {
+		return math.MaxInt32
+	}
+	return a * b
+}
@@ -0,0 +1,114 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// Deprecations returns a warning for each deprecated field and enum value used by the operation,
+// given as a literal argument, with the code "DEPRECATED". The operation has to be valid.
+func Deprecations(s *schema.Schema, doc *query.Document, op *query.Operation) []*errors.QueryError {
+	d := &deprecations{doc: doc, reported: make(map[string]bool), visited: make(map[string]bool)}
+	if t, ok := s.EntryPoints[strings.ToLower(string(op.Type))]; ok {
+		d.selections(s, op.Selections, t)
+	}
+	return d.warnings
+}
+
+type deprecations struct {
+	doc      *query.Document
+	warnings []*errors.QueryError
+	reported map[string]bool // the deprecated fields and enum values, reported once each
+	visited  map[string]bool // the fragments
+}
+
+func (d *deprecations) selections(s *schema.Schema, sels []query.Selection, t schema.NamedType) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			f := fields(t).Get(sel.Name.Name)
+			if f == nil {
+				continue // e.g. __typename
+			}
+			d.check(t.TypeName()+"."+f.Name, "field", f.Directives, sel.Alias.Loc)
+			for _, arg := range sel.Arguments {
+				if decl := f.Args.Get(arg.Name.Name); decl != nil {
+					d.literal(arg.Value, decl.Type)
+				}
+			}
+			d.selections(s, sel.Selections, unwrapType(f.Type))
+		case *query.InlineFragment:
+			fragType := t
+			if sel.On.Name != "" {
+				fragType = s.Types[sel.On.Name]
+			}
+			d.selections(s, sel.Selections, fragType)
+		case *query.FragmentSpread:
+			frag := d.doc.Fragments.Get(sel.Name.Name)
+			if frag == nil || d.visited[sel.Name.Name] {
+				continue
+			}
+			d.visited[sel.Name.Name] = true
+			d.selections(s, frag.Selections, s.Types[frag.On.Name])
+		}
+	}
+}
+
+// literal checks the enum values of the literal of type t.
+func (d *deprecations) literal(lit common.Literal, t common.Type) {
+	switch t := t.(type) {
+	case *common.NonNull:
+		d.literal(lit, t.OfType)
+	case *common.List:
+		if list, ok := lit.(*common.ListLit); ok {
+			for _, entry := range list.Entries {
+				d.literal(entry, t.OfType)
+			}
+		} else {
+			d.literal(lit, t.OfType) // a single value is coerced to a list
+		}
+	case *schema.InputObject:
+		if obj, ok := lit.(*common.ObjectLit); ok {
+			for _, field := range obj.Fields {
+				if decl := t.Values.Get(field.Name.Name); decl != nil {
+					d.literal(field.Value, decl.Type)
+				}
+			}
+		}
+	case *schema.Enum:
+		basic, ok := lit.(*common.BasicLit)
+		if !ok || basic.Type != scanner.Ident {
+			return
+		}
+		for _, v := range t.Values {
+			if v.Name == basic.Text {
+				d.check(t.Name+"."+v.Name, "enum value", v.Directives, basic.Loc)
+			}
+		}
+	}
+}
+
+// check adds a warning if the directives deprecate the field or enum value with the name.
+func (d *deprecations) check(name, kind string, directives common.DirectiveList, loc errors.Location) {
+	dep := directives.Get("deprecated")
+	if dep == nil || d.reported[name] {
+		return
+	}
+	d.reported[name] = true
+	msg := fmt.Sprintf("The %s %s is deprecated.", kind, name)
+	if reason, ok := dep.Args.Get("reason"); ok {
+		if r, ok := reason.Value(nil).(string); ok {
+			msg = fmt.Sprintf("The %s %s is deprecated: %s", kind, name, r)
+		}
+	}
+	err := errors.Errorf("%s", msg)
+	err.Locations = []errors.Location{loc}
+	err.Extensions = map[string]interface{}{"code": "DEPRECATED"}
+	d.warnings = append(d.warnings, err)
+}
@@ -0,0 +1,426 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+)
+
+// Limits restricts the shape of operations, to reject queries that are cheap to send but expensive
+// to execute. A limit of 0 disables it.
+type Limits struct {
+	// MaxIntrospectionOfTypeDepth limits the nesting of the "ofType" field of __Type. The
+	// introspection query of graphql-js nests it 7 times.
+	MaxIntrospectionOfTypeDepth int
+
+	// MaxIntrospectionDepth limits the nesting of the "fields", "inputFields", "interfaces" and
+	// "possibleTypes" fields of __Type, through which introspection queries recurse. The
+	// introspection query of graphql-js nests them once.
+	MaxIntrospectionDepth int
+
+	// MaxAliases and MaxDirectives limit the number of aliased fields and of directives of an
+	// operation, counting those of fragments every time they are spread.
+	MaxAliases    int
+	MaxDirectives int
+
+	// MaxDepth limits the nesting of fields, root fields have a depth of 1. Introspection fields
+	// are limited by MaxIntrospectionDepth instead.
+	MaxDepth int
+
+	// MaxInputDepth limits the nesting of lists and input objects in the values of arguments,
+	// directives and variables, a scalar has a depth of 0. The values of variables are checked by
+	// ValidateVariableDepth.
+	MaxInputDepth int
+}
+
+// ValidateLimits checks the operations of the document against the limits. The document has to be
+// valid, fragments are expanded where they are spread.
+func ValidateLimits(doc *query.Document, limits Limits) []*errors.QueryError {
+	c := &limitsContext{
+		doc:            doc,
+		limits:         limits,
+		checked:        make(map[fragmentDepth]bool),
+		fragmentCounts: make(map[string]counts),
+		fragmentDepths: make(map[string]int),
+	}
+	for _, op := range doc.Operations {
+		if limits.MaxIntrospectionOfTypeDepth > 0 || limits.MaxIntrospectionDepth > 0 {
+			c.rootIntrospection(op.Selections)
+		}
+		if limits.MaxAliases > 0 || limits.MaxDirectives > 0 {
+			counts := c.count(op.Selections)
+			counts.directives = saturatedAdd(counts.directives, len(op.Directives))
+			if limits.MaxAliases > 0 && counts.aliases > limits.MaxAliases {
+				c.addErr(op.Loc, "MaxAliases", "Operation has more than %d aliases.", limits.MaxAliases)
+			}
+			if limits.MaxDirectives > 0 && counts.directives > limits.MaxDirectives {
+				c.addErr(op.Loc, "MaxDirectives", "Operation has more than %d directives.", limits.MaxDirectives)
+			}
+		}
+		if limits.MaxDepth > 0 && c.depth(op.Selections) > limits.MaxDepth {
+			c.addErr(op.Loc, "MaxDepth", "Operation has fields nested more than %d levels deep.", limits.MaxDepth)
+		}
+		if limits.MaxInputDepth > 0 {
+			for _, v := range op.Vars {
+				if v.Default != nil {
+					c.inputDepth(v.Default)
+				}
+			}
+			c.inputDepthOfDirectives(op.Directives)
+			c.inputDepthOfSelections(op.Selections)
+		}
+	}
+	if limits.MaxInputDepth > 0 {
+		for _, frag := range doc.Fragments {
+			c.inputDepthOfDirectives(frag.Directives)
+			c.inputDepthOfSelections(frag.Selections)
+		}
+	}
+	return c.errs
+}
+
+// ValidateVariableDepth checks the values of the variables against limits.MaxInputDepth, before
+// they are coerced to the types of the variables.
+func ValidateVariableDepth(op *query.Operation, variables map[string]interface{}, limits Limits) []*errors.QueryError {
+	if limits.MaxInputDepth <= 0 {
+		return nil
+	}
+	var errs []*errors.QueryError
+	for _, v := range op.Vars {
+		value, ok := variables[v.Name.Name]
+		if ok && valueDepth(value, limits.MaxInputDepth) > limits.MaxInputDepth {
+			errs = append(errs, &errors.QueryError{
+				Message:   fmt.Sprintf("Variable %q is nested more than %d levels deep.", "$"+v.Name.Name, limits.MaxInputDepth),
+				Locations: []errors.Location{v.Loc},
+				Rule:      "MaxInputDepth",
+			})
+		}
+	}
+	return errs
+}
+
+// valueDepth returns the nesting of lists and objects in the value of a variable, but stops
+// counting beyond max.
+func valueDepth(value interface{}, max int) int {
+	if max < 0 {
+		return 0
+	}
+	d := 0
+	switch value := value.(type) {
+	case []interface{}:
+		for _, v := range value {
+			if vd := 1 + valueDepth(v, max-1); vd > d {
+				d = vd
+			}
+		}
+		if d == 0 {
+			d = 1
+		}
+	case map[string]interface{}:
+		for _, v := range value {
+			if vd := 1 + valueDepth(v, max-1); vd > d {
+				d = vd
+			}
+		}
+		if d == 0 {
+			d = 1
+		}
+	}
+	return d
+}
+
+func (c *limitsContext) inputDepthOfSelections(sels []query.Selection) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			for _, arg := range sel.Arguments {
+				c.inputDepth(arg.Value)
+			}
+			c.inputDepthOfDirectives(sel.Directives)
+			c.inputDepthOfSelections(sel.Selections)
+		case *query.InlineFragment:
+			c.inputDepthOfDirectives(sel.Directives)
+			c.inputDepthOfSelections(sel.Selections)
+		case *query.FragmentSpread:
+			c.inputDepthOfDirectives(sel.Directives)
+		}
+	}
+}
+
+func (c *limitsContext) inputDepthOfDirectives(directives common.DirectiveList) {
+	for _, d := range directives {
+		for _, arg := range d.Args {
+			c.inputDepth(arg.Value)
+		}
+	}
+}
+
+// inputDepth checks the nesting of lists and input objects in the literal.
+func (c *limitsContext) inputDepth(lit common.Literal) {
+	if literalDepth(lit) > c.limits.MaxInputDepth {
+		c.addErr(lit.Location(), "MaxInputDepth", "Value is nested more than %d levels deep.", c.limits.MaxInputDepth)
+	}
+}
+
+func literalDepth(lit common.Literal) int {
+	d := 0
+	switch lit := lit.(type) {
+	case *common.ListLit:
+		for _, entry := range lit.Entries {
+			if ld := literalDepth(entry); ld > d {
+				d = ld
+			}
+		}
+		d++
+	case *common.ObjectLit:
+		for _, f := range lit.Fields {
+			if ld := literalDepth(f.Value); ld > d {
+				d = ld
+			}
+		}
+		d++
+	}
+	return d
+}
+
+// depth returns the deepest nesting of fields among the selections, leaving out introspection
+// fields. The depths of fragments are computed once.
+func (c *limitsContext) depth(sels []query.Selection) int {
+	max := 0
+	for _, sel := range sels {
+		d := 0
+		switch sel := sel.(type) {
+		case *query.Field:
+			if strings.HasPrefix(sel.Name.Name, "__") {
+				continue
+			}
+			d = 1 + c.depth(sel.Selections)
+		case *query.InlineFragment:
+			d = c.depth(sel.Selections)
+		case *query.FragmentSpread:
+			d = c.fragmentDepth(sel.Name.Name)
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+func (c *limitsContext) fragmentDepth(name string) int {
+	if d, ok := c.fragmentDepths[name]; ok {
+		return d
+	}
+	frag := c.doc.Fragments.Get(name)
+	if frag == nil {
+		return 0
+	}
+	d := c.depth(frag.Selections)
+	c.fragmentDepths[name] = d
+	return d
+}
+
+// counts are the numbers of aliases and directives of selections.
+type counts struct {
+	aliases, directives int
+}
+
+// count counts the aliases and directives of the selections. The counts of fragments are computed
+// once, so that fragments spread many times don't make counting expensive.
+func (c *limitsContext) count(sels []query.Selection) counts {
+	var n counts
+	for _, sel := range sels {
+		var sub counts
+		switch sel := sel.(type) {
+		case *query.Field:
+			if sel.Alias.Name != sel.Name.Name {
+				n.aliases = saturatedAdd(n.aliases, 1)
+			}
+			n.directives = saturatedAdd(n.directives, len(sel.Directives))
+			sub = c.count(sel.Selections)
+		case *query.InlineFragment:
+			n.directives = saturatedAdd(n.directives, len(sel.Directives))
+			sub = c.count(sel.Selections)
+		case *query.FragmentSpread:
+			n.directives = saturatedAdd(n.directives, len(sel.Directives))
+			sub = c.fragmentCount(sel.Name.Name)
+		}
+		n.aliases = saturatedAdd(n.aliases, sub.aliases)
+		n.directives = saturatedAdd(n.directives, sub.directives)
+	}
+	return n
+}
+
+func (c *limitsContext) fragmentCount(name string) counts {
+	if n, ok := c.fragmentCounts[name]; ok {
+		return n
+	}
+	frag := c.doc.Fragments.Get(name)
+	if frag == nil {
+		return counts{}
+	}
+	n := c.count(frag.Selections)
+	n.directives = saturatedAdd(n.directives, len(frag.Directives))
+	c.fragmentCounts[name] = n
+	return n
+}
+
+// saturatedAdd adds without overflowing, since fragments spreading each other multiply counts.
+func saturatedAdd(a, b int) int {
+	if a > math.MaxInt32-b {
+		return math.MaxInt32
+	}
+	return a + b
+}
+
+type limitsContext struct {
+	doc     *query.Document
+	limits  Limits
+	errs    []*errors.QueryError
+	checked map[fragmentDepth]bool
+
+	fragmentCounts map[string]counts
+	fragmentDepths map[string]int
+}
+
+// fragmentDepth is a fragment spread at the nesting of the fields counted for the limits, whose
+// check has the same result wherever it is spread.
+type fragmentDepth struct {
+	name               string
+	ofTypeDepth, depth int
+}
+
+func (c *limitsContext) addErr(loc errors.Location, rule string, format string, a ...interface{}) {
+	c.errs = append(c.errs, &errors.QueryError{
+		Message:   fmt.Sprintf(format, a...),
+		Locations: []errors.Location{loc},
+		Rule:      rule,
+	})
+}
+
+// rootIntrospection checks the introspection fields among the root selections.
+func (c *limitsContext) rootIntrospection(sels []query.Selection) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			if sel.Name.Name == "__schema" || sel.Name.Name == "__type" {
+				c.introspection(sel.Selections, 0, 0)
+			}
+		case *query.InlineFragment:
+			c.rootIntrospection(sel.Selections)
+		case *query.FragmentSpread:
+			key := fragmentDepth{sel.Name.Name, -1, -1}
+			if frag := c.doc.Fragments.Get(sel.Name.Name); frag != nil && !c.checked[key] {
+				c.checked[key] = true
+				c.rootIntrospection(frag.Selections)
+			}
+		}
+	}
+}
+
+// NonIntrospectionFields returns an error for each root field of the operation other than the
+// introspection fields __schema, __type and __typename.
+func NonIntrospectionFields(doc *query.Document, op *query.Operation) []*errors.QueryError {
+	var errs []*errors.QueryError
+	visited := make(map[string]bool)
+	var check func(sels []query.Selection)
+	check = func(sels []query.Selection) {
+		for _, sel := range sels {
+			switch sel := sel.(type) {
+			case *query.Field:
+				switch sel.Name.Name {
+				case "__schema", "__type", "__typename":
+				default:
+					errs = append(errs, &errors.QueryError{
+						Message:   fmt.Sprintf("Field %q is not an introspection field.", sel.Name.Name),
+						Locations: []errors.Location{sel.Name.Loc},
+					})
+				}
+			case *query.InlineFragment:
+				check(sel.Selections)
+			case *query.FragmentSpread:
+				if frag := doc.Fragments.Get(sel.Name.Name); frag != nil && !visited[sel.Name.Name] {
+					visited[sel.Name.Name] = true
+					check(frag.Selections)
+				}
+			}
+		}
+	}
+	check(op.Selections)
+	return errs
+}
+
+// SelectsIntrospection reports whether the operation selects the __schema or __type field.
+func SelectsIntrospection(doc *query.Document, op *query.Operation) bool {
+	return selectsIntrospection(doc, op.Selections, make(map[string]bool))
+}
+
+func selectsIntrospection(doc *query.Document, sels []query.Selection, visited map[string]bool) bool {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			if sel.Name.Name == "__schema" || sel.Name.Name == "__type" {
+				return true
+			}
+		case *query.InlineFragment:
+			if selectsIntrospection(doc, sel.Selections, visited) {
+				return true
+			}
+		case *query.FragmentSpread:
+			if frag := doc.Fragments.Get(sel.Name.Name); frag != nil && !visited[sel.Name.Name] {
+				visited[sel.Name.Name] = true
+				if selectsIntrospection(doc, frag.Selections, visited) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// introspection checks the nesting of the selections of an introspection type. ofTypeDepth and
+// depth are the nesting of the fields counted for the limits. It stops at the first violation.
+func (c *limitsContext) introspection(sels []query.Selection, ofTypeDepth, depth int) bool {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			ofTypeDepth, depth := ofTypeDepth, depth
+			switch sel.Name.Name {
+			case "ofType":
+				ofTypeDepth++
+				if max := c.limits.MaxIntrospectionOfTypeDepth; max > 0 && ofTypeDepth > max {
+					c.addErr(sel.Alias.Loc, "MaxIntrospectionDepth", `Field "ofType" is nested more than %d times.`, max)
+					return false
+				}
+			case "fields", "inputFields", "interfaces", "possibleTypes":
+				depth++
+				if max := c.limits.MaxIntrospectionDepth; max > 0 && depth > max {
+					c.addErr(sel.Alias.Loc, "MaxIntrospectionDepth", "Field %q exceeds the maximum introspection depth of %d.", sel.Name.Name, max)
+					return false
+				}
+			}
+			if !c.introspection(sel.Selections, ofTypeDepth, depth) {
+				return false
+			}
+		case *query.InlineFragment:
+			if !c.introspection(sel.Selections, ofTypeDepth, depth) {
+				return false
+			}
+		case *query.FragmentSpread:
+			key := fragmentDepth{sel.Name.Name, ofTypeDepth, depth}
+			frag := c.doc.Fragments.Get(sel.Name.Name)
+			if frag == nil || c.checked[key] {
+				continue
+			}
+			c.checked[key] = true
+			if !c.introspection(frag.Selections, ofTypeDepth, depth) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"strings"
+
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// Stats describe the shape of an operation, with fragments expanded where they are spread.
+type Stats struct {
+	// Depth is the deepest nesting of fields, see Limits.MaxDepth.
+	Depth int
+
+	// Complexity is the complexity of the operation, see ValidateComplexity.
+	Complexity int
+
+	// Fields is the number of fields, counting the fields of fragments every time they are spread.
+	Fields int
+
+	// Fragments is the number of distinct fragments spread, and FragmentSpreads the number of
+	// times they are spread, counting nested spreads every time their fragment is spread.
+	Fragments       int
+	FragmentSpreads int
+}
+
+// OperationStats returns the stats of the operation. The operation has to be valid and the
+// variables have to include the default values of the operation.
+func OperationStats(s *schema.Schema, doc *query.Document, op *query.Operation, variables map[string]interface{}) Stats {
+	lc := &limitsContext{doc: doc, fragmentDepths: make(map[string]int)}
+	cc := &complexityContext{schema: s, doc: doc, vars: variables, fragments: make(map[string]int)}
+	sc := &statsContext{doc: doc, fragments: make(map[string]fieldCounts)}
+	counts := sc.count(op.Selections)
+	return Stats{
+		Depth:           lc.depth(op.Selections),
+		Complexity:      cc.selections(op.Selections, s.EntryPoints[strings.ToLower(string(op.Type))]),
+		Fields:          counts.fields,
+		Fragments:       len(sc.fragments),
+		FragmentSpreads: counts.spreads,
+	}
+}
+
+type statsContext struct {
+	doc       *query.Document
+	fragments map[string]fieldCounts // counts of the fragments spread so far
+}
+
+type fieldCounts struct {
+	fields, spreads int
+}
+
+// count counts the fields and fragment spreads of the selections. The counts of fragments are
+// computed once.
+func (c *statsContext) count(sels []query.Selection) fieldCounts {
+	var n fieldCounts
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			sub := c.count(sel.Selections)
+			n.fields = saturatedAdd(n.fields, saturatedAdd(1, sub.fields))
+			n.spreads = saturatedAdd(n.spreads, sub.spreads)
+		case *query.InlineFragment:
+			sub := c.count(sel.Selections)
+			n.fields = saturatedAdd(n.fields, sub.fields)
+			n.spreads = saturatedAdd(n.spreads, sub.spreads)
+		case *query.FragmentSpread:
+			sub, ok := c.fragments[sel.Name.Name]
+			if !ok {
+				if frag := c.doc.Fragments.Get(sel.Name.Name); frag != nil {
+					sub = c.count(frag.Selections)
+					c.fragments[sel.Name.Name] = sub
+				}
+			}
+			n.fields = saturatedAdd(n.fields, sub.fields)
+			n.spreads = saturatedAdd(n.spreads, saturatedAdd(1, sub.spreads))
+		}
+	}
+	return n
+}
@@ -3,7 +3,6 @@ package validation
 import (
 	"fmt"
 	"math"
-	"reflect"
 	"strconv"
 	"strings"
 	"text/scanner"
@@ -16,7 +15,12 @@ import (
 
 type varSet map[*common.InputValue]struct{}
 
-type selectionPair struct{ a, b query.Selection }
+// selectionPair is a pair of selections checked for conflicts, in a context where their parent
+// fields are mutually exclusive or not.
+type selectionPair struct {
+	a, b      query.Selection
+	exclusive bool
+}
 
 type fieldInfo struct {
 	sf     *schema.Field
@@ -31,6 +35,7 @@ type context struct {
 	usedVars         map[*query.Operation]varSet
 	fieldMap         map[*query.Field]fieldInfo
 	overlapValidated map[selectionPair]struct{}
+	visible          func(typeName, fieldName string) bool
 }
 
 func (c *context) addErr(loc errors.Location, rule string, format string, a ...interface{}) {
@@ -50,7 +55,9 @@ type opContext struct {
 	ops []*query.Operation
 }
 
-func Validate(s *schema.Schema, doc *query.Document) []*errors.QueryError {
+// Validate validates the document against the schema. If visible is not nil, types and fields for
+// which it returns false are treated as if they were not part of the schema.
+func Validate(s *schema.Schema, doc *query.Document, visible func(typeName, fieldName string) bool) []*errors.QueryError {
 	c := &context{
 		schema:           s,
 		doc:              doc,
@@ -58,6 +65,7 @@ func Validate(s *schema.Schema, doc *query.Document) []*errors.QueryError {
 		usedVars:         make(map[*query.Operation]varSet),
 		fieldMap:         make(map[*query.Field]fieldInfo),
 		overlapValidated: make(map[selectionPair]struct{}),
+		visible:          visible,
 	}
 
 	opNames := make(nameSet)
@@ -110,8 +118,22 @@ func Validate(s *schema.Schema, doc *query.Document) []*errors.QueryError {
 		default:
 			panic("unreachable")
 		}
+		if entryPoint == nil {
+			c.addErr(op.Loc, "KnownOperationTypes", "Schema is not configured for %ss.", strings.ToLower(string(op.Type)))
+		}
 
 		validateSelectionSet(opc, op.Selections, entryPoint)
+		if op.Type == query.Subscription {
+			names := make(map[string]bool)
+			rootFieldNames(c, op.Selections, names, make(map[string]bool))
+			if len(names) > 1 {
+				if op.Name.Name == "" {
+					c.addErr(op.Loc, "SingleFieldSubscriptions", "Anonymous Subscription must select only one top level field.")
+				} else {
+					c.addErr(op.Loc, "SingleFieldSubscriptions", "Subscription %q must select only one top level field.", op.Name.Name)
+				}
+			}
+		}
 
 		fragUsed := make(map[*query.FragmentDecl]struct{})
 		markUsedFragments(c, op.Selections, fragUsed)
@@ -166,6 +188,24 @@ func Validate(s *schema.Schema, doc *query.Document) []*errors.QueryError {
 	return c.errs
 }
 
+// rootFieldNames adds the response names of the fields of the selections to names, including the
+// ones of fragments.
+func rootFieldNames(c *context, sels []query.Selection, names map[string]bool, spread map[string]bool) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			names[sel.Alias.Name] = true
+		case *query.InlineFragment:
+			rootFieldNames(c, sel.Selections, names, spread)
+		case *query.FragmentSpread:
+			if frag := c.doc.Fragments.Get(sel.Name.Name); frag != nil && !spread[frag.Name.Name] {
+				spread[frag.Name.Name] = true
+				rootFieldNames(c, frag.Selections, names, spread)
+			}
+		}
+	}
+}
+
 func validateSelectionSet(c *opContext, sels []query.Selection, t schema.NamedType) {
 	for _, sel := range sels {
 		validateSelection(c, sel, t)
@@ -173,7 +213,7 @@ func validateSelectionSet(c *opContext, sels []query.Selection, t schema.NamedTy
 
 	for i, a := range sels {
 		for _, b := range sels[i+1:] {
-			c.validateOverlap(a, b, nil, nil)
+			c.validateOverlap(a, b, false, nil, nil)
 		}
 	}
 }
@@ -208,9 +248,9 @@ func validateSelection(c *opContext, sel query.Selection, t schema.NamedType) {
 				Type: c.schema.Types["__Type"],
 			}
 		default:
-			f = fields(t).Get(fieldName)
+			f = c.visibleFields(t).Get(fieldName)
 			if f == nil && t != nil {
-				suggestion := makeSuggestion("Did you mean", fields(t).Names(), fieldName)
+				suggestion := makeSuggestion("Did you mean", c.visibleFields(t).Names(), fieldName)
 				c.addErr(sel.Alias.Loc, "FieldsOnCorrectType", "Cannot query field %q on type %q.%s", fieldName, t, suggestion)
 			}
 		}
@@ -381,16 +421,21 @@ func detectFragmentCycleSel(c *context, sel query.Selection, fragVisited map[*qu
 	}
 }
 
-func (c *context) validateOverlap(a, b query.Selection, reasons *[]string, locs *[]errors.Location) {
+// validateOverlap checks that the fields with the same response name among the selections can be
+// merged. exclusive is true if the parent fields of the selections are never resolved for the same
+// object, e.g. because they are selected on different object types. The reasons and locations of
+// conflicts of subfields are added to reasons and locs, conflicts of the selections are errors if
+// they are nil.
+func (c *context) validateOverlap(a, b query.Selection, exclusive bool, reasons *[]string, locs *[]errors.Location) {
 	if a == b {
 		return
 	}
 
-	if _, ok := c.overlapValidated[selectionPair{a, b}]; ok {
+	if _, ok := c.overlapValidated[selectionPair{a, b, exclusive}]; ok {
 		return
 	}
-	c.overlapValidated[selectionPair{a, b}] = struct{}{}
-	c.overlapValidated[selectionPair{b, a}] = struct{}{}
+	c.overlapValidated[selectionPair{a, b, exclusive}] = struct{}{}
+	c.overlapValidated[selectionPair{b, a, exclusive}] = struct{}{}
 
 	switch a := a.(type) {
 	case *query.Field:
@@ -399,7 +444,7 @@ func (c *context) validateOverlap(a, b query.Selection, reasons *[]string, locs
 			if b.Alias.Loc.Before(a.Alias.Loc) {
 				a, b = b, a
 			}
-			if reasons2, locs2 := c.validateFieldOverlap(a, b); len(reasons2) != 0 {
+			if reasons2, locs2 := c.validateFieldOverlap(a, b, exclusive); len(reasons2) != 0 {
 				locs2 = append(locs2, a.Alias.Loc, b.Alias.Loc)
 				if reasons == nil {
 					c.addErrMultiLoc(locs2, "OverlappingFieldsCanBeMerged", "Fields %q conflict because %s. Use different aliases on the fields to fetch both if this was intentional.", a.Alias.Name, strings.Join(reasons2, " and "))
@@ -413,13 +458,13 @@ func (c *context) validateOverlap(a, b query.Selection, reasons *[]string, locs
 
 		case *query.InlineFragment:
 			for _, sel := range b.Selections {
-				c.validateOverlap(a, sel, reasons, locs)
+				c.validateOverlap(a, sel, exclusive, reasons, locs)
 			}
 
 		case *query.FragmentSpread:
 			if frag := c.doc.Fragments.Get(b.Name.Name); frag != nil {
 				for _, sel := range frag.Selections {
-					c.validateOverlap(a, sel, reasons, locs)
+					c.validateOverlap(a, sel, exclusive, reasons, locs)
 				}
 			}
 
@@ -429,13 +474,13 @@ func (c *context) validateOverlap(a, b query.Selection, reasons *[]string, locs
 
 	case *query.InlineFragment:
 		for _, sel := range a.Selections {
-			c.validateOverlap(sel, b, reasons, locs)
+			c.validateOverlap(sel, b, exclusive, reasons, locs)
 		}
 
 	case *query.FragmentSpread:
 		if frag := c.doc.Fragments.Get(a.Name.Name); frag != nil {
 			for _, sel := range frag.Selections {
-				c.validateOverlap(sel, b, reasons, locs)
+				c.validateOverlap(sel, b, exclusive, reasons, locs)
 			}
 		}
 
@@ -444,7 +489,7 @@ func (c *context) validateOverlap(a, b query.Selection, reasons *[]string, locs
 	}
 }
 
-func (c *context) validateFieldOverlap(a, b *query.Field) ([]string, []errors.Location) {
+func (c *context) validateFieldOverlap(a, b *query.Field, exclusive bool) ([]string, []errors.Location) {
 	if a.Alias.Name != b.Alias.Name {
 		return nil, nil
 	}
@@ -457,9 +502,12 @@ func (c *context) validateFieldOverlap(a, b *query.Field) ([]string, []errors.Lo
 		}
 	}
 
-	at := c.fieldMap[a].parent
-	bt := c.fieldMap[b].parent
-	if at == nil || bt == nil || at == bt {
+	// fields of different object types are never resolved for the same object, fields of an
+	// interface and of an object type may be
+	at, aIsObject := c.fieldMap[a].parent.(*schema.Object)
+	bt, bIsObject := c.fieldMap[b].parent.(*schema.Object)
+	exclusive = exclusive || aIsObject && bIsObject && at != bt
+	if !exclusive {
 		if a.Name.Name != b.Name.Name {
 			return []string{fmt.Sprintf("%s and %s are different fields", a.Name.Name, b.Name.Name)}, nil
 		}
@@ -473,7 +521,7 @@ func (c *context) validateFieldOverlap(a, b *query.Field) ([]string, []errors.Lo
 	var locs []errors.Location
 	for _, a2 := range a.Selections {
 		for _, b2 := range b.Selections {
-			c.validateOverlap(a2, b2, &reasons, &locs)
+			c.validateOverlap(a2, b2, exclusive, &reasons, &locs)
 		}
 	}
 	return reasons, locs
@@ -484,8 +532,9 @@ func argumentsConflict(a, b common.ArgumentList) bool {
 		return true
 	}
 	for _, argA := range a {
+		// the values are compared as written, so that different variables conflict
 		valB, ok := b.Get(argA.Name.Name)
-		if !ok || !reflect.DeepEqual(argA.Value.Value(nil), valB.Value(nil)) {
+		if !ok || argA.Value.String() != valB.String() {
 			return true
 		}
 	}
@@ -503,6 +552,22 @@ func fields(t common.Type) schema.FieldList {
 	}
 }
 
+// visibleFields returns the fields of the type that are visible to the request.
+func (c *context) visibleFields(t common.Type) schema.FieldList {
+	l := fields(t)
+	if c.visible == nil || l == nil {
+		return l
+	}
+	typeName := t.(schema.NamedType).TypeName()
+	visible := make(schema.FieldList, 0, len(l))
+	for _, f := range l {
+		if c.visible(typeName, f.Name) && c.visible(unwrapType(f.Type).TypeName(), "") {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
 func unwrapType(t common.Type) schema.NamedType {
 	if t == nil {
 		return nil
@@ -522,13 +587,21 @@ func unwrapType(t common.Type) schema.NamedType {
 }
 
 func resolveType(c *context, t common.Type) common.Type {
-	t2, err := common.ResolveType(t, c.schema.Resolve)
+	t2, err := common.ResolveType(t, c.resolve)
 	if err != nil {
 		c.errs = append(c.errs, err)
 	}
 	return t2
 }
 
+// resolve is like schema.Resolve, but does not find types that are hidden from the request.
+func (c *context) resolve(name string) common.Type {
+	if c.visible != nil && !c.visible(name, "") {
+		return nil
+	}
+	return c.schema.Resolve(name)
+}
+
 func validateDirectives(c *opContext, loc string, directives common.DirectiveList) {
 	directiveNames := make(nameSet)
 	for _, d := range directives {
@@ -50,7 +50,7 @@ func TestValidate(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			errs := validation.Validate(schemas[test.Schema], d)
+			errs := validation.Validate(schemas[test.Schema], d, nil)
 			got := []*errors.QueryError{}
 			for _, err := range errs {
 				if err.Rule == test.Rule {
@@ -73,3 +73,93 @@ func sortLocations(errs []*errors.QueryError) {
 		sort.Slice(locs, func(i, j int) bool { return locs[i].Before(locs[j]) })
 	}
 }
+
+func TestOverlappingFieldsCanBeMerged(t *testing.T) {
+	s := schema.New()
+	if err := s.Parse(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			pet: Pet
+			dog: Dog
+		}
+
+		interface Pet {
+			name: String
+			friend: Pet
+		}
+
+		type Dog implements Pet {
+			name: String
+			nickname: String
+			friend: Pet
+			doesKnowCommand(command: String): Boolean
+		}
+
+		type Cat implements Pet {
+			name: String
+			nickname: String
+			friend: Pet
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "interface and object parent",
+			query: `{ pet { name ... on Dog { name: nickname } } }`,
+			want:  []string{`Fields "name" conflict because name and nickname are different fields. Use different aliases on the fields to fetch both if this was intentional.`},
+		},
+		{
+			name:  "different object parents",
+			query: `{ pet { ... on Dog { name } ... on Cat { name: nickname } } }`,
+		},
+		{
+			name:  "subfields of different object parents",
+			query: `{ pet { ... on Dog { friend { name } } ... on Cat { friend { name: nickname } } } }`,
+		},
+		{
+			name:  "different variables",
+			query: `query($a: String, $b: String) { dog { doesKnowCommand(command: $a) doesKnowCommand(command: $b) } }`,
+			want:  []string{`Fields "doesKnowCommand" conflict because they have differing arguments. Use different aliases on the fields to fetch both if this was intentional.`},
+		},
+		{
+			name:  "same variable",
+			query: `query($a: String) { dog { doesKnowCommand(command: $a) doesKnowCommand(command: $a) } }`,
+		},
+		{
+			name: "nested across fragments",
+			query: `
+				{ dog { ...A ...B } }
+				fragment A on Dog { friend { ...C } }
+				fragment B on Dog { friend { name: nickname } }
+				fragment C on Pet { ... on Dog { name } }
+			`,
+			want: []string{`Fields "friend" conflict because subfields "name" conflict because nickname and name are different fields. Use different aliases on the fields to fetch both if this was intentional.`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := query.Parse(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, err := range validation.Validate(s, d, nil) {
+				if err.Rule == "OverlappingFieldsCanBeMerged" {
+					got = append(got, err.Message)
+				}
+			}
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("wrong errors\nexpected: %q\ngot:      %q", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// ValidateVariables validates the values of the variables of the operation against their declared
+// types, so that invalid values are reported before execution instead of by the resolvers of the
+// fields using them. The variables are expected to include the default values of the operation.
+// Values of custom scalars are not checked, they are validated by unmarshaling them.
+func ValidateVariables(s *schema.Schema, op *query.Operation, variables map[string]interface{}) []*errors.QueryError {
+	var errs []*errors.QueryError
+	for _, v := range op.Vars {
+		t, err := common.ResolveType(v.Type, s.Resolve)
+		if err != nil {
+			continue // reported by Validate
+		}
+
+		name := "$" + v.Name.Name
+		value, ok := variables[v.Name.Name]
+		if !ok {
+			if _, nonNull := t.(*common.NonNull); nonNull {
+				errs = append(errs, &errors.QueryError{
+					Message:   fmt.Sprintf("Variable %q of required type %q was not provided.", name, t),
+					Locations: []errors.Location{v.Loc},
+				})
+			}
+			continue
+		}
+
+		if ok, reason := validateVariableValue(value, t); !ok {
+			errs = append(errs, &errors.QueryError{
+				Message:   fmt.Sprintf("Variable %q got invalid value %s; %s", name, formatValue(value), reason),
+				Locations: []errors.Location{v.Loc},
+			})
+		}
+	}
+	return errs
+}
+
+func validateVariableValue(v interface{}, t common.Type) (bool, string) {
+	if nn, ok := t.(*common.NonNull); ok {
+		if v == nil {
+			return false, fmt.Sprintf("Expected %q, found null.", t)
+		}
+		t = nn.OfType
+	}
+	if v == nil {
+		return true, ""
+	}
+
+	switch t := t.(type) {
+	case *schema.Scalar:
+		if validateScalarValue(v, t) {
+			return true, ""
+		}
+
+	case *schema.Enum:
+		if s, ok := v.(string); ok {
+			for _, ev := range t.Values {
+				if ev.Name == s {
+					return true, ""
+				}
+			}
+		}
+
+	case *common.List:
+		list, ok := v.([]interface{})
+		if !ok {
+			return validateVariableValue(v, t.OfType) // single value instead of list
+		}
+		for i, entry := range list {
+			if ok, reason := validateVariableValue(entry, t.OfType); !ok {
+				return false, fmt.Sprintf("In element #%d: %s", i, reason)
+			}
+		}
+		return true, ""
+
+	case *schema.InputObject:
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return false, fmt.Sprintf("Expected %q, found not an object.", t)
+		}
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := fields[name]
+			iv := t.Values.Get(name)
+			if iv == nil {
+				return false, fmt.Sprintf("In field %q: Unknown field.", name)
+			}
+			if ok, reason := validateVariableValue(value, iv.Type); !ok {
+				return false, fmt.Sprintf("In field %q: %s", name, reason)
+			}
+		}
+		for _, iv := range t.Values {
+			if _, ok := fields[iv.Name.Name]; !ok {
+				if _, ok := iv.Type.(*common.NonNull); ok && iv.Default == nil {
+					return false, fmt.Sprintf("In field %q: Expected %q, found null.", iv.Name.Name, iv.Type)
+				}
+			}
+		}
+		return true, ""
+	}
+
+	return false, fmt.Sprintf("Expected type %q, found %s.", t, formatValue(v))
+}
+
+// validateScalarValue checks the value of a built-in scalar as decoded from JSON or passed by Go
+// callers.
+func validateScalarValue(v interface{}, t *schema.Scalar) bool {
+	switch t.Name {
+	case "Int":
+		switch v := v.(type) {
+		case int32:
+			return true
+		case int:
+			return v >= math.MinInt32 && v <= math.MaxInt32
+		case float64:
+			return v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32
+		}
+		return false
+	case "Float":
+		switch v := v.(type) {
+		case int32, int:
+			return true
+		case float64:
+			return !math.IsNaN(v) && !math.IsInf(v, 0)
+		}
+		return false
+	case "String":
+		return reflect.TypeOf(v).Kind() == reflect.String
+	case "Boolean":
+		_, ok := v.(bool)
+		return ok
+	case "ID":
+		switch v := v.(type) {
+		case string, int32, int:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return reflect.TypeOf(v).Kind() == reflect.String
+	default:
+		return true
+	}
+}
+
+func formatValue(v interface{}) string {
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/qdentity/graphql-go/errors"
 	"github.com/qdentity/graphql-go/internal/exec/resolvable"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/validation"
 	"github.com/qdentity/graphql-go/introspection"
 )
 
@@ -13,21 +16,65 @@ func (s *Schema) Inspect() *introspection.Schema {
 	return introspection.WrapSchema(s.schema)
 }
 
-// ToJSON encodes the schema in a JSON format used by tools like Relay.
+// SDL returns the schema definition the schema was parsed from, or the one printed with the changes
+// of the handlers of TransformDirective.
+func (s *Schema) SDL() string {
+	return s.sdl
+}
+
+// ToJSON encodes the schema in a JSON format used by tools like Relay. The limits of the queries of
+// clients, e.g. LimitIntrospection, do not apply to it.
 func (s *Schema) ToJSON() ([]byte, error) {
-	result := s.exec(context.Background(), introspectionQuery, "", nil, &resolvable.Schema{
-		Query:  &resolvable.Object{},
+	doc, qErr := query.Parse(introspectionQuery)
+	if qErr != nil {
+		return nil, qErr
+	}
+	ctx := context.Background()
+	r := s.newRequest(doc, nil, s.visibleFunc(ctx))
+	data, errs := r.Execute(ctx, s.introspectionResolvable(), doc.Operations[0])
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+	return json.MarshalIndent(json.RawMessage(data), "", "\t")
+}
+
+// ExecIntrospection executes a query selecting only the introspection fields __schema, __type and
+// __typename, e.g. of a documentation or code generation tool, without the resolver of the schema:
+// it works for schemas parsed without a resolver, and never calls resolvers, which may need an
+// authenticated user or a database connection. Queries selecting other fields fail with a
+// validation error. Hidden types and fields are left out like for Exec, see Visibility.
+func (s *Schema) ExecIntrospection(ctx context.Context, queryString string) *Response {
+	ctx = WithRequestStore(ctx)
+	doc, qErr := s.parseQuery(queryString)
+	if qErr != nil {
+		return &Response{Errors: s.queryErrors(queryString, []*errors.QueryError{qErr})}
+	}
+	op, err := getOperation(doc, "")
+	if err != nil {
+		return &Response{Errors: []*errors.QueryError{errors.Errorf("%s", err).WithCode(errors.CodeBadUserInput)}}
+	}
+	if op.Type != query.Query {
+		return &Response{Errors: []*errors.QueryError{errors.Errorf("introspection requires a query operation").WithCode(errors.CodeValidationFailed)}}
+	}
+	if errs := validation.NonIntrospectionFields(doc, op); len(errs) != 0 {
+		return &Response{Errors: s.queryErrors(queryString, withCode(errs, errors.CodeValidationFailed))}
+	}
+	return s.exec(ctx, queryString, doc, nil, "", nil, s.introspectionResolvable())
+}
+
+// introspectionResolvable returns the resolvers of a schema without a resolver, which resolve
+// only the introspection fields.
+func (s *Schema) introspectionResolvable() *resolvable.Schema {
+	return &resolvable.Schema{
+		Query:  &resolvable.Object{Name: s.schema.EntryPoints["query"].TypeName()},
 		Schema: *s.schema,
-	})
-	if len(result.Errors) != 0 {
-		panic(result.Errors[0])
 	}
-	return json.MarshalIndent(result.Data, "", "\t")
 }
 
 var introspectionQuery = `
   query {
     __schema {
+      description
       queryType { name }
       mutationType { name }
       subscriptionType { name }
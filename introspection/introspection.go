@@ -7,25 +7,66 @@ import (
 	"github.com/qdentity/graphql-go/internal/schema"
 )
 
+// visibility reports whether a type, or the field of a type if fieldName is not empty, is visible.
+// A nil visibility shows everything.
+type visibility func(typeName, fieldName string) bool
+
+func (v visibility) showType(t common.Type) bool {
+	if v == nil {
+		return true
+	}
+	for {
+		switch t2 := t.(type) {
+		case *common.List:
+			t = t2.OfType
+		case *common.NonNull:
+			t = t2.OfType
+		case schema.NamedType:
+			return v(t2.TypeName(), "")
+		default:
+			return true
+		}
+	}
+}
+
+func (v visibility) showField(typeName string, f *schema.Field) bool {
+	return v == nil || (v(typeName, f.Name) && v.showType(f.Type))
+}
+
 type Schema struct {
-	schema *schema.Schema
+	schema  *schema.Schema
+	visible visibility
 }
 
 // WrapSchema is only used internally.
 func WrapSchema(schema *schema.Schema) *Schema {
-	return &Schema{schema}
+	return &Schema{schema: schema}
+}
+
+// WrapSchemaFiltered is only used internally.
+func WrapSchemaFiltered(schema *schema.Schema, visible func(typeName, fieldName string) bool) *Schema {
+	return &Schema{schema: schema, visible: visible}
+}
+
+func (r *Schema) Description() *string {
+	if r.schema.Desc == "" {
+		return nil
+	}
+	return &r.schema.Desc
 }
 
 func (r *Schema) Types() []*Type {
 	var names []string
-	for name := range r.schema.Types {
-		names = append(names, name)
+	for name, t := range r.schema.Types {
+		if r.visible.showType(t) {
+			names = append(names, name)
+		}
 	}
 	sort.Strings(names)
 
 	l := make([]*Type, len(names))
 	for i, name := range names {
-		l[i] = &Type{r.schema.Types[name]}
+		l[i] = &Type{r.schema.Types[name], r.visible}
 	}
 	return l
 }
@@ -39,7 +80,7 @@ func (r *Schema) Directives() []*Directive {
 
 	l := make([]*Directive, len(names))
 	for i, name := range names {
-		l[i] = &Directive{r.schema.Directives[name]}
+		l[i] = &Directive{r.schema.Directives[name], r.visible}
 	}
 	return l
 }
@@ -49,7 +90,7 @@ func (r *Schema) QueryType() *Type {
 	if !ok {
 		return nil
 	}
-	return &Type{t}
+	return &Type{t, r.visible}
 }
 
 func (r *Schema) MutationType() *Type {
@@ -57,7 +98,7 @@ func (r *Schema) MutationType() *Type {
 	if !ok {
 		return nil
 	}
-	return &Type{t}
+	return &Type{t, r.visible}
 }
 
 func (r *Schema) SubscriptionType() *Type {
@@ -65,16 +106,22 @@ func (r *Schema) SubscriptionType() *Type {
 	if !ok {
 		return nil
 	}
-	return &Type{t}
+	return &Type{t, r.visible}
 }
 
 type Type struct {
-	typ common.Type
+	typ     common.Type
+	visible visibility
 }
 
 // WrapType is only used internally.
 func WrapType(typ common.Type) *Type {
-	return &Type{typ}
+	return &Type{typ: typ}
+}
+
+// WrapTypeFiltered is only used internally.
+func WrapTypeFiltered(typ common.Type, visible func(typeName, fieldName string) bool) *Type {
+	return &Type{typ, visible}
 }
 
 func (r *Type) Kind() string {
@@ -102,19 +149,25 @@ func (r *Type) Description() *string {
 
 func (r *Type) Fields(args *struct{ IncludeDeprecated bool }) *[]*Field {
 	var fields schema.FieldList
+	var typeName string
 	switch t := r.typ.(type) {
 	case *schema.Object:
 		fields = t.Fields
+		typeName = t.Name
 	case *schema.Interface:
 		fields = t.Fields
+		typeName = t.Name
 	default:
 		return nil
 	}
 
 	var l []*Field
 	for _, f := range fields {
+		if !r.visible.showField(typeName, f) {
+			continue
+		}
 		if d := f.Directives.Get("deprecated"); d == nil || args.IncludeDeprecated {
-			l = append(l, &Field{f})
+			l = append(l, &Field{f, r.visible})
 		}
 	}
 	return &l
@@ -126,9 +179,11 @@ func (r *Type) Interfaces() *[]*Type {
 		return nil
 	}
 
-	l := make([]*Type, len(t.Interfaces))
-	for i, intf := range t.Interfaces {
-		l[i] = &Type{intf}
+	l := make([]*Type, 0, len(t.Interfaces))
+	for _, intf := range t.Interfaces {
+		if r.visible.showType(intf) {
+			l = append(l, &Type{intf, r.visible})
+		}
 	}
 	return &l
 }
@@ -144,9 +199,11 @@ func (r *Type) PossibleTypes() *[]*Type {
 		return nil
 	}
 
-	l := make([]*Type, len(possibleTypes))
-	for i, intf := range possibleTypes {
-		l[i] = &Type{intf}
+	l := make([]*Type, 0, len(possibleTypes))
+	for _, intf := range possibleTypes {
+		if r.visible.showType(intf) {
+			l = append(l, &Type{intf, r.visible})
+		}
 	}
 	return &l
 }
@@ -172,26 +229,35 @@ func (r *Type) InputFields() *[]*InputValue {
 		return nil
 	}
 
-	l := make([]*InputValue, len(t.Values))
-	for i, v := range t.Values {
-		l[i] = &InputValue{v}
-	}
+	l := inputValues(t.Values, r.visible)
 	return &l
 }
 
+// inputValues returns the input values whose types are visible.
+func inputValues(values common.InputValueList, visible visibility) []*InputValue {
+	l := make([]*InputValue, 0, len(values))
+	for _, v := range values {
+		if visible.showType(v.Type) {
+			l = append(l, &InputValue{v, visible})
+		}
+	}
+	return l
+}
+
 func (r *Type) OfType() *Type {
 	switch t := r.typ.(type) {
 	case *common.List:
-		return &Type{t.OfType}
+		return &Type{t.OfType, r.visible}
 	case *common.NonNull:
-		return &Type{t.OfType}
+		return &Type{t.OfType, r.visible}
 	default:
 		return nil
 	}
 }
 
 type Field struct {
-	field *schema.Field
+	field   *schema.Field
+	visible visibility
 }
 
 func (r *Field) Name() string {
@@ -206,15 +272,11 @@ func (r *Field) Description() *string {
 }
 
 func (r *Field) Args() []*InputValue {
-	l := make([]*InputValue, len(r.field.Args))
-	for i, v := range r.field.Args {
-		l[i] = &InputValue{v}
-	}
-	return l
+	return inputValues(r.field.Args, r.visible)
 }
 
 func (r *Field) Type() *Type {
-	return &Type{r.field.Type}
+	return &Type{r.field.Type, r.visible}
 }
 
 func (r *Field) IsDeprecated() bool {
@@ -231,7 +293,8 @@ func (r *Field) DeprecationReason() *string {
 }
 
 type InputValue struct {
-	value *common.InputValue
+	value   *common.InputValue
+	visible visibility
 }
 
 func (r *InputValue) Name() string {
@@ -246,7 +309,7 @@ func (r *InputValue) Description() *string {
 }
 
 func (r *InputValue) Type() *Type {
-	return &Type{r.value.Type}
+	return &Type{r.value.Type, r.visible}
 }
 
 func (r *InputValue) DefaultValue() *string {
@@ -287,6 +350,7 @@ func (r *EnumValue) DeprecationReason() *string {
 
 type Directive struct {
 	directive *schema.DirectiveDecl
+	visible   visibility
 }
 
 func (r *Directive) Name() string {
@@ -305,9 +369,5 @@ func (r *Directive) Locations() []string {
 }
 
 func (r *Directive) Args() []*InputValue {
-	l := make([]*InputValue, len(r.directive.Args))
-	for i, v := range r.directive.Args {
-		l[i] = &InputValue{v}
-	}
-	return l
+	return inputValues(r.directive.Args, r.visible)
 }
@@ -0,0 +1,129 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/pubsub"
+)
+
+// DefaultLiveTopic is the topic of the invalidations of live queries, see LiveQueryOptions.
+const DefaultLiveTopic = "graphql.live"
+
+// LiveQueryOptions configures live queries, see LiveQueries.
+type LiveQueryOptions struct {
+	// PubSub delivers the invalidations published with Invalidate to all instances of the service.
+	// It is required.
+	PubSub *pubsub.PubSub
+
+	// Topic is the topic of the invalidations, DefaultLiveTopic if empty.
+	Topic string
+
+	// Debounce is how long a live query waits after an invalidation before it is executed again.
+	// Further invalidations in the meantime are covered by the same execution.
+	Debounce time.Duration
+}
+
+// LiveQueries enables experimental live queries: queries with the @live directive, declared in the
+// schema as "directive @live on QUERY", passed to Subscribe. Their first response is sent right
+// away. The query keeps track of the objects it resolved with a selected id field, and it is
+// executed again when one of them is invalidated with Invalidate. A response is sent whenever the
+// result differs from the previous one.
+func LiveQueries(opts LiveQueryOptions) SchemaOpt {
+	return func(s *Schema) {
+		if opts.Topic == "" {
+			opts.Topic = DefaultLiveTopic
+		}
+		s.live = &opts
+	}
+}
+
+// Invalidate drops the cached responses with the objects of the keys, see CacheResponses, and makes
+// the live queries that resolved them execute again. The key of an object is "Type:id", with the
+// type declaring the id field and its value. A key "Type" invalidates all objects of the type.
+func (s *Schema) Invalidate(ctx context.Context, keys ...string) error {
+	if s.responseCache != nil {
+		for _, key := range keys {
+			s.responseCache.invalidate(key)
+		}
+	}
+	if s.live == nil {
+		return nil
+	}
+	for _, key := range keys {
+		if err := s.live.PubSub.Publish(ctx, s.live.Topic, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribeLive executes the live query and executes it again after invalidations.
+func (s *Schema) subscribeLive(ctx context.Context, queryString string, prepared *preparedQuery, operationName string, variables map[string]interface{}) <-chan *Response {
+	if s.live == nil {
+		return singleResponse(&Response{Errors: []*errors.QueryError{errors.Errorf("live queries are not enabled")}})
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	ch, err := s.live.PubSub.Subscribe(ctx, s.live.Topic, reflect.TypeOf(""))
+	if err != nil {
+		cancel()
+		qErr := errors.Errorf("%s", err)
+		qErr.OriginalError = err
+		return singleResponse(&Response{Errors: []*errors.QueryError{qErr}})
+	}
+	invalidations := ch.(<-chan string)
+
+	responses := make(chan *Response)
+	go func() {
+		defer cancel()
+		defer close(responses)
+
+		var entities *entitySet
+		var last []byte
+		// execute sends the result of the query unless it is unchanged. It returns false if the
+		// live query has ended.
+		execute := func() bool {
+			entities = newEntitySet()
+			resp := s.exec(context.WithValue(ctx, entitySetKey{}, entities), queryString, nil, prepared, operationName, variables, s.res)
+			result, _ := json.Marshal(resp)
+			if bytes.Equal(result, last) {
+				return true
+			}
+			last = result
+			select {
+			case responses <- resp:
+			case <-ctx.Done():
+				return false
+			}
+			return resp.Data != nil // the query failed before execution otherwise
+		}
+
+		if !execute() {
+			return
+		}
+		var debounce <-chan time.Time
+		for {
+			select {
+			case key, ok := <-invalidations:
+				if !ok {
+					return
+				}
+				if debounce == nil && entities.touched(key) {
+					debounce = time.After(s.live.Debounce)
+				}
+			case <-debounce:
+				debounce = nil
+				if !execute() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return responses
+}
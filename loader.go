@@ -0,0 +1,157 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/internal/exec"
+)
+
+// BatchFunc loads the values of the keys, in the same order. errs is either nil, or has the error
+// of each key, or a single error for all of them.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (values []V, errs []error)
+
+// Loader loads values by key in batches, e.g. to resolve the author of each book of a list with a
+// single query. A Loader is usually declared once, e.g. in a package variable, and used by all
+// requests: each request has its own cache and batches, so that a key is loaded at most once per
+// request, and the values of a request are never served to another.
+//
+// The keys of a batch are loaded when every field resolving concurrently waits for a loader, or
+// for its children, so a batch collects the keys of all the entries of a list. The batch is loaded
+// earlier if it has MaxBatch keys, or MaxWait has passed since its first key. The keys are only
+// batched during Exec, ExecAST and the events of Subscribe, and not with the Synchronous option,
+// since fields are not resolved concurrently then. Otherwise each key is loaded on its own.
+type Loader[K comparable, V any] struct {
+	batch BatchFunc[K, V]
+
+	// MaxBatch, if positive, is the maximum number of keys of a batch.
+	MaxBatch int
+
+	// MaxWait, if positive, is the maximum time to wait for more keys once a batch has one.
+	MaxWait time.Duration
+}
+
+// NewLoader returns a loader loading the values with batch.
+func NewLoader[K comparable, V any](batch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{batch: batch}
+}
+
+// loaderState is the state of a loader during a request.
+type loaderState[K comparable, V any] struct {
+	mu      sync.Mutex
+	results map[K]*loaderResult[V]
+	batch   *loaderBatch[K, V] // collecting keys
+}
+
+type loaderResult[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type loaderBatch[K comparable, V any] struct {
+	keys       []K
+	results    []*loaderResult[V]
+	timer      *time.Timer
+	dispatched bool
+}
+
+// Load returns the value of the key, waiting for the batch of the key to be loaded. The values and
+// errors are cached for the rest of the request.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	loaders := exec.LoadersOf(ctx)
+	if loaders == nil {
+		res := &loaderResult[V]{done: make(chan struct{})}
+		l.load(ctx, []K{key}, []*loaderResult[V]{res})
+		return res.value, res.err
+	}
+
+	st := loaders.State(l, func() interface{} {
+		return &loaderState[K, V]{results: make(map[K]*loaderResult[V])}
+	}).(*loaderState[K, V])
+	st.mu.Lock()
+	res, ok := st.results[key]
+	if !ok {
+		res = &loaderResult[V]{done: make(chan struct{})}
+		st.results[key] = res
+		b := st.batch
+		if b == nil {
+			b = &loaderBatch[K, V]{}
+			st.batch = b
+			dispatch := func() { l.dispatch(loaders.Context(), st, b) }
+			loaders.Schedule(dispatch)
+			if l.MaxWait > 0 {
+				b.timer = time.AfterFunc(l.MaxWait, dispatch)
+			}
+		}
+		b.keys = append(b.keys, key)
+		b.results = append(b.results, res)
+		if l.MaxBatch > 0 && len(b.keys) >= l.MaxBatch {
+			st.batch = nil // the next key starts a new batch
+			st.mu.Unlock()
+			l.dispatch(loaders.Context(), st, b)
+			return res.value, res.err
+		}
+	}
+	st.mu.Unlock()
+
+	loaders.Wait(res.done)
+	return res.value, res.err
+}
+
+// dispatch loads the batch unless it already has been.
+func (l *Loader[K, V]) dispatch(ctx context.Context, st *loaderState[K, V], b *loaderBatch[K, V]) {
+	st.mu.Lock()
+	if b.dispatched {
+		st.mu.Unlock()
+		return
+	}
+	b.dispatched = true
+	if st.batch == b {
+		st.batch = nil
+	}
+	st.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	l.load(ctx, b.keys, b.results)
+}
+
+// load loads the keys and sets their results. A panic of the batch function is returned as the
+// error of every key.
+func (l *Loader[K, V]) load(ctx context.Context, keys []K, results []*loaderResult[V]) {
+	var values []V
+	var errs []error
+	defer func() {
+		if v := recover(); v != nil {
+			err, ok := v.(error)
+			if ok {
+				err = perrors.Wrap(err, "panic in loader")
+			} else {
+				err = perrors.Errorf("panic in loader: %v", v)
+			}
+			values, errs = nil, []error{err}
+		}
+		for i, res := range results {
+			switch {
+			case len(errs) == 1:
+				res.err = errs[0]
+			case len(errs) == len(keys):
+				res.err = errs[i]
+			case errs != nil:
+				res.err = perrors.Errorf("loader returned %d errors for %d keys", len(errs), len(keys))
+			}
+			switch {
+			case res.err != nil:
+			case len(values) == len(keys):
+				res.value = values[i]
+			default:
+				res.err = perrors.Errorf("loader returned %d values for %d keys", len(values), len(keys))
+			}
+			close(res.done)
+		}
+	}()
+	values, errs = l.batch(ctx, keys)
+}
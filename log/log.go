@@ -2,8 +2,13 @@ package log
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"runtime"
+	"time"
+
+	"github.com/qdentity/graphql-go/errors"
 )
 
 // Logger is the interface used to log panics that occur durring query execution. It is setable via graphql.ParseSchema
@@ -11,6 +16,20 @@ type Logger interface {
 	LogPanic(ctx context.Context, value interface{})
 }
 
+// StackLogger is implemented by loggers that also want to know where a panic occurred. If the
+// Logger of a schema implements it, LogPanicStack is called instead of LogPanic with the path of the
+// field whose resolution panicked (nil outside of a field) and the stack captured at the panic.
+type StackLogger interface {
+	LogPanicStack(ctx context.Context, value interface{}, path []interface{}, stack []byte)
+}
+
+// WarningLogger is implemented by loggers that also want to know about the warnings of responses,
+// e.g. the use of deprecated fields or slow resolvers. If the Logger of a schema implements it,
+// LogWarning is called with every warning added to the extensions of a response.
+type WarningLogger interface {
+	LogWarning(ctx context.Context, warning *errors.QueryError)
+}
+
 // DefaultLogger is the default logger used to log panics that occur durring query execution
 type DefaultLogger struct{}
 
@@ -21,3 +40,68 @@ func (l *DefaultLogger) LogPanic(_ context.Context, value interface{}) {
 	buf = buf[:runtime.Stack(buf, false)]
 	log.Printf("graphql: panic occurred: %v\n%s", value, buf)
 }
+
+// LogPanicStack logs a recovered panic value with the path of the field and the stack of the panic
+func (l *DefaultLogger) LogPanicStack(_ context.Context, value interface{}, path []interface{}, stack []byte) {
+	log.Printf("graphql: panic occurred at %v: %v\n%s", path, value, stack)
+}
+
+// LogWarning logs a warning of a response
+func (l *DefaultLogger) LogWarning(_ context.Context, warning *errors.QueryError) {
+	log.Printf("graphql: warning: %s", warning)
+}
+
+// CallbackPanic is the value passed to the Logger for a panic of a callback of the schema, e.g. of
+// its tracer. Such panics are recovered, so that a faulty integration can not abort the execution
+// of queries. Panics of the Logger itself are written to the standard logger.
+type CallbackPanic struct {
+	// Component is the callback that panicked: "tracer", "logger", "panic handler" or
+	// "operation logger".
+	Component string
+
+	// Value is the recovered value.
+	Value interface{}
+}
+
+func (p *CallbackPanic) String() string {
+	return fmt.Sprintf("panic in %s: %v", p.Component, p.Value)
+}
+
+// Redacted replaces the values of sensitive variables in logged operations.
+const Redacted = "[REDACTED]"
+
+// Operation describes an executed operation for logging, see OperationLogger.
+type Operation struct {
+	// Name is the name of the operation, empty for anonymous operations.
+	Name string
+
+	// Type is "query" or "mutation", empty if the request failed before the operation was known.
+	Type string
+
+	// Fingerprint identifies the operation independent of its formatting, empty if the request
+	// failed before the operation was known.
+	Fingerprint string
+
+	// Variables are the variables of the request with the values of sensitive arguments and input
+	// fields replaced by Redacted.
+	Variables map[string]interface{}
+
+	Duration time.Duration
+	Errors   int
+}
+
+// OperationLogger logs executed operations, e.g. for auditing. It is set with
+// graphql.LogOperations.
+type OperationLogger interface {
+	LogOperation(ctx context.Context, op *Operation)
+}
+
+// LogOperation logs an executed operation as a line of JSON.
+func (l *DefaultLogger) LogOperation(_ context.Context, op *Operation) {
+	data, err := json.Marshal(op)
+	if err != nil {
+		log.Printf("graphql: operation %q: %s", op.Name, err)
+		return
+	}
+	log.Printf("graphql: operation %s", data)
+}
@@ -0,0 +1,221 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+	"github.com/qdentity/graphql-go/log"
+)
+
+// LogOperations logs every executed operation with the logger: its name, fingerprint, duration,
+// number of errors and variables. The values of variables passed to sensitive arguments and input
+// fields are redacted: those marked with a @sensitive directive in the schema, declared as
+// "directive @sensitive on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION", and those given as
+// "Type.field.argument" or "InputType.field". Variables not passed to arguments, e.g. those only
+// used in directives or not declared by the operation, are redacted as well, and so are all
+// variables if the request fails before its operation is known.
+func LogOperations(logger log.OperationLogger, sensitive ...string) SchemaOpt {
+	return func(s *Schema) {
+		s.operationLogger = logger
+		s.sensitive = make(map[string]bool, len(sensitive))
+		for _, path := range sensitive {
+			s.sensitive[path] = true
+		}
+	}
+}
+
+// logOperation logs the request, doc and op are nil if it failed before they were known.
+func (s *Schema) logOperation(ctx context.Context, doc *query.Document, op *query.Operation, operationName string, variables map[string]interface{}, duration time.Duration, resp *Response) {
+	defer s.recoverCallback(ctx, "operation logger")
+	logged := &log.Operation{
+		Name:     operationName,
+		Duration: duration,
+		Errors:   len(resp.Errors),
+	}
+	if op == nil {
+		if len(variables) != 0 {
+			logged.Variables = make(map[string]interface{}, len(variables))
+			for name := range variables {
+				logged.Variables[name] = log.Redacted
+			}
+		}
+		s.operationLogger.LogOperation(ctx, logged)
+		return
+	}
+
+	logged.Name = op.Name.Name
+	logged.Type = strings.ToLower(string(op.Type))
+	sum := sha256.Sum256([]byte(query.PrintOperation(doc, op)))
+	logged.Fingerprint = hex.EncodeToString(sum[:])
+	if len(variables) != 0 {
+		r := &redactor{
+			schema:    s.schema,
+			doc:       doc,
+			sensitive: s.sensitive,
+			values:    variables,
+			vars:      make(map[string]interface{}, len(variables)),
+			redacted:  make(map[string]bool),
+			visited:   make(map[string]bool),
+		}
+		for name := range variables {
+			r.vars[name] = log.Redacted
+		}
+		r.selections(op.Selections, s.schema.EntryPoints[logged.Type])
+		logged.Variables = r.vars
+	}
+	s.operationLogger.LogOperation(ctx, logged)
+}
+
+// redactor redacts the values of the variables passed to sensitive arguments and input fields.
+type redactor struct {
+	schema    *schema.Schema
+	doc       *query.Document
+	sensitive map[string]bool
+	values    map[string]interface{} // the variables of the request
+	vars      map[string]interface{} // the logged variables, redacted unless passed to an argument
+	redacted  map[string]bool        // variables passed to a sensitive argument or input field
+	visited   map[string]bool        // fragments
+}
+
+func (r *redactor) isSensitive(v *common.InputValue, path string) bool {
+	return v.Directives.Get("sensitive") != nil || r.sensitive[path]
+}
+
+func (r *redactor) selections(sels []query.Selection, t common.Type) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *query.Field:
+			var fields schema.FieldList
+			switch t := t.(type) {
+			case *schema.Object:
+				fields = t.Fields
+			case *schema.Interface:
+				fields = t.Fields
+			}
+			f := fields.Get(sel.Name.Name)
+			if f == nil {
+				continue
+			}
+			for _, arg := range sel.Arguments {
+				if decl := f.Args.Get(arg.Name.Name); decl != nil {
+					path := t.(schema.NamedType).TypeName() + "." + f.Name + "." + decl.Name.Name
+					r.literal(arg.Value, decl.Type, r.isSensitive(decl, path))
+				}
+			}
+			r.selections(sel.Selections, namedType(f.Type))
+		case *query.InlineFragment:
+			on := t
+			if sel.On.Name != "" {
+				on = r.schema.Types[sel.On.Name]
+			}
+			r.selections(sel.Selections, on)
+		case *query.FragmentSpread:
+			if frag := r.doc.Fragments.Get(sel.Name.Name); frag != nil && !r.visited[frag.Name.Name] {
+				r.visited[frag.Name.Name] = true
+				r.selections(frag.Selections, r.schema.Types[frag.On.Name])
+			}
+		}
+	}
+}
+
+// literal redacts the variables used in the literal of type t, all of them if sensitive is true.
+func (r *redactor) literal(lit common.Literal, t common.Type, sensitive bool) {
+	if nn, ok := t.(*common.NonNull); ok {
+		t = nn.OfType
+	}
+	switch lit := lit.(type) {
+	case *common.Variable:
+		value, ok := r.values[lit.Name]
+		if !ok {
+			return
+		}
+		if sensitive {
+			r.redacted[lit.Name] = true
+			r.vars[lit.Name] = log.Redacted
+			return
+		}
+		if !r.redacted[lit.Name] {
+			r.vars[lit.Name] = r.value(value, t)
+		}
+	case *common.ListLit:
+		elem := t
+		if l, ok := t.(*common.List); ok {
+			elem = l.OfType
+		}
+		for _, entry := range lit.Entries {
+			r.literal(entry, elem, sensitive)
+		}
+	case *common.ObjectLit:
+		if l, ok := t.(*common.List); ok {
+			r.literal(lit, l.OfType, sensitive) // a single value is coerced to a list
+			return
+		}
+		io, ok := t.(*schema.InputObject)
+		if !ok {
+			return
+		}
+		for _, field := range lit.Fields {
+			if decl := io.Values.Get(field.Name.Name); decl != nil {
+				r.literal(field.Value, decl.Type, sensitive || r.isSensitive(decl, io.Name+"."+decl.Name.Name))
+			}
+		}
+	}
+}
+
+// value returns a copy of the value of type t with the sensitive input fields redacted.
+func (r *redactor) value(v interface{}, t common.Type) interface{} {
+	if nn, ok := t.(*common.NonNull); ok {
+		t = nn.OfType
+	}
+	switch t := t.(type) {
+	case *common.List:
+		entries, ok := v.([]interface{})
+		if !ok {
+			return r.value(v, t.OfType) // a single value is coerced to a list
+		}
+		redacted := make([]interface{}, len(entries))
+		for i, entry := range entries {
+			redacted[i] = r.value(entry, t.OfType)
+		}
+		return redacted
+	case *schema.InputObject:
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		redacted := make(map[string]interface{}, len(fields))
+		for name, value := range fields {
+			decl := t.Values.Get(name)
+			switch {
+			case decl == nil:
+				redacted[name] = value
+			case r.isSensitive(decl, t.Name+"."+name):
+				redacted[name] = log.Redacted
+			default:
+				redacted[name] = r.value(value, decl.Type)
+			}
+		}
+		return redacted
+	}
+	return v
+}
+
+// namedType returns the named type of a possibly wrapped type.
+func namedType(t common.Type) common.Type {
+	for {
+		switch wrapped := t.(type) {
+		case *common.NonNull:
+			t = wrapped.OfType
+		case *common.List:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
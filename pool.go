@@ -0,0 +1,77 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/qdentity/graphql-go/internal/exec"
+)
+
+// ResolverPool reuses resolver objects of type T across requests, e.g. the root resolvers returned
+// by the constructor of a schema or the resolvers of the entities of large lists, to save their
+// allocations. The objects taken from the pool during a request are reset with their Reset method
+// and put back when the request is done, so they must not be referenced afterwards, e.g. by a
+// goroutine started by a resolver, nor cached, e.g. by CacheResolvers. A ResolverPool is usually
+// declared once, e.g. in a package variable, and used by all requests:
+//
+//	var userResolvers = graphql.NewResolverPool[userResolver]()
+//
+//	func (r *Resolver) User(ctx context.Context) *userResolver {
+//		u := userResolvers.Get(ctx)
+//		u.id = ...
+//		return u
+//	}
+//
+// The objects are only put back for Exec, ExecAST and the events of Subscribe. Otherwise Get
+// returns a new object, which is left to the garbage collector.
+type ResolverPool[T any, P interface {
+	*T
+	Reset()
+}] struct {
+	pool sync.Pool
+}
+
+// NewResolverPool returns a pool of resolvers of type *T, which has to have a Reset method
+// resetting the resolver to its zero state.
+func NewResolverPool[T any, P interface {
+	*T
+	Reset()
+}]() *ResolverPool[T, P] {
+	return &ResolverPool[T, P]{pool: sync.Pool{New: func() interface{} { return P(new(T)) }}}
+}
+
+// pooledResolvers are the resolvers taken from a pool during a request.
+type pooledResolvers[P any] struct {
+	mu        sync.Mutex
+	resolvers []P
+}
+
+// Get returns a resolver in its zero state, which is put back into the pool when the request
+// executed with ctx is done.
+func (p *ResolverPool[T, P]) Get(ctx context.Context) P {
+	loaders := exec.LoadersOf(ctx)
+	if loaders == nil {
+		return P(new(T))
+	}
+	resolver := p.pool.Get().(P)
+	created := false
+	taken := loaders.State(p, func() interface{} {
+		created = true
+		return &pooledResolvers[P]{}
+	}).(*pooledResolvers[P])
+	if created {
+		loaders.OnRelease(func() { p.put(taken) })
+	}
+	taken.mu.Lock()
+	taken.resolvers = append(taken.resolvers, resolver)
+	taken.mu.Unlock()
+	return resolver
+}
+
+// put resets the resolvers taken during a request and puts them back into the pool.
+func (p *ResolverPool[T, P]) put(taken *pooledResolvers[P]) {
+	for _, resolver := range taken.resolvers {
+		resolver.Reset()
+		p.pool.Put(resolver)
+	}
+}
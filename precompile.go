@@ -0,0 +1,144 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/internal/query"
+)
+
+// compiledPlan is the serialized form of the documents validated by PrecompileDocuments.
+type compiledPlan struct {
+	// Schema is the fingerprint of the schema and the options the documents were validated with.
+	Schema string `json:"schema"`
+
+	// Documents are the hashes of the valid documents.
+	Documents []string `json:"documents"`
+}
+
+// PrecompileDocuments prepares the execution of trusted documents, e.g. those of a persisted query
+// manifest, at build time or startup instead of on their first request: they are parsed and
+// validated once, and requests with exactly their source skip both. If the schema has an operation
+// cache, see OperationCacheSize, it is filled with the selections of their operations for the
+// default values of the variables.
+//
+// It returns a plan listing the valid documents, which can be stored, e.g. on disk next to the
+// manifest, and passed to later calls: documents it lists are not validated again, as long as the
+// schema and the options affecting validation are unchanged. Otherwise the plan is ignored. The
+// plan may be nil. It returns an error for an invalid document, after preparing the valid ones.
+//
+// Requests of which the visibility of types and fields is restricted, see Visibility, and
+// documents with warnings, see WarnOnly, are still validated with each request.
+func (s *Schema) PrecompileDocuments(docs []string, plan []byte) ([]byte, error) {
+	fingerprint := s.validationFingerprint()
+	valid := make(map[string]bool)
+	if len(plan) != 0 {
+		var p compiledPlan
+		if err := json.Unmarshal(plan, &p); err != nil {
+			return nil, perrors.Wrap(err, "invalid plan")
+		}
+		if p.Schema == fingerprint {
+			for _, h := range p.Documents {
+				valid[h] = true
+			}
+		}
+	}
+
+	out := compiledPlan{Schema: fingerprint, Documents: []string{}}
+	var invalid []string
+	for _, queryString := range docs {
+		doc, qErr := s.parseQuery(queryString)
+		if qErr != nil {
+			invalid = append(invalid, fmt.Sprintf("%.40q: %s", queryString, qErr))
+			continue
+		}
+		h := documentHash(queryString)
+		if !valid[h] {
+			errs, warnings := s.validate(doc, nil)
+			if len(errs) != 0 {
+				invalid = append(invalid, fmt.Sprintf("%.40q: %s", queryString, errs[0]))
+				continue
+			}
+			if len(warnings) != 0 {
+				continue
+			}
+		}
+		s.addCompiled(queryString, doc)
+		out.Documents = append(out.Documents, h)
+	}
+	sort.Strings(out.Documents)
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(invalid) != 0 {
+		return data, perrors.Errorf("invalid documents:\n\t%s", strings.Join(invalid, "\n\t"))
+	}
+	return data, nil
+}
+
+func (s *Schema) addCompiled(queryString string, doc *query.Document) {
+	s.compiledMu.Lock()
+	if s.compiled == nil {
+		s.compiled = make(map[string]*query.Document)
+	}
+	s.compiled[queryString] = doc
+	s.compiledMu.Unlock()
+
+	if s.operationCache == nil || s.res == nil {
+		return
+	}
+	for _, op := range doc.Operations {
+		r := s.newRequest(doc, withVariableDefaults(op, nil), nil)
+		s.operationCache.Apply(&r.Request, s.res, op, op.Name.Name+"\x00"+queryString)
+	}
+}
+
+// compiledDocument returns the parsed document if it was validated by PrecompileDocuments.
+func (s *Schema) compiledDocument(queryString string) (*query.Document, bool) {
+	s.compiledMu.RLock()
+	defer s.compiledMu.RUnlock()
+	doc, ok := s.compiled[queryString]
+	return doc, ok
+}
+
+// parseAndValidate parses and validates the document, unless it was precompiled and the request
+// sees the whole schema.
+func (s *Schema) parseAndValidate(queryString string, visible func(typeName, fieldName string) bool) (doc *query.Document, warnings []*errors.QueryError, errs []*errors.QueryError) {
+	if visible == nil {
+		if doc, ok := s.compiledDocument(queryString); ok {
+			return doc, nil, nil
+		}
+	}
+	doc, qErr := s.parseQuery(queryString)
+	if qErr != nil {
+		return nil, nil, []*errors.QueryError{qErr}
+	}
+	errs, warnings = s.validate(doc, visible)
+	return doc, warnings, errs
+}
+
+// validationFingerprint identifies the schema and the options affecting the validation of
+// documents.
+func (s *Schema) validationFingerprint() string {
+	var rules []string
+	for rule := range s.warnRules {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%+v\x00%v", s.sdl, s.limits, rules)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func documentHash(queryString string) string {
+	h := sha256.Sum256([]byte(queryString))
+	return hex.EncodeToString(h[:])
+}
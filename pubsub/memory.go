@@ -0,0 +1,97 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is a Backend delivering the messages within the process. Publish waits until each
+// subscriber has received the message or its subscription has ended, so a subscriber that falls
+// behind by more than Buffer messages holds up the publisher.
+type Memory struct {
+	// Buffer is the number of messages a subscriber may fall behind.
+	Buffer int
+
+	mu   sync.Mutex
+	subs map[string]map[*memorySubscriber]struct{} // by topic
+}
+
+type memorySubscriber struct {
+	ch   chan []byte
+	done <-chan struct{}
+
+	mu     sync.Mutex // held while sending, so ch is not closed during a send
+	closed bool
+}
+
+// NewMemory returns an in-memory backend.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Publish implements Backend. It returns the error of ctx if it is done before all subscribers
+// have received the message.
+func (m *Memory) Publish(ctx context.Context, topic string, payload []byte) error {
+	m.mu.Lock()
+	subs := make([]*memorySubscriber, 0, len(m.subs[topic]))
+	for sub := range m.subs[topic] {
+		subs = append(subs, sub)
+	}
+	m.mu.Unlock()
+
+	for _, sub := range subs {
+		if err := sub.send(ctx, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe implements Backend.
+func (m *Memory) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	sub := &memorySubscriber{
+		ch:   make(chan []byte, m.Buffer),
+		done: ctx.Done(),
+	}
+
+	m.mu.Lock()
+	if m.subs == nil {
+		m.subs = make(map[string]map[*memorySubscriber]struct{})
+	}
+	if m.subs[topic] == nil {
+		m.subs[topic] = make(map[*memorySubscriber]struct{})
+	}
+	m.subs[topic][sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-sub.done
+		m.mu.Lock()
+		delete(m.subs[topic], sub)
+		if len(m.subs[topic]) == 0 {
+			delete(m.subs, topic)
+		}
+		m.mu.Unlock()
+
+		sub.mu.Lock()
+		sub.closed = true
+		close(sub.ch)
+		sub.mu.Unlock()
+	}()
+	return sub.ch, nil
+}
+
+func (sub *memorySubscriber) send(ctx context.Context, payload []byte) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return nil
+	}
+	select {
+	case sub.ch <- payload:
+	case <-sub.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Package pubsub publishes events to topics and delivers them to the resolvers of subscription
+// fields, whose methods return the channel of a topic:
+//
+//	func (r *Resolver) MessageAdded(ctx context.Context, args struct{ Room string }) (<-chan *Message, error) {
+//		ch, err := r.PubSub.Subscribe(ctx, "messages."+args.Room, reflect.TypeOf(&Message{}))
+//		if err != nil {
+//			return nil, err
+//		}
+//		return ch.(<-chan *Message), nil
+//	}
+//
+// while mutations publish them with r.PubSub.Publish(ctx, "messages."+room, msg). The events are
+// encoded as JSON and delivered by a Backend: Memory within the process, or a message broker like
+// Redis or NATS for services with several instances.
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// Backend delivers the messages published to a topic to its subscribers, e.g. through the channel
+// of the topic in Redis or its subject in NATS.
+type Backend interface {
+	// Publish sends the payload to the current subscribers of the topic.
+	Publish(ctx context.Context, topic string, payload []byte) error
+
+	// Subscribe returns the payloads published to the topic after the call. The channel is closed
+	// when ctx is done.
+	Subscribe(ctx context.Context, topic string) (<-chan []byte, error)
+}
+
+// PubSub publishes events to topics of a backend and subscribes to them.
+type PubSub struct {
+	Backend Backend
+
+	// Buffer is the number of events a subscriber's channel holds while its subscription is
+	// busy with earlier ones.
+	Buffer int
+}
+
+// New returns a PubSub using the backend.
+func New(backend Backend) *PubSub {
+	return &PubSub{Backend: backend}
+}
+
+// Publish encodes the event as JSON and publishes it to the topic.
+func (p *PubSub) Publish(ctx context.Context, topic string, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.Backend.Publish(ctx, topic, payload)
+}
+
+// Subscribe returns the channel of the events published to the topic, decoded as values of the
+// event type. For an event type T, the channel is a <-chan T, as returned by the resolver methods
+// of subscription fields. It is closed when ctx is done. Payloads that can not be decoded as the
+// event type are dropped.
+func (p *PubSub) Subscribe(ctx context.Context, topic string, eventType reflect.Type) (interface{}, error) {
+	payloads, err := p.Backend.Subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	events := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, eventType), p.Buffer)
+	go func() {
+		defer events.Close()
+		done := reflect.ValueOf(ctx.Done())
+		for payload := range payloads {
+			event := reflect.New(eventType)
+			if err := json.Unmarshal(payload, event.Interface()); err != nil {
+				continue
+			}
+			chosen, _, _ := reflect.Select([]reflect.SelectCase{
+				{Dir: reflect.SelectSend, Chan: events, Send: event.Elem()},
+				{Dir: reflect.SelectRecv, Chan: done},
+			})
+			if chosen == 1 {
+				return
+			}
+		}
+	}()
+	return events.Convert(reflect.ChanOf(reflect.RecvDir, eventType)).Interface(), nil
+}
@@ -0,0 +1,100 @@
+package pubsub_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/pubsub"
+)
+
+type message struct {
+	Room string `json:"room"`
+	Text string `json:"text"`
+}
+
+type resolver struct {
+	ps *pubsub.PubSub
+}
+
+func (r *resolver) Hello() string { return "hello" }
+
+func (r *resolver) MessageAdded(ctx context.Context, args struct{ Room string }) (<-chan *message, error) {
+	ch, err := r.ps.Subscribe(ctx, "messages."+args.Room, reflect.TypeOf(&message{}))
+	if err != nil {
+		return nil, err
+	}
+	return ch.(<-chan *message), nil
+}
+
+func TestSubscription(t *testing.T) {
+	ps := pubsub.New(pubsub.NewMemory())
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+			subscription: Subscription
+		}
+
+		type Query {
+			hello: String!
+		}
+
+		type Subscription {
+			messageAdded(room: String!): Message!
+		}
+
+		type Message {
+			room: String!
+			text: String!
+		}
+	`, &resolver{ps: ps})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	responses := schema.Subscribe(ctx, `subscription { messageAdded(room: "go") { text } }`, "", nil)
+
+	for _, msg := range []*message{{"go", "hello"}, {"rust", "hi"}, {"go", "bye"}} {
+		if err := ps.Publish(context.Background(), "messages."+msg.Room, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{`{"messageAdded":{"text":"hello"}}`, `{"messageAdded":{"text":"bye"}}`} {
+		resp := <-responses
+		if got := string(resp.Data); got != want || len(resp.Errors) != 0 {
+			t.Errorf("got %s %v, want %s", got, resp.Errors, want)
+		}
+	}
+
+	cancel()
+	if _, ok := <-responses; ok {
+		t.Error("got a response after the subscription ended")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := &pubsub.Memory{Buffer: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	a, _ := m.Subscribe(ctx, "topic")
+	b, _ := m.Subscribe(context.Background(), "topic")
+
+	if err := m.Publish(context.Background(), "topic", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(<-a); got != "1" {
+		t.Errorf("got %s, want 1", got)
+	}
+	if got := string(<-b); got != "1" {
+		t.Errorf("got %s, want 1", got)
+	}
+
+	cancel()
+	if _, ok := <-a; ok {
+		t.Error("got a message after the subscription ended")
+	}
+	if err := m.Publish(context.Background(), "topic", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(<-b); got != "2" {
+		t.Errorf("got %s, want 2", got)
+	}
+}
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"math"
+
+	perrors "github.com/pkg/errors"
+)
+
+// Page are the pagination arguments of a field, following the Relay connection specification and
+// offset pagination. Unset arguments are nil.
+type Page struct {
+	First  *int
+	After  *string
+	Last   *int
+	Before *string
+	Offset *int
+}
+
+// ParsePage reads the arguments "first", "after", "last", "before" and "offset" from args, e.g. the
+// arguments of a SelectedField or of a relation of a projection. Counts have to be non-negative
+// integers and cursors strings.
+func ParsePage(args map[string]interface{}) (*Page, error) {
+	p := &Page{}
+	var err error
+	if p.First, err = intArg(args, "first"); err != nil {
+		return nil, err
+	}
+	if p.Last, err = intArg(args, "last"); err != nil {
+		return nil, err
+	}
+	if p.Offset, err = intArg(args, "offset"); err != nil {
+		return nil, err
+	}
+	if p.After, err = stringArg(args, "after"); err != nil {
+		return nil, err
+	}
+	if p.Before, err = stringArg(args, "before"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Limit returns the number of rows to fetch: first or last, at most max, or max if neither is set.
+// A max of 0 or less means no maximum, for which an unset limit is -1.
+func (p *Page) Limit(max int) int {
+	n := -1
+	switch {
+	case p.First != nil:
+		n = *p.First
+	case p.Last != nil:
+		n = *p.Last
+	}
+	if max > 0 && (n < 0 || n > max) {
+		n = max
+	}
+	return n
+}
+
+func intArg(args map[string]interface{}, name string) (*int, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	var n int
+	switch v := v.(type) {
+	case int32:
+		n = int(v)
+	case int:
+		n = v
+	case int64:
+		n = int(v)
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > math.MaxInt32 {
+			return nil, perrors.Errorf("argument %q: %v is not an integer", name, v)
+		}
+		n = int(v)
+	default:
+		return nil, perrors.Errorf("argument %q: %v is not an integer", name, v)
+	}
+	if n < 0 {
+		return nil, perrors.Errorf("argument %q: %d is negative", name, n)
+	}
+	return &n, nil
+}
+
+func stringArg(args map[string]interface{}, name string) (*string, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, perrors.Errorf("argument %q: %v is not a string", name, v)
+	}
+	return &s, nil
+}
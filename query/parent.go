@@ -0,0 +1,10 @@
+package query
+
+// Parent is a parameter of resolver methods receiving the resolver of the parent object, the one
+// whose field returned the method's resolver, e.g. to use the parent entity without loading it
+// again. The method takes it after its Variables. Value is the parent resolver if it can be
+// assigned to T, or its projection to T, see graphql.ParentProjection. It is the zero value for
+// the fields of the root types.
+type Parent[T any] struct {
+	Value T
+}
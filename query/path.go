@@ -0,0 +1,48 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Path is the path of a field in the response, e.g. ["hero", "friends", 0, "name"]: the aliases of
+// the field and of its enclosing fields, and the indices of the list entries. A resolver method may
+// take the path of its field after its Parent, e.g. to correlate the requests it sends to a batch
+// backend with the response paths of the selected fields, see SelectedField.Alias.
+type Path []interface{}
+
+// Append returns a new path with the elements, aliases or list indices, appended to p.
+func (p Path) Append(elems ...interface{}) Path {
+	result := make(Path, 0, len(p)+len(elems))
+	return append(append(result, p...), elems...)
+}
+
+// Aliases returns the chain of aliases of the path, without the list indices.
+func (p Path) Aliases() []string {
+	var result []string
+	for _, elem := range p {
+		if alias, ok := elem.(string); ok {
+			result = append(result, alias)
+		}
+	}
+	return result
+}
+
+// String returns the path in the form "hero.friends[0].name".
+func (p Path) String() string {
+	var b strings.Builder
+	for _, elem := range p {
+		switch elem := elem.(type) {
+		case int:
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(elem))
+			b.WriteByte(']')
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(elem)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	p := Path{"hero", "friends", 0}
+	child := p.Append("bestFriend", "name")
+	if want := (Path{"hero", "friends", 0}); !reflect.DeepEqual(p, want) {
+		t.Errorf("append modified the path: got %v, want %v", p, want)
+	}
+	if got, want := child.String(), "hero.friends[0].bestFriend.name"; got != want {
+		t.Errorf("string: got %q, want %q", got, want)
+	}
+	if got, want := child.Aliases(), []string{"hero", "friends", "bestFriend", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("aliases: got %q, want %q", got, want)
+	}
+	if got := Path(nil).String(); got != "" {
+		t.Errorf("string of the empty path: got %q", got)
+	}
+}
@@ -0,0 +1,128 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Projection is the data selected from an entity, as a hint for database layers to fetch only the
+// requested columns and relations. Fields without selections are columns, fields with selections
+// are relations to other entities.
+type Projection struct {
+	Columns   []string // in the order of the selections, without duplicates
+	Relations []*Relation
+}
+
+// Relation is a field with selections, with the arguments it was selected with. A field selected
+// twice with different arguments, e.g. with aliases, is two relations.
+type Relation struct {
+	Field      string
+	Args       map[string]interface{}
+	Projection *Projection
+}
+
+// Project returns the projection of the fields. column maps the names of the fields to the names of
+// the columns, SnakeCase if it is nil.
+func Project(fields []SelectedField, column func(field string) string) *Projection {
+	if column == nil {
+		column = SnakeCase
+	}
+	p := &Projection{}
+	p.add(fields, column)
+	return p
+}
+
+func (p *Projection) add(fields []SelectedField, column func(field string) string) {
+	for _, f := range fields {
+		if len(f.Selected) == 0 {
+			if c := column(f.Name); !p.HasColumn(c) {
+				p.Columns = append(p.Columns, c)
+			}
+			continue
+		}
+		rel := p.relation(f.Name, f.Args)
+		if rel == nil {
+			rel = &Relation{Field: f.Name, Args: f.Args, Projection: &Projection{}}
+			p.Relations = append(p.Relations, rel)
+		}
+		rel.Projection.add(f.Selected, column)
+	}
+}
+
+func (p *Projection) relation(field string, args map[string]interface{}) *Relation {
+	for _, rel := range p.Relations {
+		if rel.Field == field && reflect.DeepEqual(rel.Args, args) {
+			return rel
+		}
+	}
+	return nil
+}
+
+// HasColumn reports whether the column is selected.
+func (p *Projection) HasColumn(column string) bool {
+	for _, c := range p.Columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// Relation returns the first relation of the field, or nil if it is not selected.
+func (p *Projection) Relation(field string) *Relation {
+	for _, rel := range p.Relations {
+		if rel.Field == field {
+			return rel
+		}
+	}
+	return nil
+}
+
+// Nodes returns the projection of the nodes of a Relay connection, selected with
+// "edges { node { ... } }" or "nodes { ... }", merged into one. It returns nil if neither is
+// selected.
+func (p *Projection) Nodes() *Projection {
+	var nodes *Projection
+	merge := func(q *Projection) {
+		if nodes == nil {
+			nodes = &Projection{}
+		}
+		for _, c := range q.Columns {
+			if !nodes.HasColumn(c) {
+				nodes.Columns = append(nodes.Columns, c)
+			}
+		}
+		nodes.Relations = append(nodes.Relations, q.Relations...)
+	}
+	for _, rel := range p.Relations {
+		switch rel.Field {
+		case "nodes":
+			merge(rel.Projection)
+		case "edges":
+			for _, node := range rel.Projection.Relations {
+				if node.Field == "node" {
+					merge(node.Projection)
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+// SnakeCase converts a field name like "createdAt" to a column name like "created_at".
+func SnakeCase(field string) string {
+	var b strings.Builder
+	runes := []rune(field)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// "userID" is "user_id", not "user_i_d"
+			if i > 0 && runes[i-1] != '_' && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
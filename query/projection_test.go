@@ -0,0 +1,92 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	fields := []SelectedField{
+		{Name: "id"},
+		{Name: "createdAt"},
+		{Name: "id"},
+		{Name: "author", Selected: []SelectedField{{Name: "name"}}},
+		{Name: "author", Selected: []SelectedField{{Name: "userID"}}},
+		{Name: "comments", Args: map[string]interface{}{"first": int32(5)}, Selected: []SelectedField{
+			{Name: "edges", Selected: []SelectedField{
+				{Name: "cursor"},
+				{Name: "node", Selected: []SelectedField{{Name: "body"}}},
+			}},
+			{Name: "nodes", Selected: []SelectedField{{Name: "body"}, {Name: "HTMLBody"}}},
+		}},
+	}
+
+	p := Project(fields, nil)
+	if want := []string{"id", "created_at"}; !reflect.DeepEqual(p.Columns, want) {
+		t.Errorf("columns: want %v, got %v", want, p.Columns)
+	}
+	if len(p.Relations) != 2 {
+		t.Fatalf("want 2 relations, got %d", len(p.Relations))
+	}
+	if want := []string{"name", "user_id"}; !reflect.DeepEqual(p.Relation("author").Projection.Columns, want) {
+		t.Errorf("author columns: want %v, got %v", want, p.Relation("author").Projection.Columns)
+	}
+	if p.Relation("editor") != nil {
+		t.Error("unexpected relation editor")
+	}
+
+	comments := p.Relation("comments")
+	if !reflect.DeepEqual(comments.Args, map[string]interface{}{"first": int32(5)}) {
+		t.Errorf("unexpected args %v", comments.Args)
+	}
+	if want := []string{"body", "html_body"}; !reflect.DeepEqual(comments.Projection.Nodes().Columns, want) {
+		t.Errorf("nodes columns: want %v, got %v", want, comments.Projection.Nodes().Columns)
+	}
+	if p.Nodes() != nil {
+		t.Error("unexpected nodes")
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for field, want := range map[string]string{
+		"id":        "id",
+		"createdAt": "created_at",
+		"userID":    "user_id",
+		"HTMLBody":  "html_body",
+		"a1B":       "a1_b",
+	} {
+		if got := SnakeCase(field); got != want {
+			t.Errorf("SnakeCase(%q): want %q, got %q", field, want, got)
+		}
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	p, err := ParsePage(map[string]interface{}{"first": float64(10), "after": "Y3Vyc29y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *p.First != 10 || *p.After != "Y3Vyc29y" || p.Last != nil || p.Before != nil || p.Offset != nil {
+		t.Errorf("unexpected page %+v", p)
+	}
+	if got := p.Limit(5); got != 5 {
+		t.Errorf("Limit(5): want 5, got %d", got)
+	}
+	if got := p.Limit(0); got != 10 {
+		t.Errorf("Limit(0): want 10, got %d", got)
+	}
+	if got := (&Page{}).Limit(0); got != -1 {
+		t.Errorf("unset Limit(0): want -1, got %d", got)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"first": int32(-1)},
+		{"last": 1.5},
+		{"offset": "3"},
+		{"before": 3},
+	} {
+		if _, err := ParsePage(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package query
+
+import "encoding/json"
+
+// RawArgs are the arguments of a field in the query, with the variables substituted, encoded as a
+// JSON object. A resolver method may take them after its arguments struct, e.g. to pass them on
+// verbatim to an upstream service.
+type RawArgs json.RawMessage
+
+// Variables are the variables of the operation, with the default values of those not given. A
+// resolver method may take them after its arguments struct, or its RawArgs, e.g. to forward them
+// along with the operation. They are shared by all resolvers and must not be modified.
+type Variables map[string]interface{}
@@ -2,5 +2,7 @@ package query
 
 type SelectedField struct {
 	Name     string
+	Alias    string                 // name of the field in the response, Name unless it is aliased
+	Args     map[string]interface{} // arguments given in the query, nil if there are none
 	Selected []SelectedField
 }
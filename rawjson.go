@@ -0,0 +1,12 @@
+package graphql
+
+// RawJSON is the JSON of the value of a field, returned by its resolver method or read from a struct
+// field, e.g. the part of the response of an upstream service that a gateway passes on. It is
+// written into the response as it is, without resolving the selections of the field: the resolver
+// asserts that the JSON is the value the selections would have resolved to, with the fields by
+// their aliases, in the order of the query. Nil or null makes the field null. A resolver taking
+// []query.SelectedField gets the selections of the query to build the JSON for.
+//
+// The JSON is not checked by default, a mismatch is passed on to the client, and invalid JSON
+// makes the whole response invalid. ValidateRawJSON checks it, e.g. while debugging.
+type RawJSON []byte
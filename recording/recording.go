@@ -0,0 +1,111 @@
+// Package recording holds the values of fields recorded by graphql.RecordFields and served by
+// graphql.ReplayFields, so that queries can be tested deterministically without the backends of
+// their resolvers.
+package recording
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Entry is the recorded value of a field.
+type Entry struct {
+	// Value is the JSON of the value. Objects within it are written as {}, the values of their
+	// fields are recorded separately.
+	Value json.RawMessage `json:"value,omitempty"`
+
+	// Error is the message of the error the field resolved to.
+	Error string `json:"error,omitempty"`
+}
+
+// Fixture holds recorded values of fields keyed by the path of the field, with the arguments of the
+// fields along it, e.g. `hero(episode:"JEDI").friends.0.name`. The concrete types of values of
+// interfaces and unions are recorded as their "__typename" field. It is safe for concurrent use.
+type Fixture struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+}
+
+// New returns an empty fixture.
+func New() *Fixture {
+	return &Fixture{entries: make(map[string]*Entry)}
+}
+
+// Load reads a fixture from a file written by Save.
+func Load(filename string) (*Fixture, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// Read reads a fixture written by Write.
+func Read(r io.Reader) (*Fixture, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f := New()
+	if err := json.Unmarshal(data, &f.entries); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Save writes the fixture to a file.
+func (f *Fixture) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := f.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Write writes the fixture as a JSON object with sorted keys, so that fixtures are diffable.
+func (f *Fixture) Write(w io.Writer) error {
+	f.mu.RLock()
+	data, err := json.MarshalIndent(f.entries, "", "  ")
+	f.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Get returns the entry of the key.
+func (f *Fixture) Get(key string) (*Entry, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	e, ok := f.entries[key]
+	return e, ok
+}
+
+// Set records the entry of the key, replacing an earlier one.
+func (f *Fixture) Set(key string, e *Entry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries[key] = e
+}
+
+// Keys returns the sorted keys of the entries.
+func (f *Fixture) Keys() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	keys := make([]string, 0, len(f.entries))
+	for key := range f.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
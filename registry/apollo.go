@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"context"
+	"strings"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/client"
+)
+
+// ApolloEndpoint is the Platform API of Apollo GraphOS.
+const ApolloEndpoint = "https://api.apollographql.com/api/graphql"
+
+// Apollo is the schema registry of Apollo GraphOS. Schemas with a service name in their metadata
+// are published as subgraphs of a federated graph, others as the schema of a monolithic graph.
+type Apollo struct {
+	Client  *client.Client
+	GraphID string
+	Variant string
+}
+
+// NewApollo returns the registry of the graph given as "graph@variant", authenticated with an API
+// key. The variant defaults to "current".
+func NewApollo(apiKey, graphRef string, opts ...client.Option) *Apollo {
+	graphID, variant := graphRef, "current"
+	if i := strings.IndexByte(graphRef, '@'); i != -1 {
+		graphID, variant = graphRef[:i], graphRef[i+1:]
+	}
+	opts = append([]client.Option{
+		client.Header("X-API-Key", apiKey),
+		client.Header("apollographql-client-name", "graphql-go"),
+	}, opts...)
+	return &Apollo{Client: client.New(ApolloEndpoint, opts...), GraphID: graphID, Variant: variant}
+}
+
+func (a *Apollo) variables(sdl string, meta Metadata) map[string]interface{} {
+	vars := map[string]interface{}{
+		"graphId": a.GraphID,
+		"variant": a.Variant,
+		"sdl":     sdl,
+		"gitContext": map[string]interface{}{
+			"commit":    meta.Commit,
+			"committer": meta.Author,
+		},
+	}
+	if meta.Service != "" {
+		vars["name"] = meta.Service
+		vars["url"] = meta.URL
+		vars["revision"] = meta.Commit
+	}
+	return vars
+}
+
+const apolloUploadSchema = `
+	mutation UploadSchema($graphId: ID!, $variant: String!, $sdl: String!, $gitContext: GitContextInput) {
+		graph(id: $graphId) {
+			uploadSchema(tag: $variant, schemaDocument: $sdl, gitContext: $gitContext) {
+				success
+				message
+			}
+		}
+	}
+`
+
+const apolloPublishSubgraph = `
+	mutation PublishSubgraph($graphId: ID!, $variant: String!, $name: String!, $url: String, $revision: String!, $sdl: String!, $gitContext: GitContextInput) {
+		graph(id: $graphId) {
+			publishSubgraph(graphVariant: $variant, name: $name, url: $url, revision: $revision, activePartialSchema: {sdl: $sdl}, gitContext: $gitContext) {
+				errors {
+					message
+				}
+			}
+		}
+	}
+`
+
+// Publish implements Registry.
+func (a *Apollo) Publish(ctx context.Context, sdl string, meta Metadata) error {
+	if meta.Service != "" {
+		var data struct {
+			Graph *struct {
+				PublishSubgraph struct {
+					Errors []struct{ Message string }
+				}
+			}
+		}
+		if err := a.Client.Execute(ctx, apolloPublishSubgraph, a.variables(sdl, meta), &data); err != nil {
+			return err
+		}
+		if data.Graph == nil {
+			return perrors.Errorf("graph %q not found", a.GraphID)
+		}
+		if errs := data.Graph.PublishSubgraph.Errors; len(errs) != 0 {
+			return &CheckError{Result: &CheckResult{Errors: messages(errs)}}
+		}
+		return nil
+	}
+
+	var data struct {
+		Graph *struct {
+			UploadSchema struct {
+				Success bool
+				Message string
+			}
+		}
+	}
+	if err := a.Client.Execute(ctx, apolloUploadSchema, a.variables(sdl, meta), &data); err != nil {
+		return err
+	}
+	if data.Graph == nil {
+		return perrors.Errorf("graph %q not found", a.GraphID)
+	}
+	if res := data.Graph.UploadSchema; !res.Success {
+		return perrors.New(res.Message)
+	}
+	return nil
+}
+
+const apolloCheckSchema = `
+	mutation CheckSchema($graphId: ID!, $variant: String!, $sdl: String!, $gitContext: GitContextInput) {
+		graph(id: $graphId) {
+			checkSchema(proposedSchemaDocument: $sdl, baseSchemaTag: $variant, gitContext: $gitContext) {
+				diffToPrevious {
+					severity
+					changes {
+						severity
+						description
+					}
+				}
+			}
+		}
+	}
+`
+
+const apolloCheckSubgraph = `
+	mutation CheckSubgraph($graphId: ID!, $variant: String!, $name: String!, $sdl: String!, $gitContext: GitContextInput) {
+		graph(id: $graphId) {
+			checkPartialSchema(graphVariant: $variant, implementingServiceName: $name, partialSchema: {sdl: $sdl}, gitContext: $gitContext) {
+				compositionValidationResult {
+					errors {
+						message
+					}
+				}
+				checkSchemaResult {
+					diffToPrevious {
+						severity
+						changes {
+							severity
+							description
+						}
+					}
+				}
+			}
+		}
+	}
+`
+
+type apolloDiff struct {
+	Severity string
+	Changes  []struct{ Severity, Description string }
+}
+
+// Check implements Registry.
+func (a *Apollo) Check(ctx context.Context, sdl string, meta Metadata) (*CheckResult, error) {
+	var diff *apolloDiff
+	result := &CheckResult{}
+	if meta.Service != "" {
+		var data struct {
+			Graph *struct {
+				CheckPartialSchema struct {
+					CompositionValidationResult struct {
+						Errors []struct{ Message string }
+					}
+					CheckSchemaResult *struct {
+						DiffToPrevious apolloDiff
+					}
+				}
+			}
+		}
+		if err := a.Client.Execute(ctx, apolloCheckSubgraph, a.variables(sdl, meta), &data); err != nil {
+			return nil, err
+		}
+		if data.Graph == nil {
+			return nil, perrors.Errorf("graph %q not found", a.GraphID)
+		}
+		res := data.Graph.CheckPartialSchema
+		result.Errors = messages(res.CompositionValidationResult.Errors)
+		if res.CheckSchemaResult != nil {
+			diff = &res.CheckSchemaResult.DiffToPrevious
+		}
+	} else {
+		var data struct {
+			Graph *struct {
+				CheckSchema struct {
+					DiffToPrevious apolloDiff
+				}
+			}
+		}
+		if err := a.Client.Execute(ctx, apolloCheckSchema, a.variables(sdl, meta), &data); err != nil {
+			return nil, err
+		}
+		if data.Graph == nil {
+			return nil, perrors.Errorf("graph %q not found", a.GraphID)
+		}
+		diff = &data.Graph.CheckSchema.DiffToPrevious
+	}
+
+	if diff != nil {
+		for _, c := range diff.Changes {
+			result.Changes = append(result.Changes, change(c.Severity, c.Description))
+			if c.Severity == "FAILURE" {
+				result.Errors = append(result.Errors, c.Description)
+			}
+		}
+	}
+	result.Valid = len(result.Errors) == 0 && (diff == nil || diff.Severity != "FAILURE")
+	return result, nil
+}
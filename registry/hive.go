@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"context"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/client"
+)
+
+// HiveEndpoint is the GraphQL API of GraphQL Hive.
+const HiveEndpoint = "https://app.graphql-hive.com/graphql"
+
+// Hive is the schema registry of GraphQL Hive.
+type Hive struct {
+	Client *client.Client
+}
+
+// NewHive returns the registry of the Hive cloud, authenticated with a registry access token. Self
+// hosted instances are used by creating a Hive with a client for their endpoint.
+func NewHive(token string, opts ...client.Option) *Hive {
+	opts = append([]client.Option{client.Header("Authorization", "Bearer "+token)}, opts...)
+	return &Hive{Client: client.New(HiveEndpoint, opts...)}
+}
+
+const hivePublish = `
+	mutation schemaPublish($input: SchemaPublishInput!) {
+		schemaPublish(input: $input) {
+			__typename
+			... on SchemaPublishError {
+				errors {
+					nodes {
+						message
+					}
+				}
+			}
+			... on SchemaPublishMissingServiceError {
+				message
+			}
+			... on SchemaPublishMissingUrlError {
+				message
+			}
+		}
+	}
+`
+
+// Publish implements Registry.
+func (h *Hive) Publish(ctx context.Context, sdl string, meta Metadata) error {
+	input := map[string]interface{}{
+		"sdl":    sdl,
+		"author": meta.Author,
+		"commit": meta.Commit,
+	}
+	if meta.Service != "" {
+		input["service"] = meta.Service
+	}
+	if meta.URL != "" {
+		input["url"] = meta.URL
+	}
+
+	var data struct {
+		SchemaPublish struct {
+			Typename string `json:"__typename"`
+			Message  string
+			Errors   struct {
+				Nodes []struct{ Message string }
+			}
+		}
+	}
+	if err := h.Client.Execute(ctx, hivePublish, map[string]interface{}{"input": input}, &data); err != nil {
+		return err
+	}
+	switch res := data.SchemaPublish; res.Typename {
+	case "SchemaPublishError":
+		return &CheckError{Result: &CheckResult{Errors: messages(res.Errors.Nodes)}}
+	case "SchemaPublishMissingServiceError", "SchemaPublishMissingUrlError":
+		return perrors.New(res.Message)
+	}
+	return nil
+}
+
+const hiveCheck = `
+	mutation schemaCheck($input: SchemaCheckInput!) {
+		schemaCheck(input: $input) {
+			__typename
+			... on SchemaCheckSuccess {
+				changes {
+					nodes {
+						message
+						criticality
+					}
+				}
+			}
+			... on SchemaCheckError {
+				errors {
+					nodes {
+						message
+					}
+				}
+				changes {
+					nodes {
+						message
+						criticality
+					}
+				}
+			}
+		}
+	}
+`
+
+// Check implements Registry.
+func (h *Hive) Check(ctx context.Context, sdl string, meta Metadata) (*CheckResult, error) {
+	input := map[string]interface{}{"sdl": sdl}
+	if meta.Service != "" {
+		input["service"] = meta.Service
+	}
+
+	var data struct {
+		SchemaCheck struct {
+			Typename string `json:"__typename"`
+			Errors   struct {
+				Nodes []struct{ Message string }
+			}
+			Changes struct {
+				Nodes []struct{ Message, Criticality string }
+			}
+		}
+	}
+	if err := h.Client.Execute(ctx, hiveCheck, map[string]interface{}{"input": input}, &data); err != nil {
+		return nil, err
+	}
+	res := data.SchemaCheck
+	result := &CheckResult{
+		Valid:  res.Typename == "SchemaCheckSuccess",
+		Errors: messages(res.Errors.Nodes),
+	}
+	for _, c := range res.Changes.Nodes {
+		result.Changes = append(result.Changes, change(c.Criticality, c.Message))
+	}
+	return result, nil
+}
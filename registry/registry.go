@@ -0,0 +1,123 @@
+// Package registry publishes the schema of a service to a schema registry like Apollo GraphOS or
+// GraphQL Hive, typically when the service starts:
+//
+//	reg := registry.NewHive(os.Getenv("HIVE_TOKEN"))
+//	meta := registry.Metadata{Service: "orders", URL: "http://orders/graphql", Commit: gitSHA}
+//	if err := registry.Publish(ctx, reg, schema, meta, registry.CheckFirst()); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// With CheckFirst the schema is published only if it passes the checks of the registry, e.g. the
+// composition with the schemas of the other services of a federated graph.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go"
+)
+
+// Metadata describes the published schema.
+type Metadata struct {
+	// Service is the name of the service in a federated graph, empty for a graph with a single
+	// schema.
+	Service string
+
+	// URL is the endpoint of the service, used by the gateway of a federated graph.
+	URL string
+
+	// Commit is the revision of the code, e.g. a git SHA, and Author its author.
+	Commit string
+	Author string
+}
+
+// Registry is a schema registry.
+type Registry interface {
+	// Publish publishes the schema definition as the current one.
+	Publish(ctx context.Context, sdl string, meta Metadata) error
+
+	// Check runs the checks of the registry against the schema definition without publishing it.
+	Check(ctx context.Context, sdl string, meta Metadata) (*CheckResult, error)
+}
+
+// CheckResult is the result of the checks of a schema definition.
+type CheckResult struct {
+	// Valid reports whether the schema passed the checks.
+	Valid bool
+
+	// Errors are the reasons for failing the checks, e.g. composition errors.
+	Errors []string
+
+	// Changes describe the changes compared to the published schema, breaking or not.
+	Changes []string
+}
+
+// CheckError is returned by Publish for a schema failing the checks of the registry.
+type CheckError struct {
+	Result *CheckResult
+}
+
+func (err *CheckError) Error() string {
+	if len(err.Result.Errors) == 0 {
+		return "registry: schema check failed"
+	}
+	return "registry: schema check failed: " + strings.Join(err.Result.Errors, "; ")
+}
+
+// PublishOpt is an option to pass to Publish.
+type PublishOpt func(*publishOptions)
+
+type publishOptions struct {
+	check bool
+}
+
+// CheckFirst checks the schema before publishing it. A schema failing the checks is not published
+// and Publish returns a *CheckError.
+func CheckFirst() PublishOpt {
+	return func(o *publishOptions) {
+		o.check = true
+	}
+}
+
+// Publish publishes the definition of the schema to the registry.
+func Publish(ctx context.Context, r Registry, s *graphql.Schema, meta Metadata, opts ...PublishOpt) error {
+	var o publishOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	sdl := s.SDL()
+	if o.check {
+		result, err := r.Check(ctx, sdl, meta)
+		if err != nil {
+			return perrors.Wrap(err, "registry: checking schema")
+		}
+		if !result.Valid {
+			return &CheckError{Result: result}
+		}
+	}
+	if err := r.Publish(ctx, sdl, meta); err != nil {
+		return perrors.Wrap(err, "registry: publishing schema")
+	}
+	return nil
+}
+
+// messages returns the messages of errors, which the registries return as lists of objects.
+func messages(errs []struct{ Message string }) []string {
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Message)
+	}
+	return msgs
+}
+
+// change formats a change reported by a registry.
+func change(severity, message string) string {
+	if severity == "" {
+		return message
+	}
+	return fmt.Sprintf("%s: %s", severity, message)
+}
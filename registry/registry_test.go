@@ -0,0 +1,134 @@
+package registry_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/client"
+	"github.com/qdentity/graphql-go/registry"
+)
+
+const sdl = `schema { query: Query } type Query { hello: String }`
+
+var schema = graphql.MustParseSchema(sdl, nil)
+
+type request struct {
+	Query     string
+	Variables map[string]interface{}
+}
+
+// fakeRegistry answers the operations of a registry with the data for the first operation name
+// found in the query, and records the requests.
+func fakeRegistry(t *testing.T, responses map[string]string) (*httptest.Server, *[]request) {
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		requests = append(requests, req)
+		for name, data := range responses {
+			if strings.Contains(req.Query, "mutation "+name+"(") {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"data":` + data + `}`))
+				return
+			}
+		}
+		t.Errorf("unexpected query %s", req.Query)
+		w.Write([]byte(`{"data":null}`))
+	}))
+	return srv, &requests
+}
+
+func TestHivePublish(t *testing.T) {
+	srv, requests := fakeRegistry(t, map[string]string{
+		"schemaCheck":   `{"schemaCheck": {"__typename": "SchemaCheckSuccess", "changes": {"nodes": [{"message": "Field 'hello' was added", "criticality": "Safe"}]}}}`,
+		"schemaPublish": `{"schemaPublish": {"__typename": "SchemaPublishSuccess"}}`,
+	})
+	defer srv.Close()
+
+	reg := &registry.Hive{Client: client.New(srv.URL)}
+	meta := registry.Metadata{Service: "hello", URL: "http://hello/graphql", Commit: "abc123", Author: "ci"}
+	if err := registry.Publish(context.Background(), reg, schema, meta, registry.CheckFirst()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(*requests))
+	}
+	want := map[string]interface{}{
+		"sdl":     sdl,
+		"service": "hello",
+		"url":     "http://hello/graphql",
+		"commit":  "abc123",
+		"author":  "ci",
+	}
+	if got := (*requests)[1].Variables["input"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got input %v, want %v", got, want)
+	}
+}
+
+func TestHiveCheckFailure(t *testing.T) {
+	srv, requests := fakeRegistry(t, map[string]string{
+		"schemaCheck": `{"schemaCheck": {"__typename": "SchemaCheckError", "errors": {"nodes": [{"message": "Field 'hello' was removed"}]}, "changes": {"nodes": []}}}`,
+	})
+	defer srv.Close()
+
+	reg := &registry.Hive{Client: client.New(srv.URL)}
+	err := registry.Publish(context.Background(), reg, schema, registry.Metadata{}, registry.CheckFirst())
+	checkErr, ok := err.(*registry.CheckError)
+	if !ok {
+		t.Fatalf("got error %v, want a CheckError", err)
+	}
+	if want := []string{"Field 'hello' was removed"}; !reflect.DeepEqual(checkErr.Result.Errors, want) {
+		t.Errorf("got errors %q, want %q", checkErr.Result.Errors, want)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("got %d requests, want the schema not to be published", len(*requests))
+	}
+}
+
+func TestApollo(t *testing.T) {
+	srv, requests := fakeRegistry(t, map[string]string{
+		"CheckSubgraph": `{"graph": {"checkPartialSchema": {
+			"compositionValidationResult": {"errors": []},
+			"checkSchemaResult": {"diffToPrevious": {"severity": "NOTICE", "changes": [{"severity": "NOTICE", "description": "type Query: field hello added"}]}}
+		}}}`,
+		"PublishSubgraph": `{"graph": {"publishSubgraph": {"errors": []}}}`,
+		"UploadSchema":    `{"graph": {"uploadSchema": {"success": false, "message": "invalid schema"}}}`,
+	})
+	defer srv.Close()
+
+	reg := registry.NewApollo("key", "shop@prod")
+	reg.Client = client.New(srv.URL)
+
+	meta := registry.Metadata{Service: "hello", URL: "http://hello/graphql", Commit: "abc123"}
+	result, err := reg.Check(context.Background(), sdl, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &registry.CheckResult{Valid: true, Changes: []string{"NOTICE: type Query: field hello added"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got check result %+v, want %+v", result, want)
+	}
+
+	if err := registry.Publish(context.Background(), reg, schema, meta); err != nil {
+		t.Fatal(err)
+	}
+	vars := (*requests)[1].Variables
+	if vars["graphId"] != "shop" || vars["variant"] != "prod" || vars["name"] != "hello" || vars["revision"] != "abc123" {
+		t.Errorf("got variables %v", vars)
+	}
+
+	err = registry.Publish(context.Background(), reg, schema, registry.Metadata{})
+	if err == nil || !strings.Contains(err.Error(), "invalid schema") {
+		t.Errorf("got error %v, want the message of the registry", err)
+	}
+}
@@ -0,0 +1,164 @@
+package relay
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/qdentity/graphql-go"
+)
+
+// ResponseCache caches the responses of queries of the public, e.g. of unauthenticated requests,
+// see Handler.ResponseCache. Responses with errors are never cached. The cache key is derived from
+// the operation, its variables, the headers of Vary and the result of Key.
+//
+// The responses are shared by all requests with the same key, so the key has to tell apart all
+// requests whose results differ. Without Key, nothing is cached for a schema whose responses may
+// depend on the request, see graphql.Schema.RequestDependent.
+type ResponseCache struct {
+	// Store holds the cached responses, e.g. in a store shared by all instances of the service.
+	Store CacheStore
+
+	// TTL, if set, returns how long the response of the request may be cached, overriding the
+	// maximum age of the schema's @cacheControl directives, see graphql.Schema.CacheControl. A
+	// non-positive duration leaves the response uncached.
+	TTL func(r *http.Request, operationName string, cacheControl time.Duration) time.Duration
+
+	// Key, if set, returns the part of the cache key derived from the request, and reports whether
+	// its response may be cached at all. By default requests with an Authorization or Cookie
+	// header are not cached, and no requests are if the schema is request dependent. Key is
+	// required then, e.g. returning the user of the request.
+	Key func(r *http.Request) (string, bool)
+
+	// Vary are the request headers, e.g. "Accept-Language", whose values the responses depend on.
+	Vary []string
+}
+
+// CacheStore stores cached responses by key.
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// key returns the cache key of the request, false if its response is not cached.
+func (c *ResponseCache) key(r *http.Request, schema *graphql.Schema, p *params) (string, bool) {
+	var custom string
+	if c.Key != nil {
+		var ok bool
+		if custom, ok = c.Key(r); !ok {
+			return "", false
+		}
+	} else if schema.RequestDependent() || r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return "", false
+	}
+	vars, err := json.Marshal(p.Variables) // sorts the keys of maps
+	if err != nil {
+		return "", false
+	}
+	h := sha256.New()
+	for _, part := range []string{p.OperationName, p.Query, string(vars), custom} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	for _, name := range c.Vary {
+		for _, value := range r.Header.Values(name) {
+			h.Write([]byte(value))
+			h.Write([]byte{1})
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// get returns the cached response with the key.
+func (c *ResponseCache) get(ctx context.Context, key string) (*graphql.Response, bool) {
+	data, ok := c.Store.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	var response graphql.Response
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
+
+// add caches the response of the request, if it has no errors and may be cached.
+func (c *ResponseCache) add(r *http.Request, schema *graphql.Schema, p *params, key string, response *graphql.Response) {
+	if len(response.Errors) != 0 {
+		return
+	}
+	ttl := schema.CacheControl(p.Query, p.OperationName)
+	if c.TTL != nil {
+		ttl = c.TTL(r, p.OperationName, ttl)
+	}
+	if ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	c.Store.Set(r.Context(), key, data, ttl)
+}
+
+// MemoryCacheStore is a CacheStore in the memory of the process, holding up to a number of
+// responses. The least recently used ones are evicted.
+type MemoryCacheStore struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List // of *memoryCacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCacheStore returns a store of up to size responses.
+func NewMemoryCacheStore(size int) *MemoryCacheStore {
+	return &MemoryCacheStore{size: size, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the value with the key, unless it expired.
+func (s *MemoryCacheStore) Get(_ context.Context, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expires) {
+		s.lru.Remove(elem)
+		delete(s.entries, key)
+		return nil, false
+	}
+	s.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores the value with the key for the ttl.
+func (s *MemoryCacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := &memoryCacheEntry{key: key, value: value, expires: time.Now().Add(ttl)}
+	if elem, ok := s.entries[key]; ok {
+		elem.Value = entry
+		s.lru.MoveToFront(elem)
+		return
+	}
+	s.entries[key] = s.lru.PushFront(entry)
+	for s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
@@ -0,0 +1,89 @@
+package relay
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+	zlibWriters = sync.Pool{New: func() interface{} { return zlib.NewWriter(nil) }}
+)
+
+// writeResponse writes the response with the status code and the body, compressed if the handler
+// and the client allow it.
+func (h *Handler) writeResponse(w http.ResponseWriter, r *http.Request, status int, body []byte) {
+	if !h.Compress {
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	var encoding string
+	if len(body) >= h.CompressMinSize {
+		encoding = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	}
+
+	var cw interface {
+		io.WriteCloser
+		Reset(io.Writer)
+	}
+	switch encoding {
+	case "gzip":
+		gw := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(gw)
+		cw = gw
+	case "deflate": // the "deflate" content coding is the zlib format
+		zw := zlibWriters.Get().(*zlib.Writer)
+		defer zlibWriters.Put(zw)
+		cw = zw
+	default:
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	cw.Reset(w)
+	cw.Write(body)
+	cw.Close()
+}
+
+// negotiateEncoding returns the preferred content coding among gzip and deflate of the
+// Accept-Encoding header, or "" if the client accepts neither.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, q := strings.TrimSpace(part), 1.0
+		if i := strings.IndexByte(coding, ';'); i != -1 {
+			param := strings.TrimSpace(coding[i+1:])
+			coding = strings.TrimSpace(coding[:i])
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					continue
+				}
+				q = v
+			}
+		}
+		coding = strings.ToLower(coding)
+		if coding == "*" {
+			coding = "gzip"
+		}
+		if (coding != "gzip" && coding != "deflate") || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && coding == "gzip") {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,111 @@
+package relay
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures the Cross-Origin Resource Sharing headers of a Handler.
+type CORS struct {
+	// AllowedOrigins are the origins allowed to send requests, e.g. "https://example.com". The
+	// origin "*" allows all other origins, without credentials.
+	AllowedOrigins []string
+
+	// AllowedHeaders are the request headers allowed in addition to Content-Type.
+	AllowedHeaders []string
+
+	// AllowCredentials allows requests with cookies and HTTP authentication from the origins listed
+	// in AllowedOrigins, never from the ones allowed by "*".
+	AllowCredentials bool
+
+	// MaxAge is how long the result of a preflight request may be cached. It is not sent if zero.
+	MaxAge time.Duration
+}
+
+// allowOrigin returns the value of the Access-Control-Allow-Origin header for the origin, which is
+// "*" if the origin is only allowed by "*", and whether the origin is allowed at all.
+func (c *CORS) allowOrigin(origin string) (string, bool) {
+	wildcard := false
+	for _, o := range c.AllowedOrigins {
+		if strings.EqualFold(o, origin) {
+			return origin, true
+		}
+		wildcard = wildcard || o == "*"
+	}
+	return "*", wildcard
+}
+
+// handleCORS sets the CORS headers of the response. It returns true if the request was a preflight
+// request, which is answered completely.
+func (h *Handler) handleCORS(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	allowed, ok := h.CORS.allowOrigin(origin)
+	if origin == "" || !ok {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return true
+		}
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", allowed)
+	if h.CORS.AllowCredentials && allowed != "*" {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if r.Method != "OPTIONS" || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(append([]string{"Content-Type"}, h.CORS.AllowedHeaders...), ", "))
+	if h.CORS.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(h.CORS.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// DefaultCSRFHeaders are the headers that mark a request as not being a cross-site request forgery
+// if Handler.CSRFHeaders is empty.
+var DefaultCSRFHeaders = []string{"GraphQL-Require-Preflight", "Apollo-Require-Preflight", "X-Apollo-Operation-Name"}
+
+// simpleContentTypes are the content types a browser sends cross-origin without a preflight
+// request.
+var simpleContentTypes = map[string]struct{}{
+	"application/x-www-form-urlencoded": {},
+	"multipart/form-data":               {},
+	"text/plain":                        {},
+}
+
+// preflighted reports whether a browser would have sent a preflight request before the request:
+// it has a content type other than the simple ones or one of the CSRF headers. A request that
+// passes can not be a cross-site request forgery, since the CORS policy was applied to it.
+func (h *Handler) preflighted(r *http.Request) bool {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return false
+		}
+		if _, simple := simpleContentTypes[mediaType]; !simple {
+			return true
+		}
+	}
+
+	for _, name := range h.csrfHeaders() {
+		if r.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) csrfHeaders() []string {
+	if len(h.CSRFHeaders) == 0 {
+		return DefaultCSRFHeaders
+	}
+	return h.CSRFHeaders
+}
@@ -0,0 +1,141 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Probe checks a dependency of the service, e.g. a database connection.
+type Probe func(ctx context.Context) error
+
+// Health serves the health of a GraphQL service. ServeHTTP reports readiness: the service is ready
+// once its schema was loaded without error and all probes pass. Live reports liveness. The state
+// of the schema is recorded with SchemaLoaded, which is meant to be called again after every
+// reload.
+type Health struct {
+	// ProbeTimeout limits the time of all probes of a readiness check. It defaults to 5 seconds.
+	ProbeTimeout time.Duration
+
+	mu       sync.RWMutex
+	loaded   bool
+	loadedAt time.Time
+	loadErr  error
+	probes   map[string]Probe
+}
+
+// SchemaLoaded records the result of loading or reloading the schema. After a failed reload the
+// service stays ready if an earlier load succeeded, since the handler keeps serving the previous
+// schema, but the error is reported.
+func (h *Health) SchemaLoaded(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loadErr = err
+	if err == nil {
+		h.loaded = true
+		h.loadedAt = time.Now()
+	}
+}
+
+// AddProbe registers a probe that has to pass for the service to be ready.
+func (h *Health) AddProbe(name string, p Probe) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.probes == nil {
+		h.probes = make(map[string]Probe)
+	}
+	h.probes[name] = p
+}
+
+type healthStatus struct {
+	Status string                 `json:"status"`
+	Schema schemaStatus           `json:"schema"`
+	Probes map[string]probeStatus `json:"probes,omitempty"`
+}
+
+type schemaStatus struct {
+	Loaded   bool       `json:"loaded"`
+	LoadedAt *time.Time `json:"loadedAt,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+type probeStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ServeHTTP responds with the readiness of the service as JSON, with status 200 if it is ready and
+// 503 otherwise.
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	status := healthStatus{
+		Status: "ok",
+		Schema: schemaStatus{Loaded: h.loaded},
+	}
+	if h.loaded {
+		loadedAt := h.loadedAt
+		status.Schema.LoadedAt = &loadedAt
+	} else {
+		status.Status = "unavailable"
+	}
+	if h.loadErr != nil {
+		status.Schema.Error = h.loadErr.Error()
+	}
+	probes := make(map[string]Probe, len(h.probes))
+	for name, p := range h.probes {
+		probes[name] = p
+	}
+	h.mu.RUnlock()
+
+	if len(probes) != 0 {
+		timeout := h.ProbeTimeout
+		if timeout == 0 {
+			timeout = 5 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		status.Probes = make(map[string]probeStatus, len(probes))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, p := range probes {
+			wg.Add(1)
+			go func(name string, p Probe) {
+				defer wg.Done()
+				ps := probeStatus{Status: "ok"}
+				if err := p(ctx); err != nil {
+					ps = probeStatus{Status: "error", Error: err.Error()}
+				}
+				mu.Lock()
+				status.Probes[name] = ps
+				if ps.Status != "ok" {
+					status.Status = "unavailable"
+				}
+				mu.Unlock()
+			}(name, p)
+		}
+		wg.Wait()
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(data)
+}
+
+// Live responds with status 200 as long as the process serves requests. It can be used as an
+// http.HandlerFunc.
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(`{"status":"ok"}`))
+}
@@ -0,0 +1,96 @@
+package relay
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	perrors "github.com/pkg/errors"
+)
+
+// readParams reads the parameters of the request within the limits of the handler. It writes the
+// error response and returns false if the request exceeds them or is invalid.
+func (h *Handler) readParams(w http.ResponseWriter, r *http.Request, p *params) bool {
+	var body io.Reader = r.Body
+	if h.MaxBodySize > 0 {
+		if r.ContentLength > h.MaxBodySize {
+			h.tooLarge(w, r, "request body", h.MaxBodySize)
+			return false
+		}
+		body = io.LimitReader(r.Body, h.MaxBodySize+1) // one more byte to detect larger bodies
+	}
+
+	data, err := h.readBody(w, r, body)
+	switch {
+	case err == errReadTimeout:
+		w.Header().Set("Connection", "close")
+		h.writeError(w, r, http.StatusRequestTimeout, "reading the request took longer than "+h.ReadTimeout.String())
+		return false
+	case err != nil:
+		h.writeError(w, r, http.StatusBadRequest, err.Error())
+		return false
+	case h.MaxBodySize > 0 && int64(len(data)) > h.MaxBodySize:
+		h.tooLarge(w, r, "request body", h.MaxBodySize)
+		return false
+	}
+
+	if err := json.Unmarshal(data, p); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if h.MaxQueryLength > 0 && len(p.Query) > h.MaxQueryLength {
+		h.tooLarge(w, r, "query", int64(h.MaxQueryLength))
+		return false
+	}
+	return true
+}
+
+var errReadTimeout = perrors.New("read timeout")
+
+// readBody reads the body, giving up after the read timeout of the handler. The read deadline of
+// the connection ends a pending read. Writers without a connection, like a ResponseRecorder, close
+// the body instead.
+func (h *Handler) readBody(w http.ResponseWriter, r *http.Request, body io.Reader) ([]byte, error) {
+	if h.ReadTimeout <= 0 {
+		return ioutil.ReadAll(body)
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Now().Add(h.ReadTimeout)); err == nil {
+		data, err := ioutil.ReadAll(body)
+		if os.IsTimeout(err) {
+			return nil, errReadTimeout
+		}
+		rc.SetReadDeadline(time.Time{}) // the server reads on while the request executes
+		return data, err
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := ioutil.ReadAll(body)
+		done <- result{data, err}
+	}()
+
+	t := time.NewTimer(h.ReadTimeout)
+	defer t.Stop()
+	select {
+	case res := <-done:
+		return res.data, res.err
+	case <-t.C:
+		r.Body.Close()
+		return nil, errReadTimeout
+	}
+}
+
+func (h *Handler) tooLarge(w http.ResponseWriter, r *http.Request, what string, limit int64) {
+	w.Header().Set("Connection", "close")
+	h.writeError(w, r, http.StatusRequestEntityTooLarge, what+" exceeds the limit of "+strconv.FormatInt(limit, 10)+" bytes")
+}
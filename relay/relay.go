@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	perrors "github.com/pkg/errors"
 	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/trace"
+	"github.com/qdentity/graphql-go/trusted"
 )
 
 func MarshalID(kind string, spec interface{}) graphql.ID {
@@ -44,26 +48,178 @@ func UnmarshalSpec(id graphql.ID, v interface{}) error {
 
 type Handler struct {
 	Schema *graphql.Schema
+
+	// TrustedDocuments, if set, restricts the handler to the documents of the store. Requests
+	// either reference a document by its id, given as "documentId", "doc_id", "id" or as the hash
+	// of an automatic persisted query, or send a source that is one of the trusted documents. The
+	// server-side variables of documents referenced by id are applied, see trusted.Variables.
+	TrustedDocuments *trusted.Store
+
+	// Compress enables gzip and deflate compression of responses of at least CompressMinSize
+	// bytes, for clients accepting one of them.
+	Compress        bool
+	CompressMinSize int
+
+	// CORS, if set, makes the handler answer preflight requests and send CORS headers to the
+	// allowed origins.
+	CORS *CORS
+
+	// CSRFPrevention rejects requests a browser could have sent cross-origin without a preflight
+	// request: those need a content type like application/json or one of the CSRFHeaders, which
+	// default to DefaultCSRFHeaders.
+	CSRFPrevention bool
+	CSRFHeaders    []string
+
+	// LegacyResponses answers with the media type application/json and the status 200 OK for
+	// all GraphQL responses, instead of following the GraphQL over HTTP specification: responses
+	// are application/graphql-response+json unless the client only accepts application/json, and
+	// requests failing before execution, e.g. because of syntax or validation errors, get the
+	// status 400 Bad Request. Errors of HTTP requests are written as text then.
+	LegacyResponses bool
+
+	// MaxBodySize and MaxQueryLength, if positive, limit the size of request bodies and of the
+	// query source in bytes. Larger requests are answered with 413 Request Entity Too Large.
+	MaxBodySize    int64
+	MaxQueryLength int
+
+	// ReadTimeout, if positive, limits the time for reading and parsing the request body. Slower
+	// requests are answered with 408 Request Timeout.
+	ReadTimeout time.Duration
+
+	// TraceContext attaches the trace context of W3C traceparent, tracestate and baggage headers
+	// to the context of requests, so the tracer parents their spans to the caller's, see
+	// trace.SpanContextFromContext. B3 headers are used too if TraceContextB3 is set.
+	TraceContext   bool
+	TraceContextB3 bool
+
+	// EchoTraceID adds the trace and span IDs the request was sent with to the extensions of the
+	// response as "traceId" and "spanId". It requires TraceContext.
+	EchoTraceID bool
+
+	// Encoder, if set, encodes the GraphQL responses instead of json.Marshal, see ResponseEncoder.
+	Encoder ResponseEncoder
+
+	// Indent, if set, pretty-prints the responses with this indent per level, e.g. for debugging.
+	// DisableHTMLEscaping writes <, > and & as they are, which the schema needs to do as well, see
+	// graphql.DisableHTMLEscaping. Both are ignored if Encoder is set.
+	Indent              string
+	DisableHTMLEscaping bool
+
+	// ResponseCache, if set, serves the responses of repeated queries from a cache, see
+	// ResponseCache.
+	ResponseCache *ResponseCache
+}
+
+type params struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	DocumentID    string                 `json:"documentId"`
+	DocID         string                 `json:"doc_id"`
+	ID            string                 `json:"id"`
+	Extensions    struct {
+		PersistedQuery struct {
+			Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+func (p *params) documentID() string {
+	for _, id := range []string{p.DocumentID, p.DocID, p.ID, p.Extensions.PersistedQuery.Hash} {
+		if id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// trustedQuery returns the source of the trusted document referenced by the request, and applies
+// the server-side variables of the document, see trusted.Variables, also if the request sends the
+// source of the document.
+func (h *Handler) trustedQuery(p *params) (string, *errors.QueryError) {
+	if id := p.documentID(); id != "" {
+		query, ok := h.TrustedDocuments.Get(id)
+		if !ok {
+			err := errors.Errorf("PersistedQueryNotFound")
+			err.Extensions = map[string]interface{}{"code": "PERSISTED_QUERY_NOT_FOUND"}
+			return "", err
+		}
+		p.Variables = h.TrustedDocuments.ApplyVariables(id, p.Variables)
+		return query, nil
+	}
+	variables, ok := h.TrustedDocuments.ApplySourceVariables(p.Query, p.Variables)
+	if !ok && h.TrustedDocuments.Allowed(p.Query) {
+		err := errors.Errorf("query is a trusted document with server-side variables, send its id instead")
+		err.Extensions = map[string]interface{}{"code": "PERSISTED_QUERY_NOT_ALLOWED"}
+		return "", err
+	}
+	if !ok {
+		err := errors.Errorf("query is not a trusted document")
+		err.Extensions = map[string]interface{}{"code": "PERSISTED_QUERY_NOT_ALLOWED"}
+		return "", err
+	}
+	p.Variables = variables
+	return p.Query, nil
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var params struct {
-		Query         string                 `json:"query"`
-		OperationName string                 `json:"operationName"`
-		Variables     map[string]interface{} `json:"variables"`
+	if h.CORS != nil && h.handleCORS(w, r) {
+		return
+	}
+	if h.CSRFPrevention && !h.preflighted(r) {
+		h.writeError(w, r, http.StatusBadRequest, "This request has been blocked as a potential Cross-Site Request Forgery. It needs a Content-Type other than application/x-www-form-urlencoded, multipart/form-data or text/plain, or a non-empty "+strings.Join(h.csrfHeaders(), " or ")+" header.")
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+
+	var params params
+	if !h.readParams(w, r, &params) {
 		return
 	}
 
-	response := h.Schema.Exec(r.Context(), params.Query, params.OperationName, params.Variables)
-	responseJSON, err := json.Marshal(response)
+	var response *graphql.Response
+	if h.TrustedDocuments != nil {
+		query, err := h.trustedQuery(&params)
+		if err != nil {
+			response = &graphql.Response{Errors: []*errors.QueryError{err}}
+		}
+		params.Query = query
+	}
+	ctx := r.Context()
+	var sc *trace.SpanContext
+	if h.TraceContext {
+		if sc, _ = trace.ExtractHTTP(r.Header, h.TraceContextB3); sc != nil {
+			ctx = trace.ContextWithSpanContext(ctx, sc)
+		}
+	}
+	if response == nil && acceptsEventStream(r) {
+		h.serveEventStream(ctx, w, r, &params)
+		return
+	}
+	if response == nil && h.ResponseCache != nil {
+		if key, ok := h.ResponseCache.key(r, h.Schema, &params); ok {
+			if response, ok = h.ResponseCache.get(ctx, key); !ok {
+				response = h.Schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+				h.ResponseCache.add(r, h.Schema, &params, key, response)
+			}
+		}
+	}
+	if response == nil {
+		response = h.Schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+	}
+	if h.EchoTraceID && sc != nil {
+		if response.Extensions == nil {
+			response.Extensions = make(map[string]interface{})
+		}
+		response.Extensions["traceId"] = sc.TraceID
+		response.Extensions["spanId"] = sc.SpanID
+	}
+	responseJSON, err := h.encode(r, response)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	mediaType := h.responseType(r)
+	w.Header().Set("Content-Type", mediaType)
+	h.writeResponse(w, r, statusCode(response, mediaType), responseJSON)
 }
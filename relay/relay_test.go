@@ -1,13 +1,25 @@
 package relay_test
 
 import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/qdentity/graphql-go"
 	"github.com/qdentity/graphql-go/example/starwars"
 	"github.com/qdentity/graphql-go/relay"
+	"github.com/qdentity/graphql-go/trace"
+	"github.com/qdentity/graphql-go/trusted"
 )
 
 var starwarsSchema = graphql.MustParseSchema(starwars.Schema, &starwars.Resolver{})
@@ -24,8 +36,8 @@ func TestServeHTTP(t *testing.T) {
 	}
 
 	contentType := w.Header().Get("Content-Type")
-	if contentType != "application/json" {
-		t.Fatalf("Invalid content-type. Expected [application/json], but instead got [%s]", contentType)
+	if contentType != "application/graphql-response+json" {
+		t.Fatalf("Invalid content-type. Expected [application/graphql-response+json], but instead got [%s]", contentType)
 	}
 
 	expectedResponse := `{"data":{"hero":{"name":"R2-D2"}}}`
@@ -34,3 +46,708 @@ func TestServeHTTP(t *testing.T) {
 		t.Fatalf("Invalid response. Expected [%s], but instead got [%s]", expectedResponse, actualResponse)
 	}
 }
+
+func TestServeHTTPTrustedDocuments(t *testing.T) {
+	h := relay.Handler{
+		Schema:           starwarsSchema,
+		TrustedDocuments: trusted.NewStore(map[string]string{"hero": "{ hero { name } }"}),
+	}
+
+	for _, tt := range []struct {
+		body string
+		want string
+	}{
+		{`{"documentId":"hero"}`, `{"data":{"hero":{"name":"R2-D2"}}}`},
+		{`{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"hero"}}}`, `{"data":{"hero":{"name":"R2-D2"}}}`},
+		{`{"query":"{ hero { name } }"}`, `{"data":{"hero":{"name":"R2-D2"}}}`},
+		{`{"documentId":"unknown"}`, `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`},
+		{`{"query":"{ hero { id } }"}`, `{"errors":[{"message":"query is not a trusted document","extensions":{"code":"PERSISTED_QUERY_NOT_ALLOWED"}}]}`},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(tt.body)))
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("request %s: got response %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestServeHTTPTrustedVariables(t *testing.T) {
+	store := trusted.NewStore(map[string]string{
+		"heroes": `query($episode: Episode, $id: ID!) { hero(episode: $episode) { name } human(id: $id) { name } }`,
+	})
+	store.SetVariables("heroes", &trusted.Variables{
+		Defaults: map[string]interface{}{"episode": "EMPIRE"},
+		Fixed:    map[string]interface{}{"id": "1002"},
+	})
+	h := relay.Handler{Schema: starwarsSchema, TrustedDocuments: store}
+
+	for _, tt := range []struct {
+		body string
+		want string
+	}{
+		{`{"documentId":"heroes"}`, `{"data":{"hero":{"name":"Luke Skywalker"},"human":{"name":"Han Solo"}}}`},
+		{`{"documentId":"heroes","variables":{"episode":"JEDI","id":"1000"}}`, `{"data":{"hero":{"name":"R2-D2"},"human":{"name":"Han Solo"}}}`},
+		{`{"query":"query($episode: Episode, $id: ID!) { hero(episode: $episode) { name } human(id: $id) { name } }","variables":{"id":"1000"}}`, `{"data":{"hero":{"name":"Luke Skywalker"},"human":{"name":"Han Solo"}}}`},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(tt.body)))
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("request %s: got response %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
+
+type cacheResolver struct {
+	calls int32
+}
+
+func (r *cacheResolver) Count() int32 {
+	r.calls++
+	return r.calls
+}
+
+func (r *cacheResolver) Greeting() *cacheGreeting { return &cacheGreeting{} }
+
+func (r *cacheResolver) Uncached() int32 { return 0 }
+
+type cacheGreeting struct{}
+
+func (g *cacheGreeting) Text() string { return "hello" }
+
+func TestServeHTTPResponseCache(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		directive @cacheControl(maxAge: Int!) on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			count: Int! @cacheControl(maxAge: 60)
+			greeting: Greeting! @cacheControl(maxAge: 30)
+			uncached: Int!
+		}
+
+		type Greeting {
+			text: String!
+		}
+	`, &cacheResolver{})
+	var ttls []time.Duration
+	h := relay.Handler{Schema: schema, ResponseCache: &relay.ResponseCache{
+		Store: relay.NewMemoryCacheStore(10),
+		TTL: func(r *http.Request, operationName string, cacheControl time.Duration) time.Duration {
+			ttls = append(ttls, cacheControl)
+			return cacheControl
+		},
+		Vary: []string{"Accept-Language"},
+	}}
+
+	for _, tt := range []struct {
+		query    string
+		header   string
+		want     string
+		wantTTLs int
+	}{
+		{`{ count greeting { text } }`, "", `{"data":{"count":1,"greeting":{"text":"hello"}}}`, 1},
+		{`{ count greeting { text } }`, "", `{"data":{"count":1,"greeting":{"text":"hello"}}}`, 1},
+		{`{ count greeting { text } }`, "Accept-Language: de", `{"data":{"count":2,"greeting":{"text":"hello"}}}`, 2},
+		{`{ count greeting { text } }`, "Authorization: Bearer x", `{"data":{"count":3,"greeting":{"text":"hello"}}}`, 2},
+		{`{ count uncached }`, "", `{"data":{"count":4,"uncached":0}}`, 3},
+		{`{ count uncached }`, "", `{"data":{"count":5,"uncached":0}}`, 4},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"`+tt.query+`"}`))
+		if tt.header != "" {
+			kv := strings.SplitN(tt.header, ": ", 2)
+			r.Header.Set(kv[0], kv[1])
+		}
+		h.ServeHTTP(w, r)
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("query %s with %q: got response %s, want %s", tt.query, tt.header, got, tt.want)
+		}
+		if len(ttls) != tt.wantTTLs {
+			t.Errorf("query %s with %q: got %d cacheable responses, want %d", tt.query, tt.header, len(ttls), tt.wantTTLs)
+		}
+	}
+	if want := []time.Duration{30 * time.Second, 30 * time.Second, 0, 0}; !reflect.DeepEqual(ttls, want) {
+		t.Errorf("got TTLs %v, want %v", ttls, want)
+	}
+}
+
+func TestServeHTTPResponseCacheRequestDependent(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		directive @cacheControl(maxAge: Int!) on FIELD_DEFINITION
+
+		schema {
+			query: Query
+		}
+
+		type Query {
+			count: Int! @cacheControl(maxAge: 60)
+		}
+	`, &cacheResolver{}, graphql.UseVisibility(func(ctx context.Context, typeName, fieldName string) bool {
+		return true
+	}))
+
+	for _, tt := range []struct {
+		key  func(r *http.Request) (string, bool)
+		want []string
+	}{
+		{nil, []string{`{"data":{"count":1}}`, `{"data":{"count":2}}`}},
+		{func(r *http.Request) (string, bool) { return "public", true }, []string{`{"data":{"count":3}}`, `{"data":{"count":3}}`}},
+	} {
+		h := relay.Handler{Schema: schema, ResponseCache: &relay.ResponseCache{
+			Store: relay.NewMemoryCacheStore(10),
+			Key:   tt.key,
+		}}
+		for _, want := range tt.want {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ count }"}`)))
+			if got := w.Body.String(); got != want {
+				t.Errorf("with key %t: got response %s, want %s", tt.key != nil, got, want)
+			}
+		}
+	}
+}
+
+type htmlResolver struct{}
+
+func (htmlResolver) Title() string { return "<b>Tom & Jerry</b>" }
+
+func (htmlResolver) Year() int32 { return 1940 }
+
+func TestServeHTTPJSONFormat(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			title: String!
+			year: Int!
+		}
+	`, htmlResolver{}, graphql.DisableHTMLEscaping())
+
+	for _, tt := range []struct {
+		handler relay.Handler
+		want    string
+	}{
+		{
+			relay.Handler{Schema: schema},
+			`{"data":{"year":1940,"title":"\u003cb\u003eTom \u0026 Jerry\u003c/b\u003e"}}`,
+		},
+		{
+			relay.Handler{Schema: schema, DisableHTMLEscaping: true},
+			`{"data":{"year":1940,"title":"<b>Tom & Jerry</b>"}}`,
+		},
+		{
+			relay.Handler{Schema: schema, Indent: "  ", DisableHTMLEscaping: true},
+			"{\n  \"data\": {\n    \"year\": 1940,\n    \"title\": \"<b>Tom & Jerry</b>\"\n  }\n}",
+		},
+	} {
+		w := httptest.NewRecorder()
+		tt.handler.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ year title }"}`)))
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("got response %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestServeHTTPCompression(t *testing.T) {
+	h := relay.Handler{Schema: starwarsSchema, Compress: true, CompressMinSize: 100}
+	const want = `{"data":{"hero":{"name":"R2-D2","friends":[{"name":"Luke Skywalker"},{"name":"Han Solo"},{"name":"Leia Organa"}]}}}`
+
+	for _, tt := range []struct {
+		query          string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{`{ hero { name friends { name } } }`, "gzip, deflate", "gzip"},
+		{`{ hero { name friends { name } } }`, "gzip;q=0.5, deflate", "deflate"},
+		{`{ hero { name friends { name } } }`, "gzip;q=0", ""},
+		{`{ hero { name friends { name } } }`, "br", ""},
+		{`{ hero { name } }`, "gzip", ""}, // below the minimum size
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"`+tt.query+`"}`))
+		r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		h.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+			t.Errorf("Accept-Encoding %q: got Content-Encoding %q, want %q", tt.acceptEncoding, got, tt.wantEncoding)
+			continue
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("got Vary %q, want Accept-Encoding", got)
+		}
+
+		var body io.Reader = w.Body
+		switch tt.wantEncoding {
+		case "gzip":
+			gr, err := gzip.NewReader(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = gr
+		case "deflate":
+			zr, err := zlib.NewReader(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = zr
+		}
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.wantEncoding != "" && string(data) != want {
+			t.Errorf("got response %s, want %s", data, want)
+		}
+	}
+}
+
+func TestServeHTTPCORS(t *testing.T) {
+	h := relay.Handler{
+		Schema: starwarsSchema,
+		CORS: &relay.CORS{
+			AllowedOrigins:   []string{"https://example.com"},
+			AllowedHeaders:   []string{"Authorization"},
+			AllowCredentials: true,
+			MaxAge:           time.Hour,
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("OPTIONS", "/graphql", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("preflight: got status %d, want %d", w.Code, http.StatusNoContent)
+	}
+	for name, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "POST, OPTIONS",
+		"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+		"Access-Control-Max-Age":           "3600",
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("preflight: got %s %q, want %q", name, got, want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ hero { name } }"}`))
+	r.Header.Set("Origin", "https://evil.example")
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("got Access-Control-Allow-Origin %q for a disallowed origin", got)
+	}
+}
+
+func TestServeHTTPCORSWildcard(t *testing.T) {
+	h := relay.Handler{
+		Schema: starwarsSchema,
+		CORS: &relay.CORS{
+			AllowedOrigins:   []string{"https://example.com", "*"},
+			AllowCredentials: true,
+		},
+	}
+
+	for origin, want := range map[string][2]string{
+		"https://example.com":  {"https://example.com", "true"},
+		"https://evil.example": {"*", ""},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ hero { name } }"}`))
+		r.Header.Set("Origin", origin)
+		h.ServeHTTP(w, r)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want[0] {
+			t.Errorf("origin %s: got Access-Control-Allow-Origin %q, want %q", origin, got, want[0])
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != want[1] {
+			t.Errorf("origin %s: got Access-Control-Allow-Credentials %q, want %q", origin, got, want[1])
+		}
+	}
+}
+
+func TestServeHTTPCSRFPrevention(t *testing.T) {
+	h := relay.Handler{Schema: starwarsSchema, CSRFPrevention: true}
+
+	for _, tt := range []struct {
+		header     map[string]string
+		wantStatus int
+	}{
+		{map[string]string{"Content-Type": "application/json"}, http.StatusOK},
+		{map[string]string{"Content-Type": "application/json; charset=utf-8"}, http.StatusOK},
+		{map[string]string{"Content-Type": "text/plain"}, http.StatusBadRequest},
+		{map[string]string{}, http.StatusBadRequest},
+		{map[string]string{"Content-Type": "text/plain", "GraphQL-Require-Preflight": "1"}, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ hero { name } }"}`))
+		for name, value := range tt.header {
+			r.Header.Set(name, value)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != tt.wantStatus {
+			t.Errorf("headers %v: got status %d, want %d", tt.header, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestServeHTTPResponseType(t *testing.T) {
+	for _, tt := range []struct {
+		legacy     bool
+		accept     string
+		body       string
+		wantType   string
+		wantStatus int
+	}{
+		{false, "", `{"query":"{ hero { name } }"}`, relay.GraphQLResponseType, http.StatusOK},
+		{false, "application/graphql-response+json, application/json;q=0.9", `{"query":"{ hero { unknown } }"}`, relay.GraphQLResponseType, http.StatusBadRequest},
+		{false, "*/*", `{"query":"{ hero { "}`, relay.GraphQLResponseType, http.StatusBadRequest},
+		{false, "application/json", `{"query":"{ hero { unknown } }"}`, "application/json", http.StatusOK},
+		{false, "", `{"query":`, relay.GraphQLResponseType, http.StatusBadRequest},
+		{true, "application/graphql-response+json", `{"query":"{ hero { unknown } }"}`, "application/json", http.StatusOK},
+		{true, "", `{"query":`, "text/plain; charset=utf-8", http.StatusBadRequest},
+	} {
+		h := relay.Handler{Schema: starwarsSchema, LegacyResponses: tt.legacy}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/graphql", strings.NewReader(tt.body))
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		h.ServeHTTP(w, r)
+		if got := w.Header().Get("Content-Type"); got != tt.wantType {
+			t.Errorf("%s (legacy %v): got content type %q, want %q", tt.body, tt.legacy, got, tt.wantType)
+		}
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s (legacy %v): got status %d, want %d", tt.body, tt.legacy, w.Code, tt.wantStatus)
+		}
+		if tt.wantType != "text/plain; charset=utf-8" && !strings.Contains(w.Body.String(), `"errors"`) && !strings.Contains(w.Body.String(), `"data"`) {
+			t.Errorf("%s (legacy %v): unexpected body %s", tt.body, tt.legacy, w.Body.String())
+		}
+	}
+}
+
+func TestServeHTTPLimits(t *testing.T) {
+	h := relay.Handler{Schema: starwarsSchema, MaxBodySize: 64, MaxQueryLength: 20, ReadTimeout: 50 * time.Millisecond}
+
+	for _, tt := range []struct {
+		name       string
+		body       io.Reader
+		wantStatus int
+	}{
+		{"small", strings.NewReader(`{"query":"{ hero { name } }"}`), http.StatusOK},
+		{"large body", strings.NewReader(`{"query":"{ hero { name } }", "variables": {"padding": "` + strings.Repeat("x", 64) + `"}}`), http.StatusRequestEntityTooLarge},
+		{"large body without length", io.MultiReader(strings.NewReader(`{"query":"{ hero { name } }", "variables": {"padding": "` + strings.Repeat("x", 64) + `"}}`)), http.StatusRequestEntityTooLarge},
+		{"long query", strings.NewReader(`{"query":"{ hero { name friends { name } } }"}`), http.StatusRequestEntityTooLarge},
+		{"slow body", slowBody(), http.StatusRequestTimeout},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/graphql", tt.body)
+		h.ServeHTTP(w, r)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+		}
+	}
+}
+
+type slowResolver struct{}
+
+// Slow returns after a while whether the context of the request was cancelled meanwhile.
+func (slowResolver) Slow(ctx context.Context) bool {
+	time.Sleep(100 * time.Millisecond)
+	return ctx.Err() != nil
+}
+
+func TestServeHTTPReadTimeoutServer(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+		}
+
+		type Query {
+			slow: Boolean!
+		}
+	`, slowResolver{})
+	srv := httptest.NewServer(&relay.Handler{Schema: schema, ReadTimeout: 50 * time.Millisecond})
+	defer srv.Close()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte(`{"query":`))
+	resp, err := http.Post(srv.URL, "application/json", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("slow body: got status %d, want %d", resp.StatusCode, http.StatusRequestTimeout)
+	}
+
+	// the read timeout does not cancel requests executing for longer
+	resp, err = http.Post(srv.URL, "application/json", strings.NewReader(`{"query":"{ slow }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if got, want := string(body), `{"data":{"slow":false}}`; got != want {
+		t.Errorf("slow query: got %s, want %s", got, want)
+	}
+}
+
+func TestServeHTTPTraceContext(t *testing.T) {
+	h := relay.Handler{Schema: starwarsSchema, TraceContext: true, TraceContextB3: true, EchoTraceID: true}
+
+	for _, tt := range []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"none", nil, `{"data":{"hero":{"name":"R2-D2"}}}`},
+		{"traceparent", map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"},
+			`{"data":{"hero":{"name":"R2-D2"}},"extensions":{"spanId":"00f067aa0ba902b7","traceId":"4bf92f3577b34da6a3ce929d0e0e4736"}}`},
+		{"invalid traceparent", map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"}, `{"data":{"hero":{"name":"R2-D2"}}}`},
+		{"b3", map[string]string{"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"},
+			`{"data":{"hero":{"name":"R2-D2"}},"extensions":{"spanId":"e457b5a2e4d86bd1","traceId":"80f198ee56343ba864fe8b2a57d3eff7"}}`},
+		{"b3 multiple headers", map[string]string{"X-B3-TraceId": "a3ce929d0e0e4736", "X-B3-SpanId": "00f067aa0ba902b7", "X-B3-Sampled": "1"},
+			`{"data":{"hero":{"name":"R2-D2"}},"extensions":{"spanId":"00f067aa0ba902b7","traceId":"0000000000000000a3ce929d0e0e4736"}}`},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ hero { name } }"}`))
+		for name, value := range tt.headers {
+			r.Header.Set(name, value)
+		}
+		h.ServeHTTP(w, r)
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestServeHTTPEventStream(t *testing.T) {
+	h := relay.Handler{Schema: starwarsSchema}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ hero { name } }"}`))
+	r.Header.Set("Accept", "text/event-stream")
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("got content type %q, want text/event-stream", got)
+	}
+	want := "event: next\ndata: {\"data\":{\"hero\":{\"name\":\"R2-D2\"}}}\n\nevent: complete\ndata:\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	}
+}
+
+func TestServeHTTPEncoder(t *testing.T) {
+	h := relay.Handler{
+		Schema: starwarsSchema,
+		Encoder: relay.ResponseEncoderFunc(func(r *http.Request, response *graphql.Response) ([]byte, error) {
+			return json.MarshalIndent(map[string]interface{}{
+				"result": response,
+				"meta":   map[string]string{"tenant": r.Header.Get("X-Tenant")},
+			}, "", " ")
+		}),
+	}
+	post := func(query, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"`+query+`"}`))
+		r.Header.Set("X-Tenant", "acme")
+		r.Header.Set("Accept", accept)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	want := "{\n \"meta\": {\n  \"tenant\": \"acme\"\n },\n \"result\": {\n  \"data\": {\n   \"hero\": {\n    \"name\": \"R2-D2\"\n   }\n  }\n }\n}"
+	if w := post("{ hero { name } }", ""); w.Body.String() != want {
+		t.Errorf("got body %q, want %q", w.Body, want)
+	}
+	if w := post("{", ""); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"meta"`) {
+		t.Errorf("syntax error: got %d %s", w.Code, w.Body)
+	}
+	wantEvent := "event: next\ndata: " + strings.ReplaceAll(want, "\n", "\ndata: ") + "\n\nevent: complete\ndata:\n\n"
+	if w := post("{ hero { name } }", "text/event-stream"); w.Body.String() != wantEvent {
+		t.Errorf("got events %q, want %q", w.Body, wantEvent)
+	}
+}
+
+func TestExtractHTTP(t *testing.T) {
+	h := http.Header{}
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	h.Set("tracestate", "congo=t61rcWkgMzE")
+	h.Add("baggage", "userId=alice,serverNode=DF%2028;prop")
+	h.Add("baggage", "isProduction=false")
+
+	sc, ok := trace.ExtractHTTP(h, false)
+	if !ok {
+		t.Fatal("got no span context")
+	}
+	want := &trace.SpanContext{
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:     "00f067aa0ba902b7",
+		TraceState: "congo=t61rcWkgMzE",
+		Baggage:    map[string]string{"userId": "alice", "serverNode": "DF 28", "isProduction": "false"},
+	}
+	if !reflect.DeepEqual(sc, want) {
+		t.Errorf("got %+v, want %+v", sc, want)
+	}
+
+	if _, ok := trace.ExtractHTTP(http.Header{"B3": {"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1"}}, false); ok {
+		t.Error("got a span context of B3 headers, want them ignored")
+	}
+}
+
+// slowBody returns a body that sends the start of a request and then stalls until it is closed.
+func slowBody() io.Reader {
+	pr, pw := io.Pipe()
+	go pw.Write([]byte(`{"query":`))
+	return pr
+}
+
+func TestSchemaHandler(t *testing.T) {
+	h := &relay.SchemaHandler{Schema: starwarsSchema}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/schema.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("got content type %q", got)
+	}
+	var result struct {
+		Data struct {
+			Schema struct {
+				QueryType struct{ Name string }
+			} `json:"__schema"`
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Data.Schema.QueryType.Name != "Query" {
+		t.Errorf("got query type %q, want Query", result.Data.Schema.QueryType.Name)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/schema.json", nil)
+	r.Header.Set("If-None-Match", `"other", `+etag)
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("revalidation: got status %d with %d bytes, want %d", w.Code, w.Body.Len(), http.StatusNotModified)
+	}
+
+	h.Schema = graphql.MustParseSchema(`schema { query: Query } type Query { hello: String }`, nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("changed schema: got status %d with ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/schema.json", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	h := &relay.Health{}
+	check := func(wantStatus int, wantBody string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+		if w.Code != wantStatus {
+			t.Errorf("got status %d, want %d", w.Code, wantStatus)
+		}
+		if !strings.Contains(w.Body.String(), wantBody) {
+			t.Errorf("got body %s, want it to contain %s", w.Body, wantBody)
+		}
+	}
+
+	check(http.StatusServiceUnavailable, `"schema":{"loaded":false}`)
+
+	h.SchemaLoaded(nil)
+	check(http.StatusOK, `"status":"ok"`)
+
+	h.SchemaLoaded(fmt.Errorf("syntax error"))
+	check(http.StatusOK, `"error":"syntax error"`) // still serving the previous schema
+
+	h.AddProbe("db", func(ctx context.Context) error { return fmt.Errorf("connection refused") })
+	check(http.StatusServiceUnavailable, `"probes":{"db":{"status":"error","error":"connection refused"}}`)
+
+	w := httptest.NewRecorder()
+	h.Live(w, httptest.NewRequest("GET", "/live", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("liveness: got status %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	hello := graphql.MustParseSchema(`schema { query: Query } type Query { hello: String! }`, &helloResolver{})
+	tenants := &relay.Tenants{Route: relay.TenantByPath, MaxInFlight: 1}
+	tenants.Handle("starwars", &relay.Handler{Schema: starwarsSchema})
+	tenants.Handle("hello", &relay.Handler{Schema: hello, MaxQueryLength: 20})
+
+	post := func(path, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		tenants.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(`{"query":"`+query+`"}`)))
+		return w
+	}
+	if w := post("/starwars/graphql", "{ hero { name } }"); w.Body.String() != `{"data":{"hero":{"name":"R2-D2"}}}` {
+		t.Errorf("starwars: got %d %s", w.Code, w.Body)
+	}
+	if w := post("/hello/graphql", "{ hello }"); w.Body.String() != `{"data":{"hello":"Hello!"}}` {
+		t.Errorf("hello: got %d %s", w.Code, w.Body)
+	}
+	if w := post("/hello/graphql", "{ hello hi: hello bye: hello }"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("limits of the tenant: got %d %s", w.Code, w.Body)
+	}
+	if w := post("/other/graphql", "{ hello }"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown tenant: got %d %s", w.Code, w.Body)
+	}
+
+	// a request of a busy tenant is rejected, while other tenants are served
+	entered, release := make(chan struct{}), make(chan struct{})
+	tenants.Handle("busy", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}))
+	go post("/busy", "")
+	<-entered
+	if w := post("/busy", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("busy tenant: got %d %s", w.Code, w.Body)
+	}
+	if w := post("/hello", "{ hello }"); w.Code != http.StatusOK {
+		t.Errorf("other tenant: got %d %s", w.Code, w.Body)
+	}
+	close(release)
+
+	tenants.Remove("hello")
+	if w := post("/hello/graphql", "{ hello }"); w.Code != http.StatusNotFound {
+		t.Errorf("removed tenant: got %d %s", w.Code, w.Body)
+	}
+
+	r := httptest.NewRequest("POST", "http://acme.example.com:8080/graphql", nil)
+	r.Header.Set("X-Tenant", "acme")
+	if got := relay.TenantByHost(r); got != "acme.example.com" {
+		t.Errorf("got host %q", got)
+	}
+	if got := relay.TenantByHeader("X-Tenant")(r); got != "acme" {
+		t.Errorf("got header %q", got)
+	}
+}
+
+type helloResolver struct{}
+
+func (r *helloResolver) Hello() string { return "Hello!" }
@@ -0,0 +1,101 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/qdentity/graphql-go"
+	"github.com/qdentity/graphql-go/errors"
+)
+
+// GraphQLResponseType is the media type of GraphQL responses defined by the GraphQL over HTTP
+// specification.
+const GraphQLResponseType = "application/graphql-response+json"
+
+// responseType returns the media type of the response: application/json if the client accepts it
+// but not application/graphql-response+json, which is the default.
+func (h *Handler) responseType(r *http.Request) string {
+	if h.LegacyResponses {
+		return "application/json"
+	}
+	acceptsJSON := false
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		switch strings.ToLower(strings.TrimSpace(strings.Split(part, ";")[0])) {
+		case GraphQLResponseType:
+			return GraphQLResponseType
+		case "application/json":
+			acceptsJSON = true
+		}
+	}
+	if acceptsJSON {
+		return "application/json"
+	}
+	return GraphQLResponseType
+}
+
+// statusCode returns the status of the response. Responses of the media type
+// application/graphql-response+json without data failed before execution: they get 400 Bad Request,
+// or 504 Gateway Timeout if the deadline of the request was exceeded.
+func statusCode(response *graphql.Response, mediaType string) int {
+	if mediaType != GraphQLResponseType || response.Data != nil {
+		return http.StatusOK
+	}
+	for _, err := range response.Errors {
+		if err.Extensions["code"] == "DEADLINE_EXCEEDED" {
+			return http.StatusGatewayTimeout
+		}
+	}
+	return http.StatusBadRequest
+}
+
+// writeError answers a request that is not executed with the status and a GraphQL response with
+// the message as error, or with the message as text for legacy responses.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if h.LegacyResponses {
+		http.Error(w, message, status)
+		return
+	}
+	body, err := h.encode(r, &graphql.Response{Errors: []*errors.QueryError{errors.Errorf("%s", message)}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", h.responseType(r))
+	h.writeResponse(w, r, status, body)
+}
+
+// ResponseEncoder encodes the GraphQL responses of a Handler, e.g. to add a top-level "meta"
+// object or to wrap them in another envelope. It is used for the responses of requests, including
+// the ones failing before execution unless LegacyResponses is set, and for each response streamed
+// as a server-sent event. The encoding has to be valid JSON.
+type ResponseEncoder interface {
+	EncodeResponse(r *http.Request, response *graphql.Response) ([]byte, error)
+}
+
+// ResponseEncoderFunc is a function implementing ResponseEncoder.
+type ResponseEncoderFunc func(r *http.Request, response *graphql.Response) ([]byte, error)
+
+// EncodeResponse calls f.
+func (f ResponseEncoderFunc) EncodeResponse(r *http.Request, response *graphql.Response) ([]byte, error) {
+	return f(r, response)
+}
+
+// encode encodes the response with the encoder of the handler, or as is.
+func (h *Handler) encode(r *http.Request, response *graphql.Response) ([]byte, error) {
+	if h.Encoder != nil {
+		return h.Encoder.EncodeResponse(r, response)
+	}
+	if h.Indent == "" && !h.DisableHTMLEscaping {
+		return json.Marshal(response)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", h.Indent)
+	enc.SetEscapeHTML(!h.DisableHTMLEscaping)
+	if err := enc.Encode(response); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,89 @@
+package relay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/qdentity/graphql-go"
+)
+
+// SchemaHandler serves the schema as the result of the standard introspection query, the format
+// read by documentation tools like GraphQL Voyager and SpectaQL. It is mounted at any path of a
+// mux. The response has an ETag, so that clients revalidating it with If-None-Match get 304 Not
+// Modified until the schema changes.
+type SchemaHandler struct {
+	Schema *graphql.Schema
+
+	mu     sync.Mutex
+	schema *graphql.Schema // of which data is the introspection result
+	data   []byte
+	etag   string
+}
+
+// ServeHTTP responds to GET and HEAD requests with the introspection result as JSON.
+func (h *SchemaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, etag, err := h.introspect()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
+
+// introspect returns the introspection result of the schema and its ETag, computed once per
+// schema.
+func (h *SchemaHandler) introspect() ([]byte, string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.schema == h.Schema && h.data != nil {
+		return h.data, h.etag, nil
+	}
+
+	schemaJSON, err := h.Schema.ToJSON()
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := json.MarshalIndent(struct {
+		Data json.RawMessage `json:"data"`
+	}{schemaJSON}, "", "\t")
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	h.schema = h.Schema
+	h.data = data
+	h.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	return h.data, h.etag, nil
+}
+
+// etagMatches reports whether the If-None-Match header lists the ETag or is "*".
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// acceptsEventStream reports whether the client asks for server-sent events, which is how
+// subscriptions and live queries are served.
+func acceptsEventStream(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.ToLower(strings.TrimSpace(strings.Split(part, ";")[0])) == "text/event-stream" {
+			return true
+		}
+	}
+	return false
+}
+
+// serveEventStream answers with the responses of the operation as server-sent events, following
+// the distinct connections mode of the GraphQL over SSE protocol: a "next" event per response and
+// a "complete" event at the end. Subscriptions and live queries send responses until the client
+// disconnects, other operations a single one.
+func (h *Handler) serveEventStream(ctx context.Context, w http.ResponseWriter, r *http.Request, params *params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for response := range h.Schema.Subscribe(ctx, params.Query, params.OperationName, params.Variables) {
+		data, err := h.encode(r, response)
+		if err != nil {
+			continue
+		}
+		// each line of an encoding with newlines is a data line of the event
+		fmt.Fprintf(w, "event: next\ndata: %s\n\n", bytes.ReplaceAll(data, []byte("\n"), []byte("\ndata: ")))
+		flusher.Flush()
+	}
+	fmt.Fprint(w, "event: complete\ndata:\n\n")
+	flusher.Flush()
+}
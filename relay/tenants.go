@@ -0,0 +1,107 @@
+package relay
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Tenants serves several GraphQL APIs from one process, passing each request to the handler of its
+// tenant. Each tenant has its own handler, usually a *Handler with the schema and the limits of the
+// tenant, e.g. MaxBodySize. Tenants may share a *graphql.Schema, and with it its operation and
+// response caches, or a trusted.Store. The Scope of a shared response cache has to tell the tenants
+// apart then, unless their data is the same, see graphql.ResponseCacheOptions. Requests of unknown
+// tenants are answered with 404 Not Found.
+type Tenants struct {
+	// Route returns the name of the tenant of the request, see TenantByHost, TenantByHeader and
+	// TenantByPath.
+	Route func(r *http.Request) string
+
+	// MaxInFlight, if positive, limits the requests each tenant serves at once, so that a busy
+	// tenant can not starve the others. Further requests of the tenant are answered with 503
+	// Service Unavailable.
+	MaxInFlight int
+
+	mu      sync.RWMutex
+	tenants map[string]*tenant
+}
+
+type tenant struct {
+	handler  http.Handler
+	inFlight chan struct{} // nil without MaxInFlight
+}
+
+// Handle sets the handler of the tenant with the name, replacing its previous one. It may be called
+// while requests are served, e.g. after reloading the schema of the tenant.
+func (t *Tenants) Handle(name string, h http.Handler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tenants == nil {
+		t.tenants = make(map[string]*tenant)
+	}
+	tn := &tenant{handler: h}
+	if prev, ok := t.tenants[name]; ok {
+		tn.inFlight = prev.inFlight // the limit covers the requests of the previous handler too
+	} else if t.MaxInFlight > 0 {
+		tn.inFlight = make(chan struct{}, t.MaxInFlight)
+	}
+	t.tenants[name] = tn
+}
+
+// Remove removes the tenant with the name. Its pending requests are completed.
+func (t *Tenants) Remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.tenants, name)
+}
+
+// ServeHTTP passes the request to the handler of its tenant.
+func (t *Tenants) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.mu.RLock()
+	tn, ok := t.tenants[t.Route(r)]
+	t.mu.RUnlock()
+	if !ok {
+		http.Error(w, "unknown tenant", http.StatusNotFound)
+		return
+	}
+
+	if tn.inFlight != nil {
+		select {
+		case tn.inFlight <- struct{}{}:
+			defer func() { <-tn.inFlight }()
+		default:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many requests of the tenant", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	tn.handler.ServeHTTP(w, r)
+}
+
+// TenantByHost routes requests by their host without the port, e.g. "acme.example.com".
+func TenantByHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		return r.Host // no port
+	}
+	return host
+}
+
+// TenantByHeader returns a route by the value of the request header with the name, e.g.
+// "X-Tenant".
+func TenantByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// TenantByPath routes requests by the first segment of their path, e.g. "acme" for
+// "/acme/graphql". The handlers get the request with the full path.
+func TenantByPath(r *http.Request) string {
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	if i := strings.IndexByte(p, '/'); i >= 0 {
+		p = p[:i]
+	}
+	return p
+}
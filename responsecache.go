@@ -0,0 +1,191 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCacheOptions configures the response cache, see CacheResponses.
+type ResponseCacheOptions struct {
+	// Size is the maximum number of cached responses. The least recently used ones are evicted.
+	Size int
+
+	// TTL, if positive, limits how long a response is cached. Otherwise it is cached until one of
+	// its objects is invalidated or it is evicted.
+	TTL time.Duration
+
+	// Scope, if set, partitions the cache, e.g. by the user of the request for data that depends
+	// on who asks. Requests for which it returns false are not cached. It is required with
+	// UseAuthorization, UseVisibility or HideInternal, since cached responses are served without
+	// checking the access to their fields again; it has to tell apart all requests whose access
+	// differs then. It is also required with a constructor of root resolvers passed to
+	// ParseSchema, which may return different data depending on the request.
+	Scope func(ctx context.Context) (string, bool)
+}
+
+// CacheResponses caches the responses of queries without errors. Each response is tagged with the
+// objects it resolved with a selected id field, by their keys "Type:id" as for live queries, and
+// it is dropped when one of them is invalidated with Invalidate or InvalidateEntity. Cached
+// responses are served without parsing, validating or executing the query again, except with
+// VariablesHooks: the variables returned by the hooks are part of the key then. The cache is local
+// to the process, invalidations reach other instances only as invalidations of live queries, see
+// LiveQueries.
+func CacheResponses(opts ResponseCacheOptions) SchemaOpt {
+	return func(s *Schema) {
+		s.responseCache = &responseCache{
+			opts:     opts,
+			lru:      list.New(),
+			entries:  make(map[string]*list.Element),
+			byEntity: make(map[string]map[string]bool),
+		}
+	}
+}
+
+// responseCache is an LRU cache of responses, indexed by the keys and types of their objects.
+type responseCache struct {
+	opts     ResponseCacheOptions
+	mu       sync.Mutex
+	lru      *list.List // of *cachedResponse, most recently used first
+	entries  map[string]*list.Element
+	byEntity map[string]map[string]bool // cache keys by object key and by type
+}
+
+type cachedResponse struct {
+	key      string
+	data     json.RawMessage
+	entities []string // object keys and types
+	expires  time.Time
+}
+
+// key returns the cache key of the request, false if it is not cached.
+func (c *responseCache) key(ctx context.Context, queryString string, operationName string, variables map[string]interface{}) (string, bool) {
+	var scope string
+	if c.opts.Scope != nil {
+		var ok bool
+		if scope, ok = c.opts.Scope(ctx); !ok {
+			return "", false
+		}
+	}
+	vars, err := json.Marshal(variables) // sorts the keys of maps
+	if err != nil {
+		return "", false
+	}
+	return scope + "\x00" + operationName + "\x00" + queryString + "\x00" + string(vars), true
+}
+
+// get returns the cached response and adds its objects to entities, if set.
+func (c *responseCache) get(key string, entities *entitySet) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	cached := e.Value.(*cachedResponse)
+	if !cached.expires.IsZero() && time.Now().After(cached.expires) {
+		c.remove(e)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	if entities != nil {
+		for _, entity := range cached.entities {
+			if strings.IndexByte(entity, ':') != -1 {
+				entities.touch(entity)
+			}
+		}
+	}
+	return &Response{Data: cached.data}, true
+}
+
+func (c *responseCache) add(key string, data json.RawMessage, entities *entitySet) {
+	cached := &cachedResponse{key: key, data: data}
+	if c.opts.TTL > 0 {
+		cached.expires = time.Now().Add(c.opts.TTL)
+	}
+	entities.mu.Lock()
+	for k := range entities.keys {
+		cached.entities = append(cached.entities, k)
+	}
+	for t := range entities.types {
+		cached.entities = append(cached.entities, t)
+	}
+	entities.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(cached)
+	for _, entity := range cached.entities {
+		if c.byEntity[entity] == nil {
+			c.byEntity[entity] = make(map[string]bool)
+		}
+		c.byEntity[entity][key] = true
+	}
+	for c.lru.Len() > c.opts.Size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// invalidate drops the responses with the object of the key, "Type:id", or with objects of the
+// type if the key is a type.
+func (c *responseCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for cacheKey := range c.byEntity[key] {
+		c.remove(c.entries[cacheKey])
+	}
+}
+
+func (c *responseCache) remove(e *list.Element) {
+	cached := e.Value.(*cachedResponse)
+	c.lru.Remove(e)
+	delete(c.entries, cached.key)
+	for _, entity := range cached.entities {
+		delete(c.byEntity[entity], cached.key)
+		if len(c.byEntity[entity]) == 0 {
+			delete(c.byEntity, entity)
+		}
+	}
+}
+
+// InvalidateEntity invalidates the object of the type with the id, see Invalidate.
+func (s *Schema) InvalidateEntity(ctx context.Context, typeName string, id string) error {
+	return s.Invalidate(ctx, typeName+":"+id)
+}
+
+// entitySet are the keys of the objects resolved by a request, and their types.
+type entitySet struct {
+	mu    sync.Mutex
+	keys  map[string]bool
+	types map[string]bool
+}
+
+type entitySetKey struct{}
+
+func newEntitySet() *entitySet {
+	return &entitySet{keys: make(map[string]bool), types: make(map[string]bool)}
+}
+
+func (e *entitySet) touch(key string) {
+	e.mu.Lock()
+	e.keys[key] = true
+	e.types[key[:strings.IndexByte(key, ':')]] = true
+	e.mu.Unlock()
+}
+
+// touched reports whether the object with the key "Type:id", or any object of the type if the key
+// is a type, has been resolved.
+func (e *entitySet) touched(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if strings.IndexByte(key, ':') == -1 {
+		return e.types[key]
+	}
+	return e.keys[key]
+}
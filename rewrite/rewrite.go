@@ -0,0 +1,296 @@
+// Package rewrite transforms query documents before they are sent on, e.g. by a gateway adding the
+// id fields of the objects it caches:
+//
+//	doc, err := rewrite.Parse(queryString, schema)
+//	if err != nil {
+//		return err
+//	}
+//	err = doc.Walk(func(f *rewrite.Field) error {
+//		if f.HasSelections() && schema.HasField(f.Type(), "id") && !f.Selects("id") {
+//			return f.AddSelections("id")
+//		}
+//		return nil
+//	})
+//	queryString = doc.String()
+//
+// Fields can be renamed, removed and given other arguments the same way. The document is printed
+// back as GraphQL source, which is valid as long as the changes keep it valid, e.g. do not leave a
+// selection set empty.
+package rewrite
+
+import (
+	"github.com/qdentity/graphql-go/internal/common"
+	"github.com/qdentity/graphql-go/internal/query"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// Schema provides the types of the fields of documents, see Field.Type.
+type Schema struct {
+	schema *schema.Schema
+}
+
+// NewSchema parses the schema.
+func NewSchema(schemaString string) (*Schema, error) {
+	s := schema.New()
+	if err := s.Parse(schemaString); err != nil {
+		return nil, err
+	}
+	return &Schema{schema: s}, nil
+}
+
+// HasField reports whether the object or interface type with the name has a field with the name.
+func (s *Schema) HasField(typeName, fieldName string) bool {
+	return s.field(typeName, fieldName) != nil
+}
+
+func (s *Schema) field(typeName, fieldName string) *schema.Field {
+	switch t := s.schema.Types[typeName].(type) {
+	case *schema.Object:
+		return t.Fields.Get(fieldName)
+	case *schema.Interface:
+		return t.Fields.Get(fieldName)
+	}
+	return nil
+}
+
+// Document is a parsed query document.
+type Document struct {
+	doc    *query.Document
+	schema *Schema
+}
+
+func init() {
+	query.DocumentOf = func(doc interface{}) *query.Document {
+		return doc.(*Document).doc
+	}
+}
+
+// Parse parses the query document. The schema, if not nil, provides the types of its fields.
+func Parse(queryString string, s *Schema) (*Document, error) {
+	doc, err := query.Parse(queryString)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{doc: doc, schema: s}, nil
+}
+
+// String returns the GraphQL source of the document.
+func (d *Document) String() string {
+	return query.Print(d.doc)
+}
+
+// Walk calls visit for the fields of the operations and fragment definitions of the document,
+// each field before the fields it selects. Selections added by visit are visited as well. It stops
+// at the first error returned by visit and returns it.
+func (d *Document) Walk(visit func(f *Field) error) error {
+	for _, op := range d.doc.Operations {
+		var typeName string
+		if d.schema != nil {
+			if t, ok := d.schema.schema.EntryPoints[operationKey(op.Type)]; ok {
+				typeName = t.TypeName()
+			}
+		}
+		if err := d.walk(&op.Selections, typeName, visit); err != nil {
+			return err
+		}
+	}
+	for _, frag := range d.doc.Fragments {
+		if err := d.walk(&frag.Selections, frag.On.Name, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Document) walk(sels *[]query.Selection, parentType string, visit func(f *Field) error) error {
+	for _, sel := range append([]query.Selection(nil), *sels...) { // visit may change the selections
+		switch sel := sel.(type) {
+		case *query.Field:
+			f := &Field{field: sel, parent: sels, parentType: parentType, doc: d}
+			if err := visit(f); err != nil {
+				return err
+			}
+			if f.removed {
+				continue
+			}
+			if err := d.walk(&sel.Selections, f.Type(), visit); err != nil {
+				return err
+			}
+		case *query.InlineFragment:
+			typeName := parentType
+			if sel.On.Name != "" {
+				typeName = sel.On.Name
+			}
+			if err := d.walk(&sel.Selections, typeName, visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func operationKey(t query.OperationType) string {
+	switch t {
+	case query.Mutation:
+		return "mutation"
+	case query.Subscription:
+		return "subscription"
+	default:
+		return "query"
+	}
+}
+
+// Field is a field of a document, see Document.Walk.
+type Field struct {
+	field      *query.Field
+	parent     *[]query.Selection
+	parentType string
+	doc        *Document
+	removed    bool
+}
+
+// Name returns the name of the field in the schema.
+func (f *Field) Name() string {
+	return f.field.Name.Name
+}
+
+// Alias returns the name of the field in the response, which is its name if it has no alias.
+func (f *Field) Alias() string {
+	return f.field.Alias.Name
+}
+
+// ParentType returns the name of the type declaring the field, empty if the document was parsed
+// without a schema.
+func (f *Field) ParentType() string {
+	return f.parentType
+}
+
+// Type returns the name of the type of the field without lists and non-null, e.g. "Character"
+// for [Character!]!. It is empty if the document was parsed without a schema or the schema has no
+// such field.
+func (f *Field) Type() string {
+	if f.doc.schema == nil {
+		return ""
+	}
+	sf := f.doc.schema.field(f.parentType, f.field.Name.Name)
+	if sf == nil {
+		return ""
+	}
+	if named, ok := namedType(sf.Type).(schema.NamedType); ok {
+		return named.TypeName()
+	}
+	return ""
+}
+
+func namedType(t common.Type) common.Type {
+	switch t := t.(type) {
+	case *common.NonNull:
+		return namedType(t.OfType)
+	case *common.List:
+		return namedType(t.OfType)
+	default:
+		return t
+	}
+}
+
+// Rename changes the name of the field in the schema, e.g. to the one of an upstream service. The
+// name of the field in the response is kept.
+func (f *Field) Rename(name string) {
+	f.field.Name.Name = name
+}
+
+// SetAlias changes the name of the field in the response.
+func (f *Field) SetAlias(alias string) {
+	f.field.Alias.Name = alias
+}
+
+// Argument returns the GraphQL source of the value of the argument, e.g. `"1000"` or `$id`.
+func (f *Field) Argument(name string) (string, bool) {
+	value, ok := f.field.Arguments.Get(name)
+	if !ok {
+		return "", false
+	}
+	return value.String(), true
+}
+
+// SetArgument sets the argument to the value given as GraphQL source, e.g. `10` or `[JEDI]`. A
+// variable has to be declared by the operations using the field.
+func (f *Field) SetArgument(name, value string) error {
+	lit, err := query.ParseValue(value)
+	if err != nil {
+		return err
+	}
+	for i, arg := range f.field.Arguments {
+		if arg.Name.Name == name {
+			f.field.Arguments[i].Value = lit
+			return nil
+		}
+	}
+	f.field.Arguments = append(f.field.Arguments, common.Argument{Name: common.Ident{Name: name}, Value: lit})
+	return nil
+}
+
+// RemoveArgument removes the argument, if the field has it.
+func (f *Field) RemoveArgument(name string) {
+	args := f.field.Arguments[:0]
+	for _, arg := range f.field.Arguments {
+		if arg.Name.Name != name {
+			args = append(args, arg)
+		}
+	}
+	f.field.Arguments = args
+}
+
+// HasSelections reports whether the field has a selection set.
+func (f *Field) HasSelections() bool {
+	return len(f.field.Selections) != 0
+}
+
+// Selects reports whether the selection set of the field directly selects a field with the name
+// in the response, not counting fragments.
+func (f *Field) Selects(alias string) bool {
+	for _, sel := range f.field.Selections {
+		if sel, ok := sel.(*query.Field); ok && sel.Alias.Name == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSelections adds the selections given as GraphQL source to the selection set of the field,
+// e.g. "id" or "friends { name }".
+func (f *Field) AddSelections(selections string) error {
+	sels, err := query.ParseSelections(selections)
+	if err != nil {
+		return err
+	}
+	f.field.Selections = append(f.field.Selections, sels...)
+	return nil
+}
+
+// RemoveSelection removes the fields with the name in the response from the selection set of the
+// field, not counting fragments.
+func (f *Field) RemoveSelection(alias string) {
+	f.field.Selections = removeField(f.field.Selections, func(sel *query.Field) bool {
+		return sel.Alias.Name == alias
+	})
+}
+
+// Remove removes the field from its selection set. Its selections are not visited then.
+func (f *Field) Remove() {
+	*f.parent = removeField(*f.parent, func(sel *query.Field) bool {
+		return sel == f.field
+	})
+	f.removed = true
+}
+
+func removeField(sels []query.Selection, remove func(*query.Field) bool) []query.Selection {
+	kept := make([]query.Selection, 0, len(sels))
+	for _, sel := range sels {
+		if field, ok := sel.(*query.Field); ok && remove(field) {
+			continue
+		}
+		kept = append(kept, sel)
+	}
+	return kept
+}
@@ -0,0 +1,102 @@
+package rewrite_test
+
+import (
+	"testing"
+
+	"github.com/qdentity/graphql-go/example/starwars"
+	"github.com/qdentity/graphql-go/rewrite"
+)
+
+func TestWalk(t *testing.T) {
+	s, err := rewrite.NewSchema(starwars.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := rewrite.Parse(`
+		query {
+			hero(episode: EMPIRE) {
+				name
+				friends {
+					name
+					appearsIn
+				}
+				...details
+			}
+		}
+
+		fragment details on Character {
+			friendsConnection(first: 10) {
+				totalCount
+			}
+		}
+	`, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = doc.Walk(func(f *rewrite.Field) error {
+		switch {
+		case f.Name() == "appearsIn":
+			f.Remove()
+		case f.Name() == "friendsConnection":
+			if err := f.SetArgument("first", "3"); err != nil {
+				return err
+			}
+		case f.ParentType() == "Query" && f.Name() == "hero":
+			f.RemoveArgument("episode")
+		}
+		if f.HasSelections() && s.HasField(f.Type(), "id") && !f.Selects("id") {
+			return f.AddSelections("id")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `query {
+  hero {
+    name
+    friends {
+      name
+      id
+    }
+    ...details
+    id
+  }
+}
+
+fragment details on Character {
+  friendsConnection(first: 3) {
+    totalCount
+  }
+}
+`
+	if got := doc.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestField(t *testing.T) {
+	doc, err := rewrite.Parse(`{ human(id: "1000") { name } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Walk(func(f *rewrite.Field) error {
+		if f.Name() != "name" {
+			if id, ok := f.Argument("id"); !ok || id != `"1000"` {
+				t.Errorf("got argument %s, want \"1000\"", id)
+			}
+			return nil
+		}
+		f.Rename("fullName")
+		return f.AddSelections("{")
+	})
+	if err == nil {
+		t.Error("got no error for invalid selections")
+	}
+	want := "query {\n  human(id: \"1000\") {\n    name: fullName\n  }\n}\n"
+	if got := doc.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,20 @@
+// Package sdlfmt formats GraphQL schema definitions, like gofmt does for Go source: the schema
+// definition comes first, followed by the directive declarations and the types, each sorted by
+// name, with the fields, arguments and values in their declared order and a consistent layout.
+// The comments preceding a definition are its description and are kept, other comments are
+// dropped.
+package sdlfmt
+
+import (
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// Format returns the canonical form of the schema definition. Formatting the result again returns
+// it unchanged.
+func Format(sdl []byte) ([]byte, error) {
+	s := schema.New()
+	if err := s.Parse(string(sdl)); err != nil {
+		return nil, err
+	}
+	return []byte(schema.Print(s)), nil
+}
@@ -0,0 +1,114 @@
+package sdlfmt_test
+
+import (
+	"testing"
+
+	"github.com/qdentity/graphql-go/example/starwars"
+	"github.com/qdentity/graphql-go/sdlfmt"
+)
+
+func TestFormat(t *testing.T) {
+	src := `
+		# A search result.
+		union SearchResult = Human|Droid
+
+		schema @auth(role: "user") { query: Query }
+
+		directive @auth(role: String!) on SCHEMA | FIELD_DEFINITION
+
+		type Query {
+			# Finds things.
+			#
+			# Matches names.
+			search(
+				# The text to find.
+				text: String!, first: Int = 10
+			): [SearchResult]! @auth(role: "admin")
+			episode: Episode
+		}
+
+		type Human implements Node & Named { id: ID!, name: String }
+		type Droid implements Node { id: ID! }
+		interface Node { id: ID! }
+		interface Named { name: String }
+
+		enum Episode { NEWHOPE, EMPIRE @deprecated(reason: "old") }
+
+		input Filter { text: String = "x" # trailing
+		  episodes: [Episode!] = [EMPIRE] }
+	`
+	want := `schema @auth(role: "user") {
+  query: Query
+}
+
+directive @auth(role: String!) on SCHEMA | FIELD_DEFINITION
+
+type Droid implements Node {
+  id: ID!
+}
+
+enum Episode {
+  NEWHOPE
+  EMPIRE @deprecated(reason: "old")
+}
+
+input Filter {
+  text: String = "x"
+  # trailing
+  episodes: [Episode!] = [EMPIRE]
+}
+
+type Human implements Node & Named {
+  id: ID!
+  name: String
+}
+
+interface Named {
+  name: String
+}
+
+interface Node {
+  id: ID!
+}
+
+type Query {
+  # Finds things.
+  #
+  # Matches names.
+  search(
+    # The text to find.
+    text: String!
+    first: Int = 10
+  ): [SearchResult]! @auth(role: "admin")
+  episode: Episode
+}
+
+# A search result.
+union SearchResult = Human | Droid
+`
+	got, err := sdlfmt.Format([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := sdlfmt.Format([]byte("type Query { hero: Hero }")); err == nil {
+		t.Error("got no error for an unknown type")
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	formatted, err := sdlfmt.Format([]byte(starwars.Schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := sdlfmt.Format(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(formatted) {
+		t.Errorf("formatting again changed the schema:\n%s\nto\n%s", formatted, again)
+	}
+}
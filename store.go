@@ -0,0 +1,70 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// Key identifies a value of type T in the store of a request, see Set and Get. Keys are compared
+// by identity, so the keys of different packages never collide, even with the same name.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new key for values of type T. The name is only used in messages.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// requestStore holds the values set during a request, see Set.
+type requestStore struct {
+	mu     sync.Mutex
+	values map[interface{}]interface{}
+}
+
+type requestStoreKey struct{}
+
+// WithRequestStore returns a context with a store for values shared during a request, e.g. by an
+// HTTP middleware and the resolvers. Exec, ExecAST, ExecIntrospection and Subscribe add a store to
+// the context of each request unless it already has one, so it is only needed to set values before
+// a request, or to share them among several. The store of a subscription is shared by all of its events.
+func WithRequestStore(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestStoreKey{}).(*requestStore); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestStoreKey{}, &requestStore{values: make(map[interface{}]interface{})})
+}
+
+// Set stores the value under the key in the store of the request, replacing the previous value.
+// It may be called concurrently, e.g. by resolvers, tracers and hooks like VariablesHook. It panics
+// if the context has no store, see WithRequestStore.
+func Set[T any](ctx context.Context, key *Key[T], value T) {
+	st, ok := ctx.Value(requestStoreKey{}).(*requestStore)
+	if !ok {
+		panic("graphql: can not set " + key.name + ", the context has no request store")
+	}
+	st.mu.Lock()
+	st.values[key] = value
+	st.mu.Unlock()
+}
+
+// Get returns the value stored under the key in the store of the request. It reports false if no
+// value was set or the context has no store.
+func Get[T any](ctx context.Context, key *Key[T]) (T, bool) {
+	var value T
+	st, ok := ctx.Value(requestStoreKey{}).(*requestStore)
+	if !ok {
+		return value, false
+	}
+	st.mu.Lock()
+	v, ok := st.values[key]
+	st.mu.Unlock()
+	if ok {
+		value = v.(T)
+	}
+	return value, ok
+}
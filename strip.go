@@ -0,0 +1,168 @@
+package graphql
+
+import (
+	"encoding/json"
+
+	"github.com/qdentity/graphql-go/internal/query"
+)
+
+// stripFields returns the JSON data without the members of objects with the given names, at any
+// depth. The data is returned as it is if it is not valid JSON, e.g. because of a response hook.
+func stripFields(data []byte, names ...string) []byte {
+	if !json.Valid(data) {
+		return data
+	}
+	s := &stripper{data: data, out: make([]byte, 0, len(data)), names: make(map[string]bool)}
+	for _, name := range names {
+		if name != "" {
+			s.names[`"`+name+`"`] = true
+		}
+	}
+	s.value(true)
+	return s.out
+}
+
+type stripper struct {
+	data  []byte
+	pos   int
+	out   []byte
+	names map[string]bool // quoted
+}
+
+// value copies the value at the position to out if write is set, or skips it otherwise.
+func (s *stripper) value(write bool) {
+	s.space()
+	switch s.data[s.pos] {
+	case '{':
+		s.pos++
+		s.write(write, '{')
+		first := true
+		for {
+			s.space()
+			if s.data[s.pos] == '}' {
+				s.pos++
+				break
+			}
+			if s.data[s.pos] == ',' {
+				s.pos++
+				s.space()
+			}
+			start := s.pos
+			s.skipString()
+			key := s.data[start:s.pos]
+			s.space()
+			s.pos++ // colon
+			keep := write && !s.names[string(key)]
+			if keep {
+				if !first {
+					s.out = append(s.out, ',')
+				}
+				first = false
+				s.out = append(s.out, key...)
+				s.out = append(s.out, ':')
+			}
+			s.value(keep)
+		}
+		s.write(write, '}')
+	case '[':
+		s.pos++
+		s.write(write, '[')
+		for {
+			s.space()
+			if s.data[s.pos] == ']' {
+				s.pos++
+				break
+			}
+			if s.data[s.pos] == ',' {
+				s.pos++
+				s.write(write, ',')
+			}
+			s.value(write)
+		}
+		s.write(write, ']')
+	case '"':
+		start := s.pos
+		s.skipString()
+		if write {
+			s.out = append(s.out, s.data[start:s.pos]...)
+		}
+	default: // number, true, false or null
+		start := s.pos
+		for s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+			default:
+				s.pos++
+				continue
+			}
+			break
+		}
+		if write {
+			s.out = append(s.out, s.data[start:s.pos]...)
+		}
+	}
+}
+
+func (s *stripper) skipString() {
+	s.pos++ // opening quote
+	for s.data[s.pos] != '"' {
+		if s.data[s.pos] == '\\' {
+			s.pos++
+		}
+		s.pos++
+	}
+	s.pos++
+}
+
+func (s *stripper) space() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *stripper) write(write bool, b byte) {
+	if write {
+		s.out = append(s.out, b)
+	}
+}
+
+// usesResponseName reports whether a field of the document has one of the response names.
+func usesResponseName(doc *query.Document, names ...string) bool {
+	var uses func(sels []query.Selection) bool
+	uses = func(sels []query.Selection) bool {
+		for _, sel := range sels {
+			switch sel := sel.(type) {
+			case *query.Field:
+				for _, name := range names {
+					if sel.Alias.Name == name {
+						return true
+					}
+				}
+				if uses(sel.Selections) {
+					return true
+				}
+			case *query.InlineFragment:
+				if uses(sel.Selections) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, op := range doc.Operations {
+		if uses(op.Selections) {
+			return true
+		}
+	}
+	for _, frag := range doc.Fragments {
+		if uses(frag.Selections) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package graphql
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/qdentity/graphql-go/internal/query"
+)
+
+// Subscribe executes a subscription with the schema's resolver. The resolver method of the
+// subscription field returns a channel, e.g. <-chan *MessageResolver, whose events are resolved
+// like the values of query fields. A response is sent on the returned channel for each event, and
+// the channel is closed when the resolver's channel is closed or ctx is done. The resolver should
+// stop sending events when ctx is done. If the subscription can not be started, e.g. because of a
+// validation error, the channel has a single response with the errors. Queries and mutations are
+// executed once, with their response as the only one, except for live queries, see LiveQueries.
+func (s *Schema) Subscribe(ctx context.Context, queryString string, operationName string, variables map[string]interface{}) <-chan *Response {
+	if s.res == nil {
+		panic("schema created without resolver, can not subscribe")
+	}
+	ctx = WithRequestStore(ctx)
+
+	visible := s.visibleFunc(ctx)
+	doc, op, vars, warnings, errs := s.prepare(ctx, queryString, nil, operationName, variables, visible)
+	if len(errs) != 0 {
+		resp := &Response{Errors: errs}
+		if len(warnings) != 0 {
+			resp.Extensions = map[string]interface{}{"warnings": warnings}
+			s.logWarnings(ctx, warnings)
+		}
+		return singleResponse(resp)
+	}
+	prepared := &preparedQuery{doc: doc, op: op, variables: vars, warnings: warnings}
+	if op.Type == query.Query && op.Directives.Get("live") != nil {
+		return s.subscribeLive(ctx, queryString, prepared, operationName, variables)
+	}
+	if op.Type != query.Subscription {
+		return singleResponse(s.exec(ctx, queryString, nil, prepared, operationName, variables, s.res))
+	}
+
+	s.logWarnings(ctx, warnings)
+	events := s.newRequest(doc, vars, visible).Subscribe(ctx, s.res, op)
+	responses := make(chan *Response)
+	go func() {
+		defer close(responses)
+		for event := range events {
+			resp := &Response{Data: event.Data, Errors: event.Errors}
+			if eventWarnings := append(warnings[:len(warnings):len(warnings)], event.Warnings...); len(eventWarnings) != 0 {
+				resp.Extensions = map[string]interface{}{"warnings": eventWarnings}
+			}
+			select {
+			case responses <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return responses
+}
+
+func singleResponse(resp *Response) <-chan *Response {
+	responses := make(chan *Response, 1)
+	responses <- resp
+	close(responses)
+	return responses
+}
+
+// FilterEvents filters and maps the events of the subscription field given as
+// "Subscription.field" before they are resolved. The filter is a func(context.Context, T) (T, bool,
+// error), where T is the type of the events of the channel returned by the field's resolver
+// method. It is called with the context of each subscriber, so subscribers sharing the events of
+// a Broadcaster can each get only the events meant for them: events for which it returns false
+// are dropped, the event it returns is resolved instead of the original one. An error is sent to
+// the subscriber as the error of the field.
+func FilterEvents(field string, filter interface{}) SchemaOpt {
+	return func(s *Schema) {
+		if s.eventFilters == nil {
+			s.eventFilters = make(map[string]interface{})
+		}
+		s.eventFilters[field] = filter
+	}
+}
+
+// Broadcaster fans the events of an upstream channel out to any number of subscribers, e.g. so
+// that all subscriptions of a field share one connection to a message broker. Each event is sent to
+// all subscribers before the next one is received, so a slow subscriber holds up the others unless
+// its channel has a buffer for the events it falls behind.
+type Broadcaster struct {
+	elem reflect.Type
+
+	mu     sync.Mutex
+	subs   map[*subscriber]struct{}
+	closed bool
+	done   chan struct{} // closed with closed
+}
+
+type subscriber struct {
+	ch   reflect.Value
+	done <-chan struct{}
+
+	mu     sync.Mutex // held while sending, so ch is not closed during a send
+	closed bool
+}
+
+func (sub *subscriber) close() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		sub.ch.Close()
+	}
+}
+
+// send sends the event to the subscriber unless its context is done.
+func (sub *subscriber) send(event reflect.Value) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: sub.ch, Send: event},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.done)},
+	})
+}
+
+// NewBroadcaster returns a broadcaster of the events of upstream, which has to be a channel the
+// events can be received from, e.g. a chan *Message or <-chan *Message. The subscribers' channels
+// are closed when upstream is closed.
+func NewBroadcaster(upstream interface{}) *Broadcaster {
+	ch := reflect.ValueOf(upstream)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		panic("graphql.NewBroadcaster: upstream is not a channel to receive from")
+	}
+	b := &Broadcaster{
+		elem: ch.Type().Elem(),
+		subs: make(map[*subscriber]struct{}),
+		done: make(chan struct{}),
+	}
+	go b.run(ch)
+	return b
+}
+
+// Subscribe returns a channel of the events sent after the call, of type <-chan T for an upstream
+// channel of T. It has a buffer for the given number of events. The channel is closed when ctx is
+// done or the upstream channel is closed.
+func (b *Broadcaster) Subscribe(ctx context.Context, buffer int) interface{} {
+	sub := &subscriber{
+		ch:   reflect.MakeChan(reflect.ChanOf(reflect.BothDir, b.elem), buffer),
+		done: ctx.Done(),
+	}
+	recvOnly := sub.ch.Convert(reflect.ChanOf(reflect.RecvDir, b.elem)).Interface()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.close()
+		return recvOnly
+	}
+	b.subs[sub] = struct{}{}
+	if sub.done != nil {
+		go func() {
+			select {
+			case <-sub.done:
+			case <-b.done:
+				return // closed by run
+			}
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.mu.Unlock()
+			sub.close()
+		}()
+	}
+	return recvOnly
+}
+
+func (b *Broadcaster) run(upstream reflect.Value) {
+	for {
+		event, ok := upstream.Recv()
+		if !ok {
+			break
+		}
+		b.mu.Lock()
+		subs := make([]*subscriber, 0, len(b.subs))
+		for sub := range b.subs {
+			subs = append(subs, sub)
+		}
+		b.mu.Unlock()
+		for _, sub := range subs {
+			sub.send(event)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	close(b.done)
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		sub.close()
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !js && !wasip1
+// +build !js,!wasip1
+
+package graphql
+
+// defaultSynchronous is whether requests are executed synchronously by default, see Synchronous.
+const defaultSynchronous = false
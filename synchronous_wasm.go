@@ -0,0 +1,8 @@
+//go:build js || wasip1
+// +build js wasip1
+
+package graphql
+
+// defaultSynchronous is whether requests are executed synchronously by default, see Synchronous.
+// WebAssembly runs on a single thread, so that goroutines only add overhead.
+const defaultSynchronous = true
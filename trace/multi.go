@@ -0,0 +1,150 @@
+package trace
+
+import (
+	"context"
+
+	"github.com/qdentity/graphql-go/errors"
+	"github.com/qdentity/graphql-go/introspection"
+)
+
+// Multi returns a tracer passing the trace events to all of the tracers, e.g. to record metrics
+// and spans at the same time. Each tracer gets the context returned by the ones before it, and the
+// finish functions are called in reverse order. A tracer that panics is isolated: the panic is
+// recovered and the event is passed on to the other tracers as if it had not been traced.
+//
+// The returned tracer implements FieldTracer, FieldContextTracer, StatsTracer, RequestTracer and
+// ConcurrencyTracer, passing their events to the tracers implementing them. If one of the tracers
+// is a RewritingFieldTracer, so is the returned one: the errors of fields pass through the rewrite
+// functions in order, and the fields of the other tracers are finished when the rewrite functions
+// are called.
+func Multi(tracers ...Tracer) Tracer {
+	m := multi(tracers)
+	for _, t := range tracers {
+		if _, ok := t.(RewritingFieldTracer); ok {
+			return multiRewriting{m}
+		}
+	}
+	return m
+}
+
+type multi []Tracer
+
+func (m multi) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, TraceQueryFinishFunc) {
+	finishes := make([]TraceQueryFinishFunc, 0, len(m))
+	for _, t := range m {
+		isolate(func() {
+			tctx, finish := t.TraceQuery(ctx, queryString, operationName, variables, varTypes)
+			ctx = tctx
+			finishes = append(finishes, finish)
+		})
+	}
+	return ctx, func(errs []*errors.QueryError) {
+		for i := len(finishes) - 1; i >= 0; i-- {
+			isolate(func() { finishes[i](errs) })
+		}
+	}
+}
+
+func (m multi) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, TraceFieldFinishFunc) {
+	return m.TraceFieldID(ctx, NewFieldIdentifier(typeName, fieldName), trivial, args)
+}
+
+func (m multi) TraceFieldID(ctx context.Context, field *FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, TraceFieldFinishFunc) {
+	finishes := make([]TraceFieldFinishFunc, 0, len(m))
+	for _, t := range m {
+		isolate(func() {
+			tctx, finish := traceFieldID(t, ctx, field, trivial, args)
+			ctx = tctx
+			finishes = append(finishes, finish)
+		})
+	}
+	return ctx, func(err *errors.QueryError) {
+		for i := len(finishes) - 1; i >= 0; i-- {
+			isolate(func() { finishes[i](err) })
+		}
+	}
+}
+
+// TracesFieldContext implements FieldContextTracer.
+func (m multi) TracesFieldContext() bool {
+	for _, t := range m {
+		if ft, ok := t.(FieldContextTracer); ok && ft.TracesFieldContext() {
+			return true
+		}
+	}
+	return false
+}
+
+// TraceQueryStats implements StatsTracer.
+func (m multi) TraceQueryStats(ctx context.Context, stats QueryStats) {
+	for _, t := range m {
+		if st, ok := t.(StatsTracer); ok {
+			isolate(func() { st.TraceQueryStats(ctx, stats) })
+		}
+	}
+}
+
+// TraceRequestDone implements RequestTracer.
+func (m multi) TraceRequestDone(ctx context.Context, errs []*errors.QueryError, responseSize int) {
+	for _, t := range m {
+		if rt, ok := t.(RequestTracer); ok {
+			isolate(func() { rt.TraceRequestDone(ctx, errs, responseSize) })
+		}
+	}
+}
+
+// TraceFieldConcurrency implements ConcurrencyTracer.
+func (m multi) TraceFieldConcurrency(ctx context.Context, field *FieldIdentifier, c FieldConcurrency) {
+	for _, t := range m {
+		if ct, ok := t.(ConcurrencyTracer); ok {
+			isolate(func() { ct.TraceFieldConcurrency(ctx, field, c) })
+		}
+	}
+}
+
+type multiRewriting struct {
+	multi
+}
+
+// TraceFieldRewrite implements RewritingFieldTracer.
+func (m multiRewriting) TraceFieldRewrite(ctx context.Context, field *FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, TraceFieldRewriteFunc) {
+	rewrites := make([]TraceFieldRewriteFunc, 0, len(m.multi))
+	for _, t := range m.multi {
+		isolate(func() {
+			if rt, ok := t.(RewritingFieldTracer); ok {
+				tctx, rewrite := rt.TraceFieldRewrite(ctx, field, trivial, args)
+				ctx = tctx
+				rewrites = append(rewrites, rewrite)
+				return
+			}
+			tctx, finish := traceFieldID(t, ctx, field, trivial, args)
+			ctx = tctx
+			rewrites = append(rewrites, func(err *errors.QueryError) *errors.QueryError {
+				finish(err)
+				return err
+			})
+		})
+	}
+	return ctx, func(err *errors.QueryError) *errors.QueryError {
+		for _, rewrite := range rewrites {
+			isolate(func() { err = rewrite(err) })
+		}
+		return err
+	}
+}
+
+// traceFieldID traces the field by its identifier if the tracer supports it.
+func traceFieldID(t Tracer, ctx context.Context, field *FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, TraceFieldFinishFunc) {
+	if ft, ok := t.(FieldTracer); ok {
+		return ft.TraceFieldID(ctx, field, trivial, args)
+	}
+	return t.TraceField(ctx, field.Label, field.TypeName, field.FieldName, trivial, args)
+}
+
+// isolate calls f and recovers a panic of the tracer it calls.
+func isolate(f func()) {
+	defer func() {
+		recover()
+	}()
+	f()
+}
@@ -0,0 +1,179 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SpanContext is the trace context a request was sent with, the trace and the span of the caller.
+// Tracers use it to parent the spans of the request, see SpanContextFromContext.
+type SpanContext struct {
+	// TraceID is the trace as 32 and SpanID the span of the caller as 16 lowercase hex digits.
+	TraceID string
+	SpanID  string
+
+	Sampled bool
+
+	// TraceState is the vendor-specific state of the W3C tracestate header.
+	TraceState string
+
+	// Baggage are the entries of the W3C baggage header, without their properties.
+	Baggage map[string]string
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a context carrying the span context.
+func ContextWithSpanContext(ctx context.Context, sc *SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context the request of the context was sent with.
+func SpanContextFromContext(ctx context.Context) (*SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(*SpanContext)
+	return sc, ok
+}
+
+// ExtractHTTP returns the span context of the W3C traceparent, tracestate and baggage headers. If
+// b3 is true, B3 headers are used for requests without a traceparent header, both the single b3
+// header and the X-B3-TraceId, X-B3-SpanId and X-B3-Sampled headers. It returns false if there is
+// no valid trace context.
+func ExtractHTTP(h http.Header, b3 bool) (*SpanContext, bool) {
+	sc, ok := parseTraceparent(h.Get("traceparent"))
+	if ok {
+		sc.TraceState = h.Get("tracestate")
+	} else if b3 {
+		sc, ok = parseB3(h)
+	}
+	if !ok {
+		return nil, false
+	}
+	sc.Baggage = parseBaggage(h["Baggage"])
+	return sc, true
+}
+
+// Header returns the span context as W3C headers, e.g. for extracting it with a tracer that
+// understands them.
+func (sc *SpanContext) Header() http.Header {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	h := http.Header{}
+	h.Set("traceparent", "00-"+sc.TraceID+"-"+sc.SpanID+"-"+flags)
+	if sc.TraceState != "" {
+		h.Set("tracestate", sc.TraceState)
+	}
+	if len(sc.Baggage) != 0 {
+		var entries []string
+		for key, value := range sc.Baggage {
+			entries = append(entries, key+"="+url.PathEscape(value))
+		}
+		h.Set("baggage", strings.Join(entries, ","))
+	}
+	return h
+}
+
+// parseTraceparent parses a header like "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+// Headers of later versions may have more fields, which are ignored.
+func parseTraceparent(header string) (*SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !isHex(parts[0]) {
+		return nil, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return nil, false
+	}
+	traceID, spanID, flags := parts[1], parts[2], parts[3]
+	if !validID(traceID, 32) || !validID(spanID, 16) || len(flags) != 2 || !isHex(flags) {
+		return nil, false
+	}
+	return &SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: hexValue(flags[1])&1 == 1,
+	}, true
+}
+
+// parseB3 parses the single b3 header, "traceid-spanid-sampled-parentspanid" with optional
+// sampling state and parent, or the multiple X-B3 headers. 64-bit trace IDs are padded to 128 bits.
+func parseB3(h http.Header) (*SpanContext, bool) {
+	var traceID, spanID, sampled string
+	if single := h.Get("b3"); single != "" {
+		parts := strings.Split(single, "-")
+		if len(parts) < 2 {
+			return nil, false // only a sampling decision
+		}
+		traceID, spanID = parts[0], parts[1]
+		if len(parts) > 2 {
+			sampled = parts[2]
+		}
+	} else {
+		traceID, spanID, sampled = h.Get("X-B3-TraceId"), h.Get("X-B3-SpanId"), h.Get("X-B3-Sampled")
+		if h.Get("X-B3-Flags") == "1" {
+			sampled = "d"
+		}
+	}
+	traceID, spanID = strings.ToLower(traceID), strings.ToLower(spanID)
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if !validID(traceID, 32) || !validID(spanID, 16) {
+		return nil, false
+	}
+	return &SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: sampled == "1" || sampled == "d" || sampled == "true",
+	}, true
+}
+
+// parseBaggage parses headers like "userId=alice,isProduction=false;prop", percent-decoding the
+// values.
+func parseBaggage(headers []string) map[string]string {
+	var baggage map[string]string
+	for _, header := range headers {
+		for _, entry := range strings.Split(header, ",") {
+			if i := strings.IndexByte(entry, ';'); i != -1 {
+				entry = entry[:i] // properties
+			}
+			i := strings.IndexByte(entry, '=')
+			if i == -1 {
+				continue
+			}
+			key := strings.TrimSpace(entry[:i])
+			value, err := url.PathUnescape(strings.TrimSpace(entry[i+1:]))
+			if key == "" || err != nil {
+				continue
+			}
+			if baggage == nil {
+				baggage = make(map[string]string)
+			}
+			baggage[key] = value
+		}
+	}
+	return baggage
+}
+
+// validID reports whether id has n lowercase hex digits that are not all zero.
+func validID(id string, n int) bool {
+	return len(id) == n && isHex(id) && strings.Trim(id, "0") != ""
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func hexValue(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
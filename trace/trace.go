@@ -3,6 +3,8 @@ package trace
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -19,10 +21,122 @@ type Tracer interface {
 	TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, TraceFieldFinishFunc)
 }
 
+// FieldIdentifier identifies a field of the schema. Identifiers are created once per field when the
+// schema is parsed and shared by all requests, so tracers may use them as map keys, e.g. to cache
+// per-field state. They must not be modified.
+type FieldIdentifier struct {
+	TypeName  string
+	FieldName string
+
+	// Label is the name of the field's span, "GraphQL field: Type.field".
+	Label string
+}
+
+type fieldKey struct {
+	typeName, fieldName string
+}
+
+var fieldIdentifiers sync.Map // of fieldKey to *FieldIdentifier
+
+// NewFieldIdentifier returns the identifier of the field of the type. Identifiers are interned, so
+// it returns the same pointer for the same names. The type name of meta fields like __typename is
+// empty.
+func NewFieldIdentifier(typeName, fieldName string) *FieldIdentifier {
+	key := fieldKey{typeName, fieldName}
+	if id, ok := fieldIdentifiers.Load(key); ok {
+		return id.(*FieldIdentifier)
+	}
+	label := "GraphQL field: " + fieldName
+	if typeName != "" {
+		label = "GraphQL field: " + typeName + "." + fieldName
+	}
+	id, _ := fieldIdentifiers.LoadOrStore(key, &FieldIdentifier{TypeName: typeName, FieldName: fieldName, Label: label})
+	return id.(*FieldIdentifier)
+}
+
+// FieldTracer may be implemented by a Tracer to trace fields by their identifiers. TraceFieldID is
+// then called instead of TraceField.
+type FieldTracer interface {
+	TraceFieldID(ctx context.Context, field *FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, TraceFieldFinishFunc)
+}
+
+// TraceFieldRewriteFunc finishes the trace of a field like a TraceFieldFinishFunc and returns the
+// error reported for the field instead of the given one: the same error, possibly annotated, e.g.
+// with an extension, a different error, or nil to drop it.
+type TraceFieldRewriteFunc func(*errors.QueryError) *errors.QueryError
+
+// RewritingFieldTracer may be implemented by a Tracer whose finish functions rewrite the errors of
+// fields, e.g. to classify the timeouts of upstream services. TraceFieldRewrite is then called
+// instead of TraceField and TraceFieldID. The returned function is called when the field's
+// resolver has returned, before the field's selections are resolved, since the error is needed
+// to resolve them. If it drops the error, the field is null without an error, unless the field is
+// non-null: its error is kept then, since its null makes the parent null. If it returns an
+// error for a field without one, the field is null with that error.
+type RewritingFieldTracer interface {
+	TraceFieldRewrite(ctx context.Context, field *FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, TraceFieldRewriteFunc)
+}
+
+// FieldContextTracer may be implemented by a Tracer that needs the field context, see
+// graphql.FieldContext, in the contexts it gets for fields, e.g. to implement policies based on the
+// directives applied to them. If TracesFieldContext returns true, the contexts passed to TraceField,
+// TraceFieldID and TraceFieldRewrite have it. It is left out otherwise, since it costs allocations
+// for each field.
+type FieldContextTracer interface {
+	TracesFieldContext() bool
+}
+
+// RequestTracer may be implemented by a Tracer to be told when the response of a request is
+// complete. TraceRequestDone is called with the context returned by TraceQuery, all errors of the
+// response, including the ones reported by the finish functions of fields, and the size of its
+// data in bytes, before the request's finish function is called.
+type RequestTracer interface {
+	TraceRequestDone(ctx context.Context, errs []*errors.QueryError, responseSize int)
+}
+
+// QueryStats describe the shape of a query, see graphql.ReportQueryStats.
+type QueryStats struct {
+	Depth           int `json:"depth"`
+	Complexity      int `json:"complexity"`
+	Fields          int `json:"fields"`
+	Fragments       int `json:"fragments"`
+	FragmentSpreads int `json:"fragmentSpreads"`
+}
+
+// StatsTracer may be implemented by a Tracer to record the stats of queries. TraceQueryStats is
+// called with the context returned by TraceQuery.
+type StatsTracer interface {
+	TraceQueryStats(ctx context.Context, stats QueryStats)
+}
+
+// FieldConcurrency describes how a field was scheduled, see ConcurrencyTracer.
+type FieldConcurrency struct {
+	// Parallel reports whether the field was resolved concurrently with the other fields of its
+	// selection set. Otherwise it was resolved serially, e.g. as a field of a mutation.
+	Parallel bool
+
+	// Group is the number of fields resolved in parallel with the field, including it, 1 if serial.
+	Group int
+
+	// QueueWait is how long the field waited for one of the resolvers allowed to run in parallel,
+	// see graphql.MaxParallelism.
+	QueueWait time.Duration
+
+	// Run is how long the field's resolver ran.
+	Run time.Duration
+}
+
+// ConcurrencyTracer may be implemented by a Tracer to record how fields were scheduled, e.g. to
+// tune graphql.MaxParallelism. TraceFieldConcurrency is called for each field that is not trivial,
+// when its resolver has returned, with the context of the field returned by TraceField,
+// TraceFieldID or TraceFieldRewrite, before the field is finished.
+type ConcurrencyTracer interface {
+	TraceFieldConcurrency(ctx context.Context, field *FieldIdentifier, c FieldConcurrency)
+}
+
 type OpenTracingTracer struct{}
 
 func (OpenTracingTracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, TraceQueryFinishFunc) {
-	span, spanCtx := opentracing.StartSpanFromContext(ctx, "GraphQL request")
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "GraphQL request", remoteParent(ctx)...)
 	span.SetTag("graphql.query", queryString)
 
 	if operationName != "" {
@@ -41,6 +155,7 @@ func (OpenTracingTracer) TraceQuery(ctx context.Context, queryString string, ope
 			}
 			ext.Error.Set(span, true)
 			span.SetTag("graphql.error", msg)
+			setErrorCode(span, errs[0])
 		}
 		span.Finish()
 	}
@@ -62,11 +177,78 @@ func (OpenTracingTracer) TraceField(ctx context.Context, label, typeName, fieldN
 		if err != nil {
 			ext.Error.Set(span, true)
 			span.SetTag("graphql.error", err.Error())
+			setErrorCode(span, err)
 		}
 		span.Finish()
 	}
 }
 
+func (t OpenTracingTracer) TraceFieldID(ctx context.Context, field *FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, TraceFieldFinishFunc) {
+	return t.TraceField(ctx, field.Label, field.TypeName, field.FieldName, trivial, args)
+}
+
+// TraceQueryStats tags the span of the request with the stats of the query.
+func (OpenTracingTracer) TraceQueryStats(ctx context.Context, stats QueryStats) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	span.SetTag("graphql.depth", stats.Depth)
+	span.SetTag("graphql.complexity", stats.Complexity)
+	span.SetTag("graphql.fields", stats.Fields)
+	span.SetTag("graphql.fragments", stats.Fragments)
+	span.SetTag("graphql.fragmentSpreads", stats.FragmentSpreads)
+}
+
+// TraceFieldConcurrency tags the span of the field with how it was scheduled.
+func (OpenTracingTracer) TraceFieldConcurrency(ctx context.Context, field *FieldIdentifier, c FieldConcurrency) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	span.SetTag("graphql.parallel", c.Parallel)
+	span.SetTag("graphql.parallelGroup", c.Group)
+	span.SetTag("graphql.queueWaitMs", float64(c.QueueWait)/float64(time.Millisecond))
+	span.SetTag("graphql.runMs", float64(c.Run)/float64(time.Millisecond))
+}
+
+// TraceRequestDone tags the span of the request with the size of the response and the number of
+// its errors.
+func (OpenTracingTracer) TraceRequestDone(ctx context.Context, errs []*errors.QueryError, responseSize int) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	span.SetTag("graphql.response.size", responseSize)
+	span.SetTag("graphql.response.errors", len(errs))
+}
+
+// remoteParent returns the option to parent the span of a request to the span of its caller, if the
+// context has no span yet and the global tracer understands the trace context the request was sent
+// with, see ContextWithSpanContext.
+func remoteParent(ctx context.Context) []opentracing.StartSpanOption {
+	if opentracing.SpanFromContext(ctx) != nil {
+		return nil
+	}
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	parent, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(sc.Header()))
+	if err != nil {
+		return nil
+	}
+	return []opentracing.StartSpanOption{opentracing.ChildOf(parent)}
+}
+
+// setErrorCode tags the span with the "code" extension of the error, e.g. DEADLINE_EXCEEDED or
+// CANCELLED if the request's context was done.
+func setErrorCode(span opentracing.Span, err *errors.QueryError) {
+	if code, ok := err.Extensions["code"]; ok {
+		span.SetTag("graphql.error.code", code)
+	}
+}
+
 func noop(*errors.QueryError) {}
 
 type NoopTracer struct{}
@@ -78,3 +260,7 @@ func (NoopTracer) TraceQuery(ctx context.Context, queryString string, operationN
 func (NoopTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, TraceFieldFinishFunc) {
 	return ctx, func(err *errors.QueryError) {}
 }
+
+func (NoopTracer) TraceFieldID(ctx context.Context, field *FieldIdentifier, trivial bool, args map[string]interface{}) (context.Context, TraceFieldFinishFunc) {
+	return ctx, noop
+}
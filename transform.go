@@ -0,0 +1,186 @@
+package graphql
+
+import (
+	"sort"
+	"strings"
+
+	perrors "github.com/pkg/errors"
+	"github.com/qdentity/graphql-go/internal/schema"
+)
+
+// DirectiveHandler rewrites a field definition marked with its directive, see TransformDirective.
+type DirectiveHandler func(f *DirectiveField) error
+
+// TransformDirective calls the handler with every field definition of an object or interface type
+// marked with the directive, which has to be declared in the schema, e.g.
+//
+//	directive @paginate on FIELD_DEFINITION
+//
+// The handlers are called after the schema is parsed and before the resolver is bound, type by type
+// in the order of their names, and may change the types and arguments of the fields, define new
+// types, and configure the schema, e.g. to resolve the fields they generate. The schema is then
+// parsed again with the changes, SDL returns the changed definition. E.g. a @paginate handler
+// changes a field of type [Book!]! into a Relay connection:
+//
+//	f.SetType("BookConnection!")
+//	f.AddArgument("first: Int")
+//	f.AddArgument("after: String")
+//	f.DefineType("BookConnection", `type BookConnection { edges: [BookEdge!]! pageInfo: PageInfo! }`)
+func TransformDirective(name string, handler DirectiveHandler) SchemaOpt {
+	return func(s *Schema) {
+		if s.directiveHandlers == nil {
+			s.directiveHandlers = make(map[string]DirectiveHandler)
+		}
+		s.directiveHandlers[name] = handler
+	}
+}
+
+// DirectiveField is a field definition marked with the directive of a handler, see
+// TransformDirective.
+type DirectiveField struct {
+	TypeName string // of the object or interface declaring the field
+	Name     string
+
+	// Args are the arguments of the directive, with the default values of its declaration.
+	Args map[string]interface{}
+
+	s     *Schema
+	field *schema.Field
+	t     *schemaTransform
+}
+
+// schemaTransform collects the changes of the handlers.
+type schemaTransform struct {
+	edits    map[string]*schema.FieldEdit
+	types    map[string]string
+	typeDefs []string // in the order they were defined
+}
+
+func (f *DirectiveField) edit() *schema.FieldEdit {
+	key := f.TypeName + "." + f.Name
+	e, ok := f.t.edits[key]
+	if !ok {
+		e = &schema.FieldEdit{}
+		f.t.edits[key] = e
+	}
+	return e
+}
+
+// Type returns the type of the field, e.g. "[Book!]!", as changed by the handlers so far.
+func (f *DirectiveField) Type() string {
+	if e, ok := f.t.edits[f.TypeName+"."+f.Name]; ok && e.Type != "" {
+		return e.Type
+	}
+	return f.field.Type.String()
+}
+
+// SetType changes the type of the field, e.g. to "BookConnection!".
+func (f *DirectiveField) SetType(typ string) {
+	f.edit().Type = typ
+}
+
+// AddArgument adds the argument with the definition, e.g. "first: Int = 10", to the field.
+func (f *DirectiveField) AddArgument(def string) {
+	e := f.edit()
+	e.Args = append(e.Args, def)
+}
+
+// HasArgument reports whether the field has the argument, also if added by a handler.
+func (f *DirectiveField) HasArgument(name string) bool {
+	if f.field.Args.Get(name) != nil {
+		return true
+	}
+	if e, ok := f.t.edits[f.TypeName+"."+f.Name]; ok {
+		for _, def := range e.Args {
+			if strings.TrimSpace(strings.SplitN(def, ":", 2)[0]) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasType reports whether the schema has the type, also if defined by a handler.
+func (f *DirectiveField) HasType(name string) bool {
+	if _, ok := f.s.schema.Types[name]; ok {
+		return true
+	}
+	_, ok := f.t.types[name]
+	return ok
+}
+
+// DefineType adds the definition of the type with the name to the schema, unless it has the type
+// already, e.g. because the handler defined it for another field. It reports whether it was added.
+func (f *DirectiveField) DefineType(name, def string) bool {
+	if f.HasType(name) {
+		return false
+	}
+	f.t.types[name] = def
+	f.t.typeDefs = append(f.t.typeDefs, def)
+	return true
+}
+
+// Apply applies the options to the schema, e.g. ResolveFieldFunc to resolve a generated field.
+func (f *DirectiveField) Apply(opts ...SchemaOpt) {
+	for _, opt := range opts {
+		opt(f.s)
+	}
+}
+
+// transformSchema calls the directive handlers and parses the schema again with their changes, see
+// TransformDirective.
+func (s *Schema) transformSchema() error {
+	t := &schemaTransform{edits: make(map[string]*schema.FieldEdit), types: make(map[string]string)}
+	var typeNames []string
+	for name := range s.schema.Types {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	for _, typeName := range typeNames {
+		var fields schema.FieldList
+		switch typ := s.schema.Types[typeName].(type) {
+		case *schema.Object:
+			fields = typ.Fields
+		case *schema.Interface:
+			fields = typ.Fields
+		}
+		for _, field := range fields {
+			for _, d := range field.Directives {
+				handler, ok := s.directiveHandlers[d.Name.Name]
+				if !ok {
+					continue
+				}
+				args := make(map[string]interface{})
+				if decl, ok := s.schema.Directives[d.Name.Name]; ok {
+					for _, arg := range decl.Args {
+						if arg.Default != nil {
+							args[arg.Name.Name] = arg.Default.Value(nil)
+						}
+					}
+				}
+				for _, arg := range d.Args {
+					args[arg.Name.Name] = arg.Value.Value(nil)
+				}
+				f := &DirectiveField{TypeName: typeName, Name: field.Name, Args: args, s: s, field: field, t: t}
+				if err := handler(f); err != nil {
+					return perrors.Wrapf(err, "directive @%s of %s.%s", d.Name.Name, typeName, field.Name)
+				}
+			}
+		}
+	}
+	if len(t.edits) == 0 && len(t.typeDefs) == 0 {
+		return nil
+	}
+
+	sdl := schema.PrintEdited(s.schema, t.edits)
+	for _, def := range t.typeDefs {
+		sdl += "\n" + def + "\n"
+	}
+	transformed := schema.New()
+	if err := transformed.ParseWithLimits(sdl, s.schemaSize); err != nil {
+		return perrors.Wrap(err, "schema changed by directives")
+	}
+	s.schema = transformed
+	s.sdl = sdl
+	return nil
+}
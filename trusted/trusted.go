@@ -0,0 +1,249 @@
+// Package trusted implements an allow-list of trusted documents, also known as persisted queries:
+// the GraphQL documents a server accepts, keyed by their ids. Clients send the id of a document
+// instead of its source. The documents are loaded from the manifests generated at build time by
+// the Relay compiler, GraphQL Code Generator or Apollo's persisted query tooling.
+package trusted
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	perrors "github.com/pkg/errors"
+)
+
+// Store holds the trusted documents. It is safe for concurrent use, documents may be replaced
+// while requests are served.
+type Store struct {
+	mu        sync.RWMutex
+	byID      map[string]string
+	ids       map[string][]string   // by source
+	variables map[string]*Variables // by id, kept when the documents are replaced
+	loaded    os.FileInfo           // of the manifest file last loaded
+}
+
+// Variables are the server-side variables of a trusted document, e.g. of an operation curated for
+// partners, see Store.SetVariables.
+type Variables struct {
+	// Defaults are merged under the variables of requests: they are used for the variables a
+	// request does not set, e.g. a page size.
+	Defaults map[string]interface{}
+
+	// Fixed replace the variables of requests, e.g. to lock a filter.
+	Fixed map[string]interface{}
+}
+
+// NewStore returns a store with the given documents, keyed by id.
+func NewStore(docs map[string]string) *Store {
+	s := &Store{}
+	s.Replace(docs)
+	return s
+}
+
+// Get returns the source of the document with the given id.
+func (s *Store) Get(id string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	doc, ok := s.byID[id]
+	return doc, ok
+}
+
+// Allowed reports whether the source of a document is one of the trusted documents.
+func (s *Store) Allowed(query string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.ids[query]
+	return ok
+}
+
+// SetVariables sets the server-side variables of the document with the id, replacing the previous
+// ones. nil removes them. They are kept when the documents of the store are replaced, e.g. by
+// Watch.
+func (s *Store) SetVariables(id string, v *Variables) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v == nil {
+		delete(s.variables, id)
+		return
+	}
+	if s.variables == nil {
+		s.variables = make(map[string]*Variables)
+	}
+	s.variables[id] = v
+}
+
+// ApplyVariables returns the variables of a request for the document with the id merged with the
+// server-side variables of the document, see Variables. The variables of the request are not
+// modified.
+func (s *Store) ApplyVariables(id string, variables map[string]interface{}) map[string]interface{} {
+	s.mu.RLock()
+	v := s.variables[id]
+	s.mu.RUnlock()
+	if v == nil {
+		return variables
+	}
+	merged := make(map[string]interface{}, len(v.Defaults)+len(variables)+len(v.Fixed))
+	for name, value := range v.Defaults {
+		merged[name] = value
+	}
+	for name, value := range variables {
+		merged[name] = value
+	}
+	for name, value := range v.Fixed {
+		merged[name] = value
+	}
+	return merged
+}
+
+// ApplySourceVariables is ApplyVariables for a request sending the source of a document instead of
+// its id: the server-side variables of the documents with the source are applied, so that sending
+// the source does not get around them. It reports false if the source is not trusted, or if the
+// documents with the source have different server-side variables, in which case the request has to
+// send the id of the document.
+func (s *Store) ApplySourceVariables(query string, variables map[string]interface{}) (map[string]interface{}, bool) {
+	s.mu.RLock()
+	ids, ok := s.ids[query]
+	var id string
+	var v *Variables
+	for _, other := range ids {
+		if w := s.variables[other]; w != nil && v == nil {
+			id, v = other, w
+		} else if w != nil && !reflect.DeepEqual(v, w) {
+			ok = false
+		}
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if v == nil {
+		return variables, true
+	}
+	return s.ApplyVariables(id, variables), true
+}
+
+// Len returns the number of trusted documents.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.byID)
+}
+
+// Replace replaces all documents of the store.
+func (s *Store) Replace(docs map[string]string) {
+	byID := make(map[string]string, len(docs))
+	ids := make(map[string][]string, len(docs))
+	for id, doc := range docs {
+		byID[id] = doc
+		ids[doc] = append(ids[doc], id)
+	}
+	s.mu.Lock()
+	s.byID = byID
+	s.ids = ids
+	s.mu.Unlock()
+}
+
+// LoadFile replaces the documents of the store with the ones of the manifest file, see
+// ParseManifest. The store is left unchanged if the file can not be read or parsed.
+func (s *Store) LoadFile(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return perrors.Wrap(err, "trusted")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return perrors.Wrap(err, "trusted")
+	}
+	docs, err := ParseManifest(data)
+	if err != nil {
+		return perrors.Wrapf(err, "trusted: %s", path)
+	}
+	s.Replace(docs)
+	s.mu.Lock()
+	s.loaded = fi
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the manifest file whenever its modification time or size differs from the file
+// loaded last, checking every interval until ctx is done. Errors of reloading are passed to onError,
+// which may be nil, and keep the documents loaded before. The file is expected to be loaded with
+// LoadFile at startup.
+func (s *Store) Watch(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	var failed os.FileInfo // the file that failed to load, not retried until it changes
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			if onError != nil {
+				onError(perrors.Wrap(err, "trusted"))
+			}
+			continue
+		}
+		s.mu.RLock()
+		loaded := s.loaded
+		s.mu.RUnlock()
+		if sameFile(fi, loaded) || sameFile(fi, failed) {
+			continue
+		}
+		if err := s.LoadFile(path); err != nil {
+			failed = fi
+			if onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func sameFile(a, b os.FileInfo) bool {
+	return b != nil && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+// ParseManifest parses a manifest of trusted documents and returns them keyed by id. It supports
+// the JSON object mapping ids to sources written by the Relay compiler and by GraphQL Code
+// Generator's persisted documents, as well as the persisted query manifest of Apollo, whose
+// "operations" list entries with an "id" and a "body".
+func ParseManifest(data []byte) (map[string]string, error) {
+	var manifest map[string]json.RawMessage
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, perrors.Wrap(err, "invalid manifest")
+	}
+
+	if ops, ok := manifest["operations"]; ok {
+		var operations []struct {
+			ID   string `json:"id"`
+			Body string `json:"body"`
+		}
+		if err := json.Unmarshal(ops, &operations); err == nil {
+			docs := make(map[string]string, len(operations))
+			for i, op := range operations {
+				if op.ID == "" || op.Body == "" {
+					return nil, perrors.Errorf("invalid manifest: operation %d has no id or body", i)
+				}
+				docs[op.ID] = op.Body
+			}
+			return docs, nil
+		}
+	}
+
+	docs := make(map[string]string, len(manifest))
+	for id, raw := range manifest {
+		var doc string
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, perrors.Errorf("invalid manifest: document %q is not a string", id)
+		}
+		docs[id] = doc
+	}
+	return docs, nil
+}
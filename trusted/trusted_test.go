@@ -0,0 +1,163 @@
+package trusted_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qdentity/graphql-go/trusted"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "relay",
+			manifest: `{"3be4abb81fa595e25eb725b2c6a87508": "query AppQuery { hero { name } }"}`,
+			want:     map[string]string{"3be4abb81fa595e25eb725b2c6a87508": "query AppQuery { hero { name } }"},
+		},
+		{
+			name: "apollo",
+			manifest: `{
+				"format": "apollo-persisted-query-manifest",
+				"version": 1,
+				"operations": [
+					{"id": "abc", "name": "AppQuery", "type": "query", "body": "query AppQuery { hero { name } }"}
+				]
+			}`,
+			want: map[string]string{"abc": "query AppQuery { hero { name } }"},
+		},
+		{
+			name:     "invalid document",
+			manifest: `{"abc": 1}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid operation",
+			manifest: `{"operations": [{"id": "abc"}]}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := trusted.ParseManifest([]byte(tt.manifest))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trusted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "persisted-documents.json")
+
+	if err := ioutil.WriteFile(path, []byte(`{"a": "{ a }"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := trusted.NewStore(nil)
+	if err := s.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if doc, ok := s.Get("a"); !ok || doc != "{ a }" || !s.Allowed("{ a }") {
+		t.Fatalf("document a not loaded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	go s.Watch(ctx, path, time.Millisecond, func(err error) { errs <- err })
+
+	if err := ioutil.WriteFile(path, []byte(`{"b": "{ bb }"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, ok := s.Get("b"); ok {
+			break
+		}
+	}
+	if _, ok := s.Get("b"); !ok || s.Allowed("{ a }") || s.Len() != 1 {
+		t.Fatalf("manifest not reloaded")
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"c": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported for an invalid manifest")
+	}
+	if _, ok := s.Get("b"); !ok {
+		t.Error("documents dropped after an invalid manifest")
+	}
+}
+
+func TestApplyVariables(t *testing.T) {
+	s := trusted.NewStore(map[string]string{"a": "query($first: Int, $status: String) { a }"})
+	s.SetVariables("a", &trusted.Variables{
+		Defaults: map[string]interface{}{"first": 10},
+		Fixed:    map[string]interface{}{"status": "PUBLISHED"},
+	})
+	s.Replace(map[string]string{"a": "query($first: Int, $status: String) { a b }"})
+
+	request := map[string]interface{}{"first": 50, "status": "DRAFT"}
+	got := s.ApplyVariables("a", request)
+	if want := map[string]interface{}{"first": 50, "status": "PUBLISHED"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables %v, want %v", got, want)
+	}
+	if request["status"] != "DRAFT" {
+		t.Error("variables of the request modified")
+	}
+	if got, want := s.ApplyVariables("a", nil), map[string]interface{}{"first": 10, "status": "PUBLISHED"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables %v, want %v", got, want)
+	}
+
+	s.SetVariables("a", nil)
+	if got := s.ApplyVariables("a", request); !reflect.DeepEqual(got, request) {
+		t.Errorf("got variables %v after removing the server-side ones", got)
+	}
+}
+
+func TestApplySourceVariables(t *testing.T) {
+	s := trusted.NewStore(map[string]string{
+		"a":     "query($status: String) { a }",
+		"c1":    "query($status: String) { c }",
+		"c2":    "query($status: String) { c }",
+		"plain": "{ plain }",
+	})
+	s.SetVariables("a", &trusted.Variables{Fixed: map[string]interface{}{"status": "PUBLISHED"}})
+	s.SetVariables("c1", &trusted.Variables{Fixed: map[string]interface{}{"status": "PUBLISHED"}})
+	s.SetVariables("c2", &trusted.Variables{Fixed: map[string]interface{}{"status": "DRAFT"}})
+
+	request := map[string]interface{}{"status": "DRAFT"}
+	got, ok := s.ApplySourceVariables("query($status: String) { a }", request)
+	if want := map[string]interface{}{"status": "PUBLISHED"}; !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables %v, %t, want %v", got, ok, want)
+	}
+	if got, ok := s.ApplySourceVariables("{ plain }", request); !ok || !reflect.DeepEqual(got, request) {
+		t.Errorf("got variables %v, %t for a document without server-side variables", got, ok)
+	}
+	if _, ok := s.ApplySourceVariables("query($status: String) { c }", request); ok {
+		t.Error("source of documents with different server-side variables accepted")
+	}
+	if _, ok := s.ApplySourceVariables("{ unknown }", request); ok {
+		t.Error("unknown source accepted")
+	}
+}